@@ -26,6 +26,10 @@ import (
 	"github.com/versity/versitygw/s3err"
 )
 
+// AllUsersGroupURI is the grantee URI of the group of all users,
+// including anonymous ones
+const AllUsersGroupURI = "http://acs.amazonaws.com/groups/global/AllUsers"
+
 type ACL struct {
 	ACL      types.BucketCannedACL
 	Owner    string
@@ -72,6 +76,10 @@ func ParseACLOutput(data []byte) (GetBucketAclOutput, error) {
 
 	for _, elem := range acl.Grantees {
 		acs := elem.Access
+		if acs == AllUsersGroupURI {
+			grants = append(grants, types.Grant{Grantee: &types.Grantee{URI: &acs, Type: types.TypeGroup}, Permission: elem.Permission})
+			continue
+		}
 		grants = append(grants, types.Grant{Grantee: &types.Grantee{ID: &acs}, Permission: elem.Permission})
 	}
 
@@ -135,10 +143,24 @@ func UpdateACL(input *s3.PutBucketAclInput, acl ACL, iam IAMService) ([]byte, er
 				}
 			}
 
-			accs = append(append(append(append(fullControlList, readList...), writeACPList...), readACPList...), writeList...)
+			for i := range grantees {
+				grantees[i].Access = parseGrantHeaderGrantee(grantees[i].Access)
+			}
+			for _, acc := range append(append(append(append(fullControlList, readList...), writeACPList...), readACPList...), writeList...) {
+				if parseGrantHeaderGrantee(acc) != AllUsersGroupURI {
+					accs = append(accs, acc)
+				}
+			}
 		} else {
 			cache := make(map[string]bool)
 			for _, grt := range input.AccessControlPolicy.Grants {
+				if grt.Grantee != nil && grt.Grantee.ID == nil && grt.Grantee.URI != nil && grt.Permission != "" {
+					if *grt.Grantee.URI != AllUsersGroupURI {
+						return nil, s3err.GetAPIError(s3err.ErrInvalidRequest)
+					}
+					grantees = append(grantees, Grantee{Access: AllUsersGroupURI, Permission: grt.Permission})
+					continue
+				}
 				if grt.Grantee == nil || grt.Grantee.ID == nil || grt.Permission == "" {
 					return nil, s3err.GetAPIError(s3err.ErrInvalidRequest)
 				}
@@ -190,6 +212,18 @@ func CheckIfAccountsExist(accs []string, iam IAMService) ([]string, error) {
 	return result, nil
 }
 
+// parseGrantHeaderGrantee returns the grantee of an x-amz-grant-* header
+// entry, which is either an account access key or the AllUsers group
+// in the uri="..." form
+func parseGrantHeaderGrantee(s string) string {
+	uri, found := strings.CutPrefix(strings.TrimSpace(s), "uri=")
+	if found && strings.Trim(uri, `"`) == AllUsersGroupURI {
+		return AllUsersGroupURI
+	}
+
+	return s
+}
+
 func splitUnique(s, divider string) []string {
 	elements := strings.Split(s, divider)
 	uniqueElements := make(map[string]bool)
@@ -208,7 +242,7 @@ func splitUnique(s, divider string) []string {
 func verifyACL(acl ACL, access string, permission types.Permission) error {
 	// Default disabled ACL case
 	if acl.ACL == "" && len(acl.Grantees) == 0 {
-		if acl.Owner == access {
+		if access != "" && acl.Owner == access {
 			return nil
 		}
 
@@ -227,11 +261,13 @@ func verifyACL(acl ACL, access string, permission types.Permission) error {
 	} else {
 		grantee := Grantee{Access: access, Permission: permission}
 		granteeFullCtrl := Grantee{Access: access, Permission: "FULL_CONTROL"}
+		allUsers := Grantee{Access: AllUsersGroupURI, Permission: permission}
+		allUsersFullCtrl := Grantee{Access: AllUsersGroupURI, Permission: "FULL_CONTROL"}
 
 		isFound := false
 
 		for _, grt := range acl.Grantees {
-			if grt == grantee || grt == granteeFullCtrl {
+			if grt == grantee || grt == granteeFullCtrl || grt == allUsers || grt == allUsersFullCtrl {
 				isFound = true
 				break
 			}
@@ -245,12 +281,54 @@ func verifyACL(acl ACL, access string, permission types.Permission) error {
 	return s3err.GetAPIError(s3err.ErrAccessDenied)
 }
 
+// publicACLPermissions are the ACL permissions that grant each action to
+// anonymous requests. The other actions are never granted by the ACL.
+var publicACLPermissions = map[Action]types.Permission{
+	ListBucketAction:                 types.PermissionRead,
+	ListBucketVersionsAction:         types.PermissionRead,
+	ListBucketMultipartUploadsAction: types.PermissionRead,
+	GetObjectAction:                  types.PermissionRead,
+	PutObjectAction:                  types.PermissionWrite,
+	DeleteObjectAction:               types.PermissionWrite,
+	GetBucketAclAction:               types.PermissionReadAcp,
+	GetObjectAclAction:               types.PermissionReadAcp,
+	PutBucketAclAction:               types.PermissionWriteAcp,
+	PutObjectAclAction:               types.PermissionWriteAcp,
+}
+
+// verifyPublicACL checks that the ACL grants the permission to the
+// AllUsers group. The canned ACLs grant READ and WRITE, the ACL itself
+// can only be read or changed through an explicit grant.
+func verifyPublicACL(acl ACL, permission types.Permission) error {
+	switch permission {
+	case types.PermissionRead:
+		if acl.ACL == types.BucketCannedACLPublicRead || acl.ACL == types.BucketCannedACLPublicReadWrite {
+			return nil
+		}
+	case types.PermissionWrite:
+		if acl.ACL == types.BucketCannedACLPublicReadWrite {
+			return nil
+		}
+	}
+
+	for _, grt := range acl.Grantees {
+		if grt.Access != AllUsersGroupURI {
+			continue
+		}
+		if grt.Permission == permission || grt.Permission == types.PermissionFullControl {
+			return nil
+		}
+	}
+
+	return s3err.GetAPIError(s3err.ErrAccessDenied)
+}
+
 func MayCreateBucket(acct Account, isRoot bool) error {
 	if isRoot {
 		return nil
 	}
 
-	if acct.Role == RoleUser {
+	if acct.Role == RoleUser || acct.Role == RoleAnonymous {
 		return s3err.GetAPIError(s3err.ErrAccessDenied)
 	}
 
@@ -258,6 +336,11 @@ func MayCreateBucket(acct Account, isRoot bool) error {
 }
 
 func IsAdminOrOwner(acct Account, isRoot bool, acl ACL) error {
+	// Anonymous requests are never the owner
+	if acct.Role == RoleAnonymous {
+		return s3err.GetAPIError(s3err.ErrAccessDenied)
+	}
+
 	// Owner check
 	if acct.Access == acl.Owner {
 		return nil
@@ -288,9 +371,25 @@ type AccessOptions struct {
 }
 
 func VerifyAccess(ctx context.Context, be backend.Backend, opts AccessOptions) error {
+	if err := verifySessionPolicy(ctx, opts); err != nil {
+		return err
+	}
 	if opts.IsRoot {
 		return nil
 	}
+	if opts.Acc.Role == RoleAnonymous {
+		return verifyPublicAccess(ctx, be, opts)
+	}
+
+	// an explicit deny of the identity policy applies to any account
+	policyAllowed, policyDenied, err := evaluateIdentityPolicy(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if policyDenied {
+		return s3err.GetAPIError(s3err.ErrAccessDenied)
+	}
+
 	if opts.Acc.Role == RoleAdmin {
 		return nil
 	}
@@ -298,6 +397,12 @@ func VerifyAccess(ctx context.Context, be backend.Backend, opts AccessOptions) e
 		return nil
 	}
 
+	// the identity policy grants access regardless of the bucket ACL,
+	// only an explicit deny of the bucket policy overrides it
+	if policyAllowed {
+		return verifyBucketPolicyDeny(ctx, be, opts)
+	}
+
 	if err := verifyACL(opts.Acl, opts.Acc.Access, opts.AclPermission); err != nil {
 		return err
 	}
@@ -308,13 +413,63 @@ func VerifyAccess(ctx context.Context, be backend.Backend, opts AccessOptions) e
 	return nil
 }
 
-func VerifyObjectCopyAccess(ctx context.Context, be backend.Backend, copySource string, opts AccessOptions) error {
+// VerifyExplicitAccess authorizes actions that the bucket ACL never
+// grants, such as bypassing governance mode retention. Apart from the
+// root account, admins and the bucket owner, the action must be allowed
+// explicitly by either the identity policy or the bucket policy.
+func VerifyExplicitAccess(ctx context.Context, be backend.Backend, opts AccessOptions) error {
+	if err := verifySessionPolicy(ctx, opts); err != nil {
+		return err
+	}
 	if opts.IsRoot {
 		return nil
 	}
-	if opts.Acc.Role == RoleAdmin {
+	if opts.Acc.Role == RoleAnonymous {
+		return s3err.GetAPIError(s3err.ErrAccessDenied)
+	}
+
+	policyAllowed, policyDenied, err := evaluateIdentityPolicy(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if policyDenied {
+		return s3err.GetAPIError(s3err.ErrAccessDenied)
+	}
+
+	if opts.Acc.Role == RoleAdmin || opts.Acc.Access == opts.Acl.Owner {
 		return nil
 	}
+	if policyAllowed {
+		return verifyBucketPolicyDeny(ctx, be, opts)
+	}
+
+	bucketPolicy, err := getBucketPolicy(ctx, be, opts.Bucket)
+	if err != nil {
+		return err
+	}
+	if bucketPolicy == nil {
+		return s3err.GetAPIError(s3err.ErrAccessDenied)
+	}
+	allowed, denied := bucketPolicy.evaluate(opts.Acc.Access, opts.Action,
+		policyResource(opts.Bucket, opts.Object), getConditionContext(ctx))
+	if !allowed || denied {
+		return s3err.GetAPIError(s3err.ErrAccessDenied)
+	}
+
+	return nil
+}
+
+func VerifyObjectCopyAccess(ctx context.Context, be backend.Backend, copySource string, opts AccessOptions) error {
+	// the source and destination of session requests and of accounts
+	// with an identity policy are both checked against the policies
+	if opts.Acc.SessionPolicy == nil && opts.Acc.Policy == "" {
+		if opts.IsRoot {
+			return nil
+		}
+		if opts.Acc.Role == RoleAdmin {
+			return nil
+		}
+	}
 
 	// Verify destination bucket access
 	if err := VerifyAccess(ctx, be, opts); err != nil {
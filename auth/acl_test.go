@@ -0,0 +1,102 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/versity/versitygw/backend"
+)
+
+// policyBackend returns the bucket policy of any bucket
+type policyBackend struct {
+	backend.BackendUnsupported
+	policy string
+}
+
+func (b policyBackend) GetBucketPolicy(context.Context, string) ([]byte, error) {
+	return []byte(b.policy), nil
+}
+
+func TestVerifyAccessAnonymous(t *testing.T) {
+	publicRead := ACL{ACL: types.BucketCannedACLPublicRead, Owner: "owner"}
+	publicReadWrite := ACL{ACL: types.BucketCannedACLPublicReadWrite, Owner: "owner"}
+	allUsers := func(permissions ...types.Permission) ACL {
+		acl := ACL{Owner: "owner"}
+		for _, p := range permissions {
+			acl.Grantees = append(acl.Grantees, Grantee{Access: AllUsersGroupURI, Permission: p})
+		}
+		return acl
+	}
+	allowConfig := `{"Statement": [{"Effect": "Allow", "Principal": "*", "Action": ["s3:PutBucketPolicy", "s3:DeleteBucket"], "Resource": "arn:aws:s3:::bucket"}]}`
+
+	tests := []struct {
+		name    string
+		acl     ACL
+		policy  string
+		action  Action
+		object  string
+		allowed bool
+	}{
+		{"private-get-object", ACL{Owner: "owner"}, "", GetObjectAction, "obj", false},
+		{"public-read-get-object", publicRead, "", GetObjectAction, "obj", true},
+		{"public-read-list-bucket", publicRead, "", ListBucketAction, "", true},
+		{"public-read-list-versions", publicRead, "", ListBucketVersionsAction, "", true},
+		{"public-read-put-object", publicRead, "", PutObjectAction, "obj", false},
+		{"public-read-get-bucket-acl", publicRead, "", GetBucketAclAction, "", false},
+		{"public-read-get-bucket-policy", publicRead, "", GetBucketPolicyAction, "", false},
+		{"public-read-get-lifecycle", publicRead, "", GetLifecycleConfigurationAction, "", false},
+		{"public-read-get-object-tagging", publicRead, "", GetObjectTaggingAction, "obj", false},
+		{"public-read-write-put-object", publicReadWrite, "", PutObjectAction, "obj", true},
+		{"public-read-write-delete-object", publicReadWrite, "", DeleteObjectAction, "obj", true},
+		{"public-read-write-put-bucket-acl", publicReadWrite, "", PutBucketAclAction, "", false},
+		{"public-read-write-put-bucket-policy", publicReadWrite, "", PutBucketPolicyAction, "", false},
+		{"public-read-write-delete-bucket-policy", publicReadWrite, "", DeleteBucketPolicyAction, "", false},
+		{"public-read-write-delete-bucket", publicReadWrite, "", DeleteBucketAction, "", false},
+		{"public-read-write-put-versioning", publicReadWrite, "", PutBucketVersioningAction, "", false},
+		{"public-read-write-put-lifecycle", publicReadWrite, "", PutLifecycleConfigurationAction, "", false},
+		{"public-read-write-put-cors", publicReadWrite, "", PutBucketCorsAction, "", false},
+		{"public-read-write-delete-website", publicReadWrite, "", DeleteBucketWebsiteAction, "", false},
+		{"public-read-write-put-object-lock", publicReadWrite, "", PutObjectLockConfigurationAction, "", false},
+		{"all-users-read-list-bucket", allUsers(types.PermissionRead), "", ListBucketAction, "", true},
+		{"all-users-read-put-object", allUsers(types.PermissionRead), "", PutObjectAction, "obj", false},
+		{"all-users-write-put-object", allUsers(types.PermissionWrite), "", PutObjectAction, "obj", true},
+		{"all-users-write-put-bucket-policy", allUsers(types.PermissionWrite), "", PutBucketPolicyAction, "", false},
+		{"all-users-write-put-bucket-acl", allUsers(types.PermissionWrite), "", PutBucketAclAction, "", false},
+		{"all-users-read-acp-get-bucket-acl", allUsers(types.PermissionReadAcp), "", GetBucketAclAction, "", true},
+		{"all-users-write-acp-put-bucket-acl", allUsers(types.PermissionWriteAcp), "", PutBucketAclAction, "", true},
+		{"all-users-full-control-delete-bucket", allUsers(types.PermissionFullControl), "", DeleteBucketAction, "", false},
+		{"policy-allow-put-bucket-policy", ACL{Owner: "owner"}, allowConfig, PutBucketPolicyAction, "", true},
+		{"policy-allow-delete-bucket", ACL{Owner: "owner"}, allowConfig, DeleteBucketAction, "", true},
+		{"policy-other-action", publicReadWrite, allowConfig, PutBucketCorsAction, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyAccess(context.Background(), policyBackend{policy: tt.policy}, AccessOptions{
+				Acl:           tt.acl,
+				AclPermission: types.PermissionWrite,
+				Acc:           AnonymousAccount,
+				Bucket:        "bucket",
+				Object:        tt.object,
+				Action:        tt.action,
+			})
+			if (err == nil) != tt.allowed {
+				t.Errorf("VerifyAccess() error = %v, allowed %v", err, tt.allowed)
+			}
+		})
+	}
+}
@@ -39,34 +39,95 @@ func (bp *BucketPolicy) Validate(bucket string, iam IAMService) error {
 	return nil
 }
 
-func (bp *BucketPolicy) isAllowed(principal string, action Action, resource string) bool {
+// isAllowed evaluates the policy statements: an explicit deny
+// overrides any allow, and access is implicitly denied when no
+// statement allows it
+func (bp *BucketPolicy) isAllowed(principal string, action Action, resource string, cc ConditionContext) bool {
+	allowed, denied := bp.evaluate(principal, action, resource, cc)
+	return allowed && !denied
+}
+
+// evaluate returns whether any of the matching statements allows or
+// explicitly denies the request
+func (bp *BucketPolicy) evaluate(principal string, action Action, resource string, cc ConditionContext) (allowed, denied bool) {
 	for _, statement := range bp.Statement {
-		if statement.isAllowed(principal, action, resource) {
-			return true
+		if !statement.matches(principal, action, resource, cc) {
+			continue
+		}
+		switch statement.Effect {
+		case BucketPolicyAccessTypeDeny:
+			return false, true
+		case BucketPolicyAccessTypeAllow:
+			allowed = true
 		}
 	}
 
-	return false
+	return allowed, false
 }
 
 type BucketPolicyItem struct {
-	Effect     BucketPolicyAccessType `json:"Effect"`
-	Principals Principals             `json:"Principal"`
-	Actions    Actions                `json:"Action"`
-	Resources  Resources              `json:"Resource"`
+	Effect        BucketPolicyAccessType `json:"Effect"`
+	Principals    Principals             `json:"Principal,omitempty"`
+	NotPrincipals Principals             `json:"NotPrincipal,omitempty"`
+	Actions       Actions                `json:"Action,omitempty"`
+	NotActions    Actions                `json:"NotAction,omitempty"`
+	Resources     Resources              `json:"Resource,omitempty"`
+	NotResources  Resources              `json:"NotResource,omitempty"`
+	Conditions    Conditions             `json:"Condition,omitempty"`
 }
 
 func (bpi *BucketPolicyItem) Validate(bucket string, iam IAMService) error {
 	if err := bpi.Effect.Validate(); err != nil {
 		return err
 	}
-	if err := bpi.Principals.Validate(iam); err != nil {
-		return err
+
+	switch {
+	case bpi.Principals != nil && bpi.NotPrincipals != nil:
+		return fmt.Errorf("only one of Principal and NotPrincipal can be specified")
+	case bpi.Principals != nil:
+		if err := bpi.Principals.Validate(iam); err != nil {
+			return err
+		}
+	case bpi.NotPrincipals != nil:
+		if err := bpi.NotPrincipals.Validate(iam); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("missing Principal or NotPrincipal")
 	}
-	if err := bpi.Resources.Validate(bucket); err != nil {
+
+	switch {
+	case bpi.Actions != nil && bpi.NotActions != nil:
+		return fmt.Errorf("only one of Action and NotAction can be specified")
+	case bpi.Actions == nil && bpi.NotActions == nil:
+		return fmt.Errorf("missing Action or NotAction")
+	}
+
+	switch {
+	case bpi.Resources != nil && bpi.NotResources != nil:
+		return fmt.Errorf("only one of Resource and NotResource can be specified")
+	case bpi.Resources != nil:
+		if err := bpi.Resources.Validate(bucket); err != nil {
+			return err
+		}
+	case bpi.NotResources != nil:
+		if err := bpi.NotResources.Validate(bucket); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("missing Resource or NotResource")
+	}
+
+	if err := bpi.Conditions.Validate(); err != nil {
 		return err
 	}
 
+	// The actions can only be checked against the resources they are
+	// applied to when both are listed explicitly
+	if bpi.Actions == nil || bpi.Resources == nil {
+		return nil
+	}
+
 	containsObjectAction := bpi.Resources.ContainsObjectPattern()
 	containsBucketAction := bpi.Resources.ContainsBucketPattern()
 
@@ -83,17 +144,34 @@ func (bpi *BucketPolicyItem) Validate(bucket string, iam IAMService) error {
 	return nil
 }
 
-func (bpi *BucketPolicyItem) isAllowed(principal string, action Action, resource string) bool {
-	if bpi.Principals.Contains(principal) && bpi.Actions.FindMatch(action) && bpi.Resources.FindMatch(resource) {
-		switch bpi.Effect {
-		case BucketPolicyAccessTypeAllow:
-			return true
-		case BucketPolicyAccessTypeDeny:
+// matches returns true if the statement applies to the request,
+// regardless of its effect
+func (bpi *BucketPolicyItem) matches(principal string, action Action, resource string, cc ConditionContext) bool {
+	if bpi.NotPrincipals != nil {
+		if bpi.NotPrincipals.Contains(principal) {
 			return false
 		}
+	} else if !bpi.Principals.Contains(principal) {
+		return false
 	}
 
-	return false
+	if bpi.NotActions != nil {
+		if bpi.NotActions.FindMatch(action) {
+			return false
+		}
+	} else if !bpi.Actions.FindMatch(action) {
+		return false
+	}
+
+	if bpi.NotResources != nil {
+		if bpi.NotResources.FindMatch(resource) {
+			return false
+		}
+	} else if !bpi.Resources.FindMatch(resource) {
+		return false
+	}
+
+	return bpi.Conditions.Evaluate(cc)
 }
 
 func getMalformedPolicyError(err error) error {
@@ -117,29 +195,92 @@ func ValidatePolicyDocument(policyBin []byte, bucket string, iam IAMService) err
 	return nil
 }
 
-func verifyBucketPolicy(ctx context.Context, be backend.Backend, access, bucket, object string, action Action) error {
+// getBucketPolicy returns the parsed bucket policy, or nil if the
+// bucket has no policy
+func getBucketPolicy(ctx context.Context, be backend.Backend, bucket string) (*BucketPolicy, error) {
 	policyDoc, err := be.GetBucketPolicy(ctx, bucket)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	// If bucket policy is not set
 	if len(policyDoc) == 0 {
-		return nil
+		return nil, nil
 	}
 
 	var bucketPolicy BucketPolicy
 	if err := json.Unmarshal(policyDoc, &bucketPolicy); err != nil {
-		return err
+		return nil, err
 	}
 
+	return &bucketPolicy, nil
+}
+
+func policyResource(bucket, object string) string {
 	resource := bucket
 	if object != "" {
-		resource += "" + object
+		resource += "/" + object
+	}
+	return resource
+}
+
+func verifyBucketPolicy(ctx context.Context, be backend.Backend, access, bucket, object string, action Action) error {
+	bucketPolicy, err := getBucketPolicy(ctx, be, bucket)
+	if err != nil {
+		return err
+	}
+	if bucketPolicy == nil {
+		return nil
 	}
 
-	if !bucketPolicy.isAllowed(access, action, resource) {
+	if !bucketPolicy.isAllowed(access, action, policyResource(bucket, object), getConditionContext(ctx)) {
 		return s3err.GetAPIError(s3err.ErrAccessDenied)
 	}
 
 	return nil
 }
+
+// verifyBucketPolicyDeny denies the requests explicitly denied by the
+// bucket policy
+func verifyBucketPolicyDeny(ctx context.Context, be backend.Backend, opts AccessOptions) error {
+	bucketPolicy, err := getBucketPolicy(ctx, be, opts.Bucket)
+	if err != nil {
+		return err
+	}
+	if bucketPolicy == nil {
+		return nil
+	}
+
+	_, denied := bucketPolicy.evaluate(opts.Acc.Access, opts.Action, policyResource(opts.Bucket, opts.Object), getConditionContext(ctx))
+	if denied {
+		return s3err.GetAPIError(s3err.ErrAccessDenied)
+	}
+
+	return nil
+}
+
+// verifyPublicAccess authorizes anonymous requests: an explicit deny in
+// the bucket policy rejects the request, otherwise either a bucket policy
+// allow or a public ACL grant is enough. ACL grants only apply to the
+// actions the ACL permissions map to, the bucket configuration actions
+// need a bucket policy allow.
+func verifyPublicAccess(ctx context.Context, be backend.Backend, opts AccessOptions) error {
+	bucketPolicy, err := getBucketPolicy(ctx, be, opts.Bucket)
+	if err != nil {
+		return err
+	}
+	if bucketPolicy != nil {
+		allowed, denied := bucketPolicy.evaluate(opts.Acc.Access, opts.Action, policyResource(opts.Bucket, opts.Object), getConditionContext(ctx))
+		if denied {
+			return s3err.GetAPIError(s3err.ErrAccessDenied)
+		}
+		if allowed {
+			return nil
+		}
+	}
+
+	permission, ok := publicACLPermissions[opts.Action]
+	if !ok {
+		return s3err.GetAPIError(s3err.ErrAccessDenied)
+	}
+	return verifyPublicACL(opts.Acl, permission)
+}
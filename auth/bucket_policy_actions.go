@@ -49,6 +49,20 @@ const (
 	DeleteObjectTaggingAction        Action = "s3:DeleteObjectTagging"
 	ListBucketVersionsAction         Action = "s3:ListBucketVersions"
 	ListBucketAction                 Action = "s3:ListBucket"
+	PutObjectLockConfigurationAction Action = "s3:PutBucketObjectLockConfiguration"
+	GetObjectLockConfigurationAction Action = "s3:GetBucketObjectLockConfiguration"
+	PutObjectRetentionAction         Action = "s3:PutObjectRetention"
+	GetObjectRetentionAction         Action = "s3:GetObjectRetention"
+	PutObjectLegalHoldAction         Action = "s3:PutObjectLegalHold"
+	GetObjectLegalHoldAction         Action = "s3:GetObjectLegalHold"
+	BypassGovernanceRetentionAction  Action = "s3:BypassGovernanceRetention"
+	PutLifecycleConfigurationAction  Action = "s3:PutLifecycleConfiguration"
+	GetLifecycleConfigurationAction  Action = "s3:GetLifecycleConfiguration"
+	PutBucketCorsAction              Action = "s3:PutBucketCORS"
+	GetBucketCorsAction              Action = "s3:GetBucketCORS"
+	PutBucketWebsiteAction           Action = "s3:PutBucketWebsite"
+	GetBucketWebsiteAction           Action = "s3:GetBucketWebsite"
+	DeleteBucketWebsiteAction        Action = "s3:DeleteBucketWebsite"
 	AllActions                       Action = "s3:*"
 )
 
@@ -79,23 +93,42 @@ var supportedActionList = map[Action]struct{}{
 	DeleteObjectTaggingAction:        {},
 	ListBucketVersionsAction:         {},
 	ListBucketAction:                 {},
+	PutObjectLockConfigurationAction: {},
+	GetObjectLockConfigurationAction: {},
+	PutObjectRetentionAction:         {},
+	GetObjectRetentionAction:         {},
+	PutObjectLegalHoldAction:         {},
+	GetObjectLegalHoldAction:         {},
+	BypassGovernanceRetentionAction:  {},
+	PutLifecycleConfigurationAction:  {},
+	GetLifecycleConfigurationAction:  {},
+	PutBucketCorsAction:              {},
+	GetBucketCorsAction:              {},
+	PutBucketWebsiteAction:           {},
+	GetBucketWebsiteAction:           {},
+	DeleteBucketWebsiteAction:        {},
 	AllActions:                       {},
 }
 
 var supportedObjectActionList = map[Action]struct{}{
-	AbortMultipartUploadAction:     {},
-	ListMultipartUploadPartsAction: {},
-	PutObjectAction:                {},
-	GetObjectAction:                {},
-	DeleteObjectAction:             {},
-	GetObjectAclAction:             {},
-	GetObjectAttributesAction:      {},
-	PutObjectAclAction:             {},
-	RestoreObjectAction:            {},
-	GetObjectTaggingAction:         {},
-	PutObjectTaggingAction:         {},
-	DeleteObjectTaggingAction:      {},
-	AllActions:                     {},
+	AbortMultipartUploadAction:      {},
+	ListMultipartUploadPartsAction:  {},
+	PutObjectAction:                 {},
+	GetObjectAction:                 {},
+	DeleteObjectAction:              {},
+	GetObjectAclAction:              {},
+	GetObjectAttributesAction:       {},
+	PutObjectAclAction:              {},
+	RestoreObjectAction:             {},
+	GetObjectTaggingAction:          {},
+	PutObjectTaggingAction:          {},
+	DeleteObjectTaggingAction:       {},
+	PutObjectRetentionAction:        {},
+	GetObjectRetentionAction:        {},
+	PutObjectLegalHoldAction:        {},
+	GetObjectLegalHoldAction:        {},
+	BypassGovernanceRetentionAction: {},
+	AllActions:                      {},
 }
 
 // Validates Action: it should either wildcard match with supported actions list or be in it
@@ -0,0 +1,389 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ConditionOperator string
+
+const (
+	StringEqualsOperator              ConditionOperator = "StringEquals"
+	StringNotEqualsOperator           ConditionOperator = "StringNotEquals"
+	StringEqualsIgnoreCaseOperator    ConditionOperator = "StringEqualsIgnoreCase"
+	StringNotEqualsIgnoreCaseOperator ConditionOperator = "StringNotEqualsIgnoreCase"
+	StringLikeOperator                ConditionOperator = "StringLike"
+	StringNotLikeOperator             ConditionOperator = "StringNotLike"
+	NumericEqualsOperator             ConditionOperator = "NumericEquals"
+	NumericNotEqualsOperator          ConditionOperator = "NumericNotEquals"
+	NumericLessThanOperator           ConditionOperator = "NumericLessThan"
+	NumericLessThanEqualsOperator     ConditionOperator = "NumericLessThanEquals"
+	NumericGreaterThanOperator        ConditionOperator = "NumericGreaterThan"
+	NumericGreaterThanEqualsOperator  ConditionOperator = "NumericGreaterThanEquals"
+	DateEqualsOperator                ConditionOperator = "DateEquals"
+	DateNotEqualsOperator             ConditionOperator = "DateNotEquals"
+	DateLessThanOperator              ConditionOperator = "DateLessThan"
+	DateLessThanEqualsOperator        ConditionOperator = "DateLessThanEquals"
+	DateGreaterThanOperator           ConditionOperator = "DateGreaterThan"
+	DateGreaterThanEqualsOperator     ConditionOperator = "DateGreaterThanEquals"
+	BoolOperator                      ConditionOperator = "Bool"
+	IpAddressOperator                 ConditionOperator = "IpAddress"
+	NotIpAddressOperator              ConditionOperator = "NotIpAddress"
+)
+
+type conditionOperator struct {
+	// parse validates a policy value of the operator
+	parse func(value string) error
+	// match compares a request value with a policy value
+	match func(reqValue, policyValue string) bool
+	// negated operators match when none of the values match
+	negated bool
+}
+
+var supportedConditionOperators = map[ConditionOperator]conditionOperator{
+	StringEqualsOperator:              {parse: parseString, match: stringEquals},
+	StringNotEqualsOperator:           {parse: parseString, match: stringEquals, negated: true},
+	StringEqualsIgnoreCaseOperator:    {parse: parseString, match: strings.EqualFold},
+	StringNotEqualsIgnoreCaseOperator: {parse: parseString, match: strings.EqualFold, negated: true},
+	StringLikeOperator:                {parse: parseString, match: stringLike},
+	StringNotLikeOperator:             {parse: parseString, match: stringLike, negated: true},
+	NumericEqualsOperator:             {parse: parseNumeric, match: numericCompare(func(c int) bool { return c == 0 })},
+	NumericNotEqualsOperator:          {parse: parseNumeric, match: numericCompare(func(c int) bool { return c == 0 }), negated: true},
+	NumericLessThanOperator:           {parse: parseNumeric, match: numericCompare(func(c int) bool { return c < 0 })},
+	NumericLessThanEqualsOperator:     {parse: parseNumeric, match: numericCompare(func(c int) bool { return c <= 0 })},
+	NumericGreaterThanOperator:        {parse: parseNumeric, match: numericCompare(func(c int) bool { return c > 0 })},
+	NumericGreaterThanEqualsOperator:  {parse: parseNumeric, match: numericCompare(func(c int) bool { return c >= 0 })},
+	DateEqualsOperator:                {parse: parseDate, match: dateCompare(func(c int) bool { return c == 0 })},
+	DateNotEqualsOperator:             {parse: parseDate, match: dateCompare(func(c int) bool { return c == 0 }), negated: true},
+	DateLessThanOperator:              {parse: parseDate, match: dateCompare(func(c int) bool { return c < 0 })},
+	DateLessThanEqualsOperator:        {parse: parseDate, match: dateCompare(func(c int) bool { return c <= 0 })},
+	DateGreaterThanOperator:           {parse: parseDate, match: dateCompare(func(c int) bool { return c > 0 })},
+	DateGreaterThanEqualsOperator:     {parse: parseDate, match: dateCompare(func(c int) bool { return c >= 0 })},
+	BoolOperator:                      {parse: parseBool, match: boolEquals},
+	IpAddressOperator:                 {parse: parseIPNet, match: ipInNet},
+	NotIpAddressOperator:              {parse: parseIPNet, match: ipInNet, negated: true},
+}
+
+type ConditionKey string
+
+const (
+	SourceIpConditionKey        ConditionKey = "aws:SourceIp"
+	SecureTransportConditionKey ConditionKey = "aws:SecureTransport"
+	CurrentTimeConditionKey     ConditionKey = "aws:CurrentTime"
+	PrefixConditionKey          ConditionKey = "s3:prefix"
+	MaxKeysConditionKey         ConditionKey = "s3:max-keys"
+	AclConditionKey             ConditionKey = "s3:x-amz-acl"
+)
+
+// Condition keys are case insensitive, so they are stored lower cased
+var supportedConditionKeys = map[string]struct{}{
+	strings.ToLower(string(SourceIpConditionKey)):        {},
+	strings.ToLower(string(SecureTransportConditionKey)): {},
+	strings.ToLower(string(CurrentTimeConditionKey)):     {},
+	strings.ToLower(string(PrefixConditionKey)):          {},
+	strings.ToLower(string(MaxKeysConditionKey)):         {},
+	strings.ToLower(string(AclConditionKey)):             {},
+}
+
+// ConditionValues is the list of values of a condition key
+type ConditionValues []string
+
+// Override UnmarshalJSON method to decode a single value or a list of
+// string, boolean and numeric values
+func (cv *ConditionValues) UnmarshalJSON(data []byte) error {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	values, ok := v.([]any)
+	if !ok {
+		values = []any{v}
+	}
+	if len(values) == 0 {
+		return fmt.Errorf("condition values can't be empty")
+	}
+
+	*cv = make(ConditionValues, 0, len(values))
+	for _, value := range values {
+		switch val := value.(type) {
+		case string:
+			*cv = append(*cv, val)
+		case bool:
+			*cv = append(*cv, strconv.FormatBool(val))
+		case float64:
+			*cv = append(*cv, strconv.FormatFloat(val, 'f', -1, 64))
+		default:
+			return fmt.Errorf("invalid condition value: %v", value)
+		}
+	}
+
+	return nil
+}
+
+// Conditions maps the condition operators to the condition keys
+// and the values they are compared with
+type Conditions map[ConditionOperator]map[string]ConditionValues
+
+func (c *Conditions) UnmarshalJSON(data []byte) error {
+	var conds map[ConditionOperator]map[string]ConditionValues
+	if err := json.Unmarshal(data, &conds); err != nil {
+		return err
+	}
+
+	*c = make(Conditions, len(conds))
+	for op, keys := range conds {
+		if len(keys) == 0 {
+			return fmt.Errorf("condition keys can't be empty for %v", op)
+		}
+		(*c)[op] = make(map[string]ConditionValues, len(keys))
+		for key, values := range keys {
+			k := strings.ToLower(key)
+			if _, found := (*c)[op][k]; found {
+				return fmt.Errorf("duplicate condition key: %v", key)
+			}
+			(*c)[op][k] = values
+		}
+	}
+
+	return nil
+}
+
+func (c Conditions) Validate() error {
+	for op, keys := range c {
+		operator, ok := supportedConditionOperators[op]
+		if !ok {
+			return fmt.Errorf("invalid condition operator: %v", op)
+		}
+		for key, values := range keys {
+			if _, ok := supportedConditionKeys[key]; !ok {
+				return fmt.Errorf("unsupported condition key: %v", key)
+			}
+			for _, value := range values {
+				if err := operator.parse(value); err != nil {
+					return fmt.Errorf("invalid %v value for %v: %w", op, key, err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Evaluate returns true if the request satisfies all of the conditions.
+// A key missing from the request context fails the condition unless
+// the operator is negated.
+func (c Conditions) Evaluate(cc ConditionContext) bool {
+	for op, keys := range c {
+		operator, ok := supportedConditionOperators[op]
+		if !ok {
+			return false
+		}
+		for key, values := range keys {
+			if operator.matchAny(cc[key], values) == operator.negated {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func (co conditionOperator) matchAny(reqValues []string, policyValues ConditionValues) bool {
+	for _, rv := range reqValues {
+		for _, pv := range policyValues {
+			if co.match(rv, pv) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// ConditionContext holds the values of the condition keys of a request,
+// keyed by the lower cased condition key
+type ConditionContext map[string][]string
+
+// ConditionContextKey is the request context key of the ConditionContext
+const ConditionContextKey = "conditionContext"
+
+func (cc ConditionContext) Set(key ConditionKey, values ...string) {
+	cc[strings.ToLower(string(key))] = values
+}
+
+// getConditionContext returns the condition context stored with the
+// request context, or an empty context if there is none
+func getConditionContext(ctx context.Context) ConditionContext {
+	cc, ok := ctx.Value(ConditionContextKey).(ConditionContext)
+	if !ok {
+		return ConditionContext{}
+	}
+	return cc
+}
+
+func parseString(string) error { return nil }
+
+func stringEquals(a, b string) bool { return a == b }
+
+// stringLike matches s against pattern, where '*' matches any sequence
+// of characters and '?' matches any single character
+func stringLike(s, pattern string) bool {
+	var si, pi int
+	star, match := -1, 0
+	for si < len(s) {
+		switch {
+		case pi < len(pattern) && pattern[pi] == '*':
+			star, match = pi, si
+			pi++
+		case pi < len(pattern) && (pattern[pi] == '?' || pattern[pi] == s[si]):
+			si++
+			pi++
+		case star != -1:
+			pi = star + 1
+			match++
+			si = match
+		default:
+			return false
+		}
+	}
+	for pi < len(pattern) && pattern[pi] == '*' {
+		pi++
+	}
+
+	return pi == len(pattern)
+}
+
+func parseNumeric(value string) error {
+	_, err := strconv.ParseFloat(value, 64)
+	return err
+}
+
+func numericCompare(cmp func(int) bool) func(string, string) bool {
+	return func(reqValue, policyValue string) bool {
+		a, err := strconv.ParseFloat(reqValue, 64)
+		if err != nil {
+			return false
+		}
+		b, err := strconv.ParseFloat(policyValue, 64)
+		if err != nil {
+			return false
+		}
+		switch {
+		case a < b:
+			return cmp(-1)
+		case a > b:
+			return cmp(1)
+		default:
+			return cmp(0)
+		}
+	}
+}
+
+// parseTime accepts ISO 8601 dates and epoch seconds
+func parseTime(value string) (time.Time, error) {
+	if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(secs, 0), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04Z07:00", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid date: %v", value)
+}
+
+func parseDate(value string) error {
+	_, err := parseTime(value)
+	return err
+}
+
+func dateCompare(cmp func(int) bool) func(string, string) bool {
+	return func(reqValue, policyValue string) bool {
+		a, err := parseTime(reqValue)
+		if err != nil {
+			return false
+		}
+		b, err := parseTime(policyValue)
+		if err != nil {
+			return false
+		}
+		return cmp(a.Compare(b))
+	}
+}
+
+func parseBool(value string) error {
+	_, err := strconv.ParseBool(value)
+	return err
+}
+
+func boolEquals(reqValue, policyValue string) bool {
+	a, err := strconv.ParseBool(reqValue)
+	if err != nil {
+		return false
+	}
+	b, err := strconv.ParseBool(policyValue)
+	if err != nil {
+		return false
+	}
+	return a == b
+}
+
+// getIPNet parses a CIDR block, a single address is treated as
+// a full length prefix
+func getIPNet(value string) (*net.IPNet, error) {
+	if !strings.Contains(value, "/") {
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid ip address: %v", value)
+		}
+		bits := 8 * net.IPv6len
+		if ip.To4() != nil {
+			ip, bits = ip.To4(), 8*net.IPv4len
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+
+	_, ipnet, err := net.ParseCIDR(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ip address: %v", value)
+	}
+	return ipnet, nil
+}
+
+func parseIPNet(value string) error {
+	_, err := getIPNet(value)
+	return err
+}
+
+func ipInNet(reqValue, policyValue string) bool {
+	ip := net.ParseIP(reqValue)
+	if ip == nil {
+		return false
+	}
+	ipnet, err := getIPNet(policyValue)
+	if err != nil {
+		return false
+	}
+	return ipnet.Contains(ip)
+}
@@ -0,0 +1,158 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestConditionsEvaluate(t *testing.T) {
+	cc := ConditionContext{}
+	cc.Set(SourceIpConditionKey, "192.168.1.10")
+	cc.Set(SecureTransportConditionKey, "true")
+	cc.Set(CurrentTimeConditionKey, "2024-06-01T12:00:00Z")
+	cc.Set(PrefixConditionKey, "home/user1/")
+	cc.Set(MaxKeysConditionKey, "100")
+	cc.Set(AclConditionKey, "private")
+
+	tests := []struct {
+		name      string
+		condition string
+		matches   bool
+	}{
+		{"string-equals", `{"StringEquals": {"s3:x-amz-acl": "private"}}`, true},
+		{"string-equals-any-value", `{"StringEquals": {"s3:x-amz-acl": ["public-read", "private"]}}`, true},
+		{"string-equals-mismatch", `{"StringEquals": {"s3:x-amz-acl": "Private"}}`, false},
+		{"string-equals-key-case", `{"StringEquals": {"S3:X-Amz-Acl": "private"}}`, true},
+		{"string-not-equals", `{"StringNotEquals": {"s3:x-amz-acl": "public-read"}}`, true},
+		{"string-not-equals-mismatch", `{"StringNotEquals": {"s3:x-amz-acl": ["public-read", "private"]}}`, false},
+		{"string-equals-ignore-case", `{"StringEqualsIgnoreCase": {"s3:x-amz-acl": "PRIVATE"}}`, true},
+		{"string-not-equals-ignore-case", `{"StringNotEqualsIgnoreCase": {"s3:x-amz-acl": "PRIVATE"}}`, false},
+		{"string-like", `{"StringLike": {"s3:prefix": "home/*/"}}`, true},
+		{"string-like-single-char", `{"StringLike": {"s3:prefix": "home/user?/"}}`, true},
+		{"string-like-mismatch", `{"StringLike": {"s3:prefix": "public/*"}}`, false},
+		{"string-not-like", `{"StringNotLike": {"s3:prefix": "public/*"}}`, true},
+		{"numeric-equals", `{"NumericEquals": {"s3:max-keys": "100"}}`, true},
+		{"numeric-not-equals", `{"NumericNotEquals": {"s3:max-keys": 100}}`, false},
+		{"numeric-less-than", `{"NumericLessThan": {"s3:max-keys": 101}}`, true},
+		{"numeric-less-than-equal-value", `{"NumericLessThan": {"s3:max-keys": 100}}`, false},
+		{"numeric-less-than-equals", `{"NumericLessThanEquals": {"s3:max-keys": 100}}`, true},
+		{"numeric-greater-than", `{"NumericGreaterThan": {"s3:max-keys": 100}}`, false},
+		{"numeric-greater-than-equals", `{"NumericGreaterThanEquals": {"s3:max-keys": 100}}`, true},
+		{"date-equals", `{"DateEquals": {"aws:CurrentTime": "2024-06-01T12:00:00Z"}}`, true},
+		{"date-not-equals", `{"DateNotEquals": {"aws:CurrentTime": "2024-06-01T12:00:00Z"}}`, false},
+		{"date-less-than", `{"DateLessThan": {"aws:CurrentTime": "2025-01-01"}}`, true},
+		{"date-less-than-past", `{"DateLessThan": {"aws:CurrentTime": "2024-01-01T00:00Z"}}`, false},
+		{"date-less-than-equals", `{"DateLessThanEquals": {"aws:CurrentTime": "2024-06-01T12:00:00Z"}}`, true},
+		{"date-greater-than", `{"DateGreaterThan": {"aws:CurrentTime": "2024-01-01"}}`, true},
+		{"date-greater-than-epoch", `{"DateGreaterThan": {"aws:CurrentTime": "1735689600"}}`, false},
+		{"date-greater-than-equals", `{"DateGreaterThanEquals": {"aws:CurrentTime": "2024-06-01T12:00:00Z"}}`, true},
+		{"bool", `{"Bool": {"aws:SecureTransport": "true"}}`, true},
+		{"bool-value", `{"Bool": {"aws:SecureTransport": false}}`, false},
+		{"ip-address", `{"IpAddress": {"aws:SourceIp": "192.168.1.0/24"}}`, true},
+		{"ip-address-single", `{"IpAddress": {"aws:SourceIp": "192.168.1.10"}}`, true},
+		{"ip-address-mismatch", `{"IpAddress": {"aws:SourceIp": ["10.0.0.0/8", "192.168.2.0/24"]}}`, false},
+		{"not-ip-address", `{"NotIpAddress": {"aws:SourceIp": "10.0.0.0/8"}}`, true},
+		{"not-ip-address-mismatch", `{"NotIpAddress": {"aws:SourceIp": "192.168.0.0/16"}}`, false},
+		{"all-conditions", `{"Bool": {"aws:SecureTransport": true}, "IpAddress": {"aws:SourceIp": "192.168.1.0/24"}}`, true},
+		{"one-condition-fails", `{"Bool": {"aws:SecureTransport": true}, "IpAddress": {"aws:SourceIp": "10.0.0.0/8"}}`, false},
+		{"missing-key", `{"StringEquals": {"s3:prefix": "home/user1/", "s3:delimiter": "/"}}`, false},
+		{"missing-key-negated", `{"StringNotEquals": {"s3:delimiter": "/"}}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c Conditions
+			if err := json.Unmarshal([]byte(tt.condition), &c); err != nil {
+				t.Fatal(err)
+			}
+			if matches := c.Evaluate(cc); matches != tt.matches {
+				t.Errorf("Evaluate() = %v, want %v", matches, tt.matches)
+			}
+		})
+	}
+}
+
+func TestConditionsValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition string
+		valid     bool
+	}{
+		{"valid", `{"IpAddress": {"aws:SourceIp": ["10.0.0.0/8", "::1"]}}`, true},
+		{"invalid-operator", `{"StringMatches": {"s3:prefix": "home/"}}`, false},
+		{"unsupported-key", `{"StringEquals": {"s3:delimiter": "/"}}`, false},
+		{"invalid-ip", `{"IpAddress": {"aws:SourceIp": "10.0.0.0/33"}}`, false},
+		{"invalid-bool", `{"Bool": {"aws:SecureTransport": "yes"}}`, false},
+		{"invalid-date", `{"DateLessThan": {"aws:CurrentTime": "tomorrow"}}`, false},
+		{"invalid-numeric", `{"NumericLessThan": {"s3:max-keys": "ten"}}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c Conditions
+			if err := json.Unmarshal([]byte(tt.condition), &c); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.Validate(); (err == nil) != tt.valid {
+				t.Errorf("Validate() error = %v, valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestVerifyAccessConditions(t *testing.T) {
+	// the bucket is restricted to the user network, and to TLS
+	policy := `{"Statement": [
+		{"Effect": "Allow", "Principal": "user1", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/*",
+			"Condition": {"IpAddress": {"aws:SourceIp": "10.0.0.0/8"}}},
+		{"Effect": "Deny", "Principal": "*", "Action": "s3:*", "Resource": ["arn:aws:s3:::bucket", "arn:aws:s3:::bucket/*"],
+			"Condition": {"Bool": {"aws:SecureTransport": "false"}}}
+	]}`
+	acl := ACL{Owner: "owner", Grantees: []Grantee{{Access: "user1", Permission: types.PermissionRead}}}
+
+	tests := []struct {
+		name    string
+		ip      string
+		secure  string
+		allowed bool
+	}{
+		{"allowed", "10.1.2.3", "true", true},
+		{"outside-network", "192.168.1.10", "true", false},
+		{"insecure-transport", "10.1.2.3", "false", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cc := ConditionContext{}
+			cc.Set(SourceIpConditionKey, tt.ip)
+			cc.Set(SecureTransportConditionKey, tt.secure)
+			ctx := context.WithValue(context.Background(), ConditionContextKey, cc)
+
+			err := VerifyAccess(ctx, policyBackend{policy: policy}, AccessOptions{
+				Acl:           acl,
+				AclPermission: types.PermissionRead,
+				Acc:           Account{Access: "user1", Role: RoleUser},
+				Bucket:        "bucket",
+				Object:        "obj",
+				Action:        GetObjectAction,
+			})
+			if (err == nil) != tt.allowed {
+				t.Errorf("VerifyAccess() error = %v, allowed %v", err, tt.allowed)
+			}
+		})
+	}
+}
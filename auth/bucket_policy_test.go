@@ -0,0 +1,105 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package auth
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestBucketPolicyIsAllowed(t *testing.T) {
+	const (
+		allowAll     = `{"Effect": "Allow", "Principal": "*", "Action": "s3:*", "Resource": ["arn:aws:s3:::bucket", "arn:aws:s3:::bucket/*"]}`
+		allowGet     = `{"Effect": "Allow", "Principal": "user1", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/*"}`
+		denyUser2    = `{"Effect": "Deny", "Principal": "user2", "Action": "s3:*", "Resource": ["arn:aws:s3:::bucket", "arn:aws:s3:::bucket/*"]}`
+		denyDelete   = `{"Effect": "Deny", "Principal": "*", "Action": "s3:DeleteObject", "Resource": "arn:aws:s3:::bucket/*"}`
+		denyNotUser1 = `{"Effect": "Deny", "NotPrincipal": "user1", "Action": "s3:*", "Resource": "arn:aws:s3:::bucket/*"}`
+		allowNotPut  = `{"Effect": "Allow", "Principal": "*", "NotAction": ["s3:PutObject", "s3:DeleteObject"], "Resource": "arn:aws:s3:::bucket/*"}`
+		allowNotPriv = `{"Effect": "Allow", "Principal": "*", "Action": "s3:GetObject", "NotResource": "arn:aws:s3:::bucket/private/*"}`
+		denyNotPub   = `{"Effect": "Deny", "Principal": "*", "Action": "s3:GetObject", "NotResource": "arn:aws:s3:::bucket/public/*"}`
+	)
+
+	tests := []struct {
+		name       string
+		statements []string
+		principal  string
+		action     Action
+		resource   string
+		allowed    bool
+	}{
+		{"implicit-deny", []string{allowGet}, "user2", GetObjectAction, "bucket/obj", false},
+		{"allow", []string{allowGet}, "user1", GetObjectAction, "bucket/obj", true},
+		{"deny-overrides-allow", []string{allowAll, denyUser2}, "user2", GetObjectAction, "bucket/obj", false},
+		{"deny-overrides-later-allow", []string{denyUser2, allowAll}, "user2", ListBucketAction, "bucket", false},
+		{"deny-other-principal", []string{allowAll, denyUser2}, "user1", GetObjectAction, "bucket/obj", true},
+		{"deny-other-action", []string{allowAll, denyDelete}, "user1", PutObjectAction, "bucket/obj", true},
+		{"deny-action", []string{allowAll, denyDelete}, "user1", DeleteObjectAction, "bucket/obj", false},
+		{"deny-only", []string{denyDelete}, "user1", GetObjectAction, "bucket/obj", false},
+		{"not-principal-excluded", []string{allowAll, denyNotUser1}, "user1", GetObjectAction, "bucket/obj", true},
+		{"not-principal-other", []string{allowAll, denyNotUser1}, "user2", GetObjectAction, "bucket/obj", false},
+		{"not-principal-other-resource", []string{allowAll, denyNotUser1}, "user2", ListBucketAction, "bucket", true},
+		{"not-action-allowed", []string{allowNotPut}, "user1", GetObjectAction, "bucket/obj", true},
+		{"not-action-excluded", []string{allowNotPut}, "user1", PutObjectAction, "bucket/obj", false},
+		{"not-resource-allowed", []string{allowNotPriv}, "user1", GetObjectAction, "bucket/obj", true},
+		{"not-resource-excluded", []string{allowNotPriv}, "user1", GetObjectAction, "bucket/private/obj", false},
+		{"deny-not-resource-allowed", []string{allowAll, denyNotPub}, "user1", GetObjectAction, "bucket/public/obj", true},
+		{"deny-not-resource-denied", []string{allowAll, denyNotPub}, "user1", GetObjectAction, "bucket/obj", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bp BucketPolicy
+			err := json.Unmarshal([]byte(`{"Statement": [`+strings.Join(tt.statements, ",")+`]}`), &bp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if allowed := bp.isAllowed(tt.principal, tt.action, tt.resource, ConditionContext{}); allowed != tt.allowed {
+				t.Errorf("isAllowed() = %v, want %v", allowed, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestBucketPolicyItemValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		statement string
+		valid     bool
+	}{
+		{"valid", `{"Effect": "Allow", "Principal": "*", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/*"}`, true},
+		{"not-principal", `{"Effect": "Deny", "NotPrincipal": "*", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/*"}`, true},
+		{"not-action", `{"Effect": "Allow", "Principal": "*", "NotAction": "s3:PutObject", "Resource": "arn:aws:s3:::bucket/*"}`, true},
+		{"not-resource", `{"Effect": "Allow", "Principal": "*", "Action": "s3:GetObject", "NotResource": "arn:aws:s3:::bucket/private/*"}`, true},
+		{"principal-and-not-principal", `{"Effect": "Allow", "Principal": "*", "NotPrincipal": "*", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/*"}`, false},
+		{"action-and-not-action", `{"Effect": "Allow", "Principal": "*", "Action": "s3:GetObject", "NotAction": "s3:PutObject", "Resource": "arn:aws:s3:::bucket/*"}`, false},
+		{"resource-and-not-resource", `{"Effect": "Allow", "Principal": "*", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/*", "NotResource": "arn:aws:s3:::bucket/private/*"}`, false},
+		{"missing-principal", `{"Effect": "Allow", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/*"}`, false},
+		{"missing-action", `{"Effect": "Allow", "Principal": "*", "Resource": "arn:aws:s3:::bucket/*"}`, false},
+		{"missing-resource", `{"Effect": "Allow", "Principal": "*", "Action": "s3:GetObject"}`, false},
+		{"not-resource-other-bucket", `{"Effect": "Allow", "Principal": "*", "Action": "s3:GetObject", "NotResource": "arn:aws:s3:::other/*"}`, false},
+		{"object-action-on-bucket", `{"Effect": "Allow", "Principal": "*", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket"}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bpi BucketPolicyItem
+			if err := json.Unmarshal([]byte(tt.statement), &bpi); err != nil {
+				t.Fatal(err)
+			}
+			if err := bpi.Validate("bucket", IAMServiceSingle{}); (err == nil) != tt.valid {
+				t.Errorf("Validate() error = %v, valid %v", err, tt.valid)
+			}
+		})
+	}
+}
@@ -26,6 +26,9 @@ const (
 	RoleUser     Role = "user"
 	RoleAdmin    Role = "admin"
 	RoleUserPlus Role = "userplus"
+	// RoleAnonymous is the role of unauthenticated requests, it can't
+	// be assigned to gateway accounts
+	RoleAnonymous Role = "anonymous"
 )
 
 // Account is a gateway IAM account
@@ -36,8 +39,20 @@ type Account struct {
 	UserID    int    `json:"userID"`
 	GroupID   int    `json:"groupID"`
 	ProjectID int    `json:"projectID"`
+	// Policy is the optional identity policy document of the account
+	Policy string `json:"policy,omitempty"`
+
+	// SessionPolicy limits the permissions of session credentials, it
+	// is only set for the accounts of session requests
+	SessionPolicy *SessionPolicy `json:"-"`
+	// Session is set for the accounts of session credentials
+	Session bool `json:"-"`
 }
 
+// AnonymousAccount is the account of unsigned requests, these are only
+// authorized by public bucket ACL grants and bucket policies
+var AnonymousAccount = Account{Role: RoleAnonymous}
+
 // IAMService is the interface for all IAM service implementations
 //
 //go:generate moq -out ../s3api/controllers/iam_moq_test.go -pkg controllers . IAMService
@@ -46,6 +61,9 @@ type IAMService interface {
 	GetUserAccount(access string) (Account, error)
 	DeleteUserAccount(access string) error
 	ListUserAccounts() ([]Account, error)
+	// PutUserPolicy sets the identity policy of the account, an empty
+	// policy removes it
+	PutUserPolicy(access, policy string) error
 	Shutdown() error
 }
 
@@ -61,6 +79,7 @@ type Opts struct {
 	LDAPAccessAtr      string
 	LDAPSecretAtr      string
 	LDAPRoleAtr        string
+	LDAPPolicyAtr      string
 	S3Access           string
 	S3Secret           string
 	S3Region           string
@@ -84,7 +103,7 @@ func New(o *Opts) (IAMService, error) {
 	case o.LDAPServerURL != "":
 		svc, err = NewLDAPService(o.LDAPServerURL, o.LDAPBindDN, o.LDAPPassword,
 			o.LDAPQueryBase, o.LDAPAccessAtr, o.LDAPSecretAtr, o.LDAPRoleAtr,
-			o.LDAPObjClasses)
+			o.LDAPPolicyAtr, o.LDAPObjClasses)
 		fmt.Printf("initializing LDAP IAM with %q\n", o.LDAPServerURL)
 	case o.S3Endpoint != "":
 		svc, err = NewS3(o.S3Access, o.S3Secret, o.S3Region, o.S3Bucket,
@@ -19,6 +19,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/versity/versitygw/metrics"
 )
 
 // IAMCache is an in memory cache of the IAM accounts
@@ -127,11 +129,11 @@ func (c *IAMCache) CreateAccount(account Account) error {
 	// we need a copy of account to be able to store beyond the
 	// lifetime of the request, otherwise Fiber will reuse and corrupt
 	// these entries
-	acct := Account{
-		Access: strings.Clone(account.Access),
-		Secret: strings.Clone(account.Secret),
-		Role:   Role(strings.Clone(string(account.Role))),
-	}
+	acct := account
+	acct.Access = strings.Clone(account.Access)
+	acct.Secret = strings.Clone(account.Secret)
+	acct.Role = Role(strings.Clone(string(account.Role)))
+	acct.Policy = strings.Clone(account.Policy)
 
 	c.iamcache.set(acct.Access, acct)
 	return nil
@@ -143,8 +145,10 @@ func (c *IAMCache) CreateAccount(account Account) error {
 func (c *IAMCache) GetUserAccount(access string) (Account, error) {
 	acct, found := c.iamcache.get(access)
 	if found {
+		metrics.IAMCacheHits.Inc()
 		return acct, nil
 	}
+	metrics.IAMCacheMisses.Inc()
 
 	a, err := c.service.GetUserAccount(access)
 	if err != nil {
@@ -166,6 +170,18 @@ func (c *IAMCache) DeleteUserAccount(access string) error {
 	return nil
 }
 
+// PutUserPolicy updates the account policy in IAM service and
+// invalidates the account cache entry
+func (c *IAMCache) PutUserPolicy(access, policy string) error {
+	err := c.service.PutUserPolicy(access, policy)
+	if err != nil {
+		return err
+	}
+
+	c.iamcache.Delete(access)
+	return nil
+}
+
 // ListUserAccounts is a passthrough to the underlying service and
 // does not make use of the cache
 func (c *IAMCache) ListUserAccounts() ([]Account, error) {
@@ -0,0 +1,58 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+// createOnlyIAM accepts the created accounts but can't return them,
+// the accounts are only returned from the cache
+type createOnlyIAM struct {
+	IAMServiceSingle
+}
+
+func (createOnlyIAM) CreateAccount(Account) error { return nil }
+
+func TestIAMCacheCreateAccount(t *testing.T) {
+	c := NewCache(createOnlyIAM{}, time.Minute, time.Minute)
+	defer c.Shutdown()
+
+	err := c.CreateAccount(Account{
+		Access:    "user",
+		Secret:    "secret",
+		Role:      RoleUser,
+		UserID:    1001,
+		GroupID:   1002,
+		ProjectID: 1003,
+		Policy:    `{"Statement": []}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acct, err := c.GetUserAccount("user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acct.UserID != 1001 || acct.GroupID != 1002 || acct.ProjectID != 1003 {
+		t.Fatalf("expected the account ids 1001, 1002, 1003, got %v, %v, %v",
+			acct.UserID, acct.GroupID, acct.ProjectID)
+	}
+	if acct.Secret != "secret" || acct.Role != RoleUser || acct.Policy == "" {
+		t.Fatalf("unexpected cached account %+v", acct)
+	}
+}
@@ -119,6 +119,31 @@ func (s *IAMServiceInternal) DeleteUserAccount(access string) error {
 	})
 }
 
+// PutUserPolicy sets the identity policy of the user account. Returns
+// ErrNoSuchUser if the account does not exist.
+func (s *IAMServiceInternal) PutUserPolicy(access, policy string) error {
+	return s.storeIAM(func(data []byte) ([]byte, error) {
+		conf, err := parseIAM(data)
+		if err != nil {
+			return nil, fmt.Errorf("get iam data: %w", err)
+		}
+
+		acct, ok := conf.AccessAccounts[access]
+		if !ok {
+			return nil, ErrNoSuchUser
+		}
+		acct.Policy = policy
+		conf.AccessAccounts[access] = acct
+
+		b, err := json.Marshal(conf)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize iam: %w", err)
+		}
+
+		return b, nil
+	})
+}
+
 // ListUserAccounts lists all the user accounts stored.
 func (s *IAMServiceInternal) ListUserAccounts() ([]Account, error) {
 	conf, err := s.getIAM()
@@ -141,6 +166,7 @@ func (s *IAMServiceInternal) ListUserAccounts() ([]Account, error) {
 			UserID:    conf.AccessAccounts[k].UserID,
 			GroupID:   conf.AccessAccounts[k].GroupID,
 			ProjectID: conf.AccessAccounts[k].ProjectID,
+			Policy:    conf.AccessAccounts[k].Policy,
 		})
 	}
 
@@ -14,11 +14,13 @@ type LdapIAMService struct {
 	accessAtr  string
 	secretAtr  string
 	roleAtr    string
+	// policyAtr is the optional identity policy attribute
+	policyAtr string
 }
 
 var _ IAMService = &LdapIAMService{}
 
-func NewLDAPService(url, bindDN, pass, queryBase, accAtr, secAtr, roleAtr, objClasses string) (IAMService, error) {
+func NewLDAPService(url, bindDN, pass, queryBase, accAtr, secAtr, roleAtr, policyAtr, objClasses string) (IAMService, error) {
 	if url == "" || bindDN == "" || pass == "" || queryBase == "" || accAtr == "" || secAtr == "" || roleAtr == "" || objClasses == "" {
 		return nil, fmt.Errorf("required parameters list not fully provided")
 	}
@@ -38,6 +40,7 @@ func NewLDAPService(url, bindDN, pass, queryBase, accAtr, secAtr, roleAtr, objCl
 		accessAtr:  accAtr,
 		secretAtr:  secAtr,
 		roleAtr:    roleAtr,
+		policyAtr:  policyAtr,
 	}, nil
 }
 
@@ -47,6 +50,12 @@ func (ld *LdapIAMService) CreateAccount(account Account) error {
 	userEntry.Attribute(ld.accessAtr, []string{account.Access})
 	userEntry.Attribute(ld.secretAtr, []string{account.Secret})
 	userEntry.Attribute(ld.roleAtr, []string{string(account.Role)})
+	if account.Policy != "" {
+		if ld.policyAtr == "" {
+			return fmt.Errorf("ldap policy attribute is not configured")
+		}
+		userEntry.Attribute(ld.policyAtr, []string{account.Policy})
+	}
 
 	err := ld.conn.Add(userEntry)
 	if err != nil {
@@ -65,7 +74,7 @@ func (ld *LdapIAMService) GetUserAccount(access string) (Account, error) {
 		0,
 		false,
 		fmt.Sprintf("(%v=%v)", ld.accessAtr, access),
-		[]string{ld.accessAtr, ld.secretAtr, ld.roleAtr},
+		ld.attributes(),
 		nil,
 	)
 
@@ -79,6 +88,7 @@ func (ld *LdapIAMService) GetUserAccount(access string) (Account, error) {
 		Access: entry.GetAttributeValue(ld.accessAtr),
 		Secret: entry.GetAttributeValue(ld.secretAtr),
 		Role:   Role(entry.GetAttributeValue(ld.roleAtr)),
+		Policy: ld.policy(entry),
 	}, nil
 }
 
@@ -106,7 +116,7 @@ func (ld *LdapIAMService) ListUserAccounts() ([]Account, error) {
 		0,
 		false,
 		fmt.Sprintf("(&%v)", searchFilter),
-		[]string{ld.accessAtr, ld.secretAtr, ld.roleAtr},
+		ld.attributes(),
 		nil,
 	)
 
@@ -121,12 +131,56 @@ func (ld *LdapIAMService) ListUserAccounts() ([]Account, error) {
 			Access: el.GetAttributeValue(ld.accessAtr),
 			Secret: el.GetAttributeValue(ld.secretAtr),
 			Role:   Role(el.GetAttributeValue(ld.roleAtr)),
+			Policy: ld.policy(el),
 		})
 	}
 
 	return result, nil
 }
 
+func (ld *LdapIAMService) PutUserPolicy(access, policy string) error {
+	if ld.policyAtr == "" {
+		return fmt.Errorf("ldap policy attribute is not configured")
+	}
+
+	modReq := ldap.NewModifyRequest(fmt.Sprintf("%v=%v, %v", ld.accessAtr, access, ld.queryBase), nil)
+	if policy == "" {
+		modReq.Delete(ld.policyAtr, nil)
+	} else {
+		modReq.Replace(ld.policyAtr, []string{policy})
+	}
+
+	err := ld.conn.Modify(modReq)
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+		return ErrNoSuchUser
+	}
+	// deleting the missing policy of an account
+	if policy == "" && ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchAttribute) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error modifying an entry: %w", err)
+	}
+
+	return nil
+}
+
+// attributes returns the account attributes of the search requests
+func (ld *LdapIAMService) attributes() []string {
+	attrs := []string{ld.accessAtr, ld.secretAtr, ld.roleAtr}
+	if ld.policyAtr != "" {
+		attrs = append(attrs, ld.policyAtr)
+	}
+	return attrs
+}
+
+func (ld *LdapIAMService) policy(entry *ldap.Entry) string {
+	if ld.policyAtr == "" {
+		return ""
+	}
+	return entry.GetAttributeValue(ld.policyAtr)
+}
+
 // Shutdown graceful termination of service
 func (ld *LdapIAMService) Shutdown() error {
 	return ld.conn.Close()
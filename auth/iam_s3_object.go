@@ -133,6 +133,22 @@ func (s *IAMServiceS3) DeleteUserAccount(access string) error {
 	return s.storeAccts(conf)
 }
 
+func (s *IAMServiceS3) PutUserPolicy(access, policy string) error {
+	conf, err := s.getAccounts()
+	if err != nil {
+		return err
+	}
+
+	acct, ok := conf.AccessAccounts[access]
+	if !ok {
+		return ErrNoSuchUser
+	}
+	acct.Policy = policy
+	conf.AccessAccounts[access] = acct
+
+	return s.storeAccts(conf)
+}
+
 func (s *IAMServiceS3) ListUserAccounts() ([]Account, error) {
 	conf, err := s.getAccounts()
 	if err != nil {
@@ -154,6 +170,7 @@ func (s *IAMServiceS3) ListUserAccounts() ([]Account, error) {
 			UserID:    conf.AccessAccounts[k].UserID,
 			GroupID:   conf.AccessAccounts[k].GroupID,
 			ProjectID: conf.AccessAccounts[k].ProjectID,
+			Policy:    conf.AccessAccounts[k].Policy,
 		})
 	}
 
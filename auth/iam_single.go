@@ -45,6 +45,11 @@ func (IAMServiceSingle) ListUserAccounts() ([]Account, error) {
 	return []Account{}, nil
 }
 
+// PutUserPolicy no accounts in single tenant mode
+func (IAMServiceSingle) PutUserPolicy(access, policy string) error {
+	return ErrNotSupported
+}
+
 // Shutdown graceful termination of service
 func (IAMServiceSingle) Shutdown() error {
 	return nil
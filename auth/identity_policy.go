@@ -0,0 +1,111 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// IdentityPolicy is the policy attached to a gateway account. It allows
+// or denies the account actions on the resources of any bucket, with the
+// Action and Resource grammar of the bucket policies.
+type IdentityPolicy struct {
+	Statement []BucketPolicyItem `json:"Statement"`
+}
+
+// ParseIdentityPolicy parses and validates the identity policy document
+func ParseIdentityPolicy(policy string) (*IdentityPolicy, error) {
+	statements, err := parseInlinePolicy(policy, "identity")
+	if err != nil {
+		return nil, err
+	}
+
+	return &IdentityPolicy{Statement: statements}, nil
+}
+
+// ValidateIdentityPolicy validates the identity policy document of the
+// admin requests
+func ValidateIdentityPolicy(policy string) error {
+	if _, err := ParseIdentityPolicy(policy); err != nil {
+		return fmt.Errorf("invalid identity policy: %w", err)
+	}
+	return nil
+}
+
+// parseInlinePolicy parses the statements of the policies attached to an
+// account or a session. These have no Principal, the statements apply to
+// the account they are attached to.
+func parseInlinePolicy(policy, kind string) ([]BucketPolicyItem, error) {
+	var doc struct {
+		Statement []BucketPolicyItem `json:"Statement"`
+	}
+	if err := json.Unmarshal([]byte(policy), &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Statement) == 0 {
+		return nil, fmt.Errorf("missing Statement")
+	}
+
+	for i, statement := range doc.Statement {
+		if err := statement.Effect.Validate(); err != nil {
+			return nil, err
+		}
+		if statement.Principals != nil || statement.NotPrincipals != nil {
+			return nil, fmt.Errorf("%v policies do not support Principal", kind)
+		}
+
+		switch {
+		case statement.Actions != nil && statement.NotActions != nil:
+			return nil, fmt.Errorf("only one of Action and NotAction can be specified")
+		case statement.Actions == nil && statement.NotActions == nil:
+			return nil, fmt.Errorf("missing Action or NotAction")
+		}
+
+		switch {
+		case statement.Resources != nil && statement.NotResources != nil:
+			return nil, fmt.Errorf("only one of Resource and NotResource can be specified")
+		case statement.Resources == nil && statement.NotResources == nil:
+			return nil, fmt.Errorf("missing Resource or NotResource")
+		}
+
+		if err := statement.Conditions.Validate(); err != nil {
+			return nil, err
+		}
+
+		// the statements apply to the account principal
+		doc.Statement[i].Principals = Principals{"*": struct{}{}}
+	}
+
+	return doc.Statement, nil
+}
+
+// evaluateIdentityPolicy returns whether the identity policy of the
+// account allows or explicitly denies the request
+func evaluateIdentityPolicy(ctx context.Context, opts AccessOptions) (allowed, denied bool, err error) {
+	if opts.Acc.Policy == "" {
+		return false, false, nil
+	}
+
+	policy, err := ParseIdentityPolicy(opts.Acc.Policy)
+	if err != nil {
+		return false, false, fmt.Errorf("parse identity policy of %v: %w", opts.Acc.Access, err)
+	}
+
+	bp := BucketPolicy(*policy)
+	allowed, denied = bp.evaluate(opts.Acc.Access, opts.Action, policyResource(opts.Bucket, opts.Object), getConditionContext(ctx))
+	return allowed, denied, nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestParseIdentityPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy string
+		valid  bool
+	}{
+		{"valid", `{"Statement": [{"Effect": "Allow", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::*"}]}`, true},
+		{"not-action", `{"Statement": [{"Effect": "Deny", "NotAction": "s3:GetObject", "NotResource": "arn:aws:s3:::data/*"}]}`, true},
+		{"principal", `{"Statement": [{"Effect": "Allow", "Principal": "*", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::*"}]}`, false},
+		{"missing-statement", `{"Statement": []}`, false},
+		{"missing-action", `{"Statement": [{"Effect": "Allow", "Resource": "arn:aws:s3:::*"}]}`, false},
+		{"missing-resource", `{"Statement": [{"Effect": "Allow", "Action": "s3:GetObject"}]}`, false},
+		{"invalid-effect", `{"Statement": [{"Effect": "Permit", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::*"}]}`, false},
+		{"invalid-condition", `{"Statement": [{"Effect": "Allow", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::*", "Condition": {"Bool": {"aws:SecureTransport": "yes"}}}]}`, false},
+		{"malformed", `{"Statement": `, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseIdentityPolicy(tt.policy)
+			if (err == nil) != tt.valid {
+				t.Errorf("ParseIdentityPolicy() error = %v, valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestVerifyAccessIdentityPolicy(t *testing.T) {
+	// read-only access to the data bucket, apart from its secret prefix
+	readData := `{"Statement": [
+		{"Effect": "Allow", "Action": ["s3:GetObject", "s3:ListBucket"], "Resource": ["arn:aws:s3:::data", "arn:aws:s3:::data/*"]},
+		{"Effect": "Deny", "Action": "s3:*", "Resource": "arn:aws:s3:::data/secret/*"}
+	]}`
+	denyDelete := `{"Statement": [{"Effect": "Deny", "Action": "s3:DeleteObject", "Resource": "arn:aws:s3:::*"}]}`
+	bucketDeny := `{"Statement": [{"Effect": "Deny", "Principal": "user1", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::data/private/*"}]}`
+
+	tests := []struct {
+		name    string
+		acc     Account
+		policy  string
+		bucket  string
+		object  string
+		action  Action
+		allowed bool
+	}{
+		{"no-policy", Account{Access: "user1", Role: RoleUser}, "", "data", "obj", GetObjectAction, false},
+		{"allow-get-object", Account{Access: "user1", Role: RoleUser, Policy: readData}, "", "data", "obj", GetObjectAction, true},
+		{"allow-list-bucket", Account{Access: "user1", Role: RoleUser, Policy: readData}, "", "data", "", ListBucketAction, true},
+		{"not-allowed-action", Account{Access: "user1", Role: RoleUser, Policy: readData}, "", "data", "obj", PutObjectAction, false},
+		{"not-allowed-bucket", Account{Access: "user1", Role: RoleUser, Policy: readData}, "", "other", "obj", GetObjectAction, false},
+		{"identity-deny", Account{Access: "user1", Role: RoleUser, Policy: readData}, "", "data", "secret/obj", GetObjectAction, false},
+		{"bucket-policy-deny", Account{Access: "user1", Role: RoleUser, Policy: readData}, bucketDeny, "data", "private/obj", GetObjectAction, false},
+		{"bucket-policy-other-object", Account{Access: "user1", Role: RoleUser, Policy: readData}, bucketDeny, "data", "obj", GetObjectAction, true},
+		{"admin", Account{Access: "admin1", Role: RoleAdmin}, "", "data", "obj", DeleteObjectAction, true},
+		{"admin-identity-deny", Account{Access: "admin1", Role: RoleAdmin, Policy: denyDelete}, "", "data", "obj", DeleteObjectAction, false},
+		{"owner-identity-deny", Account{Access: "owner", Role: RoleUser, Policy: denyDelete}, "", "data", "obj", DeleteObjectAction, false},
+		{"owner-identity-deny-other-action", Account{Access: "owner", Role: RoleUser, Policy: denyDelete}, "", "data", "obj", PutObjectAction, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyAccess(context.Background(), policyBackend{policy: tt.policy}, AccessOptions{
+				Acl:           ACL{Owner: "owner"},
+				AclPermission: types.PermissionRead,
+				Acc:           tt.acc,
+				Bucket:        tt.bucket,
+				Object:        tt.object,
+				Action:        tt.action,
+			})
+			if (err == nil) != tt.allowed {
+				t.Errorf("VerifyAccess() error = %v, allowed %v", err, tt.allowed)
+			}
+		})
+	}
+}
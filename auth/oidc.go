@@ -0,0 +1,382 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// jwksRefreshInterval limits the refreshes of the remote key set
+	// on tokens signed with unknown keys
+	jwksRefreshInterval = time.Minute
+	jwksFetchTimeout    = 10 * time.Second
+	oidcLeeway          = 30 * time.Second
+)
+
+var (
+	ErrOIDCTokenExpired = errors.New("web identity token expired")
+)
+
+// OIDCOpts configures the trusted OpenID Connect identity provider
+type OIDCOpts struct {
+	// Issuer is the expected "iss" claim of the tokens
+	Issuer string
+	// JWKS is the path or http(s) url of the provider JSON Web Key Set
+	JWKS string
+	// Audiences are the accepted "aud" claims of the tokens, these are
+	// the client ids registered with the provider. Without this any
+	// token of the provider issued for another client would be
+	// accepted, so at least one is required.
+	Audiences []string
+	// AccountClaim is the claim mapped to the gateway account access
+	// key. This is required because the claims that are unique and can
+	// not be chosen by the users differ between providers.
+	AccountClaim string
+	// RoleClaim is the optional claim mapped to the gateway role of the
+	// identities without a gateway account
+	RoleClaim string
+}
+
+// WebIdentity is the identity of a verified OpenID Connect token
+type WebIdentity struct {
+	Subject  string
+	Audience string
+	// Account is the gateway account access key of the identity
+	Account string
+	// Role is the gateway role granted by the role claim
+	Role Role
+}
+
+// OIDC verifies the web identity tokens of a trusted OpenID Connect
+// identity provider
+type OIDC struct {
+	opts OIDCOpts
+
+	mu        sync.RWMutex
+	keys      map[string]crypto.PublicKey
+	lastFetch time.Time
+}
+
+// NewOIDC returns the verifier of the configured identity provider, the
+// key set is loaded on startup
+func NewOIDC(opts OIDCOpts) (*OIDC, error) {
+	if opts.Issuer == "" {
+		return nil, fmt.Errorf("oidc issuer is required")
+	}
+	if opts.JWKS == "" {
+		return nil, fmt.Errorf("oidc jwks is required")
+	}
+	if len(opts.Audiences) == 0 {
+		return nil, fmt.Errorf("oidc audience is required")
+	}
+	if opts.AccountClaim == "" {
+		return nil, fmt.Errorf("oidc account claim is required")
+	}
+
+	o := &OIDC{opts: opts}
+	if err := o.loadKeys(); err != nil {
+		return nil, err
+	}
+
+	return o, nil
+}
+
+// Issuer returns the trusted identity provider issuer
+func (o *OIDC) Issuer() string {
+	return o.opts.Issuer
+}
+
+// VerifyToken verifies the signature and the registered claims of the
+// web identity token, and maps its claims to the gateway identity
+func (o *OIDC) VerifyToken(token string) (WebIdentity, error) {
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(o.opts.Issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(oidcLeeway),
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, o.getKey, parserOpts...)
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return WebIdentity{}, ErrOIDCTokenExpired
+	}
+	if err != nil {
+		return WebIdentity{}, err
+	}
+
+	audience, err := o.matchAudience(claims)
+	if err != nil {
+		return WebIdentity{}, err
+	}
+
+	account, ok := claims[o.opts.AccountClaim].(string)
+	if !ok || account == "" {
+		return WebIdentity{}, fmt.Errorf("missing account claim %q", o.opts.AccountClaim)
+	}
+
+	subject, _ := claims.GetSubject()
+	identity := WebIdentity{
+		Subject:  subject,
+		Audience: audience,
+		Account:  account,
+		Role:     RoleUser,
+	}
+	if o.opts.RoleClaim != "" {
+		identity.Role = mapRoleClaim(claims[o.opts.RoleClaim])
+	}
+
+	return identity, nil
+}
+
+// matchAudience returns the first audience of the token that is one of
+// the accepted audiences
+func (o *OIDC) matchAudience(claims jwt.MapClaims) (string, error) {
+	aud, err := claims.GetAudience()
+	if err != nil {
+		return "", err
+	}
+	for _, a := range aud {
+		if slices.Contains(o.opts.Audiences, a) {
+			return a, nil
+		}
+	}
+	return "", jwt.ErrTokenInvalidAudience
+}
+
+// mapRoleClaim returns the highest gateway role named by the role claim,
+// which is either a string or a list of strings such as a groups claim
+func mapRoleClaim(claim any) Role {
+	var values []string
+	switch v := claim.(type) {
+	case string:
+		values = []string{v}
+	case []any:
+		for _, val := range v {
+			if s, ok := val.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+
+	role := RoleUser
+	for _, v := range values {
+		switch Role(v) {
+		case RoleAdmin:
+			return RoleAdmin
+		case RoleUserPlus:
+			role = RoleUserPlus
+		}
+	}
+
+	return role
+}
+
+func (o *OIDC) getKey(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	key, ok := o.lookupKey(kid)
+	if ok {
+		return key, nil
+	}
+
+	// the provider may have rotated its keys
+	if o.isRemote() && o.refreshDue() {
+		if err := o.loadKeys(); err != nil {
+			return nil, err
+		}
+		if key, ok := o.lookupKey(kid); ok {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no verification key found for kid %q", kid)
+}
+
+func (o *OIDC) lookupKey(kid string) (crypto.PublicKey, bool) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	if kid == "" && len(o.keys) == 1 {
+		for _, key := range o.keys {
+			return key, true
+		}
+	}
+	key, ok := o.keys[kid]
+	return key, ok
+}
+
+func (o *OIDC) isRemote() bool {
+	return strings.HasPrefix(o.opts.JWKS, "http://") || strings.HasPrefix(o.opts.JWKS, "https://")
+}
+
+func (o *OIDC) refreshDue() bool {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return time.Since(o.lastFetch) > jwksRefreshInterval
+}
+
+func (o *OIDC) loadKeys() error {
+	var data []byte
+	var err error
+	if o.isRemote() {
+		data, err = fetchJWKS(o.opts.JWKS)
+	} else {
+		data, err = os.ReadFile(o.opts.JWKS)
+	}
+	if err != nil {
+		return fmt.Errorf("read jwks: %w", err)
+	}
+
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return fmt.Errorf("parse jwks: %w", err)
+	}
+
+	o.mu.Lock()
+	o.keys = keys
+	o.lastFetch = time.Now()
+	o.mu.Unlock()
+
+	return nil
+}
+
+func fetchJWKS(url string) ([]byte, error) {
+	client := http.Client{Timeout: jwksFetchTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %v", resp.Status)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// parseJWKS returns the RSA and EC signature keys of the key set by key id
+func parseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", jwk.Kid, err)
+		}
+		if key == nil {
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no signature keys found")
+	}
+
+	return keys, nil
+}
+
+// publicKey returns the RSA or EC public key, other key types are skipped
+func (jwk jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid rsa exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("invalid ec point")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+
+	return nil, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
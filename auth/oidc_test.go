@@ -0,0 +1,234 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestOIDCVerifyToken(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encode := func(i *big.Int) string { return base64.RawURLEncoding.EncodeToString(i.Bytes()) }
+	jwks, err := json.Marshal(map[string][]jsonWebKey{"keys": {
+		{Kty: "RSA", Kid: "rsa", Use: "sig", N: encode(rsaKey.N), E: encode(big.NewInt(int64(rsaKey.E)))},
+		{Kty: "EC", Kid: "ec", Crv: "P-256", X: encode(ecKey.X), Y: encode(ecKey.Y)},
+		{Kty: "RSA", Kid: "enc", Use: "enc", N: encode(otherKey.N), E: encode(big.NewInt(int64(otherKey.E)))},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwksPath := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(jwksPath, jwks, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	o, err := NewOIDC(OIDCOpts{
+		Issuer:       "https://idp.example.com",
+		JWKS:         jwksPath,
+		Audiences:    []string{"versitygw"},
+		AccountClaim: "preferred_username",
+		RoleClaim:    "groups",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := func(modify func(jwt.MapClaims)) jwt.MapClaims {
+		c := jwt.MapClaims{
+			"iss":                "https://idp.example.com",
+			"sub":                "1234",
+			"aud":                []string{"other", "versitygw"},
+			"exp":                time.Now().Add(time.Hour).Unix(),
+			"preferred_username": "user1",
+		}
+		if modify != nil {
+			modify(c)
+		}
+		return c
+	}
+	sign := func(method jwt.SigningMethod, kid string, key any, c jwt.MapClaims) string {
+		token := jwt.NewWithClaims(method, c)
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		s, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	tests := []struct {
+		name     string
+		token    string
+		identity WebIdentity
+		err      error
+	}{
+		{
+			name:     "rsa",
+			token:    sign(jwt.SigningMethodRS256, "rsa", rsaKey, claims(nil)),
+			identity: WebIdentity{Subject: "1234", Audience: "versitygw", Account: "user1", Role: RoleUser},
+		},
+		{
+			name:     "ec",
+			token:    sign(jwt.SigningMethodES256, "ec", ecKey, claims(nil)),
+			identity: WebIdentity{Subject: "1234", Audience: "versitygw", Account: "user1", Role: RoleUser},
+		},
+		{
+			name: "admin-role",
+			token: sign(jwt.SigningMethodRS256, "rsa", rsaKey, claims(func(c jwt.MapClaims) {
+				c["groups"] = []string{"developers", "admin"}
+			})),
+			identity: WebIdentity{Subject: "1234", Audience: "versitygw", Account: "user1", Role: RoleAdmin},
+		},
+		{
+			name: "userplus-role",
+			token: sign(jwt.SigningMethodRS256, "rsa", rsaKey, claims(func(c jwt.MapClaims) {
+				c["groups"] = "userplus"
+			})),
+			identity: WebIdentity{Subject: "1234", Audience: "versitygw", Account: "user1", Role: RoleUserPlus},
+		},
+		{
+			name: "expired",
+			token: sign(jwt.SigningMethodRS256, "rsa", rsaKey, claims(func(c jwt.MapClaims) {
+				c["exp"] = time.Now().Add(-time.Hour).Unix()
+			})),
+			err: ErrOIDCTokenExpired,
+		},
+		{
+			name: "missing-expiration",
+			token: sign(jwt.SigningMethodRS256, "rsa", rsaKey, claims(func(c jwt.MapClaims) {
+				delete(c, "exp")
+			})),
+			err: jwt.ErrTokenRequiredClaimMissing,
+		},
+		{
+			name: "other-issuer",
+			token: sign(jwt.SigningMethodRS256, "rsa", rsaKey, claims(func(c jwt.MapClaims) {
+				c["iss"] = "https://other.example.com"
+			})),
+			err: jwt.ErrTokenInvalidIssuer,
+		},
+		{
+			name: "other-audience",
+			token: sign(jwt.SigningMethodRS256, "rsa", rsaKey, claims(func(c jwt.MapClaims) {
+				c["aud"] = "other"
+			})),
+			err: jwt.ErrTokenInvalidAudience,
+		},
+		{
+			name: "missing-account-claim",
+			token: sign(jwt.SigningMethodRS256, "rsa", rsaKey, claims(func(c jwt.MapClaims) {
+				delete(c, "preferred_username")
+			})),
+		},
+		{
+			name:  "forged-signature",
+			token: sign(jwt.SigningMethodRS256, "rsa", otherKey, claims(nil)),
+			err:   jwt.ErrTokenSignatureInvalid,
+		},
+		{
+			name:  "encryption-key",
+			token: sign(jwt.SigningMethodRS256, "enc", otherKey, claims(nil)),
+		},
+		{
+			name:  "unknown-key",
+			token: sign(jwt.SigningMethodRS256, "unknown", rsaKey, claims(nil)),
+		},
+		{
+			name:  "hmac",
+			token: sign(jwt.SigningMethodHS256, "rsa", []byte("secret"), claims(nil)),
+		},
+		{
+			name:  "none",
+			token: sign(jwt.SigningMethodNone, "rsa", jwt.UnsafeAllowNoneSignatureType, claims(nil)),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := o.VerifyToken(tt.token)
+			if tt.identity != (WebIdentity{}) {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				if identity != tt.identity {
+					t.Errorf("identity = %+v, want %+v", identity, tt.identity)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("expected the token to be rejected, got %+v", identity)
+			}
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Errorf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestNewOIDC(t *testing.T) {
+	opts := OIDCOpts{
+		Issuer:       "https://idp.example.com",
+		JWKS:         filepath.Join(t.TempDir(), "missing.json"),
+		Audiences:    []string{"versitygw"},
+		AccountClaim: "sub",
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*OIDCOpts)
+	}{
+		{"missing-issuer", func(o *OIDCOpts) { o.Issuer = "" }},
+		{"missing-jwks", func(o *OIDCOpts) { o.JWKS = "" }},
+		{"missing-audience", func(o *OIDCOpts) { o.Audiences = nil }},
+		{"missing-account-claim", func(o *OIDCOpts) { o.AccountClaim = "" }},
+		{"unreadable-jwks", func(*OIDCOpts) {}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := opts
+			tt.modify(&o)
+			if _, err := NewOIDC(o); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package auth
+
+import (
+	"context"
+
+	"github.com/versity/versitygw/s3err"
+)
+
+// SessionPolicy is the optional inline policy of session credentials.
+// The session is only granted the permissions allowed both by the policy
+// and by the account the session is issued for.
+type SessionPolicy struct {
+	Statement []BucketPolicyItem `json:"Statement"`
+}
+
+// ParseSessionPolicy parses and validates the session policy document
+func ParseSessionPolicy(policy string) (*SessionPolicy, error) {
+	statements, err := parseInlinePolicy(policy, "session")
+	if err != nil {
+		return nil, err
+	}
+
+	return &SessionPolicy{Statement: statements}, nil
+}
+
+// verifySessionPolicy denies the actions not allowed by the session
+// policy of the account
+func verifySessionPolicy(ctx context.Context, opts AccessOptions) error {
+	if opts.Acc.SessionPolicy == nil {
+		return nil
+	}
+
+	policy := BucketPolicy(*opts.Acc.SessionPolicy)
+	if !policy.isAllowed(opts.Acc.Access, opts.Action, policyResource(opts.Bucket, opts.Object), getConditionContext(ctx)) {
+		return s3err.GetAPIError(s3err.ErrAccessDenied)
+	}
+
+	return nil
+}
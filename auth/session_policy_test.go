@@ -0,0 +1,77 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestVerifyAccessSessionPolicy(t *testing.T) {
+	sessionPolicy := func(policy string) *SessionPolicy {
+		t.Helper()
+		sp, err := ParseSessionPolicy(policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sp
+	}
+	getOnly := sessionPolicy(`{"Statement": [{"Effect": "Allow", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/*"}]}`)
+	writeAll := sessionPolicy(`{"Statement": [{"Effect": "Allow", "Action": "s3:*", "Resource": ["arn:aws:s3:::bucket", "arn:aws:s3:::bucket/*"]}, {"Effect": "Deny", "Action": "s3:DeleteObject", "Resource": "arn:aws:s3:::bucket/*"}]}`)
+
+	// the ACL grants user1 read access to the bucket
+	acl := ACL{Owner: "owner", Grantees: []Grantee{{Access: "user1", Permission: types.PermissionRead}}}
+
+	tests := []struct {
+		name       string
+		acc        Account
+		isRoot     bool
+		action     Action
+		permission types.Permission
+		allowed    bool
+	}{
+		{"no-session-policy", Account{Access: "user1", Role: RoleUser, Session: true}, false, ListBucketAction, types.PermissionRead, true},
+		{"both-allow", Account{Access: "user1", Role: RoleUser, Session: true, SessionPolicy: getOnly}, false, GetObjectAction, types.PermissionRead, true},
+		{"session-policy-not-allowed", Account{Access: "user1", Role: RoleUser, Session: true, SessionPolicy: getOnly}, false, ListBucketAction, types.PermissionRead, false},
+		{"account-not-allowed", Account{Access: "user1", Role: RoleUser, Session: true, SessionPolicy: writeAll}, false, PutObjectAction, types.PermissionWrite, false},
+		{"owner-session", Account{Access: "owner", Role: RoleUser, Session: true, SessionPolicy: writeAll}, false, PutObjectAction, types.PermissionWrite, true},
+		{"owner-session-deny", Account{Access: "owner", Role: RoleUser, Session: true, SessionPolicy: writeAll}, false, DeleteObjectAction, types.PermissionWrite, false},
+		{"admin-session", Account{Access: "admin1", Role: RoleAdmin, Session: true, SessionPolicy: getOnly}, false, DeleteObjectAction, types.PermissionWrite, false},
+		{"root-session", Account{Access: "root", Role: RoleAdmin, Session: true, SessionPolicy: getOnly}, true, GetObjectAction, types.PermissionRead, true},
+		{"root-session-not-allowed", Account{Access: "root", Role: RoleAdmin, Session: true, SessionPolicy: getOnly}, true, DeleteObjectAction, types.PermissionWrite, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var object string
+			if tt.action.IsObjectAction() {
+				object = "obj"
+			}
+			err := VerifyAccess(context.Background(), policyBackend{}, AccessOptions{
+				Acl:           acl,
+				AclPermission: tt.permission,
+				IsRoot:        tt.isRoot,
+				Acc:           tt.acc,
+				Bucket:        "bucket",
+				Object:        object,
+				Action:        tt.action,
+			})
+			if (err == nil) != tt.allowed {
+				t.Errorf("VerifyAccess() error = %v, allowed %v", err, tt.allowed)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/versity/versitygw/s3err"
+)
+
+const (
+	// sessionAccessPrefix is the prefix of the temporary access keys
+	sessionAccessPrefix = "ASIA"
+	sessionAccessChars  = "ABCDEFGHIJKLMNOPQRSTUVWXYZ234567"
+	sessionAccessLen    = 20
+	sessionSecretLen    = 40
+)
+
+// SessionClaims are the claims carried by a session token
+type SessionClaims struct {
+	// Access is the temporary access key of the session
+	Access string `json:"access"`
+	// Parent is the access key of the account the session is issued for
+	Parent string `json:"parent"`
+	// Role is the gateway role of web identity sessions whose parent has
+	// no gateway account, it is empty for the sessions of accounts
+	Role       Role      `json:"role,omitempty"`
+	Expiration time.Time `json:"expiration"`
+	// Policy is the optional session policy document
+	Policy string `json:"policy,omitempty"`
+}
+
+// SessionCredentials are temporary security credentials
+type SessionCredentials struct {
+	Access       string
+	Secret       string
+	SessionToken string
+	Expiration   time.Time
+}
+
+// STS issues and validates temporary security credentials. The
+// credentials are stateless: the session token carries the claims signed
+// with a key derived from the root account secret, and the session secret
+// key is derived from the signed claims. Any gateway sharing the root
+// account credentials validates the sessions issued by the others.
+type STS struct {
+	key []byte
+}
+
+// NewSTS returns the security token service of the root account secret
+func NewSTS(rootSecret string) *STS {
+	mac := hmac.New(sha256.New, []byte(rootSecret))
+	mac.Write([]byte("versitygw session token"))
+	return &STS{key: mac.Sum(nil)}
+}
+
+// NewSession issues temporary credentials of the parent account valid
+// for the duration, limited by the optional session policy. The role is
+// only set for web identities that have no gateway account.
+func (s *STS) NewSession(parent string, role Role, duration time.Duration, policy string) (SessionCredentials, error) {
+	access, err := newSessionAccess()
+	if err != nil {
+		return SessionCredentials{}, err
+	}
+
+	claims := SessionClaims{
+		Access:     access,
+		Parent:     parent,
+		Role:       role,
+		Expiration: time.Now().Add(duration).UTC().Truncate(time.Second),
+		Policy:     policy,
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return SessionCredentials{}, fmt.Errorf("marshal session claims: %w", err)
+	}
+
+	return SessionCredentials{
+		Access:       access,
+		Secret:       s.sessionSecret(payload),
+		SessionToken: base64.RawURLEncoding.EncodeToString(payload) + "." + s.sign(payload),
+		Expiration:   claims.Expiration,
+	}, nil
+}
+
+// ParseSessionToken validates the session token of the temporary access
+// key, and returns the token claims and the session secret key
+func (s *STS) ParseSessionToken(access, token string) (SessionClaims, string, error) {
+	encPayload, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return SessionClaims{}, "", s3err.GetAPIError(s3err.ErrInvalidToken)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encPayload)
+	if err != nil {
+		return SessionClaims{}, "", s3err.GetAPIError(s3err.ErrInvalidToken)
+	}
+	if !hmac.Equal([]byte(signature), []byte(s.sign(payload))) {
+		return SessionClaims{}, "", s3err.GetAPIError(s3err.ErrInvalidToken)
+	}
+
+	var claims SessionClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return SessionClaims{}, "", s3err.GetAPIError(s3err.ErrInvalidToken)
+	}
+	if claims.Access != access {
+		return SessionClaims{}, "", s3err.GetAPIError(s3err.ErrInvalidToken)
+	}
+	if time.Now().After(claims.Expiration) {
+		return SessionClaims{}, "", s3err.GetAPIError(s3err.ErrExpiredToken)
+	}
+
+	return claims, s.sessionSecret(payload), nil
+}
+
+// SessionAccount returns the account of the session: the parent account
+// with the session secret key and policy
+func SessionAccount(parent Account, claims SessionClaims, secret string) (Account, error) {
+	parent.Secret = secret
+	parent.Session = true
+	if claims.Policy == "" {
+		return parent, nil
+	}
+
+	policy, err := ParseSessionPolicy(claims.Policy)
+	if err != nil {
+		return Account{}, s3err.GetAPIError(s3err.ErrInvalidToken)
+	}
+	parent.SessionPolicy = policy
+
+	return parent, nil
+}
+
+func (s *STS) sign(payload []byte) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte("token\n"))
+	mac.Write(payload)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *STS) sessionSecret(payload []byte) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte("secret\n"))
+	mac.Write(payload)
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))[:sessionSecretLen]
+}
+
+func newSessionAccess() (string, error) {
+	b := make([]byte, sessionAccessLen-len(sessionAccessPrefix))
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate session access key: %w", err)
+	}
+	for i := range b {
+		b[i] = sessionAccessChars[int(b[i])%len(sessionAccessChars)]
+	}
+	return sessionAccessPrefix + string(b), nil
+}
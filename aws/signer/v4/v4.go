@@ -399,6 +399,16 @@ func (s *Signer) PresignHTTP(
 	return signedRequest.Request.URL.String(), signedHeaders, nil
 }
 
+// SignPostPolicy returns the signature of a browser based POST upload. The
+// string to sign is the base64 encoded policy document of the form, it is
+// signed with the key derived for the credential scope of the signing time.
+//
+// https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-authentication-HTTPPOST.html
+func (s *Signer) SignPostPolicy(credentials aws.Credentials, policy string, service string, region string, signingTime time.Time) string {
+	key := s.keyDerivator.DeriveKey(credentials, service, region, v4Internal.NewSigningTime(signingTime.UTC()))
+	return hex.EncodeToString(v4Internal.HMACSHA256(key, []byte(policy)))
+}
+
 func (s *httpSigner) buildCredentialScope() string {
 	return v4Internal.BuildCredentialScope(s.Time, s.Region, s.ServiceName)
 }
@@ -182,6 +182,29 @@ func TestSignRequest(t *testing.T) {
 	}
 }
 
+func TestSignPostPolicy(t *testing.T) {
+	policy := "eyJleHBpcmF0aW9uIjoiMjAxNS0xMi0zMFQxMjowMDowMC4wMDBaIn0="
+	signTime := time.Date(2015, 12, 29, 0, 0, 0, 0, time.UTC)
+
+	// the signing key derivation written out step by step
+	key := v4Internal.HMACSHA256([]byte("AWS4"+testCredentials.SecretAccessKey), []byte("20151229"))
+	key = v4Internal.HMACSHA256(key, []byte("us-east-1"))
+	key = v4Internal.HMACSHA256(key, []byte("s3"))
+	key = v4Internal.HMACSHA256(key, []byte("aws4_request"))
+	expectedSig := hex.EncodeToString(v4Internal.HMACSHA256(key, []byte(policy)))
+
+	signer := NewSigner()
+	sig := signer.SignPostPolicy(testCredentials, policy, "s3", "us-east-1", signTime)
+	if e, a := expectedSig, sig; e != a {
+		t.Errorf("expect %v, got %v", e, a)
+	}
+
+	sig = signer.SignPostPolicy(testCredentials, policy, "s3", "us-west-2", signTime)
+	if sig == expectedSig {
+		t.Errorf("expect signature of a different region to differ")
+	}
+}
+
 func TestBuildCanonicalRequest(t *testing.T) {
 	req, _ := buildRequest("dynamodb", "us-east-1", "{}")
 	req.URL.RawQuery = "Foo=z&Foo=o&Foo=m&Foo=a"
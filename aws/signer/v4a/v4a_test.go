@@ -0,0 +1,157 @@
+package v4a
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	accessKey = "AKISORANDOMAASORANDOM"
+	secretKey = "q+jcrXGc+0zWN6uzclKVhvMmUsIfRPa4rlRandom"
+)
+
+func TestDeriveECDSAKeyPairFromSecret(t *testing.T) {
+	privateKey, err := deriveKeyFromAccessKeyPair(accessKey, secretKey)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expectedX, _ := new(big.Int).SetString("15D242CEEBF8D8169FD6A8B5A746C41140414C3B07579038DA06AF89190FFFCB", 16)
+	expectedY, _ := new(big.Int).SetString("515242CEDD82E94799482E4C0514B505AFCCF2C0C98D6A553BF539F424C5EC0", 16)
+
+	if privateKey.X.Cmp(expectedX) != 0 {
+		t.Errorf("expected % X, got % X", expectedX, privateKey.X)
+	}
+	if privateKey.Y.Cmp(expectedY) != 0 {
+		t.Errorf("expected % X, got % X", expectedY, privateKey.Y)
+	}
+}
+
+func buildRequest() *http.Request {
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com/bucket/object?versionId=1", nil)
+	req.Header.Set("X-Amz-Content-Sha256", EmptyStringSHA256)
+	req.Header.Set("X-Amz-Meta-Color", "blue")
+	return req
+}
+
+func TestVerifyHTTP(t *testing.T) {
+	creds, err := NewCredentials(accessKey, secretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signTime := time.Unix(0, 0)
+	regionSet := []string{"us-east-1", "us-west-2"}
+
+	req := buildRequest()
+	signer := NewSigner(func(o *SignerOptions) {
+		o.DisableURIPathEscaping = true
+	})
+	err = signer.SignHTTP(context.Background(), creds, req, EmptyStringSHA256, "s3", regionSet, signTime, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	authorization := req.Header.Get("Authorization")
+	if !strings.HasPrefix(authorization, "AWS4-ECDSA-P256-SHA256 Credential=AKISORANDOMAASORANDOM/19700101/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date;x-amz-meta-color;x-amz-region-set, Signature=") {
+		t.Fatalf("unexpected authorization %v", authorization)
+	}
+	if req.Header.Get(AmzRegionSetKey) != "us-east-1,us-west-2" {
+		t.Errorf("expected region set header, got %v", req.Header.Get(AmzRegionSetKey))
+	}
+	_, signature, _ := strings.Cut(authorization, "Signature=")
+
+	verify := func(req *http.Request, regionSet []string) error {
+		req.Header.Del("Authorization")
+		return signer.VerifyHTTP(context.Background(), creds, req, EmptyStringSHA256, "s3", regionSet, signTime, nil, signature)
+	}
+
+	if err := verify(req, regionSet); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	tampered := req.Clone(context.Background())
+	tampered.Header.Set("X-Amz-Meta-Color", "red")
+	if err := verify(tampered, regionSet); !errors.Is(err, ErrSignatureDoesNotMatch) {
+		t.Errorf("expected signature mismatch for tampered header, got %v", err)
+	}
+
+	if err := verify(req.Clone(context.Background()), []string{"*"}); !errors.Is(err, ErrSignatureDoesNotMatch) {
+		t.Errorf("expected signature mismatch for different region set, got %v", err)
+	}
+
+	other, err := NewCredentials(accessKey, "other secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = signer.VerifyHTTP(context.Background(), other, req, EmptyStringSHA256, "s3", regionSet, signTime, nil, signature)
+	if !errors.Is(err, ErrSignatureDoesNotMatch) {
+		t.Errorf("expected signature mismatch for different secret, got %v", err)
+	}
+}
+
+func TestVerifyPresignHTTP(t *testing.T) {
+	creds, err := NewCredentials(accessKey, secretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signTime := time.Unix(0, 0)
+	regionSet := []string{"*"}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com/bucket/object?X-Amz-Expires=60", nil)
+	signer := NewSigner()
+	uri, _, err := signer.PresignHTTP(context.Background(), creds, req, "UNSIGNED-PAYLOAD", "s3", regionSet, signTime, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := u.Query()
+	if query.Get("X-Amz-Algorithm") != "AWS4-ECDSA-P256-SHA256" {
+		t.Errorf("unexpected algorithm %v", query.Get("X-Amz-Algorithm"))
+	}
+	if query.Get("X-Amz-Credential") != "AKISORANDOMAASORANDOM/19700101/s3/aws4_request" {
+		t.Errorf("unexpected credential %v", query.Get("X-Amz-Credential"))
+	}
+	signature := query.Get("X-Amz-Signature")
+
+	err = signer.VerifyPresignHTTP(context.Background(), creds, req, "UNSIGNED-PAYLOAD", "s3", regionSet, signTime, nil, signature)
+	if err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	err = signer.VerifyPresignHTTP(context.Background(), creds, req, "UNSIGNED-PAYLOAD", "s3", regionSet, signTime.Add(time.Second), nil, signature)
+	if !errors.Is(err, ErrSignatureDoesNotMatch) {
+		t.Errorf("expected signature mismatch for different signing time, got %v", err)
+	}
+}
+
+func TestVerifyString(t *testing.T) {
+	creds, err := NewCredentials(accessKey, secretKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signature, err := SignString(creds, "string to sign")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := VerifyString(&creds.PrivateKey.PublicKey, "string to sign", signature); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if err := VerifyString(&creds.PrivateKey.PublicKey, "other string", signature); !errors.Is(err, ErrSignatureDoesNotMatch) {
+		t.Errorf("expected signature mismatch, got %v", err)
+	}
+	if err := VerifyString(&creds.PrivateKey.PublicKey, "string to sign", "not hex"); !errors.Is(err, ErrSignatureDoesNotMatch) {
+		t.Errorf("expected signature mismatch, got %v", err)
+	}
+}
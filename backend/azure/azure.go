@@ -23,11 +23,13 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"net/http"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
@@ -40,6 +42,7 @@ import (
 	"github.com/versity/versitygw/backend"
 	"github.com/versity/versitygw/s3err"
 	"github.com/versity/versitygw/s3response"
+	"github.com/versity/versitygw/tracing"
 )
 
 // When getting container metadata with GetProperties method the sdk returns
@@ -50,6 +53,10 @@ type aclKey string
 const aclKeyCapital aclKey = "Acl"
 const aclKeyLower aclKey = "acl"
 
+// corsKey is the container metadata key holding the base64 encoded
+// bucket CORS configuration
+const corsKey = "Cors"
+
 type Azure struct {
 	backend.BackendUnsupported
 
@@ -62,6 +69,11 @@ type Azure struct {
 
 var _ backend.Backend = &Azure{}
 
+// clientOpts propagates the trace context to the storage service
+var clientOpts = azcore.ClientOptions{
+	Transport: &http.Client{Transport: tracing.Transport(http.DefaultTransport)},
+}
+
 func New(accountName, accountKey, serviceURL, sasToken string) (*Azure, error) {
 	url := serviceURL
 	if serviceURL == "" && accountName != "" {
@@ -71,7 +83,7 @@ func New(accountName, accountKey, serviceURL, sasToken string) (*Azure, error) {
 	}
 
 	if sasToken != "" {
-		client, err := azblob.NewClientWithNoCredential(url+"?"+sasToken, nil)
+		client, err := azblob.NewClientWithNoCredential(url+"?"+sasToken, &azblob.ClientOptions{ClientOptions: clientOpts})
 		if err != nil {
 			return nil, fmt.Errorf("init client: %w", err)
 		}
@@ -88,7 +100,7 @@ func New(accountName, accountKey, serviceURL, sasToken string) (*Azure, error) {
 		if err != nil {
 			return nil, fmt.Errorf("init default credentials: %w", err)
 		}
-		client, err := azblob.NewClient(url, cred, nil)
+		client, err := azblob.NewClient(url, cred, &azblob.ClientOptions{ClientOptions: clientOpts})
 		if err != nil {
 			return nil, fmt.Errorf("init client: %w", err)
 		}
@@ -100,7 +112,7 @@ func New(accountName, accountKey, serviceURL, sasToken string) (*Azure, error) {
 		return nil, fmt.Errorf("init credentials: %w", err)
 	}
 
-	client, err := azblob.NewClientWithSharedKeyCredential(url, cred, nil)
+	client, err := azblob.NewClientWithSharedKeyCredential(url, cred, &azblob.ClientOptions{ClientOptions: clientOpts})
 	if err != nil {
 		return nil, fmt.Errorf("init client: %w", err)
 	}
@@ -167,10 +179,10 @@ func (az *Azure) DeleteBucket(ctx context.Context, input *s3.DeleteBucketInput)
 	return azureErrToS3Err(err)
 }
 
-func (az *Azure) PutObject(ctx context.Context, po *s3.PutObjectInput) (string, error) {
+func (az *Azure) PutObject(ctx context.Context, po *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
 	tags, err := parseTags(po.Tagging)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	uploadResp, err := az.client.UploadStream(ctx, *po.Bucket, *po.Key, po.Body, &blockblob.UploadStreamOptions{
@@ -178,10 +190,11 @@ func (az *Azure) PutObject(ctx context.Context, po *s3.PutObjectInput) (string,
 		Tags:     tags,
 	})
 	if err != nil {
-		return "", azureErrToS3Err(err)
+		return nil, azureErrToS3Err(err)
 	}
 
-	return string(*uploadResp.ETag), nil
+	etag := string(*uploadResp.ETag)
+	return &s3.PutObjectOutput{ETag: &etag}, nil
 }
 
 func (az *Azure) PutBucketTagging(ctx context.Context, bucket string, tags map[string]string) error {
@@ -196,9 +209,13 @@ func (az *Azure) PutBucketTagging(ctx context.Context, bucket string, tags map[s
 	}
 
 	if tags == nil {
-		_, err := client.SetMetadata(ctx, &container.SetMetadataOptions{Metadata: map[string]*string{
+		meta := map[string]*string{
 			string(aclKeyCapital): resp.Metadata[string(aclKeyCapital)],
-		}})
+		}
+		if cors, ok := resp.Metadata[corsKey]; ok {
+			meta[corsKey] = cors
+		}
+		_, err := client.SetMetadata(ctx, &container.SetMetadataOptions{Metadata: meta})
 		if err != nil {
 			return azureErrToS3Err(err)
 		}
@@ -212,6 +229,9 @@ func (az *Azure) PutBucketTagging(ctx context.Context, bucket string, tags map[s
 	}
 
 	tags[string(aclKeyCapital)] = *resp.Metadata[string(aclKeyCapital)]
+	if cors, ok := resp.Metadata[corsKey]; ok {
+		tags[corsKey] = *cors
+	}
 
 	_, err = client.SetMetadata(ctx, &container.SetMetadataOptions{Metadata: parseMetadata(tags)})
 	if err != nil {
@@ -233,6 +253,7 @@ func (az *Azure) GetBucketTagging(ctx context.Context, bucket string) (map[strin
 	}
 
 	delete(resp.Metadata, string(aclKeyCapital))
+	delete(resp.Metadata, corsKey)
 
 	return parseAzMetadata(resp.Metadata), nil
 }
@@ -417,15 +438,18 @@ Pager:
 	}, nil
 }
 
-func (az *Azure) DeleteObject(ctx context.Context, input *s3.DeleteObjectInput) error {
+func (az *Azure) DeleteObject(ctx context.Context, input *s3.DeleteObjectInput) (*s3.DeleteObjectOutput, error) {
 	_, err := az.client.DeleteBlob(ctx, *input.Bucket, *input.Key, nil)
-	return azureErrToS3Err(err)
+	if err != nil {
+		return nil, azureErrToS3Err(err)
+	}
+	return &s3.DeleteObjectOutput{}, nil
 }
 
 func (az *Azure) DeleteObjects(ctx context.Context, input *s3.DeleteObjectsInput) (s3response.DeleteResult, error) {
 	delResult, errs := []types.DeletedObject{}, []types.Error{}
 	for _, obj := range input.Delete.Objects {
-		err := az.DeleteObject(ctx, &s3.DeleteObjectInput{
+		_, err := az.DeleteObject(ctx, &s3.DeleteObjectInput{
 			Bucket: input.Bucket,
 			Key:    obj.Key,
 		})
@@ -556,10 +580,10 @@ func (az *Azure) CreateMultipartUpload(ctx context.Context, input *s3.CreateMult
 }
 
 // Each part is translated into an uncommitted block in a newly created blob in staging area
-func (az *Azure) UploadPart(ctx context.Context, input *s3.UploadPartInput) (etag string, err error) {
+func (az *Azure) UploadPart(ctx context.Context, input *s3.UploadPartInput) (*s3.UploadPartOutput, error) {
 	client, err := az.getBlockBlobClient(*input.Bucket, *input.Key)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// TODO: request streamable version of StageBlock()
@@ -568,17 +592,19 @@ func (az *Azure) UploadPart(ctx context.Context, input *s3.UploadPartInput) (eta
 	// the body in memory to create an io.ReadSeekCloser
 	rdr, err := getReadSeekCloser(input.Body)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// block id serves as etag here
-	etag = blockIDInt32ToBase64(*input.PartNumber)
+	etag := blockIDInt32ToBase64(*input.PartNumber)
 	_, err = client.StageBlock(ctx, etag, rdr, nil)
 	if err != nil {
-		return "", parseMpError(err)
+		return nil, parseMpError(err)
 	}
 
-	return etag, nil
+	return &s3.UploadPartOutput{
+		ETag: &etag,
+	}, nil
 }
 
 func (az *Azure) UploadPartCopy(ctx context.Context, input *s3.UploadPartCopyInput) (s3response.CopyObjectResult, error) {
@@ -751,9 +777,16 @@ func (az *Azure) PutBucketAcl(ctx context.Context, bucket string, data []byte) e
 	if err != nil {
 		return err
 	}
+	props, err := client.GetProperties(ctx, nil)
+	if err != nil {
+		return azureErrToS3Err(err)
+	}
 	meta := map[string]*string{
 		string(aclKeyCapital): backend.GetStringPtr(string(data)),
 	}
+	if cors, ok := props.Metadata[corsKey]; ok {
+		meta[corsKey] = cors
+	}
 	_, err = client.SetMetadata(ctx, &container.SetMetadataOptions{
 		Metadata: meta,
 	})
@@ -781,6 +814,62 @@ func (az *Azure) GetBucketAcl(ctx context.Context, input *s3.GetBucketAclInput)
 	return []byte(*aclPtr), nil
 }
 
+func (az *Azure) PutBucketCors(ctx context.Context, bucket string, cors []byte) error {
+	client, err := az.getContainerClient(bucket)
+	if err != nil {
+		return err
+	}
+	props, err := client.GetProperties(ctx, nil)
+	if err != nil {
+		return azureErrToS3Err(err)
+	}
+
+	meta := props.Metadata
+	if meta == nil {
+		meta = make(map[string]*string)
+	}
+	if cors == nil {
+		delete(meta, corsKey)
+	} else {
+		meta[corsKey] = backend.GetStringPtr(base64.StdEncoding.EncodeToString(cors))
+	}
+
+	_, err = client.SetMetadata(ctx, &container.SetMetadataOptions{
+		Metadata: meta,
+	})
+	if err != nil {
+		return azureErrToS3Err(err)
+	}
+	return nil
+}
+
+func (az *Azure) GetBucketCors(ctx context.Context, bucket string) ([]byte, error) {
+	client, err := az.getContainerClient(bucket)
+	if err != nil {
+		return nil, err
+	}
+	props, err := client.GetProperties(ctx, nil)
+	if err != nil {
+		return nil, azureErrToS3Err(err)
+	}
+
+	corsPtr, ok := props.Metadata[corsKey]
+	if !ok {
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchCORSConfiguration)
+	}
+
+	cors, err := base64.StdEncoding.DecodeString(*corsPtr)
+	if err != nil {
+		return nil, fmt.Errorf("decode cors: %w", err)
+	}
+
+	return cors, nil
+}
+
+func (az *Azure) DeleteBucketCors(ctx context.Context, bucket string) error {
+	return az.PutBucketCors(ctx, bucket, nil)
+}
+
 func (az *Azure) ChangeBucketOwner(ctx context.Context, bucket, newOwner string) error {
 	client, err := az.getContainerClient(bucket)
 	if err != nil {
@@ -847,34 +936,34 @@ func (az *Azure) getBlobURL(cntr, blb string) string {
 func (az *Azure) getBlobClient(cntr, blb string) (*blob.Client, error) {
 	blobURL := az.getBlobURL(cntr, blb)
 	if az.defaultCreds != nil {
-		return blob.NewClient(blobURL, az.defaultCreds, nil)
+		return blob.NewClient(blobURL, az.defaultCreds, &blob.ClientOptions{ClientOptions: clientOpts})
 	}
 	if az.sasToken != "" {
-		return blob.NewClientWithNoCredential(blobURL+"?"+az.sasToken, nil)
+		return blob.NewClientWithNoCredential(blobURL+"?"+az.sasToken, &blob.ClientOptions{ClientOptions: clientOpts})
 	}
-	return blob.NewClientWithSharedKeyCredential(blobURL, az.sharedkeyCreds, nil)
+	return blob.NewClientWithSharedKeyCredential(blobURL, az.sharedkeyCreds, &blob.ClientOptions{ClientOptions: clientOpts})
 }
 
 func (az *Azure) getContainerClient(cntr string) (*container.Client, error) {
 	containerURL := az.getContainerURL(cntr)
 	if az.defaultCreds != nil {
-		return container.NewClient(containerURL, az.defaultCreds, nil)
+		return container.NewClient(containerURL, az.defaultCreds, &container.ClientOptions{ClientOptions: clientOpts})
 	}
 	if az.sasToken != "" {
-		return container.NewClientWithNoCredential(containerURL+"?"+az.sasToken, nil)
+		return container.NewClientWithNoCredential(containerURL+"?"+az.sasToken, &container.ClientOptions{ClientOptions: clientOpts})
 	}
-	return container.NewClientWithSharedKeyCredential(containerURL, az.sharedkeyCreds, nil)
+	return container.NewClientWithSharedKeyCredential(containerURL, az.sharedkeyCreds, &container.ClientOptions{ClientOptions: clientOpts})
 }
 
 func (az *Azure) getBlockBlobClient(cntr, blb string) (*blockblob.Client, error) {
 	blobURL := az.getBlobURL(cntr, blb)
 	if az.defaultCreds != nil {
-		return blockblob.NewClient(blobURL, az.defaultCreds, nil)
+		return blockblob.NewClient(blobURL, az.defaultCreds, &blockblob.ClientOptions{ClientOptions: clientOpts})
 	}
 	if az.sasToken != "" {
-		return blockblob.NewClientWithNoCredential(blobURL+"?"+az.sasToken, nil)
+		return blockblob.NewClientWithNoCredential(blobURL+"?"+az.sasToken, &blockblob.ClientOptions{ClientOptions: clientOpts})
 	}
-	return blockblob.NewClientWithSharedKeyCredential(blobURL, az.sharedkeyCreds, nil)
+	return blockblob.NewClientWithSharedKeyCredential(blobURL, az.sharedkeyCreds, &blockblob.ClientOptions{ClientOptions: clientOpts})
 }
 
 func parseMetadata(m map[string]string) map[string]*string {
@@ -0,0 +1,184 @@
+// Copyright 2024 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package backend
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/versity/versitygw/s3err"
+)
+
+// https://docs.aws.amazon.com/AmazonS3/latest/userguide/checking-object-integrity.html
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Checksum is an additional checksum of object or part data. The value
+// is the base64 encoded digest of the algorithm, for completed multipart
+// uploads it is the digest of the part checksums followed by "-" and the
+// number of parts.
+type Checksum struct {
+	Algorithm types.ChecksumAlgorithm `json:"algorithm"`
+	Value     string                  `json:"value,omitempty"`
+}
+
+// NewChecksumHash returns the hash calculating the checksum of the
+// algorithm
+func NewChecksumHash(algo types.ChecksumAlgorithm) (hash.Hash, error) {
+	switch algo {
+	case types.ChecksumAlgorithmCrc32:
+		return crc32.NewIEEE(), nil
+	case types.ChecksumAlgorithmCrc32c:
+		return crc32.New(crc32cTable), nil
+	case types.ChecksumAlgorithmSha1:
+		return sha1.New(), nil
+	case types.ChecksumAlgorithmSha256:
+		return sha256.New(), nil
+	}
+	return nil, s3err.GetAPIError(s3err.ErrInvalidChecksumAlgorithm)
+}
+
+// ParseChecksum returns the checksum of a request from the requested
+// algorithm and the checksum value fields. At most one value may be set,
+// the algorithm is optional if a value is set. The checksum value is
+// empty if the request only specifies the algorithm.
+func ParseChecksum(algo types.ChecksumAlgorithm, crc32, crc32c, sha1, sha256 *string) (Checksum, error) {
+	algo = types.ChecksumAlgorithm(strings.ToUpper(string(algo)))
+
+	var checksum Checksum
+	for _, c := range []Checksum{
+		{Algorithm: types.ChecksumAlgorithmCrc32, Value: GetString(crc32)},
+		{Algorithm: types.ChecksumAlgorithmCrc32c, Value: GetString(crc32c)},
+		{Algorithm: types.ChecksumAlgorithmSha1, Value: GetString(sha1)},
+		{Algorithm: types.ChecksumAlgorithmSha256, Value: GetString(sha256)},
+	} {
+		if c.Value == "" {
+			continue
+		}
+		if checksum.Value != "" {
+			return Checksum{}, s3err.GetAPIError(s3err.ErrMultipleChecksumHeaders)
+		}
+		checksum = c
+	}
+
+	if checksum.Value == "" {
+		if algo == "" {
+			return Checksum{}, nil
+		}
+		if _, err := NewChecksumHash(algo); err != nil {
+			return Checksum{}, err
+		}
+		return Checksum{Algorithm: algo}, nil
+	}
+
+	if algo != "" && algo != checksum.Algorithm {
+		return Checksum{}, s3err.GetAPIError(s3err.ErrChecksumAlgorithmMismatch)
+	}
+
+	h, _ := NewChecksumHash(checksum.Algorithm)
+	sum, err := base64.StdEncoding.DecodeString(checksum.Value)
+	if err != nil || len(sum) != h.Size() {
+		return Checksum{}, s3err.APIError{
+			Code:           "InvalidRequest",
+			Description:    fmt.Sprintf("Value for x-amz-checksum-%v header is invalid.", strings.ToLower(string(checksum.Algorithm))),
+			HTTPStatusCode: http.StatusBadRequest,
+		}
+	}
+
+	return checksum, nil
+}
+
+// Verify compares the calculated digest with the expected checksum value,
+// the value is set to the calculated checksum if it was not part of the
+// request
+func (c *Checksum) Verify(sum []byte) error {
+	value := base64.StdEncoding.EncodeToString(sum)
+	if c.Value == "" {
+		c.Value = value
+		return nil
+	}
+	if c.Value != value {
+		return s3err.APIError{
+			Code:           "BadDigest",
+			Description:    fmt.Sprintf("The %v you specified did not match the calculated checksum.", c.Algorithm),
+			HTTPStatusCode: http.StatusBadRequest,
+		}
+	}
+	return nil
+}
+
+// Fields returns the checksum value in the field of its algorithm in the
+// order CRC32, CRC32C, SHA1, SHA256
+func (c Checksum) Fields() (crc32, crc32c, sha1, sha256 *string) {
+	if c.Value == "" {
+		return
+	}
+	value := c.Value
+	switch c.Algorithm {
+	case types.ChecksumAlgorithmCrc32:
+		crc32 = &value
+	case types.ChecksumAlgorithmCrc32c:
+		crc32c = &value
+	case types.ChecksumAlgorithmSha1:
+		sha1 = &value
+	case types.ChecksumAlgorithmSha256:
+		sha256 = &value
+	}
+	return
+}
+
+// PartChecksum returns the checksum value of the algorithm of a
+// completed part
+func PartChecksum(algo types.ChecksumAlgorithm, part types.CompletedPart) string {
+	switch algo {
+	case types.ChecksumAlgorithmCrc32:
+		return GetString(part.ChecksumCRC32)
+	case types.ChecksumAlgorithmCrc32c:
+		return GetString(part.ChecksumCRC32C)
+	case types.ChecksumAlgorithmSha1:
+		return GetString(part.ChecksumSHA1)
+	case types.ChecksumAlgorithmSha256:
+		return GetString(part.ChecksumSHA256)
+	}
+	return ""
+}
+
+// MultipartChecksum returns the checksum of a completed multipart upload
+// calculated from the checksums of the parts
+func MultipartChecksum(algo types.ChecksumAlgorithm, parts []string) (Checksum, error) {
+	h, err := NewChecksumHash(algo)
+	if err != nil {
+		return Checksum{}, err
+	}
+	for _, part := range parts {
+		sum, err := base64.StdEncoding.DecodeString(part)
+		if err != nil {
+			return Checksum{}, fmt.Errorf("decode part checksum: %w", err)
+		}
+		h.Write(sum)
+	}
+	return Checksum{
+		Algorithm: algo,
+		Value: fmt.Sprintf("%v-%v",
+			base64.StdEncoding.EncodeToString(h.Sum(nil)), len(parts)),
+	}, nil
+}
@@ -0,0 +1,168 @@
+// Copyright 2024 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package backend_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"hash/crc32"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/s3err"
+)
+
+func b64Sum(b []byte) string {
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+func TestParseChecksum(t *testing.T) {
+	data := []byte("hello world")
+	crc := crc32.ChecksumIEEE(data)
+	crcSum := b64Sum([]byte{byte(crc >> 24), byte(crc >> 16), byte(crc >> 8), byte(crc)})
+	shaSum := sha256.Sum256(data)
+	sha := b64Sum(shaSum[:])
+	bad := "invalid"
+
+	tests := []struct {
+		name     string
+		algo     types.ChecksumAlgorithm
+		crc32    *string
+		sha256   *string
+		expected backend.Checksum
+		code     string
+	}{
+		{
+			name: "none",
+		},
+		{
+			name:     "algorithm-only",
+			algo:     "crc32c",
+			expected: backend.Checksum{Algorithm: types.ChecksumAlgorithmCrc32c},
+		},
+		{
+			name:     "value-only",
+			sha256:   &sha,
+			expected: backend.Checksum{Algorithm: types.ChecksumAlgorithmSha256, Value: sha},
+		},
+		{
+			name:     "algorithm-and-value",
+			algo:     types.ChecksumAlgorithmCrc32,
+			crc32:    &crcSum,
+			expected: backend.Checksum{Algorithm: types.ChecksumAlgorithmCrc32, Value: crcSum},
+		},
+		{
+			name: "unsupported-algorithm",
+			algo: "MD5",
+			code: "InvalidRequest",
+		},
+		{
+			name:   "multiple-values",
+			crc32:  &crcSum,
+			sha256: &sha,
+			code:   "InvalidRequest",
+		},
+		{
+			name:  "algorithm-mismatch",
+			algo:  types.ChecksumAlgorithmSha1,
+			crc32: &crcSum,
+			code:  "InvalidRequest",
+		},
+		{
+			name:   "invalid-value",
+			sha256: &bad,
+			code:   "InvalidRequest",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checksum, err := backend.ParseChecksum(tt.algo, tt.crc32, nil, nil, tt.sha256)
+			if tt.code != "" {
+				var apiErr s3err.APIError
+				if !errors.As(err, &apiErr) || apiErr.Code != tt.code {
+					t.Fatalf("expected error code %v, got %v", tt.code, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if checksum != tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, checksum)
+			}
+		})
+	}
+}
+
+func TestChecksumVerify(t *testing.T) {
+	h, err := backend.NewChecksumHash(types.ChecksumAlgorithmSha256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Write([]byte("hello world"))
+	sum := h.Sum(nil)
+
+	checksum := backend.Checksum{Algorithm: types.ChecksumAlgorithmSha256}
+	if err := checksum.Verify(sum); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if checksum.Value != b64Sum(sum) {
+		t.Errorf("expected calculated value to be set, got %q", checksum.Value)
+	}
+	if err := checksum.Verify(sum); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	checksum.Value = b64Sum(make([]byte, len(sum)))
+	var apiErr s3err.APIError
+	if err := checksum.Verify(sum); !errors.As(err, &apiErr) || apiErr.Code != "BadDigest" {
+		t.Errorf("expected BadDigest, got %v", err)
+	}
+}
+
+func TestMultipartChecksum(t *testing.T) {
+	var parts []string
+	composite := sha256.New()
+	for _, data := range []string{"part one", "part two"} {
+		sum := sha256.Sum256([]byte(data))
+		parts = append(parts, b64Sum(sum[:]))
+		composite.Write(sum[:])
+	}
+
+	checksum, err := backend.MultipartChecksum(types.ChecksumAlgorithmSha256, parts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := b64Sum(composite.Sum(nil)) + "-2"
+	if checksum.Value != expected {
+		t.Errorf("expected %v, got %v", expected, checksum.Value)
+	}
+
+	_, _, _, sha := checksum.Fields()
+	if sha == nil || *sha != expected {
+		t.Errorf("expected sha256 field to be set, got %v", sha)
+	}
+
+	part := types.CompletedPart{ChecksumSHA256: &parts[0]}
+	if backend.PartChecksum(types.ChecksumAlgorithmSha256, part) != parts[0] {
+		t.Errorf("expected part checksum %v", parts[0])
+	}
+	if backend.PartChecksum(types.ChecksumAlgorithmCrc32, part) != "" {
+		t.Errorf("expected empty crc32 part checksum")
+	}
+}
@@ -51,6 +51,15 @@ func GetStringPtr(s string) *string {
 	return &s
 }
 
+// GetString returns the string value, or an empty string if the
+// pointer is nil
+func GetString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
 func GetTimePtr(t time.Time) *time.Time {
 	return &t
 }
@@ -0,0 +1,132 @@
+// Copyright 2024 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package posix
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/pkg/xattr"
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/s3err"
+)
+
+const checksumkey = "user.checksum"
+
+// checksumReader returns a reader that writes the data read from r to
+// the md5 hash and, if the checksum has an algorithm, to the returned
+// hash of the checksum algorithm
+func checksumReader(r io.Reader, md5sum hash.Hash, checksum backend.Checksum) (io.Reader, hash.Hash, error) {
+	if checksum.Algorithm == "" {
+		return io.TeeReader(r, md5sum), nil, nil
+	}
+	h, err := backend.NewChecksumHash(checksum.Algorithm)
+	if err != nil {
+		return nil, nil, err
+	}
+	return io.TeeReader(r, io.MultiWriter(md5sum, h)), h, nil
+}
+
+// GetChecksum returns the stored checksum of the object, part or
+// upload at path, the checksum is empty if none was stored
+func GetChecksum(path string) (backend.Checksum, error) {
+	var checksum backend.Checksum
+	b, err := xattr.Get(path, checksumkey)
+	if isNoAttr(err) || errors.Is(err, fs.ErrNotExist) {
+		return checksum, nil
+	}
+	if err != nil {
+		return checksum, fmt.Errorf("get checksum: %w", err)
+	}
+	err = json.Unmarshal(b, &checksum)
+	if err != nil {
+		return checksum, fmt.Errorf("parse checksum: %w", err)
+	}
+	return checksum, nil
+}
+
+// SetChecksum stores the checksum of the object, part or upload at path
+func SetChecksum(path string, checksum backend.Checksum) error {
+	if checksum.Algorithm == "" {
+		return nil
+	}
+	b, err := json.Marshal(checksum)
+	if err != nil {
+		return fmt.Errorf("marshal checksum: %w", err)
+	}
+	err = xattr.Set(path, checksumkey, b)
+	if err != nil {
+		return fmt.Errorf("set checksum: %w", err)
+	}
+	return nil
+}
+
+// partChecksum returns the checksum of an upload part request, the
+// algorithm defaults to the algorithm of the upload and a different
+// algorithm than the one the upload was created with is not allowed
+func partChecksum(upiddir string, input backend.Checksum) (backend.Checksum, error) {
+	upload, err := GetChecksum(upiddir)
+	if err != nil {
+		return backend.Checksum{}, err
+	}
+	if input.Algorithm == "" {
+		return backend.Checksum{Algorithm: upload.Algorithm}, nil
+	}
+	if upload.Algorithm != "" && upload.Algorithm != input.Algorithm {
+		return backend.Checksum{}, s3err.APIError{
+			Code: "InvalidRequest",
+			Description: fmt.Sprintf("Checksum Type mismatch occurred, expected checksum Type: %v, actual checksum Type: %v",
+				strings.ToLower(string(upload.Algorithm)), strings.ToLower(string(input.Algorithm))),
+			HTTPStatusCode: http.StatusBadRequest,
+		}
+	}
+	return input, nil
+}
+
+// MultipartChecksum verifies the part checksums of a completed multipart
+// upload and returns the composite checksum of the object, the checksum
+// is empty if the upload was created without a checksum algorithm
+func MultipartChecksum(upiddir string, parts []types.CompletedPart) (backend.Checksum, error) {
+	upload, err := GetChecksum(upiddir)
+	if err != nil || upload.Algorithm == "" {
+		return backend.Checksum{}, err
+	}
+
+	sums := make([]string, 0, len(parts))
+	for _, part := range parts {
+		checksum, err := GetChecksum(filepath.Join(upiddir, fmt.Sprintf("%v", *part.PartNumber)))
+		if err != nil {
+			return backend.Checksum{}, err
+		}
+		if checksum.Algorithm != upload.Algorithm || checksum.Value == "" {
+			return backend.Checksum{}, s3err.GetAPIError(s3err.ErrInvalidPart)
+		}
+		value := backend.PartChecksum(upload.Algorithm, part)
+		if value != "" && value != checksum.Value {
+			return backend.Checksum{}, s3err.GetAPIError(s3err.ErrInvalidPart)
+		}
+		sums = append(sums, checksum.Value)
+	}
+
+	return backend.MultipartChecksum(upload.Algorithm, sums)
+}
@@ -0,0 +1,638 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package posix
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/pkg/xattr"
+	"github.com/versity/versitygw/auth"
+	"github.com/versity/versitygw/backend/sse"
+	"github.com/versity/versitygw/s3err"
+	"github.com/versity/versitygw/s3response"
+)
+
+// staleTmpAge is how long an unmodified temp file is left in the
+// bucket temp dir before the lifecycle scan removes it. These are
+// left behind if the gateway exits during an upload.
+const staleTmpAge = 24 * time.Hour
+
+// WithLifecycleScan starts a background scan at the given interval
+// that applies the bucket lifecycle rules and removes stale temp files
+func WithLifecycleScan(interval time.Duration) Option {
+	return func(p *Posix) { p.lifecycleInterval = interval }
+}
+
+func (p *Posix) PutBucketLifecycleConfiguration(_ context.Context, bucket string, config s3response.LifecycleConfiguration) error {
+	_, err := os.Stat(bucket)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+	if err != nil {
+		return fmt.Errorf("stat bucket: %w", err)
+	}
+
+	err = validateLifecycleConfig(config)
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("marshal lifecycle config: %w", err)
+	}
+
+	err = xattr.Set(bucket, lifecyclekey, b)
+	if err != nil {
+		return fmt.Errorf("set lifecycle config: %w", err)
+	}
+
+	return nil
+}
+
+func (p *Posix) GetBucketLifecycleConfiguration(_ context.Context, bucket string) (s3response.LifecycleConfiguration, error) {
+	_, err := os.Stat(bucket)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s3response.LifecycleConfiguration{}, s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+	if err != nil {
+		return s3response.LifecycleConfiguration{}, fmt.Errorf("stat bucket: %w", err)
+	}
+
+	config, err := getLifecycleConfig(bucket)
+	if err != nil {
+		return s3response.LifecycleConfiguration{}, err
+	}
+	if config == nil {
+		return s3response.LifecycleConfiguration{}, s3err.GetAPIError(s3err.ErrNoSuchLifecycleConfiguration)
+	}
+
+	return *config, nil
+}
+
+func (p *Posix) DeleteBucketLifecycle(_ context.Context, bucket string) error {
+	_, err := os.Stat(bucket)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+	if err != nil {
+		return fmt.Errorf("stat bucket: %w", err)
+	}
+
+	err = xattr.Remove(bucket, lifecyclekey)
+	if err != nil && !isNoAttr(err) {
+		return fmt.Errorf("remove lifecycle config: %w", err)
+	}
+
+	return nil
+}
+
+// getLifecycleConfig returns the bucket lifecycle configuration, or nil
+// if the bucket does not have one
+func getLifecycleConfig(bucket string) (*s3response.LifecycleConfiguration, error) {
+	b, err := xattr.Get(bucket, lifecyclekey)
+	if isNoAttr(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get lifecycle config: %w", err)
+	}
+
+	var config s3response.LifecycleConfiguration
+	err = json.Unmarshal(b, &config)
+	if err != nil {
+		return nil, fmt.Errorf("parse lifecycle config: %w", err)
+	}
+
+	return &config, nil
+}
+
+func validateLifecycleConfig(config s3response.LifecycleConfiguration) error {
+	if len(config.Rules) == 0 || len(config.Rules) > 1000 {
+		return s3err.GetAPIError(s3err.ErrMalformedXML)
+	}
+
+	ids := make(map[string]struct{}, len(config.Rules))
+	for _, rule := range config.Rules {
+		id := getString(rule.ID)
+		if len(id) > 255 {
+			return s3err.GetAPIError(s3err.ErrInvalidRequest)
+		}
+		if id != "" {
+			if _, ok := ids[id]; ok {
+				return s3err.GetAPIError(s3err.ErrInvalidRequest)
+			}
+			ids[id] = struct{}{}
+		}
+
+		switch rule.Status {
+		case types.ExpirationStatusEnabled, types.ExpirationStatusDisabled:
+		default:
+			return s3err.GetAPIError(s3err.ErrMalformedXML)
+		}
+
+		if rule.Prefix != nil && rule.Filter != nil {
+			return s3err.GetAPIError(s3err.ErrMalformedXML)
+		}
+
+		// storage class transitions don't apply to posix storage
+		if len(rule.Transitions) != 0 || len(rule.NoncurrentVersionTransitions) != 0 {
+			return s3err.GetAPIError(s3err.ErrNotImplemented)
+		}
+
+		if rule.Expiration == nil &&
+			rule.NoncurrentVersionExpiration == nil &&
+			rule.AbortIncompleteMultipartUpload == nil {
+			return s3err.GetAPIError(s3err.ErrInvalidRequest)
+		}
+
+		err := validateLifecycleFilter(rule.Filter)
+		if err != nil {
+			return err
+		}
+
+		if exp := rule.Expiration; exp != nil {
+			actions := 0
+			if exp.Days != nil {
+				if *exp.Days <= 0 {
+					return s3err.GetAPIError(s3err.ErrInvalidRequest)
+				}
+				actions++
+			}
+			if exp.Date != nil {
+				// expiration dates must be at midnight UTC
+				if !exp.Date.Equal(exp.Date.Truncate(24 * time.Hour)) {
+					return s3err.GetAPIError(s3err.ErrInvalidRequest)
+				}
+				actions++
+			}
+			if exp.ExpiredObjectDeleteMarker != nil {
+				actions++
+			}
+			if actions != 1 {
+				return s3err.GetAPIError(s3err.ErrMalformedXML)
+			}
+		}
+
+		if exp := rule.NoncurrentVersionExpiration; exp != nil {
+			if exp.NoncurrentDays == nil || *exp.NoncurrentDays <= 0 {
+				return s3err.GetAPIError(s3err.ErrInvalidRequest)
+			}
+			if exp.NewerNoncurrentVersions != nil &&
+				(*exp.NewerNoncurrentVersions <= 0 || *exp.NewerNoncurrentVersions > 100) {
+				return s3err.GetAPIError(s3err.ErrInvalidRequest)
+			}
+		}
+
+		if abort := rule.AbortIncompleteMultipartUpload; abort != nil {
+			if abort.DaysAfterInitiation == nil || *abort.DaysAfterInitiation <= 0 {
+				return s3err.GetAPIError(s3err.ErrInvalidRequest)
+			}
+			// uploads don't have tags, so these can only be
+			// filtered by prefix
+			if len(ruleTags(rule)) != 0 {
+				return s3err.GetAPIError(s3err.ErrInvalidRequest)
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateLifecycleFilter(filter *s3response.LifecycleRuleFilter) error {
+	if filter == nil {
+		return nil
+	}
+
+	set := 0
+	if filter.Prefix != nil {
+		set++
+	}
+	if filter.Tag != nil {
+		set++
+	}
+	if filter.ObjectSizeGreaterThan != nil {
+		set++
+	}
+	if filter.ObjectSizeLessThan != nil {
+		set++
+	}
+	if filter.And != nil {
+		set++
+	}
+	if set > 1 {
+		return s3err.GetAPIError(s3err.ErrMalformedXML)
+	}
+
+	return nil
+}
+
+// rulePrefix returns the object name prefix the rule applies to
+func rulePrefix(rule s3response.LifecycleRule) string {
+	switch {
+	case rule.Prefix != nil:
+		return *rule.Prefix
+	case rule.Filter == nil:
+		return ""
+	case rule.Filter.Prefix != nil:
+		return *rule.Filter.Prefix
+	case rule.Filter.And != nil:
+		return getString(rule.Filter.And.Prefix)
+	}
+	return ""
+}
+
+// ruleTags returns the object tags that must all be present for the
+// rule to apply
+func ruleTags(rule s3response.LifecycleRule) []s3response.Tag {
+	switch {
+	case rule.Filter == nil:
+		return nil
+	case rule.Filter.Tag != nil:
+		return []s3response.Tag{*rule.Filter.Tag}
+	case rule.Filter.And != nil:
+		return rule.Filter.And.Tags
+	}
+	return nil
+}
+
+// ruleSizeMatches returns true if the object size is within the rule
+// size limits
+func ruleSizeMatches(rule s3response.LifecycleRule, size int64) bool {
+	if rule.Filter == nil {
+		return true
+	}
+
+	gt, lt := rule.Filter.ObjectSizeGreaterThan, rule.Filter.ObjectSizeLessThan
+	if rule.Filter.And != nil {
+		gt, lt = rule.Filter.And.ObjectSizeGreaterThan, rule.Filter.And.ObjectSizeLessThan
+	}
+
+	if gt != nil && size <= *gt {
+		return false
+	}
+	if lt != nil && size >= *lt {
+		return false
+	}
+	return true
+}
+
+// ruleMatches returns true if the rule applies to the object. The
+// object tags are only loaded when the rule filters on tags.
+func ruleMatches(rule s3response.LifecycleRule, object string, size int64, tags func() map[string]string) bool {
+	if rule.Status != types.ExpirationStatusEnabled {
+		return false
+	}
+	if !strings.HasPrefix(object, rulePrefix(rule)) {
+		return false
+	}
+	if !ruleSizeMatches(rule, size) {
+		return false
+	}
+
+	filterTags := ruleTags(rule)
+	if len(filterTags) == 0 {
+		return true
+	}
+	objTags := tags()
+	for _, tag := range filterTags {
+		val, ok := objTags[tag.Key]
+		if !ok || val != tag.Value {
+			return false
+		}
+	}
+	return true
+}
+
+// lifecycleDue returns the time a lifecycle action becomes due. This
+// adds the number of days to the start time and rounds up to the next
+// midnight UTC.
+func lifecycleDue(start time.Time, days int32) time.Time {
+	due := start.UTC().AddDate(0, 0, int(days))
+	midnight := due.Truncate(24 * time.Hour)
+	if midnight.Equal(due) {
+		return due
+	}
+	return midnight.Add(24 * time.Hour)
+}
+
+// runLifecycle applies the bucket lifecycle rules every interval until
+// the gateway is shutdown
+func (p *Posix) runLifecycle(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stopLifecycle:
+			return
+		case <-ticker.C:
+			p.scanLifecycle(time.Now())
+		}
+	}
+}
+
+// scanLifecycle makes a single pass over all buckets applying the
+// lifecycle rules. This is best effort, objects that can not be
+// removed (for example due to object lock) are retried on the next
+// scan.
+func (p *Posix) scanLifecycle(now time.Time) {
+	entries, err := os.ReadDir(".")
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		bucket := entry.Name()
+
+		p.removeStaleTmpFiles(bucket, now)
+
+		config, err := getLifecycleConfig(bucket)
+		if err != nil || config == nil {
+			continue
+		}
+
+		var expiration, noncurrent, abort []s3response.LifecycleRule
+		for _, rule := range config.Rules {
+			if rule.Status != types.ExpirationStatusEnabled {
+				continue
+			}
+			if rule.Expiration != nil {
+				expiration = append(expiration, rule)
+			}
+			if rule.NoncurrentVersionExpiration != nil {
+				noncurrent = append(noncurrent, rule)
+			}
+			if rule.AbortIncompleteMultipartUpload != nil {
+				abort = append(abort, rule)
+			}
+		}
+
+		if len(expiration) != 0 {
+			p.expireObjects(bucket, expiration, now)
+		}
+		if len(expiration) != 0 || len(noncurrent) != 0 {
+			p.expireVersions(bucket, expiration, noncurrent, now)
+		}
+		if len(abort) != 0 {
+			p.abortUploads(bucket, abort, now)
+		}
+	}
+}
+
+// removeStaleTmpFiles removes temp files left behind by uploads that
+// never completed
+func (p *Posix) removeStaleTmpFiles(bucket string, now time.Time) {
+	dir := filepath.Join(bucket, metaTmpDir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		fi, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if now.Sub(fi.ModTime()) > staleTmpAge {
+			os.Remove(filepath.Join(dir, entry.Name()))
+		}
+	}
+}
+
+// expireObjects removes the current version of objects that have
+// reached the rule expiration
+func (p *Posix) expireObjects(bucket string, rules []s3response.LifecycleRule, now time.Time) {
+	var expired []string
+	filepath.WalkDir(bucket, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != bucket && filepath.Dir(path) == bucket &&
+				(d.Name() == metaTmpDir || d.Name() == metaVersionsDir) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		fi, err := d.Info()
+		if err != nil {
+			return nil
+		}
+
+		object, err := filepath.Rel(bucket, path)
+		if err != nil {
+			return nil
+		}
+
+		tags := func() map[string]string {
+			t, _ := p.getXattrTags(bucket, object)
+			return t
+		}
+
+		for _, rule := range rules {
+			if !ruleMatches(rule, object, sse.ObjectSize(path, fi), tags) {
+				continue
+			}
+			exp := rule.Expiration
+			switch {
+			case exp.Days != nil && !now.Before(lifecycleDue(fi.ModTime(), *exp.Days)):
+			case exp.Date != nil && !now.Before(*exp.Date):
+			default:
+				continue
+			}
+			expired = append(expired, object)
+			break
+		}
+		return nil
+	})
+
+	for _, object := range expired {
+		p.DeleteObject(context.Background(), &s3.DeleteObjectInput{
+			Bucket: &bucket,
+			Key:    &object,
+		})
+	}
+}
+
+// expireVersions removes noncurrent object versions and expired
+// delete markers from the bucket version store
+func (p *Posix) expireVersions(bucket string, expiration, noncurrent []s3response.LifecycleRule, now time.Time) {
+	entries, err := os.ReadDir(filepath.Join(bucket, metaVersionsDir))
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		b, err := xattr.Get(filepath.Join(bucket, metaVersionsDir, entry.Name()), onameAttr)
+		if err != nil {
+			continue
+		}
+		object := string(b)
+
+		versions, err := storedVersions(bucket, object)
+		if err != nil || len(versions) == 0 {
+			continue
+		}
+
+		// the successor of each version is the next most recent
+		// version, the time this was created is when the version
+		// became noncurrent
+		successor := time.Time{}
+		fi, err := os.Lstat(filepath.Join(bucket, object))
+		hasCurrent := err == nil && !fi.IsDir()
+		if hasCurrent {
+			successor = fi.ModTime()
+		}
+
+		var remove []string
+		newer := 0
+		for i, version := range versions {
+			vpath := filepath.Join(bucket, versionsDir(object), version.Name())
+			if !hasCurrent && i == 0 {
+				// the latest version is a delete marker, these
+				// expire once there are no other versions left
+				if len(versions) == 1 && isDeleteMarker(vpath) &&
+					markerExpired(expiration, object) {
+					remove = append(remove, version.Name())
+				}
+				successor = version.ModTime()
+				continue
+			}
+
+			tags := func() map[string]string {
+				t, _ := getVersionTags(vpath)
+				return t
+			}
+
+			for _, rule := range noncurrent {
+				if !ruleMatches(rule, object, sse.ObjectSize(vpath, version), tags) {
+					continue
+				}
+				exp := rule.NoncurrentVersionExpiration
+				if exp.NewerNoncurrentVersions != nil && newer < int(*exp.NewerNoncurrentVersions) {
+					continue
+				}
+				if now.Before(lifecycleDue(successor, *exp.NoncurrentDays)) {
+					continue
+				}
+				remove = append(remove, version.Name())
+				break
+			}
+
+			newer++
+			successor = version.ModTime()
+		}
+
+		for _, versionId := range remove {
+			p.deleteObjectVersion(bucket, object, versionId, false, auth.Account{})
+		}
+	}
+}
+
+// markerExpired returns true if an expiration rule for the object
+// removes expired delete markers
+func markerExpired(rules []s3response.LifecycleRule, object string) bool {
+	for _, rule := range rules {
+		exp := rule.Expiration
+		if exp.ExpiredObjectDeleteMarker == nil || !*exp.ExpiredObjectDeleteMarker {
+			continue
+		}
+		if ruleMatches(rule, object, 0, func() map[string]string { return nil }) {
+			return true
+		}
+	}
+	return false
+}
+
+// getVersionTags returns the tags of a stored object version
+func getVersionTags(path string) (map[string]string, error) {
+	b, err := xattr.Get(path, "user."+tagHdr)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make(map[string]string)
+	err = json.Unmarshal(b, &tags)
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// abortUploads aborts multipart uploads that have not completed within
+// the rule limit
+func (p *Posix) abortUploads(bucket string, rules []s3response.LifecycleRule, now time.Time) {
+	mpdir := filepath.Join(bucket, metaTmpMultipartDir)
+	objdirs, err := os.ReadDir(mpdir)
+	if err != nil {
+		return
+	}
+
+	for _, objdir := range objdirs {
+		b, err := xattr.Get(filepath.Join(mpdir, objdir.Name()), onameAttr)
+		if err != nil {
+			continue
+		}
+		object := string(b)
+
+		uploads, err := os.ReadDir(filepath.Join(mpdir, objdir.Name()))
+		if err != nil {
+			continue
+		}
+
+		for _, upload := range uploads {
+			fi, err := upload.Info()
+			if err != nil || !fi.IsDir() {
+				continue
+			}
+
+			for _, rule := range rules {
+				if !ruleMatches(rule, object, 0, func() map[string]string { return nil }) {
+					continue
+				}
+				days := *rule.AbortIncompleteMultipartUpload.DaysAfterInitiation
+				if now.Before(lifecycleDue(fi.ModTime(), days)) {
+					continue
+				}
+
+				uploadId := upload.Name()
+				p.AbortMultipartUpload(context.Background(), &s3.AbortMultipartUploadInput{
+					Bucket:   &bucket,
+					Key:      &object,
+					UploadId: &uploadId,
+				})
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,215 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package posix
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/versity/versitygw/s3response"
+)
+
+// newTestPosix returns a posix backend with a single bucket. New
+// changes the working directory to the root, this is restored when
+// the test completes.
+func newTestPosix(t *testing.T, bucket string, opts ...Option) *Posix {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	p, err := New(t.TempDir(), opts...)
+	if err != nil {
+		t.Skipf("posix backend: %v", err)
+	}
+	t.Cleanup(p.Shutdown)
+
+	err = p.CreateBucket(context.Background(), &s3.CreateBucketInput{Bucket: &bucket}, []byte("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func putObject(t *testing.T, p *Posix, bucket, object, data string) {
+	t.Helper()
+	_, err := p.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket:        &bucket,
+		Key:           &object,
+		Body:          strings.NewReader(data),
+		ContentLength: aws.Int64(int64(len(data))),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func putLifecycle(t *testing.T, p *Posix, bucket string, rules ...s3response.LifecycleRule) {
+	t.Helper()
+	err := p.PutBucketLifecycleConfiguration(context.Background(), bucket,
+		s3response.LifecycleConfiguration{Rules: rules})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func exists(t *testing.T, path string) bool {
+	t.Helper()
+	_, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return true
+}
+
+func TestLifecycleDue(t *testing.T) {
+	start := time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC)
+	due := lifecycleDue(start, 1)
+	if want := time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC); !due.Equal(want) {
+		t.Fatalf("expected %v, got %v", want, due)
+	}
+
+	midnight := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	due = lifecycleDue(midnight, 2)
+	if want := time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC); !due.Equal(want) {
+		t.Fatalf("expected %v, got %v", want, due)
+	}
+}
+
+func TestLifecycleExpiration(t *testing.T) {
+	bucket := "bucket"
+	p := newTestPosix(t, bucket)
+
+	putObject(t, p, bucket, "logs/a", "data")
+	putObject(t, p, bucket, "keep/b", "data")
+	putLifecycle(t, p, bucket, s3response.LifecycleRule{
+		Status:     types.ExpirationStatusEnabled,
+		Filter:     &s3response.LifecycleRuleFilter{Prefix: aws.String("logs/")},
+		Expiration: &types.LifecycleExpiration{Days: aws.Int32(1)},
+	})
+
+	now := time.Now()
+	p.scanLifecycle(now)
+	if !exists(t, filepath.Join(bucket, "logs/a")) {
+		t.Fatal("expected the object to be kept until the expiration")
+	}
+
+	p.scanLifecycle(now.AddDate(0, 0, 2))
+	if exists(t, filepath.Join(bucket, "logs/a")) {
+		t.Fatal("expected the expired object to be removed")
+	}
+	if !exists(t, filepath.Join(bucket, "keep/b")) {
+		t.Fatal("expected the object outside the rule prefix to be kept")
+	}
+}
+
+func TestLifecycleNoncurrentVersions(t *testing.T) {
+	bucket, object := "bucket", "obj"
+	p := newTestPosix(t, bucket)
+
+	err := p.PutBucketVersioning(context.Background(), &s3.PutBucketVersioningInput{
+		Bucket: &bucket,
+		VersioningConfiguration: &types.VersioningConfiguration{
+			Status: types.BucketVersioningStatusEnabled,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, data := range []string{"v1", "v2", "v3", "v4"} {
+		putObject(t, p, bucket, object, data)
+	}
+	putLifecycle(t, p, bucket, s3response.LifecycleRule{
+		Status: types.ExpirationStatusEnabled,
+		NoncurrentVersionExpiration: &types.NoncurrentVersionExpiration{
+			NoncurrentDays:          aws.Int32(1),
+			NewerNoncurrentVersions: aws.Int32(1),
+		},
+	})
+
+	versions := func() []types.ObjectVersion {
+		t.Helper()
+		out, err := p.ListObjectVersions(context.Background(), &s3.ListObjectVersionsInput{Bucket: &bucket})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out.Versions
+	}
+
+	now := time.Now()
+	p.scanLifecycle(now)
+	if v := versions(); len(v) != 4 {
+		t.Fatalf("expected 4 versions before the expiration, got %v", len(v))
+	}
+
+	// the current version and the newest noncurrent version are kept
+	p.scanLifecycle(now.AddDate(0, 0, 2))
+	v := versions()
+	if len(v) != 2 {
+		t.Fatalf("expected 2 versions after the expiration, got %v", len(v))
+	}
+	if !*v[0].IsLatest {
+		t.Fatal("expected the current version to be kept")
+	}
+}
+
+func TestLifecycleStaleTmpFiles(t *testing.T) {
+	bucket := "bucket"
+	p := newTestPosix(t, bucket)
+
+	dir := filepath.Join(bucket, metaTmpDir)
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	stale, recent := filepath.Join(dir, "stale"), filepath.Join(dir, "recent")
+	for _, name := range []string{stale, recent} {
+		err := os.WriteFile(name, []byte("data"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := now.Add(-2 * staleTmpAge)
+	err = os.Chtimes(stale, old, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p.scanLifecycle(now)
+	if exists(t, stale) {
+		t.Fatal("expected the stale temp file to be removed")
+	}
+	if !exists(t, recent) {
+		t.Fatal("expected the recent temp file to be kept")
+	}
+}
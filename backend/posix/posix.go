@@ -15,9 +15,12 @@
 package posix
 
 import (
+	"bufio"
 	"context"
 	"crypto/md5"
+	"crypto/rand"
 	"crypto/sha256"
+	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
@@ -30,6 +33,7 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
@@ -37,8 +41,11 @@ import (
 	"github.com/pkg/xattr"
 	"github.com/versity/versitygw/auth"
 	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/backend/sse"
 	"github.com/versity/versitygw/s3err"
 	"github.com/versity/versitygw/s3response"
+	"github.com/versity/versitygw/s3select"
+	"github.com/versity/versitygw/tracing"
 )
 
 type Posix struct {
@@ -46,6 +53,28 @@ type Posix struct {
 
 	rootfd  *os.File
 	rootdir string
+
+	// chownuid/chowngid enable setting the owner of newly created
+	// objects and directories to the requesting account uid/gid
+	chownuid bool
+	chowngid bool
+
+	// euid/egid are the effective uid/gid of the running gateway
+	euid int
+	egid int
+
+	// ownerFunc is called for each file and directory created on
+	// behalf of an account after the chown
+	ownerFunc OwnerFunc
+
+	// lifecycleInterval is how often bucket lifecycle rules are
+	// applied, 0 disables the lifecycle scan
+	lifecycleInterval time.Duration
+	stopLifecycle     chan struct{}
+
+	// sseMasterKey protects the data keys of SSE-S3 encrypted objects,
+	// SSE-S3 is not available when this is not set
+	sseMasterKey []byte
 }
 
 var _ backend.Backend = &Posix{}
@@ -53,6 +82,7 @@ var _ backend.Backend = &Posix{}
 const (
 	metaTmpDir          = ".sgwtmp"
 	metaTmpMultipartDir = metaTmpDir + "/multipart"
+	metaVersionsDir     = ".sgwversions"
 	onameAttr           = "user.objname"
 	tagHdr              = "X-Amz-Tagging"
 	metaHdr             = "X-Amz-Meta"
@@ -62,9 +92,42 @@ const (
 	aclkey              = "user.acl"
 	etagkey             = "user.etag"
 	policykey           = "user.policy"
+	versioningkey       = "user.versioning"
+	versionidkey        = "user.versionid"
+	deletemarkerkey     = "user.deletemarker"
+	objectlockkey       = "user.objectlock"
+	objectretentionkey  = "user.objectretention"
+	objectlegalholdkey  = "user.objectlegalhold"
+	lifecyclekey        = "user.lifecycle"
+	corskey             = "user.cors"
+	websitekey          = "user.website"
+	nullVersionId       = "null"
 )
 
-func New(rootdir string) (*Posix, error) {
+// Option sets various options for posix
+type Option func(p *Posix)
+
+// WithChownUID sets the owner uid of new objects to the account UserID
+func WithChownUID() Option {
+	return func(p *Posix) { p.chownuid = true }
+}
+
+// WithChownGID sets the owner gid of new objects to the account GroupID
+func WithChownGID() Option {
+	return func(p *Posix) { p.chowngid = true }
+}
+
+// OwnerFunc sets additional ownership of a file or directory created on
+// behalf of the account, such as a filesystem project ID
+type OwnerFunc func(f *os.File, acct auth.Account) error
+
+// WithOwnerFunc sets a function that is called for every object, part,
+// upload and parent directory created on behalf of an account
+func WithOwnerFunc(fn OwnerFunc) Option {
+	return func(p *Posix) { p.ownerFunc = fn }
+}
+
+func New(rootdir string, opts ...Option) (*Posix, error) {
 	err := os.Chdir(rootdir)
 	if err != nil {
 		return nil, fmt.Errorf("chdir %v: %w", rootdir, err)
@@ -81,10 +144,28 @@ func New(rootdir string) (*Posix, error) {
 		return nil, fmt.Errorf("xattr not supported on %v", rootdir)
 	}
 
-	return &Posix{rootfd: f, rootdir: rootdir}, nil
+	p := &Posix{
+		rootfd:  f,
+		rootdir: rootdir,
+		euid:    os.Geteuid(),
+		egid:    os.Getegid(),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	if p.lifecycleInterval > 0 {
+		p.stopLifecycle = make(chan struct{})
+		go p.runLifecycle(p.lifecycleInterval)
+	}
+
+	return p, nil
 }
 
 func (p *Posix) Shutdown() {
+	if p.stopLifecycle != nil {
+		close(p.stopLifecycle)
+	}
 	p.rootfd.Close()
 }
 
@@ -187,6 +268,27 @@ func (p *Posix) CreateBucket(_ context.Context, input *s3.CreateBucketInput, acl
 		return fmt.Errorf("set acl: %w", err)
 	}
 
+	if input.ObjectLockEnabledForBucket != nil && *input.ObjectLockEnabledForBucket {
+		// object lock requires versioning, so this is enabled
+		// along with the lock configuration
+		err = xattr.Set(bucket, versioningkey, []byte(types.BucketVersioningStatusEnabled))
+		if err != nil {
+			return fmt.Errorf("set versioning: %w", err)
+		}
+
+		b, err := json.Marshal(types.ObjectLockConfiguration{
+			ObjectLockEnabled: types.ObjectLockEnabledEnabled,
+		})
+		if err != nil {
+			return fmt.Errorf("marshal object lock config: %w", err)
+		}
+
+		err = xattr.Set(bucket, objectlockkey, b)
+		if err != nil {
+			return fmt.Errorf("set object lock config: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -203,12 +305,24 @@ func (p *Posix) DeleteBucket(_ context.Context, input *s3.DeleteBucketInput) err
 		return fmt.Errorf("readdir bucket: %w", err)
 	}
 
-	if len(names) == 1 && names[0].Name() == metaTmpDir {
-		// if .sgwtmp is only item in directory
-		// then clean this up before trying to remove the bucket
-		err = os.RemoveAll(filepath.Join(*input.Bucket, metaTmpDir))
+	if onlyMetaDirs(names) {
+		// a bucket with any remaining object versions or delete
+		// markers is not empty
+		versions, err := os.ReadDir(filepath.Join(*input.Bucket, metaVersionsDir))
 		if err != nil && !errors.Is(err, fs.ErrNotExist) {
-			return fmt.Errorf("remove temp dir: %w", err)
+			return fmt.Errorf("readdir versions: %w", err)
+		}
+		if len(versions) != 0 {
+			return s3err.GetAPIError(s3err.ErrBucketNotEmpty)
+		}
+
+		// if .sgwtmp and .sgwversions are the only items in directory
+		// then clean these up before trying to remove the bucket
+		for _, name := range []string{metaTmpDir, metaVersionsDir} {
+			err = os.RemoveAll(filepath.Join(*input.Bucket, name))
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return fmt.Errorf("remove %v dir: %w", name, err)
+			}
 		}
 	}
 
@@ -223,7 +337,16 @@ func (p *Posix) DeleteBucket(_ context.Context, input *s3.DeleteBucketInput) err
 	return nil
 }
 
-func (p *Posix) CreateMultipartUpload(_ context.Context, mpu *s3.CreateMultipartUploadInput) (*s3.CreateMultipartUploadOutput, error) {
+func onlyMetaDirs(names []fs.DirEntry) bool {
+	for _, name := range names {
+		if name.Name() != metaTmpDir && name.Name() != metaVersionsDir {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *Posix) CreateMultipartUpload(ctx context.Context, mpu *s3.CreateMultipartUploadInput) (*s3.CreateMultipartUploadOutput, error) {
 	if mpu.Bucket == nil {
 		return nil, s3err.GetAPIError(s3err.ErrInvalidBucketName)
 	}
@@ -248,6 +371,32 @@ func (p *Posix) CreateMultipartUpload(_ context.Context, mpu *s3.CreateMultipart
 		return nil, s3err.GetAPIError(s3err.ErrDirectoryObjectContainsData)
 	}
 
+	encReq, err := sse.ParseRequest(mpu.ServerSideEncryption,
+		mpu.SSECustomerAlgorithm, mpu.SSECustomerKey, mpu.SSECustomerKeyMD5)
+	if err != nil {
+		return nil, err
+	}
+
+	checksum, err := backend.ParseChecksum(mpu.ChecksumAlgorithm, nil, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// the data key is generated once for the upload and used to
+	// encrypt all of the parts
+	encInfo, _, err := p.newEncryption(encReq)
+	if err != nil {
+		return nil, err
+	}
+
+	// the requested object lock settings are validated now and
+	// applied to the object when the upload is completed
+	_, err = newObjectLock(bucket, mpu.ObjectLockMode,
+		mpu.ObjectLockRetainUntilDate, mpu.ObjectLockLegalHoldStatus)
+	if err != nil {
+		return nil, err
+	}
+
 	// generate random uuid for upload id
 	uploadID := uuid.New().String()
 	// hash object name for multipart container
@@ -263,6 +412,13 @@ func (p *Posix) CreateMultipartUpload(_ context.Context, mpu *s3.CreateMultipart
 		return nil, fmt.Errorf("create upload temp dir: %w", err)
 	}
 
+	err = p.Chown(filepath.Join(objdir, uploadID), AccountFromContext(ctx))
+	if err != nil {
+		os.RemoveAll(filepath.Join(objdir, uploadID))
+		os.Remove(objdir)
+		return nil, err
+	}
+
 	// set an xattr with the original object name so that we can
 	// map the hashed name back to the original object name
 	err = xattr.Set(objdir, onameAttr, []byte(object))
@@ -275,19 +431,51 @@ func (p *Posix) CreateMultipartUpload(_ context.Context, mpu *s3.CreateMultipart
 		return nil, fmt.Errorf("set name attr for upload: %w", err)
 	}
 
+	if encInfo != nil {
+		err = sse.Store(filepath.Join(objdir, uploadID), encInfo)
+		if err != nil {
+			os.RemoveAll(filepath.Join(objdir, uploadID))
+			os.Remove(objdir)
+			return nil, err
+		}
+	}
+
+	// the parts of the upload are all checksummed with the
+	// algorithm of the upload
+	err = SetChecksum(filepath.Join(objdir, uploadID), checksum)
+	if err != nil {
+		os.RemoveAll(filepath.Join(objdir, uploadID))
+		os.Remove(objdir)
+		return nil, err
+	}
+
+	err = setUploadObjectLock(filepath.Join(objdir, uploadID), mpu.ObjectLockMode,
+		mpu.ObjectLockRetainUntilDate, mpu.ObjectLockLegalHoldStatus)
+	if err != nil {
+		os.RemoveAll(filepath.Join(objdir, uploadID))
+		os.Remove(objdir)
+		return nil, err
+	}
+
 	// set user attrs
 	for k, v := range mpu.Metadata {
 		xattr.Set(filepath.Join(objdir, uploadID), "user."+k, []byte(v))
 	}
 
+	sseAlg, sseCAlg, sseCKeyMD5 := encInfo.Headers()
+
 	return &s3.CreateMultipartUploadOutput{
-		Bucket:   &bucket,
-		Key:      &object,
-		UploadId: &uploadID,
+		Bucket:               &bucket,
+		Key:                  &object,
+		UploadId:             &uploadID,
+		ServerSideEncryption: sseAlg,
+		SSECustomerAlgorithm: sseCAlg,
+		SSECustomerKeyMD5:    sseCKeyMD5,
+		ChecksumAlgorithm:    checksum.Algorithm,
 	}, nil
 }
 
-func (p *Posix) CompleteMultipartUpload(_ context.Context, input *s3.CompleteMultipartUploadInput) (*s3.CompleteMultipartUploadOutput, error) {
+func (p *Posix) CompleteMultipartUpload(ctx context.Context, input *s3.CompleteMultipartUploadInput) (*s3.CompleteMultipartUploadOutput, error) {
 	if input.Bucket == nil {
 		return nil, s3err.GetAPIError(s3err.ErrInvalidBucketName)
 	}
@@ -321,6 +509,11 @@ func (p *Posix) CompleteMultipartUpload(_ context.Context, input *s3.CompleteMul
 
 	objdir := filepath.Join(bucket, metaTmpMultipartDir, fmt.Sprintf("%x", sum))
 
+	lock, err := uploadObjectLock(bucket, filepath.Join(objdir, uploadID))
+	if err != nil {
+		return nil, err
+	}
+
 	// check all parts ok
 	last := len(parts) - 1
 	partsize := int64(0)
@@ -351,12 +544,30 @@ func (p *Posix) CompleteMultipartUpload(_ context.Context, input *s3.CompleteMul
 		}
 	}
 
+	upiddir := filepath.Join(objdir, uploadID)
+	encInfo, err := MultipartEncryption(upiddir, parts)
+	if err != nil {
+		return nil, err
+	}
+
+	checksum, err := MultipartChecksum(upiddir, parts)
+	if err != nil {
+		return nil, err
+	}
+
+	acct := AccountFromContext(ctx)
+
 	f, err := openTmpFile(filepath.Join(bucket, metaTmpDir), bucket, object, totalsize)
 	if err != nil {
 		return nil, fmt.Errorf("open temp file: %w", err)
 	}
 	defer f.cleanup()
 
+	err = p.Fchown(f.f, acct)
+	if err != nil {
+		return nil, err
+	}
+
 	for _, p := range parts {
 		pf, err := os.Open(filepath.Join(objdir, uploadID, fmt.Sprintf("%v", *p.PartNumber)))
 		if err != nil {
@@ -369,23 +580,54 @@ func (p *Posix) CompleteMultipartUpload(_ context.Context, input *s3.CompleteMul
 		}
 	}
 
+	if encInfo != nil {
+		err = sse.FStore(f.f, encInfo)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	userMetaData := make(map[string]string)
-	upiddir := filepath.Join(objdir, uploadID)
 	loadUserMetaData(upiddir, userMetaData)
 
 	objname := filepath.Join(bucket, object)
 	dir := filepath.Dir(objname)
 	if dir != "" {
-		err = mkdirAll(dir, os.FileMode(0755), bucket, object)
+		err = p.mkdirAll(dir, os.FileMode(0755), bucket, object, acct)
 		if err != nil {
 			return nil, s3err.GetAPIError(s3err.ErrExistingObjectIsDirectory)
 		}
 	}
+
+	version, err := prepareVersion(bucket, object)
+	if err != nil {
+		return nil, err
+	}
+
+	err = fsetVersionId(f.f, version.id)
+	if err != nil {
+		version.rollback()
+		return nil, err
+	}
+
 	err = f.link()
 	if err != nil {
+		version.rollback()
 		return nil, fmt.Errorf("link object in namespace: %w", err)
 	}
 
+	err = version.commit()
+	if err != nil {
+		return nil, err
+	}
+
+	err = lock.set(objname)
+	if err != nil {
+		// cleanup object if returning error
+		os.Remove(objname)
+		return nil, err
+	}
+
 	for k, v := range userMetaData {
 		err = xattr.Set(objname, "user."+k, []byte(v))
 		if err != nil {
@@ -405,16 +647,32 @@ func (p *Posix) CompleteMultipartUpload(_ context.Context, input *s3.CompleteMul
 		return nil, fmt.Errorf("set etag attr: %w", err)
 	}
 
+	err = SetChecksum(objname, checksum)
+	if err != nil {
+		// cleanup object if returning error
+		os.Remove(objname)
+		return nil, err
+	}
+
 	// cleanup tmp dirs
 	os.RemoveAll(upiddir)
 	// use Remove for objdir in case there are still other uploads
 	// for same object name outstanding
 	os.Remove(objdir)
 
+	sseAlg, _, _ := encInfo.Headers()
+	csumCRC32, csumCRC32C, csumSHA1, csumSHA256 := checksum.Fields()
+
 	return &s3.CompleteMultipartUploadOutput{
-		Bucket: &bucket,
-		ETag:   &s3MD5,
-		Key:    &object,
+		Bucket:               &bucket,
+		ETag:                 &s3MD5,
+		Key:                  &object,
+		VersionId:            responseVersionId(version.id),
+		ServerSideEncryption: sseAlg,
+		ChecksumCRC32:        csumCRC32,
+		ChecksumCRC32C:       csumCRC32C,
+		ChecksumSHA1:         csumSHA1,
+		ChecksumSHA256:       csumSHA256,
 	}, nil
 }
 
@@ -498,8 +756,9 @@ func isValidMeta(val string) bool {
 }
 
 // mkdirAll is similar to os.MkdirAll but it will return ErrObjectParentIsFile
-// when appropriate
-func mkdirAll(path string, perm os.FileMode, bucket, object string) error {
+// when appropriate, and sets the owner of any created directories to the
+// account when chown is enabled
+func (p *Posix) mkdirAll(path string, perm os.FileMode, bucket, object string, acct auth.Account) error {
 	// Fast path: if we can tell whether path is a directory or file, stop with success or error.
 	dir, err := os.Stat(path)
 	if err == nil {
@@ -522,7 +781,7 @@ func mkdirAll(path string, perm os.FileMode, bucket, object string) error {
 
 	if j > 1 {
 		// Create parent.
-		err = mkdirAll(path[:j-1], perm, bucket, object)
+		err = p.mkdirAll(path[:j-1], perm, bucket, object, acct)
 		if err != nil {
 			return err
 		}
@@ -539,7 +798,76 @@ func mkdirAll(path string, perm os.FileMode, bucket, object string) error {
 		}
 		return s3err.GetAPIError(s3err.ErrObjectParentIsFile)
 	}
-	return nil
+	return p.Chown(path, acct)
+}
+
+// AccountFromContext returns the account making the request, the zero
+// account is returned if the request context has no account
+func AccountFromContext(ctx context.Context) auth.Account {
+	acct, _ := ctx.Value("account").(auth.Account)
+	return acct
+}
+
+// getChownIDs returns the uid/gid that new files and directories created
+// on behalf of the account should be owned by, and if a chown is needed
+func (p *Posix) getChownIDs(acct auth.Account) (int, int, bool) {
+	uid := p.euid
+	gid := p.egid
+	var needsChown bool
+	// anonymous requests have no uid/gid to own the files
+	if acct.Role == auth.RoleAnonymous {
+		return uid, gid, false
+	}
+	if p.chownuid && acct.UserID != p.euid {
+		uid = acct.UserID
+		needsChown = true
+	}
+	if p.chowngid && acct.GroupID != p.egid {
+		gid = acct.GroupID
+		needsChown = true
+	}
+
+	return uid, gid, needsChown
+}
+
+// Chown sets the owner of a file or directory created on behalf of the
+// account, and calls the owner func if one is set
+func (p *Posix) Chown(path string, acct auth.Account) error {
+	uid, gid, needsChown := p.getChownIDs(acct)
+	if needsChown {
+		err := os.Lchown(path, uid, gid)
+		if err != nil {
+			return fmt.Errorf("chown %v: %w", path, err)
+		}
+	}
+
+	if p.ownerFunc == nil {
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open %v: %w", path, err)
+	}
+	defer f.Close()
+
+	return p.ownerFunc(f, acct)
+}
+
+// Fchown is the same as Chown for an open file
+func (p *Posix) Fchown(f *os.File, acct auth.Account) error {
+	uid, gid, needsChown := p.getChownIDs(acct)
+	if needsChown {
+		err := f.Chown(uid, gid)
+		if err != nil {
+			return fmt.Errorf("chown temp file: %w", err)
+		}
+	}
+
+	if p.ownerFunc == nil {
+		return nil
+	}
+	return p.ownerFunc(f, acct)
 }
 
 func (p *Posix) AbortMultipartUpload(_ context.Context, mpu *s3.AbortMultipartUploadInput) error {
@@ -803,11 +1131,21 @@ func (p *Posix) ListParts(_ context.Context, input *s3.ListPartsInput) (s3respon
 			continue
 		}
 
+		checksum, err := GetChecksum(partPath)
+		if err != nil {
+			return lpr, err
+		}
+		csumCRC32, csumCRC32C, csumSHA1, csumSHA256 := checksum.Fields()
+
 		parts = append(parts, s3response.Part{
-			PartNumber:   pn,
-			ETag:         etag,
-			LastModified: fi.ModTime().Format(backend.RFC3339TimeFormat),
-			Size:         fi.Size(),
+			PartNumber:     pn,
+			ETag:           etag,
+			LastModified:   fi.ModTime().Format(backend.RFC3339TimeFormat),
+			Size:           sse.ObjectSize(partPath, fi),
+			ChecksumCRC32:  getString(csumCRC32),
+			ChecksumCRC32C: getString(csumCRC32C),
+			ChecksumSHA1:   getString(csumSHA1),
+			ChecksumSHA256: getString(csumSHA256),
 		})
 	}
 
@@ -841,12 +1179,12 @@ func (p *Posix) ListParts(_ context.Context, input *s3.ListPartsInput) (s3respon
 	}, nil
 }
 
-func (p *Posix) UploadPart(_ context.Context, input *s3.UploadPartInput) (string, error) {
+func (p *Posix) UploadPart(ctx context.Context, input *s3.UploadPartInput) (*s3.UploadPartOutput, error) {
 	if input.Bucket == nil {
-		return "", s3err.GetAPIError(s3err.ErrInvalidBucketName)
+		return nil, s3err.GetAPIError(s3err.ErrInvalidBucketName)
 	}
 	if input.Key == nil {
-		return "", s3err.GetAPIError(s3err.ErrNoSuchKey)
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchKey)
 	}
 
 	bucket := *input.Bucket
@@ -861,10 +1199,10 @@ func (p *Posix) UploadPart(_ context.Context, input *s3.UploadPartInput) (string
 
 	_, err := os.Stat(bucket)
 	if errors.Is(err, fs.ErrNotExist) {
-		return "", s3err.GetAPIError(s3err.ErrNoSuchBucket)
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchBucket)
 	}
 	if err != nil {
-		return "", fmt.Errorf("stat bucket: %w", err)
+		return nil, fmt.Errorf("stat bucket: %w", err)
 	}
 
 	sum := sha256.Sum256([]byte(object))
@@ -872,30 +1210,87 @@ func (p *Posix) UploadPart(_ context.Context, input *s3.UploadPartInput) (string
 
 	_, err = os.Stat(filepath.Join(bucket, objdir, uploadID))
 	if errors.Is(err, fs.ErrNotExist) {
-		return "", s3err.GetAPIError(s3err.ErrNoSuchUpload)
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchUpload)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("stat uploadid: %w", err)
+	}
+
+	encReq, err := sse.ParseRequest("", input.SSECustomerAlgorithm,
+		input.SSECustomerKey, input.SSECustomerKeyMD5)
+	if err != nil {
+		return nil, err
 	}
+
+	encInfo, dataKey, err := p.uploadEncryption(filepath.Join(bucket, objdir, uploadID), encReq)
+	if err != nil {
+		return nil, err
+	}
+
+	checksum, err := backend.ParseChecksum(input.ChecksumAlgorithm, input.ChecksumCRC32,
+		input.ChecksumCRC32C, input.ChecksumSHA1, input.ChecksumSHA256)
+	if err != nil {
+		return nil, err
+	}
+	checksum, err = partChecksum(filepath.Join(bucket, objdir, uploadID), checksum)
 	if err != nil {
-		return "", fmt.Errorf("stat uploadid: %w", err)
+		return nil, err
+	}
+
+	dataLength := length
+	if encInfo != nil {
+		dataLength = sse.PaddedSize(length, sseAlign)
 	}
 
 	partPath := filepath.Join(objdir, uploadID, fmt.Sprintf("%v", *part))
 
 	f, err := openTmpFile(filepath.Join(bucket, objdir),
-		bucket, partPath, length)
+		bucket, partPath, dataLength)
+	if err != nil {
+		return nil, fmt.Errorf("open temp file: %w", err)
+	}
+
+	err = p.Fchown(f.f, AccountFromContext(ctx))
 	if err != nil {
-		return "", fmt.Errorf("open temp file: %w", err)
+		f.cleanup()
+		return nil, err
 	}
 
 	hash := md5.New()
-	tr := io.TeeReader(r, hash)
-	_, err = io.Copy(f, tr)
+	tr, checksumHash, err := checksumReader(r, hash, checksum)
 	if err != nil {
-		return "", fmt.Errorf("write part data: %w", err)
+		f.cleanup()
+		return nil, err
+	}
+	_, span := tracing.Start(ctx, "posix.WriteData")
+	err = writeData(f, tr, encInfo, dataKey, *part, sseAlign)
+	tracing.End(span, err)
+	if err != nil {
+		f.cleanup()
+		return nil, fmt.Errorf("write part data: %w", err)
+	}
+
+	if checksumHash != nil {
+		err = checksum.Verify(checksumHash.Sum(nil))
+		if err != nil {
+			f.cleanup()
+			return nil, err
+		}
+	}
+
+	if encInfo != nil {
+		err = sse.FStore(f.f, encInfo)
+		if err != nil {
+			f.cleanup()
+			return nil, err
+		}
 	}
 
+	_, span = tracing.Start(ctx, "posix.Link")
 	err = f.link()
+	tracing.End(span, err)
 	if err != nil {
-		return "", fmt.Errorf("link object in namespace: %w", err)
+		return nil, fmt.Errorf("link object in namespace: %w", err)
 	}
 
 	f.cleanup()
@@ -904,10 +1299,23 @@ func (p *Posix) UploadPart(_ context.Context, input *s3.UploadPartInput) (string
 	etag := hex.EncodeToString(dataSum)
 	xattr.Set(filepath.Join(bucket, partPath), etagkey, []byte(etag))
 
-	return etag, nil
+	err = SetChecksum(filepath.Join(bucket, partPath), checksum)
+	if err != nil {
+		return nil, err
+	}
+
+	csumCRC32, csumCRC32C, csumSHA1, csumSHA256 := checksum.Fields()
+
+	return &s3.UploadPartOutput{
+		ETag:           &etag,
+		ChecksumCRC32:  csumCRC32,
+		ChecksumCRC32C: csumCRC32C,
+		ChecksumSHA1:   csumSHA1,
+		ChecksumSHA256: csumSHA256,
+	}, nil
 }
 
-func (p *Posix) UploadPartCopy(_ context.Context, upi *s3.UploadPartCopyInput) (s3response.CopyObjectResult, error) {
+func (p *Posix) UploadPartCopy(ctx context.Context, upi *s3.UploadPartCopyInput) (s3response.CopyObjectResult, error) {
 	if upi.Bucket == nil {
 		return s3response.CopyObjectResult{}, s3err.GetAPIError(s3err.ErrInvalidBucketName)
 	}
@@ -961,6 +1369,34 @@ func (p *Posix) UploadPartCopy(_ context.Context, upi *s3.UploadPartCopyInput) (
 		return s3response.CopyObjectResult{}, fmt.Errorf("stat object: %w", err)
 	}
 
+	srcEncReq, err := sse.ParseRequest("", upi.CopySourceSSECustomerAlgorithm,
+		upi.CopySourceSSECustomerKey, upi.CopySourceSSECustomerKeyMD5)
+	if err != nil {
+		return s3response.CopyObjectResult{}, err
+	}
+
+	srcEncInfo, srcDataKey, err := p.ObjectEncryption(objPath, srcEncReq)
+	if err != nil {
+		return s3response.CopyObjectResult{}, err
+	}
+	fi = sse.FileInfo(fi, srcEncInfo)
+
+	encReq, err := sse.ParseRequest("", upi.SSECustomerAlgorithm,
+		upi.SSECustomerKey, upi.SSECustomerKeyMD5)
+	if err != nil {
+		return s3response.CopyObjectResult{}, err
+	}
+
+	encInfo, dataKey, err := p.uploadEncryption(filepath.Join(*upi.Bucket, objdir, *upi.UploadId), encReq)
+	if err != nil {
+		return s3response.CopyObjectResult{}, err
+	}
+
+	checksum, err := partChecksum(filepath.Join(*upi.Bucket, objdir, *upi.UploadId), backend.Checksum{})
+	if err != nil {
+		return s3response.CopyObjectResult{}, err
+	}
+
 	startOffset, length, err := backend.ParseRange(fi, *upi.CopySourceRange)
 	if err != nil {
 		return s3response.CopyObjectResult{}, err
@@ -974,13 +1410,23 @@ func (p *Posix) UploadPartCopy(_ context.Context, upi *s3.UploadPartCopyInput) (
 		return s3response.CopyObjectResult{}, s3err.GetAPIError(s3err.ErrInvalidRange)
 	}
 
+	dataLength := length
+	if encInfo != nil {
+		dataLength = sse.PaddedSize(length, sseAlign)
+	}
+
 	f, err := openTmpFile(filepath.Join(*upi.Bucket, objdir),
-		*upi.Bucket, partPath, length)
+		*upi.Bucket, partPath, dataLength)
 	if err != nil {
 		return s3response.CopyObjectResult{}, fmt.Errorf("open temp file: %w", err)
 	}
 	defer f.cleanup()
 
+	err = p.Fchown(f.f, AccountFromContext(ctx))
+	if err != nil {
+		return s3response.CopyObjectResult{}, err
+	}
+
 	srcf, err := os.Open(objPath)
 	if errors.Is(err, fs.ErrNotExist) {
 		return s3response.CopyObjectResult{}, s3err.GetAPIError(s3err.ErrNoSuchKey)
@@ -990,15 +1436,37 @@ func (p *Posix) UploadPartCopy(_ context.Context, upi *s3.UploadPartCopyInput) (
 	}
 	defer srcf.Close()
 
-	rdr := io.NewSectionReader(srcf, startOffset, length)
+	var rdr io.Reader = io.NewSectionReader(srcf, startOffset, length)
+	if srcEncInfo != nil {
+		pr := srcEncInfo.NewReader(srcf, srcDataKey, startOffset, length)
+		defer pr.Close()
+		rdr = pr
+	}
 	hash := md5.New()
-	tr := io.TeeReader(rdr, hash)
+	tr, checksumHash, err := checksumReader(rdr, hash, checksum)
+	if err != nil {
+		return s3response.CopyObjectResult{}, err
+	}
 
-	_, err = io.Copy(f, tr)
+	err = writeData(f, tr, encInfo, dataKey, *upi.PartNumber, sseAlign)
 	if err != nil {
 		return s3response.CopyObjectResult{}, fmt.Errorf("copy part data: %w", err)
 	}
 
+	if checksumHash != nil {
+		err = checksum.Verify(checksumHash.Sum(nil))
+		if err != nil {
+			return s3response.CopyObjectResult{}, err
+		}
+	}
+
+	if encInfo != nil {
+		err = sse.FStore(f.f, encInfo)
+		if err != nil {
+			return s3response.CopyObjectResult{}, err
+		}
+	}
+
 	err = f.link()
 	if err != nil {
 		return s3response.CopyObjectResult{}, fmt.Errorf("link object in namespace: %w", err)
@@ -1008,6 +1476,11 @@ func (p *Posix) UploadPartCopy(_ context.Context, upi *s3.UploadPartCopyInput) (
 	etag := hex.EncodeToString(dataSum)
 	xattr.Set(filepath.Join(*upi.Bucket, partPath), etagkey, []byte(etag))
 
+	err = SetChecksum(filepath.Join(*upi.Bucket, partPath), checksum)
+	if err != nil {
+		return s3response.CopyObjectResult{}, err
+	}
+
 	fi, err = os.Stat(filepath.Join(*upi.Bucket, partPath))
 	if err != nil {
 		return s3response.CopyObjectResult{}, fmt.Errorf("stat part path: %w", err)
@@ -1019,33 +1492,35 @@ func (p *Posix) UploadPartCopy(_ context.Context, upi *s3.UploadPartCopyInput) (
 	}, nil
 }
 
-func (p *Posix) PutObject(ctx context.Context, po *s3.PutObjectInput) (string, error) {
+func (p *Posix) PutObject(ctx context.Context, po *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
 	if po.Bucket == nil {
-		return "", s3err.GetAPIError(s3err.ErrInvalidBucketName)
+		return nil, s3err.GetAPIError(s3err.ErrInvalidBucketName)
 	}
 	if po.Key == nil {
-		return "", s3err.GetAPIError(s3err.ErrNoSuchKey)
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchKey)
 	}
 
 	tagsStr := getString(po.Tagging)
 	tags := make(map[string]string)
 	_, err := os.Stat(*po.Bucket)
 	if errors.Is(err, fs.ErrNotExist) {
-		return "", s3err.GetAPIError(s3err.ErrNoSuchBucket)
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchBucket)
 	}
 	if err != nil {
-		return "", fmt.Errorf("stat bucket: %w", err)
+		return nil, fmt.Errorf("stat bucket: %w", err)
 	}
 
+	acct := AccountFromContext(ctx)
+
 	if tagsStr != "" {
 		tagParts := strings.Split(tagsStr, "&")
 		for _, prt := range tagParts {
 			p := strings.Split(prt, "=")
 			if len(p) != 2 {
-				return "", s3err.GetAPIError(s3err.ErrInvalidTag)
+				return nil, s3err.GetAPIError(s3err.ErrInvalidTag)
 			}
 			if len(p[0]) > 128 || len(p[1]) > 256 {
-				return "", s3err.GetAPIError(s3err.ErrInvalidTag)
+				return nil, s3err.GetAPIError(s3err.ErrInvalidTag)
 			}
 			tags[p[0]] = p[1]
 		}
@@ -1053,6 +1528,24 @@ func (p *Posix) PutObject(ctx context.Context, po *s3.PutObjectInput) (string, e
 
 	name := filepath.Join(*po.Bucket, *po.Key)
 
+	lock, err := newObjectLock(*po.Bucket, po.ObjectLockMode,
+		po.ObjectLockRetainUntilDate, po.ObjectLockLegalHoldStatus)
+	if err != nil {
+		return nil, err
+	}
+
+	encReq, err := sse.ParseRequest(po.ServerSideEncryption,
+		po.SSECustomerAlgorithm, po.SSECustomerKey, po.SSECustomerKeyMD5)
+	if err != nil {
+		return nil, err
+	}
+
+	checksum, err := backend.ParseChecksum(po.ChecksumAlgorithm, po.ChecksumCRC32,
+		po.ChecksumCRC32C, po.ChecksumSHA1, po.ChecksumSHA256)
+	if err != nil {
+		return nil, err
+	}
+
 	contentLength := int64(0)
 	if po.ContentLength != nil {
 		contentLength = *po.ContentLength
@@ -1063,12 +1556,12 @@ func (p *Posix) PutObject(ctx context.Context, po *s3.PutObjectInput) (string, e
 			// posix directories can't contain data, send error
 			// if reuests has a data payload associated with a
 			// directory object
-			return "", s3err.GetAPIError(s3err.ErrDirectoryObjectContainsData)
+			return nil, s3err.GetAPIError(s3err.ErrDirectoryObjectContainsData)
 		}
 
-		err = mkdirAll(name, os.FileMode(0755), *po.Bucket, *po.Key)
+		err = p.mkdirAll(name, os.FileMode(0755), *po.Bucket, *po.Key, acct)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 
 		for k, v := range po.Metadata {
@@ -1078,39 +1571,102 @@ func (p *Posix) PutObject(ctx context.Context, po *s3.PutObjectInput) (string, e
 		// set etag attribute to signify this dir was specifically put
 		xattr.Set(name, etagkey, []byte(emptyMD5))
 
-		return emptyMD5, nil
+		etag := emptyMD5
+		return &s3.PutObjectOutput{ETag: &etag}, nil
 	}
 
 	// object is file
 	d, err := os.Stat(name)
 	if err == nil && d.IsDir() {
-		return "", s3err.GetAPIError(s3err.ErrExistingObjectIsDirectory)
+		return nil, s3err.GetAPIError(s3err.ErrExistingObjectIsDirectory)
+	}
+
+	encInfo, dataKey, err := p.newEncryption(encReq)
+	if err != nil {
+		return nil, err
+	}
+
+	dataLength := contentLength
+	if encInfo != nil {
+		dataLength = sse.EncryptedSize(contentLength)
 	}
 
 	f, err := openTmpFile(filepath.Join(*po.Bucket, metaTmpDir),
-		*po.Bucket, *po.Key, contentLength)
+		*po.Bucket, *po.Key, dataLength)
 	if err != nil {
-		return "", fmt.Errorf("open temp file: %w", err)
+		return nil, fmt.Errorf("open temp file: %w", err)
 	}
 	defer f.cleanup()
 
+	err = p.Fchown(f.f, acct)
+	if err != nil {
+		return nil, err
+	}
+
 	hash := md5.New()
-	rdr := io.TeeReader(po.Body, hash)
-	_, err = io.Copy(f, rdr)
+	rdr, checksumHash, err := checksumReader(po.Body, hash, checksum)
 	if err != nil {
-		return "", fmt.Errorf("write object data: %w", err)
+		return nil, err
+	}
+	_, span := tracing.Start(ctx, "posix.WriteData")
+	err = writeData(f, rdr, encInfo, dataKey, 0, 1)
+	tracing.End(span, err)
+	if err != nil {
+		return nil, fmt.Errorf("write object data: %w", err)
+	}
+
+	if checksumHash != nil {
+		// the object is not created if the data does not match
+		// the checksum of the request
+		err = checksum.Verify(checksumHash.Sum(nil))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if encInfo != nil {
+		// set the encryption info before the object is visible so that
+		// the encrypted data is never mistaken for plaintext
+		err = sse.FStore(f.f, encInfo)
+		if err != nil {
+			return nil, err
+		}
 	}
 	dir := filepath.Dir(name)
 	if dir != "" {
-		err = mkdirAll(dir, os.FileMode(0755), *po.Bucket, *po.Key)
+		err = p.mkdirAll(dir, os.FileMode(0755), *po.Bucket, *po.Key, acct)
 		if err != nil {
-			return "", s3err.GetAPIError(s3err.ErrExistingObjectIsDirectory)
+			return nil, s3err.GetAPIError(s3err.ErrExistingObjectIsDirectory)
 		}
 	}
 
+	version, err := prepareVersion(*po.Bucket, *po.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	err = fsetVersionId(f.f, version.id)
+	if err != nil {
+		version.rollback()
+		return nil, err
+	}
+
+	_, span = tracing.Start(ctx, "posix.Link")
 	err = f.link()
+	tracing.End(span, err)
 	if err != nil {
-		return "", s3err.GetAPIError(s3err.ErrExistingObjectIsDirectory)
+		version.rollback()
+		return nil, s3err.GetAPIError(s3err.ErrExistingObjectIsDirectory)
+	}
+
+	err = version.commit()
+	if err != nil {
+		return nil, err
+	}
+
+	err = lock.set(name)
+	if err != nil {
+		return nil, err
 	}
 
 	for k, v := range po.Metadata {
@@ -1120,7 +1676,7 @@ func (p *Posix) PutObject(ctx context.Context, po *s3.PutObjectInput) (string, e
 	if tagsStr != "" {
 		err := p.PutObjectTagging(ctx, *po.Bucket, *po.Key, tags)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 	}
 
@@ -1128,37 +1684,168 @@ func (p *Posix) PutObject(ctx context.Context, po *s3.PutObjectInput) (string, e
 	etag := hex.EncodeToString(dataSum[:])
 	xattr.Set(name, etagkey, []byte(etag))
 
-	return etag, nil
+	err = SetChecksum(name, checksum)
+	if err != nil {
+		return nil, err
+	}
+
+	sseAlg, sseCAlg, sseCKeyMD5 := encInfo.Headers()
+	csumCRC32, csumCRC32C, csumSHA1, csumSHA256 := checksum.Fields()
+
+	return &s3.PutObjectOutput{
+		ETag:                 &etag,
+		VersionId:            responseVersionId(version.id),
+		ServerSideEncryption: sseAlg,
+		SSECustomerAlgorithm: sseCAlg,
+		SSECustomerKeyMD5:    sseCKeyMD5,
+		ChecksumCRC32:        csumCRC32,
+		ChecksumCRC32C:       csumCRC32C,
+		ChecksumSHA1:         csumSHA1,
+		ChecksumSHA256:       csumSHA256,
+	}, nil
 }
 
-func (p *Posix) DeleteObject(_ context.Context, input *s3.DeleteObjectInput) error {
+func (p *Posix) DeleteObject(ctx context.Context, input *s3.DeleteObjectInput) (*s3.DeleteObjectOutput, error) {
 	if input.Bucket == nil {
-		return s3err.GetAPIError(s3err.ErrInvalidBucketName)
+		return nil, s3err.GetAPIError(s3err.ErrInvalidBucketName)
 	}
 	if input.Key == nil {
-		return s3err.GetAPIError(s3err.ErrNoSuchKey)
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchKey)
 	}
 
 	bucket := *input.Bucket
 	object := *input.Key
+	versionId := getString(input.VersionId)
+	bypass := input.BypassGovernanceRetention != nil && *input.BypassGovernanceRetention
 
 	_, err := os.Stat(bucket)
 	if errors.Is(err, fs.ErrNotExist) {
-		return s3err.GetAPIError(s3err.ErrNoSuchBucket)
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchBucket)
 	}
 	if err != nil {
-		return fmt.Errorf("stat bucket: %w", err)
+		return nil, fmt.Errorf("stat bucket: %w", err)
 	}
 
-	err = os.Remove(filepath.Join(bucket, object))
-	if errors.Is(err, fs.ErrNotExist) {
-		return s3err.GetAPIError(s3err.ErrNoSuchKey)
+	if versionId != "" {
+		return p.deleteObjectVersion(bucket, object, versionId, bypass, AccountFromContext(ctx))
+	}
+
+	status, err := getVersioning(bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	if status == "" || strings.HasSuffix(object, "/") {
+		// unversioned buckets and directory objects are removed
+		// from the namespace directly
+		err = checkObjectLock(filepath.Join(bucket, object), bypass)
+		if err != nil {
+			return nil, err
+		}
+		err = os.Remove(filepath.Join(bucket, object))
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, s3err.GetAPIError(s3err.ErrNoSuchKey)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("delete object: %w", err)
+		}
+
+		return &s3.DeleteObjectOutput{}, p.removeParents(bucket, object)
+	}
+
+	// deleting an object without a version in a versioned bucket
+	// moves the current version into the version store and adds
+	// a delete marker as the new latest version
+	markerId := genVersionId()
+	if status == types.BucketVersioningStatusSuspended {
+		// the delete marker becomes the null version
+		markerId = nullVersionId
+		err = removeNullVersion(bucket, object, bypass)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = archiveVersion(bucket, object)
+	if err != nil {
+		return nil, err
+	}
+
+	err = putDeleteMarker(bucket, object, markerId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.removeParents(bucket, object)
+	if err != nil {
+		return nil, err
+	}
+
+	deleteMarker := true
+	return &s3.DeleteObjectOutput{
+		DeleteMarker: &deleteMarker,
+		VersionId:    &markerId,
+	}, nil
+}
+
+func (p *Posix) deleteObjectVersion(bucket, object, versionId string, bypassGovernance bool, acct auth.Account) (*s3.DeleteObjectOutput, error) {
+	if !isValidVersionId(versionId) {
+		return nil, s3err.GetAPIError(s3err.ErrInvalidVersionId)
+	}
+
+	out := &s3.DeleteObjectOutput{VersionId: &versionId}
+
+	objPath := filepath.Join(bucket, object)
+	_, err := os.Lstat(objPath)
+	if err == nil && getVersionId(objPath) == versionId {
+		err = checkObjectLock(objPath, bypassGovernance)
+		if err != nil {
+			return nil, err
+		}
+		err = os.Remove(objPath)
+		if err != nil {
+			return nil, fmt.Errorf("delete object: %w", err)
+		}
+	} else {
+		vpath := filepath.Join(bucket, versionsDir(object), versionId)
+		_, err = os.Lstat(vpath)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, s3err.GetAPIError(s3err.ErrNoSuchVersion)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("stat version: %w", err)
+		}
+
+		if isDeleteMarker(vpath) {
+			deleteMarker := true
+			out.DeleteMarker = &deleteMarker
+		}
+
+		err = checkObjectLock(vpath, bypassGovernance)
+		if err != nil {
+			return nil, err
+		}
+
+		err = os.Remove(vpath)
+		if err != nil {
+			return nil, fmt.Errorf("delete version: %w", err)
+		}
 	}
+
+	// if there is no longer a current version of the object, the next
+	// most recent version (if any) takes its place
+	restored, err := p.restoreLatestVersion(bucket, object, acct)
 	if err != nil {
-		return fmt.Errorf("delete object: %w", err)
+		return nil, err
+	}
+	if !restored {
+		err = p.removeParents(bucket, object)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return p.removeParents(bucket, object)
+	return out, nil
 }
 
 func (p *Posix) removeParents(bucket, object string) error {
@@ -1200,12 +1887,24 @@ func (p *Posix) DeleteObjects(ctx context.Context, input *s3.DeleteObjectsInput)
 	delResult, errs := []types.DeletedObject{}, []types.Error{}
 	for _, obj := range input.Delete.Objects {
 		//TODO: Make the delete operation concurrent
-		err := p.DeleteObject(ctx, &s3.DeleteObjectInput{
-			Bucket: input.Bucket,
-			Key:    obj.Key,
+		res, err := p.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket:                    input.Bucket,
+			Key:                       obj.Key,
+			VersionId:                 obj.VersionId,
+			BypassGovernanceRetention: input.BypassGovernanceRetention,
 		})
 		if err == nil {
-			delResult = append(delResult, types.DeletedObject{Key: obj.Key})
+			delObj := types.DeletedObject{
+				Key:       obj.Key,
+				VersionId: obj.VersionId,
+			}
+			if res.DeleteMarker != nil && *res.DeleteMarker {
+				delObj.DeleteMarker = res.DeleteMarker
+				if getString(obj.VersionId) == "" {
+					delObj.DeleteMarkerVersionId = res.VersionId
+				}
+			}
+			delResult = append(delResult, delObj)
 		} else {
 			serr, ok := err.(s3err.APIError)
 			if ok {
@@ -1250,7 +1949,11 @@ func (p *Posix) GetObject(_ context.Context, input *s3.GetObjectInput, writer io
 		return nil, fmt.Errorf("stat bucket: %w", err)
 	}
 
-	object := *input.Key
+	object, err := objectVersionPath(bucket, *input.Key, getString(input.VersionId))
+	if err != nil {
+		return nil, err
+	}
+
 	objPath := filepath.Join(bucket, object)
 	fi, err := os.Stat(objPath)
 	if errors.Is(err, fs.ErrNotExist) {
@@ -1260,6 +1963,23 @@ func (p *Posix) GetObject(_ context.Context, input *s3.GetObjectInput, writer io
 		return nil, fmt.Errorf("stat object: %w", err)
 	}
 
+	versionId, err := storedVersionId(bucket, objPath)
+	if err != nil {
+		return nil, err
+	}
+
+	encReq, err := sse.ParseRequest("", input.SSECustomerAlgorithm,
+		input.SSECustomerKey, input.SSECustomerKeyMD5)
+	if err != nil {
+		return nil, err
+	}
+
+	encInfo, dataKey, err := p.ObjectEncryption(objPath, encReq)
+	if err != nil {
+		return nil, err
+	}
+	fi = sse.FileInfo(fi, encInfo)
+
 	acceptRange := *input.Range
 	startOffset, length, err := backend.ParseRange(fi, acceptRange)
 	if err != nil {
@@ -1314,6 +2034,7 @@ func (p *Posix) GetObject(_ context.Context, input *s3.GetObjectInput, writer io
 			Metadata:        userMetaData,
 			TagCount:        &tagCount,
 			ContentRange:    &contentRange,
+			VersionId:       versionId,
 		}, nil
 	}
 
@@ -1326,8 +2047,7 @@ func (p *Posix) GetObject(_ context.Context, input *s3.GetObjectInput, writer io
 	}
 	defer f.Close()
 
-	rdr := io.NewSectionReader(f, startOffset, length)
-	_, err = io.Copy(writer, rdr)
+	err = readData(writer, f, encInfo, dataKey, startOffset, length)
 	if err != nil {
 		return nil, fmt.Errorf("copy data: %w", err)
 	}
@@ -1349,16 +2069,37 @@ func (p *Posix) GetObject(_ context.Context, input *s3.GetObjectInput, writer io
 
 	tagCount := int32(len(tags))
 
+	// the checksum is of the full object, so it is not returned
+	// for range requests
+	var checksum backend.Checksum
+	if input.ChecksumMode == types.ChecksumModeEnabled && acceptRange == "" {
+		checksum, err = GetChecksum(objPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sseAlg, sseCAlg, sseCKeyMD5 := encInfo.Headers()
+	csumCRC32, csumCRC32C, csumSHA1, csumSHA256 := checksum.Fields()
+
 	return &s3.GetObjectOutput{
-		AcceptRanges:    &acceptRange,
-		ContentLength:   &length,
-		ContentEncoding: &contentEncoding,
-		ContentType:     &contentType,
-		ETag:            &etag,
-		LastModified:    backend.GetTimePtr(fi.ModTime()),
-		Metadata:        userMetaData,
-		TagCount:        &tagCount,
-		ContentRange:    &contentRange,
+		AcceptRanges:         &acceptRange,
+		ContentLength:        &length,
+		ContentEncoding:      &contentEncoding,
+		ContentType:          &contentType,
+		ETag:                 &etag,
+		LastModified:         backend.GetTimePtr(fi.ModTime()),
+		Metadata:             userMetaData,
+		TagCount:             &tagCount,
+		ContentRange:         &contentRange,
+		VersionId:            versionId,
+		ServerSideEncryption: sseAlg,
+		SSECustomerAlgorithm: sseCAlg,
+		SSECustomerKeyMD5:    sseCKeyMD5,
+		ChecksumCRC32:        csumCRC32,
+		ChecksumCRC32C:       csumCRC32C,
+		ChecksumSHA1:         csumSHA1,
+		ChecksumSHA256:       csumSHA256,
 	}, nil
 }
 
@@ -1380,6 +2121,11 @@ func (p *Posix) HeadObject(_ context.Context, input *s3.HeadObjectInput) (*s3.He
 		return nil, fmt.Errorf("stat bucket: %w", err)
 	}
 
+	object, err = objectVersionPath(bucket, object, getString(input.VersionId))
+	if err != nil {
+		return nil, err
+	}
+
 	objPath := filepath.Join(bucket, object)
 	fi, err := os.Stat(objPath)
 	if errors.Is(err, fs.ErrNotExist) {
@@ -1389,6 +2135,24 @@ func (p *Posix) HeadObject(_ context.Context, input *s3.HeadObjectInput) (*s3.He
 		return nil, fmt.Errorf("stat object: %w", err)
 	}
 
+	versionId, err := storedVersionId(bucket, objPath)
+	if err != nil {
+		return nil, err
+	}
+
+	encReq, err := sse.ParseRequest("", input.SSECustomerAlgorithm,
+		input.SSECustomerKey, input.SSECustomerKeyMD5)
+	if err != nil {
+		return nil, err
+	}
+
+	// SSE-C objects require the customer key for HEAD as well
+	encInfo, _, err := p.ObjectEncryption(objPath, encReq)
+	if err != nil {
+		return nil, err
+	}
+	fi = sse.FileInfo(fi, encInfo)
+
 	userMetaData := make(map[string]string)
 	contentType, contentEncoding := loadUserMetaData(objPath, userMetaData)
 
@@ -1400,16 +2164,150 @@ func (p *Posix) HeadObject(_ context.Context, input *s3.HeadObjectInput) (*s3.He
 
 	size := fi.Size()
 
-	return &s3.HeadObjectOutput{
-		ContentLength:   &size,
-		ContentType:     &contentType,
-		ContentEncoding: &contentEncoding,
-		ETag:            &etag,
-		LastModified:    backend.GetTimePtr(fi.ModTime()),
-		Metadata:        userMetaData,
+	sseAlg, sseCAlg, sseCKeyMD5 := encInfo.Headers()
+
+	out := &s3.HeadObjectOutput{
+		ContentLength:        &size,
+		ContentType:          &contentType,
+		ContentEncoding:      &contentEncoding,
+		ETag:                 &etag,
+		LastModified:         backend.GetTimePtr(fi.ModTime()),
+		Metadata:             userMetaData,
+		VersionId:            versionId,
+		ServerSideEncryption: sseAlg,
+		SSECustomerAlgorithm: sseCAlg,
+		SSECustomerKeyMD5:    sseCKeyMD5,
+	}
+
+	retention, err := getRetention(objPath)
+	if err != nil {
+		return nil, err
+	}
+	if retention != nil {
+		out.ObjectLockMode = types.ObjectLockMode(retention.Mode)
+		out.ObjectLockRetainUntilDate = retention.RetainUntilDate
+	}
+
+	out.ObjectLockLegalHoldStatus, err = getLegalHold(objPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.ChecksumMode == types.ChecksumModeEnabled {
+		checksum, err := GetChecksum(objPath)
+		if err != nil {
+			return nil, err
+		}
+		out.ChecksumCRC32, out.ChecksumCRC32C, out.ChecksumSHA1, out.ChecksumSHA256 = checksum.Fields()
+	}
+
+	return out, nil
+}
+
+func (p *Posix) GetObjectAttributes(ctx context.Context, input *s3.GetObjectAttributesInput) (*s3.GetObjectAttributesOutput, error) {
+	data, err := p.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:               input.Bucket,
+		Key:                  input.Key,
+		VersionId:            input.VersionId,
+		SSECustomerAlgorithm: input.SSECustomerAlgorithm,
+		SSECustomerKey:       input.SSECustomerKey,
+		SSECustomerKeyMD5:    input.SSECustomerKeyMD5,
+		ChecksumMode:         types.ChecksumModeEnabled,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var checksum *types.Checksum
+	if data.ChecksumCRC32 != nil || data.ChecksumCRC32C != nil ||
+		data.ChecksumSHA1 != nil || data.ChecksumSHA256 != nil {
+		checksum = &types.Checksum{
+			ChecksumCRC32:  data.ChecksumCRC32,
+			ChecksumCRC32C: data.ChecksumCRC32C,
+			ChecksumSHA1:   data.ChecksumSHA1,
+			ChecksumSHA256: data.ChecksumSHA256,
+		}
+	}
+
+	return &s3.GetObjectAttributesOutput{
+		ETag:         data.ETag,
+		Checksum:     checksum,
+		LastModified: data.LastModified,
+		ObjectSize:   data.ContentLength,
+		StorageClass: types.StorageClassStandard,
+		VersionId:    data.VersionId,
 	}, nil
 }
 
+func (p *Posix) SelectObjectContent(ctx context.Context, input *s3.SelectObjectContentInput) func(w *bufio.Writer) {
+	return func(w *bufio.Writer) {
+		f, r, size, err := p.openSelectObject(input)
+		if err != nil {
+			s3select.SendError(ctx, w, err)
+			return
+		}
+		defer f.Close()
+
+		s3select.Run(ctx, w, input, r, size)
+	}
+}
+
+// openSelectObject opens the object data of a select request, the
+// returned reader reads the plaintext of encrypted objects
+func (p *Posix) openSelectObject(input *s3.SelectObjectContentInput) (*os.File, io.ReaderAt, int64, error) {
+	if input.Bucket == nil {
+		return nil, nil, 0, s3err.GetAPIError(s3err.ErrInvalidBucketName)
+	}
+	if input.Key == nil {
+		return nil, nil, 0, s3err.GetAPIError(s3err.ErrNoSuchKey)
+	}
+
+	bucket := *input.Bucket
+	_, err := os.Stat(bucket)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, 0, s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("stat bucket: %w", err)
+	}
+
+	objPath := filepath.Join(bucket, *input.Key)
+	fi, err := os.Stat(objPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, 0, s3err.GetAPIError(s3err.ErrNoSuchKey)
+	}
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("stat object: %w", err)
+	}
+	if fi.IsDir() {
+		return nil, nil, 0, s3err.GetAPIError(s3err.ErrNoSuchKey)
+	}
+
+	encReq, err := sse.ParseRequest("", input.SSECustomerAlgorithm,
+		input.SSECustomerKey, input.SSECustomerKeyMD5)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	encInfo, dataKey, err := p.ObjectEncryption(objPath, encReq)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	f, err := os.Open(objPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, 0, s3err.GetAPIError(s3err.ErrNoSuchKey)
+	}
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("open object: %w", err)
+	}
+
+	if encInfo != nil {
+		return f, encInfo.ReaderAt(f, dataKey), encInfo.Size, nil
+	}
+	return f, f, fi.Size(), nil
+}
+
 func (p *Posix) CopyObject(ctx context.Context, input *s3.CopyObjectInput) (*s3.CopyObjectOutput, error) {
 	if input.Bucket == nil {
 		return nil, s3err.GetAPIError(s3err.ErrInvalidBucketName)
@@ -1427,6 +2325,7 @@ func (p *Posix) CopyObject(ctx context.Context, input *s3.CopyObjectInput) (*s3.
 	if !ok {
 		return nil, s3err.GetAPIError(s3err.ErrInvalidCopySource)
 	}
+	srcObject, srcVersionId, _ := strings.Cut(srcObject, "?versionId=")
 	dstBucket := *input.Bucket
 	dstObject := *input.Key
 
@@ -1446,6 +2345,11 @@ func (p *Posix) CopyObject(ctx context.Context, input *s3.CopyObjectInput) (*s3.
 		return nil, fmt.Errorf("stat bucket: %w", err)
 	}
 
+	srcObject, err = objectVersionPath(srcBucket, srcObject, srcVersionId)
+	if err != nil {
+		return nil, err
+	}
+
 	objPath := filepath.Join(srcBucket, srcObject)
 	f, err := os.Open(objPath)
 	if errors.Is(err, fs.ErrNotExist) {
@@ -1461,12 +2365,27 @@ func (p *Posix) CopyObject(ctx context.Context, input *s3.CopyObjectInput) (*s3.
 		return nil, fmt.Errorf("stat object: %w", err)
 	}
 
+	srcEncReq, err := sse.ParseRequest("", input.CopySourceSSECustomerAlgorithm,
+		input.CopySourceSSECustomerKey, input.CopySourceSSECustomerKeyMD5)
+	if err != nil {
+		return nil, err
+	}
+
+	srcEncInfo, srcDataKey, err := p.ObjectEncryption(objPath, srcEncReq)
+	if err != nil {
+		return nil, err
+	}
+
 	meta := make(map[string]string)
 	loadUserMetaData(objPath, meta)
 
+	// copying an object onto itself is allowed to change the encryption
+	changeEncryption := input.ServerSideEncryption != "" ||
+		input.SSECustomerAlgorithm != nil
+
 	dstObjdPath := filepath.Join(dstBucket, dstObject)
 	if dstObjdPath == objPath {
-		if compareUserMetadata(meta, input.Metadata) {
+		if compareUserMetadata(meta, input.Metadata) && !changeEncryption {
 			return &s3.CopyObjectOutput{}, s3err.GetAPIError(s3err.ErrInvalidCopyDest)
 		} else {
 			for key := range meta {
@@ -1479,14 +2398,25 @@ func (p *Posix) CopyObject(ctx context.Context, input *s3.CopyObjectInput) (*s3.
 	}
 
 	contentLength := fInfo.Size()
+	var body io.Reader = f
+	if srcEncInfo != nil {
+		contentLength = srcEncInfo.Size
+		rdr := srcEncInfo.NewReader(f, srcDataKey, 0, contentLength)
+		defer rdr.Close()
+		body = rdr
+	}
 
-	etag, err := p.PutObject(ctx,
+	res, err := p.PutObject(ctx,
 		&s3.PutObjectInput{
-			Bucket:        &dstBucket,
-			Key:           &dstObject,
-			Body:          f,
-			ContentLength: &contentLength,
-			Metadata:      meta,
+			Bucket:               &dstBucket,
+			Key:                  &dstObject,
+			Body:                 body,
+			ContentLength:        &contentLength,
+			Metadata:             meta,
+			ServerSideEncryption: input.ServerSideEncryption,
+			SSECustomerAlgorithm: input.SSECustomerAlgorithm,
+			SSECustomerKey:       input.SSECustomerKey,
+			SSECustomerKeyMD5:    input.SSECustomerKeyMD5,
 		})
 	if err != nil {
 		return nil, err
@@ -1497,11 +2427,21 @@ func (p *Posix) CopyObject(ctx context.Context, input *s3.CopyObjectInput) (*s3.
 		return nil, fmt.Errorf("stat dst object: %w", err)
 	}
 
+	var copySourceVersionId *string
+	if srcVersionId != "" {
+		copySourceVersionId = &srcVersionId
+	}
+
 	return &s3.CopyObjectOutput{
 		CopyObjectResult: &types.CopyObjectResult{
-			ETag:         &etag,
+			ETag:         res.ETag,
 			LastModified: backend.GetTimePtr(fi.ModTime()),
 		},
+		CopySourceVersionId:  copySourceVersionId,
+		VersionId:            res.VersionId,
+		ServerSideEncryption: res.ServerSideEncryption,
+		SSECustomerAlgorithm: res.SSECustomerAlgorithm,
+		SSECustomerKeyMD5:    res.SSECustomerKeyMD5,
 	}, nil
 }
 
@@ -1537,7 +2477,7 @@ func (p *Posix) ListObjects(_ context.Context, input *s3.ListObjectsInput) (*s3.
 
 	fileSystem := os.DirFS(bucket)
 	results, err := backend.Walk(fileSystem, prefix, delim, marker, maxkeys,
-		fileToObj(bucket), []string{metaTmpDir})
+		fileToObj(bucket), []string{metaTmpDir, metaVersionsDir})
 	if err != nil {
 		return nil, fmt.Errorf("walk %v: %w", bucket, err)
 	}
@@ -1607,7 +2547,7 @@ func fileToObj(bucket string) backend.GetObjFunc {
 			return types.Object{}, fmt.Errorf("get fileinfo: %w", err)
 		}
 
-		size := fi.Size()
+		size := sse.ObjectSize(filepath.Join(bucket, path), fi)
 
 		return types.Object{
 			ETag:         &etag,
@@ -1658,7 +2598,7 @@ func (p *Posix) ListObjectsV2(_ context.Context, input *s3.ListObjectsV2Input) (
 
 	fileSystem := os.DirFS(bucket)
 	results, err := backend.Walk(fileSystem, prefix, delim, marker, maxkeys,
-		fileToObj(bucket), []string{metaTmpDir})
+		fileToObj(bucket), []string{metaTmpDir, metaVersionsDir})
 	if err != nil {
 		return nil, fmt.Errorf("walk %v: %w", bucket, err)
 	}
@@ -1679,7 +2619,1282 @@ func (p *Posix) ListObjectsV2(_ context.Context, input *s3.ListObjectsV2Input) (
 	}, nil
 }
 
-func (p *Posix) PutBucketAcl(_ context.Context, bucket string, data []byte) error {
+func (p *Posix) PutBucketVersioning(_ context.Context, input *s3.PutBucketVersioningInput) error {
+	if input.Bucket == nil {
+		return s3err.GetAPIError(s3err.ErrInvalidBucketName)
+	}
+	if input.VersioningConfiguration == nil {
+		return s3err.GetAPIError(s3err.ErrIllegalVersioningConfiguration)
+	}
+
+	bucket := *input.Bucket
+	status := input.VersioningConfiguration.Status
+
+	_, err := os.Stat(bucket)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+	if err != nil {
+		return fmt.Errorf("stat bucket: %w", err)
+	}
+
+	// once versioning has been enabled for a bucket, it can only
+	// be suspended and never returned to the unversioned state
+	switch status {
+	case types.BucketVersioningStatusEnabled, types.BucketVersioningStatusSuspended:
+	default:
+		return s3err.GetAPIError(s3err.ErrIllegalVersioningConfiguration)
+	}
+
+	// versioning can not be suspended for buckets with object lock
+	// enabled, since this would allow locked versions to be replaced
+	if status == types.BucketVersioningStatusSuspended {
+		config, err := getObjectLockConfig(bucket)
+		if err != nil {
+			return err
+		}
+		if config != nil {
+			return s3err.GetAPIError(s3err.ErrInvalidBucketState)
+		}
+	}
+
+	err = xattr.Set(bucket, versioningkey, []byte(status))
+	if err != nil {
+		return fmt.Errorf("set versioning: %w", err)
+	}
+
+	return nil
+}
+
+func (p *Posix) GetBucketVersioning(_ context.Context, bucket string) (*s3.GetBucketVersioningOutput, error) {
+	_, err := os.Stat(bucket)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("stat bucket: %w", err)
+	}
+
+	status, err := getVersioning(bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	return &s3.GetBucketVersioningOutput{
+		Status: status,
+	}, nil
+}
+
+func (p *Posix) ListObjectVersions(_ context.Context, input *s3.ListObjectVersionsInput) (s3response.ListVersionsResult, error) {
+	if input.Bucket == nil {
+		return s3response.ListVersionsResult{}, s3err.GetAPIError(s3err.ErrInvalidBucketName)
+	}
+	bucket := *input.Bucket
+	prefix := getString(input.Prefix)
+	delim := getString(input.Delimiter)
+	keyMarker := getString(input.KeyMarker)
+	versionIdMarker := getString(input.VersionIdMarker)
+	maxkeys := int32(1000)
+	if input.MaxKeys != nil {
+		maxkeys = *input.MaxKeys
+	}
+
+	_, err := os.Stat(bucket)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s3response.ListVersionsResult{}, s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+	if err != nil {
+		return s3response.ListVersionsResult{}, fmt.Errorf("stat bucket: %w", err)
+	}
+
+	result := s3response.ListVersionsResult{
+		Name:            bucket,
+		Prefix:          prefix,
+		KeyMarker:       keyMarker,
+		VersionIdMarker: versionIdMarker,
+		Delimiter:       delim,
+		MaxKeys:         maxkeys,
+	}
+
+	var count int32
+	var nextKey, nextVersionId, lastPrefix string
+	var truncated bool
+
+	err = walkVersionedKeys(bucket, prefix, delim, keyMarker, func(key string) error {
+		if delim != "" {
+			rest := strings.TrimPrefix(key, prefix)
+			if i := strings.Index(rest, delim); i >= 0 {
+				cpref := prefix + rest[:i+len(delim)]
+				if cpref == lastPrefix {
+					return nil
+				}
+				lastPrefix = cpref
+				if keyMarker != "" && cpref <= keyMarker {
+					return nil
+				}
+				if count == maxkeys {
+					truncated = true
+					return fs.SkipAll
+				}
+				result.CommonPrefixes = append(result.CommonPrefixes,
+					types.CommonPrefix{Prefix: &cpref})
+				nextKey, nextVersionId = cpref, ""
+				count++
+				return nil
+			}
+		}
+
+		versions, err := objectVersions(bucket, key)
+		if err != nil {
+			return err
+		}
+
+		pastMarker := key != keyMarker
+		for _, v := range versions {
+			if !pastMarker {
+				// skip the versions up to and including the
+				// version id marker, or all versions of the key
+				// marker if no version id marker was given
+				if versionIdMarker != "" && *v.versionId == versionIdMarker {
+					pastMarker = true
+				}
+				continue
+			}
+			if count == maxkeys {
+				truncated = true
+				return fs.SkipAll
+			}
+
+			isLatest := v.isLatest
+			if v.deleteMarker {
+				result.DeleteMarkers = append(result.DeleteMarkers,
+					types.DeleteMarkerEntry{
+						Key:          v.key,
+						VersionId:    v.versionId,
+						IsLatest:     &isLatest,
+						LastModified: v.lastModified,
+					})
+			} else {
+				result.Versions = append(result.Versions,
+					types.ObjectVersion{
+						ETag:         v.etag,
+						Key:          v.key,
+						VersionId:    v.versionId,
+						IsLatest:     &isLatest,
+						LastModified: v.lastModified,
+						Size:         v.size,
+						StorageClass: types.ObjectVersionStorageClassStandard,
+					})
+			}
+			nextKey, nextVersionId = key, *v.versionId
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		return s3response.ListVersionsResult{}, err
+	}
+
+	if truncated && count > 0 {
+		result.IsTruncated = true
+		result.NextKeyMarker = nextKey
+		result.NextVersionIdMarker = nextVersionId
+	}
+
+	return result, nil
+}
+
+// objVersion describes a single version or delete marker of an object
+type objVersion struct {
+	key          *string
+	versionId    *string
+	etag         *string
+	size         *int64
+	lastModified *time.Time
+	isLatest     bool
+	deleteMarker bool
+}
+
+// walkVersionedKeys calls fn in key order with the names of the objects
+// matching prefix, from marker on, that have either a current version or
+// versions in the bucket version store. Directories holding only keys
+// before the marker are not walked, and directories that the delimiter
+// rolls up into a common prefix are passed to fn as the common prefix
+// instead of being walked. The walk stops when fn returns fs.SkipAll.
+func walkVersionedKeys(bucket, prefix, delim, marker string, fn func(key string) error) error {
+	stored, err := storedVersionKeys(bucket, prefix, marker)
+	if err != nil {
+		return err
+	}
+
+	// merge the keys that only have versions in the version store
+	emit := func(key string) error {
+		for len(stored) > 0 && stored[0] <= key {
+			if stored[0] != key {
+				err := fn(stored[0])
+				if err != nil {
+					return err
+				}
+			}
+			stored = stored[1:]
+		}
+		return fn(key)
+	}
+
+	err = walkKeys(bucket, "", prefix, delim, marker, emit)
+	for err == nil && len(stored) > 0 {
+		err = fn(stored[0])
+		stored = stored[1:]
+	}
+	if err == fs.SkipAll {
+		return nil
+	}
+	return err
+}
+
+// walkKeys walks the objects in dir in key order, the entries are sorted
+// with the directory names ending in "/" so that the keys within each
+// directory are walked together in their place
+func walkKeys(bucket, dir, prefix, delim, marker string, emit func(key string) error) error {
+	ents, err := os.ReadDir(filepath.Join(bucket, dir))
+	if errors.Is(err, fs.ErrNotExist) && dir != "" {
+		// removed during the walk
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("readdir %q: %w", dir, err)
+	}
+
+	type entry struct {
+		key   string
+		isDir bool
+	}
+	entries := make([]entry, 0, len(ents))
+	for _, ent := range ents {
+		if ent.Name() == metaTmpDir || ent.Name() == metaVersionsDir {
+			continue
+		}
+		e := entry{key: dir + ent.Name(), isDir: ent.IsDir()}
+		if e.isDir {
+			e.key += "/"
+		}
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].key < entries[j].key
+	})
+
+	for _, e := range entries {
+		if !e.isDir {
+			if e.key < marker || !strings.HasPrefix(e.key, prefix) {
+				continue
+			}
+			err := emit(e.key)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		// only descend into directories that could match prefix
+		if !strings.HasPrefix(e.key, prefix) && !strings.HasPrefix(prefix, e.key) {
+			continue
+		}
+		// all of the keys in the directory sort before the marker
+		if e.key < marker && !strings.HasPrefix(marker, e.key) {
+			continue
+		}
+
+		if strings.HasPrefix(e.key, prefix) {
+			if delim != "" {
+				rest := strings.TrimPrefix(e.key, prefix)
+				if i := strings.Index(rest, delim); i >= 0 {
+					err := emit(prefix + rest[:i+len(delim)])
+					if err != nil {
+						return err
+					}
+					continue
+				}
+			}
+
+			// directory objects are directories with an etag
+			_, err := xattr.Get(filepath.Join(bucket, e.key), etagkey)
+			if err == nil && e.key >= marker {
+				err := emit(e.key)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		err := walkKeys(bucket, e.key, prefix, delim, marker, emit)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// storedVersionKeys returns the sorted names of the objects matching
+// prefix, from marker on, that have versions in the bucket version store
+func storedVersionKeys(bucket, prefix, marker string) ([]string, error) {
+	ents, err := os.ReadDir(filepath.Join(bucket, metaVersionsDir))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("readdir versions: %w", err)
+	}
+
+	var keys []string
+	for _, ent := range ents {
+		b, err := xattr.Get(filepath.Join(bucket, metaVersionsDir, ent.Name()), onameAttr)
+		if err != nil {
+			continue
+		}
+		key := string(b)
+		if strings.HasPrefix(key, prefix) && key >= marker {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+// objectVersions returns all of the versions and delete markers of the
+// object ordered from most recent to oldest
+func objectVersions(bucket, object string) ([]objVersion, error) {
+	var versions []objVersion
+
+	objPath := filepath.Join(bucket, object)
+	fi, err := os.Stat(objPath)
+	if err == nil {
+		v := fileVersion(object, objPath, fi)
+		v.isLatest = true
+		versions = append(versions, v)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("stat object: %w", err)
+	}
+
+	stored, err := storedVersions(bucket, object)
+	if err != nil {
+		return nil, err
+	}
+	for i, fi := range stored {
+		v := fileVersion(object,
+			filepath.Join(bucket, versionsDir(object), fi.Name()), fi)
+		v.isLatest = i == 0 && len(versions) == 0
+		versions = append(versions, v)
+	}
+
+	return versions, nil
+}
+
+func fileVersion(object, path string, fi fs.FileInfo) objVersion {
+	versionId := getVersionId(path)
+
+	v := objVersion{
+		key:          &object,
+		versionId:    &versionId,
+		lastModified: backend.GetTimePtr(fi.ModTime()),
+	}
+
+	if isDeleteMarker(path) {
+		v.deleteMarker = true
+		return v
+	}
+
+	b, _ := xattr.Get(path, etagkey)
+	etag := string(b)
+	size := sse.ObjectSize(path, fi)
+	if fi.IsDir() {
+		size = 0
+	}
+	v.etag = &etag
+	v.size = &size
+
+	return v
+}
+
+// storedVersions returns the file info of the prior versions and delete
+// markers of the object in the bucket version store, most recent first
+func storedVersions(bucket, object string) ([]fs.FileInfo, error) {
+	ents, err := os.ReadDir(filepath.Join(bucket, versionsDir(object)))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("readdir versions: %w", err)
+	}
+
+	var infos []fs.FileInfo
+	for _, ent := range ents {
+		fi, err := ent.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("stat version: %w", err)
+		}
+		infos = append(infos, fi)
+	}
+
+	sort.SliceStable(infos, func(i, j int) bool {
+		if infos[i].ModTime().Equal(infos[j].ModTime()) {
+			return infos[i].Name() > infos[j].Name()
+		}
+		return infos[i].ModTime().After(infos[j].ModTime())
+	})
+
+	return infos, nil
+}
+
+// getVersioning returns the versioning status of the bucket, an empty
+// status means versioning was never configured for the bucket
+func getVersioning(bucket string) (types.BucketVersioningStatus, error) {
+	b, err := xattr.Get(bucket, versioningkey)
+	if isNoAttr(err) {
+		return "", nil
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+	if err != nil {
+		return "", fmt.Errorf("get versioning: %w", err)
+	}
+	return types.BucketVersioningStatus(b), nil
+}
+
+// versionsDir is the directory relative to the bucket that holds the prior
+// versions and delete markers of the object. The object name is hashed
+// the same way as for multipart uploads.
+func versionsDir(object string) string {
+	return filepath.Join(metaVersionsDir,
+		fmt.Sprintf("%x", sha256.Sum256([]byte(object))))
+}
+
+// genVersionId returns a new random version id, prefixed with the current
+// time so that ids generated for an object sort in creation order
+func genVersionId() string {
+	b := make([]byte, 16)
+	binary.BigEndian.PutUint64(b, uint64(time.Now().UnixNano()))
+	rand.Read(b[8:])
+	return hex.EncodeToString(b)
+}
+
+func isValidVersionId(versionId string) bool {
+	if versionId == nullVersionId {
+		return true
+	}
+	if len(versionId) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(versionId)
+	return err == nil
+}
+
+// getVersionId returns the version id of the object at path. Objects
+// created while versioning was not enabled are the "null" version.
+func getVersionId(path string) string {
+	b, err := xattr.Get(path, versionidkey)
+	if err != nil || len(b) == 0 {
+		return nullVersionId
+	}
+	return string(b)
+}
+
+func setVersionId(path, versionId string) error {
+	if versionId == "" || versionId == nullVersionId {
+		return nil
+	}
+	err := xattr.Set(path, versionidkey, []byte(versionId))
+	if err != nil {
+		return fmt.Errorf("set version id: %w", err)
+	}
+	return nil
+}
+
+// fsetVersionId sets the version id of a new object before it is linked
+// in place, so that the object is never visible without its version
+func fsetVersionId(f *os.File, versionId string) error {
+	if versionId == "" || versionId == nullVersionId {
+		return nil
+	}
+	err := xattr.FSet(f, versionidkey, []byte(versionId))
+	if err != nil {
+		return fmt.Errorf("set version id: %w", err)
+	}
+	return nil
+}
+
+// responseVersionId returns the version id to report for a newly created
+// object version, the null version id is not reported back
+func responseVersionId(versionId string) *string {
+	if versionId == "" || versionId == nullVersionId {
+		return nil
+	}
+	return &versionId
+}
+
+// storedVersionId returns the version id of the object at path if the
+// bucket has a versioning configuration
+func storedVersionId(bucket, path string) (*string, error) {
+	status, err := getVersioning(bucket)
+	if err != nil || status == "" {
+		return nil, err
+	}
+	versionId := getVersionId(path)
+	return &versionId, nil
+}
+
+func isDeleteMarker(path string) bool {
+	_, err := xattr.Get(path, deletemarkerkey)
+	return err == nil
+}
+
+// objectVersionPath returns the path relative to the bucket of the
+// requested version of the object
+func objectVersionPath(bucket, object, versionId string) (string, error) {
+	if versionId == "" {
+		return object, nil
+	}
+	if !isValidVersionId(versionId) {
+		return "", s3err.GetAPIError(s3err.ErrInvalidVersionId)
+	}
+
+	objPath := filepath.Join(bucket, object)
+	_, err := os.Lstat(objPath)
+	if err == nil && getVersionId(objPath) == versionId {
+		return object, nil
+	}
+
+	vobj := filepath.Join(versionsDir(object), versionId)
+	vpath := filepath.Join(bucket, vobj)
+	_, err = os.Lstat(vpath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", s3err.GetAPIError(s3err.ErrNoSuchVersion)
+	}
+	if err != nil {
+		return "", fmt.Errorf("stat version: %w", err)
+	}
+	if isDeleteMarker(vpath) {
+		return "", s3err.GetAPIError(s3err.ErrMethodNotAllowed)
+	}
+
+	return vobj, nil
+}
+
+// makeVersionsDir creates the version store directory for the object
+func makeVersionsDir(bucket, object string) (string, error) {
+	dir := filepath.Join(bucket, versionsDir(object))
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return "", fmt.Errorf("create versions dir: %w", err)
+	}
+
+	// set an xattr with the original object name so that we can
+	// map the hashed name back to the original object name
+	err = xattr.Set(dir, onameAttr, []byte(object))
+	if err != nil {
+		return "", fmt.Errorf("set name attr for versions: %w", err)
+	}
+
+	return dir, nil
+}
+
+// newVersion is a new current version of an object that is about to be
+// linked in place
+type newVersion struct {
+	bucket string
+	object string
+	// id is the version id of the new object, which is empty for
+	// unversioned buckets
+	id string
+	// archived is the path in the version store of the replaced
+	// current version, if any
+	archived string
+}
+
+// prepareVersion makes way for a new current version of the object
+// according to the bucket versioning status. The current version is
+// moved into the version store, rollback moves it back if the new
+// object fails to be linked in place, and commit finishes replacing
+// the version once the new object is linked.
+func prepareVersion(bucket, object string) (*newVersion, error) {
+	status, err := getVersioning(bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &newVersion{bucket: bucket, object: object}
+	objPath := filepath.Join(bucket, object)
+
+	switch status {
+	case types.BucketVersioningStatusEnabled:
+		v.id = genVersionId()
+	case types.BucketVersioningStatusSuspended:
+		// the new object becomes the null version, replacing
+		// any existing null version of the object
+		v.id = nullVersionId
+		err = checkObjectLock(filepath.Join(bucket, versionsDir(object), nullVersionId), false)
+		if err != nil {
+			return nil, err
+		}
+		fi, err := os.Lstat(objPath)
+		if err == nil && !fi.IsDir() && getVersionId(objPath) == nullVersionId {
+			// the current null version is replaced by the link
+			return v, checkObjectLock(objPath, false)
+		}
+	default:
+		// the new object replaces the current object in unversioned
+		// buckets, so the current object must not be locked
+		return v, checkObjectLock(objPath, false)
+	}
+
+	v.archived, err = archiveVersion(bucket, object)
+	if err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// rollback moves the archived current version back in place
+func (v *newVersion) rollback() {
+	if v.archived == "" {
+		return
+	}
+	os.Rename(v.archived, filepath.Join(v.bucket, v.object))
+}
+
+// commit removes the null version from the version store once the new
+// null version is linked in place
+func (v *newVersion) commit() error {
+	if v.id != nullVersionId {
+		return nil
+	}
+
+	dir := filepath.Join(v.bucket, versionsDir(v.object))
+	err := os.Remove(filepath.Join(dir, nullVersionId))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("remove null version: %w", err)
+	}
+	// cleanup the versions dir if this was the only version
+	os.Remove(dir)
+
+	return nil
+}
+
+// archiveVersion moves the current version of the object, if any, into
+// the bucket version store and returns its path there
+func archiveVersion(bucket, object string) (string, error) {
+	objPath := filepath.Join(bucket, object)
+	fi, err := os.Lstat(objPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("stat object: %w", err)
+	}
+	if fi.IsDir() {
+		return "", nil
+	}
+
+	dir, err := makeVersionsDir(bucket, object)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, getVersionId(objPath))
+	err = os.Rename(objPath, path)
+	if err != nil {
+		return "", fmt.Errorf("move object version: %w", err)
+	}
+
+	return path, nil
+}
+
+// removeNullVersion removes the null version of the object, either the
+// current object or the one in the version store
+func removeNullVersion(bucket, object string, bypassGovernance bool) error {
+	objPath := filepath.Join(bucket, object)
+	fi, err := os.Lstat(objPath)
+	if err == nil && !fi.IsDir() && getVersionId(objPath) == nullVersionId {
+		err = checkObjectLock(objPath, bypassGovernance)
+		if err != nil {
+			return err
+		}
+		err = os.Remove(objPath)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("remove null version: %w", err)
+		}
+	}
+
+	dir := filepath.Join(bucket, versionsDir(object))
+	nullPath := filepath.Join(dir, nullVersionId)
+	err = checkObjectLock(nullPath, bypassGovernance)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(nullPath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("remove null version: %w", err)
+	}
+	// cleanup the versions dir if this was the only version
+	os.Remove(dir)
+
+	return nil
+}
+
+// putDeleteMarker adds a new delete marker to the version store as the
+// most recent version of the object
+func putDeleteMarker(bucket, object, versionId string) error {
+	dir, err := makeVersionsDir(bucket, object)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, versionId)
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create delete marker: %w", err)
+	}
+	f.Close()
+
+	err = xattr.Set(path, deletemarkerkey, []byte("true"))
+	if err != nil {
+		os.Remove(path)
+		return fmt.Errorf("set delete marker attr: %w", err)
+	}
+
+	err = setVersionId(path, versionId)
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+
+	return nil
+}
+
+// restoreLatestVersion moves the most recent version of the object from
+// the version store back in place when there is no current version. This
+// returns false if there was nothing to restore, or if the most recent
+// version is a delete marker.
+func (p *Posix) restoreLatestVersion(bucket, object string, acct auth.Account) (bool, error) {
+	objPath := filepath.Join(bucket, object)
+	_, err := os.Lstat(objPath)
+	if err == nil {
+		return false, nil
+	}
+
+	dir := filepath.Join(bucket, versionsDir(object))
+	// cleanup the versions dir if there are no versions left
+	defer os.Remove(dir)
+
+	versions, err := storedVersions(bucket, object)
+	if err != nil {
+		return false, err
+	}
+	if len(versions) == 0 {
+		return false, nil
+	}
+
+	vpath := filepath.Join(dir, versions[0].Name())
+	if isDeleteMarker(vpath) {
+		return false, nil
+	}
+
+	err = p.mkdirAll(filepath.Dir(objPath), os.FileMode(0755), bucket, object, acct)
+	if err != nil {
+		return false, err
+	}
+
+	err = os.Rename(vpath, objPath)
+	if err != nil {
+		return false, fmt.Errorf("restore object version: %w", err)
+	}
+
+	return true, nil
+}
+
+func (p *Posix) PutObjectLockConfiguration(_ context.Context, input *s3.PutObjectLockConfigurationInput) error {
+	if input.Bucket == nil {
+		return s3err.GetAPIError(s3err.ErrInvalidBucketName)
+	}
+
+	bucket := *input.Bucket
+
+	_, err := os.Stat(bucket)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+	if err != nil {
+		return fmt.Errorf("stat bucket: %w", err)
+	}
+
+	config := input.ObjectLockConfiguration
+	if config == nil || config.ObjectLockEnabled != types.ObjectLockEnabledEnabled {
+		return s3err.GetAPIError(s3err.ErrMalformedXML)
+	}
+	if config.Rule != nil {
+		err = validateDefaultRetention(config.Rule.DefaultRetention)
+		if err != nil {
+			return err
+		}
+	}
+
+	// locked objects are protected from overwrites by keeping the
+	// prior versions, so versioning must be enabled first
+	status, err := getVersioning(bucket)
+	if err != nil {
+		return err
+	}
+	if status != types.BucketVersioningStatusEnabled {
+		return s3err.GetAPIError(s3err.ErrInvalidBucketState)
+	}
+
+	b, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("marshal object lock config: %w", err)
+	}
+
+	err = xattr.Set(bucket, objectlockkey, b)
+	if err != nil {
+		return fmt.Errorf("set object lock config: %w", err)
+	}
+
+	return nil
+}
+
+// validateDefaultRetention checks that the bucket default retention
+// has a valid mode and exactly one of either days or years
+func validateDefaultRetention(ret *types.DefaultRetention) error {
+	if ret == nil {
+		return s3err.GetAPIError(s3err.ErrMalformedXML)
+	}
+
+	switch ret.Mode {
+	case types.ObjectLockRetentionModeGovernance, types.ObjectLockRetentionModeCompliance:
+	default:
+		return s3err.GetAPIError(s3err.ErrMalformedXML)
+	}
+
+	days := ret.Days != nil && *ret.Days != 0
+	years := ret.Years != nil && *ret.Years != 0
+	if days == years {
+		return s3err.GetAPIError(s3err.ErrMalformedXML)
+	}
+	if (days && *ret.Days < 0) || (years && *ret.Years < 0) {
+		return s3err.GetAPIError(s3err.ErrInvalidRequest)
+	}
+
+	return nil
+}
+
+func (p *Posix) GetObjectLockConfiguration(_ context.Context, bucket string) (*types.ObjectLockConfiguration, error) {
+	_, err := os.Stat(bucket)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("stat bucket: %w", err)
+	}
+
+	config, err := getObjectLockConfig(bucket)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, s3err.GetAPIError(s3err.ErrObjectLockConfigurationNotFound)
+	}
+
+	return config, nil
+}
+
+// getObjectLockConfig returns the bucket object lock configuration, or
+// nil if object lock has not been enabled for the bucket
+func getObjectLockConfig(bucket string) (*types.ObjectLockConfiguration, error) {
+	b, err := xattr.Get(bucket, objectlockkey)
+	if isNoAttr(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get object lock config: %w", err)
+	}
+
+	var config types.ObjectLockConfiguration
+	err = json.Unmarshal(b, &config)
+	if err != nil {
+		return nil, fmt.Errorf("parse object lock config: %w", err)
+	}
+
+	return &config, nil
+}
+
+// lockedObjectPath returns the path of the requested object version in
+// a bucket that has object lock enabled
+func lockedObjectPath(bucket, object, versionId *string) (string, error) {
+	if bucket == nil {
+		return "", s3err.GetAPIError(s3err.ErrInvalidBucketName)
+	}
+	if object == nil {
+		return "", s3err.GetAPIError(s3err.ErrNoSuchKey)
+	}
+
+	_, err := os.Stat(*bucket)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+	if err != nil {
+		return "", fmt.Errorf("stat bucket: %w", err)
+	}
+
+	config, err := getObjectLockConfig(*bucket)
+	if err != nil {
+		return "", err
+	}
+	if config == nil {
+		return "", s3err.GetAPIError(s3err.ErrInvalidBucketObjectLockConfiguration)
+	}
+
+	obj, err := objectVersionPath(*bucket, *object, getString(versionId))
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(*bucket, obj)
+	_, err = os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", s3err.GetAPIError(s3err.ErrNoSuchKey)
+	}
+	if err != nil {
+		return "", fmt.Errorf("stat object: %w", err)
+	}
+
+	return path, nil
+}
+
+func (p *Posix) PutObjectRetention(_ context.Context, input *s3.PutObjectRetentionInput) error {
+	path, err := lockedObjectPath(input.Bucket, input.Key, input.VersionId)
+	if err != nil {
+		return err
+	}
+
+	retention := input.Retention
+	if retention == nil || retention.RetainUntilDate == nil {
+		return s3err.GetAPIError(s3err.ErrMalformedXML)
+	}
+	switch retention.Mode {
+	case types.ObjectLockRetentionModeGovernance, types.ObjectLockRetentionModeCompliance:
+	default:
+		return s3err.GetAPIError(s3err.ErrMalformedXML)
+	}
+	if !retention.RetainUntilDate.After(time.Now()) {
+		return s3err.GetAPIError(s3err.ErrPastObjectLockRetainDate)
+	}
+
+	bypass := input.BypassGovernanceRetention != nil && *input.BypassGovernanceRetention
+
+	current, err := getRetention(path)
+	if err != nil {
+		return err
+	}
+	if current != nil && current.RetainUntilDate.After(time.Now()) {
+		// an active retention period can only be extended, with the
+		// exception of governance mode where this may be overridden
+		// by users with bypass permissions
+		shortened := retention.RetainUntilDate.Before(*current.RetainUntilDate)
+		switch current.Mode {
+		case types.ObjectLockRetentionModeCompliance:
+			if shortened || retention.Mode != types.ObjectLockRetentionModeCompliance {
+				return s3err.GetAPIError(s3err.ErrObjectLocked)
+			}
+		case types.ObjectLockRetentionModeGovernance:
+			if shortened && !bypass {
+				return s3err.GetAPIError(s3err.ErrObjectLocked)
+			}
+		}
+	}
+
+	return setRetention(path, retention)
+}
+
+func (p *Posix) GetObjectRetention(_ context.Context, input *s3.GetObjectRetentionInput) (*types.ObjectLockRetention, error) {
+	path, err := lockedObjectPath(input.Bucket, input.Key, input.VersionId)
+	if err != nil {
+		return nil, err
+	}
+
+	retention, err := getRetention(path)
+	if err != nil {
+		return nil, err
+	}
+	if retention == nil {
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchObjectLockConfiguration)
+	}
+
+	return retention, nil
+}
+
+func (p *Posix) PutObjectLegalHold(_ context.Context, input *s3.PutObjectLegalHoldInput) error {
+	path, err := lockedObjectPath(input.Bucket, input.Key, input.VersionId)
+	if err != nil {
+		return err
+	}
+
+	if input.LegalHold == nil {
+		return s3err.GetAPIError(s3err.ErrMalformedXML)
+	}
+
+	return setLegalHold(path, input.LegalHold.Status)
+}
+
+func (p *Posix) GetObjectLegalHold(_ context.Context, input *s3.GetObjectLegalHoldInput) (*types.ObjectLockLegalHold, error) {
+	path, err := lockedObjectPath(input.Bucket, input.Key, input.VersionId)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := getLegalHold(path)
+	if err != nil {
+		return nil, err
+	}
+	if status == "" {
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchObjectLockConfiguration)
+	}
+
+	return &types.ObjectLockLegalHold{Status: status}, nil
+}
+
+func getRetention(path string) (*types.ObjectLockRetention, error) {
+	b, err := xattr.Get(path, objectretentionkey)
+	if isNoAttr(err) || errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get object retention: %w", err)
+	}
+
+	var retention types.ObjectLockRetention
+	err = json.Unmarshal(b, &retention)
+	if err != nil {
+		return nil, fmt.Errorf("parse object retention: %w", err)
+	}
+	if retention.RetainUntilDate == nil {
+		return nil, nil
+	}
+
+	return &retention, nil
+}
+
+func setRetention(path string, retention *types.ObjectLockRetention) error {
+	b, err := json.Marshal(retention)
+	if err != nil {
+		return fmt.Errorf("marshal object retention: %w", err)
+	}
+
+	err = xattr.Set(path, objectretentionkey, b)
+	if err != nil {
+		return fmt.Errorf("set object retention: %w", err)
+	}
+
+	return nil
+}
+
+func getLegalHold(path string) (types.ObjectLockLegalHoldStatus, error) {
+	b, err := xattr.Get(path, objectlegalholdkey)
+	if isNoAttr(err) || errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("get object legal hold: %w", err)
+	}
+
+	return types.ObjectLockLegalHoldStatus(b), nil
+}
+
+func setLegalHold(path string, status types.ObjectLockLegalHoldStatus) error {
+	switch status {
+	case types.ObjectLockLegalHoldStatusOn, types.ObjectLockLegalHoldStatusOff:
+	default:
+		return s3err.GetAPIError(s3err.ErrMalformedXML)
+	}
+
+	err := xattr.Set(path, objectlegalholdkey, []byte(status))
+	if err != nil {
+		return fmt.Errorf("set object legal hold: %w", err)
+	}
+
+	return nil
+}
+
+// checkObjectLock returns an error if the object at path is protected
+// from being deleted or overwritten by either a legal hold or an active
+// retention period. Governance mode retention can be bypassed by users
+// with the bypass permission.
+func checkObjectLock(path string, bypassGovernance bool) error {
+	status, err := getLegalHold(path)
+	if err != nil {
+		return err
+	}
+	if status == types.ObjectLockLegalHoldStatusOn {
+		return s3err.GetAPIError(s3err.ErrObjectLocked)
+	}
+
+	retention, err := getRetention(path)
+	if err != nil {
+		return err
+	}
+	if retention == nil || !retention.RetainUntilDate.After(time.Now()) {
+		return nil
+	}
+
+	switch retention.Mode {
+	case types.ObjectLockRetentionModeCompliance:
+		return s3err.GetAPIError(s3err.ErrObjectLocked)
+	case types.ObjectLockRetentionModeGovernance:
+		if !bypassGovernance {
+			return s3err.GetAPIError(s3err.ErrObjectLocked)
+		}
+	}
+
+	return nil
+}
+
+// objectLock holds the object lock settings for a new object
+type objectLock struct {
+	retention *types.ObjectLockRetention
+	legalHold types.ObjectLockLegalHoldStatus
+}
+
+// newObjectLock validates the object lock settings requested for a new
+// object in the bucket. The bucket default retention applies when no
+// retention was requested. This returns nil if object lock is not
+// enabled for the bucket.
+func newObjectLock(bucket string, mode types.ObjectLockMode, until *time.Time, legalHold types.ObjectLockLegalHoldStatus) (*objectLock, error) {
+	config, err := getObjectLockConfig(bucket)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		if mode != "" || until != nil || legalHold != "" {
+			return nil, s3err.GetAPIError(s3err.ErrInvalidBucketObjectLockConfiguration)
+		}
+		return nil, nil
+	}
+
+	if (mode == "") != (until == nil) {
+		return nil, s3err.GetAPIError(s3err.ErrObjectLockInvalidHeaders)
+	}
+
+	lock := &objectLock{legalHold: legalHold}
+
+	switch {
+	case mode != "":
+		switch types.ObjectLockRetentionMode(mode) {
+		case types.ObjectLockRetentionModeGovernance, types.ObjectLockRetentionModeCompliance:
+		default:
+			return nil, s3err.GetAPIError(s3err.ErrInvalidRequest)
+		}
+		if !until.After(time.Now()) {
+			return nil, s3err.GetAPIError(s3err.ErrPastObjectLockRetainDate)
+		}
+		lock.retention = &types.ObjectLockRetention{
+			Mode:            types.ObjectLockRetentionMode(mode),
+			RetainUntilDate: until,
+		}
+	case config.Rule != nil && config.Rule.DefaultRetention != nil:
+		def := config.Rule.DefaultRetention
+		retainUntil := time.Now()
+		if def.Days != nil {
+			retainUntil = retainUntil.AddDate(0, 0, int(*def.Days))
+		}
+		if def.Years != nil {
+			retainUntil = retainUntil.AddDate(int(*def.Years), 0, 0)
+		}
+		lock.retention = &types.ObjectLockRetention{
+			Mode:            def.Mode,
+			RetainUntilDate: &retainUntil,
+		}
+	}
+
+	if legalHold != "" {
+		switch legalHold {
+		case types.ObjectLockLegalHoldStatusOn, types.ObjectLockLegalHoldStatusOff:
+		default:
+			return nil, s3err.GetAPIError(s3err.ErrInvalidRequest)
+		}
+	}
+
+	return lock, nil
+}
+
+// setUploadObjectLock stores the object lock settings requested for a
+// multipart upload on the upload directory
+func setUploadObjectLock(upiddir string, mode types.ObjectLockMode, until *time.Time, legalHold types.ObjectLockLegalHoldStatus) error {
+	if mode != "" {
+		err := setRetention(upiddir, &types.ObjectLockRetention{
+			Mode:            types.ObjectLockRetentionMode(mode),
+			RetainUntilDate: until,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if legalHold != "" {
+		return setLegalHold(upiddir, legalHold)
+	}
+	return nil
+}
+
+// uploadObjectLock returns the object lock settings for the object of a
+// multipart upload. The retention requested when the upload was created
+// is kept even if it expired in the meantime, and the bucket default
+// retention applies from the completion otherwise.
+func uploadObjectLock(bucket, upiddir string) (*objectLock, error) {
+	retention, err := getRetention(upiddir)
+	if err != nil {
+		return nil, err
+	}
+	legalHold, err := getLegalHold(upiddir)
+	if err != nil {
+		return nil, err
+	}
+
+	lock, err := newObjectLock(bucket, "", nil, legalHold)
+	if err != nil {
+		return nil, err
+	}
+	if lock != nil && retention != nil {
+		lock.retention = retention
+	}
+
+	return lock, nil
+}
+
+// set stores the object lock settings on the new object
+func (l *objectLock) set(path string) error {
+	if l == nil {
+		return nil
+	}
+
+	if l.retention != nil {
+		err := setRetention(path, l.retention)
+		if err != nil {
+			return err
+		}
+	}
+
+	if l.legalHold != "" {
+		err := setLegalHold(path, l.legalHold)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *Posix) PutBucketAcl(_ context.Context, bucket string, data []byte) error {
 	_, err := os.Stat(bucket)
 	if errors.Is(err, fs.ErrNotExist) {
 		return s3err.GetAPIError(s3err.ErrNoSuchBucket)
@@ -1893,6 +4108,104 @@ func (p *Posix) DeleteBucketPolicy(ctx context.Context, bucket string) error {
 	return p.PutBucketPolicy(ctx, bucket, nil)
 }
 
+func (p *Posix) PutBucketCors(_ context.Context, bucket string, cors []byte) error {
+	_, err := os.Stat(bucket)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+	if err != nil {
+		return fmt.Errorf("stat bucket: %w", err)
+	}
+
+	if cors == nil {
+		err := xattr.Remove(bucket, corskey)
+		if err != nil && !isNoAttr(err) {
+			return fmt.Errorf("remove cors: %w", err)
+		}
+		return nil
+	}
+
+	err = xattr.Set(bucket, corskey, cors)
+	if err != nil {
+		return fmt.Errorf("set cors: %w", err)
+	}
+
+	return nil
+}
+
+func (p *Posix) GetBucketCors(_ context.Context, bucket string) ([]byte, error) {
+	_, err := os.Stat(bucket)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("stat bucket: %w", err)
+	}
+
+	cors, err := xattr.Get(bucket, corskey)
+	if isNoAttr(err) {
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchCORSConfiguration)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get cors: %w", err)
+	}
+
+	return cors, nil
+}
+
+func (p *Posix) DeleteBucketCors(ctx context.Context, bucket string) error {
+	return p.PutBucketCors(ctx, bucket, nil)
+}
+
+func (p *Posix) PutBucketWebsite(_ context.Context, bucket string, website []byte) error {
+	_, err := os.Stat(bucket)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+	if err != nil {
+		return fmt.Errorf("stat bucket: %w", err)
+	}
+
+	if website == nil {
+		err := xattr.Remove(bucket, websitekey)
+		if err != nil && !isNoAttr(err) {
+			return fmt.Errorf("remove website: %w", err)
+		}
+		return nil
+	}
+
+	err = xattr.Set(bucket, websitekey, website)
+	if err != nil {
+		return fmt.Errorf("set website: %w", err)
+	}
+
+	return nil
+}
+
+func (p *Posix) GetBucketWebsite(_ context.Context, bucket string) ([]byte, error) {
+	_, err := os.Stat(bucket)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("stat bucket: %w", err)
+	}
+
+	website, err := xattr.Get(bucket, websitekey)
+	if isNoAttr(err) {
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchWebsiteConfiguration)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get website: %w", err)
+	}
+
+	return website, nil
+}
+
+func (p *Posix) DeleteBucketWebsite(ctx context.Context, bucket string) error {
+	return p.PutBucketWebsite(ctx, bucket, nil)
+}
+
 func (p *Posix) ChangeBucketOwner(ctx context.Context, bucket, newOwner string) error {
 	_, err := os.Stat(bucket)
 	if errors.Is(err, fs.ErrNotExist) {
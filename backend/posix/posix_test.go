@@ -0,0 +1,90 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package posix
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/versity/versitygw/auth"
+)
+
+func TestOwnerFunc(t *testing.T) {
+	var dirs []string
+	var files int
+	owner := func(f *os.File, acct auth.Account) error {
+		if acct.ProjectID != 10 {
+			t.Errorf("expected the request account, got %+v", acct)
+		}
+		fi, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			dirs = append(dirs, f.Name())
+		} else {
+			files++
+		}
+		return nil
+	}
+
+	bucket, object := "bucket", "dir1/dir2/obj"
+	p := newTestPosix(t, bucket, WithOwnerFunc(owner))
+	ctx := context.WithValue(context.Background(), "account",
+		auth.Account{Access: "user", Role: auth.RoleUser, ProjectID: 10})
+
+	_, err := p.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        &bucket,
+		Key:           &object,
+		Body:          strings.NewReader("data"),
+		ContentLength: aws.Int64(4),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mp, err := p.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket: &bucket,
+		Key:    &object,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = p.UploadPart(ctx, &s3.UploadPartInput{
+		Bucket:        &bucket,
+		Key:           &object,
+		UploadId:      mp.UploadId,
+		PartNumber:    aws.Int32(1),
+		Body:          strings.NewReader("data"),
+		ContentLength: aws.Int64(4),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Strings(dirs)
+	if len(dirs) != 3 || !strings.HasSuffix(dirs[0], "/"+*mp.UploadId) ||
+		dirs[1] != "bucket/dir1" || dirs[2] != "bucket/dir1/dir2" {
+		t.Fatalf("expected the parent and upload directories, got %v", dirs)
+	}
+	if files != 2 {
+		t.Fatalf("expected the object and part files, got %v", files)
+	}
+}
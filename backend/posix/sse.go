@@ -0,0 +1,133 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package posix
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/versity/versitygw/backend/sse"
+	"github.com/versity/versitygw/s3err"
+)
+
+// sseAlign is the alignment of the encrypted data of multipart upload
+// parts, this keeps parts block aligned so that filesystems that can
+// move part extents into the final object are able to do so
+const sseAlign = 4096
+
+// WithSSEMasterKey enables SSE-S3 server side encryption with the
+// gateway managed master key
+func WithSSEMasterKey(key []byte) Option {
+	return func(p *Posix) { p.sseMasterKey = key }
+}
+
+// newEncryption returns the encryption info and data key for a new
+// object, or nil if encryption was not requested
+func (p *Posix) newEncryption(req *sse.Request) (*sse.Info, []byte, error) {
+	if req == nil {
+		return nil, nil, nil
+	}
+	return sse.NewInfo(req, p.sseMasterKey)
+}
+
+// ObjectEncryption returns the encryption info and data key of the
+// object at path, or nil if the object is not encrypted
+func (p *Posix) ObjectEncryption(path string, req *sse.Request) (*sse.Info, []byte, error) {
+	info, err := sse.Load(path)
+	if err != nil || info == nil {
+		return nil, nil, err
+	}
+	dataKey, err := info.DataKey(req, p.sseMasterKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return info, dataKey, nil
+}
+
+// uploadEncryption returns the encryption info and data key of the parts
+// of the multipart upload at upiddir. The customer key of the parts must
+// be the one the upload was created with.
+func (p *Posix) uploadEncryption(upiddir string, req *sse.Request) (*sse.Info, []byte, error) {
+	info, dataKey, err := p.ObjectEncryption(upiddir, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	if req != nil && req.Mode == sse.ModeCustomer && (info == nil || info.Mode != sse.ModeCustomer) {
+		return nil, nil, s3err.GetAPIError(s3err.ErrSSECustomerKeyNotApplicable)
+	}
+	return info, dataKey, nil
+}
+
+// MultipartEncryption returns the encryption info of the object built
+// from the parts of the upload, or nil if the upload is not encrypted
+func MultipartEncryption(upiddir string, parts []types.CompletedPart) (*sse.Info, error) {
+	info, err := sse.Load(upiddir)
+	if err != nil || info == nil {
+		return nil, err
+	}
+
+	for _, part := range parts {
+		partInfo, err := sse.Load(filepath.Join(upiddir, fmt.Sprintf("%v", *part.PartNumber)))
+		if err != nil {
+			return nil, err
+		}
+		if partInfo == nil || len(partInfo.Segments) != 1 {
+			return nil, s3err.GetAPIError(s3err.ErrInvalidPart)
+		}
+		info.Segments = append(info.Segments, partInfo.Segments[0])
+		info.Size += partInfo.Segments[0].Size
+	}
+
+	return info, nil
+}
+
+// writeData copies the data from r to w, encrypting it as the segment
+// of the object when info is not nil
+func writeData(w io.Writer, r io.Reader, info *sse.Info, dataKey []byte, segment int32, align int64) error {
+	if info == nil {
+		_, err := io.Copy(w, r)
+		return err
+	}
+
+	ew, err := sse.NewWriter(w, dataKey, segment, align)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(ew, r)
+	if err != nil {
+		return err
+	}
+	err = ew.Close()
+	if err != nil {
+		return err
+	}
+
+	seg := ew.Segment()
+	info.Segments = append(info.Segments, seg)
+	info.Size += seg.Size
+	return nil
+}
+
+// readData copies length bytes starting at offset of the object data
+// in f to w, decrypting it when info is not nil
+func readData(w io.Writer, f io.ReaderAt, info *sse.Info, dataKey []byte, offset, length int64) error {
+	if info == nil {
+		_, err := io.Copy(w, io.NewSectionReader(f, offset, length))
+		return err
+	}
+	return info.Decrypt(w, f, dataKey, offset, length)
+}
@@ -0,0 +1,96 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package posix
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/versity/versitygw/s3err"
+)
+
+func TestUploadPartSSECustomer(t *testing.T) {
+	bucket, object := "bucket", "obj"
+	p := newTestPosix(t, bucket)
+
+	rawKey := bytes.Repeat([]byte{1}, 32)
+	keyMD5 := md5.Sum(rawKey)
+	key := base64.StdEncoding.EncodeToString(rawKey)
+	md5Str := base64.StdEncoding.EncodeToString(keyMD5[:])
+
+	createUpload := func(encrypted bool) *string {
+		t.Helper()
+		input := &s3.CreateMultipartUploadInput{Bucket: &bucket, Key: &object}
+		if encrypted {
+			input.SSECustomerAlgorithm = aws.String("AES256")
+			input.SSECustomerKey = &key
+			input.SSECustomerKeyMD5 = &md5Str
+		}
+		mp, err := p.CreateMultipartUpload(context.Background(), input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return mp.UploadId
+	}
+	plainUpload, encryptedUpload := createUpload(false), createUpload(true)
+
+	tests := []struct {
+		name      string
+		uploadID  *string
+		encrypted bool
+		err       s3err.ErrorCode
+	}{
+		{"plain-upload-plain-part", plainUpload, false, s3err.ErrNone},
+		{"plain-upload-encrypted-part", plainUpload, true, s3err.ErrSSECustomerKeyNotApplicable},
+		{"encrypted-upload-encrypted-part", encryptedUpload, true, s3err.ErrNone},
+		{"encrypted-upload-plain-part", encryptedUpload, false, s3err.ErrMissingSSECustomerKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := &s3.UploadPartInput{
+				Bucket:        &bucket,
+				Key:           &object,
+				UploadId:      tt.uploadID,
+				PartNumber:    aws.Int32(1),
+				Body:          strings.NewReader("data"),
+				ContentLength: aws.Int64(4),
+			}
+			if tt.encrypted {
+				input.SSECustomerAlgorithm = aws.String("AES256")
+				input.SSECustomerKey = &key
+				input.SSECustomerKeyMD5 = &md5Str
+			}
+
+			_, err := p.UploadPart(context.Background(), input)
+			if tt.err == s3err.ErrNone {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+			var apiErr s3err.APIError
+			if !errors.As(err, &apiErr) || apiErr != s3err.GetAPIError(tt.err) {
+				t.Fatalf("expected %v, got %v", s3err.GetAPIError(tt.err).Code, err)
+			}
+		})
+	}
+}
@@ -38,6 +38,8 @@ type tmpfile struct {
 	objname string
 	isOTmp  bool
 	size    int64
+	// linked is set once the named temp file is renamed into place
+	linked bool
 }
 
 func openTmpFile(dir, bucket, obj string, size int64) (*tmpfile, error) {
@@ -148,6 +150,7 @@ func (tmp *tmpfile) fallbackLink() error {
 	if err != nil {
 		return fmt.Errorf("rename tmpfile: %w", err)
 	}
+	tmp.linked = true
 
 	return nil
 }
@@ -164,4 +167,9 @@ func (tmp *tmpfile) Write(b []byte) (int, error) {
 
 func (tmp *tmpfile) cleanup() {
 	tmp.f.Close()
+	// the unnamed O_TMPFILE inodes are freed on close, the named temp
+	// files are removed unless linked
+	if !tmp.isOTmp && !tmp.linked {
+		os.Remove(tmp.f.Name())
+	}
 }
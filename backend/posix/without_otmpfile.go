@@ -31,6 +31,8 @@ type tmpfile struct {
 	bucket  string
 	objname string
 	size    int64
+	// linked is set once the temp file is renamed into place
+	linked bool
 }
 
 func openTmpFile(dir, bucket, obj string, size int64) (*tmpfile, error) {
@@ -73,6 +75,7 @@ func (tmp *tmpfile) link() error {
 	if err != nil {
 		return fmt.Errorf("rename tmpfile: %w", err)
 	}
+	tmp.linked = true
 
 	return nil
 }
@@ -89,4 +92,8 @@ func (tmp *tmpfile) Write(b []byte) (int, error) {
 
 func (tmp *tmpfile) cleanup() {
 	tmp.f.Close()
+	// the temp file is left behind unless linked
+	if !tmp.linked {
+		os.Remove(tmp.f.Name())
+	}
 }
@@ -0,0 +1,1172 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package quota enforces storage quotas on top of any backend.
+//
+// A quota limits the number of bytes and objects stored in a bucket, or
+// in all of the buckets owned by an account. The usage of each bucket is
+// counted from the bucket contents the first time the bucket is seen,
+// and then kept up to date by the object operations going through the
+// gateway. Quotas and usage are stored together in a JSON file.
+//
+// Requests that would store more data are reserved against the quota
+// before any data is written, so concurrent uploads can not overrun the
+// quota together. Requests that free space are always allowed. The
+// uploaded parts of multipart uploads stay reserved until the upload is
+// completed or aborted.
+//
+// Objects removed by the backend itself, such as the objects expired by
+// lifecycle rules, are not seen by the gateway. The bucket contents are
+// counted again, at most once per rescan interval, when a request would
+// exceed a quota and when the quota usage is requested.
+//
+// Usage changes are batched and written out at most once per flush
+// interval, and on shutdown.
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/versity/versitygw/auth"
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/s3err"
+	"github.com/versity/versitygw/s3response"
+)
+
+const (
+	quotaFile = "quota.json"
+	quotaMode = 0600
+
+	// listMax is the page size used to count the bucket contents
+	listMax int32 = 1000
+
+	// flushInterval is how long usage changes are batched before the
+	// quota file is written
+	flushInterval = time.Second
+
+	// rescanInterval is the minimum time between two counts of the
+	// bucket contents after the bucket is first counted
+	rescanInterval = time.Minute
+)
+
+// Limits are the quota limits of a bucket or account, zero is unlimited
+type Limits struct {
+	MaxBytes   int64 `json:"maxBytes,omitempty"`
+	MaxObjects int64 `json:"maxObjects,omitempty"`
+}
+
+// Usage is the storage used by a bucket or account
+type Usage struct {
+	Bytes   int64 `json:"bytes"`
+	Objects int64 `json:"objects"`
+}
+
+func (u Usage) add(o Usage) Usage {
+	return Usage{Bytes: u.Bytes + o.Bytes, Objects: u.Objects + o.Objects}
+}
+
+func (u Usage) neg() Usage {
+	return Usage{Bytes: -u.Bytes, Objects: -u.Objects}
+}
+
+// Quota is the quota limits and current usage of a bucket or account
+type Quota struct {
+	Limits
+	Usage Usage `json:"usage"`
+}
+
+// exceeded checks if the usage grown by delta is over the limits
+func (l Limits) exceeded(used, delta Usage) bool {
+	u := used.add(delta)
+	if l.MaxBytes > 0 && delta.Bytes > 0 && u.Bytes > l.MaxBytes {
+		return true
+	}
+	if l.MaxObjects > 0 && delta.Objects > 0 && u.Objects > l.MaxObjects {
+		return true
+	}
+	return false
+}
+
+type bucketRecord struct {
+	Owner  string `json:"owner"`
+	Limits Limits `json:"limits"`
+	Usage  Usage  `json:"usage"`
+
+	// rescanned is the time of the last rescan of the bucket
+	rescanned time.Time
+	// scanning is set while the bucket is rescanned, scanDelta is the
+	// usage change committed in the meantime
+	scanning  bool
+	scanDelta Usage
+}
+
+// quotaConfig is the stored quota data
+type quotaConfig struct {
+	Accounts map[string]Limits        `json:"accounts"`
+	Buckets  map[string]*bucketRecord `json:"buckets"`
+}
+
+// Backend enforces the quotas on the wrapped backend. The request
+// strings stored in the quota data are cloned since they may refer to
+// request buffers that are reused.
+type Backend struct {
+	backend.Backend
+
+	dir string
+
+	mu   sync.Mutex
+	conf quotaConfig
+	// pending is the usage reserved by in progress requests and by the
+	// parts of multipart uploads
+	pending map[string]Usage
+	// uploads are the part sizes of the multipart uploads by bucket and
+	// upload id
+	uploads map[uploadKey]map[int32]int64
+	// dirty is set when the usage changed since the last write
+	dirty      bool
+	flushTimer *time.Timer
+
+	// fileMu orders the quota file writes, it is taken before the
+	// lock is released so the writes are in the order of the changes
+	fileMu sync.Mutex
+}
+
+type uploadKey struct {
+	bucket   string
+	uploadId string
+}
+
+// New wraps be with quota enforcement, the quotas and usage are stored
+// in dir
+func New(be backend.Backend, dir string) (*Backend, error) {
+	b := &Backend{
+		Backend: be,
+		dir:     dir,
+		conf: quotaConfig{
+			Accounts: make(map[string]Limits),
+			Buckets:  make(map[string]*bucketRecord),
+		},
+		pending: make(map[string]Usage),
+		uploads: make(map[uploadKey]map[int32]int64),
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, quotaFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return b, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read quota file: %w", err)
+	}
+
+	err = json.Unmarshal(data, &b.conf)
+	if err != nil {
+		return nil, fmt.Errorf("parse quota file: %w", err)
+	}
+	if b.conf.Accounts == nil {
+		b.conf.Accounts = make(map[string]Limits)
+	}
+	if b.conf.Buckets == nil {
+		b.conf.Buckets = make(map[string]*bucketRecord)
+	}
+
+	return b, nil
+}
+
+// store writes out the quota data, the data is serialized with the
+// lock held and written without it
+func (b *Backend) store() error {
+	b.mu.Lock()
+	b.dirty = false
+	data, err := json.Marshal(b.conf)
+	if err != nil {
+		b.mu.Unlock()
+		return fmt.Errorf("serialize quota: %w", err)
+	}
+	b.fileMu.Lock()
+	b.mu.Unlock()
+	defer b.fileMu.Unlock()
+
+	f, err := os.CreateTemp(b.dir, quotaFile)
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(data)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("write temp file: %w", err)
+	}
+
+	err = os.Chmod(f.Name(), quotaMode)
+	if err != nil {
+		return fmt.Errorf("chmod temp file: %w", err)
+	}
+
+	err = os.Rename(f.Name(), filepath.Join(b.dir, quotaFile))
+	if err != nil {
+		return fmt.Errorf("rename temp file: %w", err)
+	}
+
+	return nil
+}
+
+// storeUsage schedules the write of usage changes, must be called with
+// the lock held
+func (b *Backend) storeUsage() {
+	b.dirty = true
+	if b.flushTimer == nil {
+		b.flushTimer = time.AfterFunc(flushInterval, b.flushUsage)
+	}
+}
+
+// flushUsage writes out the batched usage changes, an error here can
+// not fail the requests that already completed in the backend
+func (b *Backend) flushUsage() {
+	b.mu.Lock()
+	b.flushTimer = nil
+	dirty := b.dirty
+	b.mu.Unlock()
+	if !dirty {
+		return
+	}
+
+	err := b.store()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to store quota usage: %v\n", err)
+		// retried with the next batch
+		b.mu.Lock()
+		b.storeUsage()
+		b.mu.Unlock()
+	}
+}
+
+// Shutdown writes out the pending usage changes before shutting down
+// the backend
+func (b *Backend) Shutdown() {
+	b.mu.Lock()
+	if b.flushTimer != nil {
+		b.flushTimer.Stop()
+		b.flushTimer = nil
+	}
+	b.mu.Unlock()
+
+	b.flushUsage()
+	b.Backend.Shutdown()
+}
+
+// initBucket makes sure the bucket has a usage record, counting the
+// bucket contents if it is not tracked yet
+func (b *Backend) initBucket(ctx context.Context, bucket string) error {
+	b.mu.Lock()
+	_, ok := b.conf.Buckets[bucket]
+	b.mu.Unlock()
+	if ok {
+		return nil
+	}
+
+	owner, err := b.bucketOwner(ctx, bucket)
+	if err != nil {
+		return err
+	}
+
+	usage, err := b.scanBucket(ctx, bucket)
+	if err != nil {
+		return fmt.Errorf("count bucket usage: %w", err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, ok = b.conf.Buckets[bucket]
+	if ok {
+		// counted by another request in the meantime
+		return nil
+	}
+	b.conf.Buckets[strings.Clone(bucket)] = &bucketRecord{Owner: owner, Usage: usage, rescanned: time.Now()}
+	b.storeUsage()
+
+	return nil
+}
+
+func (b *Backend) bucketOwner(ctx context.Context, bucket string) (string, error) {
+	data, err := b.Backend.GetBucketAcl(ctx, &s3.GetBucketAclInput{Bucket: &bucket})
+	if err != nil {
+		return "", err
+	}
+
+	return aclOwner(data)
+}
+
+func aclOwner(data []byte) (string, error) {
+	var acl auth.ACL
+	err := json.Unmarshal(data, &acl)
+	if err != nil {
+		return "", fmt.Errorf("parse bucket acl: %w", err)
+	}
+
+	return acl.Owner, nil
+}
+
+// scanBucket counts the current usage of the bucket, all of the object
+// versions are counted for buckets with versioning enabled or suspended
+func (b *Backend) scanBucket(ctx context.Context, bucket string) (Usage, error) {
+	var usage Usage
+	maxKeys := listMax
+
+	if b.versioned(ctx, bucket) {
+		var keyMarker, versionMarker *string
+		for {
+			res, err := b.Backend.ListObjectVersions(ctx, &s3.ListObjectVersionsInput{
+				Bucket:          &bucket,
+				KeyMarker:       keyMarker,
+				VersionIdMarker: versionMarker,
+				MaxKeys:         &maxKeys,
+			})
+			if err != nil {
+				return Usage{}, err
+			}
+			for _, v := range res.Versions {
+				usage.Bytes += getInt64(v.Size)
+				usage.Objects++
+			}
+			if !res.IsTruncated {
+				return usage, nil
+			}
+			keyMarker = &res.NextKeyMarker
+			versionMarker = &res.NextVersionIdMarker
+		}
+	}
+
+	var token *string
+	for {
+		res, err := b.Backend.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+			Bucket:            &bucket,
+			ContinuationToken: token,
+			MaxKeys:           &maxKeys,
+		})
+		if err != nil {
+			return Usage{}, err
+		}
+		for _, obj := range res.Contents {
+			usage.Bytes += getInt64(obj.Size)
+			usage.Objects++
+		}
+		if res.IsTruncated == nil || !*res.IsTruncated || res.NextContinuationToken == nil {
+			return usage, nil
+		}
+		token = res.NextContinuationToken
+	}
+}
+
+// versioned checks if overwritten and deleted objects are kept as
+// versions in the bucket
+func (b *Backend) versioned(ctx context.Context, bucket string) bool {
+	res, err := b.Backend.GetBucketVersioning(ctx, bucket)
+	if err != nil {
+		return false
+	}
+
+	return res.Status == types.BucketVersioningStatusEnabled ||
+		res.Status == types.BucketVersioningStatusSuspended
+}
+
+// objectUsage returns the usage of the object, or zero usage if the
+// object can not be found
+func (b *Backend) objectUsage(ctx context.Context, input *s3.HeadObjectInput) Usage {
+	res, err := b.Backend.HeadObject(ctx, input)
+	if err != nil {
+		return Usage{}
+	}
+
+	return Usage{Bytes: getInt64(res.ContentLength), Objects: 1}
+}
+
+// replacedUsage returns the usage freed by replacing the object, which
+// is none when the current object is kept as a version
+func (b *Backend) replacedUsage(ctx context.Context, bucket, key string, sseAlg, sseKey, sseKeyMD5 *string) Usage {
+	if b.versioned(ctx, bucket) {
+		return Usage{}
+	}
+
+	return b.objectUsage(ctx, &s3.HeadObjectInput{
+		Bucket:               &bucket,
+		Key:                  &key,
+		SSECustomerAlgorithm: sseAlg,
+		SSECustomerKey:       sseKey,
+		SSECustomerKeyMD5:    sseKeyMD5,
+	})
+}
+
+// sourceUsage returns the usage of a copy source
+func (b *Backend) sourceUsage(ctx context.Context, copySource string, sseAlg, sseKey, sseKeyMD5 *string) Usage {
+	srcBucket, srcObject, ok := strings.Cut(copySource, "/")
+	if !ok {
+		return Usage{}
+	}
+	srcObject, versionId, _ := strings.Cut(srcObject, "?versionId=")
+
+	input := &s3.HeadObjectInput{
+		Bucket:               &srcBucket,
+		Key:                  &srcObject,
+		SSECustomerAlgorithm: sseAlg,
+		SSECustomerKey:       sseKey,
+		SSECustomerKeyMD5:    sseKeyMD5,
+	}
+	if versionId != "" {
+		input.VersionId = &versionId
+	}
+
+	return b.objectUsage(ctx, input)
+}
+
+// reservation is the usage reserved by a request until it is released
+// or committed
+type reservation struct {
+	b      *Backend
+	bucket string
+	delta  Usage
+}
+
+// reserve checks that delta fits in the bucket and owner account quotas
+// and reserves it. The usage held by the part of the multipart upload
+// uploadId, or by all of its parts if part is zero, is not counted
+// since the request replaces it.
+func (b *Backend) reserve(ctx context.Context, bucket, uploadId string, part int32, delta Usage) (*reservation, error) {
+	err := b.initBucket(ctx, bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := b.tryReserve(bucket, uploadId, part, delta)
+	if err == nil || !isQuotaExceeded(err) {
+		return r, err
+	}
+
+	b.mu.Lock()
+	var owner string
+	if rec, ok := b.conf.Buckets[bucket]; ok {
+		owner = rec.Owner
+	}
+	b.mu.Unlock()
+
+	pruned := b.pruneUploads(ctx, bucket)
+	rescanned := b.rescanAccount(ctx, owner)
+	if !pruned && !rescanned {
+		return r, err
+	}
+
+	// retry without the usage of the uploads and objects removed
+	// outside of the gateway
+	return b.tryReserve(bucket, uploadId, part, delta)
+}
+
+func (b *Backend) tryReserve(bucket, uploadId string, part int32, delta Usage) (*reservation, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	rec, ok := b.conf.Buckets[bucket]
+	if !ok {
+		// the bucket was deleted in the meantime
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+
+	held := b.uploadUsage(bucket, uploadId, part).neg()
+	if rec.Limits.exceeded(rec.Usage.add(b.pending[bucket]).add(held), delta) {
+		return nil, s3err.GetAPIError(s3err.ErrQuotaExceeded)
+	}
+
+	limits, ok := b.conf.Accounts[rec.Owner]
+	if ok && limits.exceeded(b.accountUsage(rec.Owner, true).add(held), delta) {
+		return nil, s3err.GetAPIError(s3err.ErrQuotaExceeded)
+	}
+
+	bucket = strings.Clone(bucket)
+	b.addPending(bucket, delta)
+
+	return &reservation{b: b, bucket: bucket, delta: delta}, nil
+}
+
+// release drops the reservation without changing the bucket usage
+func (r *reservation) release() {
+	r.b.mu.Lock()
+	defer r.b.mu.Unlock()
+	r.b.addPending(r.bucket, r.delta.neg())
+}
+
+// commit drops the reservation and adds the used part of it to the
+// bucket usage
+func (r *reservation) commit(used Usage) {
+	r.b.mu.Lock()
+	defer r.b.mu.Unlock()
+	r.b.addPending(r.bucket, r.delta.neg())
+	r.b.updateUsage(r.bucket, used)
+}
+
+// holdPart replaces the reservation with the part size, which stays
+// reserved until the upload is completed or aborted
+func (r *reservation) holdPart(uploadId string, part int32, size int64) {
+	r.b.mu.Lock()
+	defer r.b.mu.Unlock()
+	r.b.addPending(r.bucket, r.delta.neg())
+
+	key := uploadKey{bucket: r.bucket, uploadId: strings.Clone(uploadId)}
+	parts, ok := r.b.uploads[key]
+	if !ok {
+		parts = make(map[int32]int64)
+		r.b.uploads[key] = parts
+	}
+	// an uploaded part replaces the previous upload of the part number
+	r.b.addPending(r.bucket, Usage{Bytes: size - parts[part]})
+	parts[part] = size
+}
+
+// addPending adds delta to the pending bucket usage, must be called
+// with the lock held
+func (b *Backend) addPending(bucket string, delta Usage) {
+	b.pending[bucket] = b.pending[bucket].add(delta)
+	if b.pending[bucket] == (Usage{}) {
+		delete(b.pending, bucket)
+	}
+}
+
+// uploadUsage returns the usage held by the upload part, or by all of
+// the upload parts if part is zero, must be called with the lock held
+func (b *Backend) uploadUsage(bucket, uploadId string, part int32) Usage {
+	parts := b.uploads[uploadKey{bucket: bucket, uploadId: uploadId}]
+	if part != 0 {
+		return Usage{Bytes: parts[part]}
+	}
+
+	var usage Usage
+	for _, size := range parts {
+		usage.Bytes += size
+	}
+	return usage
+}
+
+// dropUpload releases the usage held by the parts of the upload
+func (b *Backend) dropUpload(bucket, uploadId string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.addPending(bucket, b.uploadUsage(bucket, uploadId, 0).neg())
+	delete(b.uploads, uploadKey{bucket: bucket, uploadId: uploadId})
+}
+
+// pruneUploads drops the usage held by the uploads of the buckets of
+// the bucket owner that no longer exist in the backend, such as the
+// uploads aborted by lifecycle rules. It returns true if any upload
+// was dropped.
+func (b *Backend) pruneUploads(ctx context.Context, bucket string) bool {
+	b.mu.Lock()
+	var owner string
+	if rec, ok := b.conf.Buckets[bucket]; ok {
+		owner = rec.Owner
+	}
+	var keys []uploadKey
+	for key := range b.uploads {
+		rec, ok := b.conf.Buckets[key.bucket]
+		if key.bucket == bucket || (ok && rec.Owner == owner) {
+			keys = append(keys, key)
+		}
+	}
+	b.mu.Unlock()
+
+	var pruned bool
+	maxParts := int32(1)
+	for _, key := range keys {
+		_, err := b.Backend.ListParts(ctx, &s3.ListPartsInput{
+			Bucket:   &key.bucket,
+			UploadId: &key.uploadId,
+			MaxParts: &maxParts,
+		})
+		var apiErr s3err.APIError
+		if errors.As(err, &apiErr) && apiErr.Code == "NoSuchUpload" {
+			b.dropUpload(key.bucket, key.uploadId)
+			pruned = true
+		}
+	}
+
+	return pruned
+}
+
+// rescanAccount rescans the buckets owned by the account. It returns
+// true if the usage of any bucket changed.
+func (b *Backend) rescanAccount(ctx context.Context, access string) bool {
+	b.mu.Lock()
+	var buckets []string
+	for name, rec := range b.conf.Buckets {
+		if rec.Owner == access {
+			buckets = append(buckets, name)
+		}
+	}
+	b.mu.Unlock()
+
+	var changed bool
+	for _, name := range buckets {
+		if b.rescanBucket(ctx, name) {
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// rescanBucket counts the bucket contents again, unless the bucket was
+// rescanned within the rescan interval. It returns true if the bucket
+// usage changed.
+func (b *Backend) rescanBucket(ctx context.Context, bucket string) bool {
+	b.mu.Lock()
+	rec, ok := b.conf.Buckets[bucket]
+	if !ok || rec.scanning || time.Since(rec.rescanned) < rescanInterval {
+		b.mu.Unlock()
+		return false
+	}
+	rec.scanning = true
+	rec.scanDelta = Usage{}
+	b.mu.Unlock()
+
+	usage, err := b.scanBucket(ctx, bucket)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	rec.scanning = false
+	rec.rescanned = time.Now()
+	if err != nil || b.conf.Buckets[bucket] != rec {
+		return false
+	}
+
+	// the changes committed during the scan may be missing from the
+	// listing, these are added again. A change that is listed too is
+	// counted twice until the next rescan.
+	usage = usage.add(rec.scanDelta)
+	usage.Bytes = max(usage.Bytes, 0)
+	usage.Objects = max(usage.Objects, 0)
+	if usage == rec.Usage {
+		return false
+	}
+	rec.Usage = usage
+	b.storeUsage()
+
+	return true
+}
+
+func isQuotaExceeded(err error) bool {
+	var apiErr s3err.APIError
+	return errors.As(err, &apiErr) && apiErr.Code == "QuotaExceeded"
+}
+
+// bodyReader counts the bytes read from the request body, and fails the
+// read once the body is larger than the reserved size since the size
+// declared by the request is all the request was allowed to store
+type bodyReader struct {
+	r     io.Reader
+	limit int64
+	n     int64
+}
+
+func (br *bodyReader) Read(p []byte) (int, error) {
+	n, err := br.r.Read(p)
+	br.n += int64(n)
+	if br.n > br.limit {
+		return n, s3err.GetAPIError(s3err.ErrQuotaExceeded)
+	}
+	return n, err
+}
+
+// accountUsage sums the usage of all the buckets owned by the account,
+// must be called with the lock held
+func (b *Backend) accountUsage(access string, withPending bool) Usage {
+	var usage Usage
+	for name, rec := range b.conf.Buckets {
+		if rec.Owner != access {
+			continue
+		}
+		usage = usage.add(rec.Usage)
+		if withPending {
+			usage = usage.add(b.pending[name])
+		}
+	}
+
+	return usage
+}
+
+// updateUsage adds delta to the bucket usage, must be called with the
+// lock held
+func (b *Backend) updateUsage(bucket string, delta Usage) {
+	rec, ok := b.conf.Buckets[bucket]
+	if !ok {
+		// untracked buckets are counted when first needed
+		return
+	}
+
+	if rec.scanning {
+		rec.scanDelta = rec.scanDelta.add(delta)
+	}
+	rec.Usage = rec.Usage.add(delta)
+	rec.Usage.Bytes = max(rec.Usage.Bytes, 0)
+	rec.Usage.Objects = max(rec.Usage.Objects, 0)
+	b.storeUsage()
+}
+
+func (b *Backend) free(bucket string, usage Usage) {
+	if usage == (Usage{}) {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.updateUsage(bucket, usage.neg())
+}
+
+func (b *Backend) CreateBucket(ctx context.Context, input *s3.CreateBucketInput, defaultACL []byte) error {
+	err := b.Backend.CreateBucket(ctx, input, defaultACL)
+	if err != nil {
+		return err
+	}
+
+	owner, err := aclOwner(defaultACL)
+	if err != nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.conf.Buckets[strings.Clone(*input.Bucket)] = &bucketRecord{Owner: owner}
+	b.storeUsage()
+
+	return nil
+}
+
+func (b *Backend) DeleteBucket(ctx context.Context, input *s3.DeleteBucketInput) error {
+	err := b.Backend.DeleteBucket(ctx, input)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.conf.Buckets[*input.Bucket]; ok {
+		delete(b.conf.Buckets, *input.Bucket)
+		b.storeUsage()
+	}
+	for key := range b.uploads {
+		if key.bucket == *input.Bucket {
+			b.addPending(key.bucket, b.uploadUsage(key.bucket, key.uploadId, 0).neg())
+			delete(b.uploads, key)
+		}
+	}
+
+	return nil
+}
+
+func (b *Backend) ChangeBucketOwner(ctx context.Context, bucket, newOwner string) error {
+	err := b.Backend.ChangeBucketOwner(ctx, bucket, newOwner)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if rec, ok := b.conf.Buckets[bucket]; ok {
+		rec.Owner = strings.Clone(newOwner)
+		b.storeUsage()
+	}
+
+	return nil
+}
+
+func (b *Backend) PutObject(ctx context.Context, input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	if input.Bucket == nil || input.Key == nil {
+		return b.Backend.PutObject(ctx, input)
+	}
+
+	replaced := b.replacedUsage(ctx, *input.Bucket, *input.Key,
+		input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5)
+	size := getInt64(input.ContentLength)
+	delta := Usage{Bytes: size, Objects: 1}.add(replaced.neg())
+
+	r, err := b.reserve(ctx, *input.Bucket, "", 0, delta)
+	if err != nil {
+		return nil, err
+	}
+
+	// the usage is the size of the data actually stored, which is not
+	// the declared size for chunked uploads
+	body := &bodyReader{r: input.Body, limit: size}
+	if input.Body != nil {
+		in := *input
+		in.Body = body
+		input = &in
+	}
+
+	res, err := b.Backend.PutObject(ctx, input)
+	if err != nil {
+		r.release()
+		return res, err
+	}
+	if input.Body != nil {
+		delta = Usage{Bytes: body.n, Objects: 1}.add(replaced.neg())
+	}
+	r.commit(delta)
+	return res, nil
+}
+
+func (b *Backend) CopyObject(ctx context.Context, input *s3.CopyObjectInput) (*s3.CopyObjectOutput, error) {
+	if input.Bucket == nil || input.Key == nil || input.CopySource == nil {
+		return b.Backend.CopyObject(ctx, input)
+	}
+
+	src := b.sourceUsage(ctx, *input.CopySource, input.CopySourceSSECustomerAlgorithm,
+		input.CopySourceSSECustomerKey, input.CopySourceSSECustomerKeyMD5)
+	replaced := b.replacedUsage(ctx, *input.Bucket, *input.Key,
+		input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5)
+	delta := Usage{Bytes: src.Bytes, Objects: 1}.add(replaced.neg())
+
+	r, err := b.reserve(ctx, *input.Bucket, "", 0, delta)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := b.Backend.CopyObject(ctx, input)
+	if err != nil {
+		r.release()
+		return res, err
+	}
+	r.commit(delta)
+	return res, nil
+}
+
+// UploadPart reserves the part until the upload is completed or
+// aborted, the upload usage is added when the upload is completed
+func (b *Backend) UploadPart(ctx context.Context, input *s3.UploadPartInput) (*s3.UploadPartOutput, error) {
+	if input.Bucket == nil || input.UploadId == nil || input.PartNumber == nil {
+		return b.Backend.UploadPart(ctx, input)
+	}
+
+	size := getInt64(input.ContentLength)
+	r, err := b.reserve(ctx, *input.Bucket, *input.UploadId, *input.PartNumber, Usage{Bytes: size})
+	if err != nil {
+		return nil, err
+	}
+
+	body := &bodyReader{r: input.Body, limit: size}
+	if input.Body != nil {
+		in := *input
+		in.Body = body
+		input = &in
+	}
+
+	res, err := b.Backend.UploadPart(ctx, input)
+	if err != nil {
+		r.release()
+		return res, err
+	}
+	if input.Body != nil {
+		size = body.n
+	}
+	r.holdPart(*input.UploadId, *input.PartNumber, size)
+	return res, nil
+}
+
+func (b *Backend) UploadPartCopy(ctx context.Context, input *s3.UploadPartCopyInput) (s3response.CopyObjectResult, error) {
+	if input.Bucket == nil || input.CopySource == nil || input.UploadId == nil || input.PartNumber == nil {
+		return b.Backend.UploadPartCopy(ctx, input)
+	}
+
+	size := b.sourceUsage(ctx, *input.CopySource, input.CopySourceSSECustomerAlgorithm,
+		input.CopySourceSSECustomerKey, input.CopySourceSSECustomerKeyMD5).Bytes
+	if input.CopySourceRange != nil {
+		var start, end int64
+		_, err := fmt.Sscanf(*input.CopySourceRange, "bytes=%d-%d", &start, &end)
+		if err == nil && end >= start {
+			size = min(size, end-start+1)
+		}
+	}
+
+	r, err := b.reserve(ctx, *input.Bucket, *input.UploadId, *input.PartNumber, Usage{Bytes: size})
+	if err != nil {
+		return s3response.CopyObjectResult{}, err
+	}
+
+	res, err := b.Backend.UploadPartCopy(ctx, input)
+	if err != nil {
+		r.release()
+		return res, err
+	}
+	r.holdPart(*input.UploadId, *input.PartNumber, size)
+	return res, nil
+}
+
+func (b *Backend) CompleteMultipartUpload(ctx context.Context, input *s3.CompleteMultipartUploadInput) (*s3.CompleteMultipartUploadOutput, error) {
+	if input.Bucket == nil || input.Key == nil || input.UploadId == nil || input.MultipartUpload == nil {
+		return b.Backend.CompleteMultipartUpload(ctx, input)
+	}
+
+	size, err := b.uploadSize(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	replaced := b.replacedUsage(ctx, *input.Bucket, *input.Key,
+		input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5)
+	delta := Usage{Bytes: size, Objects: 1}.add(replaced.neg())
+
+	r, err := b.reserve(ctx, *input.Bucket, *input.UploadId, 0, delta)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := b.Backend.CompleteMultipartUpload(ctx, input)
+	if err != nil {
+		r.release()
+		return res, err
+	}
+	r.commit(delta)
+	b.dropUpload(*input.Bucket, *input.UploadId)
+	return res, nil
+}
+
+func (b *Backend) AbortMultipartUpload(ctx context.Context, input *s3.AbortMultipartUploadInput) error {
+	err := b.Backend.AbortMultipartUpload(ctx, input)
+	if err == nil && input.Bucket != nil && input.UploadId != nil {
+		b.dropUpload(*input.Bucket, *input.UploadId)
+	}
+	return err
+}
+
+// uploadSize sums the size of the uploaded parts that are completed
+func (b *Backend) uploadSize(ctx context.Context, input *s3.CompleteMultipartUploadInput) (int64, error) {
+	complete := make(map[int32]bool)
+	for _, p := range input.MultipartUpload.Parts {
+		if p.PartNumber != nil {
+			complete[*p.PartNumber] = true
+		}
+	}
+
+	var size int64
+	var marker *string
+	for {
+		res, err := b.Backend.ListParts(ctx, &s3.ListPartsInput{
+			Bucket:           input.Bucket,
+			Key:              input.Key,
+			UploadId:         input.UploadId,
+			PartNumberMarker: marker,
+		})
+		if err != nil {
+			return 0, err
+		}
+		for _, p := range res.Parts {
+			if complete[int32(p.PartNumber)] {
+				size += p.Size
+			}
+		}
+		if !res.IsTruncated {
+			return size, nil
+		}
+		next := fmt.Sprint(res.NextPartNumberMarker)
+		marker = &next
+	}
+}
+
+func (b *Backend) DeleteObject(ctx context.Context, input *s3.DeleteObjectInput) (*s3.DeleteObjectOutput, error) {
+	if input.Bucket == nil || input.Key == nil {
+		return b.Backend.DeleteObject(ctx, input)
+	}
+
+	freed := b.deletedUsage(ctx, *input.Bucket, *input.Key, getString(input.VersionId))
+
+	res, err := b.Backend.DeleteObject(ctx, input)
+	if err == nil {
+		b.free(*input.Bucket, freed)
+	}
+	return res, err
+}
+
+func (b *Backend) DeleteObjects(ctx context.Context, input *s3.DeleteObjectsInput) (s3response.DeleteResult, error) {
+	if input.Bucket == nil || input.Delete == nil {
+		return b.Backend.DeleteObjects(ctx, input)
+	}
+
+	freed := make([]Usage, len(input.Delete.Objects))
+	for i, obj := range input.Delete.Objects {
+		freed[i] = b.deletedUsage(ctx, *input.Bucket, getString(obj.Key), getString(obj.VersionId))
+	}
+
+	res, err := b.Backend.DeleteObjects(ctx, input)
+	if err != nil {
+		return res, err
+	}
+
+	deleted := make(map[string]bool)
+	for _, obj := range res.Deleted {
+		deleted[getString(obj.Key)] = true
+	}
+
+	var total Usage
+	for i, obj := range input.Delete.Objects {
+		if deleted[getString(obj.Key)] {
+			total = total.add(freed[i])
+		}
+	}
+	b.free(*input.Bucket, total)
+
+	return res, nil
+}
+
+// deletedUsage returns the usage freed by deleting the object, deleting
+// without a version only adds a delete marker in versioned buckets
+func (b *Backend) deletedUsage(ctx context.Context, bucket, key, versionId string) Usage {
+	if key == "" {
+		return Usage{}
+	}
+	if versionId == "" {
+		return b.replacedUsage(ctx, bucket, key, nil, nil, nil)
+	}
+
+	return b.objectUsage(ctx, &s3.HeadObjectInput{
+		Bucket:    &bucket,
+		Key:       &key,
+		VersionId: &versionId,
+	})
+}
+
+// ownedBuckets makes sure all of the buckets owned by the account have
+// usage records
+func (b *Backend) ownedBuckets(ctx context.Context, access string) error {
+	buckets, err := b.Backend.ListBucketsAndOwners(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, bucket := range buckets {
+		if bucket.Owner != access {
+			continue
+		}
+		err := b.initBucket(ctx, bucket.Name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PutBucketQuota sets the bucket quota, zero limits remove the quota
+func (b *Backend) PutBucketQuota(ctx context.Context, bucket string, limits Limits) error {
+	if limits.MaxBytes < 0 || limits.MaxObjects < 0 {
+		return fmt.Errorf("invalid quota: limits can not be negative")
+	}
+
+	err := b.initBucket(ctx, bucket)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	rec, ok := b.conf.Buckets[bucket]
+	if !ok {
+		b.mu.Unlock()
+		return s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+	rec.Limits = limits
+	b.mu.Unlock()
+
+	return b.store()
+}
+
+// GetBucketQuota returns the bucket quota and usage
+func (b *Backend) GetBucketQuota(ctx context.Context, bucket string) (Quota, error) {
+	err := b.initBucket(ctx, bucket)
+	if err != nil {
+		return Quota{}, err
+	}
+	b.rescanBucket(ctx, bucket)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	rec, ok := b.conf.Buckets[bucket]
+	if !ok {
+		return Quota{}, s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+
+	return Quota{Limits: rec.Limits, Usage: rec.Usage}, nil
+}
+
+// PutAccountQuota sets the quota of all the buckets owned by the
+// account, zero limits remove the quota
+func (b *Backend) PutAccountQuota(ctx context.Context, access string, limits Limits) error {
+	if limits.MaxBytes < 0 || limits.MaxObjects < 0 {
+		return fmt.Errorf("invalid quota: limits can not be negative")
+	}
+
+	err := b.ownedBuckets(ctx, access)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	if limits == (Limits{}) {
+		delete(b.conf.Accounts, access)
+	} else {
+		b.conf.Accounts[strings.Clone(access)] = limits
+	}
+	b.mu.Unlock()
+
+	return b.store()
+}
+
+// GetAccountQuota returns the account quota and the usage of all the
+// buckets owned by the account
+func (b *Backend) GetAccountQuota(ctx context.Context, access string) (Quota, error) {
+	err := b.ownedBuckets(ctx, access)
+	if err != nil {
+		return Quota{}, err
+	}
+	b.rescanAccount(ctx, access)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return Quota{
+		Limits: b.conf.Accounts[access],
+		Usage:  b.accountUsage(access, false),
+	}, nil
+}
+
+func getString(str *string) string {
+	if str == nil {
+		return ""
+	}
+	return *str
+}
+
+func getInt64(i *int64) int64 {
+	if i == nil {
+		return 0
+	}
+	return *i
+}
@@ -0,0 +1,475 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package quota_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/versity/versitygw/auth"
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/backend/posix"
+	"github.com/versity/versitygw/backend/quota"
+	"github.com/versity/versitygw/s3err"
+	"github.com/versity/versitygw/s3response"
+)
+
+// memBackend is an unversioned in memory backend storing only the
+// object sizes
+type memBackend struct {
+	backend.BackendUnsupported
+	owners  map[string]string
+	objects map[string]map[string]int64
+	uploads map[string]bool
+}
+
+func newMemBackend() *memBackend {
+	return &memBackend{
+		owners:  make(map[string]string),
+		objects: make(map[string]map[string]int64),
+		uploads: make(map[string]bool),
+	}
+}
+
+func (m *memBackend) CreateBucket(_ context.Context, input *s3.CreateBucketInput, acl []byte) error {
+	var a auth.ACL
+	err := json.Unmarshal(acl, &a)
+	if err != nil {
+		return err
+	}
+	m.owners[*input.Bucket] = a.Owner
+	m.objects[*input.Bucket] = make(map[string]int64)
+	return nil
+}
+
+func (m *memBackend) GetBucketAcl(_ context.Context, input *s3.GetBucketAclInput) ([]byte, error) {
+	owner, ok := m.owners[*input.Bucket]
+	if !ok {
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchBucket)
+	}
+	return json.Marshal(auth.ACL{Owner: owner})
+}
+
+func (m *memBackend) GetBucketVersioning(context.Context, string) (*s3.GetBucketVersioningOutput, error) {
+	return &s3.GetBucketVersioningOutput{}, nil
+}
+
+func (m *memBackend) ListBucketsAndOwners(context.Context) ([]s3response.Bucket, error) {
+	var buckets []s3response.Bucket
+	for name, owner := range m.owners {
+		buckets = append(buckets, s3response.Bucket{Name: name, Owner: owner})
+	}
+	return buckets, nil
+}
+
+func (m *memBackend) ListObjectsV2(_ context.Context, input *s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error) {
+	var contents []types.Object
+	for key, size := range m.objects[*input.Bucket] {
+		contents = append(contents, types.Object{Key: &key, Size: &size})
+	}
+	return &s3.ListObjectsV2Output{Contents: contents}, nil
+}
+
+func (m *memBackend) HeadObject(_ context.Context, input *s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+	size, ok := m.objects[*input.Bucket][*input.Key]
+	if !ok {
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchKey)
+	}
+	return &s3.HeadObjectOutput{ContentLength: &size}, nil
+}
+
+func (m *memBackend) PutObject(_ context.Context, input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	size := *input.ContentLength
+	if input.Body != nil {
+		n, err := io.Copy(io.Discard, input.Body)
+		if err != nil {
+			return nil, err
+		}
+		size = n
+	}
+	m.objects[*input.Bucket][*input.Key] = size
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (m *memBackend) UploadPart(_ context.Context, input *s3.UploadPartInput) (*s3.UploadPartOutput, error) {
+	if !m.uploads[*input.UploadId] {
+		return nil, s3err.GetAPIError(s3err.ErrNoSuchUpload)
+	}
+	_, err := io.Copy(io.Discard, input.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &s3.UploadPartOutput{}, nil
+}
+
+func (m *memBackend) ListParts(_ context.Context, input *s3.ListPartsInput) (s3response.ListPartsResult, error) {
+	if !m.uploads[*input.UploadId] {
+		return s3response.ListPartsResult{}, s3err.GetAPIError(s3err.ErrNoSuchUpload)
+	}
+	return s3response.ListPartsResult{}, nil
+}
+
+func (m *memBackend) AbortMultipartUpload(_ context.Context, input *s3.AbortMultipartUploadInput) error {
+	delete(m.uploads, *input.UploadId)
+	return nil
+}
+
+func (m *memBackend) DeleteObject(_ context.Context, input *s3.DeleteObjectInput) (*s3.DeleteObjectOutput, error) {
+	delete(m.objects[*input.Bucket], *input.Key)
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func putObject(b *quota.Backend, bucket, key string, size int64) error {
+	_, err := b.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket:        &bucket,
+		Key:           &key,
+		ContentLength: &size,
+	})
+	return err
+}
+
+func deleteObject(b *quota.Backend, bucket, key string) error {
+	_, err := b.DeleteObject(context.Background(), &s3.DeleteObjectInput{
+		Bucket: &bucket,
+		Key:    &key,
+	})
+	return err
+}
+
+func uploadPart(b *quota.Backend, bucket, uploadId string, part int32, size int64) error {
+	_, err := b.UploadPart(context.Background(), &s3.UploadPartInput{
+		Bucket:        &bucket,
+		UploadId:      &uploadId,
+		PartNumber:    &part,
+		ContentLength: &size,
+		Body:          strings.NewReader(strings.Repeat("a", int(size))),
+	})
+	return err
+}
+
+func isQuotaExceeded(err error) bool {
+	var apiErr s3err.APIError
+	return errors.As(err, &apiErr) && apiErr.Code == "QuotaExceeded"
+}
+
+func TestBucketQuota(t *testing.T) {
+	ctx := context.Background()
+	mem := newMemBackend()
+	mem.owners["bucket"] = "user"
+	mem.objects["bucket"] = map[string]int64{"existing": 10}
+
+	b, err := quota.New(mem, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Shutdown()
+
+	err = b.PutBucketQuota(ctx, "bucket", quota.Limits{MaxBytes: 100, MaxObjects: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q, err := b.GetBucketQuota(ctx, "bucket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Usage != (quota.Usage{Bytes: 10, Objects: 1}) {
+		t.Fatalf("expected the existing object to be counted, got %+v", q.Usage)
+	}
+
+	if err := putObject(b, "bucket", "obj1", 80); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := putObject(b, "bucket", "obj2", 20); !isQuotaExceeded(err) {
+		t.Fatalf("expected QuotaExceeded for bytes, got %v", err)
+	}
+	if _, ok := mem.objects["bucket"]["obj2"]; ok {
+		t.Fatal("expected the object not to be written")
+	}
+
+	// overwriting frees the replaced object
+	if err := putObject(b, "bucket", "obj1", 90); err != nil {
+		t.Fatalf("expected overwrite to fit in the quota, got %v", err)
+	}
+	if err := putObject(b, "bucket", "obj2", 0); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := putObject(b, "bucket", "obj3", 0); !isQuotaExceeded(err) {
+		t.Fatalf("expected QuotaExceeded for objects, got %v", err)
+	}
+
+	if err := deleteObject(b, "bucket", "obj1"); err != nil {
+		t.Fatal(err)
+	}
+	q, err = b.GetBucketQuota(ctx, "bucket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Usage != (quota.Usage{Bytes: 10, Objects: 2}) {
+		t.Fatalf("unexpected usage after delete: %+v", q.Usage)
+	}
+	if q.Limits != (quota.Limits{MaxBytes: 100, MaxObjects: 3}) {
+		t.Fatalf("unexpected limits: %+v", q.Limits)
+	}
+}
+
+func TestAccountQuota(t *testing.T) {
+	ctx := context.Background()
+	mem := newMemBackend()
+	dir := t.TempDir()
+
+	b, err := quota.New(mem, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	acl, _ := json.Marshal(auth.ACL{Owner: "user"})
+	for _, bucket := range []string{"bucket1", "bucket2"} {
+		bucket := bucket
+		err := b.CreateBucket(ctx, &s3.CreateBucketInput{Bucket: &bucket}, acl)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err = b.PutAccountQuota(ctx, "user", quota.Limits{MaxBytes: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := putObject(b, "bucket1", "obj", 30); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := putObject(b, "bucket2", "obj", 30); !isQuotaExceeded(err) {
+		t.Fatalf("expected QuotaExceeded for the account, got %v", err)
+	}
+
+	// the quota and usage are kept across restarts
+	b.Shutdown()
+	b, err = quota.New(mem, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Shutdown()
+	q, err := b.GetAccountQuota(ctx, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.MaxBytes != 50 || q.Usage != (quota.Usage{Bytes: 30, Objects: 1}) {
+		t.Fatalf("unexpected account quota: %+v", q)
+	}
+	if err := putObject(b, "bucket2", "obj", 30); !isQuotaExceeded(err) {
+		t.Fatalf("expected QuotaExceeded after reload, got %v", err)
+	}
+
+	err = b.PutAccountQuota(ctx, "user", quota.Limits{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := putObject(b, "bucket2", "obj", 30); err != nil {
+		t.Fatalf("expected no error after removing the quota, got %v", err)
+	}
+}
+
+func TestPutObjectBodySize(t *testing.T) {
+	ctx := context.Background()
+	mem := newMemBackend()
+	mem.owners["bucket"] = "user"
+	mem.objects["bucket"] = make(map[string]int64)
+
+	b, err := quota.New(mem, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Shutdown()
+
+	bucket, key := "bucket", "obj"
+	put := func(declared int64, data string) error {
+		_, err := b.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:        &bucket,
+			Key:           &key,
+			ContentLength: &declared,
+			Body:          strings.NewReader(data),
+		})
+		return err
+	}
+
+	if err := put(10, strings.Repeat("a", 20)); !isQuotaExceeded(err) {
+		t.Fatalf("expected QuotaExceeded for a body over the declared size, got %v", err)
+	}
+	if err := put(100, strings.Repeat("a", 30)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	q, err := b.GetBucketQuota(ctx, bucket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Usage != (quota.Usage{Bytes: 30, Objects: 1}) {
+		t.Fatalf("expected the stored size to be counted, got %+v", q.Usage)
+	}
+}
+
+func TestUploadPartReserved(t *testing.T) {
+	ctx := context.Background()
+	mem := newMemBackend()
+	mem.owners["bucket"] = "user"
+	mem.objects["bucket"] = make(map[string]int64)
+	mem.uploads["upload1"] = true
+	mem.uploads["upload2"] = true
+
+	b, err := quota.New(mem, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Shutdown()
+
+	err = b.PutBucketQuota(ctx, "bucket", quota.Limits{MaxBytes: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := uploadPart(b, "bucket", "upload1", 1, 60); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	// the re-uploaded part replaces the reservation of the part
+	if err := uploadPart(b, "bucket", "upload1", 1, 60); err != nil {
+		t.Fatalf("expected the re-uploaded part to fit, got %v", err)
+	}
+	if err := putObject(b, "bucket", "obj", 50); !isQuotaExceeded(err) {
+		t.Fatalf("expected the uploaded part to be reserved, got %v", err)
+	}
+
+	uploadId := "upload1"
+	bucket := "bucket"
+	err = b.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   &bucket,
+		UploadId: &uploadId,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := putObject(b, "bucket", "obj", 50); err != nil {
+		t.Fatalf("expected the aborted upload to be released, got %v", err)
+	}
+
+	// uploads removed outside of the gateway are released when the
+	// quota is exceeded
+	if err := uploadPart(b, "bucket", "upload2", 1, 50); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	delete(mem.uploads, "upload2")
+	if err := putObject(b, "bucket", "obj2", 50); err != nil {
+		t.Fatalf("expected the removed upload to be released, got %v", err)
+	}
+}
+
+func TestLifecycleExpiration(t *testing.T) {
+	ctx := context.Background()
+
+	// posix changes the working directory to the backend root
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	p, err := posix.New(t.TempDir(), posix.WithLifecycleScan(10*time.Millisecond))
+	if err != nil {
+		t.Skipf("posix backend: %v", err)
+	}
+
+	b, err := quota.New(p, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Shutdown()
+
+	bucket := "bucket"
+	acl, err := json.Marshal(auth.ACL{Owner: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = b.CreateBucket(ctx, &s3.CreateBucketInput{Bucket: &bucket}, acl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = b.PutBucketQuota(ctx, bucket, quota.Limits{MaxObjects: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	put := func(key string) error {
+		_, err := b.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:        &bucket,
+			Key:           &key,
+			Body:          strings.NewReader("data"),
+			ContentLength: aws.Int64(4),
+		})
+		return err
+	}
+
+	if err := put("logs/obj"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the object is removed by the backend lifecycle scan, not through
+	// the quota backend
+	err = p.PutBucketLifecycleConfiguration(ctx, bucket, s3response.LifecycleConfiguration{
+		Rules: []s3response.LifecycleRule{{
+			Status: types.ExpirationStatusEnabled,
+			Filter: &s3response.LifecycleRuleFilter{Prefix: aws.String("logs/")},
+			Expiration: &types.LifecycleExpiration{
+				Date: aws.Time(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)),
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := "logs/obj"
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, err := p.HeadObject(ctx, &s3.HeadObjectInput{Bucket: &bucket, Key: &key})
+		if err != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the object to be expired")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := put("obj"); err != nil {
+		t.Fatalf("expected the expired object to be released, got %v", err)
+	}
+	q, err := b.GetBucketQuota(ctx, bucket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Usage != (quota.Usage{Bytes: 4, Objects: 1}) {
+		t.Fatalf("expected the usage of the remaining object, got %+v", q.Usage)
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/smithy-go/middleware"
+	"github.com/versity/versitygw/tracing"
 )
 
 func (s *S3Proxy) getClientWithCtx(ctx context.Context) (*s3.Client, error) {
@@ -42,7 +43,8 @@ func (s *S3Proxy) getConfig(ctx context.Context, access, secret string) (aws.Con
 	tr := &http.Transport{
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: s.sslSkipVerify},
 	}
-	client := &http.Client{Transport: tr}
+	// the trace context is propagated to the upstream S3 service
+	client := &http.Client{Transport: tracing.Transport(tr)}
 
 	opts := []func(*config.LoadOptions) error{
 		config.WithRegion(s.awsRegion),
@@ -15,6 +15,7 @@
 package scoutfs
 
 import (
+	"bufio"
 	"context"
 	"crypto/sha256"
 	"encoding/json"
@@ -29,9 +30,12 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/pkg/xattr"
+	"github.com/versity/versitygw/auth"
 	"github.com/versity/versitygw/backend"
 	"github.com/versity/versitygw/backend/posix"
+	"github.com/versity/versitygw/backend/sse"
 	"github.com/versity/versitygw/s3err"
+	"github.com/versity/versitygw/s3select"
 )
 
 type ScoutFS struct {
@@ -49,6 +53,19 @@ type ScoutFS struct {
 	// ListObjects: if file offline, set obj storage class to GLACIER
 	// RestoreObject: add batch stage request to file
 	glaciermode bool
+
+	// chownuid/chowngid enable setting the owner of newly created
+	// objects and directories to the requesting account uid/gid
+	chownuid bool
+	chowngid bool
+
+	// setprojectid enables setting the scoutfs project ID of newly
+	// created objects to the requesting account ProjectID
+	setprojectid bool
+
+	// sseMasterKey protects the data keys of SSE-S3 encrypted objects,
+	// SSE-S3 is not available when this is not set
+	sseMasterKey []byte
 }
 
 var _ backend.Backend = &ScoutFS{}
@@ -100,6 +117,22 @@ func WithGlacierEmulation() Option {
 	return func(s *ScoutFS) { s.glaciermode = true }
 }
 
+// WithChownUID sets the owner uid of new objects to the account UserID
+func WithChownUID() Option {
+	return func(s *ScoutFS) { s.chownuid = true }
+}
+
+// WithChownGID sets the owner gid of new objects to the account GroupID
+func WithChownGID() Option {
+	return func(s *ScoutFS) { s.chowngid = true }
+}
+
+// WithSetProjectID sets the project ID of new objects, parts and
+// directories to the account ProjectID
+func WithSetProjectID() Option {
+	return func(s *ScoutFS) { s.setprojectid = true }
+}
+
 func (s *ScoutFS) Shutdown() {
 	s.Posix.Shutdown()
 	s.rootfd.Close()
@@ -110,10 +143,29 @@ func (*ScoutFS) String() string {
 	return "ScoutFS Gateway"
 }
 
+// PutBucketVersioning object versioning is not yet supported with the
+// scoutfs multipart upload and glacier mode object handling
+func (s *ScoutFS) PutBucketVersioning(_ context.Context, _ *s3.PutBucketVersioningInput) error {
+	return s3err.GetAPIError(s3err.ErrNotImplemented)
+}
+
+// PutObjectLockConfiguration object lock is not yet enforced by the
+// scoutfs multipart upload handling
+func (s *ScoutFS) PutObjectLockConfiguration(_ context.Context, _ *s3.PutObjectLockConfigurationInput) error {
+	return s3err.GetAPIError(s3err.ErrNotImplemented)
+}
+
+func (s *ScoutFS) CreateBucket(ctx context.Context, input *s3.CreateBucketInput, acl []byte) error {
+	if input.ObjectLockEnabledForBucket != nil && *input.ObjectLockEnabledForBucket {
+		return s3err.GetAPIError(s3err.ErrNotImplemented)
+	}
+	return s.Posix.CreateBucket(ctx, input, acl)
+}
+
 // CompleteMultipartUpload scoutfs complete upload uses scoutfs move blocks
 // ioctl to not have to read and copy the part data to the final object. This
 // saves a read and write cycle for all mutlipart uploads.
-func (s *ScoutFS) CompleteMultipartUpload(_ context.Context, input *s3.CompleteMultipartUploadInput) (*s3.CompleteMultipartUploadOutput, error) {
+func (s *ScoutFS) CompleteMultipartUpload(ctx context.Context, input *s3.CompleteMultipartUploadInput) (*s3.CompleteMultipartUploadOutput, error) {
 	bucket := *input.Bucket
 	object := *input.Key
 	uploadID := *input.UploadId
@@ -172,14 +224,32 @@ func (s *ScoutFS) CompleteMultipartUpload(_ context.Context, input *s3.CompleteM
 		}
 	}
 
+	upiddir := filepath.Join(objdir, uploadID)
+	encInfo, err := posix.MultipartEncryption(upiddir, parts)
+	if err != nil {
+		return nil, err
+	}
+
+	checksum, err := posix.MultipartChecksum(upiddir, parts)
+	if err != nil {
+		return nil, err
+	}
+
 	// use totalsize=0 because we wont be writing to the file, only moving
 	// extents around.  so we dont want to fallocate this.
+	acct := posix.AccountFromContext(ctx)
+
 	f, err := openTmpFile(filepath.Join(bucket, metaTmpDir), bucket, object, 0)
 	if err != nil {
 		return nil, fmt.Errorf("open temp file: %w", err)
 	}
 	defer f.cleanup()
 
+	err = s.Fchown(f.f, acct)
+	if err != nil {
+		return nil, err
+	}
+
 	for _, p := range parts {
 		pf, err := os.Open(filepath.Join(objdir, uploadID, fmt.Sprintf("%v", *p.PartNumber)))
 		if err != nil {
@@ -196,14 +266,20 @@ func (s *ScoutFS) CompleteMultipartUpload(_ context.Context, input *s3.CompleteM
 		}
 	}
 
+	if encInfo != nil {
+		err = sse.FStore(f.f, encInfo)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	userMetaData := make(map[string]string)
-	upiddir := filepath.Join(objdir, uploadID)
 	loadUserMetaData(upiddir, userMetaData)
 
 	objname := filepath.Join(bucket, object)
 	dir := filepath.Dir(objname)
 	if dir != "" {
-		err = mkdirAll(dir, os.FileMode(0755), bucket, object)
+		err = s.mkdirAll(dir, os.FileMode(0755), bucket, object, acct)
 		if err != nil {
 			return nil, s3err.GetAPIError(s3err.ErrExistingObjectIsDirectory)
 		}
@@ -232,16 +308,31 @@ func (s *ScoutFS) CompleteMultipartUpload(_ context.Context, input *s3.CompleteM
 		return nil, fmt.Errorf("set etag attr: %w", err)
 	}
 
+	err = posix.SetChecksum(objname, checksum)
+	if err != nil {
+		// cleanup object if returning error
+		os.Remove(objname)
+		return nil, err
+	}
+
 	// cleanup tmp dirs
 	os.RemoveAll(upiddir)
 	// use Remove for objdir in case there are still other uploads
 	// for same object name outstanding
 	os.Remove(objdir)
 
+	sseAlg, _, _ := encInfo.Headers()
+	csumCRC32, csumCRC32C, csumSHA1, csumSHA256 := checksum.Fields()
+
 	return &s3.CompleteMultipartUploadOutput{
-		Bucket: &bucket,
-		ETag:   &s3MD5,
-		Key:    &object,
+		Bucket:               &bucket,
+		ETag:                 &s3MD5,
+		Key:                  &object,
+		ServerSideEncryption: sseAlg,
+		ChecksumCRC32:        csumCRC32,
+		ChecksumCRC32C:       csumCRC32C,
+		ChecksumSHA1:         csumSHA1,
+		ChecksumSHA256:       csumSHA256,
 	}, nil
 }
 
@@ -311,8 +402,9 @@ func isValidMeta(val string) bool {
 }
 
 // mkdirAll is similar to os.MkdirAll but it will return ErrObjectParentIsFile
-// when appropriate
-func mkdirAll(path string, perm os.FileMode, bucket, object string) error {
+// when appropriate, and sets the owner of any created directories to the
+// account when chown is enabled
+func (s *ScoutFS) mkdirAll(path string, perm os.FileMode, bucket, object string, acct auth.Account) error {
 	// Fast path: if we can tell whether path is a directory or file, stop with success or error.
 	dir, err := os.Stat(path)
 	if err == nil {
@@ -335,7 +427,7 @@ func mkdirAll(path string, perm os.FileMode, bucket, object string) error {
 
 	if j > 1 {
 		// Create parent.
-		err = mkdirAll(path[:j-1], perm, bucket, object)
+		err = s.mkdirAll(path[:j-1], perm, bucket, object, acct)
 		if err != nil {
 			return err
 		}
@@ -352,6 +444,20 @@ func mkdirAll(path string, perm os.FileMode, bucket, object string) error {
 		}
 		return s3err.GetAPIError(s3err.ErrObjectParentIsFile)
 	}
+	return s.Chown(path, acct)
+}
+
+// fsetProjectID sets the scoutfs project ID of the file to the account
+// ProjectID, this is called by posix for each created file and directory
+func (s *ScoutFS) fsetProjectID(f *os.File, acct auth.Account) error {
+	if acct.ProjectID == 0 {
+		return nil
+	}
+
+	err := fsetProjectID(f, acct.ProjectID)
+	if err != nil {
+		return fmt.Errorf("set project id: %w", err)
+	}
 	return nil
 }
 
@@ -376,6 +482,19 @@ func (s *ScoutFS) HeadObject(_ context.Context, input *s3.HeadObjectInput) (*s3.
 		return nil, fmt.Errorf("stat object: %w", err)
 	}
 
+	encReq, err := sse.ParseRequest("", input.SSECustomerAlgorithm,
+		input.SSECustomerKey, input.SSECustomerKeyMD5)
+	if err != nil {
+		return nil, err
+	}
+
+	// SSE-C objects require the customer key for HEAD as well
+	encInfo, _, err := s.ObjectEncryption(objPath, encReq)
+	if err != nil {
+		return nil, err
+	}
+	fi = sse.FileInfo(fi, encInfo)
+
 	userMetaData := make(map[string]string)
 	contentType, contentEncoding := loadUserMetaData(objPath, userMetaData)
 
@@ -418,15 +537,33 @@ func (s *ScoutFS) HeadObject(_ context.Context, input *s3.HeadObjectInput) (*s3.
 
 	contentLength := fi.Size()
 
+	var checksum backend.Checksum
+	if input.ChecksumMode == types.ChecksumModeEnabled {
+		checksum, err = posix.GetChecksum(objPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sseAlg, sseCAlg, sseCKeyMD5 := encInfo.Headers()
+	csumCRC32, csumCRC32C, csumSHA1, csumSHA256 := checksum.Fields()
+
 	return &s3.HeadObjectOutput{
-		ContentLength:   &contentLength,
-		ContentType:     &contentType,
-		ContentEncoding: &contentEncoding,
-		ETag:            &etag,
-		LastModified:    backend.GetTimePtr(fi.ModTime()),
-		Metadata:        userMetaData,
-		StorageClass:    stclass,
-		Restore:         &requestOngoing,
+		ContentLength:        &contentLength,
+		ContentType:          &contentType,
+		ContentEncoding:      &contentEncoding,
+		ETag:                 &etag,
+		LastModified:         backend.GetTimePtr(fi.ModTime()),
+		Metadata:             userMetaData,
+		StorageClass:         stclass,
+		Restore:              &requestOngoing,
+		ServerSideEncryption: sseAlg,
+		SSECustomerAlgorithm: sseCAlg,
+		SSECustomerKeyMD5:    sseCKeyMD5,
+		ChecksumCRC32:        csumCRC32,
+		ChecksumCRC32C:       csumCRC32C,
+		ChecksumSHA1:         csumSHA1,
+		ChecksumSHA256:       csumSHA256,
 	}, nil
 }
 
@@ -452,6 +589,18 @@ func (s *ScoutFS) GetObject(_ context.Context, input *s3.GetObjectInput, writer
 		return nil, fmt.Errorf("stat object: %w", err)
 	}
 
+	encReq, err := sse.ParseRequest("", input.SSECustomerAlgorithm,
+		input.SSECustomerKey, input.SSECustomerKeyMD5)
+	if err != nil {
+		return nil, err
+	}
+
+	encInfo, dataKey, err := s.ObjectEncryption(objPath, encReq)
+	if err != nil {
+		return nil, err
+	}
+	fi = sse.FileInfo(fi, encInfo)
+
 	startOffset, length, err := backend.ParseRange(fi, acceptRange)
 	if err != nil {
 		return nil, err
@@ -501,8 +650,11 @@ func (s *ScoutFS) GetObject(_ context.Context, input *s3.GetObjectInput, writer
 	}
 	defer f.Close()
 
-	rdr := io.NewSectionReader(f, startOffset, length)
-	_, err = io.Copy(writer, rdr)
+	if encInfo != nil {
+		err = encInfo.Decrypt(writer, f, dataKey, startOffset, length)
+	} else {
+		_, err = io.Copy(writer, io.NewSectionReader(f, startOffset, length))
+	}
 	if err != nil {
 		return nil, fmt.Errorf("copy data: %w", err)
 	}
@@ -524,20 +676,79 @@ func (s *ScoutFS) GetObject(_ context.Context, input *s3.GetObjectInput, writer
 
 	tagCount := int32(len(tags))
 
+	// the checksum is of the full object, so it is not returned
+	// for range requests
+	var checksum backend.Checksum
+	if input.ChecksumMode == types.ChecksumModeEnabled && acceptRange == "" {
+		checksum, err = posix.GetChecksum(objPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sseAlg, sseCAlg, sseCKeyMD5 := encInfo.Headers()
+	csumCRC32, csumCRC32C, csumSHA1, csumSHA256 := checksum.Fields()
+
 	return &s3.GetObjectOutput{
-		AcceptRanges:    &acceptRange,
-		ContentLength:   &length,
-		ContentEncoding: &contentEncoding,
-		ContentType:     &contentType,
-		ETag:            &etag,
-		LastModified:    backend.GetTimePtr(fi.ModTime()),
-		Metadata:        userMetaData,
-		TagCount:        &tagCount,
-		StorageClass:    types.StorageClassStandard,
-		ContentRange:    &contentRange,
+		AcceptRanges:         &acceptRange,
+		ContentLength:        &length,
+		ContentEncoding:      &contentEncoding,
+		ContentType:          &contentType,
+		ETag:                 &etag,
+		LastModified:         backend.GetTimePtr(fi.ModTime()),
+		Metadata:             userMetaData,
+		TagCount:             &tagCount,
+		StorageClass:         types.StorageClassStandard,
+		ContentRange:         &contentRange,
+		ServerSideEncryption: sseAlg,
+		SSECustomerAlgorithm: sseCAlg,
+		SSECustomerKeyMD5:    sseCKeyMD5,
+		ChecksumCRC32:        csumCRC32,
+		ChecksumCRC32C:       csumCRC32C,
+		ChecksumSHA1:         csumSHA1,
+		ChecksumSHA256:       csumSHA256,
 	}, nil
 }
 
+// SelectObjectContent uses the posix select after checking that glacier
+// mode objects are not offline
+func (s *ScoutFS) SelectObjectContent(ctx context.Context, input *s3.SelectObjectContentInput) func(w *bufio.Writer) {
+	if !s.glaciermode {
+		return s.Posix.SelectObjectContent(ctx, input)
+	}
+
+	return func(w *bufio.Writer) {
+		err := checkOnline(input.Bucket, input.Key)
+		if err != nil {
+			s3select.SendError(ctx, w, err)
+			return
+		}
+
+		s.Posix.SelectObjectContent(ctx, input)(w)
+	}
+}
+
+// checkOnline returns ErrInvalidObjectState if the object has offline
+// extents that need to be restored first. Missing objects are left for
+// posix to report.
+func checkOnline(bucket, object *string) error {
+	if bucket == nil || object == nil {
+		return nil
+	}
+
+	st, err := statMore(filepath.Join(*bucket, *object))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("stat more: %w", err)
+	}
+	if st.Offline_blocks != 0 {
+		return s3err.GetAPIError(s3err.ErrInvalidObjectState)
+	}
+	return nil
+}
+
 func (s *ScoutFS) getXattrTags(bucket, object string) (map[string]string, error) {
 	tags := make(map[string]string)
 	b, err := xattr.Get(filepath.Join(bucket, object), "user."+tagHdr)
@@ -725,7 +936,7 @@ func (s *ScoutFS) fileToObj(bucket string) backend.GetObjFunc {
 			}
 		}
 
-		size := fi.Size()
+		size := sse.ObjectSize(objPath, fi)
 
 		return types.Object{
 			ETag:         &etag,
@@ -25,6 +25,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"syscall"
+	"unsafe"
 
 	"golang.org/x/sys/unix"
 
@@ -33,7 +34,26 @@ import (
 )
 
 func New(rootdir string, opts ...Option) (*ScoutFS, error) {
-	p, err := posix.New(rootdir)
+	s := &ScoutFS{rootdir: rootdir}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	var popts []posix.Option
+	if s.chownuid {
+		popts = append(popts, posix.WithChownUID())
+	}
+	if s.chowngid {
+		popts = append(popts, posix.WithChownGID())
+	}
+	if s.setprojectid {
+		popts = append(popts, posix.WithOwnerFunc(s.fsetProjectID))
+	}
+	if s.sseMasterKey != nil {
+		popts = append(popts, posix.WithSSEMasterKey(s.sseMasterKey))
+	}
+
+	p, err := posix.New(rootdir, popts...)
 	if err != nil {
 		return nil, err
 	}
@@ -43,10 +63,8 @@ func New(rootdir string, opts ...Option) (*ScoutFS, error) {
 		return nil, fmt.Errorf("open %v: %w", rootdir, err)
 	}
 
-	s := &ScoutFS{Posix: p, rootfd: f, rootdir: rootdir}
-	for _, opt := range opts {
-		opt(s)
-	}
+	s.Posix = p
+	s.rootfd = f
 
 	return s, nil
 }
@@ -59,6 +77,8 @@ type tmpfile struct {
 	objname string
 	isOTmp  bool
 	size    int64
+	// linked is set once the named temp file is renamed into place
+	linked bool
 }
 
 func openTmpFile(dir, bucket, obj string, size int64) (*tmpfile, error) {
@@ -168,6 +188,7 @@ func (tmp *tmpfile) fallbackLink() error {
 	if err != nil {
 		return fmt.Errorf("rename tmpfile: %w", err)
 	}
+	tmp.linked = true
 
 	return nil
 }
@@ -184,6 +205,70 @@ func (tmp *tmpfile) Write(b []byte) (int, error) {
 
 func (tmp *tmpfile) cleanup() {
 	tmp.f.Close()
+	// the unnamed O_TMPFILE inodes are freed on close, the named temp
+	// files are removed unless linked
+	if !tmp.isOTmp && !tmp.linked {
+		os.Remove(tmp.f.Name())
+	}
+}
+
+// fsxattr is the linux struct fsxattr used with the
+// FS_IOC_FSGETXATTR and FS_IOC_FSSETXATTR ioctls
+type fsxattr struct {
+	xflags     uint32
+	extsize    uint32
+	nextents   uint32
+	projid     uint32
+	cowextsize uint32
+	pad        [8]byte
+}
+
+// The scoutfs-go library does not have a call to set the project ID, so
+// this uses the generic linux inode attribute ioctls that are also used
+// by xfs_quota and chattr -p. These are _IOR('X', 31, struct fsxattr)
+// and _IOW('X', 32, struct fsxattr), golang.org/x/sys/unix does not
+// define these. Filesystems without project ID support fail these with
+// ENOTTY or EOPNOTSUPP.
+const (
+	fsIocFSGetXattr = 0x801c581f
+	fsIocFSSetXattr = 0x401c5820
+)
+
+func fgetFsxattr(f *os.File) (fsxattr, error) {
+	var fsx fsxattr
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, f.Fd(),
+		fsIocFSGetXattr, uintptr(unsafe.Pointer(&fsx)))
+	if errno != 0 {
+		return fsxattr{}, errno
+	}
+	return fsx, nil
+}
+
+func fgetProjectID(f *os.File) (int, error) {
+	fsx, err := fgetFsxattr(f)
+	if err != nil {
+		return 0, err
+	}
+	return int(fsx.projid), nil
+}
+
+func fsetProjectID(f *os.File, projid int) error {
+	fsx, err := fgetFsxattr(f)
+	if err != nil {
+		return err
+	}
+	if fsx.projid == uint32(projid) {
+		return nil
+	}
+
+	fsx.projid = uint32(projid)
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, f.Fd(),
+		fsIocFSSetXattr, uintptr(unsafe.Pointer(&fsx)))
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
 }
 
 func moveData(from *os.File, to *os.File) error {
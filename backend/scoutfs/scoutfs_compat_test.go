@@ -0,0 +1,82 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:build linux && amd64
+
+package scoutfs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"unsafe"
+)
+
+// ioc is the linux _IOC ioctl request encoding
+func ioc(dir, typ, nr, size uintptr) uintptr {
+	return dir<<30 | size<<16 | typ<<8 | nr
+}
+
+func TestFsxattrIoctls(t *testing.T) {
+	size := unsafe.Sizeof(fsxattr{})
+	if size != 28 {
+		t.Fatalf("expected the 28 byte struct fsxattr, got %v", size)
+	}
+	if get := ioc(2, 'X', 31, size); get != fsIocFSGetXattr {
+		t.Fatalf("expected FS_IOC_FSGETXATTR %#x, got %#x", get, fsIocFSGetXattr)
+	}
+	if set := ioc(1, 'X', 32, size); set != fsIocFSSetXattr {
+		t.Fatalf("expected FS_IOC_FSSETXATTR %#x, got %#x", set, fsIocFSSetXattr)
+	}
+}
+
+func TestFsetProjectID(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "obj"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	projid, err := fgetProjectID(f)
+	if errors.Is(err, syscall.ENOTTY) || errors.Is(err, syscall.EOPNOTSUPP) {
+		t.Skipf("project ids not supported: %v", err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// setting the current project id is always allowed
+	err = fsetProjectID(f, projid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = fsetProjectID(f, projid+1)
+	if errors.Is(err, syscall.EOPNOTSUPP) || errors.Is(err, syscall.EPERM) {
+		t.Skipf("project ids can not be changed: %v", err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := fgetProjectID(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != projid+1 {
+		t.Fatalf("expected project id %v, got %v", projid+1, got)
+	}
+}
@@ -49,6 +49,10 @@ func (tmp *tmpfile) Write(b []byte) (int, error) {
 func (tmp *tmpfile) cleanup() {
 }
 
+func fsetProjectID(_ *os.File, _ int) error {
+	return errNotSupported
+}
+
 func moveData(_, _ *os.File) error {
 	return errNotSupported
 }
@@ -0,0 +1,528 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package sse implements server side encryption of object data at rest
+// for the filesystem backends.
+//
+// Every object is encrypted with its own random data key. The data key
+// is stored with the object metadata wrapped (AES-GCM) with either the
+// gateway master key (SSE-S3) or the customer provided key (SSE-C), so
+// the stored object data and metadata alone are not enough to recover
+// the plaintext.
+//
+// Object data is split into independently encrypted segments, one for
+// a PutObject or one per part of a multipart upload. Each segment is a
+// sequence of AES-GCM sealed chunks of up to ChunkSize plaintext bytes.
+// The parts of an upload share the data key and may be uploaded again
+// with the same part number, so each segment is sealed with its own key
+// derived from the data key and a random salt stored in the segment info.
+// The chunk nonce is made from the segment number and chunk index, with
+// the final chunk of a segment flagged so that truncation is detected.
+package sse
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/versity/versitygw/s3err"
+)
+
+const (
+	// AlgorithmAES256 is the only supported encryption algorithm
+	AlgorithmAES256 = "AES256"
+
+	// KeySize is the size of the master, customer and data keys
+	KeySize = 32
+
+	// ChunkSize is the plaintext size of each encrypted chunk
+	ChunkSize = 64 * 1024
+
+	tagSize   = 16
+	nonceSize = 12
+	saltSize  = 16
+	finalFlag = uint64(1) << 63
+)
+
+// Mode is the type of key used to protect the object data key
+type Mode string
+
+const (
+	ModeS3       Mode = "SSE-S3"
+	ModeCustomer Mode = "SSE-C"
+)
+
+// Request is the server side encryption requested by a client
+type Request struct {
+	Mode        Mode
+	CustomerKey []byte
+	// KeyMD5 is the base64 encoded MD5 of the customer key
+	KeyMD5 string
+}
+
+// ParseRequest validates the server side encryption request headers.
+// A nil request is returned when no encryption was requested.
+func ParseRequest(sse types.ServerSideEncryption, algorithm, key, keyMD5 *string) (*Request, error) {
+	if algorithm == nil && key == nil && keyMD5 == nil {
+		switch sse {
+		case "":
+			return nil, nil
+		case types.ServerSideEncryptionAes256:
+			return &Request{Mode: ModeS3}, nil
+		default:
+			return nil, s3err.GetAPIError(s3err.ErrInvalidEncryptionAlgorithm)
+		}
+	}
+
+	if sse != "" {
+		return nil, s3err.GetAPIError(s3err.ErrSSEConflict)
+	}
+	if algorithm == nil || *algorithm != AlgorithmAES256 {
+		return nil, s3err.GetAPIError(s3err.ErrInvalidSSECustomerAlgorithm)
+	}
+	if key == nil {
+		return nil, s3err.GetAPIError(s3err.ErrInvalidSSECustomerKey)
+	}
+	rawKey, err := base64.StdEncoding.DecodeString(*key)
+	if err != nil || len(rawKey) != KeySize {
+		return nil, s3err.GetAPIError(s3err.ErrInvalidSSECustomerKey)
+	}
+	sum := md5.Sum(rawKey)
+	md5Str := base64.StdEncoding.EncodeToString(sum[:])
+	if keyMD5 == nil || *keyMD5 != md5Str {
+		return nil, s3err.GetAPIError(s3err.ErrSSECustomerKeyMD5Mismatch)
+	}
+
+	return &Request{
+		Mode:        ModeCustomer,
+		CustomerKey: rawKey,
+		KeyMD5:      md5Str,
+	}, nil
+}
+
+// LoadMasterKey reads the gateway master key from a file. The file must
+// contain either 32 raw bytes or the key as 64 hex characters.
+func LoadMasterKey(path string) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read key file: %w", err)
+	}
+	if len(b) == KeySize {
+		return b, nil
+	}
+
+	key, err := hex.DecodeString(strings.TrimSpace(string(b)))
+	if err != nil || len(key) != KeySize {
+		return nil, fmt.Errorf("key file %v must contain a %v byte key", path, KeySize)
+	}
+	return key, nil
+}
+
+// Info is the encryption metadata stored with an object
+type Info struct {
+	Mode Mode `json:"mode"`
+	// KeyMD5 is the base64 encoded MD5 of the customer key
+	KeyMD5     string `json:"keyMD5,omitempty"`
+	WrappedKey []byte `json:"wrappedKey"`
+	// Size is the plaintext size of the object
+	Size     int64     `json:"size"`
+	Segments []Segment `json:"segments,omitempty"`
+}
+
+// Segment is an independently encrypted range of the object data
+type Segment struct {
+	Number int32 `json:"number"`
+	// Size is the plaintext size of the segment
+	Size int64 `json:"size"`
+	// EncSize is the on disk size of the segment including any padding
+	EncSize int64 `json:"encSize"`
+	// Salt derives the segment key from the data key, the segments
+	// written without a salt are sealed with the data key
+	Salt []byte `json:"salt,omitempty"`
+}
+
+// NewInfo generates a new data key for the request, and returns the
+// encryption info along with the plaintext data key
+func NewInfo(req *Request, masterKey []byte) (*Info, []byte, error) {
+	kek := masterKey
+	if req.Mode == ModeCustomer {
+		kek = req.CustomerKey
+	}
+	if kek == nil {
+		return nil, nil, s3err.GetAPIError(s3err.ErrSSENotConfigured)
+	}
+
+	dataKey := make([]byte, KeySize)
+	_, err := rand.Read(dataKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("generate data key: %w", err)
+	}
+
+	wrapped, err := wrapKey(kek, dataKey, req.Mode)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &Info{
+		Mode:       req.Mode,
+		KeyMD5:     req.KeyMD5,
+		WrappedKey: wrapped,
+	}, dataKey, nil
+}
+
+// DataKey unwraps the object data key. SSE-C objects require the request
+// to provide the same customer key the object was stored with.
+func (i *Info) DataKey(req *Request, masterKey []byte) ([]byte, error) {
+	kek := masterKey
+	if i.Mode == ModeCustomer {
+		if req == nil || req.Mode != ModeCustomer {
+			return nil, s3err.GetAPIError(s3err.ErrMissingSSECustomerKey)
+		}
+		if req.KeyMD5 != i.KeyMD5 {
+			return nil, s3err.GetAPIError(s3err.ErrIncorrectSSECustomerKey)
+		}
+		kek = req.CustomerKey
+	}
+	if kek == nil {
+		return nil, s3err.GetAPIError(s3err.ErrSSENotConfigured)
+	}
+
+	dataKey, err := unwrapKey(kek, i.WrappedKey, i.Mode)
+	if err != nil {
+		if i.Mode == ModeCustomer {
+			return nil, s3err.GetAPIError(s3err.ErrIncorrectSSECustomerKey)
+		}
+		return nil, err
+	}
+	return dataKey, nil
+}
+
+// Headers returns the response header values describing the encryption
+func (i *Info) Headers() (sse types.ServerSideEncryption, algorithm, keyMD5 *string) {
+	if i == nil {
+		return "", nil, nil
+	}
+	if i.Mode == ModeCustomer {
+		alg, md5Str := AlgorithmAES256, i.KeyMD5
+		return "", &alg, &md5Str
+	}
+	return types.ServerSideEncryptionAes256, nil, nil
+}
+
+func wrapKey(kek, dataKey []byte, mode Mode) ([]byte, error) {
+	aead, err := newAEAD(kek)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, nonceSize)
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, dataKey, []byte(mode)), nil
+}
+
+func unwrapKey(kek, wrapped []byte, mode Mode) ([]byte, error) {
+	aead, err := newAEAD(kek)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) < nonceSize {
+		return nil, errors.New("invalid wrapped data key")
+	}
+	dataKey, err := aead.Open(nil, wrapped[:nonceSize], wrapped[nonceSize:], []byte(mode))
+	if err != nil {
+		return nil, fmt.Errorf("unwrap data key: %w", err)
+	}
+	return dataKey, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("init cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("init gcm: %w", err)
+	}
+	return aead, nil
+}
+
+// segmentAEAD returns the cipher of the segment sealed with the key
+// derived from the data key and the salt
+func segmentAEAD(dataKey, salt []byte) (cipher.AEAD, error) {
+	if len(salt) == 0 {
+		return newAEAD(dataKey)
+	}
+	mac := hmac.New(sha256.New, dataKey)
+	mac.Write([]byte("segment\n"))
+	mac.Write(salt)
+	return newAEAD(mac.Sum(nil))
+}
+
+func chunkNonce(nonce []byte, segment int32, chunk int64, final bool) []byte {
+	c := uint64(chunk)
+	if final {
+		c |= finalFlag
+	}
+	binary.BigEndian.PutUint32(nonce[:4], uint32(segment))
+	binary.BigEndian.PutUint64(nonce[4:], c)
+	return nonce
+}
+
+func numChunks(size int64) int64 {
+	if size == 0 {
+		// empty segments still have a final chunk to authenticate
+		return 1
+	}
+	return (size + ChunkSize - 1) / ChunkSize
+}
+
+// EncryptedSize returns the encrypted size of a segment of plaintext
+// size, not including any padding
+func EncryptedSize(size int64) int64 {
+	return size + numChunks(size)*tagSize
+}
+
+// PaddedSize returns the encrypted size of a segment of plaintext size
+// padded to a multiple of align
+func PaddedSize(size, align int64) int64 {
+	n := EncryptedSize(size)
+	if align <= 1 {
+		return n
+	}
+	return (n + align - 1) / align * align
+}
+
+// Writer encrypts the data written to it as a single segment
+type Writer struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	segment int32
+	salt    []byte
+	align   int64
+	chunk   int64
+	buf     []byte
+	out     []byte
+	nonce   []byte
+	size    int64
+	encSize int64
+}
+
+// NewWriter returns a Writer that writes the encrypted segment to w
+// with a new segment key. Close must be called to write the final chunk,
+// after which the encrypted data is padded with zeros to a multiple of
+// align.
+func NewWriter(w io.Writer, dataKey []byte, segment int32, align int64) (*Writer, error) {
+	salt := make([]byte, saltSize)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return nil, fmt.Errorf("generate segment salt: %w", err)
+	}
+	aead, err := segmentAEAD(dataKey, salt)
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{
+		w:       w,
+		aead:    aead,
+		segment: segment,
+		salt:    salt,
+		align:   align,
+		buf:     make([]byte, 0, ChunkSize),
+		out:     make([]byte, 0, ChunkSize+tagSize),
+		nonce:   make([]byte, nonceSize),
+	}, nil
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	var n int
+	for len(p) > 0 {
+		// chunks are only sealed once more data arrives so
+		// that the last chunk is always sealed as final
+		if len(w.buf) == ChunkSize {
+			err := w.seal(false)
+			if err != nil {
+				return n, err
+			}
+		}
+		c := copy(w.buf[len(w.buf):ChunkSize], p)
+		w.buf = w.buf[:len(w.buf)+c]
+		p = p[c:]
+		n += c
+	}
+	w.size += int64(n)
+	return n, nil
+}
+
+func (w *Writer) seal(final bool) error {
+	nonce := chunkNonce(w.nonce, w.segment, w.chunk, final)
+	w.out = w.aead.Seal(w.out[:0], nonce, w.buf, nil)
+	_, err := w.w.Write(w.out)
+	if err != nil {
+		return err
+	}
+	w.encSize += int64(len(w.out))
+	w.chunk++
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// Close writes the final chunk and any padding
+func (w *Writer) Close() error {
+	err := w.seal(true)
+	if err != nil {
+		return err
+	}
+
+	padded := PaddedSize(w.size, w.align)
+	if padded > w.encSize {
+		_, err = w.w.Write(make([]byte, padded-w.encSize))
+		if err != nil {
+			return err
+		}
+		w.encSize = padded
+	}
+	return nil
+}
+
+// Segment returns the segment written, only valid after Close
+func (w *Writer) Segment() Segment {
+	return Segment{
+		Number:  w.segment,
+		Size:    w.size,
+		EncSize: w.encSize,
+		Salt:    w.salt,
+	}
+}
+
+// Decrypt writes the plaintext of length bytes starting at offset of
+// the object data read from r to w
+func (i *Info) Decrypt(w io.Writer, r io.ReaderAt, dataKey []byte, offset, length int64) error {
+	end := offset + length
+	nonce := make([]byte, nonceSize)
+	buf := make([]byte, ChunkSize+tagSize)
+	var plain []byte
+	var segStart, encOff int64
+
+	for _, seg := range i.Segments {
+		segEnd := segStart + seg.Size
+		if segEnd <= offset || segStart >= end {
+			segStart = segEnd
+			encOff += seg.EncSize
+			continue
+		}
+
+		aead, err := segmentAEAD(dataKey, seg.Salt)
+		if err != nil {
+			return err
+		}
+
+		from := max(offset, segStart) - segStart
+		to := min(end, segEnd) - segStart
+		last := numChunks(seg.Size) - 1
+		for c := from / ChunkSize; c*ChunkSize < to; c++ {
+			plen := min(ChunkSize, seg.Size-c*ChunkSize)
+			sealed := buf[:plen+tagSize]
+			_, err := r.ReadAt(sealed, encOff+c*(ChunkSize+tagSize))
+			if err != nil {
+				return fmt.Errorf("read encrypted data: %w", err)
+			}
+
+			plain, err = aead.Open(plain[:0], chunkNonce(nonce, seg.Number, c, c == last), sealed, nil)
+			if err != nil {
+				return fmt.Errorf("decrypt object data: %w", err)
+			}
+
+			lo := max(from-c*ChunkSize, 0)
+			hi := min(to-c*ChunkSize, plen)
+			_, err = w.Write(plain[lo:hi])
+			if err != nil {
+				return err
+			}
+		}
+
+		segStart = segEnd
+		encOff += seg.EncSize
+	}
+
+	return nil
+}
+
+// NewReader returns a reader of the plaintext of length bytes starting
+// at offset of the object data read from r
+func (i *Info) NewReader(r io.ReaderAt, dataKey []byte, offset, length int64) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(i.Decrypt(pw, r, dataKey, offset, length))
+	}()
+	return pr
+}
+
+// ReaderAt returns a random access reader of the plaintext of the
+// object data read from r
+func (i *Info) ReaderAt(r io.ReaderAt, dataKey []byte) io.ReaderAt {
+	return &plainReaderAt{info: i, r: r, dataKey: dataKey}
+}
+
+type plainReaderAt struct {
+	info    *Info
+	r       io.ReaderAt
+	dataKey []byte
+}
+
+func (p *plainReaderAt) ReadAt(b []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("negative offset %v", off)
+	}
+	if off >= p.info.Size {
+		return 0, io.EOF
+	}
+	n := min(int64(len(b)), p.info.Size-off)
+	w := &sliceWriter{b: b[:0:n]}
+	err := p.info.Decrypt(w, p.r, p.dataKey, off, n)
+	if err != nil {
+		return len(w.b), err
+	}
+	if n < int64(len(b)) {
+		return int(n), io.EOF
+	}
+	return int(n), nil
+}
+
+// sliceWriter appends to a slice with a fixed capacity
+type sliceWriter struct {
+	b []byte
+}
+
+func (w *sliceWriter) Write(p []byte) (int, error) {
+	if len(p) > cap(w.b)-len(w.b) {
+		return 0, io.ErrShortWrite
+	}
+	w.b = append(w.b, p...)
+	return len(p), nil
+}
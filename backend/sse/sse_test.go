@@ -0,0 +1,319 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package sse_test
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/versity/versitygw/backend/sse"
+	"github.com/versity/versitygw/s3err"
+)
+
+func randBytes(t *testing.T, n int) []byte {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func customerRequest(t *testing.T, key []byte) *sse.Request {
+	sum := md5.Sum(key)
+	alg := sse.AlgorithmAES256
+	k := base64.StdEncoding.EncodeToString(key)
+	m := base64.StdEncoding.EncodeToString(sum[:])
+	req, err := sse.ParseRequest("", &alg, &k, &m)
+	if err != nil {
+		t.Fatalf("ParseRequest() error = %v", err)
+	}
+	return req
+}
+
+// encrypt writes each of the parts as a separate segment
+func encrypt(t *testing.T, info *sse.Info, dataKey []byte, align int64, parts ...[]byte) []byte {
+	var out bytes.Buffer
+	for i, part := range parts {
+		w, err := sse.NewWriter(&out, dataKey, int32(i+1), align)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = w.Write(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = w.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		seg := w.Segment()
+		if seg.EncSize != sse.PaddedSize(int64(len(part)), align) {
+			t.Fatalf("segment size %v, expected %v", seg.EncSize,
+				sse.PaddedSize(int64(len(part)), align))
+		}
+		info.Segments = append(info.Segments, seg)
+		info.Size += seg.Size
+	}
+	return out.Bytes()
+}
+
+func TestParseRequest(t *testing.T) {
+	key := randBytes(t, sse.KeySize)
+	sum := md5.Sum(key)
+	b64Key := base64.StdEncoding.EncodeToString(key)
+	b64MD5 := base64.StdEncoding.EncodeToString(sum[:])
+	shortKey := base64.StdEncoding.EncodeToString(key[:16])
+	alg := sse.AlgorithmAES256
+	badAlg := "aws:kms"
+
+	tests := []struct {
+		name      string
+		sse       types.ServerSideEncryption
+		algorithm *string
+		key       *string
+		keyMD5    *string
+		mode      sse.Mode
+		err       s3err.ErrorCode
+	}{
+		{name: "none"},
+		{name: "sse-s3", sse: types.ServerSideEncryptionAes256, mode: sse.ModeS3},
+		{name: "sse-kms", sse: types.ServerSideEncryptionAwsKms, err: s3err.ErrInvalidEncryptionAlgorithm},
+		{name: "sse-c", algorithm: &alg, key: &b64Key, keyMD5: &b64MD5, mode: sse.ModeCustomer},
+		{name: "sse-c-bad-algorithm", algorithm: &badAlg, key: &b64Key, keyMD5: &b64MD5, err: s3err.ErrInvalidSSECustomerAlgorithm},
+		{name: "sse-c-short-key", algorithm: &alg, key: &shortKey, keyMD5: &b64MD5, err: s3err.ErrInvalidSSECustomerKey},
+		{name: "sse-c-missing-md5", algorithm: &alg, key: &b64Key, err: s3err.ErrSSECustomerKeyMD5Mismatch},
+		{name: "sse-c-and-sse-s3", sse: types.ServerSideEncryptionAes256, algorithm: &alg, key: &b64Key, keyMD5: &b64MD5, err: s3err.ErrSSEConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := sse.ParseRequest(tt.sse, tt.algorithm, tt.key, tt.keyMD5)
+			if tt.err != 0 {
+				var apiErr s3err.APIError
+				if !errors.As(err, &apiErr) || apiErr != s3err.GetAPIError(tt.err) {
+					t.Fatalf("expected error %v, got %v", s3err.GetAPIError(tt.err), err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if tt.mode == "" {
+				if req != nil {
+					t.Fatalf("expected no request, got %v", req.Mode)
+				}
+				return
+			}
+			if req == nil || req.Mode != tt.mode {
+				t.Fatalf("expected mode %v, got %v", tt.mode, req)
+			}
+		})
+	}
+}
+
+func TestDataKey(t *testing.T) {
+	master := randBytes(t, sse.KeySize)
+
+	info, dataKey, err := sse.NewInfo(&sse.Request{Mode: sse.ModeS3}, master)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := info.DataKey(nil, master)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, dataKey) {
+		t.Fatal("SSE-S3 data key mismatch")
+	}
+
+	_, _, err = sse.NewInfo(&sse.Request{Mode: sse.ModeS3}, nil)
+	if !errors.Is(err, s3err.GetAPIError(s3err.ErrSSENotConfigured)) {
+		t.Fatalf("expected not configured error, got %v", err)
+	}
+
+	req := customerRequest(t, randBytes(t, sse.KeySize))
+	info, dataKey, err = sse.NewInfo(req, master)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = info.DataKey(req, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, dataKey) {
+		t.Fatal("SSE-C data key mismatch")
+	}
+
+	_, err = info.DataKey(nil, master)
+	if !errors.Is(err, s3err.GetAPIError(s3err.ErrMissingSSECustomerKey)) {
+		t.Fatalf("expected missing key error, got %v", err)
+	}
+
+	_, err = info.DataKey(customerRequest(t, randBytes(t, sse.KeySize)), master)
+	if !errors.Is(err, s3err.GetAPIError(s3err.ErrIncorrectSSECustomerKey)) {
+		t.Fatalf("expected incorrect key error, got %v", err)
+	}
+}
+
+func TestDecryptRanges(t *testing.T) {
+	dataKey := randBytes(t, sse.KeySize)
+	parts := [][]byte{
+		randBytes(t, 3*sse.ChunkSize),
+		randBytes(t, 2*sse.ChunkSize+100),
+		randBytes(t, 0),
+		randBytes(t, 17),
+	}
+	plain := bytes.Join(parts, nil)
+	size := int64(len(plain))
+
+	for _, align := range []int64{1, 4096} {
+		info := &sse.Info{}
+		data := encrypt(t, info, dataKey, align, parts...)
+		if info.Size != size {
+			t.Fatalf("info size %v, expected %v", info.Size, size)
+		}
+
+		ranges := [][2]int64{
+			{0, size},
+			{0, 1},
+			{size - 1, 1},
+			{sse.ChunkSize - 1, 2},
+			{3*sse.ChunkSize - 10, 20},
+			{100, 4 * sse.ChunkSize},
+			{size - 17, 17},
+			{0, 0},
+		}
+		for _, rng := range ranges {
+			var out bytes.Buffer
+			err := info.Decrypt(&out, bytes.NewReader(data), dataKey, rng[0], rng[1])
+			if err != nil {
+				t.Fatalf("align %v range %v: %v", align, rng, err)
+			}
+			if !bytes.Equal(out.Bytes(), plain[rng[0]:rng[0]+rng[1]]) {
+				t.Fatalf("align %v range %v: plaintext mismatch", align, rng)
+			}
+		}
+
+		rdr := info.NewReader(bytes.NewReader(data), dataKey, 0, size)
+		got, err := io.ReadAll(rdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, plain) {
+			t.Fatalf("align %v: reader plaintext mismatch", align)
+		}
+
+		ra := info.ReaderAt(bytes.NewReader(data), dataKey)
+		got, err = io.ReadAll(io.NewSectionReader(ra, 0, size+10))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, plain) {
+			t.Fatalf("align %v: reader at plaintext mismatch", align)
+		}
+		buf := make([]byte, 20)
+		n, err := ra.ReadAt(buf, size-10)
+		if n != 10 || err != io.EOF {
+			t.Fatalf("align %v: read past end got %v, %v", align, n, err)
+		}
+	}
+}
+
+func TestDecryptTampered(t *testing.T) {
+	dataKey := randBytes(t, sse.KeySize)
+	info := &sse.Info{}
+	data := encrypt(t, info, dataKey, 1, randBytes(t, 2*sse.ChunkSize))
+
+	// drop the final chunk, the now last chunk is not sealed as final
+	truncated := data[:sse.ChunkSize+16]
+	info.Segments[0].Size = sse.ChunkSize
+	info.Segments[0].EncSize = int64(len(truncated))
+	err := info.Decrypt(io.Discard, bytes.NewReader(truncated), dataKey, 0, sse.ChunkSize)
+	if err == nil {
+		t.Fatal("expected truncated data to fail decryption")
+	}
+
+	info = &sse.Info{}
+	data = encrypt(t, info, dataKey, 1, randBytes(t, 100))
+	data[10] ^= 0xff
+	err = info.Decrypt(io.Discard, bytes.NewReader(data), dataKey, 0, 100)
+	if err == nil {
+		t.Fatal("expected modified data to fail decryption")
+	}
+}
+
+func TestSegmentKeys(t *testing.T) {
+	dataKey := randBytes(t, sse.KeySize)
+	plain := randBytes(t, 1000)
+
+	// a part uploaded again with the same number is sealed with a new key
+	first := encrypt(t, &sse.Info{}, dataKey, 0, plain)
+	info := &sse.Info{}
+	second := encrypt(t, info, dataKey, 0, plain)
+	if bytes.Equal(first, second) {
+		t.Fatal("expected the segments to be sealed with different keys")
+	}
+
+	var out bytes.Buffer
+	err := info.Decrypt(&out, bytes.NewReader(second), dataKey, 0, info.Size)
+	if err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+	if !bytes.Equal(out.Bytes(), plain) {
+		t.Fatal("decrypted data mismatch")
+	}
+}
+
+func TestDecryptUnsaltedSegment(t *testing.T) {
+	dataKey := randBytes(t, sse.KeySize)
+	plain := []byte("sealed with the data key")
+
+	// the segments written before the segment keys are sealed with the
+	// data key, the single chunk is the final chunk of segment 1
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint32(nonce[:4], 1)
+	binary.BigEndian.PutUint64(nonce[4:], 1<<63)
+	sealed := aead.Seal(nil, nonce, plain, nil)
+
+	info := &sse.Info{
+		Size:     int64(len(plain)),
+		Segments: []sse.Segment{{Number: 1, Size: int64(len(plain)), EncSize: int64(len(sealed))}},
+	}
+	var out bytes.Buffer
+	err = info.Decrypt(&out, bytes.NewReader(sealed), dataKey, 0, info.Size)
+	if err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+	if !bytes.Equal(out.Bytes(), plain) {
+		t.Fatal("decrypted data mismatch")
+	}
+}
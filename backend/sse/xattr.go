@@ -0,0 +1,102 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package sse
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/pkg/xattr"
+)
+
+// Attr is the extended attribute that holds the object encryption info
+const Attr = "user.sse"
+
+// Load returns the encryption info stored with the file at path, or nil
+// if the file is not encrypted
+func Load(path string) (*Info, error) {
+	b, err := xattr.Get(path, Attr)
+	if errors.Is(err, xattr.ENOATTR) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get encryption attr: %w", err)
+	}
+
+	var info Info
+	err = json.Unmarshal(b, &info)
+	if err != nil {
+		return nil, fmt.Errorf("parse encryption attr: %w", err)
+	}
+	return &info, nil
+}
+
+// Store sets the encryption info of the file at path
+func Store(path string, info *Info) error {
+	b, err := json.Marshal(info)
+	if err != nil {
+		return fmt.Errorf("marshal encryption info: %w", err)
+	}
+	err = xattr.Set(path, Attr, b)
+	if err != nil {
+		return fmt.Errorf("set encryption attr: %w", err)
+	}
+	return nil
+}
+
+// FStore sets the encryption info of the open file f
+func FStore(f *os.File, info *Info) error {
+	b, err := json.Marshal(info)
+	if err != nil {
+		return fmt.Errorf("marshal encryption info: %w", err)
+	}
+	err = xattr.FSet(f, Attr, b)
+	if err != nil {
+		return fmt.Errorf("set encryption attr: %w", err)
+	}
+	return nil
+}
+
+// ObjectSize returns the plaintext size of the object at path, this is
+// the file size for objects that are not encrypted
+func ObjectSize(path string, fi fs.FileInfo) int64 {
+	if fi.IsDir() {
+		return fi.Size()
+	}
+	info, err := Load(path)
+	if err != nil || info == nil {
+		return fi.Size()
+	}
+	return info.Size
+}
+
+// FileInfo returns fi with the size replaced by the plaintext size of
+// the encrypted object
+func FileInfo(fi fs.FileInfo, info *Info) fs.FileInfo {
+	if info == nil {
+		return fi
+	}
+	return plainFileInfo{FileInfo: fi, size: info.Size}
+}
+
+type plainFileInfo struct {
+	fs.FileInfo
+	size int64
+}
+
+func (fi plainFileInfo) Size() int64 { return fi.size }
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"text/tabwriter"
 	"time"
@@ -30,6 +31,8 @@ import (
 	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
 	"github.com/urfave/cli/v2"
 	"github.com/versity/versitygw/auth"
+	"github.com/versity/versitygw/backend/quota"
+	"github.com/versity/versitygw/s3api/utils"
 	"github.com/versity/versitygw/s3response"
 )
 
@@ -83,6 +86,11 @@ func adminCommand() *cli.Command {
 						Usage:   "projectID for the new user",
 						Aliases: []string{"pi"},
 					},
+					&cli.StringFlag{
+						Name:    "policy",
+						Usage:   "path of the identity policy document for the new user",
+						Aliases: []string{"p"},
+					},
 				},
 			},
 			{
@@ -98,6 +106,38 @@ func adminCommand() *cli.Command {
 					},
 				},
 			},
+			{
+				Name:   "put-user-policy",
+				Usage:  "Attach an identity policy to a user, replacing the current one",
+				Action: putUserPolicy,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "access",
+						Usage:    "access key id of the user",
+						Required: true,
+						Aliases:  []string{"a"},
+					},
+					&cli.StringFlag{
+						Name:     "policy",
+						Usage:    "path of the identity policy document",
+						Required: true,
+						Aliases:  []string{"p"},
+					},
+				},
+			},
+			{
+				Name:   "delete-user-policy",
+				Usage:  "Remove the identity policy of a user",
+				Action: deleteUserPolicy,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "access",
+						Usage:    "access key id of the user",
+						Required: true,
+						Aliases:  []string{"a"},
+					},
+				},
+			},
 			{
 				Name:   "list-users",
 				Usage:  "List all the gateway users",
@@ -127,6 +167,114 @@ func adminCommand() *cli.Command {
 				Usage:  "Lists all the gateway buckets and owners.",
 				Action: listBuckets,
 			},
+			{
+				Name:   "put-quota",
+				Usage:  "Set the storage quota of a bucket or an account",
+				Action: putQuota,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "bucket",
+						Usage:   "the bucket name of the quota",
+						Aliases: []string{"b"},
+					},
+					&cli.StringFlag{
+						Name:    "account",
+						Usage:   "the user access key id of the account quota",
+						Aliases: []string{"u"},
+					},
+					&cli.Int64Flag{
+						Name:  "max-bytes",
+						Usage: "the maximum number of bytes stored, 0 is unlimited",
+					},
+					&cli.Int64Flag{
+						Name:  "max-objects",
+						Usage: "the maximum number of objects stored, 0 is unlimited",
+					},
+				},
+			},
+			{
+				Name:   "get-quota",
+				Usage:  "Show the storage quota and usage of a bucket or an account",
+				Action: getQuota,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "bucket",
+						Usage:   "the bucket name of the quota",
+						Aliases: []string{"b"},
+					},
+					&cli.StringFlag{
+						Name:    "account",
+						Usage:   "the user access key id of the account quota",
+						Aliases: []string{"u"},
+					},
+				},
+			},
+			{
+				Name:   "delete-quota",
+				Usage:  "Remove the storage quota of a bucket or an account",
+				Action: deleteQuota,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "bucket",
+						Usage:   "the bucket name of the quota",
+						Aliases: []string{"b"},
+					},
+					&cli.StringFlag{
+						Name:    "account",
+						Usage:   "the user access key id of the account quota",
+						Aliases: []string{"u"},
+					},
+				},
+			},
+			{
+				Name:   "put-rate-limit",
+				Usage:  "Set the request rate and bandwidth limits of an account",
+				Action: putRateLimit,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "account",
+						Usage:    "the user access key id of the rate limit",
+						Required: true,
+						Aliases:  []string{"u"},
+					},
+					&cli.Float64Flag{
+						Name:    "requests-per-second",
+						Usage:   "the maximum requests per second, 0 is unlimited",
+						Aliases: []string{"rps"},
+					},
+					&cli.Int64Flag{
+						Name:    "bytes-per-second",
+						Usage:   "the maximum bytes per second read and written, 0 is unlimited",
+						Aliases: []string{"bps"},
+					},
+				},
+			},
+			{
+				Name:   "get-rate-limit",
+				Usage:  "Show the request rate and bandwidth limits of an account",
+				Action: getRateLimit,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "account",
+						Usage:    "the user access key id of the rate limit",
+						Required: true,
+						Aliases:  []string{"u"},
+					},
+				},
+			},
+			{
+				Name:   "delete-rate-limit",
+				Usage:  "Restore the default request rate and bandwidth limits of an account",
+				Action: deleteRateLimit,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "account",
+						Usage:    "the user access key id of the rate limit",
+						Required: true,
+						Aliases:  []string{"u"},
+					},
+				},
+			},
 		},
 		Flags: []cli.Flag{
 			// TODO: create a configuration file for this
@@ -176,6 +324,13 @@ func createUser(ctx *cli.Context) error {
 		GroupID:   groupID,
 		ProjectID: projectID,
 	}
+	if policyFile := ctx.String("policy"); policyFile != "" {
+		policy, err := os.ReadFile(policyFile)
+		if err != nil {
+			return fmt.Errorf("failed to read the policy file: %w", err)
+		}
+		acc.Policy = string(policy)
+	}
 
 	accJson, err := json.Marshal(acc)
 	if err != nil {
@@ -258,6 +413,93 @@ func deleteUser(ctx *cli.Context) error {
 	return nil
 }
 
+func putUserPolicy(ctx *cli.Context) error {
+	access := ctx.String("access")
+	if access == "" {
+		return fmt.Errorf("invalid input parameter for the user")
+	}
+
+	policy, err := os.ReadFile(ctx.String("policy"))
+	if err != nil {
+		return fmt.Errorf("failed to read the policy file: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("%v/put-user-policy?access=%v", adminEndpoint, access), bytes.NewBuffer(policy))
+	if err != nil {
+		return fmt.Errorf("failed to send the request: %w", err)
+	}
+
+	signer := v4.NewSigner()
+
+	hashedPayload := sha256.Sum256(policy)
+	hexPayload := hex.EncodeToString(hashedPayload[:])
+
+	req.Header.Set("X-Amz-Content-Sha256", hexPayload)
+
+	signErr := signer.SignHTTP(req.Context(), aws.Credentials{AccessKeyID: adminAccess, SecretAccessKey: adminSecret}, req, hexPayload, "s3", region, time.Now())
+	if signErr != nil {
+		return fmt.Errorf("failed to sign the request: %w", err)
+	}
+
+	client := http.Client{}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send the request: %w", err)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	fmt.Printf("%s\n", body)
+
+	return nil
+}
+
+func deleteUserPolicy(ctx *cli.Context) error {
+	access := ctx.String("access")
+	if access == "" {
+		return fmt.Errorf("invalid input parameter for the user")
+	}
+
+	req, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("%v/delete-user-policy?access=%v", adminEndpoint, access), nil)
+	if err != nil {
+		return fmt.Errorf("failed to send the request: %w", err)
+	}
+
+	signer := v4.NewSigner()
+
+	hashedPayload := sha256.Sum256([]byte{})
+	hexPayload := hex.EncodeToString(hashedPayload[:])
+
+	req.Header.Set("X-Amz-Content-Sha256", hexPayload)
+
+	signErr := signer.SignHTTP(req.Context(), aws.Credentials{AccessKeyID: adminAccess, SecretAccessKey: adminSecret}, req, hexPayload, "s3", region, time.Now())
+	if signErr != nil {
+		return fmt.Errorf("failed to sign the request: %w", err)
+	}
+
+	client := http.Client{}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send the request: %w", err)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	fmt.Printf("%s\n", body)
+
+	return nil
+}
+
 func listUsers(ctx *cli.Context) error {
 	req, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("%v/list-users", adminEndpoint), nil)
 	if err != nil {
@@ -315,10 +557,10 @@ const (
 func printAcctTable(accs []auth.Account) {
 	w := new(tabwriter.Writer)
 	w.Init(os.Stdout, minwidth, tabwidth, padding, padchar, flags)
-	fmt.Fprintln(w, "Account\tRole\tUserID\tGroupID\tProjectID")
-	fmt.Fprintln(w, "-------\t----\t------\t-------\t---------")
+	fmt.Fprintln(w, "Account\tRole\tUserID\tGroupID\tProjectID\tPolicy")
+	fmt.Fprintln(w, "-------\t----\t------\t-------\t---------\t------")
 	for _, acc := range accs {
-		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", acc.Access, acc.Role, acc.UserID, acc.GroupID, acc.ProjectID)
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\n", acc.Access, acc.Role, acc.UserID, acc.GroupID, acc.ProjectID, acc.Policy != "")
 	}
 	fmt.Fprintln(w)
 	w.Flush()
@@ -417,3 +659,320 @@ func listBuckets(ctx *cli.Context) error {
 
 	return nil
 }
+
+// quotaQuery returns the admin api query of the bucket or account quota
+func quotaQuery(ctx *cli.Context) (string, error) {
+	bucket, account := ctx.String("bucket"), ctx.String("account")
+	if (bucket == "") == (account == "") {
+		return "", fmt.Errorf("either a bucket or an account should be specified")
+	}
+
+	query := url.Values{}
+	if bucket != "" {
+		query.Set("bucket", bucket)
+	} else {
+		query.Set("account", account)
+	}
+
+	return query.Encode(), nil
+}
+
+func putQuota(ctx *cli.Context) error {
+	query, err := quotaQuery(ctx)
+	if err != nil {
+		return err
+	}
+
+	limits, err := json.Marshal(quota.Limits{
+		MaxBytes:   ctx.Int64("max-bytes"),
+		MaxObjects: ctx.Int64("max-objects"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to parse the quota: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("%v/put-quota?%v", adminEndpoint, query), bytes.NewBuffer(limits))
+	if err != nil {
+		return fmt.Errorf("failed to send the request: %w", err)
+	}
+
+	signer := v4.NewSigner()
+
+	hashedPayload := sha256.Sum256(limits)
+	hexPayload := hex.EncodeToString(hashedPayload[:])
+
+	req.Header.Set("X-Amz-Content-Sha256", hexPayload)
+
+	signErr := signer.SignHTTP(req.Context(), aws.Credentials{AccessKeyID: adminAccess, SecretAccessKey: adminSecret}, req, hexPayload, "s3", region, time.Now())
+	if signErr != nil {
+		return fmt.Errorf("failed to sign the request: %w", err)
+	}
+
+	client := http.Client{}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send the request: %w", err)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	fmt.Printf("%s\n", body)
+
+	return nil
+}
+
+func printQuota(q quota.Quota) {
+	limit := func(l int64) string {
+		if l == 0 {
+			return "unlimited"
+		}
+		return fmt.Sprint(l)
+	}
+
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, minwidth, tabwidth, padding, padchar, flags)
+	fmt.Fprintln(w, "\tUsage\tQuota")
+	fmt.Fprintln(w, "\t-----\t-----")
+	fmt.Fprintf(w, "Bytes\t%v\t%v\n", q.Usage.Bytes, limit(q.MaxBytes))
+	fmt.Fprintf(w, "Objects\t%v\t%v\n", q.Usage.Objects, limit(q.MaxObjects))
+	fmt.Fprintln(w)
+	w.Flush()
+}
+
+func getQuota(ctx *cli.Context) error {
+	query, err := quotaQuery(ctx)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("%v/get-quota?%v", adminEndpoint, query), nil)
+	if err != nil {
+		return fmt.Errorf("failed to send the request: %w", err)
+	}
+
+	signer := v4.NewSigner()
+
+	hashedPayload := sha256.Sum256([]byte{})
+	hexPayload := hex.EncodeToString(hashedPayload[:])
+
+	req.Header.Set("X-Amz-Content-Sha256", hexPayload)
+
+	signErr := signer.SignHTTP(req.Context(), aws.Credentials{AccessKeyID: adminAccess, SecretAccessKey: adminSecret}, req, hexPayload, "s3", region, time.Now())
+	if signErr != nil {
+		return fmt.Errorf("failed to sign the request: %w", err)
+	}
+
+	client := http.Client{}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send the request: %w", err)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("%s", body)
+	}
+
+	var q quota.Quota
+	if err := json.Unmarshal(body, &q); err != nil {
+		return err
+	}
+
+	printQuota(q)
+
+	return nil
+}
+
+func deleteQuota(ctx *cli.Context) error {
+	query, err := quotaQuery(ctx)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("%v/delete-quota?%v", adminEndpoint, query), nil)
+	if err != nil {
+		return fmt.Errorf("failed to send the request: %w", err)
+	}
+
+	signer := v4.NewSigner()
+
+	hashedPayload := sha256.Sum256([]byte{})
+	hexPayload := hex.EncodeToString(hashedPayload[:])
+
+	req.Header.Set("X-Amz-Content-Sha256", hexPayload)
+
+	signErr := signer.SignHTTP(req.Context(), aws.Credentials{AccessKeyID: adminAccess, SecretAccessKey: adminSecret}, req, hexPayload, "s3", region, time.Now())
+	if signErr != nil {
+		return fmt.Errorf("failed to sign the request: %w", err)
+	}
+
+	client := http.Client{}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send the request: %w", err)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	fmt.Printf("%s\n", body)
+
+	return nil
+}
+
+func putRateLimit(ctx *cli.Context) error {
+	limits, err := json.Marshal(utils.RateLimits{
+		RequestsPerSecond: ctx.Float64("requests-per-second"),
+		BytesPerSecond:    ctx.Int64("bytes-per-second"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to parse the rate limit: %w", err)
+	}
+
+	query := url.Values{"account": {ctx.String("account")}}
+	req, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("%v/put-rate-limit?%v", adminEndpoint, query.Encode()), bytes.NewBuffer(limits))
+	if err != nil {
+		return fmt.Errorf("failed to send the request: %w", err)
+	}
+
+	signer := v4.NewSigner()
+
+	hashedPayload := sha256.Sum256(limits)
+	hexPayload := hex.EncodeToString(hashedPayload[:])
+
+	req.Header.Set("X-Amz-Content-Sha256", hexPayload)
+
+	signErr := signer.SignHTTP(req.Context(), aws.Credentials{AccessKeyID: adminAccess, SecretAccessKey: adminSecret}, req, hexPayload, "s3", region, time.Now())
+	if signErr != nil {
+		return fmt.Errorf("failed to sign the request: %w", err)
+	}
+
+	client := http.Client{}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send the request: %w", err)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	fmt.Printf("%s\n", body)
+
+	return nil
+}
+
+func getRateLimit(ctx *cli.Context) error {
+	query := url.Values{"account": {ctx.String("account")}}
+	req, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("%v/get-rate-limit?%v", adminEndpoint, query.Encode()), nil)
+	if err != nil {
+		return fmt.Errorf("failed to send the request: %w", err)
+	}
+
+	signer := v4.NewSigner()
+
+	hashedPayload := sha256.Sum256([]byte{})
+	hexPayload := hex.EncodeToString(hashedPayload[:])
+
+	req.Header.Set("X-Amz-Content-Sha256", hexPayload)
+
+	signErr := signer.SignHTTP(req.Context(), aws.Credentials{AccessKeyID: adminAccess, SecretAccessKey: adminSecret}, req, hexPayload, "s3", region, time.Now())
+	if signErr != nil {
+		return fmt.Errorf("failed to sign the request: %w", err)
+	}
+
+	client := http.Client{}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send the request: %w", err)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("%s", body)
+	}
+
+	var limits utils.RateLimits
+	if err := json.Unmarshal(body, &limits); err != nil {
+		return err
+	}
+
+	rps, bps := "unlimited", "unlimited"
+	if limits.RequestsPerSecond > 0 {
+		rps = fmt.Sprint(limits.RequestsPerSecond)
+	}
+	if limits.BytesPerSecond > 0 {
+		bps = fmt.Sprint(limits.BytesPerSecond)
+	}
+
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, minwidth, tabwidth, padding, padchar, flags)
+	fmt.Fprintln(w, "RequestsPerSecond\tBytesPerSecond")
+	fmt.Fprintln(w, "-----------------\t--------------")
+	fmt.Fprintf(w, "%v\t%v\n", rps, bps)
+	fmt.Fprintln(w)
+	w.Flush()
+
+	return nil
+}
+
+func deleteRateLimit(ctx *cli.Context) error {
+	query := url.Values{"account": {ctx.String("account")}}
+	req, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("%v/delete-rate-limit?%v", adminEndpoint, query.Encode()), nil)
+	if err != nil {
+		return fmt.Errorf("failed to send the request: %w", err)
+	}
+
+	signer := v4.NewSigner()
+
+	hashedPayload := sha256.Sum256([]byte{})
+	hexPayload := hex.EncodeToString(hashedPayload[:])
+
+	req.Header.Set("X-Amz-Content-Sha256", hexPayload)
+
+	signErr := signer.SignHTTP(req.Context(), aws.Credentials{AccessKeyID: adminAccess, SecretAccessKey: adminSecret}, req, hexPayload, "s3", region, time.Now())
+	if signErr != nil {
+		return fmt.Errorf("failed to sign the request: %w", err)
+	}
+
+	client := http.Client{}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send the request: %w", err)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	fmt.Printf("%s\n", body)
+
+	return nil
+}
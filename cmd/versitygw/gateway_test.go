@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"log"
 	"os"
@@ -9,6 +10,7 @@ import (
 	"testing"
 
 	"github.com/versity/versitygw/backend/posix"
+	"github.com/versity/versitygw/backend/sse"
 	"github.com/versity/versitygw/tests/integration"
 )
 
@@ -56,7 +58,8 @@ func initPosix(ctx context.Context) {
 		log.Fatalf("make temp directory: %v", err)
 	}
 
-	be, err := posix.New(tempdir)
+	// a fixed master key enables the SSE-S3 tests
+	be, err := posix.New(tempdir, posix.WithSSEMasterKey(bytes.Repeat([]byte{1}, sse.KeySize)))
 	if err != nil {
 		log.Fatalf("init posix: %v", err)
 	}
@@ -22,15 +22,20 @@ import (
 	"net/http"
 	_ "net/http/pprof"
 	"os"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/urfave/cli/v2"
 	"github.com/versity/versitygw/auth"
 	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/backend/quota"
+	"github.com/versity/versitygw/metrics"
 	"github.com/versity/versitygw/s3api"
 	"github.com/versity/versitygw/s3api/middlewares"
+	"github.com/versity/versitygw/s3api/utils"
 	"github.com/versity/versitygw/s3event"
 	"github.com/versity/versitygw/s3log"
+	"github.com/versity/versitygw/tracing"
 )
 
 var (
@@ -43,15 +48,36 @@ var (
 	kafkaURL, kafkaTopic, kafkaKey         string
 	natsURL, natsTopic                     string
 	logWebhookURL                          string
-	accessLog                              string
+	accessLog, accessLogFormat             string
+	accessLogMaxSize                       int64
+	accessLogRotateInterval                int
+	accessLogMaxBackups                    int
+	accessLogCompress                      bool
 	healthPath                             string
+	sigV2                                  bool
+	virtualDomain                          string
+	oidcIssuer, oidcJWKS                   string
+	oidcAudiences                          cli.StringSlice
+	oidcAccountClaim, oidcRoleClaim        string
+	quotaDir                               string
+	rateLimitDir                           string
+	rateLimitAccountRPS, rateLimitIPRPS    float64
+	rateLimitBucketRPS                     float64
+	rateLimitAccountBPS, rateLimitIPBPS    int64
+	rateLimitBucketBPS                     int64
+	websitePort, websiteDomain             string
 	debug                                  bool
 	pprof                                  string
+	metricsPort                            string
+	traceExporter, traceEndpoint           string
+	traceInsecure                          bool
+	traceSampleRatio                       float64
 	quiet                                  bool
 	iamDir                                 string
 	ldapURL, ldapBindDN, ldapPassword      string
 	ldapQueryBase, ldapObjClasses          string
 	ldapAccessAtr, ldapSecAtr, ldapRoleAtr string
+	ldapPolicyAtr                          string
 	s3IamAccess, s3IamSecret               string
 	s3IamRegion, s3IamBucket               string
 	s3IamEndpoint                          string
@@ -184,6 +210,18 @@ func initFlags() []cli.Flag {
 			EnvVars:     []string{"VGW_ADMIN_CERT_KEY"},
 			Destination: &admKeyFile,
 		},
+		&cli.StringFlag{
+			Name:        "website-port",
+			Usage:       "static website server listen address <ip>:<port> or :<port>, website server is disabled if not set",
+			EnvVars:     []string{"VGW_WEBSITE_PORT"},
+			Destination: &websitePort,
+		},
+		&cli.StringFlag{
+			Name:        "website-domain",
+			Usage:       "serve website requests for <bucket>.<domain> from bucket, path style requests are served otherwise",
+			EnvVars:     []string{"VGW_WEBSITE_DOMAIN"},
+			Destination: &websiteDomain,
+		},
 		&cli.BoolFlag{
 			Name:        "debug",
 			Usage:       "enable debug output",
@@ -196,6 +234,37 @@ func initFlags() []cli.Flag {
 			EnvVars:     []string{"VGW_PPROF"},
 			Destination: &pprof,
 		},
+		&cli.StringFlag{
+			Name:        "metrics",
+			Usage:       "enable prometheus metrics at /metrics on specified port",
+			EnvVars:     []string{"VGW_METRICS"},
+			Destination: &metricsPort,
+		},
+		&cli.StringFlag{
+			Name:        "trace-exporter",
+			Usage:       "enable opentelemetry tracing with the specified exporter: otlp-http, otlp-grpc or stdout",
+			EnvVars:     []string{"VGW_TRACE_EXPORTER"},
+			Destination: &traceExporter,
+		},
+		&cli.StringFlag{
+			Name:        "trace-endpoint",
+			Usage:       "host:port of the otlp trace collector, defaults to the OTEL_EXPORTER_OTLP_* environment variables",
+			EnvVars:     []string{"VGW_TRACE_ENDPOINT"},
+			Destination: &traceEndpoint,
+		},
+		&cli.BoolFlag{
+			Name:        "trace-insecure",
+			Usage:       "disable tls to the otlp trace collector",
+			EnvVars:     []string{"VGW_TRACE_INSECURE"},
+			Destination: &traceInsecure,
+		},
+		&cli.Float64Flag{
+			Name:        "trace-sample-ratio",
+			Usage:       "ratio of the requests traced, the requests with a propagated trace follow its sampling",
+			EnvVars:     []string{"VGW_TRACE_SAMPLE_RATIO"},
+			Value:       1,
+			Destination: &traceSampleRatio,
+		},
 		&cli.BoolFlag{
 			Name:        "quiet",
 			Usage:       "silence stdout request logging output",
@@ -209,6 +278,37 @@ func initFlags() []cli.Flag {
 			EnvVars:     []string{"LOGFILE", "VGW_ACCESS_LOG"},
 			Destination: &accessLog,
 		},
+		&cli.StringFlag{
+			Name:        "access-log-format",
+			Usage:       "format of the server access log file, text (AWS server access log) or json (JSON lines)",
+			EnvVars:     []string{"VGW_ACCESS_LOG_FORMAT"},
+			Value:       s3log.LogFormatText,
+			Destination: &accessLogFormat,
+		},
+		&cli.Int64Flag{
+			Name:        "access-log-max-size",
+			Usage:       "rotate the server access log file when it reaches this size in bytes, 0 disables",
+			EnvVars:     []string{"VGW_ACCESS_LOG_MAX_SIZE"},
+			Destination: &accessLogMaxSize,
+		},
+		&cli.IntFlag{
+			Name:        "access-log-rotate-interval",
+			Usage:       "rotate the server access log file at this interval (seconds), 0 disables",
+			EnvVars:     []string{"VGW_ACCESS_LOG_ROTATE_INTERVAL"},
+			Destination: &accessLogRotateInterval,
+		},
+		&cli.IntFlag{
+			Name:        "access-log-max-backups",
+			Usage:       "number of rotated server access log files to keep, 0 keeps all",
+			EnvVars:     []string{"VGW_ACCESS_LOG_MAX_BACKUPS"},
+			Destination: &accessLogMaxBackups,
+		},
+		&cli.BoolFlag{
+			Name:        "access-log-compress",
+			Usage:       "gzip the rotated server access log files",
+			EnvVars:     []string{"VGW_ACCESS_LOG_COMPRESS"},
+			Destination: &accessLogCompress,
+		},
 		&cli.StringFlag{
 			Name:        "log-webhook-url",
 			Usage:       "webhook url to send the audit logs",
@@ -304,6 +404,12 @@ func initFlags() []cli.Flag {
 			EnvVars:     []string{"VGW_IAM_LDAP_ROLE_ATR"},
 			Destination: &ldapRoleAtr,
 		},
+		&cli.StringFlag{
+			Name:        "iam-ldap-policy-atr",
+			Usage:       "ldap server user identity policy attribute name",
+			EnvVars:     []string{"VGW_IAM_LDAP_POLICY_ATR"},
+			Destination: &ldapPolicyAtr,
+		},
 		&cli.StringFlag{
 			Name:        "s3-iam-access",
 			Usage:       "s3 IAM access key",
@@ -374,6 +480,96 @@ func initFlags() []cli.Flag {
 			EnvVars:     []string{"VGW_HEALTH"},
 			Destination: &healthPath,
 		},
+		&cli.BoolFlag{
+			Name:        "sigv2",
+			Usage:       "allow legacy AWS Signature Version 2 signed requests",
+			EnvVars:     []string{"VGW_SIGV2"},
+			Destination: &sigV2,
+		},
+		&cli.StringFlag{
+			Name:        "virtual-domain",
+			Usage:       "enables virtual-hosted-style bucket addressing with the specified arg as the base domain",
+			EnvVars:     []string{"VGW_VIRTUAL_DOMAIN"},
+			Destination: &virtualDomain,
+		},
+		&cli.StringFlag{
+			Name:        "oidc-issuer",
+			Usage:       "trusted OpenID Connect issuer of AssumeRoleWithWebIdentity tokens",
+			EnvVars:     []string{"VGW_OIDC_ISSUER"},
+			Destination: &oidcIssuer,
+		},
+		&cli.StringFlag{
+			Name:        "oidc-jwks",
+			Usage:       "path or http(s) url of the OpenID Connect issuer JSON Web Key Set",
+			EnvVars:     []string{"VGW_OIDC_JWKS"},
+			Destination: &oidcJWKS,
+		},
+		&cli.StringSliceFlag{
+			Name:        "oidc-audience",
+			Usage:       "accepted audience (client id) of the web identity tokens, may be repeated, required with --oidc-issuer",
+			EnvVars:     []string{"VGW_OIDC_AUDIENCE"},
+			Destination: &oidcAudiences,
+		},
+		&cli.StringFlag{
+			Name:        "oidc-account-claim",
+			Usage:       "web identity token claim mapped to the gateway account access key, required with --oidc-issuer",
+			EnvVars:     []string{"VGW_OIDC_ACCOUNT_CLAIM"},
+			Destination: &oidcAccountClaim,
+		},
+		&cli.StringFlag{
+			Name:        "oidc-role-claim",
+			Usage:       "web identity token claim mapped to the role of identities without a gateway account",
+			EnvVars:     []string{"VGW_OIDC_ROLE_CLAIM"},
+			Destination: &oidcRoleClaim,
+		},
+		&cli.StringFlag{
+			Name:        "quota-dir",
+			Usage:       "if defined, enforce storage quotas and store the quotas and usage in this directory",
+			EnvVars:     []string{"VGW_QUOTA_DIR"},
+			Destination: &quotaDir,
+		},
+		&cli.Float64Flag{
+			Name:        "rate-limit-account-rps",
+			Usage:       "default maximum requests per second of each account, 0 is unlimited",
+			EnvVars:     []string{"VGW_RATE_LIMIT_ACCOUNT_RPS"},
+			Destination: &rateLimitAccountRPS,
+		},
+		&cli.Int64Flag{
+			Name:        "rate-limit-account-bps",
+			Usage:       "default maximum bytes per second read and written by each account, 0 is unlimited",
+			EnvVars:     []string{"VGW_RATE_LIMIT_ACCOUNT_BPS"},
+			Destination: &rateLimitAccountBPS,
+		},
+		&cli.Float64Flag{
+			Name:        "rate-limit-bucket-rps",
+			Usage:       "maximum requests per second to each bucket, 0 is unlimited",
+			EnvVars:     []string{"VGW_RATE_LIMIT_BUCKET_RPS"},
+			Destination: &rateLimitBucketRPS,
+		},
+		&cli.Int64Flag{
+			Name:        "rate-limit-bucket-bps",
+			Usage:       "maximum bytes per second read and written to each bucket, 0 is unlimited",
+			EnvVars:     []string{"VGW_RATE_LIMIT_BUCKET_BPS"},
+			Destination: &rateLimitBucketBPS,
+		},
+		&cli.Float64Flag{
+			Name:        "rate-limit-ip-rps",
+			Usage:       "maximum requests per second from each source ip address, 0 is unlimited",
+			EnvVars:     []string{"VGW_RATE_LIMIT_IP_RPS"},
+			Destination: &rateLimitIPRPS,
+		},
+		&cli.Int64Flag{
+			Name:        "rate-limit-ip-bps",
+			Usage:       "maximum bytes per second read and written by each source ip address, 0 is unlimited",
+			EnvVars:     []string{"VGW_RATE_LIMIT_IP_BPS"},
+			Destination: &rateLimitIPBPS,
+		},
+		&cli.StringFlag{
+			Name:        "rate-limit-dir",
+			Usage:       "if defined, enable rate limiting and store the account rate limits set with the admin apis in this directory",
+			EnvVars:     []string{"VGW_RATE_LIMIT_DIR"},
+			Destination: &rateLimitDir,
+		},
 	}
 }
 
@@ -390,6 +586,16 @@ func runGateway(ctx context.Context, be backend.Backend) error {
 		}()
 	}
 
+	if metricsPort != "" {
+		// listen on specified port for prometheus scrapes
+		// of http://<ip:port>/metrics
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		go func() {
+			log.Fatal(http.ListenAndServe(metricsPort, mux))
+		}()
+	}
+
 	app := fiber.New(fiber.Config{
 		AppName:           "versitygw",
 		ServerHeader:      "VERSITYGW",
@@ -398,6 +604,7 @@ func runGateway(ctx context.Context, be backend.Backend) error {
 	})
 
 	var opts []s3api.Option
+	var webOpts []s3api.WebsiteOpt
 
 	if certFile != "" || keyFile != "" {
 		if certFile == "" {
@@ -412,6 +619,7 @@ func runGateway(ctx context.Context, be backend.Backend) error {
 			return fmt.Errorf("tls: load certs: %v", err)
 		}
 		opts = append(opts, s3api.WithTLS(cert))
+		webOpts = append(webOpts, s3api.WithWebsiteSrvTLS(cert))
 	}
 	if debug {
 		opts = append(opts, s3api.WithDebug())
@@ -421,10 +629,76 @@ func runGateway(ctx context.Context, be backend.Backend) error {
 	}
 	if quiet {
 		opts = append(opts, s3api.WithQuiet())
+		webOpts = append(webOpts, s3api.WithWebsiteQuiet())
+	}
+	if websiteDomain != "" {
+		webOpts = append(webOpts, s3api.WithWebsiteDomain(websiteDomain))
 	}
 	if healthPath != "" {
 		opts = append(opts, s3api.WithHealth(healthPath))
 	}
+	if sigV2 {
+		opts = append(opts, s3api.WithSigV2())
+	}
+	if metricsPort != "" {
+		opts = append(opts, s3api.WithMetrics())
+	}
+	if virtualDomain != "" {
+		opts = append(opts, s3api.WithHostStyle(virtualDomain))
+	}
+	if oidcIssuer != "" || oidcJWKS != "" {
+		oidc, err := auth.NewOIDC(auth.OIDCOpts{
+			Issuer:       oidcIssuer,
+			JWKS:         oidcJWKS,
+			Audiences:    oidcAudiences.Value(),
+			AccountClaim: oidcAccountClaim,
+			RoleClaim:    oidcRoleClaim,
+		})
+		if err != nil {
+			return fmt.Errorf("setup oidc: %w", err)
+		}
+		opts = append(opts, s3api.WithOIDC(oidc))
+	}
+	var traceShutdown func(context.Context) error
+	if traceExporter != "" {
+		var err error
+		traceShutdown, err = tracing.Init(ctx, tracing.Config{
+			Exporter:    traceExporter,
+			Endpoint:    traceEndpoint,
+			Insecure:    traceInsecure,
+			SampleRatio: traceSampleRatio,
+			Version:     Version,
+		})
+		if err != nil {
+			return fmt.Errorf("setup tracing: %w", err)
+		}
+		// wrapped before the quota backend to trace its backend calls too
+		be = tracing.NewBackend(be)
+		opts = append(opts, s3api.WithTracing())
+	}
+	if quotaDir != "" {
+		qbe, err := quota.New(be, quotaDir)
+		if err != nil {
+			return fmt.Errorf("setup quota: %w", err)
+		}
+		be = qbe
+	}
+	var rl *utils.RateLimiter
+	if rateLimitDir != "" || rateLimitAccountRPS != 0 || rateLimitAccountBPS != 0 ||
+		rateLimitBucketRPS != 0 || rateLimitBucketBPS != 0 ||
+		rateLimitIPRPS != 0 || rateLimitIPBPS != 0 {
+		var err error
+		rl, err = utils.NewRateLimiter(utils.RateLimitOpts{
+			Account: utils.RateLimits{RequestsPerSecond: rateLimitAccountRPS, BytesPerSecond: rateLimitAccountBPS},
+			Bucket:  utils.RateLimits{RequestsPerSecond: rateLimitBucketRPS, BytesPerSecond: rateLimitBucketBPS},
+			IP:      utils.RateLimits{RequestsPerSecond: rateLimitIPRPS, BytesPerSecond: rateLimitIPBPS},
+			Dir:     rateLimitDir,
+		})
+		if err != nil {
+			return fmt.Errorf("setup rate limiter: %w", err)
+		}
+		opts = append(opts, s3api.WithRateLimiter(rl))
+	}
 
 	admApp := fiber.New(fiber.Config{
 		AppName:      "versitygw",
@@ -447,6 +721,9 @@ func runGateway(ctx context.Context, be backend.Backend) error {
 		}
 		admOpts = append(admOpts, s3api.WithAdminSrvTLS(cert))
 	}
+	if rl != nil {
+		admOpts = append(admOpts, s3api.WithAdminRateLimiter(rl))
+	}
 
 	iam, err := auth.New(&auth.Opts{
 		Dir:                iamDir,
@@ -458,6 +735,7 @@ func runGateway(ctx context.Context, be backend.Backend) error {
 		LDAPAccessAtr:      ldapAccessAtr,
 		LDAPSecretAtr:      ldapSecAtr,
 		LDAPRoleAtr:        ldapRoleAtr,
+		LDAPPolicyAtr:      ldapPolicyAtr,
 		S3Access:           s3IamAccess,
 		S3Secret:           s3IamSecret,
 		S3Region:           s3IamRegion,
@@ -476,6 +754,13 @@ func runGateway(ctx context.Context, be backend.Backend) error {
 	logger, err := s3log.InitLogger(&s3log.LogConfig{
 		LogFile:    accessLog,
 		WebhookURL: logWebhookURL,
+		Format:     accessLogFormat,
+		Rotation: s3log.RotationConfig{
+			MaxSize:    accessLogMaxSize,
+			Interval:   time.Duration(accessLogRotateInterval) * time.Second,
+			MaxBackups: accessLogMaxBackups,
+			Compress:   accessLogCompress,
+		},
 	})
 	if err != nil {
 		return fmt.Errorf("setup logger: %w", err)
@@ -502,11 +787,19 @@ func runGateway(ctx context.Context, be backend.Backend) error {
 
 	admSrv := s3api.NewAdminServer(admApp, be, middlewares.RootUserConfig{Access: rootUserAccess, Secret: rootUserSecret}, admPort, region, iam, admOpts...)
 
-	c := make(chan error, 2)
+	c := make(chan error, 3)
 	go func() { c <- srv.Serve() }()
 	if admPort != "" {
 		go func() { c <- admSrv.Serve() }()
 	}
+	if websitePort != "" {
+		webApp := fiber.New(fiber.Config{
+			AppName:      "versitygw",
+			ServerHeader: "VERSITYGW",
+		})
+		webSrv := s3api.NewWebsiteServer(webApp, be, websitePort, webOpts...)
+		go func() { c <- webSrv.Serve() }()
+	}
 
 	// for/select blocks until shutdown
 Loop:
@@ -548,5 +841,16 @@ Loop:
 		}
 	}
 
+	if traceShutdown != nil {
+		// flush the pending spans
+		err := traceShutdown(context.Background())
+		if err != nil {
+			if saveErr == nil {
+				saveErr = err
+			}
+			fmt.Fprintf(os.Stderr, "shutdown tracing: %v\n", err)
+		}
+	}
+
 	return saveErr
 }
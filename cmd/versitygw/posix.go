@@ -16,9 +16,17 @@ package main
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/urfave/cli/v2"
 	"github.com/versity/versitygw/backend/posix"
+	"github.com/versity/versitygw/backend/sse"
+)
+
+var (
+	chownuid, chowngid bool
+	lifecycleInterval  time.Duration
+	sseKeyFile         string
 )
 
 func posixCommand() *cli.Command {
@@ -36,6 +44,32 @@ bucket: mybucket
 object: a/b/c/myobject
 will be translated into the file /mnt/fs/gwroot/mybucket/a/b/c/myobject`,
 		Action: runPosix,
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:        "chuid",
+				Usage:       "chown newly created files and directories to client account UID",
+				EnvVars:     []string{"VGW_CHOWN_UID"},
+				Destination: &chownuid,
+			},
+			&cli.BoolFlag{
+				Name:        "chgid",
+				Usage:       "chown newly created files and directories to client account GID",
+				EnvVars:     []string{"VGW_CHOWN_GID"},
+				Destination: &chowngid,
+			},
+			&cli.DurationFlag{
+				Name:        "lifecycle-interval",
+				Usage:       "interval between bucket lifecycle scans, lifecycle processing is disabled if not set",
+				EnvVars:     []string{"VGW_LIFECYCLE_INTERVAL"},
+				Destination: &lifecycleInterval,
+			},
+			&cli.StringFlag{
+				Name:        "sse-s3-key-file",
+				Usage:       "file containing the 32 byte master key (raw or hex) used for SSE-S3 encryption",
+				EnvVars:     []string{"VGW_SSE_S3_KEY_FILE"},
+				Destination: &sseKeyFile,
+			},
+		},
 	}
 }
 
@@ -44,7 +78,25 @@ func runPosix(ctx *cli.Context) error {
 		return fmt.Errorf("no directory provided for operation")
 	}
 
-	be, err := posix.New(ctx.Args().Get(0))
+	var opts []posix.Option
+	if chownuid {
+		opts = append(opts, posix.WithChownUID())
+	}
+	if chowngid {
+		opts = append(opts, posix.WithChownGID())
+	}
+	if lifecycleInterval > 0 {
+		opts = append(opts, posix.WithLifecycleScan(lifecycleInterval))
+	}
+	if sseKeyFile != "" {
+		key, err := sse.LoadMasterKey(sseKeyFile)
+		if err != nil {
+			return fmt.Errorf("load sse key: %w", err)
+		}
+		opts = append(opts, posix.WithSSEMasterKey(key))
+	}
+
+	be, err := posix.New(ctx.Args().Get(0), opts...)
 	if err != nil {
 		return fmt.Errorf("init posix: %v", err)
 	}
@@ -19,10 +19,12 @@ import (
 
 	"github.com/urfave/cli/v2"
 	"github.com/versity/versitygw/backend/scoutfs"
+	"github.com/versity/versitygw/backend/sse"
 )
 
 var (
-	glacier bool
+	glacier      bool
+	setprojectid bool
 )
 
 func scoutfsCommand() *cli.Command {
@@ -51,6 +53,30 @@ move interfaces as well as support for tiered filesystems.`,
 				EnvVars:     []string{"VGW_SCOUTFS_GLACIER"},
 				Destination: &glacier,
 			},
+			&cli.BoolFlag{
+				Name:        "chuid",
+				Usage:       "chown newly created files and directories to client account UID",
+				EnvVars:     []string{"VGW_CHOWN_UID"},
+				Destination: &chownuid,
+			},
+			&cli.BoolFlag{
+				Name:        "chgid",
+				Usage:       "chown newly created files and directories to client account GID",
+				EnvVars:     []string{"VGW_CHOWN_GID"},
+				Destination: &chowngid,
+			},
+			&cli.BoolFlag{
+				Name:        "projectid",
+				Usage:       "set the project ID of newly created files and directories to client account ProjectID",
+				EnvVars:     []string{"VGW_SCOUTFS_PROJECTID"},
+				Destination: &setprojectid,
+			},
+			&cli.StringFlag{
+				Name:        "sse-s3-key-file",
+				Usage:       "file containing the 32 byte master key (raw or hex) used for SSE-S3 encryption",
+				EnvVars:     []string{"VGW_SSE_S3_KEY_FILE"},
+				Destination: &sseKeyFile,
+			},
 		},
 	}
 }
@@ -64,6 +90,22 @@ func runScoutfs(ctx *cli.Context) error {
 	if glacier {
 		opts = append(opts, scoutfs.WithGlacierEmulation())
 	}
+	if chownuid {
+		opts = append(opts, scoutfs.WithChownUID())
+	}
+	if chowngid {
+		opts = append(opts, scoutfs.WithChownGID())
+	}
+	if setprojectid {
+		opts = append(opts, scoutfs.WithSetProjectID())
+	}
+	if sseKeyFile != "" {
+		key, err := sse.LoadMasterKey(sseKeyFile)
+		if err != nil {
+			return fmt.Errorf("load sse key: %w", err)
+		}
+		opts = append(opts, scoutfs.WithSSEMasterKey(key))
+	}
 
 	be, err := scoutfs.New(ctx.Args().Get(0), opts...)
 	if err != nil {
@@ -22,6 +22,8 @@ var (
 	download        bool
 	pathStyle       bool
 	checksumDisable bool
+	oidcKeyFile     string
+	oidcAudience    string
 )
 
 func testCommand() *cli.Command {
@@ -63,6 +65,26 @@ func initTestFlags() []cli.Flag {
 			Aliases:     []string{"d"},
 			Destination: &debug,
 		},
+		&cli.StringFlag{
+			Name:        "virtual-domain",
+			Usage:       "gateway virtual domain for virtual-hosted-style bucket addressing tests",
+			Destination: &virtualDomain,
+		},
+		&cli.StringFlag{
+			Name:        "oidc-issuer",
+			Usage:       "gateway trusted oidc issuer for web identity tests",
+			Destination: &oidcIssuer,
+		},
+		&cli.StringFlag{
+			Name:        "oidc-audience",
+			Usage:       "gateway accepted oidc audience for web identity tests",
+			Destination: &oidcAudience,
+		},
+		&cli.StringFlag{
+			Name:        "oidc-key",
+			Usage:       "PEM encoded rsa private key signing the web identity tokens of the oidc tests",
+			Destination: &oidcKeyFile,
+		},
 	}
 }
 
@@ -84,6 +106,31 @@ func initTestCommands() []*cli.Command {
 			Usage:  "Tests iam service",
 			Action: getAction(integration.TestIAM),
 		},
+		{
+			Name:   "sigv2",
+			Usage:  "Tests SigV2 authentication, the gateway has to run with --sigv2",
+			Action: getAction(integration.TestSigV2),
+		},
+		{
+			Name:   "host-style",
+			Usage:  "Tests virtual-hosted-style bucket addressing, the gateway has to run with --virtual-domain",
+			Action: getAction(integration.TestHostStyle),
+		},
+		{
+			Name:   "oidc",
+			Usage:  "Tests AssumeRoleWithWebIdentity, the gateway has to trust the --oidc-issuer and --oidc-audience with the public key of --oidc-key and map the sub claim to the account",
+			Action: getAction(integration.TestOIDC),
+		},
+		{
+			Name:   "quota",
+			Usage:  "Tests storage quotas, the gateway has to run with --quota-dir",
+			Action: getAction(integration.TestQuota),
+		},
+		{
+			Name:   "rate-limit",
+			Usage:  "Tests request rate limiting, the gateway has to run with --rate-limit-dir",
+			Action: getAction(integration.TestRateLimit),
+		},
 		{
 			Name:  "bench",
 			Usage: "Runs download/upload performance test on the gateway",
@@ -257,6 +304,12 @@ func getAction(tf testFunc) func(*cli.Context) error {
 		if debug {
 			opts = append(opts, integration.WithDebug())
 		}
+		if virtualDomain != "" {
+			opts = append(opts, integration.WithVirtualDomain(virtualDomain))
+		}
+		if oidcIssuer != "" || oidcKeyFile != "" {
+			opts = append(opts, integration.WithOIDC(oidcIssuer, oidcAudience, oidcKeyFile))
+		}
 
 		s := integration.NewS3Conf(opts...)
 		tf(s)
@@ -0,0 +1,348 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package metrics keeps the gateway counters and serves them in the
+// Prometheus text exposition format.
+//
+// This implements only the counters, gauges and histograms the gateway
+// exports rather than using prometheus/client_golang. The client library
+// brings in the protobuf and common expfmt dependencies and a global
+// registry of Go runtime collectors, for a text format that is simple
+// enough to write out directly. The output is checked against the
+// exposition format in the tests.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the latency histogram buckets in seconds
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+var registry = &Registry{}
+
+var (
+	Requests = registry.Counter("versitygw_requests_total",
+		"Number of S3 requests by action and status code.", "action", "status")
+	RequestDuration = registry.Histogram("versitygw_request_duration_seconds",
+		"S3 request latencies by action and status code.", DefaultBuckets, "action", "status")
+	RequestsInFlight = registry.Gauge("versitygw_requests_in_flight",
+		"Number of S3 requests being handled.")
+	BucketBytesReceived = registry.Counter("versitygw_bucket_received_bytes_total",
+		"Request body bytes received by bucket.", "bucket")
+	BucketBytesSent = registry.Counter("versitygw_bucket_sent_bytes_total",
+		"Response body bytes sent by bucket.", "bucket")
+	MultipartUploads = registry.Gauge("versitygw_multipart_uploads_active",
+		"Number of multipart uploads in progress, counted from the backend every minute.")
+	IAMCacheHits = registry.Counter("versitygw_iam_cache_hits_total",
+		"Number of account lookups served from the IAM cache.")
+	IAMCacheMisses = registry.Counter("versitygw_iam_cache_misses_total",
+		"Number of account lookups forwarded to the IAM service.")
+	EventFailures = registry.Counter("versitygw_event_delivery_failures_total",
+		"Number of bucket events that failed to be sent by event sender.", "sender")
+	AuditLogFailures = registry.Counter("versitygw_audit_log_failures_total",
+		"Number of audit log entries that failed to be delivered by logger.", "logger")
+)
+
+// Handler serves the gateway metrics
+func Handler() http.Handler {
+	return registry
+}
+
+type metric interface {
+	write(w *bufio.Writer)
+}
+
+// Registry is a set of metrics written out in registration order
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	r.metrics = append(r.metrics, m)
+	r.mu.Unlock()
+}
+
+// Counter registers a counter with the label names
+func (r *Registry) Counter(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{vec: newVec(name, help, "counter", labels)}
+	r.register(c)
+	return c
+}
+
+// Gauge registers a gauge without labels
+func (r *Registry) Gauge(name, help string) *Gauge {
+	g := &Gauge{name: name, help: help}
+	r.register(g)
+	return g
+}
+
+// Histogram registers a histogram with the upper bounds of the buckets
+// and the label names
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{vec: newVec(name, help, "histogram", labels), buckets: buckets}
+	r.register(h)
+	return h
+}
+
+// WriteTo writes out the metrics in the Prometheus text format
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	metrics := r.metrics
+	r.mu.Unlock()
+
+	cw := &countWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, m := range metrics {
+		m.write(bw)
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// ServeHTTP serves the metrics to the Prometheus scrapes
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WriteTo(w)
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// vec holds a value of each label value set, the label values are
+// joined to make the map keys
+type vec struct {
+	name   string
+	help   string
+	typ    string
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]any
+}
+
+func newVec(name, help, typ string, labels []string) vec {
+	return vec{
+		name:   name,
+		help:   help,
+		typ:    typ,
+		labels: labels,
+		values: make(map[string]any),
+	}
+}
+
+// get returns the value of the label values created by newValue, must be
+// called with the lock held
+func (v *vec) get(labelValues []string, newValue func() any) any {
+	if len(labelValues) != len(v.labels) {
+		panic(fmt.Sprintf("metric %v: expected %v label values, got %v",
+			v.name, len(v.labels), len(labelValues)))
+	}
+
+	key := strings.Join(labelValues, "\xff")
+	val, ok := v.values[key]
+	if !ok {
+		// the label values may be request buffers reused by fiber
+		val = newValue()
+		v.values[strings.Clone(key)] = val
+	}
+	return val
+}
+
+// sortedKeys must be called with the lock held
+func (v *vec) sortedKeys() []string {
+	keys := make([]string, 0, len(v.values))
+	for k := range v.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func writeHeader(w *bufio.Writer, name, help, typ string) {
+	fmt.Fprintf(w, "# HELP %v %v\n", name, help)
+	fmt.Fprintf(w, "# TYPE %v %v\n", name, typ)
+}
+
+// labelPairs formats the labels of the key, with the extra name and value
+// appended when set
+func (v *vec) labelPairs(key, extraName, extraValue string) string {
+	var pairs []string
+	if len(v.labels) > 0 {
+		for i, val := range strings.Split(key, "\xff") {
+			pairs = append(pairs, fmt.Sprintf(`%v="%v"`, v.labels[i], labelEscaper.Replace(val)))
+		}
+	}
+	if extraName != "" {
+		pairs = append(pairs, fmt.Sprintf(`%v="%v"`, extraName, extraValue))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// CounterVec is a counter partitioned by its labels
+type CounterVec struct {
+	vec
+}
+
+type counterValue struct {
+	n int64
+}
+
+// Inc adds one to the counter of the label values
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds n to the counter of the label values
+func (c *CounterVec) Add(n int64, labelValues ...string) {
+	c.mu.Lock()
+	c.get(labelValues, func() any { return &counterValue{} }).(*counterValue).n += n
+	c.mu.Unlock()
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	writeHeader(w, c.name, c.help, c.typ)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.labels) == 0 && len(c.values) == 0 {
+		// counters without labels are exported from the start
+		fmt.Fprintf(w, "%v 0\n", c.name)
+		return
+	}
+	for _, k := range c.sortedKeys() {
+		fmt.Fprintf(w, "%v%v %v\n", c.name, c.labelPairs(k, "", ""),
+			c.values[k].(*counterValue).n)
+	}
+}
+
+// Gauge is a value that can go up and down
+type Gauge struct {
+	name string
+	help string
+
+	mu sync.Mutex
+	n  int64
+}
+
+// Inc adds one to the gauge
+func (g *Gauge) Inc() {
+	g.Add(1)
+}
+
+// Dec subtracts one from the gauge
+func (g *Gauge) Dec() {
+	g.Add(-1)
+}
+
+// Add adds n to the gauge
+func (g *Gauge) Add(n int64) {
+	g.mu.Lock()
+	g.n += n
+	g.mu.Unlock()
+}
+
+// Set sets the gauge to n
+func (g *Gauge) Set(n int64) {
+	g.mu.Lock()
+	g.n = n
+	g.mu.Unlock()
+}
+
+// Value returns the value of the gauge
+func (g *Gauge) Value() int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.n
+}
+
+func (g *Gauge) write(w *bufio.Writer) {
+	writeHeader(w, g.name, g.help, "gauge")
+	fmt.Fprintf(w, "%v %v\n", g.name, g.Value())
+}
+
+// HistogramVec is a histogram partitioned by its labels
+type HistogramVec struct {
+	vec
+	buckets []float64
+}
+
+type histogramValue struct {
+	counts []int64
+	count  int64
+	sum    float64
+}
+
+// Observe adds the value to the histogram of the label values
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hv := h.get(labelValues, func() any {
+		return &histogramValue{counts: make([]int64, len(h.buckets))}
+	}).(*histogramValue)
+
+	for i, le := range h.buckets {
+		if value <= le {
+			hv.counts[i]++
+		}
+	}
+	hv.count++
+	hv.sum += value
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	writeHeader(w, h.name, h.help, h.typ)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, k := range h.sortedKeys() {
+		hv := h.values[k].(*histogramValue)
+		for i, le := range h.buckets {
+			fmt.Fprintf(w, "%v_bucket%v %v\n", h.name,
+				h.labelPairs(k, "le", formatFloat(le)), hv.counts[i])
+		}
+		fmt.Fprintf(w, "%v_bucket%v %v\n", h.name, h.labelPairs(k, "le", "+Inf"), hv.count)
+		fmt.Fprintf(w, "%v_sum%v %v\n", h.name, h.labelPairs(k, "", ""), formatFloat(hv.sum))
+		fmt.Fprintf(w, "%v_count%v %v\n", h.name, h.labelPairs(k, "", ""), hv.count)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistryWriteTo(t *testing.T) {
+	r := &Registry{}
+	requests := r.Counter("test_requests_total", "Test requests.", "action", "status")
+	hits := r.Counter("test_hits_total", "Test hits.")
+	inFlight := r.Gauge("test_in_flight", "Test in flight.")
+	duration := r.Histogram("test_duration_seconds", "Test durations.", []float64{.1, 1}, "action")
+
+	requests.Inc("PutObject", "200")
+	requests.Add(2, "GetObject", "404")
+	requests.Inc("PutObject", "200")
+	requests.Inc(`a"b\c`, "500")
+	inFlight.Inc()
+	inFlight.Inc()
+	inFlight.Dec()
+	duration.Observe(.05, "GetObject")
+	duration.Observe(.5, "GetObject")
+	duration.Observe(5, "GetObject")
+
+	expected := `# HELP test_requests_total Test requests.
+# TYPE test_requests_total counter
+test_requests_total{action="GetObject",status="404"} 2
+test_requests_total{action="PutObject",status="200"} 2
+test_requests_total{action="a\"b\\c",status="500"} 1
+# HELP test_hits_total Test hits.
+# TYPE test_hits_total counter
+test_hits_total 0
+# HELP test_in_flight Test in flight.
+# TYPE test_in_flight gauge
+test_in_flight 1
+# HELP test_duration_seconds Test durations.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{action="GetObject",le="0.1"} 1
+test_duration_seconds_bucket{action="GetObject",le="1"} 2
+test_duration_seconds_bucket{action="GetObject",le="+Inf"} 3
+test_duration_seconds_sum{action="GetObject"} 5.55
+test_duration_seconds_count{action="GetObject"} 3
+`
+	if out := writeString(t, r); out != expected {
+		t.Fatalf("unexpected metrics output:\n%v\nexpected:\n%v", out, expected)
+	}
+
+	hits.Inc()
+	if !strings.Contains(writeString(t, r), "test_hits_total 1\n") {
+		t.Fatal("expected the counter to be incremented")
+	}
+
+	inFlight.Set(5)
+	if !strings.Contains(writeString(t, r), "test_in_flight 5\n") {
+		t.Fatal("expected the gauge to be set")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Fatalf("unexpected content type %v", ct)
+	}
+	if !strings.Contains(w.Body.String(), "# TYPE versitygw_requests_total counter\n") {
+		t.Fatalf("expected the gateway metrics, got:\n%v", w.Body.String())
+	}
+}
+
+func writeString(t *testing.T, r *Registry) string {
+	t.Helper()
+	var b strings.Builder
+	_, err := r.WriteTo(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
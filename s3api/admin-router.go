@@ -19,12 +19,16 @@ import (
 	"github.com/versity/versitygw/auth"
 	"github.com/versity/versitygw/backend"
 	"github.com/versity/versitygw/s3api/controllers"
+	"github.com/versity/versitygw/s3api/utils"
 )
 
-type S3AdminRouter struct{}
+type S3AdminRouter struct {
+	// RateLimiter is the request rate limiter managed by the admin apis
+	RateLimiter *utils.RateLimiter
+}
 
 func (ar *S3AdminRouter) Init(app *fiber.App, be backend.Backend, iam auth.IAMService) {
-	controller := controllers.NewAdminController(iam, be)
+	controller := controllers.NewAdminController(iam, be, ar.RateLimiter)
 
 	// CreateUser admin api
 	app.Patch("/create-user", controller.CreateUser)
@@ -32,6 +36,12 @@ func (ar *S3AdminRouter) Init(app *fiber.App, be backend.Backend, iam auth.IAMSe
 	// DeleteUsers admin api
 	app.Patch("/delete-user", controller.DeleteUser)
 
+	// PutUserPolicy admin api
+	app.Patch("/put-user-policy", controller.PutUserPolicy)
+
+	// DeleteUserPolicy admin api
+	app.Patch("/delete-user-policy", controller.DeleteUserPolicy)
+
 	// ListUsers admin api
 	app.Patch("/list-users", controller.ListUsers)
 
@@ -40,4 +50,22 @@ func (ar *S3AdminRouter) Init(app *fiber.App, be backend.Backend, iam auth.IAMSe
 
 	// ListBucketsAndOwners admin api
 	app.Patch("/list-buckets", controller.ListBuckets)
+
+	// PutQuota admin api
+	app.Patch("/put-quota", controller.PutQuota)
+
+	// GetQuota admin api
+	app.Patch("/get-quota", controller.GetQuota)
+
+	// DeleteQuota admin api
+	app.Patch("/delete-quota", controller.DeleteQuota)
+
+	// PutRateLimit admin api
+	app.Patch("/put-rate-limit", controller.PutRateLimit)
+
+	// GetRateLimit admin api
+	app.Patch("/get-rate-limit", controller.GetRateLimit)
+
+	// DeleteRateLimit admin api
+	app.Patch("/delete-rate-limit", controller.DeleteRateLimit)
 }
@@ -22,6 +22,7 @@ import (
 	"github.com/versity/versitygw/auth"
 	"github.com/versity/versitygw/backend"
 	"github.com/versity/versitygw/s3api/middlewares"
+	"github.com/versity/versitygw/s3api/utils"
 )
 
 type S3AdminServer struct {
@@ -64,6 +65,12 @@ func WithAdminSrvTLS(cert tls.Certificate) AdminOpt {
 	return func(s *S3AdminServer) { s.cert = &cert }
 }
 
+// WithAdminRateLimiter enables the admin apis managing the account rate
+// limits of the gateway
+func WithAdminRateLimiter(rl *utils.RateLimiter) AdminOpt {
+	return func(s *S3AdminServer) { s.router.RateLimiter = rl }
+}
+
 func (sa *S3AdminServer) Serve() (err error) {
 	if sa.cert != nil {
 		return sa.app.ListenTLSWithCertificate(sa.port, *sa.cert)
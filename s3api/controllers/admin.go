@@ -21,20 +21,30 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/versity/versitygw/auth"
 	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/backend/quota"
+	"github.com/versity/versitygw/s3api/utils"
 )
 
 type AdminController struct {
 	iam auth.IAMService
 	be  backend.Backend
+	rl  *utils.RateLimiter
 }
 
-func NewAdminController(iam auth.IAMService, be backend.Backend) AdminController {
-	return AdminController{iam: iam, be: be}
+func NewAdminController(iam auth.IAMService, be backend.Backend, rl *utils.RateLimiter) AdminController {
+	return AdminController{iam: iam, be: be, rl: rl}
+}
+
+// isAdmin reports if the account may use the admin apis. The session
+// policies only describe the S3 actions, so the admin sessions limited by
+// a policy are denied.
+func isAdmin(acct auth.Account) bool {
+	return acct.Role == auth.RoleAdmin && acct.SessionPolicy == nil
 }
 
 func (c AdminController) CreateUser(ctx *fiber.Ctx) error {
 	acct := ctx.Locals("account").(auth.Account)
-	if acct.Role != "admin" {
+	if !isAdmin(acct) {
 		return fmt.Errorf("access denied: only admin users have access to this resource")
 	}
 	var usr auth.Account
@@ -46,6 +56,11 @@ func (c AdminController) CreateUser(ctx *fiber.Ctx) error {
 	if usr.Role != auth.RoleAdmin && usr.Role != auth.RoleUser && usr.Role != auth.RoleUserPlus {
 		return fmt.Errorf("invalid parameters: user role have to be one of the following: 'user', 'admin', 'userplus'")
 	}
+	if usr.Policy != "" {
+		if err := auth.ValidateIdentityPolicy(usr.Policy); err != nil {
+			return fmt.Errorf("invalid parameters: %w", err)
+		}
+	}
 
 	err = c.iam.CreateAccount(usr)
 	if err != nil {
@@ -58,7 +73,7 @@ func (c AdminController) CreateUser(ctx *fiber.Ctx) error {
 func (c AdminController) DeleteUser(ctx *fiber.Ctx) error {
 	access := ctx.Query("access")
 	acct := ctx.Locals("account").(auth.Account)
-	if acct.Role != "admin" {
+	if !isAdmin(acct) {
 		return fmt.Errorf("access denied: only admin users have access to this resource")
 	}
 
@@ -70,9 +85,44 @@ func (c AdminController) DeleteUser(ctx *fiber.Ctx) error {
 	return ctx.SendString("The user has been deleted successfully")
 }
 
+func (c AdminController) PutUserPolicy(ctx *fiber.Ctx) error {
+	access := ctx.Query("access")
+	acct := ctx.Locals("account").(auth.Account)
+	if !isAdmin(acct) {
+		return fmt.Errorf("access denied: only admin users have access to this resource")
+	}
+
+	policy := string(ctx.Body())
+	if err := auth.ValidateIdentityPolicy(policy); err != nil {
+		return fmt.Errorf("invalid parameters: %w", err)
+	}
+
+	err := c.iam.PutUserPolicy(access, policy)
+	if err != nil {
+		return fmt.Errorf("failed to put the user policy: %w", err)
+	}
+
+	return ctx.SendString("The user policy has been updated successfully")
+}
+
+func (c AdminController) DeleteUserPolicy(ctx *fiber.Ctx) error {
+	access := ctx.Query("access")
+	acct := ctx.Locals("account").(auth.Account)
+	if !isAdmin(acct) {
+		return fmt.Errorf("access denied: only admin users have access to this resource")
+	}
+
+	err := c.iam.PutUserPolicy(access, "")
+	if err != nil {
+		return fmt.Errorf("failed to delete the user policy: %w", err)
+	}
+
+	return ctx.SendString("The user policy has been deleted successfully")
+}
+
 func (c AdminController) ListUsers(ctx *fiber.Ctx) error {
 	acct := ctx.Locals("account").(auth.Account)
-	if acct.Role != "admin" {
+	if !isAdmin(acct) {
 		return fmt.Errorf("access denied: only admin users have access to this resource")
 	}
 	accs, err := c.iam.ListUserAccounts()
@@ -85,7 +135,7 @@ func (c AdminController) ListUsers(ctx *fiber.Ctx) error {
 
 func (c AdminController) ChangeBucketOwner(ctx *fiber.Ctx) error {
 	acct := ctx.Locals("account").(auth.Account)
-	if acct.Role != "admin" {
+	if !isAdmin(acct) {
 		return fmt.Errorf("access denied: only admin users have access to this resource")
 	}
 	owner := ctx.Query("owner")
@@ -109,7 +159,7 @@ func (c AdminController) ChangeBucketOwner(ctx *fiber.Ctx) error {
 
 func (c AdminController) ListBuckets(ctx *fiber.Ctx) error {
 	acct := ctx.Locals("account").(auth.Account)
-	if acct.Role != "admin" {
+	if !isAdmin(acct) {
 		return fmt.Errorf("access denied: only admin users have access to this resource")
 	}
 
@@ -120,3 +170,164 @@ func (c AdminController) ListBuckets(ctx *fiber.Ctx) error {
 
 	return ctx.JSON(buckets)
 }
+
+// quotaTarget returns the quota backend and the bucket or account
+// requested for the quota admin apis
+func (c AdminController) quotaTarget(ctx *fiber.Ctx) (*quota.Backend, string, string, error) {
+	acct := ctx.Locals("account").(auth.Account)
+	if !isAdmin(acct) {
+		return nil, "", "", fmt.Errorf("access denied: only admin users have access to this resource")
+	}
+	q, ok := c.be.(*quota.Backend)
+	if !ok {
+		return nil, "", "", fmt.Errorf("quotas are not enabled in the gateway")
+	}
+
+	bucket := ctx.Query("bucket")
+	access := ctx.Query("account")
+	if (bucket == "") == (access == "") {
+		return nil, "", "", fmt.Errorf("invalid parameters: either a bucket or an account should be specified")
+	}
+	if access != "" {
+		accs, err := auth.CheckIfAccountsExist([]string{access}, c.iam)
+		if err != nil {
+			return nil, "", "", err
+		}
+		if len(accs) > 0 {
+			return nil, "", "", fmt.Errorf("user specified for the quota does not exist")
+		}
+	}
+
+	return q, bucket, access, nil
+}
+
+func (c AdminController) PutQuota(ctx *fiber.Ctx) error {
+	q, bucket, access, err := c.quotaTarget(ctx)
+	if err != nil {
+		return err
+	}
+
+	var limits quota.Limits
+	err = json.Unmarshal(ctx.Body(), &limits)
+	if err != nil {
+		return fmt.Errorf("failed to parse request body: %w", err)
+	}
+
+	if bucket != "" {
+		err = q.PutBucketQuota(ctx.Context(), bucket, limits)
+	} else {
+		err = q.PutAccountQuota(ctx.Context(), access, limits)
+	}
+	if err != nil {
+		return err
+	}
+
+	return ctx.SendString("The quota has been updated successfully")
+}
+
+func (c AdminController) GetQuota(ctx *fiber.Ctx) error {
+	q, bucket, access, err := c.quotaTarget(ctx)
+	if err != nil {
+		return err
+	}
+
+	var data quota.Quota
+	if bucket != "" {
+		data, err = q.GetBucketQuota(ctx.Context(), bucket)
+	} else {
+		data, err = q.GetAccountQuota(ctx.Context(), access)
+	}
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(data)
+}
+
+func (c AdminController) DeleteQuota(ctx *fiber.Ctx) error {
+	q, bucket, access, err := c.quotaTarget(ctx)
+	if err != nil {
+		return err
+	}
+
+	if bucket != "" {
+		err = q.PutBucketQuota(ctx.Context(), bucket, quota.Limits{})
+	} else {
+		err = q.PutAccountQuota(ctx.Context(), access, quota.Limits{})
+	}
+	if err != nil {
+		return err
+	}
+
+	return ctx.SendString("The quota has been deleted successfully")
+}
+
+// rateLimitAccount returns the account requested for the rate limit
+// admin apis
+func (c AdminController) rateLimitAccount(ctx *fiber.Ctx) (string, error) {
+	acct := ctx.Locals("account").(auth.Account)
+	if !isAdmin(acct) {
+		return "", fmt.Errorf("access denied: only admin users have access to this resource")
+	}
+	if c.rl == nil {
+		return "", fmt.Errorf("rate limiting is not enabled in the gateway")
+	}
+
+	access := ctx.Query("account")
+	if access == "" {
+		return "", fmt.Errorf("invalid parameters: an account should be specified")
+	}
+
+	return access, nil
+}
+
+func (c AdminController) PutRateLimit(ctx *fiber.Ctx) error {
+	access, err := c.rateLimitAccount(ctx)
+	if err != nil {
+		return err
+	}
+
+	accs, err := auth.CheckIfAccountsExist([]string{access}, c.iam)
+	if err != nil {
+		return err
+	}
+	if len(accs) > 0 {
+		return fmt.Errorf("user specified for the rate limit does not exist")
+	}
+
+	var limits utils.RateLimits
+	err = json.Unmarshal(ctx.Body(), &limits)
+	if err != nil {
+		return fmt.Errorf("failed to parse request body: %w", err)
+	}
+
+	err = c.rl.PutAccountLimits(access, limits)
+	if err != nil {
+		return err
+	}
+
+	return ctx.SendString("The rate limit has been updated successfully")
+}
+
+func (c AdminController) GetRateLimit(ctx *fiber.Ctx) error {
+	access, err := c.rateLimitAccount(ctx)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(c.rl.GetAccountLimits(access))
+}
+
+func (c AdminController) DeleteRateLimit(ctx *fiber.Ctx) error {
+	access, err := c.rateLimitAccount(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = c.rl.DeleteAccountLimits(access)
+	if err != nil {
+		return err
+	}
+
+	return ctx.SendString("The rate limit has been deleted successfully")
+}
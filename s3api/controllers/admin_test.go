@@ -21,10 +21,13 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/gofiber/fiber/v2"
 	"github.com/versity/versitygw/auth"
+	"github.com/versity/versitygw/backend/quota"
 	"github.com/versity/versitygw/s3response"
 )
 
@@ -71,6 +74,16 @@ func TestAdminController_CreateUser(t *testing.T) {
 
 	appErr.Patch("/create-user", adminController.CreateUser)
 
+	appSession := fiber.New()
+
+	appSession.Use(func(ctx *fiber.Ctx) error {
+		ctx.Locals("account", auth.Account{Access: "admin1", Secret: "secret", Role: "admin",
+			Session: true, SessionPolicy: &auth.SessionPolicy{}})
+		return ctx.Next()
+	})
+
+	appSession.Patch("/create-user", adminController.CreateUser)
+
 	tests := []struct {
 		name       string
 		app        *fiber.App
@@ -105,6 +118,15 @@ func TestAdminController_CreateUser(t *testing.T) {
 			wantErr:    false,
 			statusCode: 500,
 		},
+		{
+			name: "Admin-create-user-session-policy",
+			app:  appSession,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/create-user", bytes.NewBuffer(succUsr)),
+			},
+			wantErr:    false,
+			statusCode: 500,
+		},
 	}
 	for _, tt := range tests {
 		resp, err := tt.app.Test(tt.args.req)
@@ -189,6 +211,209 @@ func TestAdminController_DeleteUser(t *testing.T) {
 	}
 }
 
+func TestAdminController_PutUserPolicy(t *testing.T) {
+	type args struct {
+		req *http.Request
+	}
+
+	adminController := AdminController{
+		iam: &IAMServiceMock{
+			PutUserPolicyFunc: func(access, policy string) error {
+				return nil
+			},
+		},
+	}
+
+	app := fiber.New()
+
+	app.Use(func(ctx *fiber.Ctx) error {
+		ctx.Locals("account", auth.Account{Access: "admin1", Secret: "secret", Role: "admin"})
+		return ctx.Next()
+	})
+
+	app.Patch("/put-user-policy", adminController.PutUserPolicy)
+
+	appErr := fiber.New()
+
+	appErr.Use(func(ctx *fiber.Ctx) error {
+		ctx.Locals("account", auth.Account{Access: "user1", Secret: "secret", Role: "user"})
+		return ctx.Next()
+	})
+
+	appErr.Patch("/put-user-policy", adminController.PutUserPolicy)
+
+	policy := `{"Statement": [{"Effect": "Allow", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/*"}]}`
+	principalPolicy := `{"Statement": [{"Effect": "Allow", "Principal": "*", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/*"}]}`
+
+	tests := []struct {
+		name       string
+		app        *fiber.App
+		args       args
+		wantErr    bool
+		statusCode int
+	}{
+		{
+			name: "Admin-put-user-policy-success",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/put-user-policy?access=test", strings.NewReader(policy)),
+			},
+			wantErr:    false,
+			statusCode: 200,
+		},
+		{
+			name: "Admin-put-user-policy-invalid-policy",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/put-user-policy?access=test", strings.NewReader(principalPolicy)),
+			},
+			wantErr:    false,
+			statusCode: 500,
+		},
+		{
+			name: "Admin-put-user-policy-invalid-requester-role",
+			app:  appErr,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/put-user-policy?access=test", strings.NewReader(policy)),
+			},
+			wantErr:    false,
+			statusCode: 500,
+		},
+	}
+	for _, tt := range tests {
+		resp, err := tt.app.Test(tt.args.req)
+
+		if (err != nil) != tt.wantErr {
+			t.Errorf("AdminController.PutUserPolicy() error = %v, wantErr %v", err, tt.wantErr)
+		}
+
+		if resp.StatusCode != tt.statusCode {
+			t.Errorf("AdminController.PutUserPolicy() statusCode = %v, wantStatusCode = %v", resp.StatusCode, tt.statusCode)
+		}
+	}
+}
+
+func TestAdminController_PutQuota(t *testing.T) {
+	type args struct {
+		req *http.Request
+	}
+
+	acl, _ := json.Marshal(auth.ACL{Owner: "user1"})
+	qbe, err := quota.New(&BackendMock{
+		GetBucketAclFunc: func(contextMoqParam context.Context, getBucketAclInput *s3.GetBucketAclInput) ([]byte, error) {
+			return acl, nil
+		},
+		GetBucketVersioningFunc: func(contextMoqParam context.Context, bucket string) (*s3.GetBucketVersioningOutput, error) {
+			return &s3.GetBucketVersioningOutput{}, nil
+		},
+		ListObjectsV2Func: func(contextMoqParam context.Context, listObjectsV2Input *s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error) {
+			return &s3.ListObjectsV2Output{}, nil
+		},
+	}, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	adminController := AdminController{
+		iam: &IAMServiceMock{},
+		be:  qbe,
+	}
+
+	app := fiber.New()
+
+	app.Use(func(ctx *fiber.Ctx) error {
+		ctx.Locals("account", auth.Account{Access: "admin1", Secret: "secret", Role: "admin"})
+		return ctx.Next()
+	})
+
+	app.Patch("/put-quota", adminController.PutQuota)
+
+	appErr := fiber.New()
+
+	appErr.Use(func(ctx *fiber.Ctx) error {
+		ctx.Locals("account", auth.Account{Access: "user1", Secret: "secret", Role: "user"})
+		return ctx.Next()
+	})
+
+	appErr.Patch("/put-quota", adminController.PutQuota)
+
+	appNoQuota := fiber.New()
+
+	appNoQuota.Use(func(ctx *fiber.Ctx) error {
+		ctx.Locals("account", auth.Account{Access: "admin1", Secret: "secret", Role: "admin"})
+		return ctx.Next()
+	})
+
+	appNoQuota.Patch("/put-quota", AdminController{be: &BackendMock{}}.PutQuota)
+
+	limits := `{"maxBytes": 1024, "maxObjects": 10}`
+
+	tests := []struct {
+		name       string
+		app        *fiber.App
+		args       args
+		wantErr    bool
+		statusCode int
+	}{
+		{
+			name: "Admin-put-quota-success",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/put-quota?bucket=test", strings.NewReader(limits)),
+			},
+			wantErr:    false,
+			statusCode: 200,
+		},
+		{
+			name: "Admin-put-quota-negative-limit",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/put-quota?bucket=test", strings.NewReader(`{"maxBytes": -1}`)),
+			},
+			wantErr:    false,
+			statusCode: 500,
+		},
+		{
+			name: "Admin-put-quota-bucket-and-account",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/put-quota?bucket=test&account=user1", strings.NewReader(limits)),
+			},
+			wantErr:    false,
+			statusCode: 500,
+		},
+		{
+			name: "Admin-put-quota-not-enabled",
+			app:  appNoQuota,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/put-quota?bucket=test", strings.NewReader(limits)),
+			},
+			wantErr:    false,
+			statusCode: 500,
+		},
+		{
+			name: "Admin-put-quota-invalid-requester-role",
+			app:  appErr,
+			args: args{
+				req: httptest.NewRequest(http.MethodPatch, "/put-quota?bucket=test", strings.NewReader(limits)),
+			},
+			wantErr:    false,
+			statusCode: 500,
+		},
+	}
+	for _, tt := range tests {
+		resp, err := tt.app.Test(tt.args.req)
+
+		if (err != nil) != tt.wantErr {
+			t.Errorf("AdminController.PutQuota() error = %v, wantErr %v", err, tt.wantErr)
+		}
+
+		if resp.StatusCode != tt.statusCode {
+			t.Errorf("AdminController.PutQuota() statusCode = %v, wantStatusCode = %v", resp.StatusCode, tt.statusCode)
+		}
+	}
+}
+
 func TestAdminController_ListUsers(t *testing.T) {
 	type args struct {
 		req *http.Request
@@ -22,6 +22,7 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -55,6 +56,13 @@ func New(be backend.Backend, iam auth.IAMService, logger s3log.AuditLogger, evs
 
 func (c S3ApiController) ListBuckets(ctx *fiber.Ctx) error {
 	acct := ctx.Locals("account").(auth.Account)
+	if acct.Role == auth.RoleAnonymous {
+		return SendXMLResponse(ctx, nil, s3err.GetAPIError(s3err.ErrAccessDenied),
+			&MetaOpts{
+				Logger: c.logger,
+				Action: "ListBucket",
+			})
+	}
 	res, err := c.be.ListBuckets(ctx.Context(), acct.Access, acct.Role == "admin")
 	return SendXMLResponse(ctx, res, err,
 		&MetaOpts{
@@ -124,6 +132,70 @@ func (c S3ApiController) GetActions(ctx *fiber.Ctx) error {
 			})
 	}
 
+	if ctx.Request().URI().QueryArgs().Has("retention") {
+		err := auth.VerifyAccess(ctx.Context(), c.be, auth.AccessOptions{
+			Acl:           parsedAcl,
+			AclPermission: types.PermissionRead,
+			IsRoot:        isRoot,
+			Acc:           acct,
+			Bucket:        bucket,
+			Object:        key,
+			Action:        auth.GetObjectRetentionAction,
+		})
+		if err != nil {
+			return SendXMLResponse(ctx, nil, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "GetObjectRetention",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		res, err := c.be.GetObjectRetention(ctx.Context(), &s3.GetObjectRetentionInput{
+			Bucket:    &bucket,
+			Key:       &key,
+			VersionId: &versionId,
+		})
+		return SendXMLResponse(ctx, res, err,
+			&MetaOpts{
+				Logger:      c.logger,
+				Action:      "GetObjectRetention",
+				BucketOwner: parsedAcl.Owner,
+			})
+	}
+
+	if ctx.Request().URI().QueryArgs().Has("legal-hold") {
+		err := auth.VerifyAccess(ctx.Context(), c.be, auth.AccessOptions{
+			Acl:           parsedAcl,
+			AclPermission: types.PermissionRead,
+			IsRoot:        isRoot,
+			Acc:           acct,
+			Bucket:        bucket,
+			Object:        key,
+			Action:        auth.GetObjectLegalHoldAction,
+		})
+		if err != nil {
+			return SendXMLResponse(ctx, nil, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "GetObjectLegalHold",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		res, err := c.be.GetObjectLegalHold(ctx.Context(), &s3.GetObjectLegalHoldInput{
+			Bucket:    &bucket,
+			Key:       &key,
+			VersionId: &versionId,
+		})
+		return SendXMLResponse(ctx, res, err,
+			&MetaOpts{
+				Logger:      c.logger,
+				Action:      "GetObjectLegalHold",
+				BucketOwner: parsedAcl.Owner,
+			})
+	}
+
 	if uploadId != "" {
 		if maxParts < 0 && ctx.Request().URI().QueryArgs().Has("max-parts") {
 			return SendResponse(ctx,
@@ -232,17 +304,39 @@ func (c S3ApiController) GetActions(ctx *fiber.Ctx) error {
 					BucketOwner: parsedAcl.Owner,
 				})
 		}
+		// the attributes may be a comma separated list, or sent
+		// as multiple headers
 		var oattrs []types.ObjectAttributes
-		for _, a := range strings.Split(attrs, ",") {
-			oattrs = append(oattrs, types.ObjectAttributes(a))
+		for _, hdr := range ctx.Request().Header.PeekAll("X-Amz-Object-Attributes") {
+			for _, a := range strings.Split(string(hdr), ",") {
+				oattrs = append(oattrs, types.ObjectAttributes(strings.TrimSpace(a)))
+			}
 		}
 		res, err := c.be.GetObjectAttributes(ctx.Context(),
 			&s3.GetObjectAttributesInput{
-				Bucket:           &bucket,
-				Key:              &key,
-				ObjectAttributes: oattrs,
+				Bucket:               &bucket,
+				Key:                  &key,
+				VersionId:            &versionId,
+				ObjectAttributes:     oattrs,
+				SSECustomerAlgorithm: getHeaderPtr(ctx, "X-Amz-Server-Side-Encryption-Customer-Algorithm"),
+				SSECustomerKey:       getHeaderPtr(ctx, "X-Amz-Server-Side-Encryption-Customer-Key"),
+				SSECustomerKeyMD5:    getHeaderPtr(ctx, "X-Amz-Server-Side-Encryption-Customer-Key-Md5"),
 			})
-		return SendXMLResponse(ctx, res, err,
+		if err != nil {
+			return SendXMLResponse(ctx, nil, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "GetObjectAttributes",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+		if res.LastModified != nil {
+			ctx.Response().Header.Set("Last-Modified", res.LastModified.Format(timefmt))
+		}
+		if getstring(res.VersionId) != "" {
+			ctx.Response().Header.Set("x-amz-version-id", *res.VersionId)
+		}
+		return SendXMLResponse(ctx, objectAttributesResponse(res, oattrs), nil,
 			&MetaOpts{
 				Logger:      c.logger,
 				Action:      "GetObjectAttributes",
@@ -270,10 +364,14 @@ func (c S3ApiController) GetActions(ctx *fiber.Ctx) error {
 
 	ctx.Locals("logResBody", false)
 	res, err := c.be.GetObject(ctx.Context(), &s3.GetObjectInput{
-		Bucket:    &bucket,
-		Key:       &key,
-		Range:     &acceptRange,
-		VersionId: &versionId,
+		Bucket:               &bucket,
+		Key:                  &key,
+		Range:                &acceptRange,
+		VersionId:            &versionId,
+		SSECustomerAlgorithm: getHeaderPtr(ctx, "X-Amz-Server-Side-Encryption-Customer-Algorithm"),
+		SSECustomerKey:       getHeaderPtr(ctx, "X-Amz-Server-Side-Encryption-Customer-Key"),
+		SSECustomerKeyMD5:    getHeaderPtr(ctx, "X-Amz-Server-Side-Encryption-Customer-Key-Md5"),
+		ChecksumMode:         types.ChecksumMode(strings.ToUpper(ctx.Get("X-Amz-Checksum-Mode"))),
 	}, ctx.Response().BodyWriter())
 	if err != nil {
 		return SendResponse(ctx, err,
@@ -341,6 +439,16 @@ func (c S3ApiController) GetActions(ctx *fiber.Ctx) error {
 			},
 		})
 	}
+	if getstring(res.VersionId) != "" {
+		utils.SetResponseHeaders(ctx, []utils.CustomHeader{
+			{
+				Key:   "x-amz-version-id",
+				Value: *res.VersionId,
+			},
+		})
+	}
+	setSSEHeaders(ctx, res.ServerSideEncryption, res.SSECustomerAlgorithm, res.SSECustomerKeyMD5)
+	setChecksumHeaders(ctx, res.ChecksumCRC32, res.ChecksumCRC32C, res.ChecksumSHA1, res.ChecksumSHA256)
 
 	return SendResponse(ctx, err,
 		&MetaOpts{
@@ -350,6 +458,27 @@ func (c S3ApiController) GetActions(ctx *fiber.Ctx) error {
 		})
 }
 
+// objectAttributesResponse returns the requested attributes of the
+// object attributes output
+func objectAttributesResponse(res *s3.GetObjectAttributesOutput, attrs []types.ObjectAttributes) s3response.GetObjectAttributesResponse {
+	var resp s3response.GetObjectAttributesResponse
+	for _, attr := range attrs {
+		switch attr {
+		case types.ObjectAttributesEtag:
+			resp.ETag = res.ETag
+		case types.ObjectAttributesChecksum:
+			resp.Checksum = res.Checksum
+		case types.ObjectAttributesObjectParts:
+			resp.ObjectParts = res.ObjectParts
+		case types.ObjectAttributesStorageClass:
+			resp.StorageClass = res.StorageClass
+		case types.ObjectAttributesObjectSize:
+			resp.ObjectSize = res.ObjectSize
+		}
+	}
+	return resp
+}
+
 func getstring(s *string) string {
 	if s == nil {
 		return ""
@@ -357,6 +486,59 @@ func getstring(s *string) string {
 	return *s
 }
 
+// getHeaderPtr returns the request header value, or nil if the header
+// is not set
+func getHeaderPtr(ctx *fiber.Ctx, key string) *string {
+	val := ctx.Get(key)
+	if val == "" {
+		return nil
+	}
+	return &val
+}
+
+// setSSEHeaders sets the server side encryption response headers
+func setSSEHeaders(ctx *fiber.Ctx, sse types.ServerSideEncryption, customerAlgorithm, customerKeyMD5 *string) {
+	if sse != "" {
+		ctx.Response().Header.Set("x-amz-server-side-encryption", string(sse))
+	}
+	if getstring(customerAlgorithm) != "" {
+		ctx.Response().Header.Set("x-amz-server-side-encryption-customer-algorithm", *customerAlgorithm)
+	}
+	if getstring(customerKeyMD5) != "" {
+		ctx.Response().Header.Set("x-amz-server-side-encryption-customer-key-MD5", *customerKeyMD5)
+	}
+}
+
+// checksumAlgorithm returns the additional checksum algorithm of the
+// request, either from the SDK algorithm header or from the checksum
+// sent in the trailer of a streaming upload
+func checksumAlgorithm(ctx *fiber.Ctx) types.ChecksumAlgorithm {
+	if algo := ctx.Get("X-Amz-Sdk-Checksum-Algorithm"); algo != "" {
+		return types.ChecksumAlgorithm(strings.ToUpper(algo))
+	}
+	trailer := strings.ToLower(ctx.Get("X-Amz-Trailer"))
+	if algo, ok := strings.CutPrefix(trailer, "x-amz-checksum-"); ok {
+		return types.ChecksumAlgorithm(strings.ToUpper(algo))
+	}
+	return ""
+}
+
+// setChecksumHeaders sets the additional checksum response headers
+func setChecksumHeaders(ctx *fiber.Ctx, crc32, crc32c, sha1, sha256 *string) {
+	if getstring(crc32) != "" {
+		ctx.Response().Header.Set("x-amz-checksum-crc32", *crc32)
+	}
+	if getstring(crc32c) != "" {
+		ctx.Response().Header.Set("x-amz-checksum-crc32c", *crc32c)
+	}
+	if getstring(sha1) != "" {
+		ctx.Response().Header.Set("x-amz-checksum-sha1", *sha1)
+	}
+	if getstring(sha256) != "" {
+		ctx.Response().Header.Set("x-amz-checksum-sha256", *sha256)
+	}
+}
+
 func getint64(i *int64) int64 {
 	if i == nil {
 		return 0
@@ -364,6 +546,18 @@ func getint64(i *int64) int64 {
 	return *i
 }
 
+// bypassGovernance returns true if the request asks to bypass governance
+// mode object lock retention and the requester is explicitly allowed to
+// do so, the bucket ACL grants never allow the bypass
+func (c S3ApiController) bypassGovernance(ctx *fiber.Ctx, opts auth.AccessOptions) bool {
+	if !strings.EqualFold(ctx.Get("X-Amz-Bypass-Governance-Retention"), "true") {
+		return false
+	}
+
+	opts.Action = auth.BypassGovernanceRetentionAction
+	return auth.VerifyExplicitAccess(ctx.Context(), c.be, opts) == nil
+}
+
 func (c S3ApiController) ListActions(ctx *fiber.Ctx) error {
 	bucket := ctx.Params("bucket")
 	prefix := ctx.Query("prefix")
@@ -460,6 +654,114 @@ func (c S3ApiController) ListActions(ctx *fiber.Ctx) error {
 			})
 	}
 
+	if ctx.Request().URI().QueryArgs().Has("object-lock") {
+		err := auth.VerifyAccess(ctx.Context(), c.be, auth.AccessOptions{
+			Acl:           parsedAcl,
+			AclPermission: types.PermissionRead,
+			IsRoot:        isRoot,
+			Acc:           acct,
+			Bucket:        bucket,
+			Action:        auth.GetObjectLockConfigurationAction,
+		})
+		if err != nil {
+			return SendXMLResponse(ctx, nil, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "GetObjectLockConfiguration",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		data, err := c.be.GetObjectLockConfiguration(ctx.Context(), bucket)
+		return SendXMLResponse(ctx, data, err,
+			&MetaOpts{
+				Logger:      c.logger,
+				Action:      "GetObjectLockConfiguration",
+				BucketOwner: parsedAcl.Owner,
+			})
+	}
+
+	if ctx.Request().URI().QueryArgs().Has("lifecycle") {
+		err := auth.VerifyAccess(ctx.Context(), c.be, auth.AccessOptions{
+			Acl:           parsedAcl,
+			AclPermission: types.PermissionRead,
+			IsRoot:        isRoot,
+			Acc:           acct,
+			Bucket:        bucket,
+			Action:        auth.GetLifecycleConfigurationAction,
+		})
+		if err != nil {
+			return SendXMLResponse(ctx, nil, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "GetBucketLifecycleConfiguration",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		data, err := c.be.GetBucketLifecycleConfiguration(ctx.Context(), bucket)
+		return SendXMLResponse(ctx, data, err,
+			&MetaOpts{
+				Logger:      c.logger,
+				Action:      "GetBucketLifecycleConfiguration",
+				BucketOwner: parsedAcl.Owner,
+			})
+	}
+
+	if ctx.Request().URI().QueryArgs().Has("cors") {
+		err := auth.VerifyAccess(ctx.Context(), c.be, auth.AccessOptions{
+			Acl:           parsedAcl,
+			AclPermission: types.PermissionRead,
+			IsRoot:        isRoot,
+			Acc:           acct,
+			Bucket:        bucket,
+			Action:        auth.GetBucketCorsAction,
+		})
+		if err != nil {
+			return SendXMLResponse(ctx, nil, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "GetBucketCors",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		data, err := c.be.GetBucketCors(ctx.Context(), bucket)
+		return SendXMLResponse(ctx, data, err,
+			&MetaOpts{
+				Logger:      c.logger,
+				Action:      "GetBucketCors",
+				BucketOwner: parsedAcl.Owner,
+			})
+	}
+
+	if ctx.Request().URI().QueryArgs().Has("website") {
+		err := auth.VerifyAccess(ctx.Context(), c.be, auth.AccessOptions{
+			Acl:           parsedAcl,
+			AclPermission: types.PermissionRead,
+			IsRoot:        isRoot,
+			Acc:           acct,
+			Bucket:        bucket,
+			Action:        auth.GetBucketWebsiteAction,
+		})
+		if err != nil {
+			return SendXMLResponse(ctx, nil, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "GetBucketWebsite",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		data, err := c.be.GetBucketWebsite(ctx.Context(), bucket)
+		return SendXMLResponse(ctx, data, err,
+			&MetaOpts{
+				Logger:      c.logger,
+				Action:      "GetBucketWebsite",
+				BucketOwner: parsedAcl.Owner,
+			})
+	}
+
 	if ctx.Request().URI().QueryArgs().Has("policy") {
 		err := auth.VerifyAccess(ctx.Context(), c.be, auth.AccessOptions{
 			Acl:           parsedAcl,
@@ -705,6 +1007,7 @@ func (c S3ApiController) PutBucketActions(ctx *fiber.Ctx) error {
 	grantWriteACP := ctx.Get("X-Amz-Grant-Write-Acp")
 	mfa := ctx.Get("X-Amz-Mfa")
 	contentMD5 := ctx.Get("Content-MD5")
+	objectLockEnabled := strings.EqualFold(ctx.Get("X-Amz-Bucket-Object-Lock-Enabled"), "true")
 	acct := ctx.Locals("account").(auth.Account)
 	isRoot := ctx.Locals("isRoot").(bool)
 
@@ -806,6 +1109,164 @@ func (c S3ApiController) PutBucketActions(ctx *fiber.Ctx) error {
 			})
 	}
 
+	if ctx.Request().URI().QueryArgs().Has("object-lock") {
+		parsedAcl := ctx.Locals("parsedAcl").(auth.ACL)
+		err := auth.VerifyAccess(ctx.Context(), c.be, auth.AccessOptions{
+			Acl:           parsedAcl,
+			AclPermission: types.PermissionWrite,
+			IsRoot:        isRoot,
+			Acc:           acct,
+			Bucket:        bucket,
+			Action:        auth.PutObjectLockConfigurationAction,
+		})
+		if err != nil {
+			return SendResponse(ctx, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "PutObjectLockConfiguration",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		var lockConfig types.ObjectLockConfiguration
+		err = xml.Unmarshal(ctx.Body(), &lockConfig)
+		if err != nil {
+			return SendResponse(ctx, s3err.GetAPIError(s3err.ErrMalformedXML),
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "PutObjectLockConfiguration",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		err = c.be.PutObjectLockConfiguration(ctx.Context(), &s3.PutObjectLockConfigurationInput{
+			Bucket:                  &bucket,
+			ObjectLockConfiguration: &lockConfig,
+			ContentMD5:              &contentMD5,
+		})
+		return SendResponse(ctx, err,
+			&MetaOpts{
+				Logger:      c.logger,
+				Action:      "PutObjectLockConfiguration",
+				BucketOwner: parsedAcl.Owner,
+			})
+	}
+
+	if ctx.Request().URI().QueryArgs().Has("lifecycle") {
+		parsedAcl := ctx.Locals("parsedAcl").(auth.ACL)
+		err := auth.VerifyAccess(ctx.Context(), c.be, auth.AccessOptions{
+			Acl:           parsedAcl,
+			AclPermission: types.PermissionWrite,
+			IsRoot:        isRoot,
+			Acc:           acct,
+			Bucket:        bucket,
+			Action:        auth.PutLifecycleConfigurationAction,
+		})
+		if err != nil {
+			return SendResponse(ctx, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "PutBucketLifecycleConfiguration",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		var config s3response.LifecycleConfiguration
+		err = xml.Unmarshal(ctx.Body(), &config)
+		if err != nil {
+			return SendResponse(ctx, s3err.GetAPIError(s3err.ErrMalformedXML),
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "PutBucketLifecycleConfiguration",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		err = c.be.PutBucketLifecycleConfiguration(ctx.Context(), bucket, config)
+		return SendResponse(ctx, err,
+			&MetaOpts{
+				Logger:      c.logger,
+				Action:      "PutBucketLifecycleConfiguration",
+				BucketOwner: parsedAcl.Owner,
+			})
+	}
+
+	if ctx.Request().URI().QueryArgs().Has("website") {
+		parsedAcl := ctx.Locals("parsedAcl").(auth.ACL)
+		err := auth.VerifyAccess(ctx.Context(), c.be, auth.AccessOptions{
+			Acl:           parsedAcl,
+			AclPermission: types.PermissionWrite,
+			IsRoot:        isRoot,
+			Acc:           acct,
+			Bucket:        bucket,
+			Action:        auth.PutBucketWebsiteAction,
+		})
+		if err != nil {
+			return SendResponse(ctx, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "PutBucketWebsite",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		_, err = utils.ParseWebsiteConfiguration(ctx.Body())
+		if err != nil {
+			return SendResponse(ctx, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "PutBucketWebsite",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		err = c.be.PutBucketWebsite(ctx.Context(), bucket, ctx.Body())
+		return SendResponse(ctx, err,
+			&MetaOpts{
+				Logger:      c.logger,
+				Action:      "PutBucketWebsite",
+				BucketOwner: parsedAcl.Owner,
+			})
+	}
+
+	if ctx.Request().URI().QueryArgs().Has("cors") {
+		parsedAcl := ctx.Locals("parsedAcl").(auth.ACL)
+		err := auth.VerifyAccess(ctx.Context(), c.be, auth.AccessOptions{
+			Acl:           parsedAcl,
+			AclPermission: types.PermissionWrite,
+			IsRoot:        isRoot,
+			Acc:           acct,
+			Bucket:        bucket,
+			Action:        auth.PutBucketCorsAction,
+		})
+		if err != nil {
+			return SendResponse(ctx, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "PutBucketCors",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		_, err = utils.ParseCORSConfiguration(ctx.Body())
+		if err != nil {
+			return SendResponse(ctx, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "PutBucketCors",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		err = c.be.PutBucketCors(ctx.Context(), bucket, ctx.Body())
+		return SendResponse(ctx, err,
+			&MetaOpts{
+				Logger:      c.logger,
+				Action:      "PutBucketCors",
+				BucketOwner: parsedAcl.Owner,
+			})
+	}
+
 	if ctx.Request().URI().QueryArgs().Has("policy") {
 		parsedAcl := ctx.Locals("parsedAcl").(auth.ACL)
 		err := auth.VerifyAccess(ctx.Context(), c.be, auth.AccessOptions{
@@ -1003,8 +1464,9 @@ func (c S3ApiController) PutBucketActions(ctx *fiber.Ctx) error {
 	}
 
 	err = c.be.CreateBucket(ctx.Context(), &s3.CreateBucketInput{
-		Bucket:          &bucket,
-		ObjectOwnership: types.ObjectOwnership(acct.Access),
+		Bucket:                     &bucket,
+		ObjectOwnership:            types.ObjectOwnership(acct.Access),
+		ObjectLockEnabledForBucket: &objectLockEnabled,
 	}, updAcl)
 	return SendResponse(ctx, err,
 		&MetaOpts{
@@ -1019,6 +1481,7 @@ func (c S3ApiController) PutActions(ctx *fiber.Ctx) error {
 	keyStart := ctx.Params("key")
 	keyEnd := ctx.Params("*1")
 	uploadId := ctx.Query("uploadId")
+	versionId := ctx.Query("versionId")
 	acct := ctx.Locals("account").(auth.Account)
 	isRoot := ctx.Locals("isRoot").(bool)
 	parsedAcl := ctx.Locals("parsedAcl").(auth.ACL)
@@ -1045,8 +1508,34 @@ func (c S3ApiController) PutActions(ctx *fiber.Ctx) error {
 	if contentLengthStr == "" {
 		contentLengthStr = "0"
 	}
+	// for chunked uploads the content length includes the chunk
+	// metadata, the object size is the decoded content length
+	if decodedLength := ctx.Get("X-Amz-Decoded-Content-Length"); decodedLength != "" {
+		contentLengthStr = decodedLength
+	}
 	bucketOwner := ctx.Get("X-Amz-Expected-Bucket-Owner")
 
+	// Object lock headers
+	objLockMode := ctx.Get("X-Amz-Object-Lock-Mode")
+	objLockRetainUntil := ctx.Get("X-Amz-Object-Lock-Retain-Until-Date")
+	objLockLegalHold := ctx.Get("X-Amz-Object-Lock-Legal-Hold")
+
+	// Server side encryption headers
+	sseAlg := types.ServerSideEncryption(ctx.Get("X-Amz-Server-Side-Encryption"))
+	sseCAlg := getHeaderPtr(ctx, "X-Amz-Server-Side-Encryption-Customer-Algorithm")
+	sseCKey := getHeaderPtr(ctx, "X-Amz-Server-Side-Encryption-Customer-Key")
+	sseCKeyMD5 := getHeaderPtr(ctx, "X-Amz-Server-Side-Encryption-Customer-Key-Md5")
+	copySrcSSECAlg := getHeaderPtr(ctx, "X-Amz-Copy-Source-Server-Side-Encryption-Customer-Algorithm")
+	copySrcSSECKey := getHeaderPtr(ctx, "X-Amz-Copy-Source-Server-Side-Encryption-Customer-Key")
+	copySrcSSECKeyMD5 := getHeaderPtr(ctx, "X-Amz-Copy-Source-Server-Side-Encryption-Customer-Key-Md5")
+
+	// Additional checksum headers
+	checksumAlg := checksumAlgorithm(ctx)
+	checksumCRC32 := getHeaderPtr(ctx, "X-Amz-Checksum-Crc32")
+	checksumCRC32C := getHeaderPtr(ctx, "X-Amz-Checksum-Crc32c")
+	checksumSHA1 := getHeaderPtr(ctx, "X-Amz-Checksum-Sha1")
+	checksumSHA256 := getHeaderPtr(ctx, "X-Amz-Checksum-Sha256")
+
 	grants := grantFullControl + grantRead + grantReadACP + granWrite + grantWriteACP
 
 	if keyEnd != "" {
@@ -1083,32 +1572,123 @@ func (c S3ApiController) PutActions(ctx *fiber.Ctx) error {
 			tags[tag.Key] = tag.Value
 		}
 
-		err = auth.VerifyAccess(ctx.Context(), c.be, auth.AccessOptions{
+		err = auth.VerifyAccess(ctx.Context(), c.be, auth.AccessOptions{
+			Acl:           parsedAcl,
+			AclPermission: types.PermissionWrite,
+			IsRoot:        isRoot,
+			Acc:           acct,
+			Bucket:        bucket,
+			Object:        keyStart,
+			Action:        auth.PutBucketTaggingAction,
+		})
+		if err != nil {
+			return SendResponse(ctx, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "PutObjectTagging",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		err = c.be.PutObjectTagging(ctx.Context(), bucket, keyStart, tags)
+		return SendResponse(ctx, err, &MetaOpts{
+			Logger:      c.logger,
+			EvSender:    c.evSender,
+			Action:      "PutObjectTagging",
+			BucketOwner: parsedAcl.Owner,
+			EventName:   s3event.EventObjectTaggingPut,
+		})
+	}
+
+	if ctx.Request().URI().QueryArgs().Has("retention") {
+		opts := auth.AccessOptions{
+			Acl:           parsedAcl,
+			AclPermission: types.PermissionWrite,
+			IsRoot:        isRoot,
+			Acc:           acct,
+			Bucket:        bucket,
+			Object:        keyStart,
+			Action:        auth.PutObjectRetentionAction,
+		}
+		err := auth.VerifyAccess(ctx.Context(), c.be, opts)
+		if err != nil {
+			return SendResponse(ctx, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "PutObjectRetention",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		var retention types.ObjectLockRetention
+		err = xml.Unmarshal(ctx.Body(), &retention)
+		if err != nil {
+			return SendResponse(ctx, s3err.GetAPIError(s3err.ErrMalformedXML),
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "PutObjectRetention",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		bypass := c.bypassGovernance(ctx, opts)
+		err = c.be.PutObjectRetention(ctx.Context(), &s3.PutObjectRetentionInput{
+			Bucket:                    &bucket,
+			Key:                       &keyStart,
+			VersionId:                 &versionId,
+			Retention:                 &retention,
+			BypassGovernanceRetention: &bypass,
+		})
+		return SendResponse(ctx, err,
+			&MetaOpts{
+				Logger:      c.logger,
+				Action:      "PutObjectRetention",
+				BucketOwner: parsedAcl.Owner,
+			})
+	}
+
+	if ctx.Request().URI().QueryArgs().Has("legal-hold") {
+		err := auth.VerifyAccess(ctx.Context(), c.be, auth.AccessOptions{
 			Acl:           parsedAcl,
 			AclPermission: types.PermissionWrite,
 			IsRoot:        isRoot,
 			Acc:           acct,
 			Bucket:        bucket,
 			Object:        keyStart,
-			Action:        auth.PutBucketTaggingAction,
+			Action:        auth.PutObjectLegalHoldAction,
 		})
 		if err != nil {
 			return SendResponse(ctx, err,
 				&MetaOpts{
 					Logger:      c.logger,
-					Action:      "PutObjectTagging",
+					Action:      "PutObjectLegalHold",
 					BucketOwner: parsedAcl.Owner,
 				})
 		}
 
-		err = c.be.PutObjectTagging(ctx.Context(), bucket, keyStart, tags)
-		return SendResponse(ctx, err, &MetaOpts{
-			Logger:      c.logger,
-			EvSender:    c.evSender,
-			Action:      "PutObjectTagging",
-			BucketOwner: parsedAcl.Owner,
-			EventName:   s3event.EventObjectTaggingPut,
+		var legalHold types.ObjectLockLegalHold
+		err = xml.Unmarshal(ctx.Body(), &legalHold)
+		if err != nil {
+			return SendResponse(ctx, s3err.GetAPIError(s3err.ErrMalformedXML),
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "PutObjectLegalHold",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		err = c.be.PutObjectLegalHold(ctx.Context(), &s3.PutObjectLegalHoldInput{
+			Bucket:    &bucket,
+			Key:       &keyStart,
+			VersionId: &versionId,
+			LegalHold: &legalHold,
 		})
+		return SendResponse(ctx, err,
+			&MetaOpts{
+				Logger:      c.logger,
+				Action:      "PutObjectLegalHold",
+				BucketOwner: parsedAcl.Owner,
+			})
 	}
 
 	if ctx.Request().URI().QueryArgs().Has("uploadId") &&
@@ -1144,14 +1724,23 @@ func (c S3ApiController) PutActions(ctx *fiber.Ctx) error {
 		}
 
 		resp, err := c.be.UploadPartCopy(ctx.Context(), &s3.UploadPartCopyInput{
-			Bucket:              &bucket,
-			Key:                 &keyStart,
-			CopySource:          &copySource,
-			PartNumber:          &partNumber,
-			UploadId:            &uploadId,
-			ExpectedBucketOwner: &bucketOwner,
-			CopySourceRange:     &copySrcRange,
+			Bucket:                         &bucket,
+			Key:                            &keyStart,
+			CopySource:                     &copySource,
+			PartNumber:                     &partNumber,
+			UploadId:                       &uploadId,
+			ExpectedBucketOwner:            &bucketOwner,
+			CopySourceRange:                &copySrcRange,
+			SSECustomerAlgorithm:           sseCAlg,
+			SSECustomerKey:                 sseCKey,
+			SSECustomerKeyMD5:              sseCKeyMD5,
+			CopySourceSSECustomerAlgorithm: copySrcSSECAlg,
+			CopySourceSSECustomerKey:       copySrcSSECKey,
+			CopySourceSSECustomerKeyMD5:    copySrcSSECKeyMD5,
 		})
+		if err == nil {
+			setSSEHeaders(ctx, "", sseCAlg, sseCKeyMD5)
+		}
 		return SendXMLResponse(ctx, resp, err,
 			&MetaOpts{
 				Logger:      c.logger,
@@ -1209,16 +1798,29 @@ func (c S3ApiController) PutActions(ctx *fiber.Ctx) error {
 		}
 
 		ctx.Locals("logReqBody", false)
-		etag, err := c.be.UploadPart(ctx.Context(),
+		res, err := c.be.UploadPart(ctx.Context(),
 			&s3.UploadPartInput{
-				Bucket:        &bucket,
-				Key:           &keyStart,
-				UploadId:      &uploadId,
-				PartNumber:    &partNumber,
-				ContentLength: &contentLength,
-				Body:          body,
-			})
-		ctx.Response().Header.Set("Etag", etag)
+				Bucket:               &bucket,
+				Key:                  &keyStart,
+				UploadId:             &uploadId,
+				PartNumber:           &partNumber,
+				ContentLength:        &contentLength,
+				Body:                 body,
+				SSECustomerAlgorithm: sseCAlg,
+				SSECustomerKey:       sseCKey,
+				SSECustomerKeyMD5:    sseCKeyMD5,
+				ChecksumAlgorithm:    checksumAlg,
+				ChecksumCRC32:        checksumCRC32,
+				ChecksumCRC32C:       checksumCRC32C,
+				ChecksumSHA1:         checksumSHA1,
+				ChecksumSHA256:       checksumSHA256,
+			})
+		if err == nil {
+			ctx.Response().Header.Set("Etag", getstring(res.ETag))
+			setSSEHeaders(ctx, "", sseCAlg, sseCKeyMD5)
+			setChecksumHeaders(ctx, res.ChecksumCRC32, res.ChecksumCRC32C,
+				res.ChecksumSHA1, res.ChecksumSHA256)
+		}
 		return SendResponse(ctx, err,
 			&MetaOpts{
 				Logger:      c.logger,
@@ -1378,8 +1980,23 @@ func (c S3ApiController) PutActions(ctx *fiber.Ctx) error {
 			CopySourceIfUnmodifiedSince: umtime,
 			ExpectedBucketOwner:         &acct.Access,
 			Metadata:                    metadata,
+
+			ServerSideEncryption:           sseAlg,
+			SSECustomerAlgorithm:           sseCAlg,
+			SSECustomerKey:                 sseCKey,
+			SSECustomerKeyMD5:              sseCKeyMD5,
+			CopySourceSSECustomerAlgorithm: copySrcSSECAlg,
+			CopySourceSSECustomerKey:       copySrcSSECKey,
+			CopySourceSSECustomerKeyMD5:    copySrcSSECKeyMD5,
 		})
 		if err == nil {
+			if getstring(res.VersionId) != "" {
+				ctx.Response().Header.Set("x-amz-version-id", *res.VersionId)
+			}
+			if getstring(res.CopySourceVersionId) != "" {
+				ctx.Response().Header.Set("x-amz-copy-source-version-id", *res.CopySourceVersionId)
+			}
+			setSSEHeaders(ctx, res.ServerSideEncryption, res.SSECustomerAlgorithm, res.SSECustomerKeyMD5)
 			return SendXMLResponse(ctx, res, err, &MetaOpts{
 				Logger:      c.logger,
 				EvSender:    c.evSender,
@@ -1428,6 +2045,20 @@ func (c S3ApiController) PutActions(ctx *fiber.Ctx) error {
 			})
 	}
 
+	var retainUntil *time.Time
+	if objLockRetainUntil != "" {
+		t, err := time.Parse(time.RFC3339, objLockRetainUntil)
+		if err != nil {
+			return SendResponse(ctx, s3err.GetAPIError(s3err.ErrInvalidRequest),
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "PutObject",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+		retainUntil = &t
+	}
+
 	var body io.Reader
 	bodyi := ctx.Locals("body-reader")
 	if bodyi != nil {
@@ -1437,21 +2068,51 @@ func (c S3ApiController) PutActions(ctx *fiber.Ctx) error {
 	}
 
 	ctx.Locals("logReqBody", false)
-	etag, err := c.be.PutObject(ctx.Context(), &s3.PutObjectInput{
+	res, err := c.be.PutObject(ctx.Context(), &s3.PutObjectInput{
 		Bucket:        &bucket,
 		Key:           &keyStart,
 		ContentLength: &contentLength,
 		Metadata:      metadata,
 		Body:          body,
 		Tagging:       &tagging,
+
+		ObjectLockMode:            types.ObjectLockMode(objLockMode),
+		ObjectLockRetainUntilDate: retainUntil,
+		ObjectLockLegalHoldStatus: types.ObjectLockLegalHoldStatus(objLockLegalHold),
+
+		ServerSideEncryption: sseAlg,
+		SSECustomerAlgorithm: sseCAlg,
+		SSECustomerKey:       sseCKey,
+		SSECustomerKeyMD5:    sseCKeyMD5,
+
+		ChecksumAlgorithm: checksumAlg,
+		ChecksumCRC32:     checksumCRC32,
+		ChecksumCRC32C:    checksumCRC32C,
+		ChecksumSHA1:      checksumSHA1,
+		ChecksumSHA256:    checksumSHA256,
 	})
-	ctx.Response().Header.Set("ETag", etag)
+	if err != nil {
+		return SendResponse(ctx, err,
+			&MetaOpts{
+				Logger:      c.logger,
+				Action:      "PutObject",
+				BucketOwner: parsedAcl.Owner,
+			})
+	}
+
+	ctx.Response().Header.Set("ETag", getstring(res.ETag))
+	if getstring(res.VersionId) != "" {
+		ctx.Response().Header.Set("x-amz-version-id", *res.VersionId)
+	}
+	setSSEHeaders(ctx, res.ServerSideEncryption, res.SSECustomerAlgorithm, res.SSECustomerKeyMD5)
+	setChecksumHeaders(ctx, res.ChecksumCRC32, res.ChecksumCRC32C, res.ChecksumSHA1, res.ChecksumSHA256)
 	return SendResponse(ctx, err, &MetaOpts{
 		Logger:      c.logger,
 		EvSender:    c.evSender,
 		Action:      "PutObject",
 		BucketOwner: parsedAcl.Owner,
-		ObjectETag:  &etag,
+		ObjectETag:  res.ETag,
+		VersionId:   res.VersionId,
 		ObjectSize:  contentLength,
 		EventName:   s3event.EventObjectPut,
 	})
@@ -1491,6 +2152,90 @@ func (c S3ApiController) DeleteBucket(ctx *fiber.Ctx) error {
 			})
 	}
 
+	if ctx.Request().URI().QueryArgs().Has("lifecycle") {
+		err := auth.VerifyAccess(ctx.Context(), c.be, auth.AccessOptions{
+			Acl:           parsedAcl,
+			AclPermission: types.PermissionWrite,
+			IsRoot:        isRoot,
+			Acc:           acct,
+			Bucket:        bucket,
+			Action:        auth.PutLifecycleConfigurationAction,
+		})
+		if err != nil {
+			return SendResponse(ctx, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "DeleteBucketLifecycle",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		err = c.be.DeleteBucketLifecycle(ctx.Context(), bucket)
+		return SendResponse(ctx, err,
+			&MetaOpts{
+				Logger:      c.logger,
+				Action:      "DeleteBucketLifecycle",
+				BucketOwner: parsedAcl.Owner,
+				Status:      http.StatusNoContent,
+			})
+	}
+
+	if ctx.Request().URI().QueryArgs().Has("website") {
+		err := auth.VerifyAccess(ctx.Context(), c.be, auth.AccessOptions{
+			Acl:           parsedAcl,
+			AclPermission: types.PermissionWrite,
+			IsRoot:        isRoot,
+			Acc:           acct,
+			Bucket:        bucket,
+			Action:        auth.DeleteBucketWebsiteAction,
+		})
+		if err != nil {
+			return SendResponse(ctx, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "DeleteBucketWebsite",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		err = c.be.DeleteBucketWebsite(ctx.Context(), bucket)
+		return SendResponse(ctx, err,
+			&MetaOpts{
+				Logger:      c.logger,
+				Action:      "DeleteBucketWebsite",
+				BucketOwner: parsedAcl.Owner,
+				Status:      http.StatusNoContent,
+			})
+	}
+
+	if ctx.Request().URI().QueryArgs().Has("cors") {
+		err := auth.VerifyAccess(ctx.Context(), c.be, auth.AccessOptions{
+			Acl:           parsedAcl,
+			AclPermission: types.PermissionWrite,
+			IsRoot:        isRoot,
+			Acc:           acct,
+			Bucket:        bucket,
+			Action:        auth.PutBucketCorsAction,
+		})
+		if err != nil {
+			return SendResponse(ctx, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "DeleteBucketCors",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+
+		err = c.be.DeleteBucketCors(ctx.Context(), bucket)
+		return SendResponse(ctx, err,
+			&MetaOpts{
+				Logger:      c.logger,
+				Action:      "DeleteBucketCors",
+				BucketOwner: parsedAcl.Owner,
+				Status:      http.StatusNoContent,
+			})
+	}
+
 	if ctx.Request().URI().QueryArgs().Has("policy") {
 		err := auth.VerifyAccess(ctx.Context(), c.be, auth.AccessOptions{
 			Acl:           parsedAcl,
@@ -1565,14 +2310,15 @@ func (c S3ApiController) DeleteObjects(ctx *fiber.Ctx) error {
 			})
 	}
 
-	err = auth.VerifyAccess(ctx.Context(), c.be, auth.AccessOptions{
+	opts := auth.AccessOptions{
 		Acl:           parsedAcl,
 		AclPermission: types.PermissionWrite,
 		IsRoot:        isRoot,
 		Acc:           acct,
 		Bucket:        bucket,
 		Action:        auth.DeleteObjectAction,
-	})
+	}
+	err = auth.VerifyAccess(ctx.Context(), c.be, opts)
 	if err != nil {
 		return SendResponse(ctx, err,
 			&MetaOpts{
@@ -1582,12 +2328,14 @@ func (c S3ApiController) DeleteObjects(ctx *fiber.Ctx) error {
 			})
 	}
 
+	bypass := c.bypassGovernance(ctx, opts)
 	res, err := c.be.DeleteObjects(ctx.Context(),
 		&s3.DeleteObjectsInput{
 			Bucket: &bucket,
 			Delete: &types.Delete{
 				Objects: dObj.Objects,
 			},
+			BypassGovernanceRetention: &bypass,
 		})
 	return SendXMLResponse(ctx, res, err,
 		&MetaOpts{
@@ -1597,6 +2345,192 @@ func (c S3ApiController) DeleteObjects(ctx *fiber.Ctx) error {
 		})
 }
 
+func (c S3ApiController) PostBucketActions(ctx *fiber.Ctx) error {
+	if _, ok := ctx.Locals("post-form").(*utils.PostForm); ok {
+		return c.PostObject(ctx)
+	}
+	return c.DeleteObjects(ctx)
+}
+
+// PostObject handles browser based uploads, the multipart/form-data
+// body is parsed and the signature verified by the VerifyPostPolicy
+// middleware
+func (c S3ApiController) PostObject(ctx *fiber.Ctx) error {
+	bucket := ctx.Params("bucket")
+	acct := ctx.Locals("account").(auth.Account)
+	isRoot := ctx.Locals("isRoot").(bool)
+	parsedAcl := ctx.Locals("parsedAcl").(auth.ACL)
+	form := ctx.Locals("post-form").(*utils.PostForm)
+	key := form.Fields["key"]
+
+	if key == "" {
+		return SendResponse(ctx, utils.PostFieldRequired("key"),
+			&MetaOpts{
+				Logger:      c.logger,
+				Action:      "PostObject",
+				BucketOwner: parsedAcl.Owner,
+			})
+	}
+
+	if form.Fields["policy"] != "" {
+		policy, err := utils.ParsePostPolicy(form.Fields["policy"])
+		if err == nil {
+			err = policy.Check(form, bucket, time.Now())
+		}
+		if err != nil {
+			return SendResponse(ctx, err,
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "PostObject",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+	}
+
+	err := auth.VerifyAccess(ctx.Context(), c.be, auth.AccessOptions{
+		Acl:           parsedAcl,
+		AclPermission: types.PermissionWrite,
+		IsRoot:        isRoot,
+		Acc:           acct,
+		Bucket:        bucket,
+		Object:        key,
+		Action:        auth.PutObjectAction,
+	})
+	if err != nil {
+		return SendResponse(ctx, err,
+			&MetaOpts{
+				Logger:      c.logger,
+				Action:      "PostObject",
+				BucketOwner: parsedAcl.Owner,
+			})
+	}
+
+	// the tagging field is a Tagging XML document
+	var tagging string
+	if form.Fields["tagging"] != "" {
+		var objTagging s3response.TaggingInput
+		err := xml.Unmarshal([]byte(form.Fields["tagging"]), &objTagging)
+		if err != nil {
+			return SendResponse(ctx, s3err.GetAPIError(s3err.ErrMalformedXML),
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "PostObject",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+		tags := make(url.Values)
+		for _, tag := range objTagging.TagSet.Tags {
+			tags.Set(tag.Key, tag.Value)
+		}
+		tagging = tags.Encode()
+	}
+
+	metadata := make(map[string]string)
+	for name, value := range form.Fields {
+		if strings.HasPrefix(name, "x-amz-meta-") {
+			metadata[name[len("x-amz-meta-"):]] = value
+		}
+	}
+
+	body, err := form.Open()
+	if err != nil {
+		return SendResponse(ctx, err,
+			&MetaOpts{
+				Logger:      c.logger,
+				Action:      "PostObject",
+				BucketOwner: parsedAcl.Owner,
+			})
+	}
+	defer body.Close()
+
+	ctx.Locals("logReqBody", false)
+	res, err := c.be.PutObject(ctx.Context(), &s3.PutObjectInput{
+		Bucket:             &bucket,
+		Key:                &key,
+		ContentLength:      &form.Size,
+		ContentType:        formFieldPtr(form, "content-type"),
+		CacheControl:       formFieldPtr(form, "cache-control"),
+		ContentDisposition: formFieldPtr(form, "content-disposition"),
+		ContentEncoding:    formFieldPtr(form, "content-encoding"),
+		Metadata:           metadata,
+		Body:               body,
+		Tagging:            &tagging,
+
+		ServerSideEncryption: types.ServerSideEncryption(form.Fields["x-amz-server-side-encryption"]),
+		SSECustomerAlgorithm: formFieldPtr(form, "x-amz-server-side-encryption-customer-algorithm"),
+		SSECustomerKey:       formFieldPtr(form, "x-amz-server-side-encryption-customer-key"),
+		SSECustomerKeyMD5:    formFieldPtr(form, "x-amz-server-side-encryption-customer-key-md5"),
+	})
+	if err != nil {
+		return SendResponse(ctx, err,
+			&MetaOpts{
+				Logger:      c.logger,
+				Action:      "PostObject",
+				BucketOwner: parsedAcl.Owner,
+			})
+	}
+
+	etag := getstring(res.ETag)
+	ctx.Response().Header.Set("ETag", etag)
+	if getstring(res.VersionId) != "" {
+		ctx.Response().Header.Set("x-amz-version-id", *res.VersionId)
+	}
+	setSSEHeaders(ctx, res.ServerSideEncryption, res.SSECustomerAlgorithm, res.SSECustomerKeyMD5)
+
+	meta := &MetaOpts{
+		Logger:      c.logger,
+		EvSender:    c.evSender,
+		Action:      "PostObject",
+		BucketOwner: parsedAcl.Owner,
+		ObjectETag:  res.ETag,
+		VersionId:   res.VersionId,
+		ObjectSize:  form.Size,
+		EventName:   s3event.EventObjectPost,
+		Status:      http.StatusNoContent,
+	}
+
+	redirect := form.Fields["success_action_redirect"]
+	if redirect == "" {
+		redirect = form.Fields["redirect"]
+	}
+	if redirect != "" {
+		// an invalid redirect url falls back to success_action_status
+		u, err := url.Parse(redirect)
+		if err == nil && u.IsAbs() {
+			query := u.Query()
+			query.Set("bucket", bucket)
+			query.Set("key", key)
+			query.Set("etag", etag)
+			u.RawQuery = query.Encode()
+			ctx.Location(u.String())
+			meta.Status = http.StatusSeeOther
+			return SendResponse(ctx, nil, meta)
+		}
+	}
+
+	switch form.Fields["success_action_status"] {
+	case "200":
+		meta.Status = http.StatusOK
+	case "201":
+		ctx.Status(http.StatusCreated)
+		return SendXMLResponse(ctx, s3response.PostResponse{
+			Location: fmt.Sprintf("%v/%v/%v", ctx.BaseURL(), bucket, url.PathEscape(key)),
+			Bucket:   bucket,
+			Key:      key,
+			ETag:     etag,
+		}, nil, meta)
+	}
+	return SendResponse(ctx, nil, meta)
+}
+
+func formFieldPtr(form *utils.PostForm, name string) *string {
+	v, ok := form.Fields[name]
+	if !ok {
+		return nil
+	}
+	return &v
+}
+
 func (c S3ApiController) DeleteActions(ctx *fiber.Ctx) error {
 	bucket := ctx.Params("bucket")
 	key := ctx.Params("key")
@@ -1680,7 +2614,7 @@ func (c S3ApiController) DeleteActions(ctx *fiber.Ctx) error {
 			})
 	}
 
-	err := auth.VerifyAccess(ctx.Context(), c.be, auth.AccessOptions{
+	opts := auth.AccessOptions{
 		Acl:           parsedAcl,
 		AclPermission: types.PermissionWrite,
 		IsRoot:        isRoot,
@@ -1688,7 +2622,8 @@ func (c S3ApiController) DeleteActions(ctx *fiber.Ctx) error {
 		Bucket:        bucket,
 		Object:        key,
 		Action:        auth.DeleteObjectAction,
-	})
+	}
+	err := auth.VerifyAccess(ctx.Context(), c.be, opts)
 	if err != nil {
 		return SendResponse(ctx, err,
 			&MetaOpts{
@@ -1698,12 +2633,22 @@ func (c S3ApiController) DeleteActions(ctx *fiber.Ctx) error {
 			})
 	}
 
-	err = c.be.DeleteObject(ctx.Context(),
+	bypass := c.bypassGovernance(ctx, opts)
+	res, err := c.be.DeleteObject(ctx.Context(),
 		&s3.DeleteObjectInput{
-			Bucket:    &bucket,
-			Key:       &key,
-			VersionId: &versionId,
+			Bucket:                    &bucket,
+			Key:                       &key,
+			VersionId:                 &versionId,
+			BypassGovernanceRetention: &bypass,
 		})
+	if err == nil && res != nil {
+		if res.DeleteMarker != nil && *res.DeleteMarker {
+			ctx.Response().Header.Set("x-amz-delete-marker", "true")
+		}
+		if getstring(res.VersionId) != "" {
+			ctx.Response().Header.Set("x-amz-version-id", *res.VersionId)
+		}
+	}
 	return SendResponse(ctx, err,
 		&MetaOpts{
 			Logger:      c.logger,
@@ -1761,6 +2706,7 @@ func (c S3ApiController) HeadObject(ctx *fiber.Ctx) error {
 	parsedAcl := ctx.Locals("parsedAcl").(auth.ACL)
 	key := ctx.Params("key")
 	keyEnd := ctx.Params("*1")
+	versionId := ctx.Query("versionId")
 	if keyEnd != "" {
 		key = strings.Join([]string{key, keyEnd}, "/")
 	}
@@ -1785,8 +2731,13 @@ func (c S3ApiController) HeadObject(ctx *fiber.Ctx) error {
 
 	res, err := c.be.HeadObject(ctx.Context(),
 		&s3.HeadObjectInput{
-			Bucket: &bucket,
-			Key:    &key,
+			Bucket:               &bucket,
+			Key:                  &key,
+			VersionId:            &versionId,
+			SSECustomerAlgorithm: getHeaderPtr(ctx, "X-Amz-Server-Side-Encryption-Customer-Algorithm"),
+			SSECustomerKey:       getHeaderPtr(ctx, "X-Amz-Server-Side-Encryption-Customer-Key"),
+			SSECustomerKeyMD5:    getHeaderPtr(ctx, "X-Amz-Server-Side-Encryption-Customer-Key-Md5"),
+			ChecksumMode:         types.ChecksumMode(strings.ToUpper(ctx.Get("X-Amz-Checksum-Mode"))),
 		})
 	if err != nil {
 		return SendResponse(ctx, err,
@@ -1840,6 +2791,36 @@ func (c S3ApiController) HeadObject(ctx *fiber.Ctx) error {
 			Value: getstring(res.Restore),
 		},
 	})
+	if getstring(res.VersionId) != "" {
+		utils.SetResponseHeaders(ctx, []utils.CustomHeader{
+			{
+				Key:   "x-amz-version-id",
+				Value: *res.VersionId,
+			},
+		})
+	}
+	if res.ObjectLockMode != "" && res.ObjectLockRetainUntilDate != nil {
+		utils.SetResponseHeaders(ctx, []utils.CustomHeader{
+			{
+				Key:   "x-amz-object-lock-mode",
+				Value: string(res.ObjectLockMode),
+			},
+			{
+				Key:   "x-amz-object-lock-retain-until-date",
+				Value: res.ObjectLockRetainUntilDate.Format(time.RFC3339),
+			},
+		})
+	}
+	if res.ObjectLockLegalHoldStatus != "" {
+		utils.SetResponseHeaders(ctx, []utils.CustomHeader{
+			{
+				Key:   "x-amz-object-lock-legal-hold",
+				Value: string(res.ObjectLockLegalHoldStatus),
+			},
+		})
+	}
+	setSSEHeaders(ctx, res.ServerSideEncryption, res.SSECustomerAlgorithm, res.SSECustomerKeyMD5)
+	setChecksumHeaders(ctx, res.ChecksumCRC32, res.ChecksumCRC32C, res.ChecksumSHA1, res.ChecksumSHA256)
 
 	return SendResponse(ctx, nil,
 		&MetaOpts{
@@ -1945,14 +2926,17 @@ func (c S3ApiController) CreateActions(ctx *fiber.Ctx) error {
 
 		sw := c.be.SelectObjectContent(ctx.Context(),
 			&s3.SelectObjectContentInput{
-				Bucket:              &bucket,
-				Key:                 &key,
-				Expression:          payload.Expression,
-				ExpressionType:      payload.ExpressionType,
-				InputSerialization:  payload.InputSerialization,
-				OutputSerialization: payload.OutputSerialization,
-				RequestProgress:     payload.RequestProgress,
-				ScanRange:           payload.ScanRange,
+				Bucket:               &bucket,
+				Key:                  &key,
+				Expression:           payload.Expression,
+				ExpressionType:       payload.ExpressionType,
+				InputSerialization:   payload.InputSerialization,
+				OutputSerialization:  payload.OutputSerialization,
+				RequestProgress:      payload.RequestProgress,
+				ScanRange:            payload.ScanRange,
+				SSECustomerAlgorithm: getHeaderPtr(ctx, "X-Amz-Server-Side-Encryption-Customer-Algorithm"),
+				SSECustomerKey:       getHeaderPtr(ctx, "X-Amz-Server-Side-Encryption-Customer-Key"),
+				SSECustomerKeyMD5:    getHeaderPtr(ctx, "X-Amz-Server-Side-Encryption-Customer-Key-Md5"),
 			})
 
 		ctx.Context().SetBodyStreamWriter(sw)
@@ -2003,6 +2987,10 @@ func (c S3ApiController) CreateActions(ctx *fiber.Ctx) error {
 				},
 			})
 		if err == nil {
+			if getstring(res.VersionId) != "" {
+				ctx.Response().Header.Set("x-amz-version-id", *res.VersionId)
+			}
+			setSSEHeaders(ctx, res.ServerSideEncryption, nil, nil)
 			return SendXMLResponse(ctx, res, err,
 				&MetaOpts{
 					Logger:      c.logger,
@@ -2040,8 +3028,40 @@ func (c S3ApiController) CreateActions(ctx *fiber.Ctx) error {
 			})
 	}
 
+	var retainUntil *time.Time
+	if objLockRetainUntil := ctx.Get("X-Amz-Object-Lock-Retain-Until-Date"); objLockRetainUntil != "" {
+		t, err := time.Parse(time.RFC3339, objLockRetainUntil)
+		if err != nil {
+			return SendXMLResponse(ctx, nil, s3err.GetAPIError(s3err.ErrInvalidRequest),
+				&MetaOpts{
+					Logger:      c.logger,
+					Action:      "CreateMultipartUpload",
+					BucketOwner: parsedAcl.Owner,
+				})
+		}
+		retainUntil = &t
+	}
+
 	res, err := c.be.CreateMultipartUpload(ctx.Context(),
-		&s3.CreateMultipartUploadInput{Bucket: &bucket, Key: &key})
+		&s3.CreateMultipartUploadInput{
+			Bucket:               &bucket,
+			Key:                  &key,
+			ServerSideEncryption: types.ServerSideEncryption(ctx.Get("X-Amz-Server-Side-Encryption")),
+			SSECustomerAlgorithm: getHeaderPtr(ctx, "X-Amz-Server-Side-Encryption-Customer-Algorithm"),
+			SSECustomerKey:       getHeaderPtr(ctx, "X-Amz-Server-Side-Encryption-Customer-Key"),
+			SSECustomerKeyMD5:    getHeaderPtr(ctx, "X-Amz-Server-Side-Encryption-Customer-Key-Md5"),
+			ChecksumAlgorithm:    types.ChecksumAlgorithm(strings.ToUpper(ctx.Get("X-Amz-Checksum-Algorithm"))),
+
+			ObjectLockMode:            types.ObjectLockMode(ctx.Get("X-Amz-Object-Lock-Mode")),
+			ObjectLockRetainUntilDate: retainUntil,
+			ObjectLockLegalHoldStatus: types.ObjectLockLegalHoldStatus(ctx.Get("X-Amz-Object-Lock-Legal-Hold")),
+		})
+	if err == nil {
+		setSSEHeaders(ctx, res.ServerSideEncryption, res.SSECustomerAlgorithm, res.SSECustomerKeyMD5)
+		if res.ChecksumAlgorithm != "" {
+			ctx.Response().Header.Set("x-amz-checksum-algorithm", string(res.ChecksumAlgorithm))
+		}
+	}
 	return SendXMLResponse(ctx, res, err,
 		&MetaOpts{
 			Logger:      c.logger,
@@ -2063,6 +3083,7 @@ type MetaOpts struct {
 }
 
 func SendResponse(ctx *fiber.Ctx, err error, l *MetaOpts) error {
+	ctx.Locals("action", l.Action)
 	if l.Logger != nil {
 		l.Logger.Log(ctx, err, nil, s3log.LogMeta{
 			Action:      l.Action,
@@ -2112,6 +3133,7 @@ const (
 )
 
 func SendXMLResponse(ctx *fiber.Ctx, resp any, err error, l *MetaOpts) error {
+	ctx.Locals("action", l.Action)
 	if err != nil {
 		if l.Logger != nil {
 			l.Logger.Log(ctx, err, nil, s3log.LogMeta{
@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
@@ -33,6 +34,7 @@ import (
 	"github.com/valyala/fasthttp"
 	"github.com/versity/versitygw/auth"
 	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/s3api/utils"
 	"github.com/versity/versitygw/s3err"
 	"github.com/versity/versitygw/s3response"
 )
@@ -204,6 +206,12 @@ func TestS3ApiController_GetActions(t *testing.T) {
 			GetObjectTaggingFunc: func(_ context.Context, bucket, object string) (map[string]string, error) {
 				return map[string]string{"hello": "world"}, nil
 			},
+			GetObjectRetentionFunc: func(contextMoqParam context.Context, getObjectRetentionInput *s3.GetObjectRetentionInput) (*types.ObjectLockRetention, error) {
+				return &types.ObjectLockRetention{}, nil
+			},
+			GetObjectLegalHoldFunc: func(contextMoqParam context.Context, getObjectLegalHoldInput *s3.GetObjectLegalHoldInput) (*types.ObjectLockLegalHold, error) {
+				return &types.ObjectLockLegalHold{}, nil
+			},
 		},
 	}
 	app.Use(func(ctx *fiber.Ctx) error {
@@ -235,6 +243,24 @@ func TestS3ApiController_GetActions(t *testing.T) {
 			wantErr:    false,
 			statusCode: 200,
 		},
+		{
+			name: "Get-actions-get-object-retention-success",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodGet, "/my-bucket/my-key?retention", nil),
+			},
+			wantErr:    false,
+			statusCode: 200,
+		},
+		{
+			name: "Get-actions-get-object-legal-hold-success",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodGet, "/my-bucket/my-key?legal-hold", nil),
+			},
+			wantErr:    false,
+			statusCode: 200,
+		},
 		{
 			name: "Get-actions-invalid-max-parts-string",
 			app:  app,
@@ -349,12 +375,24 @@ func TestS3ApiController_ListActions(t *testing.T) {
 			GetBucketVersioningFunc: func(contextMoqParam context.Context, bucket string) (*s3.GetBucketVersioningOutput, error) {
 				return &s3.GetBucketVersioningOutput{}, nil
 			},
-			ListObjectVersionsFunc: func(contextMoqParam context.Context, listObjectVersionsInput *s3.ListObjectVersionsInput) (*s3.ListObjectVersionsOutput, error) {
-				return &s3.ListObjectVersionsOutput{}, nil
+			ListObjectVersionsFunc: func(contextMoqParam context.Context, listObjectVersionsInput *s3.ListObjectVersionsInput) (s3response.ListVersionsResult, error) {
+				return s3response.ListVersionsResult{}, nil
 			},
 			GetBucketPolicyFunc: func(contextMoqParam context.Context, bucket string) ([]byte, error) {
 				return []byte{}, nil
 			},
+			GetObjectLockConfigurationFunc: func(contextMoqParam context.Context, bucket string) (*types.ObjectLockConfiguration, error) {
+				return &types.ObjectLockConfiguration{}, nil
+			},
+			GetBucketLifecycleConfigurationFunc: func(contextMoqParam context.Context, bucket string) (s3response.LifecycleConfiguration, error) {
+				return s3response.LifecycleConfiguration{}, nil
+			},
+			GetBucketCorsFunc: func(contextMoqParam context.Context, bucket string) ([]byte, error) {
+				return []byte{}, nil
+			},
+			GetBucketWebsiteFunc: func(contextMoqParam context.Context, bucket string) ([]byte, error) {
+				return []byte{}, nil
+			},
 		},
 	}
 
@@ -480,6 +518,42 @@ func TestS3ApiController_ListActions(t *testing.T) {
 			wantErr:    false,
 			statusCode: 200,
 		},
+		{
+			name: "List-actions-get-object-lock-configuration-success",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodGet, "/my-bucket?object-lock", nil),
+			},
+			wantErr:    false,
+			statusCode: 200,
+		},
+		{
+			name: "List-actions-get-bucket-lifecycle-success",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodGet, "/my-bucket?lifecycle", nil),
+			},
+			wantErr:    false,
+			statusCode: 200,
+		},
+		{
+			name: "List-actions-get-bucket-cors-success",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodGet, "/my-bucket?cors", nil),
+			},
+			wantErr:    false,
+			statusCode: 200,
+		},
+		{
+			name: "List-actions-get-bucket-website-success",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodGet, "/my-bucket?website", nil),
+			},
+			wantErr:    false,
+			statusCode: 200,
+		},
 		{
 			name: "List-actions-list-object-versions-success",
 			app:  app,
@@ -570,6 +644,48 @@ func TestS3ApiController_PutBucketActions(t *testing.T) {
 	</VersioningConfiguration>
 	`
 
+	objectLockBody := `
+	<ObjectLockConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+		<ObjectLockEnabled>Enabled</ObjectLockEnabled>
+	</ObjectLockConfiguration>
+	`
+
+	lifecycleBody := `
+	<LifecycleConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+		<Rule>
+			<ID>expire-logs</ID>
+			<Filter>
+				<Prefix>logs/</Prefix>
+			</Filter>
+			<Status>Enabled</Status>
+			<Expiration>
+				<Days>30</Days>
+			</Expiration>
+		</Rule>
+	</LifecycleConfiguration>
+	`
+
+	corsBody := `
+	<CORSConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+		<CORSRule>
+			<AllowedOrigin>https://www.example.com</AllowedOrigin>
+			<AllowedMethod>PUT</AllowedMethod>
+			<AllowedHeader>*</AllowedHeader>
+		</CORSRule>
+	</CORSConfiguration>
+	`
+
+	websiteBody := `
+	<WebsiteConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+		<IndexDocument>
+			<Suffix>index.html</Suffix>
+		</IndexDocument>
+		<ErrorDocument>
+			<Key>error.html</Key>
+		</ErrorDocument>
+	</WebsiteConfiguration>
+	`
+
 	policyBody := `
 	{
 		"Statement": [
@@ -597,6 +713,18 @@ func TestS3ApiController_PutBucketActions(t *testing.T) {
 			PutBucketTaggingFunc: func(contextMoqParam context.Context, bucket string, tags map[string]string) error {
 				return nil
 			},
+			PutObjectLockConfigurationFunc: func(contextMoqParam context.Context, putObjectLockConfigurationInput *s3.PutObjectLockConfigurationInput) error {
+				return nil
+			},
+			PutBucketLifecycleConfigurationFunc: func(contextMoqParam context.Context, bucket string, config s3response.LifecycleConfiguration) error {
+				return nil
+			},
+			PutBucketCorsFunc: func(contextMoqParam context.Context, bucket string, cors []byte) error {
+				return nil
+			},
+			PutBucketWebsiteFunc: func(contextMoqParam context.Context, bucket string, website []byte) error {
+				return nil
+			},
 			PutBucketVersioningFunc: func(contextMoqParam context.Context, putBucketVersioningInput *s3.PutBucketVersioningInput) error {
 				return nil
 			},
@@ -679,6 +807,78 @@ func TestS3ApiController_PutBucketActions(t *testing.T) {
 			wantErr:    false,
 			statusCode: 200,
 		},
+		{
+			name: "Put-bucket-object-lock-invalid-body",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPut, "/my-bucket?object-lock", nil),
+			},
+			wantErr:    false,
+			statusCode: 400,
+		},
+		{
+			name: "Put-bucket-object-lock-success",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPut, "/my-bucket?object-lock", strings.NewReader(objectLockBody)),
+			},
+			wantErr:    false,
+			statusCode: 200,
+		},
+		{
+			name: "Put-bucket-lifecycle-invalid-body",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPut, "/my-bucket?lifecycle", nil),
+			},
+			wantErr:    false,
+			statusCode: 400,
+		},
+		{
+			name: "Put-bucket-lifecycle-success",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPut, "/my-bucket?lifecycle", strings.NewReader(lifecycleBody)),
+			},
+			wantErr:    false,
+			statusCode: 200,
+		},
+		{
+			name: "Put-bucket-cors-invalid-body",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPut, "/my-bucket?cors", nil),
+			},
+			wantErr:    false,
+			statusCode: 400,
+		},
+		{
+			name: "Put-bucket-cors-success",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPut, "/my-bucket?cors", strings.NewReader(corsBody)),
+			},
+			wantErr:    false,
+			statusCode: 200,
+		},
+		{
+			name: "Put-bucket-website-invalid-body",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPut, "/my-bucket?website", nil),
+			},
+			wantErr:    false,
+			statusCode: 400,
+		},
+		{
+			name: "Put-bucket-website-success",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPut, "/my-bucket?website", strings.NewReader(websiteBody)),
+			},
+			wantErr:    false,
+			statusCode: 200,
+		},
 		{
 			name: "Put-bucket-policy-invalid-body",
 			app:  app,
@@ -804,6 +1004,17 @@ func TestS3ApiController_PutActions(t *testing.T) {
 		</TagSet>
 	</Tagging>
 	`
+	retentionBody := `
+	<Retention xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+		<Mode>GOVERNANCE</Mode>
+		<RetainUntilDate>2030-01-01T00:00:00Z</RetainUntilDate>
+	</Retention>
+	`
+	legalHoldBody := `
+	<LegalHold xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+		<Status>ON</Status>
+	</LegalHold>
+	`
 
 	app := fiber.New()
 	s3ApiController := S3ApiController{
@@ -819,15 +1030,22 @@ func TestS3ApiController_PutActions(t *testing.T) {
 					CopyObjectResult: &types.CopyObjectResult{},
 				}, nil
 			},
-			PutObjectFunc: func(context.Context, *s3.PutObjectInput) (string, error) {
-				return "ETag", nil
+			PutObjectFunc: func(context.Context, *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+				return &s3.PutObjectOutput{}, nil
 			},
-			UploadPartFunc: func(context.Context, *s3.UploadPartInput) (string, error) {
-				return "hello", nil
+			UploadPartFunc: func(context.Context, *s3.UploadPartInput) (*s3.UploadPartOutput, error) {
+				etag := "hello"
+				return &s3.UploadPartOutput{ETag: &etag}, nil
 			},
 			PutObjectTaggingFunc: func(_ context.Context, bucket, object string, tags map[string]string) error {
 				return nil
 			},
+			PutObjectRetentionFunc: func(contextMoqParam context.Context, putObjectRetentionInput *s3.PutObjectRetentionInput) error {
+				return nil
+			},
+			PutObjectLegalHoldFunc: func(contextMoqParam context.Context, putObjectLegalHoldInput *s3.PutObjectLegalHoldInput) error {
+				return nil
+			},
 			UploadPartCopyFunc: func(context.Context, *s3.UploadPartCopyInput) (s3response.CopyObjectResult, error) {
 				return s3response.CopyObjectResult{}, nil
 			},
@@ -909,6 +1127,33 @@ func TestS3ApiController_PutActions(t *testing.T) {
 			wantErr:    false,
 			statusCode: 200,
 		},
+		{
+			name: "Put-object-retention-invalid-body",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPut, "/my-bucket/my-key?retention", nil),
+			},
+			wantErr:    false,
+			statusCode: 400,
+		},
+		{
+			name: "Put-object-retention-success",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPut, "/my-bucket/my-key?retention", strings.NewReader(retentionBody)),
+			},
+			wantErr:    false,
+			statusCode: 200,
+		},
+		{
+			name: "Put-object-legal-hold-success",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodPut, "/my-bucket/my-key?legal-hold", strings.NewReader(legalHoldBody)),
+			},
+			wantErr:    false,
+			statusCode: 200,
+		},
 		{
 			name: "Put-object-acl-invalid-acl",
 			app:  app,
@@ -1029,6 +1274,15 @@ func TestS3ApiController_DeleteBucket(t *testing.T) {
 			DeleteBucketTaggingFunc: func(contextMoqParam context.Context, bucket string) error {
 				return nil
 			},
+			DeleteBucketLifecycleFunc: func(contextMoqParam context.Context, bucket string) error {
+				return nil
+			},
+			DeleteBucketCorsFunc: func(contextMoqParam context.Context, bucket string) error {
+				return nil
+			},
+			DeleteBucketWebsiteFunc: func(contextMoqParam context.Context, bucket string) error {
+				return nil
+			},
 		},
 	}
 
@@ -1067,6 +1321,33 @@ func TestS3ApiController_DeleteBucket(t *testing.T) {
 			wantErr:    false,
 			statusCode: 204,
 		},
+		{
+			name: "Delete-bucket-lifecycle-success",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodDelete, "/my-bucket?lifecycle", nil),
+			},
+			wantErr:    false,
+			statusCode: 204,
+		},
+		{
+			name: "Delete-bucket-cors-success",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodDelete, "/my-bucket?cors", nil),
+			},
+			wantErr:    false,
+			statusCode: 204,
+		},
+		{
+			name: "Delete-bucket-website-success",
+			app:  app,
+			args: args{
+				req: httptest.NewRequest(http.MethodDelete, "/my-bucket?website", nil),
+			},
+			wantErr:    false,
+			statusCode: 204,
+		},
 	}
 	for _, tt := range tests {
 		resp, err := tt.app.Test(tt.args.req)
@@ -1152,6 +1433,109 @@ func TestS3ApiController_DeleteObjects(t *testing.T) {
 	}
 }
 
+func TestS3ApiController_PostObject(t *testing.T) {
+	type args struct {
+		fields map[string]string
+	}
+
+	s3ApiController := S3ApiController{
+		be: &BackendMock{
+			GetBucketAclFunc: func(context.Context, *s3.GetBucketAclInput) ([]byte, error) {
+				return acldata, nil
+			},
+			PutObjectFunc: func(context.Context, *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+				etag := "\"etag\""
+				return &s3.PutObjectOutput{ETag: &etag}, nil
+			},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		args       args
+		wantErr    bool
+		statusCode int
+	}{
+		{
+			name: "Post-Object-missing-key",
+			args: args{
+				fields: map[string]string{},
+			},
+			wantErr:    false,
+			statusCode: 400,
+		},
+		{
+			name: "Post-Object-success",
+			args: args{
+				fields: map[string]string{"key": "my-obj"},
+			},
+			wantErr:    false,
+			statusCode: 204,
+		},
+		{
+			name: "Post-Object-success-status",
+			args: args{
+				fields: map[string]string{"key": "my-obj", "success_action_status": "201"},
+			},
+			wantErr:    false,
+			statusCode: 201,
+		},
+		{
+			name: "Post-Object-success-redirect",
+			args: args{
+				fields: map[string]string{"key": "my-obj", "success_action_redirect": "https://example.com/done"},
+			},
+			wantErr:    false,
+			statusCode: 303,
+		},
+		{
+			name: "Post-Object-invalid-policy",
+			args: args{
+				fields: map[string]string{"key": "my-obj", "policy": "invalid"},
+			},
+			wantErr:    false,
+			statusCode: 400,
+		},
+	}
+	for _, tt := range tests {
+		var body strings.Builder
+		mw := multipart.NewWriter(&body)
+		for name, value := range tt.args.fields {
+			mw.WriteField(name, value)
+		}
+		fw, _ := mw.CreateFormFile("file", "data.txt")
+		io.WriteString(fw, "data")
+		mw.Close()
+
+		form, err := utils.ParsePostForm(strings.NewReader(body.String()), mw.Boundary())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		app := fiber.New()
+		app.Use(func(ctx *fiber.Ctx) error {
+			ctx.Locals("account", auth.Account{Access: "valid access"})
+			ctx.Locals("isRoot", true)
+			ctx.Locals("isDebug", false)
+			ctx.Locals("parsedAcl", auth.ACL{})
+			ctx.Locals("post-form", form)
+			return ctx.Next()
+		})
+		app.Post("/:bucket", s3ApiController.PostBucketActions)
+
+		resp, err := app.Test(httptest.NewRequest(http.MethodPost, "/my-bucket", nil))
+		form.Close()
+
+		if (err != nil) != tt.wantErr {
+			t.Errorf("S3ApiController.PostObject() %v error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+
+		if resp.StatusCode != tt.statusCode {
+			t.Errorf("S3ApiController.PostObject() %v statusCode = %v, wantStatusCode = %v", tt.name, resp.StatusCode, tt.statusCode)
+		}
+	}
+}
+
 func TestS3ApiController_DeleteActions(t *testing.T) {
 	type args struct {
 		req *http.Request
@@ -1163,8 +1547,8 @@ func TestS3ApiController_DeleteActions(t *testing.T) {
 			GetBucketAclFunc: func(context.Context, *s3.GetBucketAclInput) ([]byte, error) {
 				return acldata, nil
 			},
-			DeleteObjectFunc: func(context.Context, *s3.DeleteObjectInput) error {
-				return nil
+			DeleteObjectFunc: func(context.Context, *s3.DeleteObjectInput) (*s3.DeleteObjectOutput, error) {
+				return &s3.DeleteObjectOutput{}, nil
 			},
 			AbortMultipartUploadFunc: func(context.Context, *s3.AbortMultipartUploadInput) error {
 				return nil
@@ -1191,8 +1575,8 @@ func TestS3ApiController_DeleteActions(t *testing.T) {
 		GetBucketAclFunc: func(context.Context, *s3.GetBucketAclInput) ([]byte, error) {
 			return acldata, nil
 		},
-		DeleteObjectFunc: func(context.Context, *s3.DeleteObjectInput) error {
-			return s3err.GetAPIError(7)
+		DeleteObjectFunc: func(context.Context, *s3.DeleteObjectInput) (*s3.DeleteObjectOutput, error) {
+			return nil, s3err.GetAPIError(7)
 		},
 	}}
 
@@ -1364,20 +1748,25 @@ func TestS3ApiController_HeadObject(t *testing.T) {
 	eTag := "Valid etag"
 	lastModifie := time.Now()
 	contentLength := int64(64)
+	checksum := "uU0nuZNNPgilLlLX2n2r+sSE7+N6U4DukIj3rOLvzek="
 
 	s3ApiController := S3ApiController{
 		be: &BackendMock{
 			GetBucketAclFunc: func(context.Context, *s3.GetBucketAclInput) ([]byte, error) {
 				return acldata, nil
 			},
-			HeadObjectFunc: func(context.Context, *s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
-				return &s3.HeadObjectOutput{
+			HeadObjectFunc: func(_ context.Context, input *s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+				out := &s3.HeadObjectOutput{
 					ContentEncoding: &contentEncoding,
 					ContentLength:   &contentLength,
 					ContentType:     &contentType,
 					LastModified:    &lastModifie,
 					ETag:            &eTag,
-				}, nil
+				}
+				if input.ChecksumMode == types.ChecksumModeEnabled {
+					out.ChecksumSHA256 = &checksum
+				}
+				return out, nil
 			},
 		},
 	}
@@ -1420,6 +1809,7 @@ func TestS3ApiController_HeadObject(t *testing.T) {
 		args       args
 		wantErr    bool
 		statusCode int
+		checksum   string
 	}{
 		{
 			name: "Head-object-success",
@@ -1430,6 +1820,20 @@ func TestS3ApiController_HeadObject(t *testing.T) {
 			wantErr:    false,
 			statusCode: 200,
 		},
+		{
+			name: "Head-object-checksum-mode",
+			app:  app,
+			args: args{
+				req: func() *http.Request {
+					req := httptest.NewRequest(http.MethodHead, "/my-bucket/my-key", nil)
+					req.Header.Set("X-Amz-Checksum-Mode", "ENABLED")
+					return req
+				}(),
+			},
+			wantErr:    false,
+			statusCode: 200,
+			checksum:   checksum,
+		},
 		{
 			name: "Head-object-error",
 			app:  appErr,
@@ -1450,6 +1854,10 @@ func TestS3ApiController_HeadObject(t *testing.T) {
 		if resp.StatusCode != tt.statusCode {
 			t.Errorf("S3ApiController.HeadObject() statusCode = %v, wantStatusCode = %v", resp.StatusCode, tt.statusCode)
 		}
+
+		if got := resp.Header.Get("X-Amz-Checksum-Sha256"); got != tt.checksum {
+			t.Errorf("S3ApiController.HeadObject() checksum = %v, wantChecksum = %v", got, tt.checksum)
+		}
 	}
 }
 
@@ -30,6 +30,9 @@ var _ auth.IAMService = &IAMServiceMock{}
 //			ListUserAccountsFunc: func() ([]auth.Account, error) {
 //				panic("mock out the ListUserAccounts method")
 //			},
+//			PutUserPolicyFunc: func(access string, policy string) error {
+//				panic("mock out the PutUserPolicy method")
+//			},
 //			ShutdownFunc: func() error {
 //				panic("mock out the Shutdown method")
 //			},
@@ -52,6 +55,9 @@ type IAMServiceMock struct {
 	// ListUserAccountsFunc mocks the ListUserAccounts method.
 	ListUserAccountsFunc func() ([]auth.Account, error)
 
+	// PutUserPolicyFunc mocks the PutUserPolicy method.
+	PutUserPolicyFunc func(access string, policy string) error
+
 	// ShutdownFunc mocks the Shutdown method.
 	ShutdownFunc func() error
 
@@ -75,6 +81,13 @@ type IAMServiceMock struct {
 		// ListUserAccounts holds details about calls to the ListUserAccounts method.
 		ListUserAccounts []struct {
 		}
+		// PutUserPolicy holds details about calls to the PutUserPolicy method.
+		PutUserPolicy []struct {
+			// Access is the access argument value.
+			Access string
+			// Policy is the policy argument value.
+			Policy string
+		}
 		// Shutdown holds details about calls to the Shutdown method.
 		Shutdown []struct {
 		}
@@ -83,6 +96,7 @@ type IAMServiceMock struct {
 	lockDeleteUserAccount sync.RWMutex
 	lockGetUserAccount    sync.RWMutex
 	lockListUserAccounts  sync.RWMutex
+	lockPutUserPolicy     sync.RWMutex
 	lockShutdown          sync.RWMutex
 }
 
@@ -209,6 +223,42 @@ func (mock *IAMServiceMock) ListUserAccountsCalls() []struct {
 	return calls
 }
 
+// PutUserPolicy calls PutUserPolicyFunc.
+func (mock *IAMServiceMock) PutUserPolicy(access string, policy string) error {
+	if mock.PutUserPolicyFunc == nil {
+		panic("IAMServiceMock.PutUserPolicyFunc: method is nil but IAMService.PutUserPolicy was just called")
+	}
+	callInfo := struct {
+		Access string
+		Policy string
+	}{
+		Access: access,
+		Policy: policy,
+	}
+	mock.lockPutUserPolicy.Lock()
+	mock.calls.PutUserPolicy = append(mock.calls.PutUserPolicy, callInfo)
+	mock.lockPutUserPolicy.Unlock()
+	return mock.PutUserPolicyFunc(access, policy)
+}
+
+// PutUserPolicyCalls gets all the calls that were made to PutUserPolicy.
+// Check the length with:
+//
+//	len(mockedIAMService.PutUserPolicyCalls())
+func (mock *IAMServiceMock) PutUserPolicyCalls() []struct {
+	Access string
+	Policy string
+} {
+	var calls []struct {
+		Access string
+		Policy string
+	}
+	mock.lockPutUserPolicy.RLock()
+	calls = mock.calls.PutUserPolicy
+	mock.lockPutUserPolicy.RUnlock()
+	return calls
+}
+
 // Shutdown calls ShutdownFunc.
 func (mock *IAMServiceMock) Shutdown() error {
 	if mock.ShutdownFunc == nil {
@@ -0,0 +1,346 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package controllers
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/versity/versitygw/auth"
+	"github.com/versity/versitygw/s3err"
+	"github.com/versity/versitygw/s3log"
+	"github.com/versity/versitygw/s3response"
+)
+
+const (
+	stsVersion = "2011-06-15"
+
+	minSessionDuration          = 15 * time.Minute
+	defaultAssumeRoleDuration   = time.Hour
+	maxAssumeRoleDuration       = 12 * time.Hour
+	defaultSessionTokenDuration = 12 * time.Hour
+	maxSessionTokenDuration     = 36 * time.Hour
+
+	maxSessionPolicySize = 2048
+)
+
+var (
+	roleSessionNameRegexp = regexp.MustCompile(`^[\w+=,.@-]{2,64}$`)
+)
+
+// STSController implements the AssumeRole, AssumeRoleWithWebIdentity and
+// GetSessionToken actions of the security token service query API. The
+// gateway has no IAM roles, the temporary credentials are issued for the
+// requester account or the account of the web identity, optionally
+// limited by a session policy.
+type STSController struct {
+	sts    *auth.STS
+	iam    auth.IAMService
+	oidc   *auth.OIDC
+	logger s3log.AuditLogger
+}
+
+func NewSTSController(sts *auth.STS, iam auth.IAMService, oidc *auth.OIDC, logger s3log.AuditLogger) STSController {
+	return STSController{sts: sts, iam: iam, oidc: oidc, logger: logger}
+}
+
+func (c STSController) HandleAction(ctx *fiber.Ctx) error {
+	action := ctx.FormValue("Action")
+	switch action {
+	case "AssumeRole", "GetSessionToken":
+	case "AssumeRoleWithWebIdentity":
+		// the web identity token authenticates the request
+		return c.assumeRoleWithWebIdentity(ctx)
+	default:
+		return c.sendError(ctx, action, s3err.APIError{
+			Code:           "InvalidAction",
+			Description:    fmt.Sprintf("Could not find operation %v for version %v", action, stsVersion),
+			HTTPStatusCode: http.StatusBadRequest,
+		})
+	}
+
+	acct := ctx.Locals("account").(auth.Account)
+	if acct.Role == auth.RoleAnonymous {
+		return c.sendError(ctx, action, s3err.APIError{
+			Code:           "MissingAuthenticationToken",
+			Description:    "Request is missing Authentication Token",
+			HTTPStatusCode: http.StatusForbidden,
+		})
+	}
+	// the sessions are only issued with long-term credentials, the
+	// session token may be in the header or the presigned url query
+	if acct.Session {
+		return c.sendError(ctx, action, s3err.APIError{
+			Code:           "AccessDenied",
+			Description:    fmt.Sprintf("Cannot call %v with session credentials", action),
+			HTTPStatusCode: http.StatusForbidden,
+		})
+	}
+
+	if action == "AssumeRole" {
+		return c.assumeRole(ctx, acct)
+	}
+	return c.getSessionToken(ctx, acct)
+}
+
+func (c STSController) assumeRole(ctx *fiber.Ctx, acct auth.Account) error {
+	action := "AssumeRole"
+	roleArn := ctx.FormValue("RoleArn")
+	sessionName := ctx.FormValue("RoleSessionName")
+	policy := ctx.FormValue("Policy")
+
+	if !strings.HasPrefix(roleArn, "arn:") {
+		return c.sendError(ctx, action, getSTSValidationError(roleArn, "roleArn", "Member must be a valid ARN"))
+	}
+	if !roleSessionNameRegexp.MatchString(sessionName) {
+		return c.sendError(ctx, action, getSTSValidationError(sessionName, "roleSessionName", `Member must satisfy regular expression pattern: [\w+=,.@-]{2,64}`))
+	}
+	duration, err := parseSessionDuration(ctx.FormValue("DurationSeconds"), defaultAssumeRoleDuration, maxAssumeRoleDuration)
+	if err != nil {
+		return c.sendError(ctx, action, err)
+	}
+
+	if err := validateSessionPolicy(policy); err != nil {
+		return c.sendError(ctx, action, err)
+	}
+
+	creds, err := c.sts.NewSession(acct.Access, "", duration, policy)
+	if err != nil {
+		return c.sendError(ctx, action, err)
+	}
+
+	return c.sendResponse(ctx, action, s3response.AssumeRoleResponse{
+		AssumeRoleResult: s3response.AssumeRoleResult{
+			Credentials:     getSTSCredentials(creds),
+			AssumedRoleUser: getAssumedRoleUser(roleArn, sessionName, creds),
+		},
+		ResponseMetadata: s3response.STSResponseMetadata{RequestId: uuid.NewString()},
+	})
+}
+
+func (c STSController) assumeRoleWithWebIdentity(ctx *fiber.Ctx) error {
+	action := "AssumeRoleWithWebIdentity"
+	roleArn := ctx.FormValue("RoleArn")
+	sessionName := ctx.FormValue("RoleSessionName")
+	token := ctx.FormValue("WebIdentityToken")
+	policy := ctx.FormValue("Policy")
+
+	if !strings.HasPrefix(roleArn, "arn:") {
+		return c.sendError(ctx, action, getSTSValidationError(roleArn, "roleArn", "Member must be a valid ARN"))
+	}
+	if !roleSessionNameRegexp.MatchString(sessionName) {
+		return c.sendError(ctx, action, getSTSValidationError(sessionName, "roleSessionName", `Member must satisfy regular expression pattern: [\w+=,.@-]{2,64}`))
+	}
+	if len(token) < 4 {
+		return c.sendError(ctx, action, getSTSValidationError(token, "webIdentityToken", "Member must have length greater than or equal to 4"))
+	}
+	duration, err := parseSessionDuration(ctx.FormValue("DurationSeconds"), defaultAssumeRoleDuration, maxAssumeRoleDuration)
+	if err != nil {
+		return c.sendError(ctx, action, err)
+	}
+	if err := validateSessionPolicy(policy); err != nil {
+		return c.sendError(ctx, action, err)
+	}
+
+	if c.oidc == nil {
+		return c.sendError(ctx, action, s3err.APIError{
+			Code:           "InvalidIdentityToken",
+			Description:    "No OpenIDConnect provider found in your account",
+			HTTPStatusCode: http.StatusBadRequest,
+		})
+	}
+
+	identity, err := c.oidc.VerifyToken(token)
+	if errors.Is(err, auth.ErrOIDCTokenExpired) {
+		return c.sendError(ctx, action, s3err.APIError{
+			Code:           "ExpiredTokenException",
+			Description:    "Token expired",
+			HTTPStatusCode: http.StatusBadRequest,
+		})
+	}
+	if err != nil {
+		return c.sendError(ctx, action, s3err.APIError{
+			Code:           "InvalidIdentityToken",
+			Description:    fmt.Sprintf("Couldn't verify the web identity token: %v", err),
+			HTTPStatusCode: http.StatusBadRequest,
+		})
+	}
+
+	// the identities with a gateway account assume the account, the
+	// others are granted the role of their role claim
+	role := identity.Role
+	_, err = c.iam.GetUserAccount(identity.Account)
+	if err == nil {
+		role = ""
+	} else if !errors.Is(err, auth.ErrNoSuchUser) {
+		return c.sendError(ctx, action, err)
+	}
+
+	creds, err := c.sts.NewSession(identity.Account, role, duration, policy)
+	if err != nil {
+		return c.sendError(ctx, action, err)
+	}
+
+	return c.sendResponse(ctx, action, s3response.AssumeRoleWithWebIdentityResponse{
+		AssumeRoleWithWebIdentityResult: s3response.AssumeRoleWithWebIdentityResult{
+			Credentials:                 getSTSCredentials(creds),
+			SubjectFromWebIdentityToken: identity.Subject,
+			AssumedRoleUser:             getAssumedRoleUser(roleArn, sessionName, creds),
+			Provider:                    c.oidc.Issuer(),
+			Audience:                    identity.Audience,
+		},
+		ResponseMetadata: s3response.STSResponseMetadata{RequestId: uuid.NewString()},
+	})
+}
+
+func (c STSController) getSessionToken(ctx *fiber.Ctx, acct auth.Account) error {
+	action := "GetSessionToken"
+	duration, err := parseSessionDuration(ctx.FormValue("DurationSeconds"), defaultSessionTokenDuration, maxSessionTokenDuration)
+	if err != nil {
+		return c.sendError(ctx, action, err)
+	}
+
+	creds, err := c.sts.NewSession(acct.Access, "", duration, "")
+	if err != nil {
+		return c.sendError(ctx, action, err)
+	}
+
+	return c.sendResponse(ctx, action, s3response.GetSessionTokenResponse{
+		GetSessionTokenResult: s3response.GetSessionTokenResult{
+			Credentials: getSTSCredentials(creds),
+		},
+		ResponseMetadata: s3response.STSResponseMetadata{RequestId: uuid.NewString()},
+	})
+}
+
+func (c STSController) sendResponse(ctx *fiber.Ctx, action string, resp any) error {
+	return SendXMLResponse(ctx, resp, nil, &MetaOpts{Logger: c.logger, Action: action})
+}
+
+// sendError sends the errors in the security token service error
+// response format
+func (c STSController) sendError(ctx *fiber.Ctx, action string, err error) error {
+	if c.logger != nil {
+		c.logger.Log(ctx, err, nil, s3log.LogMeta{Action: action})
+	}
+
+	var apierr s3err.APIError
+	if !errors.As(err, &apierr) {
+		log.Printf("Internal Error, %v", err)
+		apierr = s3err.GetAPIError(s3err.ErrInternalError)
+	}
+
+	errType := "Sender"
+	if apierr.HTTPStatusCode >= http.StatusInternalServerError {
+		errType = "Receiver"
+	}
+	b, err := xml.Marshal(s3response.STSErrorResponse{
+		Error: s3response.STSError{
+			Type:    errType,
+			Code:    apierr.Code,
+			Message: apierr.Description,
+		},
+		RequestId: uuid.NewString(),
+	})
+	if err != nil {
+		return err
+	}
+
+	res := make([]byte, 0, len(xmlhdr)+len(b))
+	res = append(res, xmlhdr...)
+	res = append(res, b...)
+
+	ctx.Response().Header.SetContentType(fiber.MIMEApplicationXML)
+	return ctx.Status(apierr.HTTPStatusCode).Send(res)
+}
+
+// parseSessionDuration parses the DurationSeconds parameter of the
+// session requests
+func parseSessionDuration(str string, def, max time.Duration) (time.Duration, error) {
+	if str == "" {
+		return def, nil
+	}
+
+	secs, err := strconv.Atoi(str)
+	if err != nil {
+		return 0, getSTSValidationError(str, "durationSeconds", "Member must be a number")
+	}
+	duration := time.Duration(secs) * time.Second
+	if duration < minSessionDuration {
+		return 0, getSTSValidationError(str, "durationSeconds",
+			fmt.Sprintf("Member must have value greater than or equal to %v", int(minSessionDuration.Seconds())))
+	}
+	if duration > max {
+		return 0, getSTSValidationError(str, "durationSeconds",
+			fmt.Sprintf("Member must have value less than or equal to %v", int(max.Seconds())))
+	}
+
+	return duration, nil
+}
+
+// validateSessionPolicy validates the optional Policy parameter of the
+// assume role requests
+func validateSessionPolicy(policy string) error {
+	if policy == "" {
+		return nil
+	}
+	if len(policy) > maxSessionPolicySize {
+		return getSTSValidationError(policy, "policy",
+			fmt.Sprintf("Member must have length less than or equal to %v", maxSessionPolicySize))
+	}
+	if _, err := auth.ParseSessionPolicy(policy); err != nil {
+		return s3err.APIError{
+			Code:           "MalformedPolicyDocument",
+			Description:    err.Error(),
+			HTTPStatusCode: http.StatusBadRequest,
+		}
+	}
+
+	return nil
+}
+
+func getSTSValidationError(value, member, constraint string) s3err.APIError {
+	return s3err.APIError{
+		Code:           "ValidationError",
+		Description:    fmt.Sprintf("1 validation error detected: Value '%v' at '%v' failed to satisfy constraint: %v", value, member, constraint),
+		HTTPStatusCode: http.StatusBadRequest,
+	}
+}
+
+func getAssumedRoleUser(roleArn, sessionName string, creds auth.SessionCredentials) s3response.AssumedRoleUser {
+	roleName := roleArn[strings.LastIndex(roleArn, "/")+1:]
+	return s3response.AssumedRoleUser{
+		Arn:           fmt.Sprintf("arn:aws:sts:::assumed-role/%v/%v", roleName, sessionName),
+		AssumedRoleId: fmt.Sprintf("%v:%v", creds.Access, sessionName),
+	}
+}
+
+func getSTSCredentials(creds auth.SessionCredentials) s3response.STSCredentials {
+	return s3response.STSCredentials{
+		AccessKeyId:     creds.Access,
+		SecretAccessKey: creds.Secret,
+		SessionToken:    creds.SessionToken,
+		Expiration:      creds.Expiration,
+	}
+}
@@ -0,0 +1,256 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/gofiber/fiber/v2"
+	"github.com/versity/versitygw/auth"
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/s3api/utils"
+	"github.com/versity/versitygw/s3err"
+)
+
+// WebsiteController serves the objects of buckets with a website
+// configuration to anonymous clients
+type WebsiteController struct {
+	be     backend.Backend
+	domain string
+}
+
+// NewWebsiteController returns a website controller. When domain is
+// not empty, requests to <bucket>.<domain> are served from bucket,
+// otherwise the bucket is the first element of the request path.
+func NewWebsiteController(be backend.Backend, domain string) WebsiteController {
+	return WebsiteController{be: be, domain: domain}
+}
+
+func (c WebsiteController) ServeWebsite(ctx *fiber.Ctx) error {
+	if ctx.Method() != http.MethodGet && ctx.Method() != http.MethodHead {
+		return sendWebsiteError(ctx, s3err.GetAPIError(s3err.ErrWebsiteMethodNotAllowed))
+	}
+
+	bucket, key, pathPrefix := c.bucketAndKey(ctx)
+	if bucket == "" {
+		return sendWebsiteError(ctx, s3err.GetAPIError(s3err.ErrNoSuchBucket))
+	}
+
+	data, err := c.be.GetBucketWebsite(ctx.Context(), bucket)
+	if err != nil {
+		return sendWebsiteError(ctx, err)
+	}
+	config, err := utils.ParseWebsiteConfiguration(data)
+	if err != nil {
+		return sendWebsiteError(ctx, err)
+	}
+
+	protocol := ctx.Protocol()
+	host := string(ctx.Request().Host())
+
+	if redirect := config.RedirectAllRequestsTo; redirect != nil {
+		if redirect.Protocol != "" {
+			protocol = redirect.Protocol
+		}
+		return ctx.Redirect(protocol+"://"+redirect.HostName+"/"+utils.EscapeKeyPath(key), http.StatusMovedPermanently)
+	}
+
+	if rule := utils.MatchRoutingRule(config, key, 0); rule != nil {
+		location, code := utils.RoutingRuleRedirect(rule, key, protocol, host, pathPrefix)
+		return ctx.Redirect(location, code)
+	}
+
+	aclData, err := c.be.GetBucketAcl(ctx.Context(), &s3.GetBucketAclInput{Bucket: &bucket})
+	if err != nil {
+		return sendWebsiteError(ctx, err)
+	}
+	acl, err := auth.ParseACL(aclData)
+	if err != nil {
+		return sendWebsiteError(ctx, err)
+	}
+
+	suffix := config.IndexDocument.Suffix
+	objKey := key
+	if key == "" || strings.HasSuffix(key, "/") {
+		objKey += suffix
+	}
+
+	err = c.serveObject(ctx, bucket, objKey, acl)
+	if err == nil {
+		return nil
+	}
+
+	// a request for a prefix without the trailing slash is redirected
+	// to the prefix when it has an index document. The index document
+	// is only looked up if it is readable, otherwise the redirect would
+	// reveal the existence of private objects.
+	if objKey == key && errors.Is(err, s3err.GetAPIError(s3err.ErrNoSuchKey)) {
+		indexKey := key + "/" + suffix
+		if c.verifyAccess(ctx, bucket, indexKey, acl) == nil {
+			_, herr := c.be.HeadObject(ctx.Context(), &s3.HeadObjectInput{
+				Bucket: &bucket,
+				Key:    &indexKey,
+			})
+			if herr == nil {
+				return ctx.Redirect(pathPrefix+"/"+utils.EscapeKeyPath(key)+"/", http.StatusFound)
+			}
+		}
+	}
+
+	status := http.StatusInternalServerError
+	var apierr s3err.APIError
+	if errors.As(err, &apierr) {
+		status = apierr.HTTPStatusCode
+	}
+
+	if rule := utils.MatchRoutingRule(config, key, status); rule != nil {
+		location, code := utils.RoutingRuleRedirect(rule, key, protocol, host, pathPrefix)
+		return ctx.Redirect(location, code)
+	}
+
+	if config.ErrorDocument != nil && (status == http.StatusForbidden || status == http.StatusNotFound) {
+		if c.serveObject(ctx, bucket, config.ErrorDocument.Key, acl) == nil {
+			ctx.Status(status)
+			return nil
+		}
+	}
+
+	return sendWebsiteError(ctx, err)
+}
+
+// bucketAndKey returns the bucket and object key of the request, and
+// the path prefix of the bucket for path style requests
+func (c WebsiteController) bucketAndKey(ctx *fiber.Ctx) (string, string, string) {
+	path := strings.TrimPrefix(ctx.Path(), "/")
+
+	if c.domain != "" {
+		host := string(ctx.Request().Host())
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if bucket, ok := strings.CutSuffix(host, "."+c.domain); ok {
+			return bucket, path, ""
+		}
+	}
+
+	bucket, key, _ := strings.Cut(path, "/")
+	return bucket, key, "/" + bucket
+}
+
+// verifyAccess checks that the object is readable by anonymous clients
+func (c WebsiteController) verifyAccess(ctx *fiber.Ctx, bucket, key string, acl auth.ACL) error {
+	return auth.VerifyAccess(ctx.Context(), c.be, auth.AccessOptions{
+		Acl:           acl,
+		AclPermission: types.PermissionRead,
+		IsRoot:        false,
+		Acc:           auth.AnonymousAccount,
+		Bucket:        bucket,
+		Object:        key,
+		Action:        auth.GetObjectAction,
+	})
+}
+
+// serveObject writes the object to the response if it is readable by
+// anonymous clients
+func (c WebsiteController) serveObject(ctx *fiber.Ctx, bucket, key string, acl auth.ACL) error {
+	err := c.verifyAccess(ctx, bucket, key, acl)
+	if err != nil {
+		return err
+	}
+
+	var (
+		contentLength *int64
+		contentType   *string
+		etag          *string
+		lastmod       string
+	)
+
+	if ctx.Method() == http.MethodHead {
+		res, err := c.be.HeadObject(ctx.Context(), &s3.HeadObjectInput{
+			Bucket: &bucket,
+			Key:    &key,
+		})
+		if err != nil {
+			return err
+		}
+		contentLength, contentType, etag = res.ContentLength, res.ContentType, res.ETag
+		if res.LastModified != nil {
+			lastmod = res.LastModified.Format(timefmt)
+		}
+	} else {
+		var acceptRange, versionId string
+		res, err := c.be.GetObject(ctx.Context(), &s3.GetObjectInput{
+			Bucket:    &bucket,
+			Key:       &key,
+			Range:     &acceptRange,
+			VersionId: &versionId,
+		}, ctx.Response().BodyWriter())
+		if err != nil {
+			return err
+		}
+		if res == nil {
+			return fmt.Errorf("get object nil response")
+		}
+		contentLength, contentType, etag = res.ContentLength, res.ContentType, res.ETag
+		if res.LastModified != nil {
+			lastmod = res.LastModified.Format(timefmt)
+		}
+	}
+
+	utils.SetResponseHeaders(ctx, []utils.CustomHeader{
+		{
+			Key:   "Content-Length",
+			Value: fmt.Sprint(getint64(contentLength)),
+		},
+		{
+			Key:   "Content-Type",
+			Value: getstring(contentType),
+		},
+		{
+			Key:   "ETag",
+			Value: getstring(etag),
+		},
+		{
+			Key:   "Last-Modified",
+			Value: lastmod,
+		},
+	})
+	return nil
+}
+
+// sendWebsiteError responds with an html error page, website clients
+// are browsers rather than S3 clients
+func sendWebsiteError(ctx *fiber.Ctx, err error) error {
+	var apierr s3err.APIError
+	if !errors.As(err, &apierr) {
+		log.Printf("Internal Error, %v", err)
+		apierr = s3err.GetAPIError(s3err.ErrInternalError)
+	}
+
+	title := fmt.Sprintf("%v %v", apierr.HTTPStatusCode, http.StatusText(apierr.HTTPStatusCode))
+	ctx.Status(apierr.HTTPStatusCode)
+	ctx.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return ctx.SendString(fmt.Sprintf(
+		"<html>\n<head><title>%v</title></head>\n<body>\n<h1>%v</h1>\n<ul>\n<li>Code: %v</li>\n<li>Message: %v</li>\n</ul>\n</body>\n</html>\n",
+		title, title, html.EscapeString(apierr.Code), html.EscapeString(apierr.Description)))
+}
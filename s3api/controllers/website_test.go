@@ -0,0 +1,211 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/gofiber/fiber/v2"
+	"github.com/versity/versitygw/auth"
+	"github.com/versity/versitygw/s3err"
+)
+
+func TestWebsiteController_ServeWebsite(t *testing.T) {
+	websiteBody := `
+	<WebsiteConfiguration>
+		<IndexDocument><Suffix>index.html</Suffix></IndexDocument>
+		<ErrorDocument><Key>error.html</Key></ErrorDocument>
+		<RoutingRules>
+			<RoutingRule>
+				<Condition><KeyPrefixEquals>old/</KeyPrefixEquals></Condition>
+				<Redirect><ReplaceKeyPrefixWith>new/</ReplaceKeyPrefixWith></Redirect>
+			</RoutingRule>
+		</RoutingRules>
+	</WebsiteConfiguration>
+	`
+
+	publicAcl, err := json.Marshal(auth.ACL{ACL: types.BucketCannedACLPublicRead, Owner: "owner"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the objects under private/ are not readable by anonymous clients
+	policy := `{"Statement": [{"Effect": "Deny", "Principal": "*", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::site/private/*"}]}`
+
+	objects := map[string]string{
+		"index.html":         "home",
+		"error.html":         "oops",
+		"dir/index.html":     "dir",
+		"a b/index.html":     "space",
+		"private/index.html": "private",
+	}
+
+	be := &BackendMock{
+		GetBucketWebsiteFunc: func(contextMoqParam context.Context, bucket string) ([]byte, error) {
+			if bucket != "site" {
+				return nil, s3err.GetAPIError(s3err.ErrNoSuchWebsiteConfiguration)
+			}
+			return []byte(websiteBody), nil
+		},
+		GetBucketAclFunc: func(context.Context, *s3.GetBucketAclInput) ([]byte, error) {
+			return publicAcl, nil
+		},
+		GetBucketPolicyFunc: func(contextMoqParam context.Context, bucket string) ([]byte, error) {
+			return []byte(policy), nil
+		},
+		HeadObjectFunc: func(contextMoqParam context.Context, input *s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+			data, ok := objects[*input.Key]
+			if !ok {
+				return nil, s3err.GetAPIError(s3err.ErrNoSuchKey)
+			}
+			return &s3.HeadObjectOutput{ContentLength: aws.Int64(int64(len(data)))}, nil
+		},
+		GetObjectFunc: func(contextMoqParam context.Context, input *s3.GetObjectInput, w io.Writer) (*s3.GetObjectOutput, error) {
+			data, ok := objects[*input.Key]
+			if !ok {
+				return nil, s3err.GetAPIError(s3err.ErrNoSuchKey)
+			}
+			_, err := io.WriteString(w, data)
+			if err != nil {
+				return nil, err
+			}
+			return &s3.GetObjectOutput{
+				ContentLength: aws.Int64(int64(len(data))),
+				ContentType:   aws.String("text/html"),
+			}, nil
+		},
+	}
+
+	app := fiber.New()
+	// the website server decodes the request path before the controller
+	app.Use(func(ctx *fiber.Ctx) error {
+		path, err := url.PathUnescape(ctx.Path())
+		if err != nil {
+			return err
+		}
+		ctx.Path(path)
+		return ctx.Next()
+	})
+	app.All("/*", NewWebsiteController(be, "").ServeWebsite)
+
+	domainApp := fiber.New()
+	domainApp.All("/*", NewWebsiteController(be, "web.example.com").ServeWebsite)
+
+	domainReq := httptest.NewRequest(http.MethodGet, "/", nil)
+	domainReq.Host = "site.web.example.com:7071"
+
+	tests := []struct {
+		name         string
+		app          *fiber.App
+		req          *http.Request
+		statusCode   int
+		wantBody     string
+		wantLocation string
+	}{
+		{
+			name:       "Website-index-document",
+			app:        app,
+			req:        httptest.NewRequest(http.MethodGet, "/site/", nil),
+			statusCode: 200,
+			wantBody:   "home",
+		},
+		{
+			name:       "Website-error-document",
+			app:        app,
+			req:        httptest.NewRequest(http.MethodGet, "/site/missing.html", nil),
+			statusCode: 404,
+			wantBody:   "oops",
+		},
+		{
+			name:         "Website-prefix-redirect",
+			app:          app,
+			req:          httptest.NewRequest(http.MethodGet, "/site/dir", nil),
+			statusCode:   302,
+			wantLocation: "/site/dir/",
+		},
+		{
+			name:         "Website-prefix-redirect-escaped",
+			app:          app,
+			req:          httptest.NewRequest(http.MethodGet, "/site/a%20b", nil),
+			statusCode:   302,
+			wantLocation: "/site/a%20b/",
+		},
+		{
+			name:       "Website-prefix-redirect-private-index",
+			app:        app,
+			req:        httptest.NewRequest(http.MethodGet, "/site/private", nil),
+			statusCode: 404,
+			wantBody:   "oops",
+		},
+		{
+			name:         "Website-routing-rule",
+			app:          app,
+			req:          httptest.NewRequest(http.MethodGet, "http://localhost/site/old/page.html", nil),
+			statusCode:   301,
+			wantLocation: "http://localhost/site/new/page.html",
+		},
+		{
+			name:       "Website-no-configuration",
+			app:        app,
+			req:        httptest.NewRequest(http.MethodGet, "/bucket/", nil),
+			statusCode: 404,
+		},
+		{
+			name:       "Website-method-not-allowed",
+			app:        app,
+			req:        httptest.NewRequest(http.MethodPut, "/site/index.html", nil),
+			statusCode: 405,
+		},
+		{
+			name:       "Website-virtual-host",
+			app:        domainApp,
+			req:        domainReq,
+			statusCode: 200,
+			wantBody:   "home",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := tt.app.Test(tt.req)
+			if err != nil {
+				t.Fatalf("ServeWebsite() error = %v", err)
+			}
+			if resp.StatusCode != tt.statusCode {
+				t.Errorf("ServeWebsite() statusCode = %v, want %v", resp.StatusCode, tt.statusCode)
+			}
+			if tt.wantLocation != "" && resp.Header.Get("Location") != tt.wantLocation {
+				t.Errorf("ServeWebsite() location = %v, want %v", resp.Header.Get("Location"), tt.wantLocation)
+			}
+			if tt.wantBody != "" {
+				body, err := io.ReadAll(resp.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(body) != tt.wantBody {
+					t.Errorf("ServeWebsite() body = %q, want %q", body, tt.wantBody)
+				}
+			}
+		})
+	}
+}
@@ -37,7 +37,9 @@ func AclParser(be backend.Backend, logger s3log.AuditLogger) fiber.Handler {
 		path := ctx.Path()
 		pathParts := strings.Split(path, "/")
 		bucket := pathParts[1]
-		if path == "/" && ctx.Method() == http.MethodGet {
+		// service requests (ListBuckets and the sts actions) are not
+		// bucket requests
+		if path == "/" {
 			return ctx.Next()
 		}
 		if ctx.Method() == http.MethodPatch {
@@ -48,6 +50,10 @@ func AclParser(be backend.Backend, logger s3log.AuditLogger) fiber.Handler {
 			!ctx.Request().URI().QueryArgs().Has("acl") &&
 			!ctx.Request().URI().QueryArgs().Has("tagging") &&
 			!ctx.Request().URI().QueryArgs().Has("versioning") &&
+			!ctx.Request().URI().QueryArgs().Has("object-lock") &&
+			!ctx.Request().URI().QueryArgs().Has("lifecycle") &&
+			!ctx.Request().URI().QueryArgs().Has("cors") &&
+			!ctx.Request().URI().QueryArgs().Has("website") &&
 			!ctx.Request().URI().QueryArgs().Has("policy") {
 			if err := auth.MayCreateBucket(acct, isRoot); err != nil {
 				return controllers.SendXMLResponse(ctx, nil, err, &controllers.MetaOpts{Logger: logger, Action: "CreateBucket"})
@@ -54,7 +54,11 @@ func VerifyV4Signature(root RootUserConfig, iam auth.IAMService, logger s3log.Au
 		ctx.Locals("startTime", time.Now())
 		authorization := ctx.Get("Authorization")
 		if authorization == "" {
-			return sendResponse(ctx, s3err.GetAPIError(s3err.ErrAuthHeaderEmpty), logger)
+			// unsigned requests continue as the anonymous account, these
+			// are only authorized by public ACL grants and bucket policies
+			ctx.Locals("isRoot", false)
+			ctx.Locals("account", auth.AnonymousAccount)
+			return ctx.Next()
 		}
 
 		authData, err := utils.ParseAuthorization(authorization)
@@ -62,27 +66,41 @@ func VerifyV4Signature(root RootUserConfig, iam auth.IAMService, logger s3log.Au
 			return sendResponse(ctx, err, logger)
 		}
 
-		if authData.Algorithm != "AWS4-HMAC-SHA256" {
-			return sendResponse(ctx, s3err.GetAPIError(s3err.ErrSignatureVersionNotSupported), logger)
+		// only the security token service requests are signed for sts
+		if authData.Service == utils.STSService && !utils.IsSTSRequest(ctx) {
+			return sendResponse(ctx, s3err.GetAPIError(s3err.ErrSignatureIncorrService), logger)
 		}
 
-		if authData.Region != region {
-			return sendResponse(ctx, s3err.APIError{
-				Code:           "SignatureDoesNotMatch",
-				Description:    fmt.Sprintf("Credential should be scoped to a valid Region, not %v", authData.Region),
-				HTTPStatusCode: http.StatusForbidden,
-			}, logger)
+		switch authData.Algorithm {
+		case utils.SigV4Algorithm:
+			if authData.Region != region {
+				return sendResponse(ctx, s3err.APIError{
+					Code:           "SignatureDoesNotMatch",
+					Description:    fmt.Sprintf("Credential should be scoped to a valid Region, not %v", authData.Region),
+					HTTPStatusCode: http.StatusForbidden,
+				}, logger)
+			}
+		case utils.SigV4aAlgorithm:
+			// v4a signatures are valid for a set of regions
+			authData.RegionSet, err = utils.ParseRegionSet(ctx.Get("X-Amz-Region-Set"), region)
+			if err != nil {
+				return sendResponse(ctx, err, logger)
+			}
+		default:
+			return sendResponse(ctx, s3err.GetAPIError(s3err.ErrSignatureVersionNotSupported), logger)
 		}
+		// the access logs report sigv4a signatures as SigV4
+		ctx.Locals("signatureVersion", "SigV4")
+		ctx.Locals("authType", "AuthHeader")
 
-		ctx.Locals("isRoot", authData.Access == root.Access)
-
-		account, err := acct.getAccount(authData.Access)
+		account, err := acct.getAccount(authData.Access, ctx.Get("X-Amz-Security-Token"))
 		if err == auth.ErrNoSuchUser {
 			return sendResponse(ctx, s3err.GetAPIError(s3err.ErrInvalidAccessKeyID), logger)
 		}
 		if err != nil {
 			return sendResponse(ctx, err, logger)
 		}
+		ctx.Locals("isRoot", account.Access == root.Access)
 		ctx.Locals("account", account)
 
 		// Check X-Amz-Date header
@@ -118,6 +136,12 @@ func VerifyV4Signature(root RootUserConfig, iam auth.IAMService, logger s3log.Au
 		}
 
 		hashPayload := ctx.Get("X-Amz-Content-Sha256")
+		if hashPayload == "" && authData.Service == utils.STSService {
+			// the sts requests do not include the payload hash header,
+			// the signature is verified with the hash of the body
+			hashedPayload := sha256.Sum256(ctx.Body())
+			hashPayload = hex.EncodeToString(hashedPayload[:])
+		}
 		if !utils.IsSpecialPayload(hashPayload) {
 			// Calculate the hash of the request payload
 			hashedPayload := sha256.Sum256(ctx.Body())
@@ -152,7 +176,12 @@ type accounts struct {
 	iam  auth.IAMService
 }
 
-func (a accounts) getAccount(access string) (auth.Account, error) {
+// getAccount returns the account of the access key, the access key is
+// temporary when the request has a session token
+func (a accounts) getAccount(access, sessionToken string) (auth.Account, error) {
+	if sessionToken != "" {
+		return a.getSessionAccount(access, sessionToken)
+	}
 	if access == a.root.Access {
 		return auth.Account{
 			Access: a.root.Access,
@@ -164,6 +193,34 @@ func (a accounts) getAccount(access string) (auth.Account, error) {
 	return a.iam.GetUserAccount(access)
 }
 
+// getSessionAccount returns the account of session credentials issued by
+// the security token service
+func (a accounts) getSessionAccount(access, sessionToken string) (auth.Account, error) {
+	claims, secret, err := auth.NewSTS(a.root.Secret).ParseSessionToken(access, sessionToken)
+	if err != nil {
+		return auth.Account{}, err
+	}
+
+	// web identities without a gateway account carry their role in the
+	// session claims
+	if claims.Role != "" {
+		if claims.Parent == a.root.Access {
+			return auth.Account{}, s3err.GetAPIError(s3err.ErrInvalidToken)
+		}
+		return auth.SessionAccount(auth.Account{
+			Access: claims.Parent,
+			Role:   claims.Role,
+		}, claims, secret)
+	}
+
+	parent, err := a.getAccount(claims.Parent, "")
+	if err != nil {
+		return auth.Account{}, err
+	}
+
+	return auth.SessionAccount(parent, claims, secret)
+}
+
 func sendResponse(ctx *fiber.Ctx, err error, logger s3log.AuditLogger) error {
 	return controllers.SendResponse(ctx, err, &controllers.MetaOpts{Logger: logger})
 }
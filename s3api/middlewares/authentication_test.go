@@ -0,0 +1,130 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package middlewares
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/gofiber/fiber/v2"
+	"github.com/versity/versitygw/auth"
+)
+
+func TestVerifySessionToken(t *testing.T) {
+	root := RootUserConfig{Access: "rootuser", Secret: "rootsecret"}
+	sts := auth.NewSTS(root.Secret)
+	session, err := sts.NewSession(root.Access, "", time.Hour, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := sts.NewSession(root.Access, "", time.Hour, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := sts.NewSession(root.Access, "", -time.Minute, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a token signed with another root secret
+	forged, err := auth.NewSTS("othersecret").NewSession(root.Access, "", time.Hour, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := fiber.New()
+	app.Use(VerifyPresignedV4Signature(root, noUsersIAM{}, nil, "us-east-1", false))
+	app.Use(VerifyV4Signature(root, noUsersIAM{}, nil, "us-east-1", false))
+	app.Get("/bucket/obj", func(ctx *fiber.Ctx) error {
+		acct := ctx.Locals("account").(auth.Account)
+		if !acct.Session {
+			return ctx.SendStatus(http.StatusInternalServerError)
+		}
+		return ctx.SendString(acct.Access)
+	})
+
+	signer := v4.NewSigner()
+	header := func(creds auth.SessionCredentials, token string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/bucket/obj", nil)
+		req.Header.Set("X-Amz-Content-Sha256", "UNSIGNED-PAYLOAD")
+		err := signer.SignHTTP(context.Background(), aws.Credentials{
+			AccessKeyID:     creds.Access,
+			SecretAccessKey: creds.Secret,
+			SessionToken:    token,
+		}, req, "UNSIGNED-PAYLOAD", "s3", "us-east-1", time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return req
+	}
+	presigned := func(creds auth.SessionCredentials, token string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "http://example.com/bucket/obj?X-Amz-Expires=60", nil)
+		uri, _, err := signer.PresignHTTP(context.Background(), aws.Credentials{
+			AccessKeyID:     creds.Access,
+			SecretAccessKey: creds.Secret,
+			SessionToken:    token,
+		}, req, "UNSIGNED-PAYLOAD", "s3", "us-east-1", time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return httptest.NewRequest(http.MethodGet, strings.TrimPrefix(uri, "http://example.com"), nil)
+	}
+
+	tests := []struct {
+		name   string
+		req    *http.Request
+		status int
+		code   string
+	}{
+		{"header", header(session, session.SessionToken), http.StatusOK, ""},
+		{"header-tampered-token", header(session, session.SessionToken+"x"), http.StatusBadRequest, "InvalidToken"},
+		{"header-forged-token", header(forged, forged.SessionToken), http.StatusBadRequest, "InvalidToken"},
+		{"header-other-session-token", header(session, other.SessionToken), http.StatusBadRequest, "InvalidToken"},
+		{"header-expired-token", header(expired, expired.SessionToken), http.StatusBadRequest, "ExpiredToken"},
+		{"header-missing-token", header(session, ""), http.StatusForbidden, "InvalidAccessKeyId"},
+		{"presigned", presigned(session, session.SessionToken), http.StatusOK, ""},
+		{"presigned-tampered-token", presigned(session, session.SessionToken+"x"), http.StatusBadRequest, "InvalidToken"},
+		{"presigned-forged-token", presigned(forged, forged.SessionToken), http.StatusBadRequest, "InvalidToken"},
+		{"presigned-other-session-token", presigned(session, other.SessionToken), http.StatusBadRequest, "InvalidToken"},
+		{"presigned-expired-token", presigned(expired, expired.SessionToken), http.StatusBadRequest, "ExpiredToken"},
+		{"presigned-missing-token", presigned(session, ""), http.StatusForbidden, "InvalidAccessKeyId"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := app.Test(tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %v, want %v: %s", resp.StatusCode, tt.status, body)
+			}
+			if tt.code != "" && !strings.Contains(string(body), "<Code>"+tt.code+"</Code>") {
+				t.Errorf("expected %v error, got %s", tt.code, body)
+			}
+		})
+	}
+}
@@ -32,7 +32,6 @@ func ProcessChunkedBody(root RootUserConfig, iam auth.IAMService, logger s3log.A
 		if decodedLength == "" {
 			return ctx.Next()
 		}
-		// TODO: validate content length
 
 		authData, err := utils.ParseAuthorization(ctx.Get("Authorization"))
 		if err != nil {
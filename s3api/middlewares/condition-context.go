@@ -0,0 +1,48 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package middlewares
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/versity/versitygw/auth"
+)
+
+// SetConditionContext collects the request values used to evaluate
+// bucket policy conditions
+func SetConditionContext() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		cc := auth.ConditionContext{}
+		cc.Set(auth.SourceIpConditionKey, ctx.IP())
+		cc.Set(auth.SecureTransportConditionKey, strconv.FormatBool(ctx.Protocol() == "https"))
+		cc.Set(auth.CurrentTimeConditionKey, time.Now().UTC().Format(time.RFC3339))
+
+		args := ctx.Request().URI().QueryArgs()
+		if args.Has("prefix") {
+			cc.Set(auth.PrefixConditionKey, string(args.Peek("prefix")))
+		}
+		if args.Has("max-keys") {
+			cc.Set(auth.MaxKeysConditionKey, string(args.Peek("max-keys")))
+		}
+		if acl := ctx.Get("x-amz-acl"); acl != "" {
+			cc.Set(auth.AclConditionKey, acl)
+		}
+
+		ctx.Locals(auth.ConditionContextKey, cc)
+		return ctx.Next()
+	}
+}
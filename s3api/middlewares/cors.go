@@ -0,0 +1,140 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/s3api/controllers"
+	"github.com/versity/versitygw/s3api/utils"
+	"github.com/versity/versitygw/s3err"
+	"github.com/versity/versitygw/s3log"
+	"github.com/versity/versitygw/s3response"
+)
+
+// ApplyBucketCORS answers CORS preflight requests and adds the CORS
+// response headers to browser requests based on the bucket CORS
+// configuration. This must run before the authentication middlewares
+// because browsers do not sign preflight requests.
+func ApplyBucketCORS(be backend.Backend, logger s3log.AuditLogger) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		origin := ctx.Get("Origin")
+		isPreflight := ctx.Method() == http.MethodOptions
+		if origin == "" && !isPreflight {
+			return ctx.Next()
+		}
+
+		bucket := strings.Split(ctx.Path(), "/")[1]
+
+		if !isPreflight {
+			if bucket == "" {
+				return ctx.Next()
+			}
+			config := getBucketCORS(ctx, be, bucket)
+			if config != nil {
+				rule := utils.MatchCORSRule(config, origin, ctx.Method(), nil)
+				if rule != nil {
+					setCORSHeaders(ctx, rule, origin)
+				}
+			}
+			return ctx.Next()
+		}
+
+		if origin == "" {
+			return controllers.SendResponse(ctx, s3err.GetAPIError(s3err.ErrMissingCORSOrigin),
+				&controllers.MetaOpts{Logger: logger, Action: "PreflightRequest"})
+		}
+
+		method := ctx.Get("Access-Control-Request-Method")
+		if method == "" {
+			return controllers.SendResponse(ctx, s3err.GetAPIError(s3err.ErrInvalidCORSRequestMethod),
+				&controllers.MetaOpts{Logger: logger, Action: "PreflightRequest"})
+		}
+
+		var headers []string
+		for _, hdr := range strings.Split(ctx.Get("Access-Control-Request-Headers"), ",") {
+			hdr = strings.TrimSpace(hdr)
+			if hdr != "" {
+				headers = append(headers, hdr)
+			}
+		}
+
+		if bucket == "" {
+			return controllers.SendResponse(ctx, s3err.GetAPIError(s3err.ErrCORSForbidden),
+				&controllers.MetaOpts{Logger: logger, Action: "PreflightRequest"})
+		}
+
+		config := getBucketCORS(ctx, be, bucket)
+		if config == nil {
+			return controllers.SendResponse(ctx, s3err.GetAPIError(s3err.ErrCORSForbidden),
+				&controllers.MetaOpts{Logger: logger, Action: "PreflightRequest"})
+		}
+
+		rule := utils.MatchCORSRule(config, origin, method, headers)
+		if rule == nil {
+			return controllers.SendResponse(ctx, s3err.GetAPIError(s3err.ErrCORSForbidden),
+				&controllers.MetaOpts{Logger: logger, Action: "PreflightRequest"})
+		}
+
+		setCORSHeaders(ctx, rule, origin)
+		if len(headers) != 0 {
+			ctx.Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+		}
+		ctx.Set("Vary", "Origin, Access-Control-Request-Headers, Access-Control-Request-Method")
+
+		return ctx.SendStatus(http.StatusOK)
+	}
+}
+
+// getBucketCORS returns the parsed bucket CORS configuration, or nil if
+// the bucket does not exist or has no valid CORS configuration
+func getBucketCORS(ctx *fiber.Ctx, be backend.Backend, bucket string) *s3response.CORSConfiguration {
+	data, err := be.GetBucketCors(ctx.Context(), bucket)
+	if err != nil {
+		return nil
+	}
+	config, err := utils.ParseCORSConfiguration(data)
+	if err != nil {
+		return nil
+	}
+	return config
+}
+
+func setCORSHeaders(ctx *fiber.Ctx, rule *s3response.CORSRule, origin string) {
+	allowOrigin := origin
+	for _, o := range rule.AllowedOrigins {
+		if o == "*" {
+			allowOrigin = "*"
+			break
+		}
+	}
+
+	ctx.Set("Access-Control-Allow-Origin", allowOrigin)
+	if allowOrigin != "*" {
+		ctx.Set("Access-Control-Allow-Credentials", "true")
+	}
+	ctx.Set("Access-Control-Allow-Methods", strings.Join(rule.AllowedMethods, ", "))
+	if len(rule.ExposeHeaders) != 0 {
+		ctx.Set("Access-Control-Expose-Headers", strings.Join(rule.ExposeHeaders, ", "))
+	}
+	if rule.MaxAgeSeconds != nil {
+		ctx.Set("Access-Control-Max-Age", fmt.Sprint(*rule.MaxAgeSeconds))
+	}
+	ctx.Set("Vary", "Origin")
+}
@@ -0,0 +1,57 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package middlewares
+
+import (
+	"net"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/versity/versitygw/s3err"
+	"github.com/versity/versitygw/s3log"
+)
+
+// HostStyleParser rewrites virtual-hosted-style requests, addressing the
+// bucket as a sub-domain of the virtual domain (bucket.domain/key), to the
+// path-style routes (/bucket/key). Only the routed uri is changed, the
+// request line and Host header are kept for signature verification.
+func HostStyleParser(virtualDomain string, logger s3log.AuditLogger) fiber.Handler {
+	suffix := "." + strings.ToLower(virtualDomain)
+
+	return func(ctx *fiber.Ctx) error {
+		host := string(ctx.Request().Host())
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+
+		bucket, ok := strings.CutSuffix(strings.ToLower(host), suffix)
+		if !ok || bucket == "" {
+			return ctx.Next()
+		}
+
+		uri := ctx.Request().URI()
+		origHost := string(uri.Host())
+		scheme := string(uri.Scheme())
+		reqURI := "/" + bucket + string(ctx.Request().Header.RequestURI())
+
+		err := uri.Parse([]byte(origHost), []byte(reqURI))
+		if err != nil {
+			return sendResponse(ctx, s3err.GetAPIError(s3err.ErrInvalidURI), logger)
+		}
+		uri.SetScheme(scheme)
+
+		return ctx.Next()
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package middlewares
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestHostStyleParser(t *testing.T) {
+	root := RootUserConfig{Access: "rootuser", Secret: "rootsecret"}
+
+	app := fiber.New()
+	app.Use(HostStyleParser("s3.example.com", nil))
+	app.Use(DecodeURL(nil))
+	app.Use(VerifyV4Signature(root, noUsersIAM{}, nil, "us-east-1", false))
+	app.Get("/", func(ctx *fiber.Ctx) error {
+		return ctx.SendString("list-buckets")
+	})
+	app.Get("/:bucket", func(ctx *fiber.Ctx) error {
+		return ctx.SendString("bucket " + ctx.Params("bucket") + " " + ctx.Query("prefix"))
+	})
+	app.Get("/:bucket/*", func(ctx *fiber.Ctx) error {
+		return ctx.SendString("object " + ctx.Params("bucket") + " " + ctx.Params("*"))
+	})
+
+	// the s3 paths are escaped once
+	signer := v4.NewSigner(func(o *v4.SignerOptions) { o.DisableURIPathEscaping = true })
+	request := func(host, target string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Host = host
+		req.Header.Set("X-Amz-Content-Sha256", "UNSIGNED-PAYLOAD")
+		err := signer.SignHTTP(context.Background(), aws.Credentials{
+			AccessKeyID:     root.Access,
+			SecretAccessKey: root.Secret,
+		}, req, "UNSIGNED-PAYLOAD", "s3", "us-east-1", time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return req
+	}
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		body string
+	}{
+		{"path-style", request("s3.example.com", "/bucket/dir/obj"), "object bucket dir/obj"},
+		{"service", request("s3.example.com", "/"), "list-buckets"},
+		{"host-style-object", request("bucket.s3.example.com", "/dir/obj"), "object bucket dir/obj"},
+		{"host-style-bucket", request("bucket.s3.example.com", "/?prefix=dir"), "bucket bucket dir"},
+		{"host-style-port", request("bucket.s3.example.com:7070", "/obj"), "object bucket obj"},
+		{"host-style-upper-case-host", request("Bucket.S3.Example.com", "/obj"), "object bucket obj"},
+		{"host-style-escaped-key", request("bucket.s3.example.com", "/my%20obj"), "object bucket my obj"},
+		{"path-style-escaped-key", request("s3.example.com", "/bucket/my%20obj"), "object bucket my obj"},
+		{"other-domain", request("bucket.other.com", "/dir/obj"), "object dir obj"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := app.Test(tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("status = %v: %s", resp.StatusCode, body)
+			}
+			if string(body) != tt.body {
+				t.Errorf("body = %q, want %q", body, tt.body)
+			}
+		})
+	}
+}
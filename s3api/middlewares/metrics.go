@@ -0,0 +1,142 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/gofiber/fiber/v2"
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/metrics"
+)
+
+// Metrics records the request counts, latencies and the bytes transferred
+// of each bucket. The action of the request is set by the S3 controllers
+// when sending the response, the requests rejected before reaching them
+// and the admin requests are recorded as Unknown.
+func Metrics(be backend.Backend) fiber.Handler {
+	go countMultipartUploads(be)
+
+	return func(ctx *fiber.Ctx) error {
+		start := time.Now()
+		metrics.RequestsInFlight.Inc()
+		defer metrics.RequestsInFlight.Dec()
+
+		received := ctx.Request().Header.ContentLength()
+
+		err := ctx.Next()
+
+		status := ctx.Response().StatusCode()
+		if err != nil {
+			status = http.StatusInternalServerError
+			var ferr *fiber.Error
+			if errors.As(err, &ferr) {
+				status = ferr.Code
+			}
+		}
+
+		action, _ := ctx.Locals("action").(string)
+		if action == "" {
+			action = "Unknown"
+		}
+		code := strconv.Itoa(status)
+		metrics.Requests.Inc(action, code)
+		metrics.RequestDuration.Observe(time.Since(start).Seconds(), action, code)
+
+		// the failed and unknown requests may name any bucket
+		if status >= http.StatusBadRequest || action == "Unknown" {
+			return err
+		}
+
+		bucket := strings.Split(ctx.Path(), "/")[1]
+		if bucket == "" {
+			return err
+		}
+		if received > 0 {
+			metrics.BucketBytesReceived.Add(int64(received), bucket)
+		}
+		// the response body is buffered unless streamed by the handler
+		sent := len(ctx.Response().Body())
+		if ctx.Response().IsBodyStream() {
+			sent = ctx.Response().Header.ContentLength()
+		}
+		if sent > 0 {
+			metrics.BucketBytesSent.Add(int64(sent), bucket)
+		}
+
+		return err
+	}
+}
+
+// multipartCountInterval is how often the multipart uploads in progress
+// are counted. These are counted from the backend rather than from the
+// requests, so that the uploads aborted by the lifecycle rules or removed
+// with the bucket are not counted.
+const multipartCountInterval = time.Minute
+
+// countMultipartUploads sets the active multipart uploads gauge to the
+// number of multipart uploads in progress every count interval
+func countMultipartUploads(be backend.Backend) {
+	for {
+		count, err := multipartUploadCount(be)
+		if err != nil {
+			log.Printf("count multipart uploads: %v", err)
+		} else {
+			metrics.MultipartUploads.Set(count)
+		}
+		time.Sleep(multipartCountInterval)
+	}
+}
+
+// multipartUploadCount returns the number of multipart uploads in
+// progress in all buckets
+func multipartUploadCount(be backend.Backend) (int64, error) {
+	ctx := context.Background()
+	buckets, err := be.ListBucketsAndOwners(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	for _, b := range buckets {
+		bucket := b.Name
+		var keyMarker, uploadIDMarker string
+		for {
+			res, err := be.ListMultipartUploads(ctx, &s3.ListMultipartUploadsInput{
+				Bucket:         &bucket,
+				KeyMarker:      &keyMarker,
+				UploadIdMarker: &uploadIDMarker,
+			})
+			if err != nil {
+				log.Printf("count multipart uploads of bucket %v: %v", bucket, err)
+				break
+			}
+			count += int64(len(res.Uploads))
+			if !res.IsTruncated {
+				break
+			}
+			keyMarker, uploadIDMarker = res.NextKeyMarker, res.NextUploadIDMarker
+		}
+	}
+
+	return count, nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package middlewares
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/versity/versitygw/auth"
+	"github.com/versity/versitygw/s3api/utils"
+	"github.com/versity/versitygw/s3err"
+	"github.com/versity/versitygw/s3log"
+)
+
+// VerifyPostPolicy parses the multipart/form-data body of browser based
+// POST Object uploads and verifies the signature of the form policy.
+// Forms without a signature continue as the anonymous account.
+func VerifyPostPolicy(root RootUserConfig, iam auth.IAMService, logger s3log.AuditLogger, region string) fiber.Handler {
+	acct := accounts{root: root, iam: iam}
+
+	return func(ctx *fiber.Ctx) error {
+		if ctx.Method() != http.MethodPost || !singlePath.MatchString(ctx.Path()) {
+			return ctx.Next()
+		}
+		mediaType, params, err := mime.ParseMediaType(ctx.Get("Content-Type"))
+		if err != nil || mediaType != "multipart/form-data" {
+			return ctx.Next()
+		}
+
+		ctx.Locals("region", region)
+		ctx.Locals("startTime", time.Now())
+
+		var body io.Reader = ctx.Request().BodyStream()
+		if body == nil {
+			body = bytes.NewReader(ctx.Body())
+		}
+		form, err := utils.ParsePostForm(body, params["boundary"])
+		if err != nil {
+			return sendResponse(ctx, err, logger)
+		}
+		defer form.Close()
+		ctx.Locals("post-form", form)
+
+		if form.Fields["x-amz-signature"] == "" {
+			return ctx.Next()
+		}
+
+		authData, err := form.AuthData(region)
+		if err != nil {
+			return sendResponse(ctx, err, logger)
+		}
+		// the form fields are neither an authorization header nor a
+		// presigned query, the authentication type is not logged
+		ctx.Locals("signatureVersion", "SigV4")
+
+		account, err := acct.getAccount(authData.Access, form.Fields["x-amz-security-token"])
+		if err == auth.ErrNoSuchUser {
+			return sendResponse(ctx, s3err.GetAPIError(s3err.ErrInvalidAccessKeyID), logger)
+		}
+		if err != nil {
+			return sendResponse(ctx, err, logger)
+		}
+		ctx.Locals("isRoot", account.Access == root.Access)
+		ctx.Locals("account", account)
+
+		err = utils.CheckPostPolicySignature(authData, account.Secret, form.Fields["policy"])
+		if err != nil {
+			return sendResponse(ctx, err, logger)
+		}
+
+		return ctx.Next()
+	}
+}
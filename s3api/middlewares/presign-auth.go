@@ -40,15 +40,17 @@ func VerifyPresignedV4Signature(root RootUserConfig, iam auth.IAMService, logger
 		if err != nil {
 			return sendResponse(ctx, err, logger)
 		}
+		ctx.Locals("signatureVersion", "SigV4")
+		ctx.Locals("authType", "QueryString")
 
-		ctx.Locals("isRoot", authData.Access == root.Access)
-		account, err := acct.getAccount(authData.Access)
+		account, err := acct.getAccount(authData.Access, ctx.Query("X-Amz-Security-Token"))
 		if err == auth.ErrNoSuchUser {
 			return sendResponse(ctx, s3err.GetAPIError(s3err.ErrInvalidAccessKeyID), logger)
 		}
 		if err != nil {
 			return sendResponse(ctx, err, logger)
 		}
+		ctx.Locals("isRoot", account.Access == root.Access)
 		ctx.Locals("account", account)
 
 		if utils.IsBigDataAction(ctx) {
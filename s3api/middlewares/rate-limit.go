@@ -0,0 +1,73 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package middlewares
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/versity/versitygw/auth"
+	"github.com/versity/versitygw/s3api/utils"
+	"github.com/versity/versitygw/s3log"
+)
+
+// RateLimitIP rejects the requests over the request rate of the source
+// IP with SlowDown errors. This runs before the authentication
+// middlewares, so that unauthenticated request floods are rejected
+// before the signatures are verified.
+func RateLimitIP(rl *utils.RateLimiter, logger s3log.AuditLogger) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		err := rl.AllowIP(ctx.IP())
+		if err != nil {
+			return sendResponse(ctx, err, logger)
+		}
+		return ctx.Next()
+	}
+}
+
+// RateLimit rejects the requests over the request rate of the account or
+// bucket with SlowDown errors, and throttles the request and response
+// bodies to the account, bucket and source IP bandwidth limits. This
+// must run after the authentication middlewares to know the request
+// account.
+func RateLimit(rl *utils.RateLimiter, logger s3log.AuditLogger) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		acct, _ := ctx.Locals("account").(auth.Account)
+		bucket := strings.Split(ctx.Path(), "/")[1]
+
+		throttle, err := rl.Allow(acct.Access, bucket, ctx.IP())
+		if err != nil {
+			return sendResponse(ctx, err, logger)
+		}
+		if throttle == nil {
+			return ctx.Next()
+		}
+
+		if utils.IsBigDataAction(ctx) {
+			wrapBodyReader(ctx, throttle.Reader)
+		} else {
+			throttle.Wait(ctx.Request().Header.ContentLength())
+		}
+
+		err = ctx.Next()
+
+		// the response body is buffered unless streamed by the handler
+		if !ctx.Response().IsBodyStream() {
+			throttle.Wait(len(ctx.Response().Body()))
+		}
+
+		return err
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/versity/versitygw/s3api/utils"
+	"github.com/versity/versitygw/tracing"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceRequest starts the server span of the request, continuing the trace
+// propagated in the request headers. The span is renamed to the action of
+// the request once handled. The spans are exported after the request, so
+// the request strings reused by fiber are cloned.
+func TraceRequest() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		method := strings.Clone(ctx.Method())
+		_, span := tracing.Start(tracing.Extract(ctx), method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(method),
+				semconv.URLPath(strings.Clone(ctx.Path())),
+			))
+		tracing.SetRequestSpan(ctx, span)
+
+		err := ctx.Next()
+
+		status := ctx.Response().StatusCode()
+		if err != nil {
+			status = http.StatusInternalServerError
+			var ferr *fiber.Error
+			if errors.As(err, &ferr) {
+				status = ferr.Code
+			}
+		}
+
+		if action, _ := ctx.Locals("action").(string); action != "" {
+			span.SetName(strings.Clone(action))
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+		span.End()
+
+		return err
+	}
+}
+
+// StartSpan starts a span of the middlewares up to EndSpan, the span
+// ends with the response when one of the middlewares sends it
+func StartSpan(name string) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		_, span := tracing.Start(ctx.Context(), name)
+		ctx.Locals("trace-span", span)
+
+		err := ctx.Next()
+
+		// the span is still recording when the request is rejected
+		if span.IsRecording() {
+			if status := ctx.Response().StatusCode(); status >= http.StatusBadRequest {
+				span.SetStatus(codes.Error, fmt.Sprintf("response status %v", status))
+			}
+			span.End()
+		}
+
+		return err
+	}
+}
+
+// EndSpan ends the span started by StartSpan
+func EndSpan() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		if span, ok := ctx.Locals("trace-span").(trace.Span); ok {
+			span.End()
+		}
+		return ctx.Next()
+	}
+}
+
+// TraceBody times the reads of the request body, with the signature
+// verification of the payload and chunks, in a span of the request
+func TraceBody() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		if !utils.IsBigDataAction(ctx) {
+			return ctx.Next()
+		}
+
+		name := "RequestBody"
+		if ctx.Get("X-Amz-Decoded-Content-Length") != "" {
+			name = "ChunkReader"
+		}
+		var br *tracing.BodyReader
+		wrapBodyReader(ctx, func(r io.Reader) io.Reader {
+			br = tracing.Reader(ctx.Context(), name, r)
+			return br
+		})
+
+		err := ctx.Next()
+		// the body is not read to the end when the request fails
+		br.End(err)
+		return err
+	}
+}
+
+// TraceController starts the span of the controller action, the parent of
+// the backend call spans
+func TraceController() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		_, span := tracing.Start(ctx.Context(), "S3ApiController")
+		tracing.SetRequestSpan(ctx, span)
+
+		err := ctx.Next()
+
+		if action, _ := ctx.Locals("action").(string); action != "" {
+			span.SetName("S3ApiController." + action)
+		}
+		tracing.End(span, err)
+
+		return err
+	}
+}
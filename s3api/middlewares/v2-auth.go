@@ -0,0 +1,91 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package middlewares
+
+import (
+	"io"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/versity/versitygw/auth"
+	"github.com/versity/versitygw/s3api/utils"
+	"github.com/versity/versitygw/s3err"
+	"github.com/versity/versitygw/s3log"
+)
+
+// VerifyV2Signature authenticates requests signed with the legacy AWS
+// Signature Version 2, both in the Authorization header and in presigned
+// urls. Requests not signed with SigV2 are passed to the next handlers.
+func VerifyV2Signature(root RootUserConfig, iam auth.IAMService, logger s3log.AuditLogger, region string, debug bool) fiber.Handler {
+	acct := accounts{root: root, iam: iam}
+
+	return func(ctx *fiber.Ctx) error {
+		if !utils.IsV2Request(ctx) {
+			return ctx.Next()
+		}
+
+		ctx.Locals("region", region)
+		ctx.Locals("startTime", time.Now())
+
+		ctx.Locals("signatureVersion", "SigV2")
+
+		var authData utils.V2AuthData
+		var err error
+		var token string
+		authorization := ctx.Get("Authorization")
+		if authorization != "" {
+			ctx.Locals("authType", "AuthHeader")
+			authData, err = utils.ParseV2Authorization(authorization)
+			if err != nil {
+				return sendResponse(ctx, err, logger)
+			}
+			err = utils.ValidateV2Date(ctx)
+			token = ctx.Get("X-Amz-Security-Token")
+		} else {
+			ctx.Locals("authType", "QueryString")
+			authData, err = utils.ParseV2PresignedQuery(ctx)
+			token = ctx.Query("X-Amz-Security-Token")
+		}
+		if err != nil {
+			return sendResponse(ctx, err, logger)
+		}
+
+		account, err := acct.getAccount(authData.Access, token)
+		if err == auth.ErrNoSuchUser {
+			return sendResponse(ctx, s3err.GetAPIError(s3err.ErrInvalidAccessKeyID), logger)
+		}
+		if err != nil {
+			return sendResponse(ctx, err, logger)
+		}
+
+		err = utils.CheckV2Signature(ctx, authData, account.Secret, debug)
+		if err != nil {
+			return sendResponse(ctx, err, logger)
+		}
+
+		// the account is set only once the signature is verified, the
+		// v4 authentication middleware is skipped for the request
+		ctx.Locals("isRoot", account.Access == root.Access)
+		ctx.Locals("account", account)
+
+		if utils.IsBigDataAction(ctx) {
+			// v2 signatures do not cover the payload, the body stream
+			// is passed through as is
+			wrapBodyReader(ctx, func(r io.Reader) io.Reader { return r })
+		}
+
+		return ctx.Next()
+	}
+}
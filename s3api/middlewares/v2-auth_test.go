@@ -0,0 +1,127 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package middlewares
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/versity/versitygw/auth"
+)
+
+// noUsersIAM has no user accounts
+type noUsersIAM struct {
+	auth.IAMServiceSingle
+}
+
+func (noUsersIAM) GetUserAccount(string) (auth.Account, error) {
+	return auth.Account{}, auth.ErrNoSuchUser
+}
+
+func signV2(secret, strToSign string) string {
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write([]byte(strToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyV2Signature(t *testing.T) {
+	root := RootUserConfig{Access: "rootuser", Secret: "rootsecret"}
+	session, err := auth.NewSTS(root.Secret).NewSession(root.Access, "", time.Hour, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := fiber.New()
+	app.Use(VerifyV2Signature(root, noUsersIAM{}, nil, "us-east-1", false))
+	app.Get("/bucket/obj", func(ctx *fiber.Ctx) error {
+		acct := ctx.Locals("account").(auth.Account)
+		return ctx.SendString(fmt.Sprintf("%v %v %v", acct.Access, acct.Session, ctx.Locals("isRoot")))
+	})
+
+	date := time.Now().UTC().Format(http.TimeFormat)
+	expires := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	expired := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+
+	header := func(access, secret, token string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/bucket/obj", nil)
+		req.Header.Set("Date", date)
+		strToSign := "GET\n\n\n" + date + "\n"
+		if token != "" {
+			req.Header.Set("X-Amz-Security-Token", token)
+			strToSign += "x-amz-security-token:" + token + "\n"
+		}
+		strToSign += "/bucket/obj"
+		req.Header.Set("Authorization", "AWS "+access+":"+signV2(secret, strToSign))
+		return req
+	}
+	presigned := func(access, secret, token, expires string) *http.Request {
+		query := url.Values{}
+		query.Set("AWSAccessKeyId", access)
+		query.Set("Expires", expires)
+		query.Set("Signature", signV2(secret, "GET\n\n\n"+expires+"\n/bucket/obj"))
+		if token != "" {
+			query.Set("X-Amz-Security-Token", token)
+		}
+		return httptest.NewRequest(http.MethodGet, "/bucket/obj?"+query.Encode(), nil)
+	}
+
+	tests := []struct {
+		name   string
+		req    *http.Request
+		status int
+		body   string
+	}{
+		{"header", header(root.Access, root.Secret, ""), http.StatusOK, "rootuser false true"},
+		{"header-wrong-secret", header(root.Access, "wrong", ""), http.StatusForbidden, ""},
+		{"header-unknown-access", header("unknown", root.Secret, ""), http.StatusForbidden, ""},
+		{"header-session", header(session.Access, session.Secret, session.SessionToken), http.StatusOK, "rootuser true true"},
+		{"header-forged-session", header(session.Access, session.Secret, session.SessionToken+"x"), http.StatusBadRequest, ""},
+		{"presigned", presigned(root.Access, root.Secret, "", expires), http.StatusOK, "rootuser false true"},
+		{"presigned-expired", presigned(root.Access, root.Secret, "", expired), http.StatusForbidden, ""},
+		{"presigned-wrong-secret", presigned(root.Access, "wrong", "", expires), http.StatusForbidden, ""},
+		{"presigned-session", presigned(session.Access, session.Secret, session.SessionToken, expires), http.StatusOK, "rootuser true true"},
+		{"presigned-session-no-token", presigned(session.Access, session.Secret, "", expires), http.StatusForbidden, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := app.Test(tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %v, want %v: %s", resp.StatusCode, tt.status, body)
+			}
+			if tt.body != "" && string(body) != tt.body {
+				t.Errorf("body = %q, want %q", body, tt.body)
+			}
+		})
+	}
+}
@@ -19,19 +19,25 @@ import (
 	"github.com/versity/versitygw/auth"
 	"github.com/versity/versitygw/backend"
 	"github.com/versity/versitygw/s3api/controllers"
+	"github.com/versity/versitygw/s3api/utils"
 	"github.com/versity/versitygw/s3event"
 	"github.com/versity/versitygw/s3log"
 )
 
 type S3ApiRouter struct {
 	WithAdmSrv bool
+	// OIDC is the trusted identity provider of AssumeRoleWithWebIdentity
+	OIDC *auth.OIDC
+	// RateLimiter is the request rate limiter managed by the admin apis
+	RateLimiter *utils.RateLimiter
 }
 
-func (sa *S3ApiRouter) Init(app *fiber.App, be backend.Backend, iam auth.IAMService, logger s3log.AuditLogger, evs s3event.S3EventSender) {
+func (sa *S3ApiRouter) Init(app *fiber.App, be backend.Backend, iam auth.IAMService, logger s3log.AuditLogger, evs s3event.S3EventSender, sts *auth.STS) {
 	s3ApiController := controllers.New(be, iam, logger, evs)
+	stsController := controllers.NewSTSController(sts, iam, sa.OIDC, logger)
 
 	if sa.WithAdmSrv {
-		adminController := controllers.NewAdminController(iam, be)
+		adminController := controllers.NewAdminController(iam, be, sa.RateLimiter)
 
 		// CreateUser admin api
 		app.Patch("/create-user", adminController.CreateUser)
@@ -39,6 +45,12 @@ func (sa *S3ApiRouter) Init(app *fiber.App, be backend.Backend, iam auth.IAMServ
 		// DeleteUsers admin api
 		app.Patch("/delete-user", adminController.DeleteUser)
 
+		// PutUserPolicy admin api
+		app.Patch("/put-user-policy", adminController.PutUserPolicy)
+
+		// DeleteUserPolicy admin api
+		app.Patch("/delete-user-policy", adminController.DeleteUserPolicy)
+
 		// ListUsers admin api
 		app.Patch("/list-users", adminController.ListUsers)
 
@@ -47,16 +59,46 @@ func (sa *S3ApiRouter) Init(app *fiber.App, be backend.Backend, iam auth.IAMServ
 
 		// ListBucketsAndOwners admin api
 		app.Patch("/list-buckets", adminController.ListBuckets)
+
+		// PutQuota admin api
+		app.Patch("/put-quota", adminController.PutQuota)
+
+		// GetQuota admin api
+		app.Patch("/get-quota", adminController.GetQuota)
+
+		// DeleteQuota admin api
+		app.Patch("/delete-quota", adminController.DeleteQuota)
+
+		// PutRateLimit admin api
+		app.Patch("/put-rate-limit", adminController.PutRateLimit)
+
+		// GetRateLimit admin api
+		app.Patch("/get-rate-limit", adminController.GetRateLimit)
+
+		// DeleteRateLimit admin api
+		app.Patch("/delete-rate-limit", adminController.DeleteRateLimit)
 	}
 
 	// ListBuckets action
 	app.Get("/", s3ApiController.ListBuckets)
 
+	// AssumeRole STS action
+	// AssumeRoleWithWebIdentity STS action
+	// GetSessionToken STS action
+	app.Post("/", stsController.HandleAction)
+
 	// CreateBucket action
 	// PutBucketAcl action
+	// PutObjectLockConfiguration action
+	// PutBucketLifecycleConfiguration action
+	// PutBucketCors action
+	// PutBucketWebsite action
 	app.Put("/:bucket", s3ApiController.PutBucketActions)
 
 	// DeleteBucket action
+	// DeleteBucketLifecycle action
+	// DeleteBucketCors action
+	// DeleteBucketWebsite action
 	app.Delete("/:bucket", s3ApiController.DeleteBucket)
 
 	// HeadBucket
@@ -66,6 +108,10 @@ func (sa *S3ApiRouter) Init(app *fiber.App, be backend.Backend, iam auth.IAMServ
 	// ListMultipartUploads action
 	// ListObjects action
 	// ListObjectsV2 action
+	// GetObjectLockConfiguration action
+	// GetBucketLifecycleConfiguration action
+	// GetBucketCors action
+	// GetBucketWebsite action
 	app.Get("/:bucket", s3ApiController.ListActions)
 
 	// HeadObject action
@@ -77,6 +123,8 @@ func (sa *S3ApiRouter) Init(app *fiber.App, be backend.Backend, iam auth.IAMServ
 	// GetObjectTagging action
 	// ListParts action
 	// GetObjectAttributes action
+	// GetObjectRetention action
+	// GetObjectLegalHold action
 	app.Get("/:bucket/:key/*", s3ApiController.GetActions)
 
 	// DeleteObject action
@@ -85,7 +133,8 @@ func (sa *S3ApiRouter) Init(app *fiber.App, be backend.Backend, iam auth.IAMServ
 	app.Delete("/:bucket/:key/*", s3ApiController.DeleteActions)
 
 	// DeleteObjects action
-	app.Post("/:bucket", s3ApiController.DeleteObjects)
+	// PostObject action
+	app.Post("/:bucket", s3ApiController.PostBucketActions)
 
 	// CompleteMultipartUpload action
 	// CreateMultipartUpload
@@ -99,5 +148,7 @@ func (sa *S3ApiRouter) Init(app *fiber.App, be backend.Backend, iam auth.IAMServ
 	// UploadPartCopy action
 	// PutObjectTagging action
 	// PutObjectAcl action
+	// PutObjectRetention action
+	// PutObjectLegalHold action
 	app.Put("/:bucket/:key/*", s3ApiController.PutActions)
 }
@@ -23,19 +23,25 @@ import (
 	"github.com/versity/versitygw/auth"
 	"github.com/versity/versitygw/backend"
 	"github.com/versity/versitygw/s3api/middlewares"
+	"github.com/versity/versitygw/s3api/utils"
 	"github.com/versity/versitygw/s3event"
 	"github.com/versity/versitygw/s3log"
 )
 
 type S3ApiServer struct {
-	app     *fiber.App
-	backend backend.Backend
-	router  *S3ApiRouter
-	port    string
-	cert    *tls.Certificate
-	quiet   bool
-	debug   bool
-	health  string
+	app           *fiber.App
+	backend       backend.Backend
+	router        *S3ApiRouter
+	port          string
+	cert          *tls.Certificate
+	quiet         bool
+	debug         bool
+	health        string
+	sigV2         bool
+	virtualDomain string
+	rateLimiter   *utils.RateLimiter
+	metrics       bool
+	tracing       bool
 }
 
 func New(app *fiber.App, be backend.Backend, root middlewares.RootUserConfig, port, region string, iam auth.IAMService, l s3log.AuditLogger, evs s3event.S3EventSender, opts ...Option) (*S3ApiServer, error) {
@@ -60,17 +66,52 @@ func New(app *fiber.App, be backend.Backend, root middlewares.RootUserConfig, po
 			return ctx.SendStatus(http.StatusOK)
 		})
 	}
+	if server.metrics {
+		app.Use(middlewares.Metrics(be))
+	}
+	if server.tracing {
+		app.Use(middlewares.TraceRequest())
+	}
+	if server.virtualDomain != "" {
+		app.Use(middlewares.HostStyleParser(server.virtualDomain, l))
+	}
 	app.Use(middlewares.DecodeURL(l))
 	app.Use(middlewares.RequestLogger(server.debug))
+	if server.rateLimiter != nil {
+		app.Use(middlewares.RateLimitIP(server.rateLimiter, l))
+	}
+
+	// CORS preflight requests are not signed
+	app.Use(middlewares.ApplyBucketCORS(be, l))
 
 	// Authentication middlewares
+	if server.tracing {
+		app.Use(middlewares.StartSpan("Authentication"))
+	}
+	if server.sigV2 {
+		app.Use(middlewares.VerifyV2Signature(root, iam, l, region, server.debug))
+	}
 	app.Use(middlewares.VerifyPresignedV4Signature(root, iam, l, region, server.debug))
+	app.Use(middlewares.VerifyPostPolicy(root, iam, l, region))
 	app.Use(middlewares.VerifyV4Signature(root, iam, l, region, server.debug))
+	if server.tracing {
+		app.Use(middlewares.EndSpan())
+	}
+	if server.rateLimiter != nil {
+		app.Use(middlewares.RateLimit(server.rateLimiter, l))
+	}
 	app.Use(middlewares.ProcessChunkedBody(root, iam, l, region))
 	app.Use(middlewares.VerifyMD5Body(l))
+	if server.tracing {
+		app.Use(middlewares.TraceBody())
+	}
 	app.Use(middlewares.AclParser(be, l))
+	app.Use(middlewares.SetConditionContext())
+	if server.tracing {
+		app.Use(middlewares.TraceController())
+	}
 
-	server.router.Init(app, be, iam, l, evs)
+	server.router.Init(app, be, iam, l, evs, auth.NewSTS(root.Secret))
 
 	return server, nil
 }
@@ -103,6 +144,43 @@ func WithHealth(health string) Option {
 	return func(s *S3ApiServer) { s.health = health }
 }
 
+// WithSigV2 accepts legacy AWS Signature Version 2 signed requests
+func WithSigV2() Option {
+	return func(s *S3ApiServer) { s.sigV2 = true }
+}
+
+// WithHostStyle enables virtual-hosted-style bucket addressing with the
+// virtual domain as the base domain, e.g. bucket.virtualDomain/key
+func WithHostStyle(virtualDomain string) Option {
+	return func(s *S3ApiServer) { s.virtualDomain = virtualDomain }
+}
+
+// WithOIDC trusts the OpenID Connect identity provider for
+// AssumeRoleWithWebIdentity requests
+func WithOIDC(o *auth.OIDC) Option {
+	return func(s *S3ApiServer) { s.router.OIDC = o }
+}
+
+// WithRateLimiter enables the request rate and bandwidth limits, and the
+// admin apis managing the account limits
+func WithRateLimiter(rl *utils.RateLimiter) Option {
+	return func(s *S3ApiServer) {
+		s.rateLimiter = rl
+		s.router.RateLimiter = rl
+	}
+}
+
+// WithMetrics records the request metrics served by the metrics package
+func WithMetrics() Option {
+	return func(s *S3ApiServer) { s.metrics = true }
+}
+
+// WithTracing traces the requests with the spans of the authentication,
+// the request body reads and the controller actions
+func WithTracing() Option {
+	return func(s *S3ApiServer) { s.tracing = true }
+}
+
 func (sa *S3ApiServer) Serve() (err error) {
 	if sa.cert != nil {
 		return sa.app.ListenTLSWithCertificate(sa.port, *sa.cert)
@@ -18,7 +18,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
+	"path"
+	"slices"
 	"strings"
 	"time"
 	"unicode"
@@ -27,6 +30,7 @@ import (
 	"github.com/aws/smithy-go/logging"
 	"github.com/gofiber/fiber/v2"
 	v4 "github.com/versity/versitygw/aws/signer/v4"
+	"github.com/versity/versitygw/aws/signer/v4a"
 	"github.com/versity/versitygw/s3err"
 )
 
@@ -112,12 +116,25 @@ func (ar *AuthReader) validateSignature() error {
 
 const (
 	service = "s3"
+	// STSService is the signing service of the security token service
+	// requests
+	STSService = "sts"
+
+	// SigV4Algorithm is the algorithm of AWS SigV4 signed requests
+	SigV4Algorithm = "AWS4-HMAC-SHA256"
+	// SigV4aAlgorithm is the algorithm of AWS SigV4a (ECDSA P-256)
+	// signed requests
+	SigV4aAlgorithm = "AWS4-ECDSA-P256-SHA256"
 )
 
-// CheckValidSignature validates the ctx v4 auth signature
+// CheckValidSignature validates the ctx v4 or v4a auth signature
 func CheckValidSignature(ctx *fiber.Ctx, auth AuthData, secret, checksum string, tdate time.Time, contentLen int64, debug bool) error {
 	signedHdrs := strings.Split(auth.SignedHeaders, ";")
 
+	if auth.Algorithm == SigV4aAlgorithm {
+		return checkValidSignatureV4a(ctx, auth, secret, checksum, tdate, contentLen, signedHdrs, debug)
+	}
+
 	// Create a new http request instance from fasthttp request
 	req, err := createHttpRequestFromCtx(ctx, signedHdrs, contentLen)
 	if err != nil {
@@ -131,7 +148,7 @@ func CheckValidSignature(ctx *fiber.Ctx, auth AuthData, secret, checksum string,
 			AccessKeyID:     auth.Access,
 			SecretAccessKey: secret,
 		},
-		req, checksum, service, auth.Region, tdate, signedHdrs,
+		req, checksum, auth.signingService(), auth.Region, tdate, signedHdrs,
 		func(options *v4.SignerOptions) {
 			options.DisableURIPathEscaping = true
 			if debug {
@@ -155,6 +172,39 @@ func CheckValidSignature(ctx *fiber.Ctx, auth AuthData, secret, checksum string,
 	return nil
 }
 
+// checkValidSignatureV4a verifies the ctx v4a auth signature with the
+// public key derived from the account credentials
+func checkValidSignatureV4a(ctx *fiber.Ctx, auth AuthData, secret, checksum string, tdate time.Time, contentLen int64, signedHdrs []string, debug bool) error {
+	req, err := createHttpRequestFromCtx(ctx, signedHdrs, contentLen)
+	if err != nil {
+		return fmt.Errorf("create http request from context: %w", err)
+	}
+
+	creds, err := v4a.NewCredentials(auth.Access, secret)
+	if err != nil {
+		return err
+	}
+
+	signer := v4a.NewSigner()
+	err = signer.VerifyHTTP(req.Context(), creds, req, checksum, auth.signingService(),
+		auth.RegionSet, tdate, signedHdrs, auth.Signature,
+		func(options *v4a.SignerOptions) {
+			options.DisableURIPathEscaping = true
+			if debug {
+				options.LogSigning = true
+				options.Logger = logging.NewStandardLogger(os.Stderr)
+			}
+		})
+	if errors.Is(err, v4a.ErrSignatureDoesNotMatch) {
+		return s3err.GetAPIError(s3err.ErrSignatureDoesNotMatch)
+	}
+	if err != nil {
+		return fmt.Errorf("verify http request: %w", err)
+	}
+
+	return nil
+}
+
 // AuthData is the parsed authorization data from the header
 type AuthData struct {
 	Algorithm     string
@@ -163,6 +213,45 @@ type AuthData struct {
 	SignedHeaders string
 	Signature     string
 	Date          string
+	// Service is the signing service of the credential scope
+	Service string
+	// RegionSet is the set of regions a v4a signature is valid for,
+	// the credential scope of v4a signatures does not include a region
+	RegionSet []string
+}
+
+// signingService returns the signing service of the credential scope,
+// which defaults to s3
+func (a AuthData) signingService() string {
+	if a.Service == "" {
+		return service
+	}
+	return a.Service
+}
+
+// ParseRegionSet returns the regions of the v4a X-Amz-Region-Set header
+// or query parameter, the region set must include the gateway region.
+// The region set may include wildcards, such as "*" or "us-*".
+func ParseRegionSet(regionSet, region string) ([]string, error) {
+	if regionSet == "" {
+		return nil, s3err.GetAPIError(s3err.ErrMissingFields)
+	}
+
+	regions := strings.Split(regionSet, ",")
+	for i, r := range regions {
+		regions[i] = strings.TrimSpace(r)
+	}
+	for _, r := range regions {
+		if ok, _ := path.Match(strings.ToLower(r), region); ok {
+			return regions, nil
+		}
+	}
+
+	return nil, s3err.APIError{
+		Code:           "SignatureDoesNotMatch",
+		Description:    fmt.Sprintf("Credential should be scoped to a valid Region set including %v, not %v", region, regionSet),
+		HTTPStatusCode: http.StatusForbidden,
+	}
 }
 
 // ParseAuthorization returns the parsed fields for the aws v4 auth header
@@ -198,7 +287,7 @@ func ParseAuthorization(authorization string) (AuthData, error) {
 		return a, s3err.GetAPIError(s3err.ErrMissingFields)
 	}
 
-	var access, region, signedHeaders, signature, date string
+	var access, region, signedHeaders, signature, date, svc string
 
 	for _, kv := range kvPairs {
 		keyValue := strings.Split(kv, "=")
@@ -217,10 +306,15 @@ func ParseAuthorization(authorization string) (AuthData, error) {
 		switch key {
 		case "Credential":
 			creds := strings.Split(value, "/")
+			if algo == SigV4aAlgorithm && len(creds) == 4 {
+				// the v4a credential scope does not include a region,
+				// the regions are listed in the X-Amz-Region-Set header
+				creds = slices.Insert(creds, 2, "")
+			}
 			if len(creds) != 5 {
 				return a, s3err.GetAPIError(s3err.ErrCredMalformed)
 			}
-			if creds[3] != "s3" {
+			if creds[3] != service && creds[3] != STSService {
 				return a, s3err.GetAPIError(s3err.ErrSignatureIncorrService)
 			}
 			if creds[4] != "aws4_request" {
@@ -233,6 +327,7 @@ func ParseAuthorization(authorization string) (AuthData, error) {
 			access = creds[0]
 			date = creds[1]
 			region = creds[2]
+			svc = creds[3]
 		case "SignedHeaders":
 			signedHeaders = value
 		case "Signature":
@@ -247,6 +342,7 @@ func ParseAuthorization(authorization string) (AuthData, error) {
 		SignedHeaders: signedHeaders,
 		Signature:     signature,
 		Date:          date,
+		Service:       svc,
 	}, nil
 }
 
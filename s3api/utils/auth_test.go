@@ -36,6 +36,12 @@ func TestAuthParse(t *testing.T) {
 			algo:    "AWS4-HMAC-SHA256",
 			sig:     "37a35d96998d786113ad420c57c22c5433f6aca74f88f26566caa047fc3601c6",
 		},
+		{
+			name:    "sigv4a",
+			authstr: "AWS4-ECDSA-P256-SHA256 Credential=access_key/20240206/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date;x-amz-region-set, Signature=3045022100a4c4b0c6c1ad1d5c2a19e2de9cbb1e4ef1a1aef68e5ed19e5f7e0f3c2b2e0e3a022035a5e5f3c1d4e8e8c91e4b6e2c7f0a0e8c5f1e3d2b6a4e6c8f0e1d2c3b4a5f6e7d8",
+			algo:    "AWS4-ECDSA-P256-SHA256",
+			sig:     "3045022100a4c4b0c6c1ad1d5c2a19e2de9cbb1e4ef1a1aef68e5ed19e5f7e0f3c2b2e0e3a022035a5e5f3c1d4e8e8c91e4b6e2c7f0a0e8c5f1e3d2b6a4e6c8f0e1d2c3b4a5f6e7d8",
+		},
 	}
 
 	for _, v := range vectors {
@@ -54,6 +60,28 @@ func TestAuthParse(t *testing.T) {
 	}
 }
 
+func TestParseRegionSet(t *testing.T) {
+	tests := []struct {
+		regionSet string
+		valid     bool
+	}{
+		{regionSet: "us-east-1", valid: true},
+		{regionSet: "us-west-2, us-east-1", valid: true},
+		{regionSet: "*", valid: true},
+		{regionSet: "us-*", valid: true},
+		{regionSet: "us-west-2", valid: false},
+		{regionSet: "eu-*", valid: false},
+		{regionSet: "", valid: false},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseRegionSet(tt.regionSet, "us-east-1")
+		if (err == nil) != tt.valid {
+			t.Errorf("region set %q: expected valid %v, got %v", tt.regionSet, tt.valid, err)
+		}
+	}
+}
+
 // 2024/02/06 21:03:28 Request headers:
 // 2024/02/06 21:03:28 Host: 172.21.0.160:11000
 // 2024/02/06 21:03:28 User-Agent: S3 Browser/11.5.7 (https://s3browser.com)
@@ -15,7 +15,9 @@
 package utils
 
 import (
+	"bufio"
 	"bytes"
+	"crypto/ecdsa"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
@@ -24,91 +26,366 @@ import (
 	"hash"
 	"io"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/gofiber/fiber/v2"
+	"github.com/versity/versitygw/aws/signer/v4a"
+	"github.com/versity/versitygw/backend"
 	"github.com/versity/versitygw/s3err"
 )
 
 // chunked uploads described in:
 // https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-streaming.html
+// https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-streaming-trailers.html
 
 const (
-	chunkHdrStr       = ";chunk-signature="
+	chunkSigPrefix    = "chunk-signature="
 	chunkHdrDelim     = "\r\n"
 	zeroLenSig        = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
 	awsV4             = "AWS4"
 	awsS3Service      = "s3"
 	awsV4Request      = "aws4_request"
 	streamPayloadAlgo = "AWS4-HMAC-SHA256-PAYLOAD"
+	streamTrailerAlgo = "AWS4-HMAC-SHA256-TRAILER"
+	ecdsaPayloadAlgo  = "AWS4-ECDSA-P256-SHA256-PAYLOAD"
+	ecdsaTrailerAlgo  = "AWS4-ECDSA-P256-SHA256-TRAILER"
+	ecdsaSigPadding   = "*"
+	trailerSigHdr     = "x-amz-trailer-signature"
+	checksumHdrPrefix = "x-amz-checksum-"
+	streamingTrailer  = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD-TRAILER"
+	unsignedTrailer   = "STREAMING-UNSIGNED-PAYLOAD-TRAILER"
+	ecdsaPayload      = "STREAMING-AWS4-ECDSA-P256-SHA256-PAYLOAD"
+	ecdsaTrailer      = "STREAMING-AWS4-ECDSA-P256-SHA256-PAYLOAD-TRAILER"
 )
 
 // ChunkReader reads from chunked upload request body, and returns
 // object data stream
 type ChunkReader struct {
-	r                io.Reader
-	signingKey       []byte
-	prevSig          string
-	parsedSig        string
-	currentChunkSize int64
-	chunkDataLeft    int64
-	trailerExpected  int
-	stash            []byte
-	chunkHash        hash.Hash
-	strToSignPrefix  string
-	skipcheck        bool
+	r *bufio.Reader
+
+	// signed is set when every chunk and the trailer carry a
+	// signature chained from the seed signature of the request
+	signed     bool
+	signingKey []byte
+	// ecdsaKey is set for v4a signed uploads, the chunk and trailer
+	// signatures are ECDSA signatures verified with the public key
+	ecdsaKey        *ecdsa.PublicKey
+	prevSig         string
+	parsedSig       string
+	chunkHash       hash.Hash
+	strToSignPrefix string
+	trlToSignPrefix string
+	chunkDataLeft   int64
+	// decodedLeft is the object data declared in the
+	// x-amz-decoded-content-length header not yet in a chunk
+	decodedLeft int64
+
+	// trailer is the lower case name of the trailing checksum header
+	// declared with x-amz-trailer, empty if there is no trailer
+	trailer      string
+	checksum     backend.Checksum
+	checksumHash hash.Hash
+
+	err error
 }
 
 // NewChunkReader reads from request body io.Reader and parses out the
-// chunk metadata in stream. The headers are validated for proper signatures.
-// Reading from the chunk reader will read only the object data stream
-// without the chunk headers/trailers.
+// chunk metadata in stream. The chunk and trailer signatures and the
+// trailing checksum are validated while reading. Reading from the chunk
+// reader will read only the object data stream without the chunk
+// headers/trailers.
 func NewChunkReader(ctx *fiber.Ctx, r io.Reader, authdata AuthData, region, secret string, date time.Time) (*ChunkReader, error) {
-	return &ChunkReader{
-		r:          r,
+	decodedLength, err := strconv.ParseInt(ctx.Get("X-Amz-Decoded-Content-Length"), 10, 64)
+	if err != nil || decodedLength < 0 {
+		return nil, s3err.GetAPIError(s3err.ErrInvalidRequest)
+	}
+
+	scope := fmt.Sprintf("%s/%s/%s/%s",
+		date.Format(yyyymmdd),
+		region,
+		awsS3Service,
+		awsV4Request)
+
+	cr := &ChunkReader{
+		r:          bufio.NewReader(r),
+		signed:     true,
 		signingKey: getSigningKey(secret, region, date),
 		// the authdata.Signature is validated in the auth-reader,
 		// so we can use that here without any other checks
 		prevSig:         authdata.Signature,
 		chunkHash:       sha256.New(),
-		strToSignPrefix: getStringToSignPrefix(date, region),
-	}, nil
+		strToSignPrefix: getStringToSignPrefix(streamPayloadAlgo, date, scope),
+		trlToSignPrefix: getStringToSignPrefix(streamTrailerAlgo, date, scope),
+		decodedLeft:     decodedLength,
+	}
+
+	payload := ctx.Get("X-Amz-Content-Sha256")
+	switch payload {
+	case unsignedTrailer:
+		cr.signed = false
+	case ecdsaPayload, ecdsaTrailer:
+		creds, err := v4a.NewCredentials(authdata.Access, secret)
+		if err != nil {
+			return nil, err
+		}
+		// the v4a credential scope does not include a region
+		scope = fmt.Sprintf("%s/%s/%s",
+			date.Format(yyyymmdd),
+			awsS3Service,
+			awsV4Request)
+		cr.ecdsaKey = &creds.PrivateKey.PublicKey
+		cr.prevSig = strings.TrimRight(authdata.Signature, ecdsaSigPadding)
+		cr.strToSignPrefix = getStringToSignPrefix(ecdsaPayloadAlgo, date, scope)
+		cr.trlToSignPrefix = getStringToSignPrefix(ecdsaTrailerAlgo, date, scope)
+	}
+
+	if payload == unsignedTrailer || payload == streamingTrailer || payload == ecdsaTrailer {
+		trailer := strings.ToLower(ctx.Get("X-Amz-Trailer"))
+		algo, ok := strings.CutPrefix(trailer, checksumHdrPrefix)
+		if !ok {
+			return nil, s3err.GetAPIError(s3err.ErrMalformedTrailer)
+		}
+		cr.checksum.Algorithm = types.ChecksumAlgorithm(strings.ToUpper(algo))
+		h, err := backend.NewChecksumHash(cr.checksum.Algorithm)
+		if err != nil {
+			return nil, err
+		}
+		cr.trailer = trailer
+		cr.checksumHash = h
+	}
+
+	return cr, nil
 }
 
 // Read satisfies the io.Reader for this type
 func (cr *ChunkReader) Read(p []byte) (int, error) {
-	n, err := cr.r.Read(p)
-	if err != nil && err != io.EOF {
-		return n, err
+	if cr.err != nil {
+		return 0, cr.err
 	}
 
-	if cr.chunkDataLeft < int64(n) {
-		chunkSize := cr.chunkDataLeft
-		if chunkSize > 0 {
-			cr.chunkHash.Write(p[:chunkSize])
+	if cr.chunkDataLeft == 0 {
+		cr.err = cr.readChunkHeader()
+		if cr.err != nil {
+			return 0, cr.err
 		}
-		n, err := cr.parseAndRemoveChunkInfo(p[chunkSize:n])
-		n += int(chunkSize)
-		return n, err
 	}
 
+	if int64(len(p)) > cr.chunkDataLeft {
+		p = p[:cr.chunkDataLeft]
+	}
+
+	n, err := cr.r.Read(p)
 	cr.chunkDataLeft -= int64(n)
 	cr.chunkHash.Write(p[:n])
-	return n, err
+	if cr.checksumHash != nil {
+		cr.checksumHash.Write(p[:n])
+	}
+	if err == io.EOF {
+		err = s3err.GetAPIError(s3err.ErrIncompleteBody)
+	}
+	if err != nil {
+		cr.err = err
+		return n, err
+	}
+
+	if cr.chunkDataLeft == 0 {
+		// the chunk data is followed by the delimiter before
+		// the next chunk header
+		line, err := cr.readLine()
+		if err == nil && line != "" {
+			err = s3err.GetAPIError(s3err.ErrIncompleteBody)
+		}
+		if err == nil {
+			err = cr.verifyChunkSignature()
+		}
+		if err != nil {
+			cr.err = err
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+// readChunkHeader parses the next chunk header, the final zero length
+// chunk is followed by the trailer which is validated before io.EOF is
+// returned
+func (cr *ChunkReader) readChunkHeader() error {
+	line, err := cr.readLine()
+	if err == io.EOF {
+		return s3err.GetAPIError(s3err.ErrIncompleteBody)
+	}
+	if err != nil {
+		return err
+	}
+
+	sizeStr, ext, _ := strings.Cut(line, ";")
+	chunkSize, err := strconv.ParseInt(sizeStr, 16, 64)
+	if err != nil || chunkSize < 0 {
+		return errInvalidChunkFormat
+	}
+
+	if cr.signed {
+		sig, ok := strings.CutPrefix(ext, chunkSigPrefix)
+		if !ok {
+			return errInvalidChunkFormat
+		}
+		cr.parsedSig = sig
+	}
+
+	// the chunks must add up to the decoded content length that
+	// the object size is taken from
+	if chunkSize > cr.decodedLeft {
+		return s3err.GetAPIError(s3err.ErrIncompleteBody)
+	}
+	cr.decodedLeft -= chunkSize
+
+	cr.chunkDataLeft = chunkSize
+	if chunkSize != 0 {
+		return nil
+	}
+	if cr.decodedLeft != 0 {
+		return s3err.GetAPIError(s3err.ErrIncompleteBody)
+	}
+
+	err = cr.verifyChunkSignature()
+	if err != nil {
+		return err
+	}
+
+	err = cr.readTrailer()
+	if err != nil {
+		return err
+	}
+
+	// consume the rest of the body so that the wrapped readers see
+	// the end of the stream and can finish their validations
+	_, err = io.Copy(io.Discard, cr.r)
+	if err != nil {
+		return err
+	}
+
+	return io.EOF
+}
+
+// verifyChunkSignature checks the signature of the chunk that was just
+// read, each chunk signature is chained to the previous signature
+func (cr *ChunkReader) verifyChunkSignature() error {
+	if !cr.signed {
+		return nil
+	}
+
+	chunkhash := cr.chunkHash.Sum(nil)
+	cr.chunkHash.Reset()
+
+	sigstr := getChunkStringToSign(cr.strToSignPrefix, cr.prevSig, chunkhash)
+	if cr.ecdsaKey != nil {
+		// v4a chunk signatures are padded to a fixed length
+		cr.prevSig = strings.TrimRight(cr.parsedSig, ecdsaSigPadding)
+		return verifyECDSASignature(cr.ecdsaKey, sigstr, cr.prevSig)
+	}
+	cr.prevSig = hex.EncodeToString(hmac256(cr.signingKey, []byte(sigstr)))
+
+	if !hmac.Equal([]byte(cr.prevSig), []byte(cr.parsedSig)) {
+		return s3err.GetAPIError(s3err.ErrSignatureDoesNotMatch)
+	}
+	return nil
+}
+
+// readTrailer parses the trailing headers following the final chunk up
+// to the terminating empty line. The trailer signature covers the
+// trailing headers and is chained to the final chunk signature.
+func (cr *ChunkReader) readTrailer() error {
+	var canonical strings.Builder
+	var signature string
+	for {
+		line, err := cr.readLine()
+		if err == io.EOF && cr.trailer == "" {
+			// some clients leave out the final delimiter when
+			// there are no trailing headers
+			break
+		}
+		if err == io.EOF {
+			return s3err.GetAPIError(s3err.ErrMalformedTrailer)
+		}
+		if err != nil {
+			return err
+		}
+		if line == "" {
+			break
+		}
+
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return s3err.GetAPIError(s3err.ErrMalformedTrailer)
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		value = strings.TrimSpace(value)
+
+		if name == trailerSigHdr {
+			signature = value
+			continue
+		}
+		canonical.WriteString(name + ":" + value + "\n")
+		if name == cr.trailer {
+			cr.checksum.Value = value
+		}
+	}
+
+	if cr.trailer == "" {
+		return nil
+	}
+
+	if cr.signed {
+		trailerHash := sha256.Sum256([]byte(canonical.String()))
+		sigstr := getTrailerStringToSign(cr.trlToSignPrefix, cr.prevSig, trailerHash[:])
+		if cr.ecdsaKey != nil {
+			err := verifyECDSASignature(cr.ecdsaKey, sigstr, strings.TrimRight(signature, ecdsaSigPadding))
+			if err != nil {
+				return err
+			}
+		} else {
+			expected := hex.EncodeToString(hmac256(cr.signingKey, []byte(sigstr)))
+			if !hmac.Equal([]byte(expected), []byte(signature)) {
+				return s3err.GetAPIError(s3err.ErrSignatureDoesNotMatch)
+			}
+		}
+	}
+
+	if cr.checksum.Value == "" {
+		return s3err.GetAPIError(s3err.ErrMalformedTrailer)
+	}
+
+	return cr.checksum.Verify(cr.checksumHash.Sum(nil))
+}
+
+// readLine returns the next CRLF terminated line without the delimiter,
+// io.EOF is only returned at the end of the body
+func (cr *ChunkReader) readLine() (string, error) {
+	line, err := cr.r.ReadSlice('\n')
+	if err == io.EOF && len(line) == 0 {
+		return "", io.EOF
+	}
+	if err == io.EOF || errors.Is(err, bufio.ErrBufferFull) {
+		return "", errInvalidChunkFormat
+	}
+	if err != nil {
+		return "", err
+	}
+	if !bytes.HasSuffix(line, []byte(chunkHdrDelim)) {
+		return "", errInvalidChunkFormat
+	}
+	return string(line[:len(line)-len(chunkHdrDelim)]), nil
 }
 
 // https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-streaming.html#sigv4-chunked-body-definition
 // This part is the same for all chunks,
 // only the previous signature and hash of current chunk changes
-func getStringToSignPrefix(date time.Time, region string) string {
-	credentialScope := fmt.Sprintf("%s/%s/%s/%s",
-		date.Format("20060102"),
-		region,
-		awsS3Service,
-		awsV4Request)
-
+func getStringToSignPrefix(algo string, date time.Time, credentialScope string) string {
 	return fmt.Sprintf("%s\n%s\n%s",
-		streamPayloadAlgo,
+		algo,
 		date.Format("20060102T150405Z"),
 		credentialScope)
 }
@@ -125,80 +402,23 @@ func getChunkStringToSign(prefix, prevSig string, chunkHash []byte) string {
 		hex.EncodeToString(chunkHash))
 }
 
-// The provided p should have all of the previous chunk data and trailer
-// consumed already. The positioning here is expected that p[0] starts the
-// new chunk size with the ";chunk-signature=" following. The only exception
-// is if we started consuming the trailer, but hit the end of the read buffer.
-// In this case, parseAndRemoveChunkInfo is called with skipcheck=true to
-// finish consuming the final trailer bytes.
-// This parses the chunk metadata in situ without allocating an extra buffer.
-// It will just read and validate the chunk metadata and then move the
-// following chunk data to overwrite the metadata in the provided buffer.
-func (cr *ChunkReader) parseAndRemoveChunkInfo(p []byte) (int, error) {
-	n := len(p)
-
-	if !cr.skipcheck && cr.parsedSig != "" {
-		chunkhash := cr.chunkHash.Sum(nil)
-		cr.chunkHash.Reset()
-
-		sigstr := getChunkStringToSign(cr.strToSignPrefix, cr.prevSig, chunkhash)
-		cr.prevSig = hex.EncodeToString(hmac256(cr.signingKey, []byte(sigstr)))
-
-		if cr.currentChunkSize != 0 && cr.prevSig != cr.parsedSig {
-			return 0, s3err.GetAPIError(s3err.ErrSignatureDoesNotMatch)
-		}
-	}
-
-	if cr.trailerExpected != 0 {
-		if len(p) < len(chunkHdrDelim) {
-			// This is the special case where we need to consume the
-			// trailer, but instead hit the end of the buffer. The
-			// subsequent call will finish consuming the trailer.
-			cr.chunkDataLeft = 0
-			cr.trailerExpected -= len(p)
-			cr.skipcheck = true
-			return 0, nil
-		}
-		// move data up to remove trailer
-		copy(p, p[cr.trailerExpected:])
-		n -= cr.trailerExpected
-	}
-
-	cr.skipcheck = false
-
-	chunkSize, sig, bufOffset, err := cr.parseChunkHeaderBytes(p[:n])
-	cr.currentChunkSize = chunkSize
-	cr.parsedSig = sig
-	if err == errskipHeader {
-		cr.chunkDataLeft = 0
-		return 0, nil
-	}
-	if err != nil {
-		return 0, err
-	}
-	if chunkSize == 0 {
-		return 0, io.EOF
-	}
-
-	cr.trailerExpected = len(chunkHdrDelim)
-
-	// move data up to remove chunk header
-	copy(p, p[bufOffset:n])
-	n -= bufOffset
+// https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-streaming-trailers.html
+// The trailer signature is calculated from the signature of the final
+// chunk and the hash of the trailing headers.
+func getTrailerStringToSign(prefix, prevSig string, trailerHash []byte) string {
+	return fmt.Sprintf("%s\n%s\n%s",
+		prefix,
+		prevSig,
+		hex.EncodeToString(trailerHash))
+}
 
-	// if remaining buffer larger than chunk data,
-	// parse next header in buffer
-	if int64(n) > chunkSize {
-		cr.chunkDataLeft = 0
-		cr.chunkHash.Write(p[:chunkSize])
-		n, err := cr.parseAndRemoveChunkInfo(p[chunkSize:n])
-		return n + int(chunkSize), err
-	} else {
-		cr.chunkDataLeft = chunkSize - int64(n)
-		cr.chunkHash.Write(p[:n])
+// verifyECDSASignature verifies a v4a chunk or trailer signature
+func verifyECDSASignature(key *ecdsa.PublicKey, sigstr, signature string) error {
+	err := v4a.VerifyString(key, sigstr, signature)
+	if errors.Is(err, v4a.ErrSignatureDoesNotMatch) {
+		return s3err.GetAPIError(s3err.ErrSignatureDoesNotMatch)
 	}
-
-	return n, nil
+	return err
 }
 
 // https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html
@@ -219,51 +439,5 @@ func hmac256(key []byte, data []byte) []byte {
 }
 
 var (
-	errInvalidChunkFormat = errors.New("invalid chunk header format")
-	errskipHeader         = errors.New("skip to next header")
+	errInvalidChunkFormat = s3err.GetAPIError(s3err.ErrIncompleteBody)
 )
-
-const (
-	maxHeaderSize = 1024
-)
-
-// Theis returns the chunk payload size, signature, data start offset, and
-// error if any. See the AWS documentation for the chunk header format. The
-// header[0] byte is expected to be the first byte of the chunk size here.
-func (cr *ChunkReader) parseChunkHeaderBytes(header []byte) (int64, string, int, error) {
-	if cr.stash != nil {
-		tmp := make([]byte, maxHeaderSize)
-		copy(tmp, cr.stash)
-		copy(tmp[len(cr.stash):], header)
-		header = tmp
-		cr.stash = nil
-	}
-
-	semicolonIndex := bytes.Index(header, []byte(chunkHdrStr))
-	if semicolonIndex == -1 {
-		cr.stash = make([]byte, len(header))
-		copy(cr.stash, header)
-		cr.trailerExpected = 0
-		return 0, "", 0, errskipHeader
-	}
-
-	sigIndex := semicolonIndex + len(chunkHdrStr)
-	sigEndIndex := bytes.Index(header[sigIndex:], []byte(chunkHdrDelim))
-	if sigEndIndex == -1 {
-		cr.stash = make([]byte, len(header))
-		copy(cr.stash, header)
-		cr.trailerExpected = 0
-		return 0, "", 0, errskipHeader
-	}
-
-	chunkSizeBytes := header[:semicolonIndex]
-	chunkSize, err := strconv.ParseInt(string(chunkSizeBytes), 16, 64)
-	if err != nil {
-		return 0, "", 0, errInvalidChunkFormat
-	}
-
-	signature := string(header[sigIndex:(sigIndex + sigEndIndex)])
-	dataStartOffset := sigIndex + sigEndIndex + len(chunkHdrDelim)
-
-	return chunkSize, signature, dataStartOffset, nil
-}
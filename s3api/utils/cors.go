@@ -0,0 +1,134 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package utils
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strings"
+
+	"github.com/versity/versitygw/s3err"
+	"github.com/versity/versitygw/s3response"
+)
+
+var corsMethods = map[string]struct{}{
+	http.MethodGet:    {},
+	http.MethodPut:    {},
+	http.MethodPost:   {},
+	http.MethodDelete: {},
+	http.MethodHead:   {},
+}
+
+// ParseCORSConfiguration parses and validates a bucket CORS configuration
+func ParseCORSConfiguration(data []byte) (*s3response.CORSConfiguration, error) {
+	// the request body is not required to set the S3 namespace
+	var input struct {
+		Rules []s3response.CORSRule `xml:"CORSRule"`
+	}
+	err := xml.Unmarshal(data, &input)
+	if err != nil {
+		return nil, s3err.GetAPIError(s3err.ErrMalformedXML)
+	}
+	config := s3response.CORSConfiguration{Rules: input.Rules}
+
+	if len(config.Rules) == 0 || len(config.Rules) > 100 {
+		return nil, s3err.GetAPIError(s3err.ErrMalformedXML)
+	}
+
+	for _, rule := range config.Rules {
+		if rule.ID != nil && len(*rule.ID) > 255 {
+			return nil, s3err.GetAPIError(s3err.ErrInvalidRequest)
+		}
+		if len(rule.AllowedMethods) == 0 || len(rule.AllowedOrigins) == 0 {
+			return nil, s3err.GetAPIError(s3err.ErrMalformedXML)
+		}
+		for _, method := range rule.AllowedMethods {
+			if _, ok := corsMethods[method]; !ok {
+				return nil, s3err.GetAPIError(s3err.ErrInvalidCORSMethod)
+			}
+		}
+		for _, origin := range rule.AllowedOrigins {
+			if strings.Count(origin, "*") > 1 {
+				return nil, s3err.GetAPIError(s3err.ErrInvalidRequest)
+			}
+		}
+		for _, header := range rule.AllowedHeaders {
+			if strings.Count(header, "*") > 1 {
+				return nil, s3err.GetAPIError(s3err.ErrInvalidRequest)
+			}
+		}
+	}
+
+	return &config, nil
+}
+
+// MatchCORSRule returns the first rule of the configuration that allows
+// the origin, method and request headers, or nil if no rule matches
+func MatchCORSRule(config *s3response.CORSConfiguration, origin, method string, headers []string) *s3response.CORSRule {
+	for i, rule := range config.Rules {
+		if !matchAnyWildcard(rule.AllowedOrigins, origin, false) {
+			continue
+		}
+		if !containsString(rule.AllowedMethods, method) {
+			continue
+		}
+		allowed := true
+		for _, header := range headers {
+			if !matchAnyWildcard(rule.AllowedHeaders, header, true) {
+				allowed = false
+				break
+			}
+		}
+		if allowed {
+			return &config.Rules[i]
+		}
+	}
+	return nil
+}
+
+// matchAnyWildcard returns true if the value matches any of the
+// patterns, where each pattern may contain a single "*" wildcard
+func matchAnyWildcard(patterns []string, value string, foldCase bool) bool {
+	if foldCase {
+		value = strings.ToLower(value)
+	}
+	for _, pattern := range patterns {
+		if foldCase {
+			pattern = strings.ToLower(pattern)
+		}
+		prefix, suffix, found := strings.Cut(pattern, "*")
+		if !found {
+			if pattern == value {
+				return true
+			}
+			continue
+		}
+		if len(value) >= len(prefix)+len(suffix) &&
+			strings.HasPrefix(value, prefix) &&
+			strings.HasSuffix(value, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, value string) bool {
+	for _, s := range list {
+		if s == value {
+			return true
+		}
+	}
+	return false
+}
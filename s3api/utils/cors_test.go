@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"testing"
+)
+
+func TestParseCORSConfiguration(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{
+			name:    "Parse-cors-malformed-xml",
+			data:    "<CORSConfiguration>",
+			wantErr: true,
+		},
+		{
+			name:    "Parse-cors-no-rules",
+			data:    "<CORSConfiguration></CORSConfiguration>",
+			wantErr: true,
+		},
+		{
+			name:    "Parse-cors-missing-origin",
+			data:    "<CORSConfiguration><CORSRule><AllowedMethod>GET</AllowedMethod></CORSRule></CORSConfiguration>",
+			wantErr: true,
+		},
+		{
+			name:    "Parse-cors-invalid-method",
+			data:    "<CORSConfiguration><CORSRule><AllowedOrigin>*</AllowedOrigin><AllowedMethod>PATCH</AllowedMethod></CORSRule></CORSConfiguration>",
+			wantErr: true,
+		},
+		{
+			name:    "Parse-cors-multiple-origin-wildcards",
+			data:    "<CORSConfiguration><CORSRule><AllowedOrigin>http://*.*.com</AllowedOrigin><AllowedMethod>GET</AllowedMethod></CORSRule></CORSConfiguration>",
+			wantErr: true,
+		},
+		{
+			name:    "Parse-cors-success",
+			data:    "<CORSConfiguration><CORSRule><AllowedOrigin>http://*.example.com</AllowedOrigin><AllowedMethod>GET</AllowedMethod><AllowedMethod>PUT</AllowedMethod><AllowedHeader>*</AllowedHeader></CORSRule></CORSConfiguration>",
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseCORSConfiguration([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseCORSConfiguration() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMatchCORSRule(t *testing.T) {
+	config, err := ParseCORSConfiguration([]byte(`
+	<CORSConfiguration>
+		<CORSRule>
+			<ID>uploads</ID>
+			<AllowedOrigin>https://*.example.com</AllowedOrigin>
+			<AllowedMethod>PUT</AllowedMethod>
+			<AllowedHeader>Content-*</AllowedHeader>
+		</CORSRule>
+		<CORSRule>
+			<ID>reads</ID>
+			<AllowedOrigin>*</AllowedOrigin>
+			<AllowedMethod>GET</AllowedMethod>
+		</CORSRule>
+	</CORSConfiguration>
+	`))
+	if err != nil {
+		t.Fatalf("ParseCORSConfiguration() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		origin  string
+		method  string
+		headers []string
+		wantID  string
+	}{
+		{
+			name:    "Match-cors-wildcard-origin-and-header",
+			origin:  "https://app.example.com",
+			method:  "PUT",
+			headers: []string{"content-type"},
+			wantID:  "uploads",
+		},
+		{
+			name:    "Match-cors-header-not-allowed",
+			origin:  "https://app.example.com",
+			method:  "PUT",
+			headers: []string{"x-amz-meta-foo"},
+			wantID:  "",
+		},
+		{
+			name:   "Match-cors-origin-not-allowed",
+			origin: "https://example.org",
+			method: "PUT",
+			wantID: "",
+		},
+		{
+			name:   "Match-cors-any-origin",
+			origin: "https://example.org",
+			method: "GET",
+			wantID: "reads",
+		},
+		{
+			name:   "Match-cors-method-not-allowed",
+			origin: "https://example.org",
+			method: "DELETE",
+			wantID: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := MatchCORSRule(config, tt.origin, tt.method, tt.headers)
+			gotID := ""
+			if rule != nil {
+				gotID = *rule.ID
+			}
+			if gotID != tt.wantID {
+				t.Errorf("MatchCORSRule() rule = %q, want %q", gotID, tt.wantID)
+			}
+		})
+	}
+}
@@ -0,0 +1,416 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package utils
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/versity/versitygw/aws/signer/v4"
+	"github.com/versity/versitygw/s3err"
+)
+
+// maxPostFormMemory is the size of the uploaded file kept in memory,
+// larger files are buffered in temporary files until the upload is
+// written to the backend
+const maxPostFormMemory = 32 << 20
+
+// PostForm is a parsed browser based POST Object upload
+type PostForm struct {
+	// Fields are the form fields by lower case field name
+	Fields map[string]string
+	// FileName is the file name of the uploaded file
+	FileName string
+	// Size is the size of the uploaded file
+	Size int64
+
+	form  *multipart.Form
+	file  *multipart.FileHeader
+	value string
+}
+
+// ParsePostForm reads the multipart/form-data body of a POST Object
+// request. The form must contain exactly one "file" field, any
+// "${filename}" in the key is replaced with the name of the uploaded file.
+func ParsePostForm(r io.Reader, boundary string) (*PostForm, error) {
+	if boundary == "" {
+		return nil, s3err.GetAPIError(s3err.ErrMalformedPOSTRequest)
+	}
+	form, err := multipart.NewReader(r, boundary).ReadForm(maxPostFormMemory)
+	if err != nil {
+		return nil, s3err.GetAPIError(s3err.ErrMalformedPOSTRequest)
+	}
+
+	pf := &PostForm{
+		Fields: make(map[string]string),
+		form:   form,
+	}
+
+	files := 0
+	for name, values := range form.Value {
+		name = strings.ToLower(name)
+		if name == "file" {
+			// a file part without a file name
+			files += len(values)
+			pf.value = values[0]
+			pf.Size = int64(len(pf.value))
+			continue
+		}
+		pf.Fields[name] = values[0]
+	}
+	for name, fhs := range form.File {
+		if strings.ToLower(name) != "file" {
+			continue
+		}
+		files += len(fhs)
+		pf.file = fhs[0]
+		pf.FileName = path.Base(fhs[0].Filename)
+		pf.Size = fhs[0].Size
+	}
+	if files != 1 {
+		form.RemoveAll()
+		return nil, s3err.GetAPIError(s3err.ErrPOSTFileRequired)
+	}
+
+	if key, ok := pf.Fields["key"]; ok {
+		pf.Fields["key"] = strings.ReplaceAll(key, "${filename}", pf.FileName)
+	}
+
+	return pf, nil
+}
+
+// Open returns the contents of the uploaded file
+func (f *PostForm) Open() (io.ReadCloser, error) {
+	if f.file == nil {
+		return io.NopCloser(strings.NewReader(f.value)), nil
+	}
+	return f.file.Open()
+}
+
+// Close removes any temporary files of the form
+func (f *PostForm) Close() error {
+	return f.form.RemoveAll()
+}
+
+// PostFieldRequired is the error for a POST Object form that is missing
+// a required field
+func PostFieldRequired(name string) error {
+	return s3err.APIError{
+		Code:           "InvalidArgument",
+		Description:    fmt.Sprintf("Bucket POST must contain a field named '%v'.  If it is specified, please check the order of the fields.", name),
+		HTTPStatusCode: http.StatusBadRequest,
+	}
+}
+
+// AuthData parses and validates the signature fields of a SigV4 signed
+// POST Object form
+func (f *PostForm) AuthData(region string) (AuthData, error) {
+	a := AuthData{}
+
+	for _, name := range []string{"policy", "x-amz-algorithm", "x-amz-credential", "x-amz-date"} {
+		if f.Fields[name] == "" {
+			return a, PostFieldRequired(name)
+		}
+	}
+
+	if f.Fields["x-amz-algorithm"] != "AWS4-HMAC-SHA256" {
+		return a, s3err.GetAPIError(s3err.ErrSignatureVersionNotSupported)
+	}
+
+	creds := strings.Split(f.Fields["x-amz-credential"], "/")
+	if len(creds) != 5 {
+		return a, s3err.GetAPIError(s3err.ErrCredMalformed)
+	}
+	if creds[3] != service {
+		return a, s3err.GetAPIError(s3err.ErrSignatureIncorrService)
+	}
+	if creds[4] != "aws4_request" {
+		return a, s3err.GetAPIError(s3err.ErrSignatureTerminationStr)
+	}
+	_, err := time.Parse(yyyymmdd, creds[1])
+	if err != nil {
+		return a, s3err.GetAPIError(s3err.ErrSignatureDateDoesNotMatch)
+	}
+
+	date := f.Fields["x-amz-date"]
+	_, err = time.Parse(iso8601Format, date)
+	if err != nil {
+		return a, s3err.GetAPIError(s3err.ErrMalformedDate)
+	}
+	if date[:8] != creds[1] {
+		return a, s3err.GetAPIError(s3err.ErrSignatureDateDoesNotMatch)
+	}
+
+	if creds[2] != region {
+		return a, s3err.APIError{
+			Code:           "SignatureDoesNotMatch",
+			Description:    fmt.Sprintf("Credential should be scoped to a valid Region, not %v", creds[2]),
+			HTTPStatusCode: http.StatusForbidden,
+		}
+	}
+
+	return AuthData{
+		Algorithm: f.Fields["x-amz-algorithm"],
+		Access:    creds[0],
+		Region:    creds[2],
+		Signature: f.Fields["x-amz-signature"],
+		Date:      date,
+	}, nil
+}
+
+// CheckPostPolicySignature validates the signature of the base64
+// encoded policy of a POST Object form
+func CheckPostPolicySignature(auth AuthData, secret, policy string) error {
+	date, _ := time.Parse(iso8601Format, auth.Date)
+
+	signer := v4.NewSigner()
+	signature := signer.SignPostPolicy(aws.Credentials{
+		AccessKeyID:     auth.Access,
+		SecretAccessKey: secret,
+	}, policy, service, auth.Region, date)
+	if !hmac.Equal([]byte(signature), []byte(auth.Signature)) {
+		return s3err.GetAPIError(s3err.ErrSignatureDoesNotMatch)
+	}
+
+	return nil
+}
+
+const (
+	policyCondEq                 = "eq"
+	policyCondStartsWith         = "starts-with"
+	policyCondContentLengthRange = "content-length-range"
+)
+
+// PostPolicy is a decoded POST Object policy document
+type PostPolicy struct {
+	Expiration time.Time
+	Conditions []PostPolicyCondition
+}
+
+// PostPolicyCondition is a single condition of the policy, Field is
+// the lower case form field name the condition applies to
+type PostPolicyCondition struct {
+	Operator string
+	Field    string
+	Value    string
+	Min      int64
+	Max      int64
+}
+
+// ParsePostPolicy decodes and validates the base64 encoded policy of a
+// POST Object form
+func ParsePostPolicy(policy string) (*PostPolicy, error) {
+	data, err := base64.StdEncoding.DecodeString(policy)
+	if err != nil {
+		return nil, s3err.GetAPIError(s3err.ErrInvalidPolicyDocument)
+	}
+
+	var doc struct {
+		Expiration string `json:"expiration"`
+		Conditions []any  `json:"conditions"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, s3err.GetAPIError(s3err.ErrInvalidPolicyDocument)
+	}
+
+	exp, err := time.Parse(time.RFC3339Nano, doc.Expiration)
+	if err != nil {
+		return nil, invalidPolicy("Policy expiration must be an ISO8601 formatted date")
+	}
+
+	p := &PostPolicy{Expiration: exp}
+	for _, c := range doc.Conditions {
+		switch c := c.(type) {
+		case map[string]any:
+			// {"field": "value"} is an exact match
+			for field, v := range c {
+				value, ok := v.(string)
+				if !ok {
+					return nil, invalidPolicy(fmt.Sprintf("Condition value of %v must be a string", field))
+				}
+				p.Conditions = append(p.Conditions, PostPolicyCondition{
+					Operator: policyCondEq,
+					Field:    strings.ToLower(field),
+					Value:    value,
+				})
+			}
+		case []any:
+			cond, err := parsePolicyCondition(c)
+			if err != nil {
+				return nil, err
+			}
+			p.Conditions = append(p.Conditions, cond)
+		default:
+			return nil, invalidPolicy("Conditions must be objects or arrays")
+		}
+	}
+
+	return p, nil
+}
+
+func parsePolicyCondition(c []any) (PostPolicyCondition, error) {
+	if len(c) != 3 {
+		return PostPolicyCondition{}, invalidPolicy("Condition arrays must have exactly 3 elements")
+	}
+	op, ok := c[0].(string)
+	if !ok {
+		return PostPolicyCondition{}, invalidPolicy("Condition operator must be a string")
+	}
+	op = strings.ToLower(op)
+
+	if op == policyCondContentLengthRange {
+		min, err := policyInt(c[1])
+		if err != nil {
+			return PostPolicyCondition{}, err
+		}
+		max, err := policyInt(c[2])
+		if err != nil {
+			return PostPolicyCondition{}, err
+		}
+		if min < 0 || max < min {
+			return PostPolicyCondition{}, invalidPolicy("Invalid content-length-range")
+		}
+		return PostPolicyCondition{Operator: op, Min: min, Max: max}, nil
+	}
+
+	if op != policyCondEq && op != policyCondStartsWith {
+		return PostPolicyCondition{}, invalidPolicy(fmt.Sprintf("Invalid condition operator %v", op))
+	}
+	field, ok := c[1].(string)
+	if !ok || !strings.HasPrefix(field, "$") {
+		return PostPolicyCondition{}, invalidPolicy("Condition field names must start with $")
+	}
+	value, ok := c[2].(string)
+	if !ok {
+		return PostPolicyCondition{}, invalidPolicy(fmt.Sprintf("Condition value of %v must be a string", field))
+	}
+
+	return PostPolicyCondition{
+		Operator: op,
+		Field:    strings.ToLower(field[1:]),
+		Value:    value,
+	}, nil
+}
+
+func policyInt(v any) (int64, error) {
+	var s string
+	switch v := v.(type) {
+	case json.Number:
+		s = v.String()
+	case string:
+		s = v
+	}
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, invalidPolicy("Invalid content-length-range")
+	}
+	return i, nil
+}
+
+func invalidPolicy(msg string) error {
+	return s3err.APIError{
+		Code:           "InvalidPolicyDocument",
+		Description:    "Invalid Policy: " + msg + ".",
+		HTTPStatusCode: http.StatusBadRequest,
+	}
+}
+
+// these form fields are not required to be covered by a condition
+func isPolicyExemptField(name string) bool {
+	switch name {
+	case "policy", "x-amz-signature", "file":
+		return true
+	}
+	return strings.HasPrefix(name, "x-ignore-")
+}
+
+// Check verifies the form against the policy. The bucket of the request
+// is checked as a form field, every form field must be covered by a
+// condition of the policy.
+func (p *PostPolicy) Check(form *PostForm, bucket string, now time.Time) error {
+	if !now.Before(p.Expiration) {
+		return s3err.GetAPIError(s3err.ErrPostPolicyExpired)
+	}
+
+	fields := make(map[string]string, len(form.Fields)+1)
+	for name, value := range form.Fields {
+		fields[name] = value
+	}
+	fields["bucket"] = bucket
+
+	covered := make(map[string]bool)
+	for _, c := range p.Conditions {
+		switch c.Operator {
+		case policyCondContentLengthRange:
+			if form.Size < c.Min {
+				return s3err.GetAPIError(s3err.ErrEntityTooSmall)
+			}
+			if form.Size > c.Max {
+				return s3err.GetAPIError(s3err.ErrEntityTooLarge)
+			}
+			continue
+		case policyCondEq:
+			if fields[c.Field] != c.Value {
+				return policyConditionFailed(c)
+			}
+		case policyCondStartsWith:
+			values := []string{fields[c.Field]}
+			if c.Field == "content-type" {
+				// each of a comma separated list of content types
+				// is checked against the prefix
+				values = strings.Split(fields[c.Field], ",")
+			}
+			for _, v := range values {
+				if !strings.HasPrefix(strings.TrimSpace(v), c.Value) {
+					return policyConditionFailed(c)
+				}
+			}
+		}
+		covered[c.Field] = true
+	}
+
+	for name := range fields {
+		if !covered[name] && !isPolicyExemptField(name) {
+			return s3err.APIError{
+				Code:           "AccessDenied",
+				Description:    fmt.Sprintf("Invalid according to Policy: Extra input fields: %v", name),
+				HTTPStatusCode: http.StatusForbidden,
+			}
+		}
+	}
+
+	return nil
+}
+
+func policyConditionFailed(c PostPolicyCondition) error {
+	return s3err.APIError{
+		Code:           "AccessDenied",
+		Description:    fmt.Sprintf("Invalid according to Policy: Policy Condition failed: [\"%v\", \"$%v\", \"%v\"]", c.Operator, c.Field, c.Value),
+		HTTPStatusCode: http.StatusForbidden,
+	}
+}
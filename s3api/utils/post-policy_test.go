@@ -0,0 +1,203 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime/multipart"
+	"testing"
+	"time"
+
+	"github.com/versity/versitygw/s3err"
+)
+
+func buildPostForm(t *testing.T, fields [][2]string, file string) (*bytes.Buffer, string) {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, f := range fields {
+		if err := mw.WriteField(f[0], f[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fw, err := mw.CreateFormFile("file", "photo.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(fw, file); err != nil {
+		t.Fatal(err)
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &body, mw.Boundary()
+}
+
+func TestParsePostForm(t *testing.T) {
+	body, boundary := buildPostForm(t, [][2]string{
+		{"Key", "uploads/${filename}"},
+		{"X-Amz-Meta-Color", "blue"},
+	}, "file data")
+
+	form, err := ParsePostForm(body, boundary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer form.Close()
+
+	if form.Fields["key"] != "uploads/photo.jpg" {
+		t.Errorf("got key %q", form.Fields["key"])
+	}
+	if form.Fields["x-amz-meta-color"] != "blue" {
+		t.Errorf("got metadata %q", form.Fields["x-amz-meta-color"])
+	}
+	if form.Size != 9 {
+		t.Errorf("got size %v", form.Size)
+	}
+	rdr, err := form.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(rdr)
+	rdr.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "file data" {
+		t.Errorf("got file data %q", data)
+	}
+
+	// no file field
+	var noFile bytes.Buffer
+	mw := multipart.NewWriter(&noFile)
+	mw.WriteField("key", "obj")
+	mw.Close()
+	_, err = ParsePostForm(&noFile, mw.Boundary())
+	if !errors.Is(err, s3err.GetAPIError(s3err.ErrPOSTFileRequired)) {
+		t.Errorf("expected file required error, got %v", err)
+	}
+
+	_, err = ParsePostForm(bytes.NewReader([]byte("not a form")), "boundary")
+	if !errors.Is(err, s3err.GetAPIError(s3err.ErrMalformedPOSTRequest)) {
+		t.Errorf("expected malformed request error, got %v", err)
+	}
+}
+
+func TestPostPolicy(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	policy := func(doc string) string {
+		return base64.StdEncoding.EncodeToString([]byte(doc))
+	}
+	form := &PostForm{
+		Fields: map[string]string{
+			"key":                   "user/alice/photo.jpg",
+			"content-type":          "image/jpeg",
+			"success_action_status": "201",
+			"x-amz-signature":       "sig",
+			"policy":                "policy",
+			"x-ignore-note":         "ignored",
+		},
+		Size: 1024,
+	}
+	const conditions = `{"bucket": "photos"},
+		["starts-with", "$key", "user/alice/"],
+		["starts-with", "$Content-Type", "image/"],
+		{"success_action_status": "201"}`
+
+	tests := []struct {
+		name     string
+		policy   string
+		parseErr bool
+		code     string
+	}{
+		{
+			name:   "success",
+			policy: policy(`{"expiration": "2024-01-02T00:00:00.000Z", "conditions": [` + conditions + `, ["content-length-range", 1, 2048]]}`),
+		},
+		{
+			name:     "not-base64",
+			policy:   "{{{",
+			parseErr: true,
+			code:     "InvalidPolicyDocument",
+		},
+		{
+			name:     "missing-expiration",
+			policy:   policy(`{"conditions": [` + conditions + `]}`),
+			parseErr: true,
+			code:     "InvalidPolicyDocument",
+		},
+		{
+			name:     "invalid-operator",
+			policy:   policy(`{"expiration": "2024-01-02T00:00:00Z", "conditions": [["ends-with", "$key", "x"]]}`),
+			parseErr: true,
+			code:     "InvalidPolicyDocument",
+		},
+		{
+			name:   "expired",
+			policy: policy(`{"expiration": "2023-12-31T00:00:00Z", "conditions": [` + conditions + `]}`),
+			code:   "AccessDenied",
+		},
+		{
+			name:   "wrong-bucket",
+			policy: policy(`{"expiration": "2024-01-02T00:00:00Z", "conditions": [` + conditions + `, ["eq", "$bucket", "other"]]}`),
+			code:   "AccessDenied",
+		},
+		{
+			name:   "key-prefix",
+			policy: policy(`{"expiration": "2024-01-02T00:00:00Z", "conditions": [` + conditions + `, ["starts-with", "$key", "user/bob/"]]}`),
+			code:   "AccessDenied",
+		},
+		{
+			name:   "extra-field",
+			policy: policy(`{"expiration": "2024-01-02T00:00:00Z", "conditions": [{"bucket": "photos"}, ["starts-with", "$key", ""]]}`),
+			code:   "AccessDenied",
+		},
+		{
+			name:   "too-large",
+			policy: policy(`{"expiration": "2024-01-02T00:00:00Z", "conditions": [` + conditions + `, ["content-length-range", 0, 1023]]}`),
+			code:   "EntityTooLarge",
+		},
+		{
+			name:   "too-small",
+			policy: policy(`{"expiration": "2024-01-02T00:00:00Z", "conditions": [` + conditions + `, ["content-length-range", "2048", "4096"]]}`),
+			code:   "EntityTooSmall",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParsePostPolicy(tt.policy)
+			if err == nil && !tt.parseErr {
+				err = p.Check(form, "photos", now)
+			} else if !tt.parseErr {
+				t.Fatalf("unexpected parse error %v", err)
+			}
+
+			if tt.code == "" {
+				if err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+				return
+			}
+			var apiErr s3err.APIError
+			if !errors.As(err, &apiErr) || apiErr.Code != tt.code {
+				t.Fatalf("expected error code %v, got %v", tt.code, err)
+			}
+		})
+	}
+}
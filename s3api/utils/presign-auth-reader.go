@@ -21,6 +21,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -29,6 +30,7 @@ import (
 	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
 	"github.com/aws/smithy-go/logging"
 	"github.com/gofiber/fiber/v2"
+	"github.com/versity/versitygw/aws/signer/v4a"
 	"github.com/versity/versitygw/s3err"
 )
 
@@ -94,6 +96,10 @@ func CheckPresignedSignature(ctx *fiber.Ctx, auth AuthData, secret string, debug
 
 	date, _ := time.Parse(iso8601Format, auth.Date)
 
+	if auth.Algorithm == SigV4aAlgorithm {
+		return checkPresignedSignatureV4a(ctx, req, auth, secret, date, signedHdrs, debug)
+	}
+
 	signer := v4.NewSigner()
 	uri, _, signErr := signer.PresignHTTP(ctx.Context(), aws.Credentials{
 		AccessKeyID:     auth.Access,
@@ -122,6 +128,34 @@ func CheckPresignedSignature(ctx *fiber.Ctx, auth AuthData, secret string, debug
 	return nil
 }
 
+// checkPresignedSignatureV4a verifies the presigned request v4a signature
+// with the public key derived from the account credentials
+func checkPresignedSignatureV4a(ctx *fiber.Ctx, req *http.Request, auth AuthData, secret string, date time.Time, signedHdrs []string, debug bool) error {
+	creds, err := v4a.NewCredentials(auth.Access, secret)
+	if err != nil {
+		return err
+	}
+
+	signer := v4a.NewSigner()
+	err = signer.VerifyPresignHTTP(ctx.Context(), creds, req, unsignedPayload,
+		service, auth.RegionSet, date, signedHdrs, auth.Signature,
+		func(options *v4a.SignerOptions) {
+			options.DisableURIPathEscaping = true
+			if debug {
+				options.LogSigning = true
+				options.Logger = logging.NewStandardLogger(os.Stderr)
+			}
+		})
+	if errors.Is(err, v4a.ErrSignatureDoesNotMatch) {
+		return s3err.GetAPIError(s3err.ErrSignatureDoesNotMatch)
+	}
+	if err != nil {
+		return fmt.Errorf("verify presigned http request: %w", err)
+	}
+
+	return nil
+}
+
 // https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-query-string-auth.html
 //
 // # ParsePresignedURIParts parses and validates request URL query parameters
@@ -140,7 +174,7 @@ func ParsePresignedURIParts(ctx *fiber.Ctx) (AuthData, error) {
 	if algo == "" {
 		return a, s3err.GetAPIError(s3err.ErrInvalidQueryParams)
 	}
-	if algo != "AWS4-HMAC-SHA256" {
+	if algo != SigV4Algorithm && algo != SigV4aAlgorithm {
 		return a, s3err.GetAPIError(s3err.ErrInvalidQuerySignatureAlgo)
 	}
 
@@ -151,6 +185,11 @@ func ParsePresignedURIParts(ctx *fiber.Ctx) (AuthData, error) {
 	}
 
 	creds := strings.Split(credsQuery, "/")
+	if algo == SigV4aAlgorithm && len(creds) == 4 {
+		// the v4a credential scope does not include a region,
+		// the regions are listed in the X-Amz-Region-Set query param
+		creds = slices.Insert(creds, 2, "")
+	}
 	if len(creds) != 5 {
 		return a, s3err.GetAPIError(s3err.ErrCredMalformed)
 	}
@@ -180,7 +219,13 @@ func ParsePresignedURIParts(ctx *fiber.Ctx) (AuthData, error) {
 		return a, s3err.GetAPIError(s3err.ErrSignatureDateDoesNotMatch)
 	}
 
-	if ctx.Locals("region") != creds[2] {
+	region, _ := ctx.Locals("region").(string)
+	if algo == SigV4aAlgorithm {
+		a.RegionSet, err = ParseRegionSet(ctx.Query("X-Amz-Region-Set"), region)
+		if err != nil {
+			return a, err
+		}
+	} else if region != creds[2] {
 		return a, s3err.APIError{
 			Code:           "SignatureDoesNotMatch",
 			Description:    fmt.Sprintf("Credential should be scoped to a valid Region, not %v", creds[2]),
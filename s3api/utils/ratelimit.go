@@ -0,0 +1,342 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package utils
+
+import (
+	"container/list"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/versity/versitygw/s3err"
+)
+
+const (
+	rateLimitFile = "ratelimit.json"
+	rateLimitMode = 0600
+
+	// maxLimiters is the number of token buckets kept, the least
+	// recently used ones are dropped beyond it
+	maxLimiters = 10000
+)
+
+// RateLimits are the requests and bytes per second allowed, zero is
+// unlimited
+type RateLimits struct {
+	RequestsPerSecond float64 `json:"requestsPerSecond,omitempty"`
+	BytesPerSecond    int64   `json:"bytesPerSecond,omitempty"`
+}
+
+// RateLimitOpts are the default limits of each account, bucket and
+// source IP address
+type RateLimitOpts struct {
+	Account RateLimits
+	Bucket  RateLimits
+	IP      RateLimits
+	// Dir when set stores the account limits set with the admin api
+	Dir string
+}
+
+// tokenBucket holds up to one second of tokens, the tokens can go
+// negative when bytes are transferred faster than the limit
+type tokenBucket struct {
+	key    string
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func (tb *tokenBucket) burst() float64 {
+	return max(tb.rate, 1)
+}
+
+func (tb *tokenBucket) refill(now time.Time) {
+	tb.tokens = min(tb.tokens+now.Sub(tb.last).Seconds()*tb.rate, tb.burst())
+	tb.last = now
+}
+
+// RateLimiter limits the request rate and bandwidth of each account,
+// bucket and source IP address with token buckets
+type RateLimiter struct {
+	opts RateLimitOpts
+	now  func() time.Time
+
+	mu       sync.Mutex
+	accounts map[string]RateLimits
+	buckets  map[string]*list.Element
+	// lru orders the token buckets from the most recently used
+	lru *list.List
+}
+
+// NewRateLimiter creates a rate limiter, loading the account limits
+// stored in opts.Dir
+func NewRateLimiter(opts RateLimitOpts) (*RateLimiter, error) {
+	rl := &RateLimiter{
+		opts:     opts,
+		now:      time.Now,
+		accounts: make(map[string]RateLimits),
+		buckets:  make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+
+	if opts.Dir == "" {
+		return rl, nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(opts.Dir, rateLimitFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return rl, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read rate limit file: %w", err)
+	}
+
+	err = json.Unmarshal(data, &rl.accounts)
+	if err != nil {
+		return nil, fmt.Errorf("parse rate limit file: %w", err)
+	}
+
+	return rl, nil
+}
+
+// storeAccounts writes out the account limits, must be called with the
+// lock held
+func (rl *RateLimiter) storeAccounts() error {
+	if rl.opts.Dir == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(rl.accounts)
+	if err != nil {
+		return fmt.Errorf("serialize rate limits: %w", err)
+	}
+
+	f, err := os.CreateTemp(rl.opts.Dir, rateLimitFile)
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(data)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("write temp file: %w", err)
+	}
+
+	err = os.Chmod(f.Name(), rateLimitMode)
+	if err != nil {
+		return fmt.Errorf("chmod temp file: %w", err)
+	}
+
+	err = os.Rename(f.Name(), filepath.Join(rl.opts.Dir, rateLimitFile))
+	if err != nil {
+		return fmt.Errorf("rename temp file: %w", err)
+	}
+
+	return nil
+}
+
+// PutAccountLimits sets the limits of the account in place of the
+// default account limits
+func (rl *RateLimiter) PutAccountLimits(access string, limits RateLimits) error {
+	if limits.RequestsPerSecond < 0 || limits.BytesPerSecond < 0 {
+		return fmt.Errorf("invalid rate limit: limits can not be negative")
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.accounts[strings.Clone(access)] = limits
+	return rl.storeAccounts()
+}
+
+// DeleteAccountLimits restores the default limits of the account
+func (rl *RateLimiter) DeleteAccountLimits(access string) error {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	delete(rl.accounts, access)
+	return rl.storeAccounts()
+}
+
+// GetAccountLimits returns the limits applied to the account
+func (rl *RateLimiter) GetAccountLimits(access string) RateLimits {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	return rl.accountLimits(access)
+}
+
+func (rl *RateLimiter) accountLimits(access string) RateLimits {
+	limits, ok := rl.accounts[access]
+	if !ok {
+		return rl.opts.Account
+	}
+	return limits
+}
+
+// tokenBucket returns the refilled token bucket of key, must be called
+// with the lock held
+func (rl *RateLimiter) tokenBucket(key string, rate float64, now time.Time) *tokenBucket {
+	e, ok := rl.buckets[key]
+	if ok {
+		rl.lru.MoveToFront(e)
+		tb := e.Value.(*tokenBucket)
+		tb.refill(now)
+		// the account limits may have changed
+		tb.rate = rate
+		tb.tokens = min(tb.tokens, tb.burst())
+		return tb
+	}
+
+	for len(rl.buckets) >= maxLimiters {
+		oldest := rl.lru.Back()
+		rl.lru.Remove(oldest)
+		delete(rl.buckets, oldest.Value.(*tokenBucket).key)
+	}
+
+	tb := &tokenBucket{key: key, rate: rate, last: now}
+	tb.tokens = tb.burst()
+	rl.buckets[key] = rl.lru.PushFront(tb)
+	return tb
+}
+
+// AllowIP takes a request token from the source IP token bucket, and
+// returns ErrSlowDown when it is out of request tokens. This is checked
+// before the request is authenticated, so that the requests over the
+// limit don't get to the signature verification.
+func (rl *RateLimiter) AllowIP(ip string) error {
+	if rl.opts.IP.RequestsPerSecond <= 0 {
+		return nil
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	tb := rl.tokenBucket("req:ip:"+ip, rl.opts.IP.RequestsPerSecond, rl.now())
+	if tb.tokens < 1 {
+		return s3err.GetAPIError(s3err.ErrSlowDown)
+	}
+	tb.tokens--
+	return nil
+}
+
+// Allow takes a request token from the account and bucket token buckets,
+// the source IP request rate is limited by AllowIP. It returns
+// ErrSlowDown when one of them is out of request tokens, or when one of
+// them or the source IP has used up its bandwidth. The returned Throttle
+// limits the bandwidth of the request.
+func (rl *RateLimiter) Allow(access, bucket, ip string) (*Throttle, error) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.now()
+
+	type key struct {
+		name   string
+		limits RateLimits
+	}
+	keys := []key{{name: "ip:" + ip, limits: RateLimits{BytesPerSecond: rl.opts.IP.BytesPerSecond}}}
+	if access != "" {
+		keys = append(keys, key{name: "account:" + access, limits: rl.accountLimits(access)})
+	}
+	if bucket != "" {
+		keys = append(keys, key{name: "bucket:" + bucket, limits: rl.opts.Bucket})
+	}
+
+	var requests []*tokenBucket
+	t := &Throttle{rl: rl}
+	for _, k := range keys {
+		if k.limits.RequestsPerSecond > 0 {
+			tb := rl.tokenBucket("req:"+k.name, k.limits.RequestsPerSecond, now)
+			if tb.tokens < 1 {
+				return nil, s3err.GetAPIError(s3err.ErrSlowDown)
+			}
+			requests = append(requests, tb)
+		}
+		if k.limits.BytesPerSecond > 0 {
+			tb := rl.tokenBucket("bytes:"+k.name, float64(k.limits.BytesPerSecond), now)
+			if tb.tokens <= 0 {
+				return nil, s3err.GetAPIError(s3err.ErrSlowDown)
+			}
+			t.bytes = append(t.bytes, tb)
+		}
+	}
+
+	for _, tb := range requests {
+		tb.tokens--
+	}
+
+	if len(t.bytes) == 0 {
+		return nil, nil
+	}
+	return t, nil
+}
+
+// Throttle limits the bandwidth of a request
+type Throttle struct {
+	rl    *RateLimiter
+	bytes []*tokenBucket
+}
+
+// reserve takes n bytes from the token buckets and returns how long to
+// wait for the transfer to be within the bandwidth limits
+func (t *Throttle) reserve(n int) time.Duration {
+	t.rl.mu.Lock()
+	defer t.rl.mu.Unlock()
+
+	now := t.rl.now()
+	var wait time.Duration
+	for _, tb := range t.bytes {
+		tb.refill(now)
+		tb.tokens -= float64(n)
+		if tb.tokens < 0 {
+			wait = max(wait, time.Duration(-tb.tokens/tb.rate*float64(time.Second)))
+		}
+	}
+
+	return wait
+}
+
+// Wait blocks until n transferred bytes are within the bandwidth limits
+func (t *Throttle) Wait(n int) {
+	if t == nil || n <= 0 {
+		return
+	}
+	time.Sleep(t.reserve(n))
+}
+
+// Reader limits the bandwidth of reading from r
+func (t *Throttle) Reader(r io.Reader) io.Reader {
+	return &throttledReader{r: r, t: t}
+}
+
+type throttledReader struct {
+	r io.Reader
+	t *Throttle
+}
+
+func (tr *throttledReader) Read(p []byte) (int, error) {
+	n, err := tr.r.Read(p)
+	tr.t.Wait(n)
+	return n, err
+}
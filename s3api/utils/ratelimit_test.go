@@ -0,0 +1,214 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/versity/versitygw/s3err"
+)
+
+func isSlowDown(err error) bool {
+	var apiErr s3err.APIError
+	return errors.As(err, &apiErr) && apiErr.Code == "SlowDown"
+}
+
+func TestRateLimiterRequests(t *testing.T) {
+	rl, err := NewRateLimiter(RateLimitOpts{
+		Account: RateLimits{RequestsPerSecond: 2},
+		Bucket:  RateLimits{RequestsPerSecond: 3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(0, 0)
+	rl.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if _, err := rl.Allow("user1", "bucket", "127.0.0.1"); err != nil {
+			t.Fatalf("request %v: expected no error, got %v", i, err)
+		}
+	}
+	if _, err := rl.Allow("user1", "bucket", "127.0.0.1"); !isSlowDown(err) {
+		t.Fatalf("expected SlowDown for the account, got %v", err)
+	}
+
+	// the bucket limit is shared by the accounts
+	if _, err := rl.Allow("user2", "bucket", "127.0.0.1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := rl.Allow("user3", "bucket", "127.0.0.1"); !isSlowDown(err) {
+		t.Fatalf("expected SlowDown for the bucket, got %v", err)
+	}
+
+	// a rejected request does not take the tokens of the other limits
+	if _, err := rl.Allow("user3", "", "127.0.0.1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	now = now.Add(time.Second)
+	if _, err := rl.Allow("user1", "bucket", "127.0.0.1"); err != nil {
+		t.Fatalf("expected the tokens to be refilled, got %v", err)
+	}
+}
+
+func TestRateLimiterIP(t *testing.T) {
+	rl, err := NewRateLimiter(RateLimitOpts{
+		IP:      RateLimits{RequestsPerSecond: 2},
+		Account: RateLimits{RequestsPerSecond: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(0, 0)
+	rl.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if err := rl.AllowIP("127.0.0.1"); err != nil {
+			t.Fatalf("request %v: expected no error, got %v", i, err)
+		}
+	}
+	if err := rl.AllowIP("127.0.0.1"); !isSlowDown(err) {
+		t.Fatalf("expected SlowDown for the address, got %v", err)
+	}
+	if err := rl.AllowIP("127.0.0.2"); err != nil {
+		t.Fatalf("expected no error for another address, got %v", err)
+	}
+
+	// the authenticated request only takes the account token
+	if _, err := rl.Allow("user1", "", "127.0.0.1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := rl.Allow("user1", "", "127.0.0.2"); !isSlowDown(err) {
+		t.Fatalf("expected SlowDown for the account, got %v", err)
+	}
+
+	now = now.Add(time.Second)
+	if err := rl.AllowIP("127.0.0.1"); err != nil {
+		t.Fatalf("expected the tokens to be refilled, got %v", err)
+	}
+}
+
+func TestRateLimiterAccountLimits(t *testing.T) {
+	dir := t.TempDir()
+	rl, err := NewRateLimiter(RateLimitOpts{
+		Account: RateLimits{RequestsPerSecond: 1},
+		Dir:     dir,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(0, 0)
+	rl.now = func() time.Time { return now }
+
+	if err := rl.PutAccountLimits("user1", RateLimits{RequestsPerSecond: -1}); err == nil {
+		t.Fatal("expected negative limits to be rejected")
+	}
+
+	// zero limits make the account unlimited
+	err = rl.PutAccountLimits("user1", RateLimits{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if _, err := rl.Allow("user1", "", "127.0.0.1"); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+
+	// the account limits are kept across restarts
+	rl, err = NewRateLimiter(RateLimitOpts{
+		Account: RateLimits{RequestsPerSecond: 1},
+		Dir:     dir,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limits := rl.GetAccountLimits("user1"); limits != (RateLimits{}) {
+		t.Fatalf("expected the stored account limits, got %+v", limits)
+	}
+	if limits := rl.GetAccountLimits("user2"); limits.RequestsPerSecond != 1 {
+		t.Fatalf("expected the default account limits, got %+v", limits)
+	}
+
+	err = rl.DeleteAccountLimits("user1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limits := rl.GetAccountLimits("user1"); limits.RequestsPerSecond != 1 {
+		t.Fatalf("expected the default account limits, got %+v", limits)
+	}
+}
+
+func TestRateLimiterBandwidth(t *testing.T) {
+	rl, err := NewRateLimiter(RateLimitOpts{
+		IP: RateLimits{BytesPerSecond: 1000},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(0, 0)
+	rl.now = func() time.Time { return now }
+
+	throttle, err := rl.Allow("", "", "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if throttle == nil {
+		t.Fatal("expected a bandwidth throttle")
+	}
+
+	if wait := throttle.reserve(1000); wait != 0 {
+		t.Fatalf("expected the burst to be allowed, got wait %v", wait)
+	}
+	if wait := throttle.reserve(500); wait != 500*time.Millisecond {
+		t.Fatalf("expected 500ms wait, got %v", wait)
+	}
+
+	// the bandwidth is used up until the tokens are refilled
+	if _, err := rl.Allow("", "", "127.0.0.1"); !isSlowDown(err) {
+		t.Fatalf("expected SlowDown, got %v", err)
+	}
+	if _, err := rl.Allow("", "", "127.0.0.2"); err != nil {
+		t.Fatalf("expected no error for another address, got %v", err)
+	}
+
+	now = now.Add(time.Second)
+	if _, err := rl.Allow("", "", "127.0.0.1"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+}
+
+func TestRateLimiterMaxLimiters(t *testing.T) {
+	rl, err := NewRateLimiter(RateLimitOpts{
+		IP: RateLimits{RequestsPerSecond: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(0, 0)
+	rl.now = func() time.Time { return now }
+
+	if err := rl.AllowIP("recent"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for i := 0; i < maxLimiters*2; i++ {
+		// the used up token buckets are kept while recently used
+		if i%1000 == 0 {
+			if err := rl.AllowIP("recent"); !isSlowDown(err) {
+				t.Fatalf("expected SlowDown for the recent address, got %v", err)
+			}
+		}
+		if err := rl.AllowIP(fmt.Sprintf("10.0.%v.%v", i/256, i%256)); err != nil {
+			t.Fatalf("address %v: expected no error, got %v", i, err)
+		}
+	}
+
+	if len(rl.buckets) != maxLimiters || rl.lru.Len() != maxLimiters {
+		t.Fatalf("expected %v token buckets, got %v and %v", maxLimiters, len(rl.buckets), rl.lru.Len())
+	}
+	if _, ok := rl.buckets["req:ip:10.0.0.0"]; ok {
+		t.Fatal("expected the least recently used token bucket to be dropped")
+	}
+}
@@ -115,7 +115,15 @@ func createPresignedHttpRequestFromCtx(ctx *fiber.Ctx, signedHdrs []string, cont
 		body = bytes.NewReader(req.Body())
 	}
 
-	uri := string(ctx.Request().URI().Path())
+	// the signed path is the one of the request line, the routed uri
+	// path differs from it for virtual-hosted-style requests
+	var reqURI fasthttp.URI
+	err := reqURI.Parse(nil, req.Header.RequestURI())
+	if err != nil {
+		return nil, errors.New("error in creating an http request")
+	}
+
+	uri := string(reqURI.Path())
 	uri = httpbinding.EscapePath(uri, false)
 	isFirst := true
 
@@ -213,6 +221,12 @@ func includeHeader(hdr string, signedHdrs []string) bool {
 	return false
 }
 
+// IsSTSRequest returns true for the security token service query API
+// requests, which are form posts to the service root
+func IsSTSRequest(ctx *fiber.Ctx) bool {
+	return ctx.Method() == http.MethodPost && ctx.Path() == "/"
+}
+
 func IsBigDataAction(ctx *fiber.Ctx) bool {
 	if ctx.Method() == http.MethodPut && len(strings.Split(ctx.Path(), "/")) >= 3 {
 		if !ctx.Request().URI().QueryArgs().Has("tagging") && ctx.Get("X-Amz-Copy-Source") == "" && !ctx.Request().URI().QueryArgs().Has("acl") {
@@ -0,0 +1,250 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/versity/versitygw/s3err"
+)
+
+const (
+	sigV2Prefix = "AWS "
+)
+
+// v2SubResources are the query parameters that are part of the
+// canonicalized resource of a SigV2 string to sign
+var v2SubResources = map[string]bool{
+	"acl":                          true,
+	"cors":                         true,
+	"delete":                       true,
+	"encryption":                   true,
+	"legal-hold":                   true,
+	"lifecycle":                    true,
+	"location":                     true,
+	"logging":                      true,
+	"notification":                 true,
+	"partNumber":                   true,
+	"policy":                       true,
+	"requestPayment":               true,
+	"response-cache-control":       true,
+	"response-content-disposition": true,
+	"response-content-encoding":    true,
+	"response-content-language":    true,
+	"response-content-type":        true,
+	"response-expires":             true,
+	"retention":                    true,
+	"select":                       true,
+	"select-type":                  true,
+	"tagging":                      true,
+	"torrent":                      true,
+	"uploadId":                     true,
+	"uploads":                      true,
+	"versionId":                    true,
+	"versioning":                   true,
+	"versions":                     true,
+	"website":                      true,
+}
+
+// V2AuthData is the authorization data of a SigV2 signed request
+type V2AuthData struct {
+	Access    string
+	Signature string
+	// Expires is the expiration unix timestamp of presigned requests,
+	// it is empty for requests with an Authorization header
+	Expires string
+}
+
+// IsV2Request returns true if the request is signed with SigV2
+// either in the Authorization header or in the query params
+func IsV2Request(ctx *fiber.Ctx) bool {
+	if strings.HasPrefix(ctx.Get("Authorization"), sigV2Prefix) {
+		return true
+	}
+	return ctx.Query("AWSAccessKeyId") != "" && ctx.Query("Signature") != ""
+}
+
+// ParseV2Authorization parses the "AWS AccessKeyId:Signature" SigV2
+// Authorization header
+func ParseV2Authorization(authorization string) (V2AuthData, error) {
+	creds, ok := strings.CutPrefix(authorization, sigV2Prefix)
+	if !ok {
+		return V2AuthData{}, s3err.GetAPIError(s3err.ErrSignatureVersionNotSupported)
+	}
+
+	access, signature, ok := strings.Cut(strings.TrimSpace(creds), ":")
+	if !ok || access == "" || signature == "" {
+		return V2AuthData{}, s3err.APIError{
+			Code:           "InvalidArgument",
+			Description:    "AWS authorization header is invalid.  Expected AwsAccessKeyId:signature",
+			HTTPStatusCode: http.StatusBadRequest,
+		}
+	}
+
+	return V2AuthData{
+		Access:    access,
+		Signature: signature,
+	}, nil
+}
+
+// ParseV2PresignedQuery parses and validates the AWSAccessKeyId, Signature
+// and Expires query params of a SigV2 presigned request
+func ParseV2PresignedQuery(ctx *fiber.Ctx) (V2AuthData, error) {
+	access := ctx.Query("AWSAccessKeyId")
+	signature := ctx.Query("Signature")
+	expires := ctx.Query("Expires")
+	if access == "" || signature == "" || expires == "" {
+		return V2AuthData{}, s3err.APIError{
+			Code:           "AccessDenied",
+			Description:    "Query-string authentication requires the Signature, Expires and AWSAccessKeyId parameters",
+			HTTPStatusCode: http.StatusForbidden,
+		}
+	}
+
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return V2AuthData{}, s3err.GetAPIError(s3err.ErrMalformedExpires)
+	}
+	if time.Now().Unix() > exp {
+		return V2AuthData{}, s3err.GetAPIError(s3err.ErrExpiredPresignRequest)
+	}
+
+	return V2AuthData{
+		Access:    access,
+		Signature: signature,
+		Expires:   expires,
+	}, nil
+}
+
+// ValidateV2Date checks the Date or X-Amz-Date header of a SigV2 signed
+// request to be within the allowed clock skew
+func ValidateV2Date(ctx *fiber.Ctx) error {
+	date := ctx.Get("X-Amz-Date")
+	if date == "" {
+		date = ctx.Get("Date")
+	}
+	if date == "" {
+		return s3err.GetAPIError(s3err.ErrMissingDateHeader)
+	}
+
+	tdate, err := http.ParseTime(date)
+	if err != nil {
+		return s3err.GetAPIError(s3err.ErrMalformedDate)
+	}
+
+	return ValidateDate(tdate)
+}
+
+// CheckV2Signature computes the SigV2 signature of the request and
+// compares it with the provided one
+func CheckV2Signature(ctx *fiber.Ctx, auth V2AuthData, secret string, debug bool) error {
+	strToSign := GetV2StringToSign(ctx, auth.Expires)
+	if debug {
+		fmt.Fprintf(os.Stderr, "SigV2 string to sign:\n%v\n", strToSign)
+	}
+
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write([]byte(strToSign))
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	if !hmac.Equal([]byte(signature), []byte(auth.Signature)) {
+		return s3err.GetAPIError(s3err.ErrSignatureDoesNotMatch)
+	}
+
+	return nil
+}
+
+// GetV2StringToSign builds the SigV2 string to sign of the request.
+// For presigned requests expires replaces the Date header.
+func GetV2StringToSign(ctx *fiber.Ctx, expires string) string {
+	date := expires
+	if date == "" && ctx.Get("X-Amz-Date") == "" {
+		date = ctx.Get("Date")
+	}
+
+	var b strings.Builder
+	b.WriteString(ctx.Method())
+	b.WriteByte('\n')
+	b.WriteString(ctx.Get("Content-Md5"))
+	b.WriteByte('\n')
+	b.WriteString(ctx.Get("Content-Type"))
+	b.WriteByte('\n')
+	b.WriteString(date)
+	b.WriteByte('\n')
+	b.WriteString(canonicalAmzHeaders(ctx))
+	b.WriteString(canonicalV2Resource(ctx))
+
+	return b.String()
+}
+
+// canonicalAmzHeaders returns the lowercased and sorted x-amz-* headers,
+// one "name:value" line per header with repeated values comma joined
+func canonicalAmzHeaders(ctx *fiber.Ctx) string {
+	headers := map[string][]string{}
+	ctx.Request().Header.VisitAll(func(key, value []byte) {
+		name := strings.ToLower(string(key))
+		if strings.HasPrefix(name, "x-amz-") {
+			headers[name] = append(headers[name], strings.TrimSpace(string(value)))
+		}
+	})
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "%v:%v\n", name, strings.Join(headers[name], ","))
+	}
+
+	return b.String()
+}
+
+// canonicalV2Resource returns the original request path followed by the
+// sorted sub-resource query params
+func canonicalV2Resource(ctx *fiber.Ctx) string {
+	var subResources []string
+	ctx.Request().URI().QueryArgs().VisitAll(func(key, value []byte) {
+		name := string(key)
+		if !v2SubResources[name] {
+			return
+		}
+		if len(value) == 0 {
+			subResources = append(subResources, name)
+			return
+		}
+		subResources = append(subResources, name+"="+string(value))
+	})
+	sort.Strings(subResources)
+
+	resource := string(ctx.Request().URI().PathOriginal())
+	if len(subResources) == 0 {
+		return resource
+	}
+
+	return resource + "?" + strings.Join(subResources, "&")
+}
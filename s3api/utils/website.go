@@ -0,0 +1,170 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package utils
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/versity/versitygw/s3err"
+	"github.com/versity/versitygw/s3response"
+)
+
+const maxRoutingRules = 50
+
+// ParseWebsiteConfiguration parses and validates a bucket website
+// configuration
+func ParseWebsiteConfiguration(data []byte) (*s3response.WebsiteConfiguration, error) {
+	// the request body is not required to set the S3 namespace
+	var input struct {
+		IndexDocument         *s3response.IndexDocument         `xml:"IndexDocument"`
+		ErrorDocument         *s3response.ErrorDocument         `xml:"ErrorDocument"`
+		RedirectAllRequestsTo *s3response.RedirectAllRequestsTo `xml:"RedirectAllRequestsTo"`
+		RoutingRules          []s3response.RoutingRule          `xml:"RoutingRules>RoutingRule"`
+	}
+	err := xml.Unmarshal(data, &input)
+	if err != nil {
+		return nil, s3err.GetAPIError(s3err.ErrMalformedXML)
+	}
+	config := s3response.WebsiteConfiguration{
+		IndexDocument:         input.IndexDocument,
+		ErrorDocument:         input.ErrorDocument,
+		RedirectAllRequestsTo: input.RedirectAllRequestsTo,
+		RoutingRules:          input.RoutingRules,
+	}
+
+	if config.RedirectAllRequestsTo != nil {
+		// redirecting all requests excludes any other setting
+		if config.IndexDocument != nil || config.ErrorDocument != nil || len(config.RoutingRules) != 0 {
+			return nil, s3err.GetAPIError(s3err.ErrInvalidWebsiteConfiguration)
+		}
+		if config.RedirectAllRequestsTo.HostName == "" || !isValidProtocol(config.RedirectAllRequestsTo.Protocol) {
+			return nil, s3err.GetAPIError(s3err.ErrInvalidWebsiteConfiguration)
+		}
+		return &config, nil
+	}
+
+	if config.IndexDocument == nil || config.IndexDocument.Suffix == "" || strings.Contains(config.IndexDocument.Suffix, "/") {
+		return nil, s3err.GetAPIError(s3err.ErrInvalidWebsiteConfiguration)
+	}
+	if config.ErrorDocument != nil && config.ErrorDocument.Key == "" {
+		return nil, s3err.GetAPIError(s3err.ErrInvalidWebsiteConfiguration)
+	}
+	if len(config.RoutingRules) > maxRoutingRules {
+		return nil, s3err.GetAPIError(s3err.ErrInvalidWebsiteConfiguration)
+	}
+
+	for _, rule := range config.RoutingRules {
+		if rule.Condition != nil && rule.Condition.HttpErrorCodeReturnedEquals != "" {
+			code, err := strconv.Atoi(rule.Condition.HttpErrorCodeReturnedEquals)
+			if err != nil || code < 400 || code > 599 {
+				return nil, s3err.GetAPIError(s3err.ErrInvalidWebsiteConfiguration)
+			}
+		}
+		redirect := rule.Redirect
+		if redirect == (s3response.RoutingRuleRedirect{}) {
+			return nil, s3err.GetAPIError(s3err.ErrInvalidWebsiteConfiguration)
+		}
+		if redirect.ReplaceKeyPrefixWith != "" && redirect.ReplaceKeyWith != "" {
+			return nil, s3err.GetAPIError(s3err.ErrInvalidWebsiteConfiguration)
+		}
+		if redirect.HttpRedirectCode != "" {
+			code, err := strconv.Atoi(redirect.HttpRedirectCode)
+			if err != nil || code < 300 || code > 399 {
+				return nil, s3err.GetAPIError(s3err.ErrInvalidWebsiteConfiguration)
+			}
+		}
+		if !isValidProtocol(redirect.Protocol) {
+			return nil, s3err.GetAPIError(s3err.ErrInvalidWebsiteConfiguration)
+		}
+	}
+
+	return &config, nil
+}
+
+func isValidProtocol(protocol string) bool {
+	return protocol == "" || protocol == "http" || protocol == "https"
+}
+
+// MatchRoutingRule returns the first routing rule that applies to the
+// key, or nil if no rule matches. With a zero status only the rules
+// without an error code condition are considered, these apply before
+// the object is looked up.
+func MatchRoutingRule(config *s3response.WebsiteConfiguration, key string, status int) *s3response.RoutingRule {
+	for i, rule := range config.RoutingRules {
+		if rule.Condition == nil {
+			if status == 0 {
+				return &config.RoutingRules[i]
+			}
+			continue
+		}
+		if !strings.HasPrefix(key, rule.Condition.KeyPrefixEquals) {
+			continue
+		}
+		errCode := rule.Condition.HttpErrorCodeReturnedEquals
+		if (status == 0 && errCode == "") || (status != 0 && errCode == strconv.Itoa(status)) {
+			return &config.RoutingRules[i]
+		}
+	}
+	return nil
+}
+
+// RoutingRuleRedirect returns the redirect location and status code of
+// the routing rule for the key. The protocol and host default to the
+// ones of the request, and pathPrefix is prepended to the new key when
+// the redirect stays on the same host.
+func RoutingRuleRedirect(rule *s3response.RoutingRule, key, protocol, host, pathPrefix string) (string, int) {
+	redirect := rule.Redirect
+
+	newKey := key
+	switch {
+	case redirect.ReplaceKeyWith != "":
+		newKey = redirect.ReplaceKeyWith
+	case redirect.ReplaceKeyPrefixWith != "":
+		prefix := ""
+		if rule.Condition != nil {
+			prefix = rule.Condition.KeyPrefixEquals
+		}
+		newKey = redirect.ReplaceKeyPrefixWith + strings.TrimPrefix(key, prefix)
+	}
+
+	if redirect.Protocol != "" {
+		protocol = redirect.Protocol
+	}
+	if redirect.HostName != "" {
+		host = redirect.HostName
+		pathPrefix = ""
+	}
+
+	code := http.StatusMovedPermanently
+	if redirect.HttpRedirectCode != "" {
+		code, _ = strconv.Atoi(redirect.HttpRedirectCode)
+	}
+
+	return protocol + "://" + host + pathPrefix + "/" + EscapeKeyPath(newKey), code
+}
+
+// EscapeKeyPath escapes each path segment of an object key, so the
+// key can be used in a redirect location
+func EscapeKeyPath(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,120 @@
+package utils
+
+import (
+	"testing"
+)
+
+func TestParseWebsiteConfiguration(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{
+			name:    "Parse-website-malformed-xml",
+			data:    "<WebsiteConfiguration>",
+			wantErr: true,
+		},
+		{
+			name:    "Parse-website-missing-index",
+			data:    "<WebsiteConfiguration></WebsiteConfiguration>",
+			wantErr: true,
+		},
+		{
+			name:    "Parse-website-invalid-suffix",
+			data:    "<WebsiteConfiguration><IndexDocument><Suffix>docs/index.html</Suffix></IndexDocument></WebsiteConfiguration>",
+			wantErr: true,
+		},
+		{
+			name:    "Parse-website-redirect-all-with-index",
+			data:    "<WebsiteConfiguration><RedirectAllRequestsTo><HostName>example.com</HostName></RedirectAllRequestsTo><IndexDocument><Suffix>index.html</Suffix></IndexDocument></WebsiteConfiguration>",
+			wantErr: true,
+		},
+		{
+			name:    "Parse-website-invalid-protocol",
+			data:    "<WebsiteConfiguration><RedirectAllRequestsTo><HostName>example.com</HostName><Protocol>ftp</Protocol></RedirectAllRequestsTo></WebsiteConfiguration>",
+			wantErr: true,
+		},
+		{
+			name:    "Parse-website-invalid-redirect-code",
+			data:    "<WebsiteConfiguration><IndexDocument><Suffix>index.html</Suffix></IndexDocument><RoutingRules><RoutingRule><Redirect><HttpRedirectCode>200</HttpRedirectCode></Redirect></RoutingRule></RoutingRules></WebsiteConfiguration>",
+			wantErr: true,
+		},
+		{
+			name:    "Parse-website-replace-key-and-prefix",
+			data:    "<WebsiteConfiguration><IndexDocument><Suffix>index.html</Suffix></IndexDocument><RoutingRules><RoutingRule><Redirect><ReplaceKeyWith>a</ReplaceKeyWith><ReplaceKeyPrefixWith>b</ReplaceKeyPrefixWith></Redirect></RoutingRule></RoutingRules></WebsiteConfiguration>",
+			wantErr: true,
+		},
+		{
+			name:    "Parse-website-redirect-all-success",
+			data:    "<WebsiteConfiguration><RedirectAllRequestsTo><HostName>example.com</HostName><Protocol>https</Protocol></RedirectAllRequestsTo></WebsiteConfiguration>",
+			wantErr: false,
+		},
+		{
+			name:    "Parse-website-success",
+			data:    "<WebsiteConfiguration><IndexDocument><Suffix>index.html</Suffix></IndexDocument><ErrorDocument><Key>error.html</Key></ErrorDocument><RoutingRules><RoutingRule><Condition><KeyPrefixEquals>docs/</KeyPrefixEquals></Condition><Redirect><ReplaceKeyPrefixWith>documents/</ReplaceKeyPrefixWith></Redirect></RoutingRule></RoutingRules></WebsiteConfiguration>",
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseWebsiteConfiguration([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseWebsiteConfiguration() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRoutingRules(t *testing.T) {
+	config, err := ParseWebsiteConfiguration([]byte(`
+	<WebsiteConfiguration>
+		<IndexDocument><Suffix>index.html</Suffix></IndexDocument>
+		<RoutingRules>
+			<RoutingRule>
+				<Condition><KeyPrefixEquals>docs/</KeyPrefixEquals></Condition>
+				<Redirect><ReplaceKeyPrefixWith>documents/</ReplaceKeyPrefixWith></Redirect>
+			</RoutingRule>
+			<RoutingRule>
+				<Condition><HttpErrorCodeReturnedEquals>404</HttpErrorCodeReturnedEquals></Condition>
+				<Redirect><HostName>example.com</HostName><ReplaceKeyWith>missing.html</ReplaceKeyWith><HttpRedirectCode>302</HttpRedirectCode></Redirect>
+			</RoutingRule>
+		</RoutingRules>
+	</WebsiteConfiguration>
+	`))
+	if err != nil {
+		t.Fatalf("ParseWebsiteConfiguration() error = %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		key          string
+		status       int
+		wantLocation string
+		wantCode     int
+	}{
+		{"prefix-match", "docs/guide.html", 0, "http://localhost:7071/site/documents/guide.html", 301},
+		{"prefix-match-escaped", "docs/my guide?.html", 0, "http://localhost:7071/site/documents/my%20guide%3F.html", 301},
+		{"no-prefix-match", "img/logo.png", 0, "", 0},
+		{"error-code-match", "img/logo.png", 404, "http://example.com/missing.html", 302},
+		{"error-code-mismatch", "img/logo.png", 403, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := MatchRoutingRule(config, tt.key, tt.status)
+			if rule == nil {
+				if tt.wantLocation != "" {
+					t.Fatalf("MatchRoutingRule() = nil, want match")
+				}
+				return
+			}
+			if tt.wantLocation == "" {
+				t.Fatalf("MatchRoutingRule() = %+v, want nil", rule)
+			}
+			location, code := RoutingRuleRedirect(rule, tt.key, "http", "localhost:7071", "/site")
+			if location != tt.wantLocation || code != tt.wantCode {
+				t.Errorf("RoutingRuleRedirect() = %v %v, want %v %v", location, code, tt.wantLocation, tt.wantCode)
+			}
+		})
+	}
+}
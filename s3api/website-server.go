@@ -0,0 +1,85 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package s3api
+
+import (
+	"crypto/tls"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/logger"
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/s3api/middlewares"
+)
+
+// S3WebsiteServer serves the buckets with a website configuration to
+// anonymous clients
+type S3WebsiteServer struct {
+	app     *fiber.App
+	backend backend.Backend
+	router  *S3WebsiteRouter
+	port    string
+	cert    *tls.Certificate
+	domain  string
+	quiet   bool
+}
+
+func NewWebsiteServer(app *fiber.App, be backend.Backend, port string, opts ...WebsiteOpt) *S3WebsiteServer {
+	server := &S3WebsiteServer{
+		app:     app,
+		backend: be,
+		router:  new(S3WebsiteRouter),
+		port:    port,
+	}
+
+	for _, opt := range opts {
+		opt(server)
+	}
+
+	// Logging middlewares
+	if !server.quiet {
+		app.Use(logger.New())
+	}
+	app.Use(middlewares.DecodeURL(nil))
+
+	// Website requests are anonymous, there is no authentication
+	app.Use(middlewares.SetConditionContext())
+
+	server.router.Init(app, be, server.domain)
+
+	return server
+}
+
+type WebsiteOpt func(s *S3WebsiteServer)
+
+func WithWebsiteSrvTLS(cert tls.Certificate) WebsiteOpt {
+	return func(s *S3WebsiteServer) { s.cert = &cert }
+}
+
+// WithWebsiteDomain serves requests to <bucket>.<domain> from bucket
+func WithWebsiteDomain(domain string) WebsiteOpt {
+	return func(s *S3WebsiteServer) { s.domain = domain }
+}
+
+// WithWebsiteQuiet silences default logging output
+func WithWebsiteQuiet() WebsiteOpt {
+	return func(s *S3WebsiteServer) { s.quiet = true }
+}
+
+func (sw *S3WebsiteServer) Serve() (err error) {
+	if sw.cert != nil {
+		return sw.app.ListenTLSWithCertificate(sw.port, *sw.cert)
+	}
+	return sw.app.Listen(sw.port)
+}
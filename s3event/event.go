@@ -41,6 +41,7 @@ type EventType string
 const (
 	EventObjectPut               EventType = "s3:ObjectCreated:Put"
 	EventObjectCopy              EventType = "s3:ObjectCreated:Copy"
+	EventObjectPost              EventType = "s3:ObjectCreated:Post"
 	EventCompleteMultipartUpload EventType = "s3:ObjectCreated:CompleteMultipartUpload"
 	EventObjectDelete            EventType = "s3:ObjectRemoved:Delete"
 	EventObjectRestoreCompleted  EventType = "s3:ObjectRestore:Completed"
@@ -25,6 +25,7 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/segmentio/kafka-go"
+	"github.com/versity/versitygw/metrics"
 )
 
 var sequencer = 0
@@ -144,6 +145,7 @@ func (ks *Kafka) send(evnt []EventSchema) {
 	err = ks.writer.WriteMessages(ctx, message)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "failed to send kafka event: %v\n", err.Error())
+		metrics.EventFailures.Inc("kafka")
 	}
 }
 
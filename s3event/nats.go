@@ -24,6 +24,7 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/nats-io/nats.go"
+	"github.com/versity/versitygw/metrics"
 )
 
 type NatsEventSender struct {
@@ -108,5 +109,6 @@ func (ns *NatsEventSender) send(evnt []EventSchema) {
 	err = ns.client.Publish(ns.topic, msg)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "failed to send nats event: %v\n", err.Error())
+		metrics.EventFailures.Inc("nats")
 	}
 }
@@ -17,12 +17,21 @@ package s3log
 import (
 	"crypto/tls"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"math/rand"
 	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/versity/versitygw/s3err"
+)
+
+const (
+	// LogFormatText is the AWS server access log format
+	LogFormatText = "text"
+	// LogFormatJSON is one JSON encoded log entry per line
+	LogFormatJSON = "json"
 )
 
 type AuditLogger interface {
@@ -40,6 +49,24 @@ type LogMeta struct {
 type LogConfig struct {
 	LogFile    string
 	WebhookURL string
+	// Format is the format of the log file entries, defaults to text
+	Format   string
+	Rotation RotationConfig
+}
+
+// RotationConfig is the log file rotation policy, the log file is rotated
+// when either limit is reached
+type RotationConfig struct {
+	// MaxSize is the size in bytes of the log file to rotate at,
+	// 0 disables the size based rotation
+	MaxSize int64
+	// Interval is the age of the log file to rotate at, checked on each
+	// write, 0 disables the time based rotation
+	Interval time.Duration
+	// MaxBackups is the number of rotated log files kept, 0 keeps all
+	MaxBackups int
+	// Compress gzips the rotated log files
+	Compress bool
 }
 
 type LogFields struct {
@@ -71,18 +98,155 @@ type LogFields struct {
 	AclRequired        string
 }
 
+// InitLogger initializes the audit loggers of the config, the logs are
+// sent to all of them when more than one is configured
 func InitLogger(cfg *LogConfig) (AuditLogger, error) {
-	if cfg.WebhookURL != "" && cfg.LogFile != "" {
-		return nil, fmt.Errorf("there should be specified one of the following: file, webhook")
+	var loggers []AuditLogger
+	if cfg.LogFile != "" {
+		l, err := InitFileLogger(cfg.LogFile, cfg.Format, cfg.Rotation)
+		if err != nil {
+			return nil, err
+		}
+		loggers = append(loggers, l)
 	}
 	if cfg.WebhookURL != "" {
-		return InitWebhookLogger(cfg.WebhookURL)
+		l, err := InitWebhookLogger(cfg.WebhookURL)
+		if err != nil {
+			for _, l := range loggers {
+				l.Shutdown()
+			}
+			return nil, err
+		}
+		loggers = append(loggers, l)
 	}
-	if cfg.LogFile != "" {
-		return InitFileLogger(cfg.LogFile)
+
+	switch len(loggers) {
+	case 0:
+		return nil, nil
+	case 1:
+		return loggers[0], nil
+	default:
+		return NewMultiLogger(loggers...), nil
+	}
+}
+
+// MultiLogger sends the audit logs to several loggers
+type MultiLogger struct {
+	loggers []AuditLogger
+}
+
+var _ AuditLogger = &MultiLogger{}
+
+// NewMultiLogger returns an audit logger that sends the logs to all of the
+// loggers
+func NewMultiLogger(loggers ...AuditLogger) *MultiLogger {
+	return &MultiLogger{loggers: loggers}
+}
+
+// Log sends the log message to each logger
+func (m *MultiLogger) Log(ctx *fiber.Ctx, err error, body []byte, meta LogMeta) {
+	for _, l := range m.loggers {
+		l.Log(ctx, err, body, meta)
+	}
+}
+
+// HangUp hangs up each logger, and returns the errors of the loggers
+// that failed
+func (m *MultiLogger) HangUp() error {
+	var errs []error
+	for _, l := range m.loggers {
+		errs = append(errs, l.HangUp())
+	}
+	return errors.Join(errs...)
+}
+
+// Shutdown shuts down each logger, and returns the errors of the loggers
+// that failed
+func (m *MultiLogger) Shutdown() error {
+	var errs []error
+	for _, l := range m.loggers {
+		errs = append(errs, l.Shutdown())
+	}
+	return errors.Join(errs...)
+}
+
+// newLogFields gathers the log fields of the request. The request id is
+// kept in the request locals for the entries of the request in each
+// logger to match.
+func newLogFields(ctx *fiber.Ctx, err error, body []byte, meta LogMeta) LogFields {
+	lf := LogFields{}
+
+	access := "-"
+	reqURI := ctx.OriginalURL()
+	path := strings.Split(ctx.Path(), "/")
+	bucket, object := path[1], strings.Join(path[2:], "/")
+	errorCode := ""
+	httpStatus := 200
+	// the requests answered before the authentication, such as the CORS
+	// preflight requests, have no start time or region
+	startTime, ok := ctx.Locals("startTime").(time.Time)
+	if !ok {
+		startTime = time.Now()
+	}
+	region, _ := ctx.Locals("region").(string)
+	tlsConnState := ctx.Context().TLSConnectionState()
+	if tlsConnState != nil {
+		lf.CipherSuite = tls.CipherSuiteName(tlsConnState.CipherSuite)
+		lf.TLSVersion = getTLSVersionName(tlsConnState.Version)
+	}
+
+	if err != nil {
+		serr, ok := err.(s3err.APIError)
+		if ok {
+			errorCode = serr.Code
+			httpStatus = serr.HTTPStatusCode
+		} else {
+			errorCode = err.Error()
+			httpStatus = 500
+		}
+	}
+
+	switch ctx.Locals("access").(type) {
+	case string:
+		access = ctx.Locals("access").(string)
+	}
+
+	requestID, ok := ctx.Locals("logRequestID").(string)
+	if !ok {
+		requestID = genID()
+		ctx.Locals("logRequestID", requestID)
+	}
+
+	lf.BucketOwner = meta.BucketOwner
+	lf.Bucket = bucket
+	lf.Time = time.Now()
+	lf.RemoteIP = ctx.IP()
+	lf.Requester = access
+	lf.RequestID = requestID
+	lf.Operation = meta.Action
+	lf.Key = object
+	lf.RequestURI = reqURI
+	lf.HttpStatus = httpStatus
+	lf.ErrorCode = errorCode
+	lf.BytesSent = len(body)
+	lf.ObjectSize = meta.ObjectSize
+	lf.TotalTime = time.Since(startTime).Milliseconds()
+	lf.TurnAroundTime = time.Since(startTime).Milliseconds()
+	lf.Referer = ctx.Get("Referer")
+	lf.UserAgent = ctx.Get("User-Agent")
+	lf.VersionID = ctx.Query("versionId")
+	lf.HostID = ctx.Get("X-Amz-Id-2")
+	// the authentication middlewares set the signature version and the
+	// authentication type, these are empty for unsigned requests
+	lf.SignatureVersion, _ = ctx.Locals("signatureVersion").(string)
+	lf.AuthenticationType, _ = ctx.Locals("authType").(string)
+	if region != "" {
+		lf.HostHeader = fmt.Sprintf("s3.%v.amazonaws.com", region)
 	}
+	lf.AccessPointARN = fmt.Sprintf("arn:aws:s3:::%v", strings.Join(path, "/"))
+	lf.AclRequired = "Yes"
 
-	return nil, nil
+	return lf
 }
 
 func genID() string {
@@ -15,42 +15,95 @@
 package s3log
 
 import (
-	"crypto/tls"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/versity/versitygw/s3err"
+	"github.com/versity/versitygw/metrics"
 )
 
 const (
 	logFileMode = 0600
 	timeFormat  = "02/January/2006:15:04:05 -0700"
+	// backupTimeFormat is the suffix of the rotated log files, it sorts
+	// the backups by the time of the rotation
+	backupTimeFormat = "20060102T150405.000"
 )
 
 // FileLogger is a local file audit log
 type FileLogger struct {
-	logfile string
-	f       *os.File
-	gotErr  bool
-	mu      sync.Mutex
+	logfile  string
+	format   string
+	rotation RotationConfig
+	f        *os.File
+	size     int64
+	opened   time.Time
+	gotErr   bool
+	mu       sync.Mutex
+
+	// backupMu serializes the compression and removal of the backups
+	backupMu sync.Mutex
+	backupWg sync.WaitGroup
 }
 
 var _ AuditLogger = &FileLogger{}
 
-// InitFileLogger initializes audit logs to local file
-func InitFileLogger(logname string) (AuditLogger, error) {
-	f, err := os.OpenFile(logname, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+// InitFileLogger initializes audit logs to local file with the entries in
+// the format, rotated with the rotation policy
+func InitFileLogger(logname, format string, rotation RotationConfig) (AuditLogger, error) {
+	switch format {
+	case "":
+		format = LogFormatText
+	case LogFormatText, LogFormatJSON:
+	default:
+		return nil, fmt.Errorf("invalid log format %q, should be one of %v, %v",
+			format, LogFormatText, LogFormatJSON)
+	}
+	if rotation.MaxSize < 0 || rotation.Interval < 0 || rotation.MaxBackups < 0 {
+		return nil, fmt.Errorf("invalid log rotation: negative limits")
+	}
+
+	f := &FileLogger{logfile: logname, format: format, rotation: rotation}
+	err := f.open()
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// open opens the log file for appending, must be called with the lock held
+func (f *FileLogger) open() error {
+	file, err := os.OpenFile(f.logfile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, logFileMode)
+	if err != nil {
+		return fmt.Errorf("open log: %w", err)
+	}
+	fi, err := file.Stat()
 	if err != nil {
-		return nil, fmt.Errorf("open log: %w", err)
+		file.Close()
+		return fmt.Errorf("stat log: %w", err)
 	}
 
-	f.WriteString(fmt.Sprintf("log starts %v\n", time.Now()))
+	f.f = file
+	f.size = fi.Size()
+	f.opened = time.Now()
 
-	return &FileLogger{logfile: logname, f: f}, nil
+	// the JSON lines have no room for the start marker
+	if f.format == LogFormatText {
+		n, _ := f.f.WriteString(fmt.Sprintf("log starts %v\n", time.Now()))
+		f.size += int64(n)
+	}
+
+	return nil
 }
 
 // Log sends log message to file logger
@@ -58,70 +111,54 @@ func (f *FileLogger) Log(ctx *fiber.Ctx, err error, body []byte, meta LogMeta) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
-	if f.gotErr {
+	// the log file is closed when it failed to be reopened
+	if f.gotErr || f.f == nil {
 		return
 	}
 
-	lf := LogFields{}
+	f.writeLog(newLogFields(ctx, err, body, meta))
+}
 
-	access := "-"
-	reqURI := ctx.OriginalURL()
-	path := strings.Split(ctx.Path(), "/")
-	bucket, object := path[1], strings.Join(path[2:], "/")
-	errorCode := ""
-	httpStatus := 200
-	startTime := ctx.Locals("startTime").(time.Time)
-	tlsConnState := ctx.Context().TLSConnectionState()
-	if tlsConnState != nil {
-		lf.CipherSuite = tls.CipherSuiteName(tlsConnState.CipherSuite)
-		lf.TLSVersion = getTLSVersionName(tlsConnState.Version)
+func (f *FileLogger) writeLog(lf LogFields) {
+	var log string
+	if f.format == LogFormatJSON {
+		log = formatJSON(lf)
+	} else {
+		log = formatText(lf)
 	}
 
-	if err != nil {
-		serr, ok := err.(s3err.APIError)
-		if ok {
-			errorCode = serr.Code
-			httpStatus = serr.HTTPStatusCode
-		} else {
-			errorCode = err.Error()
-			httpStatus = 500
+	if f.shouldRotate(int64(len(log))) {
+		err := f.rotate()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error rotating log file: %v\n", err)
+			metrics.AuditLogFailures.Inc("file")
+			if f.f == nil {
+				f.gotErr = true
+				return
+			}
 		}
 	}
 
-	switch ctx.Locals("access").(type) {
-	case string:
-		access = ctx.Locals("access").(string)
-	}
-
-	lf.BucketOwner = meta.BucketOwner
-	lf.Bucket = bucket
-	lf.Time = time.Now()
-	lf.RemoteIP = ctx.IP()
-	lf.Requester = access
-	lf.RequestID = genID()
-	lf.Operation = meta.Action
-	lf.Key = object
-	lf.RequestURI = reqURI
-	lf.HttpStatus = httpStatus
-	lf.ErrorCode = errorCode
-	lf.BytesSent = len(body)
-	lf.ObjectSize = meta.ObjectSize
-	lf.TotalTime = time.Since(startTime).Milliseconds()
-	lf.TurnAroundTime = time.Since(startTime).Milliseconds()
-	lf.Referer = ctx.Get("Referer")
-	lf.UserAgent = ctx.Get("User-Agent")
-	lf.VersionID = ctx.Query("versionId")
-	lf.HostID = ctx.Get("X-Amz-Id-2")
-	lf.SignatureVersion = "SigV4"
-	lf.AuthenticationType = "AuthHeader"
-	lf.HostHeader = fmt.Sprintf("s3.%v.amazonaws.com", ctx.Locals("region").(string))
-	lf.AccessPointARN = fmt.Sprintf("arn:aws:s3:::%v", strings.Join(path, "/"))
-	lf.AclRequired = "Yes"
-
-	f.writeLog(lf)
+	n, err := f.f.WriteString(log)
+	f.size += int64(n)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error writing to log file: %v\n", err)
+		metrics.AuditLogFailures.Inc("file")
+		// TODO: do we need to terminate on log error?
+		// set err for now so that we don't spew errors
+		f.gotErr = true
+	}
 }
 
-func (f *FileLogger) writeLog(lf LogFields) {
+func formatJSON(lf LogFields) string {
+	b, err := json.Marshal(lf)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to parse the log data: %v\n", err)
+	}
+	return string(b) + "\n"
+}
+
+func formatText(lf LogFields) string {
 	if lf.BucketOwner == "" {
 		lf.BucketOwner = "-"
 	}
@@ -158,9 +195,15 @@ func (f *FileLogger) writeLog(lf LogFields) {
 	if lf.HostID == "" {
 		lf.HostID = "-"
 	}
+	if lf.SignatureVersion == "" {
+		lf.SignatureVersion = "-"
+	}
 	if lf.CipherSuite == "" {
 		lf.CipherSuite = "-"
 	}
+	if lf.AuthenticationType == "" {
+		lf.AuthenticationType = "-"
+	}
 	if lf.HostHeader == "" {
 		lf.HostHeader = "-"
 	}
@@ -168,7 +211,7 @@ func (f *FileLogger) writeLog(lf LogFields) {
 		lf.TLSVersion = "-"
 	}
 
-	log := fmt.Sprintf("%v %v %v %v %v %v %v %v %v %v %v %v %v %v %v %v %v %v %v %v %v %v %v %v %v %v\n",
+	return fmt.Sprintf("%v %v %v %v %v %v %v %v %v %v %v %v %v %v %v %v %v %v %v %v %v %v %v %v %v %v\n",
 		lf.BucketOwner,
 		lf.Bucket,
 		fmt.Sprintf("[%v]", lf.Time.Format(timeFormat)),
@@ -196,35 +239,162 @@ func (f *FileLogger) writeLog(lf LogFields) {
 		lf.AccessPointARN,
 		lf.AclRequired,
 	)
+}
 
-	_, err := f.f.WriteString(log)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "error writing to log file: %v\n", err)
-		// TODO: do we need to terminate on log error?
-		// set err for now so that we don't spew errors
-		f.gotErr = true
+// shouldRotate reports if the log file reaches a limit of the rotation
+// policy with the write of n bytes, an empty log file is not rotated for
+// its size
+func (f *FileLogger) shouldRotate(n int64) bool {
+	if f.rotation.MaxSize > 0 && f.size > 0 && f.size+n > f.rotation.MaxSize {
+		return true
 	}
+	return f.rotation.Interval > 0 && time.Since(f.opened) >= f.rotation.Interval
 }
 
-// HangUp closes current logfile handle and opens a new one
-// typically needed for log rotations
-func (f *FileLogger) HangUp() error {
+// rotate renames the log file to a backup with the time of the rotation
+// and opens a new log file, the backups are compressed and removed in the
+// background. Must be called with the lock held.
+func (f *FileLogger) rotate() error {
 	err := f.f.Close()
 	if err != nil {
 		return fmt.Errorf("close log: %w", err)
 	}
+	f.f = nil
 
-	f.f, err = os.OpenFile(f.logfile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	backup := f.logfile + "." + time.Now().Format(backupTimeFormat)
+	renameErr := os.Rename(f.logfile, backup)
+	if renameErr != nil {
+		renameErr = fmt.Errorf("rename log: %w", renameErr)
+	}
+
+	// the log is reopened even if the rename failed, the entries are then
+	// appended to the current file
+	err = f.open()
 	if err != nil {
-		return fmt.Errorf("open log: %w", err)
+		return errors.Join(renameErr, err)
+	}
+	if renameErr != nil {
+		return renameErr
 	}
 
-	f.f.WriteString(fmt.Sprintf("log starts %v\n", time.Now()))
+	f.backupWg.Add(1)
+	go func() {
+		defer f.backupWg.Done()
+		f.backupMu.Lock()
+		defer f.backupMu.Unlock()
+
+		if f.rotation.Compress {
+			err := compressFile(backup)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error compressing log file: %v\n", err)
+			}
+		}
+		if f.rotation.MaxBackups > 0 {
+			err := f.removeBackups()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error removing log file backups: %v\n", err)
+			}
+		}
+	}()
 
 	return nil
 }
 
-// Shutdown closes logfile handle
+// compressFile gzips the file to the file with the .gz extension, and
+// removes the file
+func compressFile(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, logFileMode)
+	if err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	if err == nil {
+		err = zw.Close()
+	}
+	if err == nil {
+		err = dst.Sync()
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(name + ".gz")
+		return err
+	}
+
+	return os.Remove(name)
+}
+
+// removeBackups removes the oldest rotated log files past the max backups
+func (f *FileLogger) removeBackups() error {
+	dir, base := filepath.Split(f.logfile)
+	if dir == "" {
+		dir = "."
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	var backups []string
+	for _, e := range entries {
+		suffix, ok := strings.CutPrefix(e.Name(), base+".")
+		if !ok || e.IsDir() {
+			continue
+		}
+		_, err := time.Parse(backupTimeFormat, strings.TrimSuffix(suffix, ".gz"))
+		if err != nil {
+			continue
+		}
+		backups = append(backups, e.Name())
+	}
+	if len(backups) <= f.rotation.MaxBackups {
+		return nil
+	}
+
+	sort.Strings(backups)
+	var errs []error
+	for _, name := range backups[:len(backups)-f.rotation.MaxBackups] {
+		errs = append(errs, os.Remove(filepath.Join(dir, name)))
+	}
+	return errors.Join(errs...)
+}
+
+// HangUp closes current logfile handle and opens a new one
+// typically needed for log rotations
+func (f *FileLogger) HangUp() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.f != nil {
+		err := f.f.Close()
+		if err != nil {
+			return fmt.Errorf("close log: %w", err)
+		}
+		f.f = nil
+	}
+
+	return f.open()
+}
+
+// Shutdown closes logfile handle, after the pending compression of the
+// rotated log files
 func (f *FileLogger) Shutdown() error {
+	f.backupWg.Wait()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.f == nil {
+		return nil
+	}
 	return f.f.Close()
 }
@@ -0,0 +1,248 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package s3log
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func initFileLogger(t *testing.T, logfile, format string, rotation RotationConfig) *FileLogger {
+	t.Helper()
+	l, err := InitFileLogger(logfile, format, rotation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l.(*FileLogger)
+}
+
+func backups(t *testing.T, logfile string) []string {
+	t.Helper()
+	names, err := filepath.Glob(logfile + ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return names
+}
+
+func TestFileLoggerJSON(t *testing.T) {
+	logfile := filepath.Join(t.TempDir(), "access.log")
+	l := initFileLogger(t, logfile, LogFormatJSON, RotationConfig{})
+
+	l.writeLog(LogFields{Bucket: "bucket", Key: "key", HttpStatus: 200})
+	l.writeLog(LogFields{Bucket: "bucket", Key: "other", HttpStatus: 404})
+	err := l.Shutdown()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(logfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var entries []LogFields
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var lf LogFields
+		err := json.Unmarshal(scanner.Bytes(), &lf)
+		if err != nil {
+			t.Fatalf("invalid json line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, lf)
+	}
+	if len(entries) != 2 || entries[1].Key != "other" || entries[1].HttpStatus != 404 {
+		t.Fatalf("unexpected log entries %+v", entries)
+	}
+}
+
+func TestFileLoggerInvalidFormat(t *testing.T) {
+	_, err := InitFileLogger(filepath.Join(t.TempDir(), "access.log"), "csv", RotationConfig{})
+	if err == nil {
+		t.Fatal("expected the format to be rejected")
+	}
+}
+
+func TestFileLoggerRotateSize(t *testing.T) {
+	logfile := filepath.Join(t.TempDir(), "access.log")
+	l := initFileLogger(t, logfile, LogFormatText, RotationConfig{
+		MaxSize:    600,
+		MaxBackups: 2,
+		Compress:   true,
+	})
+
+	for i := 0; i < 10; i++ {
+		l.writeLog(LogFields{Bucket: "bucket", Key: strings.Repeat("k", 100)})
+		// the backups are named by the rotation time
+		time.Sleep(2 * time.Millisecond)
+	}
+	err := l.Shutdown()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fi, err := os.Stat(logfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() > 600 {
+		t.Fatalf("expected the log file to be rotated, size %v", fi.Size())
+	}
+
+	if fi.Mode().Perm() != logFileMode {
+		t.Fatalf("expected the log file mode %v, got %v", os.FileMode(logFileMode), fi.Mode().Perm())
+	}
+
+	names := backups(t, logfile)
+	if len(names) != 2 {
+		t.Fatalf("expected 2 backups, got %v", names)
+	}
+	for _, name := range names {
+		if !strings.HasSuffix(name, ".gz") {
+			t.Fatalf("expected compressed backups, got %v", name)
+		}
+		fi, err := os.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Mode().Perm() != logFileMode {
+			t.Fatalf("expected the backup mode %v, got %v", os.FileMode(logFileMode), fi.Mode().Perm())
+		}
+
+		f, err := os.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(zr)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), "bucket") {
+			t.Fatalf("expected the log entries in %v, got %q", name, data)
+		}
+	}
+}
+
+func TestFileLoggerRotateInterval(t *testing.T) {
+	logfile := filepath.Join(t.TempDir(), "access.log")
+	l := initFileLogger(t, logfile, LogFormatText, RotationConfig{
+		Interval: time.Hour,
+	})
+
+	l.writeLog(LogFields{Bucket: "bucket"})
+	if names := backups(t, logfile); len(names) != 0 {
+		t.Fatalf("expected no backups, got %v", names)
+	}
+
+	l.mu.Lock()
+	l.opened = l.opened.Add(-time.Hour)
+	l.mu.Unlock()
+
+	l.writeLog(LogFields{Bucket: "bucket"})
+	err := l.Shutdown()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := backups(t, logfile); len(names) != 1 {
+		t.Fatalf("expected 1 backup, got %v", names)
+	}
+}
+
+func TestLogFieldsSignature(t *testing.T) {
+	var signed, unsigned LogFields
+	app := fiber.New()
+	app.Get("/signed/*", func(ctx *fiber.Ctx) error {
+		ctx.Locals("signatureVersion", "SigV2")
+		ctx.Locals("authType", "QueryString")
+		signed = newLogFields(ctx, nil, nil, LogMeta{})
+		return nil
+	})
+	app.Get("/unsigned/*", func(ctx *fiber.Ctx) error {
+		unsigned = newLogFields(ctx, nil, nil, LogMeta{})
+		return nil
+	})
+
+	for _, path := range []string{"/signed/key", "/unsigned/key"} {
+		_, err := app.Test(httptest.NewRequest(fiber.MethodGet, path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if signed.SignatureVersion != "SigV2" || signed.AuthenticationType != "QueryString" {
+		t.Fatalf("expected the signature of the request, got %v %v", signed.SignatureVersion, signed.AuthenticationType)
+	}
+	if unsigned.SignatureVersion != "" || unsigned.AuthenticationType != "" {
+		t.Fatalf("expected no signature for the unsigned request, got %v %v", unsigned.SignatureVersion, unsigned.AuthenticationType)
+	}
+}
+
+type testLogger struct {
+	logs     int
+	shutdown error
+}
+
+func (l *testLogger) Log(*fiber.Ctx, error, []byte, LogMeta) { l.logs++ }
+func (l *testLogger) HangUp() error                          { return nil }
+func (l *testLogger) Shutdown() error                        { return l.shutdown }
+
+func TestMultiLogger(t *testing.T) {
+	l1, l2 := &testLogger{}, &testLogger{shutdown: errors.New("shutdown failed")}
+	ml := NewMultiLogger(l1, l2)
+
+	ml.Log(nil, nil, nil, LogMeta{Action: "GetObject"})
+	if l1.logs != 1 || l2.logs != 1 {
+		t.Fatalf("expected the log to be sent to each logger, got %v, %v", l1.logs, l2.logs)
+	}
+	if err := ml.Shutdown(); err == nil {
+		t.Fatal("expected the shutdown error of the logger")
+	}
+}
+
+func TestInitLoggerFileAndWebhook(t *testing.T) {
+	srv := httptest.NewServer(nil)
+	defer srv.Close()
+
+	l, err := InitLogger(&LogConfig{
+		LogFile:    filepath.Join(t.TempDir(), "access.log"),
+		WebhookURL: srv.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Shutdown()
+
+	ml, ok := l.(*MultiLogger)
+	if !ok || len(ml.loggers) != 2 {
+		t.Fatalf("expected the file and webhook loggers, got %#v", l)
+	}
+}
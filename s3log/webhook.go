@@ -16,18 +16,16 @@ package s3log
 
 import (
 	"bytes"
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"net"
 	"net/http"
 	"os"
-	"strings"
 	"sync"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/versity/versitygw/s3err"
+	"github.com/versity/versitygw/metrics"
 )
 
 // WebhookLogger is a webhook URL audit log
@@ -59,62 +57,7 @@ func (wl *WebhookLogger) Log(ctx *fiber.Ctx, err error, body []byte, meta LogMet
 	wl.mu.Lock()
 	defer wl.mu.Unlock()
 
-	lf := LogFields{}
-
-	access := "-"
-	reqURI := ctx.OriginalURL()
-	path := strings.Split(ctx.Path(), "/")
-	bucket, object := path[1], strings.Join(path[2:], "/")
-	errorCode := ""
-	httpStatus := 200
-	startTime := ctx.Locals("startTime").(time.Time)
-	tlsConnState := ctx.Context().TLSConnectionState()
-	if tlsConnState != nil {
-		lf.CipherSuite = tls.CipherSuiteName(tlsConnState.CipherSuite)
-		lf.TLSVersion = getTLSVersionName(tlsConnState.Version)
-	}
-
-	if err != nil {
-		serr, ok := err.(s3err.APIError)
-		if ok {
-			errorCode = serr.Code
-			httpStatus = serr.HTTPStatusCode
-		} else {
-			errorCode = err.Error()
-			httpStatus = 500
-		}
-	}
-
-	switch ctx.Locals("access").(type) {
-	case string:
-		access = ctx.Locals("access").(string)
-	}
-
-	lf.BucketOwner = meta.BucketOwner
-	lf.Bucket = bucket
-	lf.Time = time.Now()
-	lf.RemoteIP = ctx.IP()
-	lf.Requester = access
-	lf.RequestID = genID()
-	lf.Operation = meta.Action
-	lf.Key = object
-	lf.RequestURI = reqURI
-	lf.HttpStatus = httpStatus
-	lf.ErrorCode = errorCode
-	lf.BytesSent = len(body)
-	lf.ObjectSize = meta.ObjectSize
-	lf.TotalTime = time.Since(startTime).Milliseconds()
-	lf.TurnAroundTime = time.Since(startTime).Milliseconds()
-	lf.Referer = ctx.Get("Referer")
-	lf.UserAgent = ctx.Get("User-Agent")
-	lf.VersionID = ctx.Query("versionId")
-	lf.HostID = ctx.Get("X-Amz-Id-2")
-	lf.SignatureVersion = "SigV4"
-	lf.AuthenticationType = "AuthHeader"
-	lf.HostHeader = fmt.Sprintf("s3.%v.amazonaws.com", ctx.Locals("region").(string))
-	lf.AccessPointARN = fmt.Sprintf("arn:aws:s3:::%v", strings.Join(path, "/"))
-	lf.AclRequired = "Yes"
-
+	lf := newLogFields(ctx, err, body, meta)
 	wl.sendLog(lf)
 }
 
@@ -137,11 +80,17 @@ func makeRequest(req *http.Request) {
 	client := &http.Client{
 		Timeout: 1 * time.Second,
 	}
-	_, err := client.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
+		metrics.AuditLogFailures.Inc("webhook")
 		if err, ok := err.(net.Error); ok && !err.Timeout() {
 			fmt.Fprintf(os.Stderr, "error sending webhook log: %v\n", err)
 		}
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		metrics.AuditLogFailures.Inc("webhook")
 	}
 }
 
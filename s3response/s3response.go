@@ -27,6 +27,13 @@ type Part struct {
 	LastModified string
 	ETag         string
 	Size         int64
+
+	// Additional checksum of the part data, only the checksum of
+	// the algorithm of the upload is set
+	ChecksumCRC32  string `xml:",omitempty"`
+	ChecksumCRC32C string `xml:",omitempty"`
+	ChecksumSHA1   string `xml:",omitempty"`
+	ChecksumSHA256 string `xml:",omitempty"`
 }
 
 // ListPartsResponse - s3 api list parts response.
@@ -116,6 +123,129 @@ type TaggingInput struct {
 	TagSet TagSet `xml:"TagSet"`
 }
 
+// ListVersionsResult - s3 api list object versions response.
+type ListVersionsResult struct {
+	XMLName xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListVersionsResult" json:"-"`
+
+	Name                string
+	Prefix              string
+	KeyMarker           string
+	VersionIdMarker     string
+	NextKeyMarker       string `xml:"NextKeyMarker,omitempty"`
+	NextVersionIdMarker string `xml:"NextVersionIdMarker,omitempty"`
+	Delimiter           string `xml:"Delimiter,omitempty"`
+	MaxKeys             int32
+	IsTruncated         bool
+
+	// List of object versions and delete markers.
+	Versions      []types.ObjectVersion     `xml:"Version"`
+	DeleteMarkers []types.DeleteMarkerEntry `xml:"DeleteMarker"`
+
+	// Delimed common prefixes.
+	CommonPrefixes []types.CommonPrefix
+}
+
+// LifecycleConfiguration bucket lifecycle configuration
+type LifecycleConfiguration struct {
+	XMLName xml.Name        `xml:"http://s3.amazonaws.com/doc/2006-03-01/ LifecycleConfiguration" json:"-"`
+	Rules   []LifecycleRule `xml:"Rule"`
+}
+
+// LifecycleRule a single lifecycle rule, the SDK types can not be
+// used here because the rule filter is a union type
+type LifecycleRule struct {
+	ID     *string `xml:"ID,omitempty"`
+	Status types.ExpirationStatus
+
+	// Prefix is the deprecated rule prefix, Filter should be used
+	// instead for new configurations
+	Prefix *string              `xml:"Prefix,omitempty"`
+	Filter *LifecycleRuleFilter `xml:"Filter,omitempty"`
+
+	Expiration                     *types.LifecycleExpiration
+	NoncurrentVersionExpiration    *types.NoncurrentVersionExpiration
+	AbortIncompleteMultipartUpload *types.AbortIncompleteMultipartUpload
+	Transitions                    []types.Transition                  `xml:"Transition"`
+	NoncurrentVersionTransitions   []types.NoncurrentVersionTransition `xml:"NoncurrentVersionTransition"`
+}
+
+// LifecycleRuleFilter selects the objects a lifecycle rule applies to,
+// only one of the fields may be set
+type LifecycleRuleFilter struct {
+	Prefix                *string                   `xml:"Prefix,omitempty"`
+	Tag                   *Tag                      `xml:"Tag,omitempty"`
+	ObjectSizeGreaterThan *int64                    `xml:"ObjectSizeGreaterThan,omitempty"`
+	ObjectSizeLessThan    *int64                    `xml:"ObjectSizeLessThan,omitempty"`
+	And                   *LifecycleRuleAndOperator `xml:"And,omitempty"`
+}
+
+// LifecycleRuleAndOperator combines multiple lifecycle filter predicates
+type LifecycleRuleAndOperator struct {
+	Prefix                *string `xml:"Prefix,omitempty"`
+	Tags                  []Tag   `xml:"Tag"`
+	ObjectSizeGreaterThan *int64  `xml:"ObjectSizeGreaterThan,omitempty"`
+	ObjectSizeLessThan    *int64  `xml:"ObjectSizeLessThan,omitempty"`
+}
+
+// CORSConfiguration bucket cross-origin resource sharing configuration
+type CORSConfiguration struct {
+	XMLName xml.Name   `xml:"http://s3.amazonaws.com/doc/2006-03-01/ CORSConfiguration" json:"-"`
+	Rules   []CORSRule `xml:"CORSRule"`
+}
+
+// CORSRule a single CORS rule, each of the list elements is repeated
+// in the XML with the singular element name
+type CORSRule struct {
+	ID             *string  `xml:"ID,omitempty"`
+	AllowedHeaders []string `xml:"AllowedHeader"`
+	AllowedMethods []string `xml:"AllowedMethod"`
+	AllowedOrigins []string `xml:"AllowedOrigin"`
+	ExposeHeaders  []string `xml:"ExposeHeader"`
+	MaxAgeSeconds  *int32   `xml:"MaxAgeSeconds,omitempty"`
+}
+
+// WebsiteConfiguration bucket static website hosting configuration
+type WebsiteConfiguration struct {
+	XMLName               xml.Name               `xml:"http://s3.amazonaws.com/doc/2006-03-01/ WebsiteConfiguration" json:"-"`
+	IndexDocument         *IndexDocument         `xml:"IndexDocument,omitempty"`
+	ErrorDocument         *ErrorDocument         `xml:"ErrorDocument,omitempty"`
+	RedirectAllRequestsTo *RedirectAllRequestsTo `xml:"RedirectAllRequestsTo,omitempty"`
+	RoutingRules          []RoutingRule          `xml:"RoutingRules>RoutingRule,omitempty"`
+}
+
+type IndexDocument struct {
+	Suffix string `xml:"Suffix"`
+}
+
+type ErrorDocument struct {
+	Key string `xml:"Key"`
+}
+
+type RedirectAllRequestsTo struct {
+	HostName string `xml:"HostName"`
+	Protocol string `xml:"Protocol,omitempty"`
+}
+
+// RoutingRule redirects the requests matching the condition, a rule
+// without a condition applies to all requests
+type RoutingRule struct {
+	Condition *RoutingRuleCondition `xml:"Condition,omitempty"`
+	Redirect  RoutingRuleRedirect   `xml:"Redirect"`
+}
+
+type RoutingRuleCondition struct {
+	HttpErrorCodeReturnedEquals string `xml:"HttpErrorCodeReturnedEquals,omitempty"`
+	KeyPrefixEquals             string `xml:"KeyPrefixEquals,omitempty"`
+}
+
+type RoutingRuleRedirect struct {
+	HostName             string `xml:"HostName,omitempty"`
+	HttpRedirectCode     string `xml:"HttpRedirectCode,omitempty"`
+	Protocol             string `xml:"Protocol,omitempty"`
+	ReplaceKeyPrefixWith string `xml:"ReplaceKeyPrefixWith,omitempty"`
+	ReplaceKeyWith       string `xml:"ReplaceKeyWith,omitempty"`
+}
+
 type DeleteObjects struct {
 	Objects []types.ObjectIdentifier `xml:"Object"`
 }
@@ -166,12 +296,33 @@ type CanonicalUser struct {
 	DisplayName string
 }
 
+// GetObjectAttributesResponse s3 api get object attributes response,
+// only the requested attributes are set
+type GetObjectAttributesResponse struct {
+	XMLName      xml.Name                        `xml:"http://s3.amazonaws.com/doc/2006-03-01/ GetObjectAttributesResponse" json:"-"`
+	ETag         *string                         `xml:"ETag,omitempty"`
+	Checksum     *types.Checksum                 `xml:"Checksum,omitempty"`
+	ObjectParts  *types.GetObjectAttributesParts `xml:"ObjectParts,omitempty"`
+	StorageClass types.StorageClass              `xml:"StorageClass,omitempty"`
+	ObjectSize   *int64                          `xml:"ObjectSize,omitempty"`
+}
+
 type CopyObjectResult struct {
 	XMLName      xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ CopyObjectResult" json:"-"`
 	LastModified time.Time
 	ETag         string
 }
 
+// PostResponse is the response of a POST Object upload with a
+// success_action_status of 201
+type PostResponse struct {
+	XMLName  xml.Name `xml:"PostResponse" json:"-"`
+	Location string
+	Bucket   string
+	Key      string
+	ETag     string
+}
+
 type AccessControlPolicy struct {
 	XMLName           xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ AccessControlPolicy" json:"-"`
 	Owner             CanonicalUser
@@ -200,3 +351,70 @@ type Grantee struct {
 	ID          string
 	DisplayName string
 }
+
+// STSCredentials are the temporary security credentials issued by the
+// security token service
+type STSCredentials struct {
+	AccessKeyId     string
+	SecretAccessKey string
+	SessionToken    string
+	Expiration      time.Time
+}
+
+type AssumedRoleUser struct {
+	Arn           string
+	AssumedRoleId string
+}
+
+type AssumeRoleResult struct {
+	Credentials     STSCredentials
+	AssumedRoleUser AssumedRoleUser
+}
+
+type AssumeRoleResponse struct {
+	XMLName          xml.Name `xml:"https://sts.amazonaws.com/doc/2011-06-15/ AssumeRoleResponse"`
+	AssumeRoleResult AssumeRoleResult
+	ResponseMetadata STSResponseMetadata
+}
+
+type AssumeRoleWithWebIdentityResult struct {
+	Credentials                 STSCredentials
+	SubjectFromWebIdentityToken string
+	AssumedRoleUser             AssumedRoleUser
+	Provider                    string
+	Audience                    string `xml:",omitempty"`
+}
+
+type AssumeRoleWithWebIdentityResponse struct {
+	XMLName                         xml.Name `xml:"https://sts.amazonaws.com/doc/2011-06-15/ AssumeRoleWithWebIdentityResponse"`
+	AssumeRoleWithWebIdentityResult AssumeRoleWithWebIdentityResult
+	ResponseMetadata                STSResponseMetadata
+}
+
+type GetSessionTokenResult struct {
+	Credentials STSCredentials
+}
+
+type GetSessionTokenResponse struct {
+	XMLName               xml.Name `xml:"https://sts.amazonaws.com/doc/2011-06-15/ GetSessionTokenResponse"`
+	GetSessionTokenResult GetSessionTokenResult
+	ResponseMetadata      STSResponseMetadata
+}
+
+type STSResponseMetadata struct {
+	RequestId string
+}
+
+// STSErrorResponse is the error response of the security token service,
+// which differs from the s3 error response
+type STSErrorResponse struct {
+	XMLName   xml.Name `xml:"https://sts.amazonaws.com/doc/2011-06-15/ ErrorResponse"`
+	Error     STSError
+	RequestId string
+}
+
+type STSError struct {
+	Type    string
+	Code    string
+	Message string
+}
@@ -0,0 +1,525 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package s3select
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/versity/versitygw/s3select/parquet"
+	"github.com/versity/versitygw/s3select/sql"
+)
+
+// recordReader reads the input records of the object
+type recordReader interface {
+	Read() (sql.Value, error)
+}
+
+// countingReader counts the bytes read for the Progress and Stats
+// messages
+type countingReader struct {
+	r io.Reader
+	n *int64
+}
+
+func (c countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	atomic.AddInt64(c.n, int64(n))
+	return n, err
+}
+
+// countingReaderAt counts the bytes read by a parquet reader
+type countingReaderAt struct {
+	r io.ReaderAt
+	n *int64
+}
+
+func (c countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := c.r.ReadAt(p, off)
+	atomic.AddInt64(c.n, int64(n))
+	return n, err
+}
+
+// csvOptions are the CSV input or output serialization characters
+type csvOptions struct {
+	fieldDelimiter  string
+	recordDelimiter string
+	quote           byte
+	quoteEscape     byte
+	comments        string
+	headerInfo      types.FileHeaderInfo
+}
+
+func singleChar(name, s string, def byte) (byte, error) {
+	switch len(s) {
+	case 0:
+		return def, nil
+	case 1:
+		return s[0], nil
+	}
+	return 0, newError(ErrCodeInvalidRequestParameter, "%v must be a single character", name)
+}
+
+func csvInputOptions(in *types.CSVInput) (csvOptions, error) {
+	opts := csvOptions{
+		fieldDelimiter:  getString(in.FieldDelimiter, ","),
+		recordDelimiter: getString(in.RecordDelimiter, "\n"),
+		comments:        getString(in.Comments, "#"),
+		headerInfo:      in.FileHeaderInfo,
+	}
+	if opts.fieldDelimiter == "" || opts.recordDelimiter == "" {
+		return opts, newError(ErrCodeInvalidRequestParameter, "delimiters must not be empty")
+	}
+
+	var err error
+	opts.quote, err = singleChar("QuoteCharacter", getString(in.QuoteCharacter, `"`), '"')
+	if err != nil {
+		return opts, err
+	}
+	opts.quoteEscape, err = singleChar("QuoteEscapeCharacter", getString(in.QuoteEscapeCharacter, `"`), '"')
+	if err != nil {
+		return opts, err
+	}
+
+	switch opts.headerInfo {
+	case "", types.FileHeaderInfoNone, types.FileHeaderInfoIgnore, types.FileHeaderInfoUse:
+	default:
+		return opts, newError(ErrCodeInvalidFileHeaderInfo, "invalid FileHeaderInfo %q", opts.headerInfo)
+	}
+	return opts, nil
+}
+
+func getString(s *string, def string) string {
+	if s == nil {
+		return def
+	}
+	return *s
+}
+
+// csvReader splits the input into records and fields, a field starting
+// with the quote character may contain the delimiters and escaped
+// quotes
+type csvReader struct {
+	r      *bufio.Reader
+	opts   csvOptions
+	names  []string
+	offset int64
+	// end is the offset after which no new records are started, -1 for
+	// the end of the input
+	end int64
+}
+
+func newCSVReader(r io.Reader, opts csvOptions) *csvReader {
+	return &csvReader{
+		r:    bufio.NewReaderSize(r, 64*1024),
+		opts: opts,
+		end:  -1,
+	}
+}
+
+// readHeader consumes the header line of the input
+func (c *csvReader) readHeader() error {
+	if c.opts.headerInfo != types.FileHeaderInfoUse &&
+		c.opts.headerInfo != types.FileHeaderInfoIgnore {
+		return nil
+	}
+	fields, err := c.readRecord()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if c.opts.headerInfo == types.FileHeaderInfoUse {
+		c.names = fields
+	}
+	return nil
+}
+
+// skipPartial skips to the start of the next record, this is used when
+// the scan range starts inside of a record
+func (c *csvReader) skipPartial() error {
+	for {
+		ok, err := c.consume(c.opts.recordDelimiter)
+		if ok || err != nil {
+			return err
+		}
+		if _, err := c.readByte(); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *csvReader) readByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.offset++
+	}
+	return b, err
+}
+
+// consume reads s if the input continues with it
+func (c *csvReader) consume(s string) (bool, error) {
+	p, err := c.r.Peek(len(s))
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	if string(p) != s {
+		return false, nil
+	}
+	c.r.Discard(len(s))
+	c.offset += int64(len(s))
+	return true, nil
+}
+
+func (c *csvReader) readRecord() ([]string, error) {
+	for {
+		if c.end >= 0 && c.offset > c.end {
+			return nil, io.EOF
+		}
+		if c.opts.comments != "" {
+			comment, err := c.consume(c.opts.comments)
+			if err != nil {
+				return nil, err
+			}
+			if comment {
+				if err := c.skipPartial(); err != nil && err != io.EOF {
+					return nil, err
+				}
+				continue
+			}
+		}
+		break
+	}
+
+	var fields []string
+	var field []byte
+	inQuotes, quoted, started := false, false, false
+
+	for {
+		if inQuotes {
+			b, err := c.readByte()
+			if err == io.EOF {
+				return nil, newError(ErrCodeCSVParsingError, "unterminated quoted field at offset %v", c.offset)
+			}
+			if err != nil {
+				return nil, err
+			}
+			if b == c.opts.quoteEscape && c.opts.quoteEscape != c.opts.quote {
+				ok, err := c.consume(string(c.opts.quote))
+				if err != nil {
+					return nil, err
+				}
+				if ok {
+					field = append(field, c.opts.quote)
+					continue
+				}
+			}
+			if b == c.opts.quote {
+				ok, err := c.consume(string(c.opts.quote))
+				if err != nil {
+					return nil, err
+				}
+				if ok && c.opts.quoteEscape == c.opts.quote {
+					field = append(field, c.opts.quote)
+					continue
+				}
+				inQuotes = false
+				continue
+			}
+			field = append(field, b)
+			continue
+		}
+
+		ok, err := c.consume(c.opts.recordDelimiter)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return append(fields, string(field)), nil
+		}
+		ok, err = c.consume(c.opts.fieldDelimiter)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			fields = append(fields, string(field))
+			field, quoted, started = field[:0:0], false, true
+			continue
+		}
+
+		b, err := c.readByte()
+		if err == io.EOF {
+			if !started && len(field) == 0 && !quoted && fields == nil {
+				return nil, io.EOF
+			}
+			return append(fields, string(field)), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		started = true
+		if b == c.opts.quote && len(field) == 0 && !quoted {
+			inQuotes, quoted = true, true
+			continue
+		}
+		field = append(field, b)
+	}
+}
+
+func (c *csvReader) Read() (sql.Value, error) {
+	fields, err := c.readRecord()
+	if err != nil {
+		return sql.Value{}, err
+	}
+	rec := make([]sql.Field, len(fields))
+	for i, f := range fields {
+		name := "_" + strconv.Itoa(i+1)
+		if i < len(c.names) {
+			name = c.names[i]
+		}
+		rec[i] = sql.Field{Name: name, Value: sql.String(f)}
+	}
+	return sql.Object(rec), nil
+}
+
+// jsonReader reads a stream of JSON values of a DOCUMENT input, the
+// values may span several lines
+type jsonReader struct {
+	dec *json.Decoder
+}
+
+func newJSONReader(r io.Reader) *jsonReader {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	return &jsonReader{dec: dec}
+}
+
+func (j *jsonReader) Read() (sql.Value, error) {
+	v, err := decodeJSON(j.dec)
+	if err == io.EOF {
+		return sql.Value{}, io.EOF
+	}
+	if err != nil {
+		return sql.Value{}, newError(ErrCodeJSONParsingError, "%v", err)
+	}
+	return v, nil
+}
+
+// jsonLinesReader reads a LINES input where each line holds a record
+type jsonLinesReader struct {
+	r      *bufio.Reader
+	offset int64
+	// end is the offset after which no new records are started, -1 for
+	// the end of the input
+	end int64
+}
+
+func newJSONLinesReader(r io.Reader) *jsonLinesReader {
+	return &jsonLinesReader{r: bufio.NewReaderSize(r, 64*1024), end: -1}
+}
+
+// skipPartial skips to the start of the next line, this is used when
+// the scan range starts inside of a record
+func (j *jsonLinesReader) skipPartial() error {
+	line, err := j.r.ReadBytes('\n')
+	j.offset += int64(len(line))
+	return err
+}
+
+func (j *jsonLinesReader) Read() (sql.Value, error) {
+	for {
+		if j.end >= 0 && j.offset > j.end {
+			return sql.Value{}, io.EOF
+		}
+		line, err := j.r.ReadBytes('\n')
+		j.offset += int64(len(line))
+		if err != nil && err != io.EOF {
+			return sql.Value{}, err
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
+			if err == io.EOF {
+				return sql.Value{}, io.EOF
+			}
+			continue
+		}
+
+		dec := json.NewDecoder(bytes.NewReader(line))
+		dec.UseNumber()
+		v, derr := decodeJSON(dec)
+		if derr == nil {
+			// a line holds a single value
+			if _, terr := dec.Token(); terr != io.EOF {
+				derr = fmt.Errorf("unexpected data after value at offset %v", j.offset)
+			}
+		}
+		if derr != nil {
+			return sql.Value{}, newError(ErrCodeJSONParsingError, "%v", unexpectedEOF(derr))
+		}
+		return v, nil
+	}
+}
+
+// decodeJSON decodes the next JSON value keeping the order of object
+// members
+func decodeJSON(dec *json.Decoder) (sql.Value, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return sql.Value{}, err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			var fields []sql.Field
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return sql.Value{}, unexpectedEOF(err)
+				}
+				name, _ := key.(string)
+				v, err := decodeJSON(dec)
+				if err != nil {
+					return sql.Value{}, unexpectedEOF(err)
+				}
+				fields = append(fields, sql.Field{Name: name, Value: v})
+			}
+			if _, err := dec.Token(); err != nil {
+				return sql.Value{}, unexpectedEOF(err)
+			}
+			return sql.Object(fields), nil
+		case '[':
+			values := []sql.Value{}
+			for dec.More() {
+				v, err := decodeJSON(dec)
+				if err != nil {
+					return sql.Value{}, unexpectedEOF(err)
+				}
+				values = append(values, v)
+			}
+			if _, err := dec.Token(); err != nil {
+				return sql.Value{}, unexpectedEOF(err)
+			}
+			return sql.List(values), nil
+		}
+		return sql.Value{}, fmt.Errorf("unexpected %v", t)
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return sql.Int(i), nil
+		}
+		f, err := t.Float64()
+		if err != nil {
+			return sql.Value{}, err
+		}
+		return sql.Float(f), nil
+	case string:
+		return sql.String(t), nil
+	case bool:
+		return sql.Bool(t), nil
+	case nil:
+		return sql.Null(), nil
+	}
+	return sql.Value{}, fmt.Errorf("unexpected token %v", tok)
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// parquetReader builds the records from the rows of a parquet file,
+// the columns of nested groups become nested objects
+type parquetReader struct {
+	r     *parquet.Reader
+	paths [][]string
+}
+
+func newParquetReader(r io.ReaderAt, size int64) (*parquetReader, error) {
+	pr, err := parquet.NewReader(r, size)
+	if err != nil {
+		if errors.Is(err, parquet.ErrUnsupported) {
+			return nil, newError(ErrCodeUnsupportedParquetType, "%v", err)
+		}
+		if errors.Is(err, parquet.ErrInvalidFile) {
+			return nil, newError(ErrCodeParquetParsingError, "%v", err)
+		}
+		return nil, err
+	}
+	return &parquetReader{r: pr, paths: pr.Columns()}, nil
+}
+
+func (p *parquetReader) Read() (sql.Value, error) {
+	row, err := p.r.Read()
+	if err == io.EOF {
+		return sql.Value{}, io.EOF
+	}
+	if err != nil {
+		if errors.Is(err, parquet.ErrUnsupported) {
+			return sql.Value{}, newError(ErrCodeUnsupportedParquetType, "%v", err)
+		}
+		return sql.Value{}, newError(ErrCodeParquetParsingError, "%v", err)
+	}
+
+	var fields []sql.Field
+	for i, v := range row {
+		fields = insertField(fields, p.paths[i], parquetValue(v))
+	}
+	return sql.Object(fields), nil
+}
+
+// insertField adds the value at the nested path to the object fields
+func insertField(fields []sql.Field, path []string, v sql.Value) []sql.Field {
+	if len(path) == 1 {
+		return append(fields, sql.Field{Name: path[0], Value: v})
+	}
+	for i := range fields {
+		if fields[i].Name == path[0] {
+			fields[i].Value = sql.Object(insertField(fields[i].Value.Fields(), path[1:], v))
+			return fields
+		}
+	}
+	return append(fields, sql.Field{
+		Name:  path[0],
+		Value: sql.Object(insertField(nil, path[1:], v)),
+	})
+}
+
+func parquetValue(v any) sql.Value {
+	switch v := v.(type) {
+	case bool:
+		return sql.Bool(v)
+	case int64:
+		return sql.Int(v)
+	case float64:
+		return sql.Float(v)
+	case string:
+		return sql.String(v)
+	case time.Time:
+		return sql.Timestamp(v)
+	case []byte:
+		return sql.String(string(bytes.Clone(v)))
+	}
+	return sql.Null()
+}
@@ -0,0 +1,108 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package s3select
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/versity/versitygw/s3select/sql"
+)
+
+// recordWriter serializes the rows returned by the query
+type recordWriter interface {
+	Append(b []byte, row sql.Value) []byte
+}
+
+type csvWriter struct {
+	fieldDelimiter  string
+	recordDelimiter string
+	quote           byte
+	quoteEscape     byte
+	always          bool
+}
+
+func newCSVWriter(out *types.CSVOutput) (*csvWriter, error) {
+	w := &csvWriter{
+		fieldDelimiter:  getString(out.FieldDelimiter, ","),
+		recordDelimiter: getString(out.RecordDelimiter, "\n"),
+	}
+	if w.fieldDelimiter == "" || w.recordDelimiter == "" {
+		return nil, newError(ErrCodeInvalidRequestParameter, "delimiters must not be empty")
+	}
+
+	var err error
+	w.quote, err = singleChar("QuoteCharacter", getString(out.QuoteCharacter, `"`), '"')
+	if err != nil {
+		return nil, err
+	}
+	w.quoteEscape, err = singleChar("QuoteEscapeCharacter", getString(out.QuoteEscapeCharacter, `"`), '"')
+	if err != nil {
+		return nil, err
+	}
+
+	switch out.QuoteFields {
+	case "", types.QuoteFieldsAsneeded:
+	case types.QuoteFieldsAlways:
+		w.always = true
+	default:
+		return nil, newError(ErrCodeInvalidQuoteFields, "invalid QuoteFields %q", out.QuoteFields)
+	}
+	return w, nil
+}
+
+func (w *csvWriter) Append(b []byte, row sql.Value) []byte {
+	for i, f := range row.Fields() {
+		if i > 0 {
+			b = append(b, w.fieldDelimiter...)
+		}
+		b = w.appendField(b, f.Value.Text())
+	}
+	return append(b, w.recordDelimiter...)
+}
+
+func (w *csvWriter) appendField(b []byte, s string) []byte {
+	if !w.always && !w.needsQuotes(s) {
+		return append(b, s...)
+	}
+	b = append(b, w.quote)
+	for i := 0; i < len(s); i++ {
+		if s[i] == w.quote {
+			b = append(b, w.quoteEscape)
+		}
+		b = append(b, s[i])
+	}
+	return append(b, w.quote)
+}
+
+func (w *csvWriter) needsQuotes(s string) bool {
+	return strings.IndexByte(s, w.quote) >= 0 ||
+		strings.Contains(s, w.fieldDelimiter) ||
+		strings.Contains(s, w.recordDelimiter) ||
+		strings.ContainsAny(s, "\r\n")
+}
+
+type jsonWriter struct {
+	recordDelimiter string
+}
+
+func newJSONWriter(out *types.JSONOutput) *jsonWriter {
+	return &jsonWriter{recordDelimiter: getString(out.RecordDelimiter, "\n")}
+}
+
+func (w *jsonWriter) Append(b []byte, row sql.Value) []byte {
+	b = row.AppendJSON(b)
+	return append(b, w.recordDelimiter...)
+}
@@ -0,0 +1,167 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package parquet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+var errCorruptPage = errors.New("corrupt parquet page")
+
+// decodeRLE decodes count values of the RLE/bit packing hybrid encoding
+// used for definition levels, booleans and dictionary indexes. The runs
+// may hold any number of values in a few bytes, count must be bounded by
+// the caller.
+func decodeRLE(data []byte, bitWidth int, count int) ([]int32, error) {
+	if bitWidth < 0 || bitWidth > 32 || count < 0 || count > maxPageValues {
+		return nil, errCorruptPage
+	}
+	values := make([]int32, 0, count)
+	byteWidth := (bitWidth + 7) / 8
+
+	for len(values) < count {
+		header, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, errCorruptPage
+		}
+		data = data[n:]
+
+		if header&1 == 0 {
+			// rle run of a single repeated value
+			run := int(header >> 1)
+			if len(data) < byteWidth {
+				return nil, errCorruptPage
+			}
+			var v uint32
+			for i := 0; i < byteWidth; i++ {
+				v |= uint32(data[i]) << (8 * i)
+			}
+			data = data[byteWidth:]
+			for i := 0; i < run && len(values) < count; i++ {
+				values = append(values, int32(v))
+			}
+			continue
+		}
+
+		// bit packed groups of 8 values
+		groups := header >> 1
+		if groups > uint64(count) {
+			// the groups past count are not decoded
+			groups = uint64(count)
+		}
+		size := int(groups) * bitWidth
+		if len(data) < size {
+			size = len(data)
+		}
+		packed := data[:size]
+		data = data[size:]
+		for i := 0; i < int(groups)*8 && len(values) < count; i++ {
+			bit := i * bitWidth
+			var v uint32
+			for b := 0; b < bitWidth; b++ {
+				pos := bit + b
+				if pos/8 >= len(packed) {
+					return nil, errCorruptPage
+				}
+				v |= uint32(packed[pos/8]>>(pos%8)&1) << b
+			}
+			values = append(values, int32(v))
+		}
+	}
+
+	return values, nil
+}
+
+// decodePlain decodes count values of the PLAIN encoding of the
+// physical type
+func decodePlain(data []byte, typ int32, typeLength int32, count int) ([]any, error) {
+	// the values take at least a bit each
+	if count < 0 || count > len(data)*8 {
+		return nil, errCorruptPage
+	}
+	values := make([]any, 0, count)
+
+	switch typ {
+	case typeBoolean:
+		if len(data)*8 < count {
+			return nil, errCorruptPage
+		}
+		for i := 0; i < count; i++ {
+			values = append(values, data[i/8]>>(i%8)&1 == 1)
+		}
+	case typeInt32:
+		if len(data) < count*4 {
+			return nil, errCorruptPage
+		}
+		for i := 0; i < count; i++ {
+			values = append(values, int32(binary.LittleEndian.Uint32(data[i*4:])))
+		}
+	case typeInt64:
+		if len(data) < count*8 {
+			return nil, errCorruptPage
+		}
+		for i := 0; i < count; i++ {
+			values = append(values, int64(binary.LittleEndian.Uint64(data[i*8:])))
+		}
+	case typeInt96:
+		if len(data) < count*12 {
+			return nil, errCorruptPage
+		}
+		for i := 0; i < count; i++ {
+			values = append(values, [12]byte(data[i*12:i*12+12]))
+		}
+	case typeFloat:
+		if len(data) < count*4 {
+			return nil, errCorruptPage
+		}
+		for i := 0; i < count; i++ {
+			values = append(values, math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:])))
+		}
+	case typeDoubleValue:
+		if len(data) < count*8 {
+			return nil, errCorruptPage
+		}
+		for i := 0; i < count; i++ {
+			values = append(values, math.Float64frombits(binary.LittleEndian.Uint64(data[i*8:])))
+		}
+	case typeByteArray:
+		for i := 0; i < count; i++ {
+			if len(data) < 4 {
+				return nil, errCorruptPage
+			}
+			size := int(binary.LittleEndian.Uint32(data))
+			if size < 0 || len(data)-4 < size {
+				return nil, errCorruptPage
+			}
+			values = append(values, data[4:4+size])
+			data = data[4+size:]
+		}
+	case typeFixedLenByteArray:
+		size := int(typeLength)
+		if size <= 0 || len(data) < count*size {
+			return nil, errCorruptPage
+		}
+		for i := 0; i < count; i++ {
+			values = append(values, data[i*size:(i+1)*size])
+		}
+	default:
+		return nil, fmt.Errorf("unsupported parquet type %v", typ)
+	}
+
+	return values, nil
+}
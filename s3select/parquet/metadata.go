@@ -0,0 +1,378 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package parquet
+
+// physical types
+const (
+	typeBoolean           = 0
+	typeInt32             = 1
+	typeInt64             = 2
+	typeInt96             = 3
+	typeFloat             = 4
+	typeDoubleValue       = 5
+	typeByteArray         = 6
+	typeFixedLenByteArray = 7
+)
+
+// repetition types
+const (
+	repetitionRequired = 0
+	repetitionOptional = 1
+	repetitionRepeated = 2
+)
+
+// converted types
+const (
+	convertedUTF8            = 0
+	convertedDecimal         = 5
+	convertedDate            = 6
+	convertedTimestampMillis = 9
+	convertedTimestampMicros = 10
+)
+
+// compression codecs
+const (
+	codecUncompressed = 0
+	codecSnappy       = 1
+	codecGzip         = 2
+	codecZstd         = 6
+)
+
+// page types
+const (
+	pageData       = 0
+	pageDictionary = 2
+	pageDataV2     = 3
+)
+
+// encodings
+const (
+	encodingPlain           = 0
+	encodingPlainDictionary = 2
+	encodingRLE             = 3
+	encodingRLEDictionary   = 8
+)
+
+// time units of timestamps
+const (
+	unitMillis = 1
+	unitMicros = 2
+	unitNanos  = 3
+)
+
+type schemaElement struct {
+	typ           int32
+	hasType       bool
+	typeLength    int32
+	repetition    int32
+	name          string
+	numChildren   int32
+	convertedType int32
+	hasConverted  bool
+	scale         int32
+
+	// logical types
+	isString      bool
+	isDate        bool
+	isDecimal     bool
+	timestampUnit int
+}
+
+type columnMetaData struct {
+	typ                   int32
+	codec                 int32
+	numValues             int64
+	totalCompressedSize   int64
+	dataPageOffset        int64
+	dictionaryPageOffset  int64
+	hasDictionaryOffset   bool
+	totalUncompressedSize int64
+}
+
+type columnChunk struct {
+	meta columnMetaData
+}
+
+type rowGroup struct {
+	columns []columnChunk
+	numRows int64
+}
+
+type fileMetaData struct {
+	schema    []schemaElement
+	numRows   int64
+	rowGroups []rowGroup
+}
+
+type dataPageHeader struct {
+	numValues int32
+	encoding  int32
+}
+
+type dataPageHeaderV2 struct {
+	numValues       int32
+	numNulls        int32
+	numRows         int32
+	encoding        int32
+	defLevelsLength int32
+	repLevelsLength int32
+	isCompressed    bool
+}
+
+type dictionaryPageHeader struct {
+	numValues int32
+	encoding  int32
+}
+
+type pageHeader struct {
+	typ                  int32
+	uncompressedPageSize int32
+	compressedPageSize   int32
+	dataPage             *dataPageHeader
+	dataPageV2           *dataPageHeaderV2
+	dictionaryPage       *dictionaryPageHeader
+}
+
+func (t *thriftReader) readFileMetaData() (*fileMetaData, error) {
+	var md fileMetaData
+	err := t.readStruct(func(id int16, typ byte) error {
+		var err error
+		switch {
+		case id == 2 && typ == typeList:
+			err = t.readList(func(byte) error {
+				el, err := t.readSchemaElement()
+				md.schema = append(md.schema, el)
+				return err
+			})
+		case id == 3 && typ == typeI64:
+			md.numRows, err = t.readI64()
+		case id == 4 && typ == typeList:
+			err = t.readList(func(byte) error {
+				rg, err := t.readRowGroup()
+				md.rowGroups = append(md.rowGroups, rg)
+				return err
+			})
+		default:
+			err = t.skip(typ)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &md, nil
+}
+
+func (t *thriftReader) readSchemaElement() (schemaElement, error) {
+	var el schemaElement
+	err := t.readStruct(func(id int16, typ byte) error {
+		var err error
+		switch {
+		case id == 1 && typ == typeI32:
+			el.typ, err = t.readI32()
+			el.hasType = true
+		case id == 2 && typ == typeI32:
+			el.typeLength, err = t.readI32()
+		case id == 3 && typ == typeI32:
+			el.repetition, err = t.readI32()
+		case id == 4 && typ == typeBinary:
+			el.name, err = t.readString()
+		case id == 5 && typ == typeI32:
+			el.numChildren, err = t.readI32()
+		case id == 6 && typ == typeI32:
+			el.convertedType, err = t.readI32()
+			el.hasConverted = true
+		case id == 7 && typ == typeI32:
+			el.scale, err = t.readI32()
+		case id == 10 && typ == typeStruct:
+			err = t.readLogicalType(&el)
+		default:
+			err = t.skip(typ)
+		}
+		return err
+	})
+	return el, err
+}
+
+// readLogicalType reads the logical type union of a schema element
+func (t *thriftReader) readLogicalType(el *schemaElement) error {
+	return t.readStruct(func(id int16, typ byte) error {
+		switch id {
+		case 1, 4, 12:
+			// STRING, ENUM and JSON
+			el.isString = true
+		case 5:
+			el.isDecimal = true
+			return t.readStruct(func(id int16, typ byte) error {
+				if id == 1 && typ == typeI32 {
+					var err error
+					el.scale, err = t.readI32()
+					return err
+				}
+				return t.skip(typ)
+			})
+		case 6:
+			el.isDate = true
+		case 8:
+			return t.readStruct(func(id int16, typ byte) error {
+				if id != 2 || typ != typeStruct {
+					return t.skip(typ)
+				}
+				return t.readStruct(func(id int16, typ byte) error {
+					el.timestampUnit = int(id)
+					return t.skip(typ)
+				})
+			})
+		}
+		return t.skip(typ)
+	})
+}
+
+func (t *thriftReader) readRowGroup() (rowGroup, error) {
+	var rg rowGroup
+	err := t.readStruct(func(id int16, typ byte) error {
+		var err error
+		switch {
+		case id == 1 && typ == typeList:
+			err = t.readList(func(byte) error {
+				cc, err := t.readColumnChunk()
+				rg.columns = append(rg.columns, cc)
+				return err
+			})
+		case id == 3 && typ == typeI64:
+			rg.numRows, err = t.readI64()
+		default:
+			err = t.skip(typ)
+		}
+		return err
+	})
+	return rg, err
+}
+
+func (t *thriftReader) readColumnChunk() (columnChunk, error) {
+	var cc columnChunk
+	err := t.readStruct(func(id int16, typ byte) error {
+		if id == 3 && typ == typeStruct {
+			var err error
+			cc.meta, err = t.readColumnMetaData()
+			return err
+		}
+		return t.skip(typ)
+	})
+	return cc, err
+}
+
+func (t *thriftReader) readColumnMetaData() (columnMetaData, error) {
+	var md columnMetaData
+	err := t.readStruct(func(id int16, typ byte) error {
+		var err error
+		switch {
+		case id == 1 && typ == typeI32:
+			md.typ, err = t.readI32()
+		case id == 4 && typ == typeI32:
+			md.codec, err = t.readI32()
+		case id == 5 && typ == typeI64:
+			md.numValues, err = t.readI64()
+		case id == 6 && typ == typeI64:
+			md.totalUncompressedSize, err = t.readI64()
+		case id == 7 && typ == typeI64:
+			md.totalCompressedSize, err = t.readI64()
+		case id == 9 && typ == typeI64:
+			md.dataPageOffset, err = t.readI64()
+		case id == 11 && typ == typeI64:
+			md.dictionaryPageOffset, err = t.readI64()
+			md.hasDictionaryOffset = true
+		default:
+			err = t.skip(typ)
+		}
+		return err
+	})
+	return md, err
+}
+
+func (t *thriftReader) readPageHeader() (*pageHeader, error) {
+	var ph pageHeader
+	err := t.readStruct(func(id int16, typ byte) error {
+		var err error
+		switch {
+		case id == 1 && typ == typeI32:
+			ph.typ, err = t.readI32()
+		case id == 2 && typ == typeI32:
+			ph.uncompressedPageSize, err = t.readI32()
+		case id == 3 && typ == typeI32:
+			ph.compressedPageSize, err = t.readI32()
+		case id == 5 && typ == typeStruct:
+			ph.dataPage = &dataPageHeader{}
+			err = t.readStruct(func(id int16, typ byte) error {
+				var err error
+				switch {
+				case id == 1 && typ == typeI32:
+					ph.dataPage.numValues, err = t.readI32()
+				case id == 2 && typ == typeI32:
+					ph.dataPage.encoding, err = t.readI32()
+				default:
+					err = t.skip(typ)
+				}
+				return err
+			})
+		case id == 7 && typ == typeStruct:
+			ph.dictionaryPage = &dictionaryPageHeader{}
+			err = t.readStruct(func(id int16, typ byte) error {
+				var err error
+				switch {
+				case id == 1 && typ == typeI32:
+					ph.dictionaryPage.numValues, err = t.readI32()
+				case id == 2 && typ == typeI32:
+					ph.dictionaryPage.encoding, err = t.readI32()
+				default:
+					err = t.skip(typ)
+				}
+				return err
+			})
+		case id == 8 && typ == typeStruct:
+			ph.dataPageV2 = &dataPageHeaderV2{isCompressed: true}
+			err = t.readStruct(func(id int16, typ byte) error {
+				var err error
+				v2 := ph.dataPageV2
+				switch {
+				case id == 1 && typ == typeI32:
+					v2.numValues, err = t.readI32()
+				case id == 2 && typ == typeI32:
+					v2.numNulls, err = t.readI32()
+				case id == 3 && typ == typeI32:
+					v2.numRows, err = t.readI32()
+				case id == 4 && typ == typeI32:
+					v2.encoding, err = t.readI32()
+				case id == 5 && typ == typeI32:
+					v2.defLevelsLength, err = t.readI32()
+				case id == 6 && typ == typeI32:
+					v2.repLevelsLength, err = t.readI32()
+				case id == 7 && (typ == typeTrue || typ == typeFalse):
+					v2.isCompressed = readBool(typ)
+				default:
+					err = t.skip(typ)
+				}
+				return err
+			})
+		default:
+			err = t.skip(typ)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ph, nil
+}
@@ -0,0 +1,523 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package parquet implements a minimal reader of the parquet columnar
+// file format for S3 Select. It supports flat and nested (but not
+// repeated) schemas, PLAIN and dictionary encodings, data page v1 and
+// v2 and the uncompressed, snappy, gzip and zstd codecs.
+package parquet
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"math/bits"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	magic         = "PAR1"
+	footerSize    = 8
+	maxFooterSize = 64 * 1024 * 1024
+	maxPageSize   = 256 * 1024 * 1024
+	// maxPageValues bounds the values of a page, the definition levels
+	// and dictionary indexes of any number of values fit in a few bytes
+	maxPageValues = 16 * 1024 * 1024
+)
+
+var (
+	// ErrInvalidFile is returned when the object is not a parquet file
+	ErrInvalidFile = errors.New("invalid parquet file")
+	// ErrUnsupported is returned for parquet features not supported by
+	// the reader
+	ErrUnsupported = errors.New("unsupported parquet feature")
+)
+
+var (
+	zstdOnce    sync.Once
+	zstdDecoder *zstd.Decoder
+	zstdErr     error
+)
+
+// Reader reads the rows of a parquet file
+type Reader struct {
+	r        io.ReaderAt
+	md       *fileMetaData
+	columns  []column
+	group    int
+	readers  []*columnReader
+	rowsLeft int64
+}
+
+type column struct {
+	path   []string
+	leaf   schemaElement
+	maxDef int
+}
+
+// NewReader reads the footer of the parquet file of size bytes
+func NewReader(r io.ReaderAt, size int64) (*Reader, error) {
+	if size < int64(len(magic)+footerSize) {
+		return nil, ErrInvalidFile
+	}
+
+	var footer [footerSize]byte
+	if _, err := r.ReadAt(footer[:], size-footerSize); err != nil {
+		return nil, err
+	}
+	if string(footer[4:]) != magic {
+		return nil, ErrInvalidFile
+	}
+	mdSize := int64(binary.LittleEndian.Uint32(footer[:4]))
+	if mdSize > maxFooterSize || mdSize > size-footerSize-int64(len(magic)) {
+		return nil, ErrInvalidFile
+	}
+
+	buf := make([]byte, mdSize)
+	if _, err := r.ReadAt(buf, size-footerSize-mdSize); err != nil {
+		return nil, err
+	}
+	t := &thriftReader{r: bytes.NewReader(buf)}
+	md, err := t.readFileMetaData()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	if len(md.schema) == 0 {
+		return nil, ErrInvalidFile
+	}
+
+	pr := &Reader{r: r, md: md, group: -1}
+	next, err := pr.walkSchema(1, int(md.schema[0].numChildren), nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	if next != len(md.schema) {
+		return nil, ErrInvalidFile
+	}
+	for _, rg := range md.rowGroups {
+		if len(rg.columns) != len(pr.columns) {
+			return nil, ErrInvalidFile
+		}
+	}
+
+	return pr, nil
+}
+
+// walkSchema collects the leaf columns of the flattened schema tree
+func (r *Reader) walkSchema(idx, children int, path []string, maxDef int) (int, error) {
+	for i := 0; i < children; i++ {
+		if idx >= len(r.md.schema) {
+			return 0, ErrInvalidFile
+		}
+		el := r.md.schema[idx]
+		idx++
+
+		def := maxDef
+		switch el.repetition {
+		case repetitionOptional:
+			def++
+		case repetitionRepeated:
+			return 0, fmt.Errorf("%w: repeated field %q", ErrUnsupported, el.name)
+		}
+
+		elPath := append(append([]string{}, path...), el.name)
+		if el.numChildren > 0 {
+			var err error
+			idx, err = r.walkSchema(idx, int(el.numChildren), elPath, def)
+			if err != nil {
+				return 0, err
+			}
+			continue
+		}
+		if !el.hasType {
+			return 0, ErrInvalidFile
+		}
+		r.columns = append(r.columns, column{path: elPath, leaf: el, maxDef: def})
+	}
+	return idx, nil
+}
+
+// Columns returns the path of each column in the schema, nested
+// columns have one element per group
+func (r *Reader) Columns() [][]string {
+	paths := make([][]string, len(r.columns))
+	for i, c := range r.columns {
+		paths[i] = c.path
+	}
+	return paths
+}
+
+// NumRows returns the total number of rows in the file
+func (r *Reader) NumRows() int64 {
+	return r.md.numRows
+}
+
+// Read returns the values of the next row in column order, a nil
+// value is a null. Values are bool, int64, float64, string or
+// time.Time. Read returns io.EOF after the last row.
+func (r *Reader) Read() ([]any, error) {
+	for r.rowsLeft == 0 {
+		r.group++
+		if r.group >= len(r.md.rowGroups) {
+			return nil, io.EOF
+		}
+		rg := r.md.rowGroups[r.group]
+		r.rowsLeft = rg.numRows
+		r.readers = make([]*columnReader, len(r.columns))
+		for i := range r.columns {
+			r.readers[i] = newColumnReader(r.r, &r.columns[i], rg.columns[i].meta, rg.numRows)
+		}
+	}
+
+	row := make([]any, len(r.columns))
+	for i, cr := range r.readers {
+		v, err := cr.next()
+		if err != nil {
+			return nil, err
+		}
+		row[i] = v
+	}
+	r.rowsLeft--
+	return row, nil
+}
+
+// columnReader reads the values of a column chunk page by page
+type columnReader struct {
+	r      io.ReaderAt
+	col    *column
+	meta   columnMetaData
+	offset int64
+	end    int64
+	dict   []any
+	values []any
+	pos    int
+	// rowsLeft is the number of values of the row group not yet read,
+	// the columns are not repeated so each row has one value
+	rowsLeft int64
+}
+
+func newColumnReader(r io.ReaderAt, col *column, meta columnMetaData, numRows int64) *columnReader {
+	start := meta.dataPageOffset
+	if meta.hasDictionaryOffset && meta.dictionaryPageOffset > 0 &&
+		meta.dictionaryPageOffset < start {
+		start = meta.dictionaryPageOffset
+	}
+	return &columnReader{
+		r:        r,
+		col:      col,
+		meta:     meta,
+		offset:   start,
+		end:      start + meta.totalCompressedSize,
+		rowsLeft: numRows,
+	}
+}
+
+// pageValues checks the number of values of a data page before the values
+// are allocated
+func (c *columnReader) pageValues(numValues int32) (int, error) {
+	if numValues < 0 || int64(numValues) > c.rowsLeft || numValues > maxPageValues {
+		return 0, fmt.Errorf("%w: column %v page of %v values", ErrInvalidFile, c.col.path, numValues)
+	}
+	c.rowsLeft -= int64(numValues)
+	return int(numValues), nil
+}
+
+func (c *columnReader) next() (any, error) {
+	for c.pos >= len(c.values) {
+		if err := c.readPage(); err != nil {
+			return nil, err
+		}
+	}
+	v := c.values[c.pos]
+	c.pos++
+	return v, nil
+}
+
+func (c *columnReader) readPage() error {
+	if c.offset >= c.end {
+		return fmt.Errorf("%w: column %v ended early", ErrInvalidFile, c.col.path)
+	}
+
+	t := &thriftReader{r: bufio.NewReader(io.NewSectionReader(c.r, c.offset, c.end-c.offset))}
+	ph, err := t.readPageHeader()
+	if err != nil {
+		return fmt.Errorf("%w: page header: %v", ErrInvalidFile, err)
+	}
+	if ph.compressedPageSize < 0 || ph.compressedPageSize > maxPageSize ||
+		ph.uncompressedPageSize < 0 || ph.uncompressedPageSize > maxPageSize {
+		return ErrInvalidFile
+	}
+
+	payload := make([]byte, ph.compressedPageSize)
+	if _, err := c.r.ReadAt(payload, c.offset+t.n); err != nil {
+		return err
+	}
+	c.offset += t.n + int64(ph.compressedPageSize)
+
+	switch ph.typ {
+	case pageDictionary:
+		if ph.dictionaryPage == nil {
+			return ErrInvalidFile
+		}
+		data, err := c.decompress(payload, int(ph.uncompressedPageSize))
+		if err != nil {
+			return err
+		}
+		raw, err := decodePlain(data, c.meta.typ, c.col.leaf.typeLength, int(ph.dictionaryPage.numValues))
+		if err != nil {
+			return err
+		}
+		c.dict = make([]any, len(raw))
+		for i, v := range raw {
+			c.dict[i] = convert(v, c.col.leaf)
+		}
+		return nil
+	case pageData:
+		if ph.dataPage == nil {
+			return ErrInvalidFile
+		}
+		data, err := c.decompress(payload, int(ph.uncompressedPageSize))
+		if err != nil {
+			return err
+		}
+		count, err := c.pageValues(ph.dataPage.numValues)
+		if err != nil {
+			return err
+		}
+		var defs []int32
+		if c.col.maxDef > 0 {
+			if len(data) < 4 {
+				return errCorruptPage
+			}
+			size := int(binary.LittleEndian.Uint32(data))
+			if size < 0 || size > len(data)-4 {
+				return errCorruptPage
+			}
+			defs, err = decodeRLE(data[4:4+size], bitWidth(c.col.maxDef), count)
+			if err != nil {
+				return err
+			}
+			data = data[4+size:]
+		}
+		return c.decodeValues(data, ph.dataPage.encoding, count, defs)
+	case pageDataV2:
+		v2 := ph.dataPageV2
+		if v2 == nil || v2.repLevelsLength < 0 || v2.defLevelsLength < 0 ||
+			int(v2.repLevelsLength)+int(v2.defLevelsLength) > len(payload) {
+			return ErrInvalidFile
+		}
+		count, err := c.pageValues(v2.numValues)
+		if err != nil {
+			return err
+		}
+		levels := payload[v2.repLevelsLength : v2.repLevelsLength+v2.defLevelsLength]
+		data := payload[v2.repLevelsLength+v2.defLevelsLength:]
+		var defs []int32
+		if c.col.maxDef > 0 {
+			defs, err = decodeRLE(levels, bitWidth(c.col.maxDef), count)
+			if err != nil {
+				return err
+			}
+		}
+		if v2.isCompressed {
+			size := int(ph.uncompressedPageSize) - int(v2.repLevelsLength) - int(v2.defLevelsLength)
+			data, err = c.decompress(data, size)
+			if err != nil {
+				return err
+			}
+		}
+		return c.decodeValues(data, v2.encoding, count, defs)
+	}
+
+	// skip index pages and unknown page types
+	return nil
+}
+
+// decodeValues decodes the values of a data page, defs holds the
+// definition level of each value when the column is optional
+func (c *columnReader) decodeValues(data []byte, encoding int32, count int, defs []int32) error {
+	nonNull := count
+	if defs != nil {
+		nonNull = 0
+		for _, d := range defs {
+			if int(d) == c.col.maxDef {
+				nonNull++
+			}
+		}
+	}
+
+	var vals []any
+	switch encoding {
+	case encodingPlain:
+		raw, err := decodePlain(data, c.meta.typ, c.col.leaf.typeLength, nonNull)
+		if err != nil {
+			return err
+		}
+		vals = make([]any, len(raw))
+		for i, v := range raw {
+			vals[i] = convert(v, c.col.leaf)
+		}
+	case encodingPlainDictionary, encodingRLEDictionary:
+		if c.dict == nil || len(data) < 1 {
+			return errCorruptPage
+		}
+		idx, err := decodeRLE(data[1:], int(data[0]), nonNull)
+		if err != nil {
+			return err
+		}
+		vals = make([]any, len(idx))
+		for i, n := range idx {
+			if n < 0 || int(n) >= len(c.dict) {
+				return errCorruptPage
+			}
+			vals[i] = c.dict[n]
+		}
+	case encodingRLE:
+		if c.meta.typ != typeBoolean || len(data) < 4 {
+			return fmt.Errorf("%w: encoding %v", ErrUnsupported, encoding)
+		}
+		bools, err := decodeRLE(data[4:], 1, nonNull)
+		if err != nil {
+			return err
+		}
+		vals = make([]any, len(bools))
+		for i, b := range bools {
+			vals[i] = b == 1
+		}
+	default:
+		return fmt.Errorf("%w: encoding %v", ErrUnsupported, encoding)
+	}
+
+	if defs == nil {
+		c.values = vals
+	} else {
+		c.values = make([]any, count)
+		j := 0
+		for i, d := range defs {
+			if int(d) == c.col.maxDef {
+				c.values[i] = vals[j]
+				j++
+			}
+		}
+	}
+	c.pos = 0
+	return nil
+}
+
+func (c *columnReader) decompress(data []byte, size int) ([]byte, error) {
+	switch c.meta.codec {
+	case codecUncompressed:
+		return data, nil
+	case codecSnappy:
+		// s2 decodes snappy blocks
+		return s2.Decode(make([]byte, 0, size), data)
+	case codecGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		out := make([]byte, 0, size)
+		buf := bytes.NewBuffer(out)
+		if _, err := io.Copy(buf, io.LimitReader(zr, maxPageSize)); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case codecZstd:
+		zstdOnce.Do(func() {
+			zstdDecoder, zstdErr = zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+		})
+		if zstdErr != nil {
+			return nil, zstdErr
+		}
+		return zstdDecoder.DecodeAll(data, make([]byte, 0, size))
+	}
+	return nil, fmt.Errorf("%w: compression codec %v", ErrUnsupported, c.meta.codec)
+}
+
+func bitWidth(max int) int {
+	return bits.Len(uint(max))
+}
+
+// julianUnixEpoch is the julian day number of 1970-01-01
+const julianUnixEpoch = 2440588
+
+// convert maps a physical value to the value of its logical type
+func convert(v any, el schemaElement) any {
+	decimal := el.isDecimal || (el.hasConverted && el.convertedType == convertedDecimal)
+
+	switch v := v.(type) {
+	case int32:
+		switch {
+		case el.isDate || (el.hasConverted && el.convertedType == convertedDate):
+			return time.Unix(int64(v)*86400, 0).UTC()
+		case decimal:
+			return float64(v) / math.Pow10(int(el.scale))
+		}
+		return int64(v)
+	case int64:
+		unit := el.timestampUnit
+		if el.hasConverted {
+			switch el.convertedType {
+			case convertedTimestampMillis:
+				unit = unitMillis
+			case convertedTimestampMicros:
+				unit = unitMicros
+			}
+		}
+		switch {
+		case unit == unitMillis:
+			return time.UnixMilli(v).UTC()
+		case unit == unitMicros:
+			return time.UnixMicro(v).UTC()
+		case unit == unitNanos:
+			return time.Unix(0, v).UTC()
+		case decimal:
+			return float64(v) / math.Pow10(int(el.scale))
+		}
+		return v
+	case [12]byte:
+		nanos := int64(binary.LittleEndian.Uint64(v[:8]))
+		days := int64(binary.LittleEndian.Uint32(v[8:]))
+		return time.Unix((days-julianUnixEpoch)*86400, nanos).UTC()
+	case float32:
+		return float64(v)
+	case []byte:
+		if decimal {
+			return decodeDecimal(v, el.scale)
+		}
+		return string(v)
+	}
+	return v
+}
+
+// decodeDecimal decodes a big endian two's complement unscaled decimal
+func decodeDecimal(b []byte, scale int32) float64 {
+	n := new(big.Int).SetBytes(b)
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(len(b)*8)))
+	}
+	f, _ := new(big.Float).SetInt(n).Float64()
+	return f / math.Pow10(int(scale))
+}
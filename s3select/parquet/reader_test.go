@@ -0,0 +1,421 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/s2"
+)
+
+// thriftWriter writes the thrift compact protocol for building test
+// files, every field uses the long form header
+type thriftWriter struct {
+	bytes.Buffer
+}
+
+func (w *thriftWriter) varint(v uint64) {
+	w.Write(binary.AppendUvarint(nil, v))
+}
+
+func (w *thriftWriter) zigzag(v int64) {
+	w.varint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (w *thriftWriter) field(id int16, typ byte) {
+	w.WriteByte(typ)
+	w.zigzag(int64(id))
+}
+
+func (w *thriftWriter) i32(id int16, v int32) {
+	w.field(id, typeI32)
+	w.zigzag(int64(v))
+}
+
+func (w *thriftWriter) i64(id int16, v int64) {
+	w.field(id, typeI64)
+	w.zigzag(v)
+}
+
+func (w *thriftWriter) str(id int16, s string) {
+	w.field(id, typeBinary)
+	w.varint(uint64(len(s)))
+	w.WriteString(s)
+}
+
+func (w *thriftWriter) list(id int16, typ byte, n int) {
+	w.field(id, typeList)
+	w.WriteByte(byte(n<<4) | typ)
+}
+
+func (w *thriftWriter) stop() {
+	w.WriteByte(typeStop)
+}
+
+type testSchema struct {
+	name        string
+	typ         int32
+	repetition  int32
+	numChildren int32
+	converted   int32
+}
+
+type testChunk struct {
+	typ       int32
+	codec     int32
+	numValues int64
+	pages     [][]byte
+	hasDict   bool
+}
+
+// rleRuns encodes values as RLE runs of length one
+func rleRuns(values []int32, bitWidth int) []byte {
+	var b []byte
+	for _, v := range values {
+		b = binary.AppendUvarint(b, 2)
+		for i := 0; i < (bitWidth+7)/8; i++ {
+			b = append(b, byte(v>>(8*i)))
+		}
+	}
+	return b
+}
+
+func plainStrings(values ...string) []byte {
+	var b []byte
+	for _, s := range values {
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(s)))
+		b = append(b, s...)
+	}
+	return b
+}
+
+func pageV1(typ int32, data []byte, compressed []byte, numValues int32, encoding int32) []byte {
+	var w thriftWriter
+	w.i32(1, typ)
+	w.i32(2, int32(len(data)))
+	w.i32(3, int32(len(compressed)))
+	if typ == pageDictionary {
+		w.field(7, typeStruct)
+	} else {
+		w.field(5, typeStruct)
+	}
+	w.i32(1, numValues)
+	w.i32(2, encoding)
+	w.stop()
+	w.stop()
+	w.Write(compressed)
+	return w.Bytes()
+}
+
+func pageV2(defs []byte, data []byte, numValues, numNulls int32) []byte {
+	var w thriftWriter
+	w.i32(1, pageDataV2)
+	w.i32(2, int32(len(defs)+len(data)))
+	w.i32(3, int32(len(defs)+len(data)))
+	w.field(8, typeStruct)
+	w.i32(1, numValues)
+	w.i32(2, numNulls)
+	w.i32(3, numValues)
+	w.i32(4, encodingPlain)
+	w.i32(5, int32(len(defs)))
+	w.i32(6, 0)
+	w.field(7, typeFalse)
+	w.stop()
+	w.stop()
+	w.Write(defs)
+	w.Write(data)
+	return w.Bytes()
+}
+
+func buildFile(schema []testSchema, chunks []testChunk, numRows int64) []byte {
+	var out bytes.Buffer
+	out.WriteString(magic)
+
+	type placed struct {
+		dictOffset, dataOffset, size int64
+	}
+	offsets := make([]placed, len(chunks))
+	for i, c := range chunks {
+		start := int64(out.Len())
+		offsets[i].dataOffset = start
+		for j, p := range c.pages {
+			if c.hasDict && j == 1 {
+				offsets[i].dictOffset = start
+				offsets[i].dataOffset = int64(out.Len())
+			}
+			out.Write(p)
+		}
+		offsets[i].size = int64(out.Len()) - start
+	}
+
+	var w thriftWriter
+	w.i32(1, 1)
+	w.list(2, typeStruct, len(schema))
+	for _, el := range schema {
+		if el.numChildren == 0 {
+			w.i32(1, el.typ)
+		}
+		if el.name != "schema" {
+			w.i32(3, el.repetition)
+		}
+		w.str(4, el.name)
+		if el.numChildren > 0 {
+			w.i32(5, el.numChildren)
+		}
+		if el.converted >= 0 {
+			w.i32(6, el.converted)
+		}
+		w.stop()
+	}
+	w.i64(3, numRows)
+	w.list(4, typeStruct, 1)
+	w.list(1, typeStruct, len(chunks))
+	for i, c := range chunks {
+		w.i64(2, offsets[i].dataOffset)
+		w.field(3, typeStruct)
+		w.i32(1, c.typ)
+		w.i32(4, c.codec)
+		w.i64(5, c.numValues)
+		w.i64(6, offsets[i].size)
+		w.i64(7, offsets[i].size)
+		w.i64(9, offsets[i].dataOffset)
+		if c.hasDict {
+			w.i64(11, offsets[i].dictOffset)
+		}
+		w.stop()
+		w.stop()
+	}
+	w.i64(2, 0)
+	w.i64(3, numRows)
+	w.stop()
+	w.str(6, "versitygw test")
+	w.stop()
+
+	out.Write(w.Bytes())
+	out.Write(binary.LittleEndian.AppendUint32(nil, uint32(w.Len())))
+	out.WriteString(magic)
+	return out.Bytes()
+}
+
+func TestReader(t *testing.T) {
+	// id: required int64, plain
+	var ids []byte
+	for _, id := range []int64{1, 2, 3, 4} {
+		ids = binary.LittleEndian.AppendUint64(ids, uint64(id))
+	}
+
+	// name: optional utf8, dictionary encoded and snappy compressed
+	dict := plainStrings("alice", "bob")
+	nameData := binary.LittleEndian.AppendUint32(nil, uint32(len(rleRuns([]int32{1, 0, 1, 1}, 1))))
+	nameData = append(nameData, rleRuns([]int32{1, 0, 1, 1}, 1)...)
+	nameData = append(nameData, 1)
+	nameData = append(nameData, rleRuns([]int32{1, 0, 1}, 1)...)
+
+	// day: required date
+	var days []byte
+	for _, d := range []int32{0, 1, 19000, 365} {
+		days = binary.LittleEndian.AppendUint32(days, uint32(d))
+	}
+
+	// address.zip: nested optional utf8, data page v2
+	zipDefs := rleRuns([]int32{2, 1, 0, 2}, 2)
+	zips := plainStrings("80202", "02134")
+
+	schema := []testSchema{
+		{name: "schema", numChildren: 4, converted: -1},
+		{name: "id", typ: typeInt64, repetition: repetitionRequired, converted: -1},
+		{name: "name", typ: typeByteArray, repetition: repetitionOptional, converted: convertedUTF8},
+		{name: "day", typ: typeInt32, repetition: repetitionRequired, converted: convertedDate},
+		{name: "address", repetition: repetitionOptional, numChildren: 1, converted: -1},
+		{name: "zip", typ: typeByteArray, repetition: repetitionOptional, converted: convertedUTF8},
+	}
+	chunks := []testChunk{
+		{typ: typeInt64, numValues: 4, pages: [][]byte{
+			pageV1(pageData, ids, ids, 4, encodingPlain),
+		}},
+		{typ: typeByteArray, codec: codecSnappy, numValues: 4, hasDict: true, pages: [][]byte{
+			pageV1(pageDictionary, dict, s2.EncodeSnappy(nil, dict), 2, encodingPlain),
+			pageV1(pageData, nameData, s2.EncodeSnappy(nil, nameData), 4, encodingRLEDictionary),
+		}},
+		{typ: typeInt32, numValues: 4, pages: [][]byte{
+			pageV1(pageData, days[:8], days[:8], 2, encodingPlain),
+			pageV1(pageData, days[8:], days[8:], 2, encodingPlain),
+		}},
+		{typ: typeByteArray, numValues: 4, pages: [][]byte{
+			pageV2(zipDefs, zips, 4, 2),
+		}},
+	}
+	file := buildFile(schema, chunks, 4)
+
+	r, err := NewReader(bytes.NewReader(file), int64(len(file)))
+	if err != nil {
+		t.Fatalf("new reader: %v", err)
+	}
+
+	wantCols := [][]string{{"id"}, {"name"}, {"day"}, {"address", "zip"}}
+	if !reflect.DeepEqual(r.Columns(), wantCols) {
+		t.Errorf("got columns %v, want %v", r.Columns(), wantCols)
+	}
+	if r.NumRows() != 4 {
+		t.Errorf("got %v rows, want 4", r.NumRows())
+	}
+
+	want := [][]any{
+		{int64(1), "bob", time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC), "80202"},
+		{int64(2), nil, time.Date(1970, 1, 2, 0, 0, 0, 0, time.UTC), nil},
+		{int64(3), "alice", time.Date(2022, 1, 8, 0, 0, 0, 0, time.UTC), nil},
+		{int64(4), "bob", time.Date(1971, 1, 1, 0, 0, 0, 0, time.UTC), "02134"},
+	}
+	for i, w := range want {
+		row, err := r.Read()
+		if err != nil {
+			t.Fatalf("read row %v: %v", i, err)
+		}
+		if !reflect.DeepEqual(row, w) {
+			t.Errorf("row %v: got %#v, want %#v", i, row, w)
+		}
+	}
+	if _, err := r.Read(); !errors.Is(err, io.EOF) {
+		t.Errorf("expected io.EOF after the last row, got %v", err)
+	}
+}
+
+func TestReaderInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"too-short", []byte("PAR1"), ErrInvalidFile},
+		{"bad-magic", []byte("PAR1\x00\x00\x00\x00\x00\x00\x00\x00CSV1"), ErrInvalidFile},
+		{"bad-footer-size", []byte("PAR1\x00\x00\x00\x00\xff\x00\x00\x00PAR1"), ErrInvalidFile},
+		{"repeated", buildFile([]testSchema{
+			{name: "schema", numChildren: 1, converted: -1},
+			{name: "tags", typ: typeByteArray, repetition: repetitionRepeated, converted: -1},
+		}, nil, 0), ErrUnsupported},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewReader(bytes.NewReader(tt.data), int64(len(tt.data)))
+			if !errors.Is(err, tt.err) {
+				t.Errorf("got error %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func readAll(r *Reader) error {
+	for {
+		_, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func TestReaderPageValues(t *testing.T) {
+	schema := []testSchema{
+		{name: "schema", numChildren: 1, converted: -1},
+		{name: "id", typ: typeInt32, repetition: repetitionOptional, converted: -1},
+	}
+	var ids []byte
+	for _, id := range []int32{1, 2} {
+		ids = binary.LittleEndian.AppendUint32(ids, uint32(id))
+	}
+	// the definition levels of a single rle run of n values
+	run := func(n uint64, def byte) []byte {
+		levels := binary.AppendUvarint(nil, n<<1)
+		levels = append(levels, def)
+		return append(binary.LittleEndian.AppendUint32(nil, uint32(len(levels))), levels...)
+	}
+	nullRun := run(math.MaxInt32, 0)
+	defs := append(run(1<<20, 1), ids...)
+
+	tests := []struct {
+		name  string
+		pages [][]byte
+		rows  int64
+	}{
+		{"negative", [][]byte{pageV1(pageData, ids, ids, -1, encodingPlain)}, 2},
+		{"past-row-group", [][]byte{pageV1(pageData, ids, ids, 3, encodingPlain)}, 2},
+		{"huge-null-run", [][]byte{pageV1(pageData, nullRun, nullRun, math.MaxInt32, encodingPlain)}, math.MaxInt64},
+		{"huge-plain", [][]byte{pageV1(pageData, defs, defs, 1<<20, encodingPlain)}, 1 << 20},
+		{"huge-dictionary", [][]byte{
+			pageV1(pageDictionary, ids, ids, math.MaxInt32, encodingPlain),
+			pageV1(pageData, ids, ids, 2, encodingPlainDictionary),
+		}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := buildFile(schema, []testChunk{{
+				typ:       typeInt32,
+				numValues: tt.rows,
+				hasDict:   len(tt.pages) > 1,
+				pages:     tt.pages,
+			}}, tt.rows)
+			r, err := NewReader(bytes.NewReader(file), int64(len(file)))
+			if err != nil {
+				t.Fatalf("new reader: %v", err)
+			}
+			err = readAll(r)
+			if !errors.Is(err, ErrInvalidFile) && !errors.Is(err, errCorruptPage) {
+				t.Errorf("got error %v, want invalid file", err)
+			}
+		})
+	}
+}
+
+func FuzzReader(f *testing.F) {
+	schema := []testSchema{
+		{name: "schema", numChildren: 2, converted: -1},
+		{name: "id", typ: typeInt64, repetition: repetitionRequired, converted: -1},
+		{name: "name", typ: typeByteArray, repetition: repetitionOptional, converted: convertedUTF8},
+	}
+	ids := binary.LittleEndian.AppendUint64(nil, 1)
+	dict := plainStrings("alice")
+	names := binary.LittleEndian.AppendUint32(nil, uint32(len(rleRuns([]int32{1}, 1))))
+	names = append(names, rleRuns([]int32{1}, 1)...)
+	names = append(names, 1)
+	names = append(names, rleRuns([]int32{0}, 1)...)
+	f.Add(buildFile(schema, []testChunk{
+		{typ: typeInt64, numValues: 1, pages: [][]byte{
+			pageV1(pageData, ids, ids, 1, encodingPlain),
+		}},
+		{typ: typeByteArray, numValues: 1, hasDict: true, pages: [][]byte{
+			pageV1(pageDictionary, dict, dict, 1, encodingPlain),
+			pageV1(pageData, names, names, 1, encodingRLEDictionary),
+		}},
+	}, 1))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		r, err := NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return
+		}
+		readAll(r)
+	})
+}
@@ -0,0 +1,246 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package parquet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// thrift compact protocol types
+const (
+	typeStop      = 0
+	typeTrue      = 1
+	typeFalse     = 2
+	typeByte      = 3
+	typeI16       = 4
+	typeI32       = 5
+	typeI64       = 6
+	typeDouble    = 7
+	typeBinary    = 8
+	typeList      = 9
+	typeSet       = 10
+	typeMap       = 11
+	typeStruct    = 12
+	maxStructSize = 64 * 1024 * 1024
+	maxNesting    = 64
+)
+
+var errInvalidThrift = errors.New("invalid thrift encoding")
+
+type byteReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// thriftReader decodes the thrift compact protocol encoding of the
+// parquet metadata structures
+type thriftReader struct {
+	r     byteReader
+	n     int64
+	depth int
+}
+
+func (t *thriftReader) ReadByte() (byte, error) {
+	b, err := t.r.ReadByte()
+	if err == nil {
+		t.n++
+	}
+	return b, err
+}
+
+func (t *thriftReader) readVarint() (uint64, error) {
+	return binary.ReadUvarint(t)
+}
+
+func (t *thriftReader) readZigzag() (int64, error) {
+	u, err := t.readVarint()
+	if err != nil {
+		return 0, err
+	}
+	return int64(u>>1) ^ -int64(u&1), nil
+}
+
+func (t *thriftReader) readI32() (int32, error) {
+	v, err := t.readZigzag()
+	if err != nil {
+		return 0, err
+	}
+	if v < math.MinInt32 || v > math.MaxInt32 {
+		return 0, errInvalidThrift
+	}
+	return int32(v), nil
+}
+
+func (t *thriftReader) readI64() (int64, error) {
+	return t.readZigzag()
+}
+
+func (t *thriftReader) readDouble() (float64, error) {
+	var b [8]byte
+	if _, err := io.ReadFull(t.r, b[:]); err != nil {
+		return 0, err
+	}
+	t.n += 8
+	return math.Float64frombits(binary.LittleEndian.Uint64(b[:])), nil
+}
+
+func (t *thriftReader) readBinary() ([]byte, error) {
+	size, err := t.readVarint()
+	if err != nil {
+		return nil, err
+	}
+	if size > maxStructSize {
+		return nil, errInvalidThrift
+	}
+	b := make([]byte, size)
+	if _, err := io.ReadFull(t.r, b); err != nil {
+		return nil, err
+	}
+	t.n += int64(size)
+	return b, nil
+}
+
+func (t *thriftReader) readString() (string, error) {
+	b, err := t.readBinary()
+	return string(b), err
+}
+
+// readBool returns the value of a boolean struct field, these are
+// encoded in the field type
+func readBool(typ byte) bool {
+	return typ == typeTrue
+}
+
+// readStruct calls fn for each field of a struct, fn has to read or
+// skip the field value
+func (t *thriftReader) readStruct(fn func(id int16, typ byte) error) error {
+	t.depth++
+	defer func() { t.depth-- }()
+	if t.depth > maxNesting {
+		return errInvalidThrift
+	}
+
+	var id int16
+	for {
+		b, err := t.ReadByte()
+		if err != nil {
+			return err
+		}
+		typ := b & 0x0f
+		if typ == typeStop {
+			return nil
+		}
+		if delta := int16(b >> 4); delta != 0 {
+			id += delta
+		} else {
+			v, err := t.readZigzag()
+			if err != nil {
+				return err
+			}
+			id = int16(v)
+		}
+		if err := fn(id, typ); err != nil {
+			return err
+		}
+	}
+}
+
+func (t *thriftReader) readListHeader() (int, byte, error) {
+	b, err := t.ReadByte()
+	if err != nil {
+		return 0, 0, err
+	}
+	size := int(b >> 4)
+	if size == 15 {
+		s, err := t.readVarint()
+		if err != nil {
+			return 0, 0, err
+		}
+		if s > maxStructSize {
+			return 0, 0, errInvalidThrift
+		}
+		size = int(s)
+	}
+	return size, b & 0x0f, nil
+}
+
+// readList calls fn for each element of a list
+func (t *thriftReader) readList(fn func(typ byte) error) error {
+	size, typ, err := t.readListHeader()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < size; i++ {
+		if err := fn(typ); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *thriftReader) skip(typ byte) error {
+	switch typ {
+	case typeTrue, typeFalse:
+		return nil
+	case typeByte:
+		_, err := t.ReadByte()
+		return err
+	case typeI16, typeI32, typeI64:
+		_, err := t.readVarint()
+		return err
+	case typeDouble:
+		_, err := t.readDouble()
+		return err
+	case typeBinary:
+		_, err := t.readBinary()
+		return err
+	case typeList, typeSet:
+		return t.readList(func(typ byte) error {
+			// booleans in lists are encoded as a byte
+			if typ == typeTrue || typ == typeFalse {
+				_, err := t.ReadByte()
+				return err
+			}
+			return t.skip(typ)
+		})
+	case typeMap:
+		size, err := t.readVarint()
+		if err != nil || size == 0 {
+			return err
+		}
+		kv, err := t.ReadByte()
+		if err != nil {
+			return err
+		}
+		for i := uint64(0); i < size; i++ {
+			if err := t.skip(kv >> 4); err != nil {
+				return err
+			}
+			if err := t.skip(kv & 0x0f); err != nil {
+				return err
+			}
+		}
+		return nil
+	case typeStruct:
+		return t.readStruct(func(_ int16, typ byte) error {
+			return t.skip(typ)
+		})
+	}
+	return fmt.Errorf("%w: unknown type %v", errInvalidThrift, typ)
+}
@@ -0,0 +1,395 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package s3select
+
+import (
+	"bufio"
+	"compress/bzip2"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/versity/versitygw/s3err"
+	"github.com/versity/versitygw/s3select/sql"
+)
+
+// Error codes of the request validation and input parsing errors
+const (
+	ErrCodeMissingRequiredParameter    = "MissingRequiredParameter"
+	ErrCodeInvalidExpressionType       = "InvalidExpressionType"
+	ErrCodeObjectSerializationConflict = "ObjectSerializationConflict"
+	ErrCodeInvalidCompressionFormat    = "InvalidCompressionFormat"
+	ErrCodeInvalidRequestParameter     = "InvalidRequestParameter"
+	ErrCodeInvalidFileHeaderInfo       = "InvalidFileHeaderInfo"
+	ErrCodeInvalidJSONType             = "InvalidJsonType"
+	ErrCodeInvalidQuoteFields          = "InvalidQuoteFields"
+	ErrCodeUnsupportedScanRangeInput   = "UnsupportedScanRangeInput"
+	ErrCodeCSVParsingError             = "CSVParsingError"
+	ErrCodeJSONParsingError            = "JSONParsingError"
+	ErrCodeParquetParsingError         = "ParquetParsingError"
+	ErrCodeUnsupportedParquetType      = "UnsupportedParquetType"
+)
+
+// recordsBatchSize is the payload size after which the returned rows
+// are sent in a Records message
+const recordsBatchSize = 256 * 1024
+
+func newError(code, format string, args ...any) *sql.Error {
+	return &sql.Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// selectRun is the state of a running select request
+type selectRun struct {
+	input     *s3.SelectObjectContentInput
+	r         io.ReaderAt
+	size      int64
+	scanned   int64
+	processed int64
+	parquet   bool
+}
+
+// Run evaluates the select request over the object data of size bytes
+// read from r and streams the results to w as event stream messages.
+// Errors of the request or the query are sent in an error message.
+func Run(ctx context.Context, w *bufio.Writer, input *s3.SelectObjectContentInput, r io.ReaderAt, size int64) {
+	run := &selectRun{input: input, r: r, size: size}
+
+	var getProgress GetProgress
+	if input.RequestProgress != nil && input.RequestProgress.Enabled != nil &&
+		*input.RequestProgress.Enabled {
+		getProgress = run.progress
+	}
+
+	mh := NewMessageHandler(ctx, w, getProgress)
+	err := run.runRecover(ctx, mh)
+	if err != nil {
+		code, message := errorCode(err)
+		mh.FinishWithError(code, message)
+		return
+	}
+
+	scanned, processed := run.progress()
+	mh.Finish(scanned, processed)
+}
+
+// SendError sends an error message for a request that failed before
+// the object could be read
+func SendError(ctx context.Context, w *bufio.Writer, err error) {
+	mh := NewMessageHandler(ctx, w, nil)
+	code, message := errorCode(err)
+	mh.FinishWithError(code, message)
+}
+
+func errorCode(err error) (string, string) {
+	var serr *sql.Error
+	if errors.As(err, &serr) {
+		return serr.Code, serr.Message
+	}
+	var apiErr s3err.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code, apiErr.Description
+	}
+	var structErr bzip2.StructuralError
+	if errors.Is(err, gzip.ErrHeader) || errors.Is(err, gzip.ErrChecksum) ||
+		errors.As(err, &structErr) {
+		return ErrCodeInvalidCompressionFormat, err.Error()
+	}
+	apiErr = s3err.GetAPIError(s3err.ErrInternalError)
+	return apiErr.Code, apiErr.Description
+}
+
+func (s *selectRun) progress() (int64, int64) {
+	scanned := atomic.LoadInt64(&s.scanned)
+	if s.parquet {
+		return scanned, scanned
+	}
+	return scanned, atomic.LoadInt64(&s.processed)
+}
+
+// runRecover runs the select as run, a panic on malformed object data is
+// returned as an internal error, the stream writer of the response runs
+// outside of the request handler recovery
+func (s *selectRun) runRecover(ctx context.Context, mh *MessageHandler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("select object content: %v", r)
+		}
+	}()
+	return s.run(ctx, mh)
+}
+
+func (s *selectRun) run(ctx context.Context, mh *MessageHandler) error {
+	if s.input.ExpressionType != types.ExpressionTypeSql {
+		return newError(ErrCodeInvalidExpressionType, "the ExpressionType must be SQL")
+	}
+	if s.input.Expression == nil || *s.input.Expression == "" {
+		return newError(ErrCodeMissingRequiredParameter, "the Expression is missing")
+	}
+
+	stmt, err := sql.Parse(*s.input.Expression)
+	if err != nil {
+		return err
+	}
+	rw, err := s.recordWriter()
+	if err != nil {
+		return err
+	}
+	rr, err := s.recordReader()
+	if err != nil {
+		return err
+	}
+
+	var buf []byte
+	flush := func() error {
+		if len(buf) == 0 {
+			return nil
+		}
+		err := mh.SendRecord(buf)
+		buf = buf[:0]
+		return err
+	}
+
+	for !stmt.Done() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		doc, err := rr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, rec := range stmt.Records(doc) {
+			row, ok, err := stmt.Eval(rec)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			buf = rw.Append(buf, row)
+			if len(buf) >= recordsBatchSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if stmt.IsAggregate() {
+		row, err := stmt.Result()
+		if err != nil {
+			return err
+		}
+		buf = rw.Append(buf, row)
+	}
+	return flush()
+}
+
+func (s *selectRun) recordWriter() (recordWriter, error) {
+	out := s.input.OutputSerialization
+	if out == nil || (out.CSV == nil && out.JSON == nil) {
+		return nil, newError(ErrCodeMissingRequiredParameter, "the OutputSerialization is missing")
+	}
+	if out.CSV != nil && out.JSON != nil {
+		return nil, newError(ErrCodeObjectSerializationConflict, "the OutputSerialization must specify exactly one format")
+	}
+	if out.CSV != nil {
+		return newCSVWriter(out.CSV)
+	}
+	return newJSONWriter(out.JSON), nil
+}
+
+// scanRange returns the first and last byte of the requested range
+func (s *selectRun) scanRange() (int64, int64, bool, error) {
+	sr := s.input.ScanRange
+	if sr == nil || (sr.Start == nil && sr.End == nil) {
+		return 0, s.size - 1, false, nil
+	}
+
+	start, end := int64(0), s.size-1
+	switch {
+	case sr.Start == nil:
+		// the last End bytes of the object
+		if *sr.End < 0 {
+			return 0, 0, false, newError(ErrCodeInvalidRequestParameter, "the ScanRange End must not be negative")
+		}
+		start = s.size - *sr.End
+		if start < 0 {
+			start = 0
+		}
+	default:
+		start = *sr.Start
+		if sr.End != nil && *sr.End < end {
+			end = *sr.End
+		}
+		if start < 0 || (sr.End != nil && *sr.End < start) {
+			return 0, 0, false, newError(ErrCodeInvalidRequestParameter, "the ScanRange is invalid")
+		}
+	}
+	return start, end, true, nil
+}
+
+func (s *selectRun) recordReader() (recordReader, error) {
+	in := s.input.InputSerialization
+	if in == nil {
+		return nil, newError(ErrCodeMissingRequiredParameter, "the InputSerialization is missing")
+	}
+	formats := 0
+	for _, set := range []bool{in.CSV != nil, in.JSON != nil, in.Parquet != nil} {
+		if set {
+			formats++
+		}
+	}
+	switch formats {
+	case 0:
+		return nil, newError(ErrCodeMissingRequiredParameter, "the InputSerialization must specify the input format")
+	case 1:
+	default:
+		return nil, newError(ErrCodeObjectSerializationConflict, "the InputSerialization must specify exactly one format")
+	}
+
+	compression := in.CompressionType
+	switch compression {
+	case "", types.CompressionTypeNone, types.CompressionTypeGzip, types.CompressionTypeBzip2:
+	default:
+		return nil, newError(ErrCodeInvalidCompressionFormat, "invalid CompressionType %q", compression)
+	}
+	compressed := compression == types.CompressionTypeGzip || compression == types.CompressionTypeBzip2
+
+	start, end, hasRange, err := s.scanRange()
+	if err != nil {
+		return nil, err
+	}
+
+	if in.Parquet != nil {
+		if compressed {
+			return nil, newError(ErrCodeInvalidCompressionFormat, "Parquet input does not support the CompressionType %v", compression)
+		}
+		if hasRange {
+			return nil, newError(ErrCodeUnsupportedScanRangeInput, "ScanRange is not supported for Parquet input")
+		}
+		s.parquet = true
+		return newParquetReader(countingReaderAt{r: s.r, n: &s.scanned}, s.size)
+	}
+
+	var lines bool
+	if in.JSON != nil {
+		switch in.JSON.Type {
+		case types.JSONTypeDocument:
+		case types.JSONTypeLines:
+			lines = true
+		default:
+			return nil, newError(ErrCodeInvalidJSONType, "invalid JSON Type %q", in.JSON.Type)
+		}
+	}
+	if hasRange && (compressed || (in.JSON != nil && !lines)) {
+		return nil, newError(ErrCodeUnsupportedScanRangeInput, "ScanRange is only supported for uncompressed CSV and JSON LINES input")
+	}
+
+	var opts csvOptions
+	var names []string
+	readFrom := int64(0)
+	if in.CSV != nil {
+		opts, err = csvInputOptions(in.CSV)
+		if err != nil {
+			return nil, err
+		}
+		if hasRange && start > 0 {
+			// the header line is at the start of the object outside
+			// of the range
+			hdr := newCSVReader(io.NewSectionReader(s.r, 0, s.size), opts)
+			if err := hdr.readHeader(); err != nil {
+				return nil, err
+			}
+			names = hdr.names
+			opts.headerInfo = types.FileHeaderInfoNone
+			if start < hdr.offset {
+				start = hdr.offset
+			}
+		}
+	}
+
+	// records that start inside the range are processed, reading
+	// starts before the range to find out if start is the beginning of
+	// a record
+	skip := false
+	if hasRange && start > 0 {
+		readFrom = start - 1
+		if in.CSV != nil {
+			readFrom = start - int64(len(opts.recordDelimiter))
+			if readFrom < 0 {
+				readFrom = 0
+			}
+		}
+		skip = true
+	}
+
+	var rd io.Reader = countingReader{
+		r: io.NewSectionReader(s.r, readFrom, s.size-readFrom),
+		n: &s.scanned,
+	}
+	switch compression {
+	case types.CompressionTypeGzip:
+		zr, err := gzip.NewReader(rd)
+		if err != nil {
+			return nil, newError(ErrCodeInvalidCompressionFormat, "the object is not GZIP compressed: %v", err)
+		}
+		rd = zr
+	case types.CompressionTypeBzip2:
+		rd = bzip2.NewReader(rd)
+	}
+	rd = countingReader{r: rd, n: &s.processed}
+
+	if in.JSON != nil {
+		if !lines {
+			return newJSONReader(rd), nil
+		}
+		jr := newJSONLinesReader(rd)
+		jr.offset = readFrom
+		if hasRange {
+			jr.end = end
+		}
+		if skip {
+			if err := jr.skipPartial(); err != nil && err != io.EOF {
+				return nil, err
+			}
+		}
+		return jr, nil
+	}
+
+	cr := newCSVReader(rd, opts)
+	cr.offset = readFrom
+	cr.names = names
+	if hasRange {
+		cr.end = end
+	}
+	if skip {
+		if err := cr.skipPartial(); err != nil && err != io.EOF {
+			return nil, err
+		}
+	}
+	if err := cr.readHeader(); err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
@@ -0,0 +1,380 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package s3select
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"encoding/xml"
+	"hash/crc32"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+type selectResult struct {
+	records   string
+	stats     *stats
+	errorCode string
+	ended     bool
+}
+
+// decodeEvents decodes the event stream messages of a select response
+func decodeEvents(t *testing.T, data []byte) selectResult {
+	t.Helper()
+	var res selectResult
+	for len(data) > 0 {
+		if len(data) < preludeLen+msgCrcLen {
+			t.Fatalf("truncated message")
+		}
+		total := int(binary.BigEndian.Uint32(data))
+		hlen := int(binary.BigEndian.Uint32(data[4:]))
+		if crc32.ChecksumIEEE(data[:8]) != binary.BigEndian.Uint32(data[8:]) {
+			t.Fatalf("invalid prelude crc")
+		}
+		if crc32.ChecksumIEEE(data[:total-msgCrcLen]) != binary.BigEndian.Uint32(data[total-msgCrcLen:]) {
+			t.Fatalf("invalid message crc")
+		}
+
+		headers := map[string]string{}
+		h := data[preludeLen : preludeLen+hlen]
+		for len(h) > 0 {
+			nlen := int(h[0])
+			name := string(h[1 : 1+nlen])
+			vlen := int(binary.BigEndian.Uint16(h[2+nlen:]))
+			headers[name] = string(h[4+nlen : 4+nlen+vlen])
+			h = h[4+nlen+vlen:]
+		}
+		payload := data[preludeLen+hlen : total-msgCrcLen]
+		data = data[total:]
+
+		if headers[":message-type"] == "error" {
+			res.errorCode = headers[":error-code"]
+			continue
+		}
+		switch headers[":event-type"] {
+		case "Records":
+			res.records += string(payload)
+		case "Stats":
+			res.stats = &stats{}
+			if err := xml.Unmarshal(payload, res.stats); err != nil {
+				t.Fatalf("unmarshal stats: %v", err)
+			}
+		case "End":
+			res.ended = true
+		}
+	}
+	return res
+}
+
+func runSelect(t *testing.T, input *s3.SelectObjectContentInput, data []byte) selectResult {
+	t.Helper()
+	var out bytes.Buffer
+	w := bufio.NewWriter(&out)
+	input.ExpressionType = types.ExpressionTypeSql
+	Run(context.Background(), w, input, bytes.NewReader(data), int64(len(data)))
+	return decodeEvents(t, out.Bytes())
+}
+
+func gzipData(t *testing.T, data string) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	if _, err := zw.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+const testCSV = `name,city,age
+# a comment line
+alice,Denver,34
+bob,"Boston, MA",27
+"carol ""c""",Denver,45
+`
+
+const testJSONLines = `{"name":"alice","age":34,"tags":["a","b"]}
+{"name":"bob","age":27}
+{"name":"carol","age":45,"address":{"city":"Denver"}}
+`
+
+func TestRun(t *testing.T) {
+	csvIn := &types.InputSerialization{CSV: &types.CSVInput{FileHeaderInfo: types.FileHeaderInfoUse}}
+	csvOut := &types.OutputSerialization{CSV: &types.CSVOutput{}}
+	jsonOut := &types.OutputSerialization{JSON: &types.JSONOutput{}}
+	linesIn := &types.InputSerialization{JSON: &types.JSONInput{Type: types.JSONTypeLines}}
+
+	tests := []struct {
+		name  string
+		input *s3.SelectObjectContentInput
+		data  []byte
+		want  string
+	}{
+		{
+			name: "csv-header-where",
+			input: &s3.SelectObjectContentInput{
+				Expression:          aws.String("SELECT name, city FROM S3Object WHERE city LIKE 'Denver%'"),
+				InputSerialization:  csvIn,
+				OutputSerialization: csvOut,
+			},
+			data: []byte(testCSV),
+			want: "alice,Denver\n\"carol \"\"c\"\"\",Denver\n",
+		},
+		{
+			name: "csv-quoted-delimiter",
+			input: &s3.SelectObjectContentInput{
+				Expression:          aws.String("SELECT s.city FROM S3Object s WHERE s.name = 'bob'"),
+				InputSerialization:  csvIn,
+				OutputSerialization: jsonOut,
+			},
+			data: []byte(testCSV),
+			want: `{"city":"Boston, MA"}` + "\n",
+		},
+		{
+			name: "csv-no-header-custom-delimiters",
+			input: &s3.SelectObjectContentInput{
+				Expression: aws.String("SELECT _2, _1 FROM S3Object"),
+				InputSerialization: &types.InputSerialization{CSV: &types.CSVInput{
+					FieldDelimiter:  aws.String("|"),
+					RecordDelimiter: aws.String(";"),
+				}},
+				OutputSerialization: &types.OutputSerialization{CSV: &types.CSVOutput{
+					FieldDelimiter: aws.String("\t"),
+					QuoteFields:    types.QuoteFieldsAlways,
+				}},
+			},
+			data: []byte("a|1;b|2;"),
+			want: "\"1\"\t\"a\"\n\"2\"\t\"b\"\n",
+		},
+		{
+			name: "csv-gzip",
+			input: &s3.SelectObjectContentInput{
+				Expression: aws.String("SELECT COUNT(*), MAX(CAST(age AS INT)) FROM S3Object"),
+				InputSerialization: &types.InputSerialization{
+					CSV:             &types.CSVInput{FileHeaderInfo: types.FileHeaderInfoUse},
+					CompressionType: types.CompressionTypeGzip,
+				},
+				OutputSerialization: jsonOut,
+			},
+			data: gzipData(t, testCSV),
+			want: `{"_1":3,"_2":45}` + "\n",
+		},
+		{
+			name: "json-lines",
+			input: &s3.SelectObjectContentInput{
+				Expression:          aws.String("SELECT s.name, s.tags[0] AS tag FROM S3Object s WHERE s.age > 30"),
+				InputSerialization:  linesIn,
+				OutputSerialization: jsonOut,
+			},
+			data: []byte(testJSONLines),
+			want: `{"name":"alice","tag":"a"}` + "\n" + `{"name":"carol"}` + "\n",
+		},
+		{
+			name: "json-document",
+			input: &s3.SelectObjectContentInput{
+				Expression: aws.String("SELECT p.name FROM S3Object[*].people[*] p WHERE p.address.city = 'Denver'"),
+				InputSerialization: &types.InputSerialization{
+					JSON: &types.JSONInput{Type: types.JSONTypeDocument},
+				},
+				OutputSerialization: csvOut,
+			},
+			data: []byte(`{
+  "people": [
+    {"name": "alice", "address": {"city": "Denver"}},
+    {"name": "bob", "address": {"city": "Boston"}}
+  ]
+}`),
+			want: "alice\n",
+		},
+		{
+			name: "csv-scan-range",
+			input: &s3.SelectObjectContentInput{
+				Expression:          aws.String("SELECT name FROM S3Object"),
+				InputSerialization:  csvIn,
+				OutputSerialization: csvOut,
+				// starts inside of the alice record and ends inside of
+				// the bob record
+				ScanRange: &types.ScanRange{Start: aws.Int64(35), End: aws.Int64(50)},
+			},
+			data: []byte(testCSV),
+			want: "bob\n",
+		},
+		{
+			name: "json-lines-scan-range",
+			input: &s3.SelectObjectContentInput{
+				Expression:          aws.String("SELECT s.name FROM S3Object s"),
+				InputSerialization:  linesIn,
+				OutputSerialization: csvOut,
+				// the first byte of the bob record
+				ScanRange: &types.ScanRange{Start: aws.Int64(43), End: aws.Int64(43)},
+			},
+			data: []byte(testJSONLines),
+			want: "bob\n",
+		},
+		{
+			name: "limit",
+			input: &s3.SelectObjectContentInput{
+				Expression:          aws.String("SELECT * FROM S3Object LIMIT 2"),
+				InputSerialization:  linesIn,
+				OutputSerialization: csvOut,
+			},
+			data: []byte(testJSONLines),
+			want: "alice,34,\"[\"\"a\"\",\"\"b\"\"]\"\nbob,27\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := runSelect(t, tt.input, tt.data)
+			if res.errorCode != "" {
+				t.Fatalf("unexpected error %v", res.errorCode)
+			}
+			if !res.ended {
+				t.Errorf("missing End message")
+			}
+			if res.records != tt.want {
+				t.Errorf("got records %q, want %q", res.records, tt.want)
+			}
+			if res.stats == nil {
+				t.Fatalf("missing Stats message")
+			}
+			if res.stats.BytesReturned != int64(len(tt.want)) {
+				t.Errorf("got %v bytes returned, want %v", res.stats.BytesReturned, len(tt.want))
+			}
+			if tt.input.ScanRange == nil && res.stats.BytesScanned != int64(len(tt.data)) {
+				t.Errorf("got %v bytes scanned, want %v", res.stats.BytesScanned, len(tt.data))
+			}
+		})
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	csvIn := &types.InputSerialization{CSV: &types.CSVInput{}}
+	csvOut := &types.OutputSerialization{CSV: &types.CSVOutput{}}
+
+	tests := []struct {
+		name  string
+		input *s3.SelectObjectContentInput
+		data  string
+		code  string
+	}{
+		{
+			name: "parse-error",
+			input: &s3.SelectObjectContentInput{
+				Expression:          aws.String("SELECT FROM S3Object"),
+				InputSerialization:  csvIn,
+				OutputSerialization: csvOut,
+			},
+			code: "ParseUnexpectedToken",
+		},
+		{
+			name: "missing-input-format",
+			input: &s3.SelectObjectContentInput{
+				Expression:          aws.String("SELECT * FROM S3Object"),
+				InputSerialization:  &types.InputSerialization{},
+				OutputSerialization: csvOut,
+			},
+			code: ErrCodeMissingRequiredParameter,
+		},
+		{
+			name: "serialization-conflict",
+			input: &s3.SelectObjectContentInput{
+				Expression: aws.String("SELECT * FROM S3Object"),
+				InputSerialization: &types.InputSerialization{
+					CSV:  &types.CSVInput{},
+					JSON: &types.JSONInput{Type: types.JSONTypeLines},
+				},
+				OutputSerialization: csvOut,
+			},
+			code: ErrCodeObjectSerializationConflict,
+		},
+		{
+			name: "scan-range-compressed",
+			input: &s3.SelectObjectContentInput{
+				Expression: aws.String("SELECT * FROM S3Object"),
+				InputSerialization: &types.InputSerialization{
+					CSV:             &types.CSVInput{},
+					CompressionType: types.CompressionTypeGzip,
+				},
+				OutputSerialization: csvOut,
+				ScanRange:           &types.ScanRange{Start: aws.Int64(1)},
+			},
+			code: ErrCodeUnsupportedScanRangeInput,
+		},
+		{
+			name: "not-gzip",
+			input: &s3.SelectObjectContentInput{
+				Expression: aws.String("SELECT * FROM S3Object"),
+				InputSerialization: &types.InputSerialization{
+					CSV:             &types.CSVInput{},
+					CompressionType: types.CompressionTypeGzip,
+				},
+				OutputSerialization: csvOut,
+			},
+			data: "a,b\n",
+			code: ErrCodeInvalidCompressionFormat,
+		},
+		{
+			name: "invalid-json",
+			input: &s3.SelectObjectContentInput{
+				Expression:          aws.String("SELECT * FROM S3Object"),
+				InputSerialization:  &types.InputSerialization{JSON: &types.JSONInput{Type: types.JSONTypeLines}},
+				OutputSerialization: csvOut,
+			},
+			data: "{\"a\":1}\n{\"a\":\n",
+			code: ErrCodeJSONParsingError,
+		},
+		{
+			name: "invalid-parquet",
+			input: &s3.SelectObjectContentInput{
+				Expression:          aws.String("SELECT * FROM S3Object"),
+				InputSerialization:  &types.InputSerialization{Parquet: &types.ParquetInput{}},
+				OutputSerialization: csvOut,
+			},
+			data: "a,b\n",
+			code: ErrCodeParquetParsingError,
+		},
+		{
+			name: "cast-failed",
+			input: &s3.SelectObjectContentInput{
+				Expression:          aws.String("SELECT CAST(_1 AS INT) FROM S3Object"),
+				InputSerialization:  csvIn,
+				OutputSerialization: csvOut,
+			},
+			data: "1\nx\n",
+			code: "CastFailed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := runSelect(t, tt.input, []byte(tt.data))
+			if res.errorCode != tt.code {
+				t.Errorf("got error code %q, want %q", res.errorCode, tt.code)
+			}
+			if res.ended {
+				t.Errorf("unexpected End message")
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package sql
+
+import "fmt"
+
+// Error codes returned in the error message of the select event stream
+const (
+	ErrCodeParseUnexpectedToken      = "ParseUnexpectedToken"
+	ErrCodeParseExpectedExpression   = "ParseExpectedExpression"
+	ErrCodeParseExpectedTypeName     = "ParseExpectedTypeName"
+	ErrCodeParseSelectMissingFrom    = "ParseSelectMissingFrom"
+	ErrCodeParseInvalidPathComponent = "ParseInvalidPathComponent"
+	ErrCodeParseUnsupportedSyntax    = "UnsupportedSyntax"
+	ErrCodeUnsupportedFunction       = "UnsupportedFunction"
+	ErrCodeInvalidAggregate          = "InvalidAggregate"
+	ErrCodeInvalidArguments          = "EvaluatorInvalidArguments"
+	ErrCodeInvalidArgumentType       = "IncorrectSqlFunctionArgumentType"
+	ErrCodeInvalidCast               = "InvalidCast"
+	ErrCodeCastFailed                = "CastFailed"
+	ErrCodeDivisionByZero            = "DivisionByZero"
+	ErrCodeInvalidTimestampFormat    = "EvaluatorInvalidTimestampFormatPattern"
+	ErrCodeInvalidColumnIndex        = "InvalidColumnIndex"
+	ErrCodeInvalidLimit              = "EvaluatorNegativeLimit"
+)
+
+// Error is an error of the parsing or evaluation of a select
+// expression
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+func newError(code, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
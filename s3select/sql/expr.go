@@ -0,0 +1,506 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package sql
+
+import (
+	"math"
+	"strings"
+)
+
+// env is the evaluation environment of an expression
+type env struct {
+	record Value
+	alias  string
+}
+
+type expr interface {
+	eval(e *env) (Value, error)
+}
+
+type literalExpr struct {
+	v Value
+}
+
+func (x *literalExpr) eval(*env) (Value, error) { return x.v, nil }
+
+type pathElemKind int
+
+const (
+	elemName pathElemKind = iota
+	elemIndex
+	elemWildcard
+)
+
+type pathElem struct {
+	kind   pathElemKind
+	name   string
+	quoted bool
+	index  int
+}
+
+// pathExpr references the record, a column of the record or a value
+// nested in the record
+type pathExpr struct {
+	elems []pathElem
+}
+
+func (x *pathExpr) eval(e *env) (Value, error) {
+	elems := x.elems
+	if len(elems) > 0 && elems[0].kind == elemName && !elems[0].quoted &&
+		e.alias != "" && strings.EqualFold(elems[0].name, e.alias) {
+		elems = elems[1:]
+	}
+	return walkPath(e.record, elems), nil
+}
+
+// name is the name of the column in JSON output
+func (x *pathExpr) name() string {
+	last := x.elems[len(x.elems)-1]
+	if last.kind != elemName {
+		return ""
+	}
+	return last.name
+}
+
+func walkPath(v Value, elems []pathElem) Value {
+	for _, elem := range elems {
+		switch elem.kind {
+		case elemName:
+			v = v.Field(elem.name, elem.quoted)
+		case elemIndex:
+			v = v.Index(elem.index)
+		}
+		if v.IsMissing() {
+			return v
+		}
+	}
+	return v
+}
+
+type notExpr struct {
+	x expr
+}
+
+func (x *notExpr) eval(e *env) (Value, error) {
+	v, err := x.x.eval(e)
+	if err != nil || v.IsNull() {
+		return Null(), err
+	}
+	b, err := asBool(v, "NOT")
+	if err != nil {
+		return Value{}, err
+	}
+	return Bool(!b), nil
+}
+
+type negExpr struct {
+	x expr
+}
+
+func (x *negExpr) eval(e *env) (Value, error) {
+	v, err := x.x.eval(e)
+	if err != nil || v.IsNull() {
+		return Null(), err
+	}
+	n, ok := asNumber(v)
+	if !ok {
+		return Value{}, newError(ErrCodeInvalidArgumentType, "operand of unary minus is not a number")
+	}
+	if n.kind == KindInt {
+		return Int(-n.i), nil
+	}
+	return Float(-n.f), nil
+}
+
+func asBool(v Value, op string) (bool, error) {
+	switch v.kind {
+	case KindBool:
+		return v.b, nil
+	case KindString:
+		switch strings.ToLower(v.s) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+	}
+	return false, newError(ErrCodeInvalidArgumentType, "operand of %v is not a boolean", op)
+}
+
+type logicalExpr struct {
+	and  bool
+	l, r expr
+}
+
+// eval implements the three valued logic of AND and OR
+func (x *logicalExpr) eval(e *env) (Value, error) {
+	op := "OR"
+	if x.and {
+		op = "AND"
+	}
+
+	l, err := x.l.eval(e)
+	if err != nil {
+		return Value{}, err
+	}
+	var lb bool
+	if !l.IsNull() {
+		lb, err = asBool(l, op)
+		if err != nil {
+			return Value{}, err
+		}
+		// short circuit
+		if lb != x.and {
+			return Bool(lb), nil
+		}
+	}
+
+	r, err := x.r.eval(e)
+	if err != nil {
+		return Value{}, err
+	}
+	var rb bool
+	if !r.IsNull() {
+		rb, err = asBool(r, op)
+		if err != nil {
+			return Value{}, err
+		}
+		if rb != x.and {
+			return Bool(rb), nil
+		}
+	}
+
+	if l.IsNull() || r.IsNull() {
+		return Null(), nil
+	}
+	return Bool(x.and), nil
+}
+
+type compareExpr struct {
+	op   string
+	l, r expr
+}
+
+func (x *compareExpr) eval(e *env) (Value, error) {
+	l, err := x.l.eval(e)
+	if err != nil {
+		return Value{}, err
+	}
+	r, err := x.r.eval(e)
+	if err != nil {
+		return Value{}, err
+	}
+	return compareValues(x.op, l, r), nil
+}
+
+func compareValues(op string, l, r Value) Value {
+	if l.IsNull() || r.IsNull() {
+		return Null()
+	}
+	c, ok := compare(l, r)
+	if !ok {
+		// values of different types are never equal
+		switch op {
+		case "=":
+			return Bool(false)
+		case "!=", "<>":
+			return Bool(true)
+		}
+		return Null()
+	}
+	switch op {
+	case "=":
+		return Bool(c == 0)
+	case "!=", "<>":
+		return Bool(c != 0)
+	case "<":
+		return Bool(c < 0)
+	case "<=":
+		return Bool(c <= 0)
+	case ">":
+		return Bool(c > 0)
+	default:
+		return Bool(c >= 0)
+	}
+}
+
+type arithExpr struct {
+	op   string
+	l, r expr
+}
+
+func (x *arithExpr) eval(e *env) (Value, error) {
+	l, err := x.l.eval(e)
+	if err != nil {
+		return Value{}, err
+	}
+	r, err := x.r.eval(e)
+	if err != nil {
+		return Value{}, err
+	}
+	if l.IsNull() || r.IsNull() {
+		return Null(), nil
+	}
+
+	if x.op == "||" {
+		return String(l.Text() + r.Text()), nil
+	}
+
+	ln, lok := asNumber(l)
+	rn, rok := asNumber(r)
+	if !lok || !rok {
+		return Value{}, newError(ErrCodeInvalidArgumentType, "operands of %v are not numbers", x.op)
+	}
+
+	if ln.kind == KindInt && rn.kind == KindInt {
+		a, b := ln.i, rn.i
+		switch x.op {
+		case "+":
+			return Int(a + b), nil
+		case "-":
+			return Int(a - b), nil
+		case "*":
+			return Int(a * b), nil
+		case "/":
+			if b == 0 {
+				return Value{}, newError(ErrCodeDivisionByZero, "division by zero")
+			}
+			return Int(a / b), nil
+		default:
+			if b == 0 {
+				return Value{}, newError(ErrCodeDivisionByZero, "division by zero")
+			}
+			return Int(a % b), nil
+		}
+	}
+
+	a, b := toFloat(ln), toFloat(rn)
+	switch x.op {
+	case "+":
+		return Float(a + b), nil
+	case "-":
+		return Float(a - b), nil
+	case "*":
+		return Float(a * b), nil
+	case "/":
+		if b == 0 {
+			return Value{}, newError(ErrCodeDivisionByZero, "division by zero")
+		}
+		return Float(a / b), nil
+	default:
+		if b == 0 {
+			return Value{}, newError(ErrCodeDivisionByZero, "division by zero")
+		}
+		return Float(math.Mod(a, b)), nil
+	}
+}
+
+type isExpr struct {
+	x    expr
+	not  bool
+	what string
+}
+
+func (x *isExpr) eval(e *env) (Value, error) {
+	v, err := x.x.eval(e)
+	if err != nil {
+		return Value{}, err
+	}
+	var res bool
+	switch x.what {
+	case "NULL":
+		res = v.IsNull()
+	case "MISSING":
+		res = v.IsMissing()
+	case "TRUE":
+		res = v.kind == KindBool && v.b
+	default:
+		res = v.kind == KindBool && !v.b
+	}
+	return Bool(res != x.not), nil
+}
+
+type likeExpr struct {
+	x, pattern, escape expr
+	not                bool
+}
+
+func (x *likeExpr) eval(e *env) (Value, error) {
+	v, err := x.x.eval(e)
+	if err != nil {
+		return Value{}, err
+	}
+	p, err := x.pattern.eval(e)
+	if err != nil {
+		return Value{}, err
+	}
+	if v.IsNull() || p.IsNull() {
+		return Null(), nil
+	}
+
+	esc := rune(-1)
+	if x.escape != nil {
+		ev, err := x.escape.eval(e)
+		if err != nil {
+			return Value{}, err
+		}
+		r := []rune(ev.Text())
+		if len(r) != 1 {
+			return Value{}, newError(ErrCodeInvalidArguments, "LIKE escape must be a single character")
+		}
+		esc = r[0]
+	}
+
+	return Bool(likeMatch([]rune(v.Text()), []rune(p.Text()), esc) != x.not), nil
+}
+
+// likeMatch matches s against the LIKE pattern where % matches any
+// sequence and _ matches any single character
+func likeMatch(s, p []rune, esc rune) bool {
+	for len(p) > 0 {
+		c := p[0]
+		switch {
+		case c == esc && len(p) > 1:
+			if len(s) == 0 || s[0] != p[1] {
+				return false
+			}
+			s, p = s[1:], p[2:]
+		case c == '%':
+			for len(p) > 0 && p[0] == '%' {
+				p = p[1:]
+			}
+			if len(p) == 0 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if likeMatch(s[i:], p, esc) {
+					return true
+				}
+			}
+			return false
+		case c == '_':
+			if len(s) == 0 {
+				return false
+			}
+			s, p = s[1:], p[1:]
+		default:
+			if len(s) == 0 || s[0] != c {
+				return false
+			}
+			s, p = s[1:], p[1:]
+		}
+	}
+	return len(s) == 0
+}
+
+type betweenExpr struct {
+	x, lo, hi expr
+	not       bool
+}
+
+func (x *betweenExpr) eval(e *env) (Value, error) {
+	v, err := x.x.eval(e)
+	if err != nil {
+		return Value{}, err
+	}
+	lo, err := x.lo.eval(e)
+	if err != nil {
+		return Value{}, err
+	}
+	hi, err := x.hi.eval(e)
+	if err != nil {
+		return Value{}, err
+	}
+	ge := compareValues(">=", v, lo)
+	le := compareValues("<=", v, hi)
+	if ge.IsNull() || le.IsNull() {
+		return Null(), nil
+	}
+	return Bool((ge.b && le.b) != x.not), nil
+}
+
+type inExpr struct {
+	x    expr
+	list []expr
+	not  bool
+}
+
+func (x *inExpr) eval(e *env) (Value, error) {
+	v, err := x.x.eval(e)
+	if err != nil {
+		return Value{}, err
+	}
+	if v.IsNull() {
+		return Null(), nil
+	}
+	sawNull := false
+	for _, item := range x.list {
+		iv, err := item.eval(e)
+		if err != nil {
+			return Value{}, err
+		}
+		eq := compareValues("=", v, iv)
+		if eq.IsNull() {
+			sawNull = true
+			continue
+		}
+		if eq.b {
+			return Bool(!x.not), nil
+		}
+	}
+	if sawNull {
+		return Null(), nil
+	}
+	return Bool(x.not), nil
+}
+
+type whenClause struct {
+	cond, result expr
+}
+
+type caseExpr struct {
+	operand expr
+	whens   []whenClause
+	els     expr
+}
+
+func (x *caseExpr) eval(e *env) (Value, error) {
+	var operand Value
+	if x.operand != nil {
+		var err error
+		operand, err = x.operand.eval(e)
+		if err != nil {
+			return Value{}, err
+		}
+	}
+
+	for _, w := range x.whens {
+		c, err := w.cond.eval(e)
+		if err != nil {
+			return Value{}, err
+		}
+		if x.operand != nil {
+			c = compareValues("=", operand, c)
+		}
+		if c.kind == KindBool && c.b {
+			return w.result.eval(e)
+		}
+	}
+
+	if x.els != nil {
+		return x.els.eval(e)
+	}
+	return Null(), nil
+}
@@ -0,0 +1,504 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package sql
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// castTypes maps the type names accepted by CAST to the value kinds
+var castTypes = map[string]Kind{
+	"BOOL":      KindBool,
+	"BOOLEAN":   KindBool,
+	"INT":       KindInt,
+	"INTEGER":   KindInt,
+	"BIGINT":    KindInt,
+	"SMALLINT":  KindInt,
+	"FLOAT":     KindFloat,
+	"REAL":      KindFloat,
+	"DOUBLE":    KindFloat,
+	"DECIMAL":   KindFloat,
+	"NUMERIC":   KindFloat,
+	"STRING":    KindString,
+	"VARCHAR":   KindString,
+	"CHAR":      KindString,
+	"TEXT":      KindString,
+	"TIMESTAMP": KindTimestamp,
+}
+
+type castExpr struct {
+	x   expr
+	typ Kind
+}
+
+func (x *castExpr) eval(e *env) (Value, error) {
+	v, err := x.x.eval(e)
+	if err != nil {
+		return Value{}, err
+	}
+	return cast(v, x.typ)
+}
+
+func cast(v Value, typ Kind) (Value, error) {
+	if v.IsNull() {
+		return Null(), nil
+	}
+
+	switch typ {
+	case KindString:
+		if v.isCollection() {
+			break
+		}
+		return String(v.Text()), nil
+
+	case KindInt:
+		switch v.kind {
+		case KindInt:
+			return v, nil
+		case KindFloat:
+			return Int(int64(v.f)), nil
+		case KindBool:
+			if v.b {
+				return Int(1), nil
+			}
+			return Int(0), nil
+		case KindString:
+			if n, ok := parseNumber(v.s); ok {
+				if n.kind == KindFloat {
+					return Int(int64(n.f)), nil
+				}
+				return n, nil
+			}
+		}
+
+	case KindFloat:
+		switch v.kind {
+		case KindInt:
+			return Float(float64(v.i)), nil
+		case KindFloat:
+			return v, nil
+		case KindBool:
+			if v.b {
+				return Float(1), nil
+			}
+			return Float(0), nil
+		case KindString:
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v.s), 64); err == nil {
+				return Float(f), nil
+			}
+		}
+
+	case KindBool:
+		switch v.kind {
+		case KindBool:
+			return v, nil
+		case KindInt:
+			return Bool(v.i != 0), nil
+		case KindFloat:
+			return Bool(v.f != 0), nil
+		case KindString:
+			switch strings.ToLower(strings.TrimSpace(v.s)) {
+			case "true":
+				return Bool(true), nil
+			case "false":
+				return Bool(false), nil
+			}
+		}
+
+	case KindTimestamp:
+		switch v.kind {
+		case KindTimestamp:
+			return v, nil
+		case KindString:
+			if t, err := parseTimestamp(strings.TrimSpace(v.s)); err == nil {
+				return Timestamp(t), nil
+			}
+		}
+	}
+
+	return Value{}, newError(ErrCodeCastFailed, "can not cast %q to %v", v.Text(), kindName(typ))
+}
+
+func kindName(k Kind) string {
+	switch k {
+	case KindBool:
+		return "BOOL"
+	case KindInt:
+		return "INT"
+	case KindFloat:
+		return "FLOAT"
+	case KindString:
+		return "STRING"
+	case KindTimestamp:
+		return "TIMESTAMP"
+	}
+	return "UNKNOWN"
+}
+
+// builtin is a scalar SQL function
+type builtin struct {
+	minArgs, maxArgs int
+	// nullable functions are called with null arguments, the others
+	// return null when any argument is null
+	nullable bool
+	fn       func(args []Value) (Value, error)
+}
+
+var builtins map[string]builtin
+
+func init() {
+	builtins = map[string]builtin{
+		"CHAR_LENGTH":      {1, 1, false, charLength},
+		"CHARACTER_LENGTH": {1, 1, false, charLength},
+		"LOWER":            {1, 1, false, lower},
+		"UPPER":            {1, 1, false, upper},
+		"SUBSTRING":        {2, 3, false, substring},
+		"TRIM":             {1, 3, false, trim},
+		"COALESCE":         {1, -1, true, coalesce},
+		"NULLIF":           {2, 2, true, nullif},
+		"DATE_ADD":         {3, 3, false, dateAddFn},
+		"DATE_DIFF":        {3, 3, false, dateDiffFn},
+		"EXTRACT":          {2, 2, false, extractFn},
+		"TO_STRING":        {2, 2, false, toString},
+		"TO_TIMESTAMP":     {1, 1, false, toTimestamp},
+		"UTCNOW":           {0, 0, false, utcNow},
+	}
+}
+
+// aggregateFuncs are the aggregate functions
+var aggregateFuncs = map[string]bool{
+	"COUNT": true,
+	"SUM":   true,
+	"AVG":   true,
+	"MIN":   true,
+	"MAX":   true,
+}
+
+type callExpr struct {
+	name string
+	fn   builtin
+	args []expr
+}
+
+func (x *callExpr) eval(e *env) (Value, error) {
+	args := make([]Value, len(x.args))
+	for i, arg := range x.args {
+		v, err := arg.eval(e)
+		if err != nil {
+			return Value{}, err
+		}
+		if v.IsNull() && !x.fn.nullable {
+			return Null(), nil
+		}
+		args[i] = v
+	}
+	return x.fn.fn(args)
+}
+
+func textArg(v Value, fn string) (string, error) {
+	if v.kind != KindString {
+		return "", newError(ErrCodeInvalidArgumentType, "%v expects a string argument", fn)
+	}
+	return v.s, nil
+}
+
+func intArg(v Value, fn string) (int64, error) {
+	n, ok := asNumber(v)
+	if !ok {
+		return 0, newError(ErrCodeInvalidArgumentType, "%v expects a number argument", fn)
+	}
+	if n.kind == KindFloat {
+		if n.f != math.Trunc(n.f) {
+			return 0, newError(ErrCodeInvalidArgumentType, "%v expects an integer argument", fn)
+		}
+		return int64(n.f), nil
+	}
+	return n.i, nil
+}
+
+func timestampArg(v Value, fn string) (time.Time, error) {
+	t, ok := asTimestamp(v)
+	if !ok {
+		return time.Time{}, newError(ErrCodeInvalidArgumentType, "%v expects a timestamp argument", fn)
+	}
+	return t, nil
+}
+
+func charLength(args []Value) (Value, error) {
+	s, err := textArg(args[0], "CHAR_LENGTH")
+	if err != nil {
+		return Value{}, err
+	}
+	return Int(int64(utf8.RuneCountInString(s))), nil
+}
+
+func lower(args []Value) (Value, error) {
+	s, err := textArg(args[0], "LOWER")
+	if err != nil {
+		return Value{}, err
+	}
+	return String(strings.ToLower(s)), nil
+}
+
+func upper(args []Value) (Value, error) {
+	s, err := textArg(args[0], "UPPER")
+	if err != nil {
+		return Value{}, err
+	}
+	return String(strings.ToUpper(s)), nil
+}
+
+// substring returns the characters starting at the 1 based position
+// args[1], positions before the start of the string count toward the
+// length
+func substring(args []Value) (Value, error) {
+	s, err := textArg(args[0], "SUBSTRING")
+	if err != nil {
+		return Value{}, err
+	}
+	start, err := intArg(args[1], "SUBSTRING")
+	if err != nil {
+		return Value{}, err
+	}
+	runes := []rune(s)
+	end := int64(len(runes)) + 1
+	if len(args) == 3 {
+		length, err := intArg(args[2], "SUBSTRING")
+		if err != nil {
+			return Value{}, err
+		}
+		if length < 0 {
+			return Value{}, newError(ErrCodeInvalidArguments, "SUBSTRING length can not be negative")
+		}
+		end = min(end, start+length)
+	}
+	start = max(start, 1)
+	if start >= end {
+		return String(""), nil
+	}
+	return String(string(runes[start-1 : end-1])), nil
+}
+
+// trim arguments are the string, then the characters to remove and
+// the LEADING, TRAILING or BOTH mode when given
+func trim(args []Value) (Value, error) {
+	s, err := textArg(args[0], "TRIM")
+	if err != nil {
+		return Value{}, err
+	}
+	chars := " "
+	if len(args) > 1 {
+		chars, err = textArg(args[1], "TRIM")
+		if err != nil {
+			return Value{}, err
+		}
+	}
+	mode := "BOTH"
+	if len(args) > 2 {
+		mode = args[2].Text()
+	}
+	switch mode {
+	case "LEADING":
+		return String(strings.TrimLeft(s, chars)), nil
+	case "TRAILING":
+		return String(strings.TrimRight(s, chars)), nil
+	}
+	return String(strings.Trim(s, chars)), nil
+}
+
+func coalesce(args []Value) (Value, error) {
+	for _, v := range args {
+		if !v.IsNull() {
+			return v, nil
+		}
+	}
+	return Null(), nil
+}
+
+func nullif(args []Value) (Value, error) {
+	eq := compareValues("=", args[0], args[1])
+	if eq.kind == KindBool && eq.b {
+		return Null(), nil
+	}
+	return args[0], nil
+}
+
+func dateAddFn(args []Value) (Value, error) {
+	part, err := parseDatePart(args[0].Text(), false)
+	if err != nil {
+		return Value{}, err
+	}
+	n, err := intArg(args[1], "DATE_ADD")
+	if err != nil {
+		return Value{}, err
+	}
+	t, err := timestampArg(args[2], "DATE_ADD")
+	if err != nil {
+		return Value{}, err
+	}
+	return Timestamp(dateAdd(part, n, t)), nil
+}
+
+func dateDiffFn(args []Value) (Value, error) {
+	part, err := parseDatePart(args[0].Text(), false)
+	if err != nil {
+		return Value{}, err
+	}
+	from, err := timestampArg(args[1], "DATE_DIFF")
+	if err != nil {
+		return Value{}, err
+	}
+	to, err := timestampArg(args[2], "DATE_DIFF")
+	if err != nil {
+		return Value{}, err
+	}
+	return Int(dateDiff(part, from, to)), nil
+}
+
+func extractFn(args []Value) (Value, error) {
+	part, err := parseDatePart(args[0].Text(), true)
+	if err != nil {
+		return Value{}, err
+	}
+	t, err := timestampArg(args[1], "EXTRACT")
+	if err != nil {
+		return Value{}, err
+	}
+	return Int(extract(part, t)), nil
+}
+
+func toString(args []Value) (Value, error) {
+	t, err := timestampArg(args[0], "TO_STRING")
+	if err != nil {
+		return Value{}, err
+	}
+	pattern, err := textArg(args[1], "TO_STRING")
+	if err != nil {
+		return Value{}, err
+	}
+	s, err := formatTimestampPattern(t, pattern)
+	if err != nil {
+		return Value{}, err
+	}
+	return String(s), nil
+}
+
+func toTimestamp(args []Value) (Value, error) {
+	if args[0].kind == KindTimestamp {
+		return args[0], nil
+	}
+	s, err := textArg(args[0], "TO_TIMESTAMP")
+	if err != nil {
+		return Value{}, err
+	}
+	t, err := parseTimestamp(strings.TrimSpace(s))
+	if err != nil {
+		return Value{}, newError(ErrCodeCastFailed, "%v", err)
+	}
+	return Timestamp(t), nil
+}
+
+func utcNow([]Value) (Value, error) {
+	return Timestamp(time.Now().UTC()), nil
+}
+
+// aggregateExpr is an aggregate function call, the aggregate state is
+// updated for each record and the result is evaluated once all the
+// records are read
+type aggregateExpr struct {
+	name string
+	arg  expr
+	star bool
+
+	count  int64
+	sumI   int64
+	sumF   float64
+	isF    bool
+	result Value
+}
+
+func (x *aggregateExpr) accumulate(e *env) error {
+	if x.star {
+		x.count++
+		return nil
+	}
+
+	v, err := x.arg.eval(e)
+	if err != nil {
+		return err
+	}
+	if v.IsNull() {
+		return nil
+	}
+
+	switch x.name {
+	case "COUNT":
+	case "SUM", "AVG":
+		n, ok := asNumber(v)
+		if !ok {
+			return newError(ErrCodeInvalidArgumentType, "%v expects a number argument", x.name)
+		}
+		if n.kind == KindFloat && !x.isF {
+			x.isF = true
+			x.sumF = float64(x.sumI)
+		}
+		if x.isF {
+			x.sumF += toFloat(n)
+		} else {
+			x.sumI += n.i
+		}
+	case "MIN", "MAX":
+		if x.count == 0 {
+			x.result = v
+			break
+		}
+		c, ok := compare(v, x.result)
+		if !ok {
+			return newError(ErrCodeInvalidArgumentType, "%v arguments can not be compared", x.name)
+		}
+		if (x.name == "MIN" && c < 0) || (x.name == "MAX" && c > 0) {
+			x.result = v
+		}
+	}
+	x.count++
+	return nil
+}
+
+func (x *aggregateExpr) eval(*env) (Value, error) {
+	switch x.name {
+	case "COUNT":
+		return Int(x.count), nil
+	}
+	if x.count == 0 {
+		return Null(), nil
+	}
+	switch x.name {
+	case "SUM":
+		if x.isF {
+			return Float(x.sumF), nil
+		}
+		return Int(x.sumI), nil
+	case "AVG":
+		if x.isF {
+			return Float(x.sumF / float64(x.count)), nil
+		}
+		return Float(float64(x.sumI) / float64(x.count)), nil
+	}
+	return x.result, nil
+}
@@ -0,0 +1,824 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package sql
+
+import (
+	"strconv"
+	"strings"
+)
+
+// reserved are the keywords that can not be used as column names
+// without quoting them
+var reserved = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "LIMIT": true,
+	"AS": true, "AND": true, "OR": true, "NOT": true, "IS": true,
+	"NULL": true, "MISSING": true, "TRUE": true, "FALSE": true,
+	"LIKE": true, "ESCAPE": true, "BETWEEN": true, "IN": true,
+	"CASE": true, "WHEN": true, "THEN": true, "ELSE": true, "END": true,
+	"CAST": true,
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+
+	// aggregate tracking of the select list
+	aggs           []*aggregateExpr
+	inAggregate    bool
+	allowAggregate bool
+	pathsOutside   int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is the keyword or operator s
+func (p *parser) accept(s string) bool {
+	if p.peek().is(s) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(s string) error {
+	if !p.accept(s) {
+		return unexpected(p.peek(), s)
+	}
+	return nil
+}
+
+func unexpected(t token, want string) error {
+	if want != "" {
+		return newError(ErrCodeParseUnexpectedToken, "expected %v but found %v at position %v", want, t, t.pos)
+	}
+	return newError(ErrCodeParseUnexpectedToken, "unexpected %v at position %v", t, t.pos)
+}
+
+func isReserved(t token) bool {
+	return t.kind == tokIdent && reserved[strings.ToUpper(t.text)]
+}
+
+// Parse parses an S3 Select SQL expression
+func Parse(query string) (*Select, error) {
+	tokens, err := tokenize(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	return p.parseSelect()
+}
+
+func (p *parser) parseSelect() (*Select, error) {
+	if err := p.expect("SELECT"); err != nil {
+		return nil, err
+	}
+
+	s := &Select{limit: -1}
+
+	if p.accept("*") {
+		s.star = true
+	} else {
+		p.allowAggregate = true
+		for {
+			item, err := p.parseSelectItem(s)
+			if err != nil {
+				return nil, err
+			}
+			if item != nil {
+				s.items = append(s.items, *item)
+			}
+			if !p.accept(",") {
+				break
+			}
+		}
+		p.allowAggregate = false
+		if s.star && len(s.items) > 0 {
+			return nil, newError(ErrCodeParseUnsupportedSyntax, "* can not be combined with other columns")
+		}
+		if len(p.aggs) > 0 && p.pathsOutside > 0 {
+			return nil, newError(ErrCodeInvalidAggregate, "aggregate queries can only select aggregate functions")
+		}
+		s.aggs = p.aggs
+	}
+
+	if !p.accept("FROM") {
+		return nil, newError(ErrCodeParseSelectMissingFrom, "expected FROM but found %v", p.peek())
+	}
+	if err := p.parseFrom(s); err != nil {
+		return nil, err
+	}
+
+	if p.accept("WHERE") {
+		where, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		s.where = where
+	}
+
+	if p.accept("LIMIT") {
+		t := p.next()
+		if t.kind == tokOp && t.text == "-" {
+			return nil, newError(ErrCodeInvalidLimit, "LIMIT must not be negative")
+		}
+		limit, err := strconv.ParseInt(t.text, 10, 64)
+		if t.kind != tokNumber || err != nil {
+			return nil, unexpected(t, "LIMIT value")
+		}
+		s.limit = limit
+	}
+
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, unexpected(t, "")
+	}
+
+	return s, nil
+}
+
+// parseSelectItem parses a column of the select list, alias.* sets the
+// star of the select and returns no item
+func (p *parser) parseSelectItem(s *Select) (*selectItem, error) {
+	if p.peek().kind == tokIdent && p.tokens[p.pos+1].is(".") && p.tokens[p.pos+2].is("*") {
+		p.pos += 3
+		s.star = true
+		return nil, nil
+	}
+
+	x, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	item := &selectItem{x: x}
+
+	if p.accept("AS") {
+		t := p.next()
+		if t.kind != tokIdent && t.kind != tokQuotedIdent || isReserved(t) {
+			return nil, unexpected(t, "column alias")
+		}
+		item.alias = t.text
+	} else if t := p.peek(); (t.kind == tokIdent && !isReserved(t)) || t.kind == tokQuotedIdent {
+		p.pos++
+		item.alias = t.text
+	}
+
+	return item, nil
+}
+
+func (p *parser) parseFrom(s *Select) error {
+	t := p.next()
+	if !t.is("S3Object") {
+		return unexpected(t, "S3Object")
+	}
+
+	for {
+		switch {
+		case p.accept("["):
+			t := p.next()
+			switch {
+			case t.is("*"):
+				s.from = append(s.from, pathElem{kind: elemWildcard})
+			case t.kind == tokNumber:
+				i, err := strconv.Atoi(t.text)
+				if err != nil {
+					return newError(ErrCodeParseInvalidPathComponent, "invalid index %v", t.text)
+				}
+				s.from = append(s.from, pathElem{kind: elemIndex, index: i})
+			case t.kind == tokString:
+				s.from = append(s.from, pathElem{kind: elemName, name: t.text, quoted: true})
+			default:
+				return unexpected(t, "path index")
+			}
+			if err := p.expect("]"); err != nil {
+				return err
+			}
+		case p.accept("."):
+			t := p.next()
+			if t.kind != tokIdent && t.kind != tokQuotedIdent {
+				return unexpected(t, "path name")
+			}
+			s.from = append(s.from, pathElem{kind: elemName, name: t.text, quoted: t.kind == tokQuotedIdent})
+		default:
+			if p.accept("AS") {
+				t := p.next()
+				if t.kind != tokIdent || isReserved(t) {
+					return unexpected(t, "table alias")
+				}
+				s.alias = t.text
+			} else if t := p.peek(); t.kind == tokIdent && !isReserved(t) {
+				p.pos++
+				s.alias = t.text
+			}
+			return nil
+		}
+	}
+}
+
+func (p *parser) parseExpr() (expr, error) {
+	return p.parseOr()
+}
+
+func (p *parser) parseOr() (expr, error) {
+	l, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("OR") {
+		r, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l = &logicalExpr{l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *parser) parseAnd() (expr, error) {
+	l, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("AND") {
+		r, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		l = &logicalExpr{and: true, l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *parser) parseNot() (expr, error) {
+	if p.accept("NOT") {
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notExpr{x: x}, nil
+	}
+	return p.parsePredicate()
+}
+
+var compareOps = []string{"=", "!=", "<>", "<", "<=", ">", ">="}
+
+func (p *parser) parsePredicate() (expr, error) {
+	l, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, op := range compareOps {
+		if p.accept(op) {
+			r, err := p.parseAdditive()
+			if err != nil {
+				return nil, err
+			}
+			return &compareExpr{op: op, l: l, r: r}, nil
+		}
+	}
+
+	if p.accept("IS") {
+		not := p.accept("NOT")
+		t := p.next()
+		for _, what := range []string{"NULL", "MISSING", "TRUE", "FALSE"} {
+			if t.is(what) {
+				return &isExpr{x: l, not: not, what: what}, nil
+			}
+		}
+		return nil, unexpected(t, "NULL or MISSING")
+	}
+
+	not := false
+	if p.peek().is("NOT") {
+		after := p.tokens[p.pos+1]
+		if after.is("LIKE") || after.is("BETWEEN") || after.is("IN") {
+			p.pos++
+			not = true
+		}
+	}
+
+	switch {
+	case p.accept("LIKE"):
+		pattern, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		x := &likeExpr{x: l, pattern: pattern, not: not}
+		if p.accept("ESCAPE") {
+			x.escape, err = p.parseAdditive()
+			if err != nil {
+				return nil, err
+			}
+		}
+		return x, nil
+
+	case p.accept("BETWEEN"):
+		lo, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("AND"); err != nil {
+			return nil, err
+		}
+		hi, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		return &betweenExpr{x: l, lo: lo, hi: hi, not: not}, nil
+
+	case p.accept("IN"):
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		list, err := p.parseExprList(")")
+		if err != nil {
+			return nil, err
+		}
+		return &inExpr{x: l, list: list, not: not}, nil
+	}
+
+	return l, nil
+}
+
+func (p *parser) parseAdditive() (expr, error) {
+	l, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if !t.is("+") && !t.is("-") && !t.is("||") {
+			return l, nil
+		}
+		p.pos++
+		r, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		l = &arithExpr{op: t.text, l: l, r: r}
+	}
+}
+
+func (p *parser) parseMultiplicative() (expr, error) {
+	l, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if !t.is("*") && !t.is("/") && !t.is("%") {
+			return l, nil
+		}
+		p.pos++
+		r, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l = &arithExpr{op: t.text, l: l, r: r}
+	}
+}
+
+func (p *parser) parseUnary() (expr, error) {
+	if p.accept("-") {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if lit, ok := x.(*literalExpr); ok && lit.v.isNumber() {
+			return negExprLiteral(lit), nil
+		}
+		return &negExpr{x: x}, nil
+	}
+	if p.accept("+") {
+		return p.parseUnary()
+	}
+	return p.parsePrimary()
+}
+
+func negExprLiteral(lit *literalExpr) expr {
+	if lit.v.kind == KindInt {
+		return &literalExpr{v: Int(-lit.v.i)}
+	}
+	return &literalExpr{v: Float(-lit.v.f)}
+}
+
+func (p *parser) parseExprList(end string) ([]expr, error) {
+	var list []expr
+	if p.accept(end) {
+		return list, nil
+	}
+	for {
+		x, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, x)
+		if p.accept(end) {
+			return list, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *parser) parsePrimary() (expr, error) {
+	t := p.peek()
+
+	switch t.kind {
+	case tokString:
+		p.pos++
+		return &literalExpr{v: String(t.text)}, nil
+
+	case tokNumber:
+		p.pos++
+		v, ok := parseNumber(t.text)
+		if !ok {
+			return nil, unexpected(t, "number")
+		}
+		return &literalExpr{v: v}, nil
+
+	case tokQuotedIdent:
+		return p.parsePath()
+
+	case tokOp:
+		if p.accept("(") {
+			x, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return x, nil
+		}
+		return nil, newError(ErrCodeParseExpectedExpression, "expected expression but found %v at position %v", t, t.pos)
+
+	case tokEOF:
+		return nil, newError(ErrCodeParseExpectedExpression, "expected expression but found %v", t)
+	}
+
+	switch {
+	case t.is("NULL"):
+		p.pos++
+		return &literalExpr{v: Null()}, nil
+	case t.is("MISSING"):
+		p.pos++
+		return &literalExpr{v: Missing()}, nil
+	case t.is("TRUE"):
+		p.pos++
+		return &literalExpr{v: Bool(true)}, nil
+	case t.is("FALSE"):
+		p.pos++
+		return &literalExpr{v: Bool(false)}, nil
+	case t.is("CASE"):
+		p.pos++
+		return p.parseCase()
+	case t.is("CAST"):
+		p.pos++
+		return p.parseCast()
+	case isReserved(t):
+		return nil, unexpected(t, "")
+	}
+
+	if p.tokens[p.pos+1].is("(") {
+		return p.parseCall()
+	}
+	return p.parsePath()
+}
+
+func (p *parser) parsePath() (expr, error) {
+	first := p.next()
+	if pos, ok := isPositionalName(first); ok && pos < 1 {
+		return nil, newError(ErrCodeInvalidColumnIndex, "invalid column index %v", first.text)
+	}
+	x := &pathExpr{elems: []pathElem{{
+		kind:   elemName,
+		name:   first.text,
+		quoted: first.kind == tokQuotedIdent,
+	}}}
+
+	for {
+		switch {
+		case p.peek().is(".") && p.tokens[p.pos+1].is("*"):
+			return nil, newError(ErrCodeParseUnsupportedSyntax, "path wildcards are only supported in the select list")
+		case p.accept("."):
+			t := p.next()
+			if t.kind != tokIdent && t.kind != tokQuotedIdent {
+				return nil, unexpected(t, "path name")
+			}
+			x.elems = append(x.elems, pathElem{kind: elemName, name: t.text, quoted: t.kind == tokQuotedIdent})
+		case p.accept("["):
+			t := p.next()
+			switch t.kind {
+			case tokNumber:
+				i, err := strconv.Atoi(t.text)
+				if err != nil {
+					return nil, newError(ErrCodeParseInvalidPathComponent, "invalid index %v", t.text)
+				}
+				x.elems = append(x.elems, pathElem{kind: elemIndex, index: i})
+			case tokString:
+				x.elems = append(x.elems, pathElem{kind: elemName, name: t.text, quoted: true})
+			default:
+				return nil, newError(ErrCodeParseInvalidPathComponent, "invalid path component %v", t)
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+		default:
+			if !p.inAggregate {
+				p.pathsOutside++
+			}
+			return x, nil
+		}
+	}
+}
+
+func isPositionalName(t token) (int, bool) {
+	if t.kind != tokIdent || len(t.text) < 2 || t.text[0] != '_' {
+		return 0, false
+	}
+	pos, err := strconv.Atoi(t.text[1:])
+	return pos, err == nil
+}
+
+func (p *parser) parseCase() (expr, error) {
+	x := &caseExpr{}
+	if !p.peek().is("WHEN") {
+		operand, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		x.operand = operand
+	}
+
+	for p.accept("WHEN") {
+		cond, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("THEN"); err != nil {
+			return nil, err
+		}
+		result, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		x.whens = append(x.whens, whenClause{cond: cond, result: result})
+	}
+	if len(x.whens) == 0 {
+		return nil, unexpected(p.peek(), "WHEN")
+	}
+
+	if p.accept("ELSE") {
+		els, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		x.els = els
+	}
+
+	if err := p.expect("END"); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+func (p *parser) parseCast() (expr, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	x, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect("AS"); err != nil {
+		return nil, err
+	}
+	t := p.next()
+	typ, ok := castTypes[strings.ToUpper(t.text)]
+	if t.kind != tokIdent || !ok {
+		if t.kind == tokIdent {
+			return nil, newError(ErrCodeInvalidCast, "unsupported cast type %v", t.text)
+		}
+		return nil, newError(ErrCodeParseExpectedTypeName, "expected type name but found %v", t)
+	}
+	// precision and scale are accepted but not used
+	if p.accept("(") {
+		if _, err := p.parseExprList(")"); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	return &castExpr{x: x, typ: typ}, nil
+}
+
+func (p *parser) parseCall() (expr, error) {
+	name := strings.ToUpper(p.next().text)
+	p.pos++ // (
+
+	if aggregateFuncs[name] {
+		return p.parseAggregate(name)
+	}
+
+	fn, ok := builtins[name]
+	if !ok {
+		return nil, newError(ErrCodeUnsupportedFunction, "unsupported function %v", name)
+	}
+
+	var args []expr
+	var err error
+	switch name {
+	case "SUBSTRING":
+		args, err = p.parseSubstringArgs()
+	case "TRIM":
+		args, err = p.parseTrimArgs()
+	case "EXTRACT":
+		args, err = p.parseExtractArgs()
+	case "DATE_ADD", "DATE_DIFF":
+		args, err = p.parseDateArgs()
+	default:
+		args, err = p.parseExprList(")")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(args) < fn.minArgs || (fn.maxArgs >= 0 && len(args) > fn.maxArgs) {
+		return nil, newError(ErrCodeInvalidArguments, "invalid number of arguments for %v", name)
+	}
+	return &callExpr{name: name, fn: fn, args: args}, nil
+}
+
+func (p *parser) parseAggregate(name string) (expr, error) {
+	if !p.allowAggregate {
+		return nil, newError(ErrCodeInvalidAggregate, "aggregate function %v is only allowed in the select list", name)
+	}
+	if p.inAggregate {
+		return nil, newError(ErrCodeInvalidAggregate, "aggregate function calls can not be nested")
+	}
+
+	x := &aggregateExpr{name: name}
+	if name == "COUNT" && p.accept("*") {
+		x.star = true
+	} else {
+		p.inAggregate = true
+		arg, err := p.parseExpr()
+		p.inAggregate = false
+		if err != nil {
+			return nil, err
+		}
+		x.arg = arg
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+
+	p.aggs = append(p.aggs, x)
+	return x, nil
+}
+
+// parseSubstringArgs parses SUBSTRING(s FROM start [FOR length]) and
+// SUBSTRING(s, start [, length])
+func (p *parser) parseSubstringArgs() ([]expr, error) {
+	s, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.accept("FROM") {
+		rest, err := p.parseCommaArgs()
+		if err != nil {
+			return nil, err
+		}
+		return append([]expr{s}, rest...), nil
+	}
+
+	start, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	args := []expr{s, start}
+	if p.accept("FOR") {
+		length, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, length)
+	}
+	return args, p.expect(")")
+}
+
+// parseCommaArgs parses the remaining comma separated arguments of a
+// call once the first one is consumed
+func (p *parser) parseCommaArgs() ([]expr, error) {
+	var args []expr
+	for p.accept(",") {
+		x, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, x)
+	}
+	return args, p.expect(")")
+}
+
+// parseTrimArgs parses TRIM([[LEADING|TRAILING|BOTH] [chars] FROM] s)
+// into the string, characters and mode arguments
+func (p *parser) parseTrimArgs() ([]expr, error) {
+	mode := ""
+	for _, m := range []string{"LEADING", "TRAILING", "BOTH"} {
+		if p.accept(m) {
+			mode = m
+			break
+		}
+	}
+
+	var chars expr
+	if !p.peek().is("FROM") {
+		x, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if mode == "" && p.accept(")") {
+			return []expr{x}, nil
+		}
+		chars = x
+	}
+	if err := p.expect("FROM"); err != nil {
+		return nil, err
+	}
+	s, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+
+	if chars == nil {
+		chars = &literalExpr{v: String(" ")}
+	}
+	if mode == "" {
+		mode = "BOTH"
+	}
+	return []expr{s, chars, &literalExpr{v: String(mode)}}, nil
+}
+
+// parseExtractArgs parses EXTRACT(part FROM timestamp)
+func (p *parser) parseExtractArgs() ([]expr, error) {
+	t := p.next()
+	if t.kind != tokIdent {
+		return nil, unexpected(t, "date part")
+	}
+	if _, err := parseDatePart(t.text, true); err != nil {
+		return nil, err
+	}
+	if err := p.expect("FROM"); err != nil {
+		return nil, err
+	}
+	ts, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return []expr{&literalExpr{v: String(t.text)}, ts}, p.expect(")")
+}
+
+// parseDateArgs parses the date part followed by the arguments of
+// DATE_ADD and DATE_DIFF
+func (p *parser) parseDateArgs() ([]expr, error) {
+	t := p.next()
+	if t.kind != tokIdent {
+		return nil, unexpected(t, "date part")
+	}
+	if _, err := parseDatePart(t.text, false); err != nil {
+		return nil, err
+	}
+	rest, err := p.parseCommaArgs()
+	if err != nil {
+		return nil, err
+	}
+	return append([]expr{&literalExpr{v: String(t.text)}}, rest...), nil
+}
@@ -0,0 +1,152 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package sql
+
+import "fmt"
+
+type selectItem struct {
+	x     expr
+	alias string
+}
+
+// Select is a parsed S3 Select statement
+type Select struct {
+	star  bool
+	items []selectItem
+	from  []pathElem
+	alias string
+	where expr
+	limit int64
+	aggs  []*aggregateExpr
+
+	returned int64
+}
+
+// IsAggregate reports whether the statement selects aggregate
+// functions, these return a single row once all records are processed
+func (s *Select) IsAggregate() bool {
+	return len(s.aggs) > 0
+}
+
+// Done reports whether the LIMIT of the statement is reached and no
+// more records need to be processed
+func (s *Select) Done() bool {
+	return !s.IsAggregate() && s.limit >= 0 && s.returned >= s.limit
+}
+
+// Records returns the records selected by the FROM clause path in the
+// input document, S3Object[*] iterates over the elements of lists
+func (s *Select) Records(doc Value) []Value {
+	values := []Value{doc}
+	for _, elem := range s.from {
+		var next []Value
+		for _, v := range values {
+			switch elem.kind {
+			case elemWildcard:
+				if v.kind == KindList {
+					next = append(next, v.list...)
+				} else {
+					next = append(next, v)
+				}
+			case elemName:
+				if f := v.Field(elem.name, elem.quoted); !f.IsMissing() {
+					next = append(next, f)
+				}
+			case elemIndex:
+				if e := v.Index(elem.index); !e.IsMissing() {
+					next = append(next, e)
+				}
+			}
+		}
+		values = next
+	}
+	return values
+}
+
+// Eval evaluates the statement for the record. It returns the row to
+// output and true when the record matches a non aggregate statement,
+// aggregate statements only update their state.
+func (s *Select) Eval(record Value) (Value, bool, error) {
+	if s.Done() {
+		return Value{}, false, nil
+	}
+
+	e := &env{record: record, alias: s.alias}
+
+	if s.where != nil {
+		v, err := s.where.eval(e)
+		if err != nil {
+			return Value{}, false, err
+		}
+		if v.kind != KindBool || !v.b {
+			return Value{}, false, nil
+		}
+	}
+
+	if s.IsAggregate() {
+		for _, agg := range s.aggs {
+			if err := agg.accumulate(e); err != nil {
+				return Value{}, false, err
+			}
+		}
+		return Value{}, false, nil
+	}
+
+	row, err := s.project(e)
+	if err != nil {
+		return Value{}, false, err
+	}
+	s.returned++
+	return row, true, nil
+}
+
+// Result returns the row of an aggregate statement once all the
+// records are evaluated
+func (s *Select) Result() (Value, error) {
+	return s.project(&env{alias: s.alias})
+}
+
+func (s *Select) project(e *env) (Value, error) {
+	if s.star {
+		if e.record.kind == KindObject {
+			return e.record, nil
+		}
+		return Object([]Field{{Name: "_1", Value: e.record}}), nil
+	}
+
+	fields := make([]Field, len(s.items))
+	for i, item := range s.items {
+		v, err := item.x.eval(e)
+		if err != nil {
+			return Value{}, err
+		}
+		fields[i] = Field{Name: item.name(i), Value: v}
+	}
+	return Object(fields), nil
+}
+
+// name is the name of the column in JSON output, this is the alias or
+// the name of the referenced column, or _N for the Nth column
+func (item selectItem) name(i int) string {
+	if item.alias != "" {
+		return item.alias
+	}
+	if path, ok := item.x.(*pathExpr); ok {
+		if name := path.name(); name != "" {
+			return name
+		}
+	}
+	return fmt.Sprintf("_%v", i+1)
+}
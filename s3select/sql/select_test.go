@@ -0,0 +1,188 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package sql
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func csvRecord(names []string, values ...string) Value {
+	fields := make([]Field, len(values))
+	for i, v := range values {
+		fields[i] = Field{Name: names[i], Value: String(v)}
+	}
+	return Object(fields)
+}
+
+// run evaluates the query over the records and returns the JSON of the
+// returned rows
+func run(t *testing.T, query string, records []Value) ([]string, error) {
+	t.Helper()
+	s, err := Parse(query)
+	if err != nil {
+		return nil, err
+	}
+	var rows []string
+	for _, rec := range records {
+		for _, r := range s.Records(rec) {
+			row, ok, err := s.Eval(r)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				rows = append(rows, string(row.AppendJSON(nil)))
+			}
+		}
+	}
+	if s.IsAggregate() {
+		row, err := s.Result()
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, string(row.AppendJSON(nil)))
+	}
+	return rows, nil
+}
+
+func TestSelect(t *testing.T) {
+	names := []string{"name", "city", "age", "joined"}
+	records := []Value{
+		csvRecord(names, "alice", "Denver", "34", "2019-03-01T10:00:00Z"),
+		csvRecord(names, "bob", "Boston", "27", "2021-11-15T08:30:00Z"),
+		csvRecord(names, "carol", "Denver", "45", "2015-07-20T00:00:00Z"),
+		csvRecord(names, "dave", "", "19", "2023-01-02T00:00:00Z"),
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"star", "SELECT * FROM S3Object LIMIT 1",
+			[]string{`{"name":"alice","city":"Denver","age":"34","joined":"2019-03-01T10:00:00Z"}`}},
+		{"columns-and-alias", "select s.name, s.age AS years from s3object s where s.city = 'Boston'",
+			[]string{`{"name":"bob","years":"27"}`}},
+		{"positional", "SELECT _1 FROM S3Object WHERE _3 > 40",
+			[]string{`{"_1":"carol"}`}},
+		{"cast-and-arithmetic", "SELECT CAST(age AS INT) + 1 FROM S3Object WHERE CAST(age AS INT) < 20",
+			[]string{`{"_1":20}`}},
+		{"like-and-or", "SELECT name FROM S3Object WHERE name LIKE '%o%' OR name LIKE 'd__e'",
+			[]string{`{"name":"bob"}`, `{"name":"carol"}`, `{"name":"dave"}`}},
+		{"between-and-in", "SELECT name FROM S3Object WHERE CAST(age AS INT) BETWEEN 20 AND 40 AND city IN ('Denver', 'Austin')",
+			[]string{`{"name":"alice"}`}},
+		{"string-functions", "SELECT UPPER(name), CHAR_LENGTH(city), SUBSTRING(name FROM 2 FOR 2), TRIM(LEADING 'a' FROM name) FROM S3Object LIMIT 1",
+			[]string{`{"_1":"ALICE","_2":6,"_3":"li","_4":"lice"}`}},
+		{"case-and-nullif", "SELECT CASE WHEN CAST(age AS INT) >= 30 THEN 'senior' ELSE 'junior' END AS level, COALESCE(NULLIF(city, ''), 'unknown') AS city FROM S3Object WHERE name = 'dave'",
+			[]string{`{"level":"junior","city":"unknown"}`}},
+		{"date-functions", "SELECT EXTRACT(YEAR FROM TO_TIMESTAMP(joined)), DATE_DIFF(month, TO_TIMESTAMP(joined), TO_TIMESTAMP('2020-03-01T10:00:00Z')), TO_STRING(DATE_ADD(day, 1, TO_TIMESTAMP(joined)), 'yyyy-MM-dd') FROM S3Object LIMIT 1",
+			[]string{`{"_1":2019,"_2":12,"_3":"2019-03-02"}`}},
+		{"aggregates", "SELECT COUNT(*), SUM(CAST(age AS INT)), MIN(name), MAX(CAST(age AS INT)), AVG(CAST(age AS FLOAT)) FROM S3Object s WHERE s.city <> ''",
+			[]string{`{"_1":3,"_2":106,"_3":"alice","_4":45,"_5":35.333333333333336}`}},
+		{"missing-column", "SELECT name FROM S3Object WHERE nope IS MISSING LIMIT 1",
+			[]string{`{"name":"alice"}`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := run(t, tt.query, records)
+			if err != nil {
+				t.Fatalf("query error: %v", err)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectJSON(t *testing.T) {
+	doc := Object([]Field{
+		{Name: "users", Value: List([]Value{
+			Object([]Field{
+				{Name: "id", Value: Int(1)},
+				{Name: "tags", Value: List([]Value{String("a"), String("b")})},
+				{Name: "address", Value: Object([]Field{{Name: "zip", Value: String("80202")}})},
+			}),
+			Object([]Field{
+				{Name: "id", Value: Int(2)},
+				{Name: "active", Value: Bool(false)},
+			}),
+		})},
+	})
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"from-path", "SELECT u.id FROM S3Object[*].users[*] u",
+			[]string{`{"id":1}`, `{"id":2}`}},
+		{"nested-path", "SELECT u.address.zip, u.tags[1] FROM S3Object[*].users[*] u WHERE u.id = 1",
+			[]string{`{"zip":"80202","_2":"b"}`}},
+		{"missing-omitted", "SELECT u.id, u.active FROM S3Object[*].users[*] u",
+			[]string{`{"id":1}`, `{"id":2,"active":false}`}},
+		{"is-false", "SELECT u.id FROM S3Object[*].users[*] u WHERE u.active IS FALSE",
+			[]string{`{"id":2}`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := run(t, tt.query, []Value{doc})
+			if err != nil {
+				t.Fatalf("query error: %v", err)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectErrors(t *testing.T) {
+	records := []Value{csvRecord([]string{"a", "b"}, "x", "0")}
+
+	tests := []struct {
+		name  string
+		query string
+		code  string
+	}{
+		{"missing-from", "SELECT a", ErrCodeParseSelectMissingFrom},
+		{"unexpected-token", "SELECT a FROM S3Object WHERE", ErrCodeParseExpectedExpression},
+		{"unterminated-string", "SELECT a FROM S3Object WHERE a = 'x", ErrCodeParseUnexpectedToken},
+		{"unknown-function", "SELECT FOO(a) FROM S3Object", ErrCodeUnsupportedFunction},
+		{"mixed-aggregate", "SELECT a, COUNT(*) FROM S3Object", ErrCodeInvalidAggregate},
+		{"aggregate-in-where", "SELECT a FROM S3Object WHERE COUNT(*) > 1", ErrCodeInvalidAggregate},
+		{"invalid-cast-type", "SELECT CAST(a AS BLOB) FROM S3Object", ErrCodeInvalidCast},
+		{"cast-failed", "SELECT CAST(a AS INT) FROM S3Object", ErrCodeCastFailed},
+		{"division-by-zero", "SELECT 1 / CAST(b AS INT) FROM S3Object", ErrCodeDivisionByZero},
+		{"column-index", "SELECT _0 FROM S3Object", ErrCodeInvalidColumnIndex},
+		{"bad-timestamp-pattern", "SELECT TO_STRING(UTCNOW(), 'yyyy-QQ') FROM S3Object", ErrCodeInvalidTimestampFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := run(t, tt.query, records)
+			var serr *Error
+			if !errors.As(err, &serr) {
+				t.Fatalf("expected select error, got %v", err)
+			}
+			if serr.Code != tt.code {
+				t.Errorf("got error code %v, want %v (%v)", serr.Code, tt.code, serr.Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package sql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timestampLayouts are the timestamp formats accepted by TO_TIMESTAMP
+// and casts, from the year only form to nanosecond precision
+var timestampLayouts = []string{
+	"2006T",
+	"2006-01T",
+	"2006-01-02",
+	"2006-01-02T",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02T15:04:05.999999999Z07:00",
+}
+
+func parseTimestamp(s string) (time.Time, error) {
+	for _, layout := range timestampLayouts {
+		t, err := time.Parse(layout, s)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q", s)
+}
+
+func formatTimestamp(t time.Time) string {
+	return t.Format(time.RFC3339Nano)
+}
+
+// datePart is a part of a timestamp used by the date functions
+type datePart string
+
+const (
+	partYear           datePart = "year"
+	partMonth          datePart = "month"
+	partDay            datePart = "day"
+	partHour           datePart = "hour"
+	partMinute         datePart = "minute"
+	partSecond         datePart = "second"
+	partTimezoneHour   datePart = "timezone_hour"
+	partTimezoneMinute datePart = "timezone_minute"
+)
+
+func parseDatePart(s string, extract bool) (datePart, error) {
+	part := datePart(strings.ToLower(s))
+	switch part {
+	case partYear, partMonth, partDay, partHour, partMinute, partSecond:
+		return part, nil
+	case partTimezoneHour, partTimezoneMinute:
+		if extract {
+			return part, nil
+		}
+	}
+	return "", newError(ErrCodeInvalidArguments, "invalid date part %v", s)
+}
+
+func dateAdd(part datePart, n int64, t time.Time) time.Time {
+	switch part {
+	case partYear:
+		return t.AddDate(int(n), 0, 0)
+	case partMonth:
+		return t.AddDate(0, int(n), 0)
+	case partDay:
+		return t.AddDate(0, 0, int(n))
+	case partHour:
+		return t.Add(time.Duration(n) * time.Hour)
+	case partMinute:
+		return t.Add(time.Duration(n) * time.Minute)
+	default:
+		return t.Add(time.Duration(n) * time.Second)
+	}
+}
+
+func dateDiff(part datePart, from, to time.Time) int64 {
+	switch part {
+	case partYear, partMonth:
+		months := int64(to.Year()-from.Year())*12 + int64(to.Month()-from.Month())
+		// only count the complete months
+		if months > 0 && from.AddDate(0, int(months), 0).After(to) {
+			months--
+		} else if months < 0 && from.AddDate(0, int(months), 0).Before(to) {
+			months++
+		}
+		if part == partYear {
+			return months / 12
+		}
+		return months
+	case partDay:
+		return int64(to.Sub(from) / (24 * time.Hour))
+	case partHour:
+		return int64(to.Sub(from) / time.Hour)
+	case partMinute:
+		return int64(to.Sub(from) / time.Minute)
+	default:
+		return int64(to.Sub(from) / time.Second)
+	}
+}
+
+func extract(part datePart, t time.Time) int64 {
+	switch part {
+	case partYear:
+		return int64(t.Year())
+	case partMonth:
+		return int64(t.Month())
+	case partDay:
+		return int64(t.Day())
+	case partHour:
+		return int64(t.Hour())
+	case partMinute:
+		return int64(t.Minute())
+	case partSecond:
+		return int64(t.Second())
+	}
+	_, offset := t.Zone()
+	if part == partTimezoneHour {
+		return int64(offset / 3600)
+	}
+	return int64(offset % 3600 / 60)
+}
+
+// formatTimestampPattern formats the timestamp with a TO_STRING
+// pattern, the pattern letters follow the java DateTimeFormatter ones
+func formatTimestampPattern(t time.Time, pattern string) (string, error) {
+	var sb strings.Builder
+	runes := []rune(pattern)
+
+	for i := 0; i < len(runes); {
+		c := runes[i]
+
+		if c == '\'' {
+			i++
+			for i < len(runes) {
+				if runes[i] == '\'' {
+					if i+1 < len(runes) && runes[i+1] == '\'' {
+						sb.WriteRune('\'')
+						i += 2
+						continue
+					}
+					break
+				}
+				sb.WriteRune(runes[i])
+				i++
+			}
+			if i >= len(runes) {
+				return "", newError(ErrCodeInvalidTimestampFormat, "unterminated quote in %q", pattern)
+			}
+			i++
+			continue
+		}
+
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+			sb.WriteRune(c)
+			i++
+			continue
+		}
+
+		n := 1
+		for i+n < len(runes) && runes[i+n] == c {
+			n++
+		}
+		i += n
+
+		s, err := formatPatternLetter(t, c, n)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(s)
+	}
+
+	return sb.String(), nil
+}
+
+func formatPatternLetter(t time.Time, c rune, n int) (string, error) {
+	switch c {
+	case 'y':
+		if n == 2 {
+			return pad(t.Year()%100, 2), nil
+		}
+		return pad(t.Year(), n), nil
+	case 'M':
+		switch {
+		case n == 3:
+			return t.Month().String()[:3], nil
+		case n == 4:
+			return t.Month().String(), nil
+		case n == 5:
+			return t.Month().String()[:1], nil
+		}
+		return pad(int(t.Month()), n), nil
+	case 'd':
+		return pad(t.Day(), n), nil
+	case 'a':
+		if t.Hour() < 12 {
+			return "AM", nil
+		}
+		return "PM", nil
+	case 'h':
+		h := t.Hour() % 12
+		if h == 0 {
+			h = 12
+		}
+		return pad(h, n), nil
+	case 'H':
+		return pad(t.Hour(), n), nil
+	case 'm':
+		return pad(t.Minute(), n), nil
+	case 's':
+		return pad(t.Second(), n), nil
+	case 'S':
+		frac := fmt.Sprintf("%09d", t.Nanosecond())
+		if n > 9 {
+			return frac + strings.Repeat("0", n-9), nil
+		}
+		return frac[:n], nil
+	case 'n':
+		return strconv.Itoa(t.Nanosecond()), nil
+	case 'X', 'x':
+		_, offset := t.Zone()
+		if c == 'X' && offset == 0 {
+			return "Z", nil
+		}
+		return formatOffset(offset, n), nil
+	}
+	return "", newError(ErrCodeInvalidTimestampFormat, "unsupported pattern letter %q", c)
+}
+
+func pad(v, n int) string {
+	s := strconv.Itoa(v)
+	if len(s) < n {
+		s = strings.Repeat("0", n-len(s)) + s
+	}
+	return s
+}
+
+func formatOffset(offset, n int) string {
+	sign := "+"
+	if offset < 0 {
+		sign = "-"
+		offset = -offset
+	}
+	hours, minutes := offset/3600, offset%3600/60
+	switch n {
+	case 1:
+		if minutes == 0 {
+			return sign + pad(hours, 2)
+		}
+		return sign + pad(hours, 2) + pad(minutes, 2)
+	case 2:
+		return sign + pad(hours, 2) + pad(minutes, 2)
+	default:
+		return sign + pad(hours, 2) + ":" + pad(minutes, 2)
+	}
+}
@@ -0,0 +1,297 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package sql
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kind is the type of a value
+type Kind int
+
+const (
+	KindMissing Kind = iota
+	KindNull
+	KindBool
+	KindInt
+	KindFloat
+	KindString
+	KindTimestamp
+	KindList
+	KindObject
+)
+
+// Field is a named value of an object
+type Field struct {
+	Name  string
+	Value Value
+}
+
+// Value is a value of the S3 Select data model. Objects keep the order
+// of their fields so that records are returned the way they were read.
+type Value struct {
+	kind   Kind
+	b      bool
+	i      int64
+	f      float64
+	s      string
+	t      time.Time
+	list   []Value
+	fields []Field
+}
+
+func Missing() Value                { return Value{kind: KindMissing} }
+func Null() Value                   { return Value{kind: KindNull} }
+func Bool(b bool) Value             { return Value{kind: KindBool, b: b} }
+func Int(i int64) Value             { return Value{kind: KindInt, i: i} }
+func Float(f float64) Value         { return Value{kind: KindFloat, f: f} }
+func String(s string) Value         { return Value{kind: KindString, s: s} }
+func Timestamp(t time.Time) Value   { return Value{kind: KindTimestamp, t: t} }
+func List(values []Value) Value     { return Value{kind: KindList, list: values} }
+func Object(fields []Field) Value   { return Value{kind: KindObject, fields: fields} }
+func (v Value) Kind() Kind          { return v.kind }
+func (v Value) IsMissing() bool     { return v.kind == KindMissing }
+func (v Value) IsNull() bool        { return v.kind == KindNull || v.kind == KindMissing }
+func (v Value) Bool() bool          { return v.b }
+func (v Value) Int() int64          { return v.i }
+func (v Value) Float() float64      { return v.f }
+func (v Value) Str() string         { return v.s }
+func (v Value) Time() time.Time     { return v.t }
+func (v Value) Elements() []Value   { return v.list }
+func (v Value) Fields() []Field     { return v.fields }
+func (v Value) isNumber() bool      { return v.kind == KindInt || v.kind == KindFloat }
+func (v Value) isText() bool        { return v.kind == KindString }
+func (v Value) isTimestamp() bool   { return v.kind == KindTimestamp }
+func (v Value) isCollection() bool  { return v.kind == KindList || v.kind == KindObject }
+func (k Kind) isScalar() bool       { return k >= KindBool && k <= KindTimestamp }
+func (v Value) floatValue() float64 { return toFloat(v) }
+
+// Field returns the value of the named field of an object. Names that
+// are not quoted in the query match case insensitively, and positional
+// names _1, _2... match the fields by position when no field has that
+// name.
+func (v Value) Field(name string, caseSensitive bool) Value {
+	if v.kind != KindObject {
+		return Missing()
+	}
+	for _, f := range v.fields {
+		if f.Name == name {
+			return f.Value
+		}
+	}
+	if !caseSensitive {
+		for _, f := range v.fields {
+			if strings.EqualFold(f.Name, name) {
+				return f.Value
+			}
+		}
+	}
+	if pos, ok := positionalIndex(name); ok && pos <= len(v.fields) {
+		return v.fields[pos-1].Value
+	}
+	return Missing()
+}
+
+// Index returns the element at position i of a list
+func (v Value) Index(i int) Value {
+	if v.kind != KindList || i < 0 || i >= len(v.list) {
+		return Missing()
+	}
+	return v.list[i]
+}
+
+func positionalIndex(name string) (int, bool) {
+	if len(name) < 2 || name[0] != '_' {
+		return 0, false
+	}
+	pos, err := strconv.Atoi(name[1:])
+	if err != nil || pos < 1 {
+		return 0, false
+	}
+	return pos, true
+}
+
+func toFloat(v Value) float64 {
+	if v.kind == KindInt {
+		return float64(v.i)
+	}
+	return v.f
+}
+
+// Text returns the text representation of scalar values, this is how
+// values are written to CSV output
+func (v Value) Text() string {
+	switch v.kind {
+	case KindMissing, KindNull:
+		return ""
+	case KindBool:
+		return strconv.FormatBool(v.b)
+	case KindInt:
+		return strconv.FormatInt(v.i, 10)
+	case KindFloat:
+		return formatFloat(v.f)
+	case KindString:
+		return v.s
+	case KindTimestamp:
+		return formatTimestamp(v.t)
+	default:
+		return string(v.AppendJSON(nil))
+	}
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	abs := math.Abs(f)
+	if abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// AppendJSON appends the JSON encoding of the value to b
+func (v Value) AppendJSON(b []byte) []byte {
+	switch v.kind {
+	case KindMissing, KindNull:
+		return append(b, "null"...)
+	case KindBool, KindInt:
+		return append(b, v.Text()...)
+	case KindFloat:
+		if math.IsInf(v.f, 0) || math.IsNaN(v.f) {
+			return appendJSONString(b, v.Text())
+		}
+		return append(b, v.Text()...)
+	case KindString, KindTimestamp:
+		return appendJSONString(b, v.Text())
+	case KindList:
+		b = append(b, '[')
+		for i, e := range v.list {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = e.AppendJSON(b)
+		}
+		return append(b, ']')
+	default:
+		b = append(b, '{')
+		first := true
+		for _, f := range v.fields {
+			if f.Value.IsMissing() {
+				continue
+			}
+			if !first {
+				b = append(b, ',')
+			}
+			first = false
+			b = appendJSONString(b, f.Name)
+			b = append(b, ':')
+			b = f.Value.AppendJSON(b)
+		}
+		return append(b, '}')
+	}
+}
+
+func appendJSONString(b []byte, s string) []byte {
+	enc, _ := json.Marshal(s)
+	return append(b, enc...)
+}
+
+// parseNumber parses the text as an integer or a float
+func parseNumber(s string) (Value, bool) {
+	s = strings.TrimSpace(s)
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return Int(i), true
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return Float(f), true
+	}
+	return Value{}, false
+}
+
+// asNumber returns the value as a number, text is converted when it
+// holds a number since CSV input only has text values
+func asNumber(v Value) (Value, bool) {
+	switch v.kind {
+	case KindInt, KindFloat:
+		return v, true
+	case KindString:
+		return parseNumber(v.s)
+	}
+	return Value{}, false
+}
+
+// compare compares a and b, ok is false when the values can not be
+// compared
+func compare(a, b Value) (c int, ok bool) {
+	switch {
+	case a.kind == KindString && b.kind == KindString:
+		return strings.Compare(a.s, b.s), true
+	case a.kind == KindBool && b.kind == KindBool:
+		switch {
+		case a.b == b.b:
+			return 0, true
+		case !a.b:
+			return -1, true
+		default:
+			return 1, true
+		}
+	case a.isTimestamp() || b.isTimestamp():
+		ta, oka := asTimestamp(a)
+		tb, okb := asTimestamp(b)
+		if !oka || !okb {
+			return 0, false
+		}
+		return ta.Compare(tb), true
+	}
+
+	na, oka := asNumber(a)
+	nb, okb := asNumber(b)
+	if !oka || !okb {
+		return 0, false
+	}
+	if na.kind == KindInt && nb.kind == KindInt {
+		switch {
+		case na.i < nb.i:
+			return -1, true
+		case na.i > nb.i:
+			return 1, true
+		}
+		return 0, true
+	}
+	fa, fb := toFloat(na), toFloat(nb)
+	switch {
+	case fa < fb:
+		return -1, true
+	case fa > fb:
+		return 1, true
+	}
+	return 0, true
+}
+
+func asTimestamp(v Value) (time.Time, bool) {
+	switch v.kind {
+	case KindTimestamp:
+		return v.t, true
+	case KindString:
+		t, err := parseTimestamp(v.s)
+		return t, err == nil
+	}
+	return time.Time{}, false
+}
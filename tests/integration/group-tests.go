@@ -263,6 +263,22 @@ func TestPutBucketPolicy(s *S3Conf) {
 	PutBucketPolicy_object_action_on_bucket_resource(s)
 	PutBucketPolicy_bucket_action_on_object_resource(s)
 	PutBucketPolicy_success(s)
+	PutBucketPolicy_invalid_condition_operator(s)
+	PutBucketPolicy_unsupported_condition_key(s)
+	PutBucketPolicy_invalid_condition_value(s)
+	PutBucketPolicy_condition_prefix(s)
+	PutBucketPolicy_condition_source_ip(s)
+	PutBucketPolicy_principal_and_not_principal(s)
+	PutBucketPolicy_missing_action(s)
+	PutBucketPolicy_explicit_deny(s)
+	PutBucketPolicy_not_principal(s)
+	PutBucketPolicy_not_action(s)
+	PutBucketPolicy_not_resource(s)
+	PublicAccess_private_bucket(s)
+	PublicAccess_canned_acl(s)
+	PublicAccess_all_users_grant(s)
+	PublicAccess_bucket_policy(s)
+	PublicAccess_public_read_write_bucket_config(s)
 }
 
 func TestGetBucketPolicy(s *S3Conf) {
@@ -316,6 +332,70 @@ func TestPosix(s *S3Conf) {
 	PutObject_overwrite_file_obj(s)
 	PutObject_dir_obj_with_data(s)
 	CreateMultipartUpload_dir_obj(s)
+	PutBucketVersioning_non_existing_bucket(s)
+	PutBucketVersioning_invalid_status(s)
+	GetBucketVersioning_success(s)
+	Versioning_PutObject_GetObject_by_version(s)
+	Versioning_DeleteObject_delete_marker(s)
+	ListObjectVersions_success(s)
+	ListObjectVersions_paginated_nested(s)
+	Versioning_CompleteMultipartUpload_version_id(s)
+	PutObjectLockConfiguration_non_existing_bucket(s)
+	GetObjectLockConfiguration_not_found(s)
+	PutObjectLockConfiguration_success(s)
+	PutObjectRetention_missing_lock_configuration(s)
+	ObjectLock_governance_retention_delete(s)
+	ObjectLock_legal_hold_delete(s)
+	ObjectLock_multipart_upload_retention(s)
+	ObjectLock_bypass_requires_permission(s)
+	PutBucketLifecycleConfiguration_non_existing_bucket(s)
+	PutBucketLifecycleConfiguration_invalid_expiration(s)
+	GetBucketLifecycleConfiguration_not_found(s)
+	PutBucketLifecycleConfiguration_success(s)
+	PutBucketCors_invalid_method(s)
+	GetBucketCors_not_found(s)
+	PutBucketCors_success(s)
+	CORS_preflight_request(s)
+	PutBucketWebsite_missing_index_document(s)
+	GetBucketWebsite_not_found(s)
+	PutBucketWebsite_success(s)
+	SelectObjectContent_csv_success(s)
+	SelectObjectContent_invalid_expression(s)
+	PostObject_success(s)
+	PostObject_policy_condition_failed(s)
+	PostObject_entity_too_large(s)
+	PostObject_invalid_signature(s)
+	PutObject_checksum_success(s)
+	PutObject_checksum_mismatch(s)
+	CompleteMultipartUpload_checksum_success(s)
+	UploadPart_checksum_algorithm_mismatch(s)
+	PutObject_trailing_checksum_success(s)
+	PutObject_trailing_checksum_mismatch(s)
+	SigV4a_put_get_object_success(s)
+	SigV4a_region_set_mismatch(s)
+	SigV4a_signature_mismatch(s)
+	SigV4a_presigned_get_object_success(s)
+	STS_GetSessionToken_success(s)
+	STS_AssumeRole_session_policy(s)
+	STS_AssumeRole_malformed_policy(s)
+	STS_GetSessionToken_invalid_duration(s)
+	STS_GetSessionToken_with_session_credentials(s)
+	STS_GetSessionToken_presigned_session_credentials(s)
+	STS_invalid_session_token(s)
+	STS_session_credentials_presigned(s)
+	STS_AssumeRoleWithWebIdentity_invalid_token(s)
+	IdentityPolicy_read_only_grant(s)
+	IdentityPolicy_explicit_deny(s)
+	IdentityPolicy_bucket_policy_deny(s)
+	IdentityPolicy_delete_policy(s)
+	IdentityPolicy_invalid_policy(s)
+	SSE_C_PutObject_GetObject_success(s)
+	SSE_C_GetObject_missing_key(s)
+	SSE_C_PutObject_invalid_key(s)
+	SSE_C_CompleteMultipartUpload_success(s)
+	SSE_C_UploadPart_unencrypted_upload(s)
+	SSE_S3_PutObject_GetObject_success(s)
+	SSE_S3_UploadPart_reupload_success(s)
 }
 
 func TestIAM(s *S3Conf) {
@@ -325,6 +405,57 @@ func TestIAM(s *S3Conf) {
 	IAM_admin_ChangeBucketOwner(s)
 }
 
+// TestSigV2 runs against a gateway started with --sigv2
+func TestSigV2(s *S3Conf) {
+	SigV2_put_get_object_success(s)
+	SigV2_sub_resource_success(s)
+	SigV2_signature_mismatch(s)
+	SigV2_invalid_auth_header(s)
+	SigV2_invalid_access_key(s)
+	SigV2_expired_date(s)
+	SigV2_presigned_get_object_success(s)
+	SigV2_presigned_expired(s)
+}
+
+// TestHostStyle runs against a gateway started with --virtual-domain
+func TestHostStyle(s *S3Conf) {
+	HostStyle_put_get_object_success(s)
+	HostStyle_list_objects_success(s)
+	HostStyle_list_buckets_success(s)
+	HostStyle_presigned_get_object_success(s)
+}
+
+// TestOIDC runs against a gateway started with --oidc-issuer,
+// --oidc-audience, --oidc-account-claim sub and an --oidc-jwks key set
+// of the test oidc private key
+func TestOIDC(s *S3Conf) {
+	OIDC_AssumeRoleWithWebIdentity_success(s)
+	OIDC_identity_without_account_access_denied(s)
+	OIDC_identity_mapped_to_account(s)
+	OIDC_root_account_denied(s)
+	OIDC_invalid_signature(s)
+	OIDC_invalid_issuer(s)
+	OIDC_invalid_audience(s)
+	OIDC_expired_token(s)
+}
+
+// TestQuota runs against a gateway started with --quota-dir
+func TestQuota(s *S3Conf) {
+	Quota_bucket_bytes_exceeded(s)
+	Quota_bucket_objects_exceeded(s)
+	Quota_multipart_upload_exceeded(s)
+	Quota_account_exceeded(s)
+	Quota_invalid_target(s)
+}
+
+// TestRateLimit runs against a gateway started with --rate-limit-dir and
+// without default account limits
+func TestRateLimit(s *S3Conf) {
+	RateLimit_account_requests_slow_down(s)
+	RateLimit_account_bandwidth_throttled(s)
+	RateLimit_invalid_account(s)
+}
+
 type IntTests map[string]func(s *S3Conf) error
 
 func GetIntTests() IntTests {
@@ -503,6 +634,22 @@ func GetIntTests() IntTests {
 		"PutBucketPolicy_object_action_on_bucket_resource":      PutBucketPolicy_object_action_on_bucket_resource,
 		"PutBucketPolicy_bucket_action_on_object_resource":      PutBucketPolicy_bucket_action_on_object_resource,
 		"PutBucketPolicy_success":                               PutBucketPolicy_success,
+		"PutBucketPolicy_invalid_condition_operator":            PutBucketPolicy_invalid_condition_operator,
+		"PutBucketPolicy_unsupported_condition_key":             PutBucketPolicy_unsupported_condition_key,
+		"PutBucketPolicy_invalid_condition_value":               PutBucketPolicy_invalid_condition_value,
+		"PutBucketPolicy_condition_prefix":                      PutBucketPolicy_condition_prefix,
+		"PutBucketPolicy_condition_source_ip":                   PutBucketPolicy_condition_source_ip,
+		"PutBucketPolicy_principal_and_not_principal":           PutBucketPolicy_principal_and_not_principal,
+		"PutBucketPolicy_missing_action":                        PutBucketPolicy_missing_action,
+		"PutBucketPolicy_explicit_deny":                         PutBucketPolicy_explicit_deny,
+		"PutBucketPolicy_not_principal":                         PutBucketPolicy_not_principal,
+		"PutBucketPolicy_not_action":                            PutBucketPolicy_not_action,
+		"PutBucketPolicy_not_resource":                          PutBucketPolicy_not_resource,
+		"PublicAccess_private_bucket":                           PublicAccess_private_bucket,
+		"PublicAccess_canned_acl":                               PublicAccess_canned_acl,
+		"PublicAccess_all_users_grant":                          PublicAccess_all_users_grant,
+		"PublicAccess_bucket_policy":                            PublicAccess_bucket_policy,
+		"PublicAccess_public_read_write_bucket_config":          PublicAccess_public_read_write_bucket_config,
 		"GetBucketPolicy_non_existing_bucket":                   GetBucketPolicy_non_existing_bucket,
 		"GetBucketPolicy_default_empty_policy":                  GetBucketPolicy_default_empty_policy,
 		"GetBucketPolicy_success":                               GetBucketPolicy_success,
@@ -513,6 +660,98 @@ func GetIntTests() IntTests {
 		"PutObject_overwrite_file_obj":                          PutObject_overwrite_file_obj,
 		"PutObject_dir_obj_with_data":                           PutObject_dir_obj_with_data,
 		"CreateMultipartUpload_dir_obj":                         CreateMultipartUpload_dir_obj,
+		"PutBucketVersioning_non_existing_bucket":               PutBucketVersioning_non_existing_bucket,
+		"PutBucketVersioning_invalid_status":                    PutBucketVersioning_invalid_status,
+		"GetBucketVersioning_success":                           GetBucketVersioning_success,
+		"Versioning_PutObject_GetObject_by_version":             Versioning_PutObject_GetObject_by_version,
+		"Versioning_DeleteObject_delete_marker":                 Versioning_DeleteObject_delete_marker,
+		"ListObjectVersions_success":                            ListObjectVersions_success,
+		"ListObjectVersions_paginated_nested":                   ListObjectVersions_paginated_nested,
+		"Versioning_CompleteMultipartUpload_version_id":         Versioning_CompleteMultipartUpload_version_id,
+		"PutObjectLockConfiguration_non_existing_bucket":        PutObjectLockConfiguration_non_existing_bucket,
+		"GetObjectLockConfiguration_not_found":                  GetObjectLockConfiguration_not_found,
+		"PutObjectLockConfiguration_success":                    PutObjectLockConfiguration_success,
+		"PutObjectRetention_missing_lock_configuration":         PutObjectRetention_missing_lock_configuration,
+		"ObjectLock_governance_retention_delete":                ObjectLock_governance_retention_delete,
+		"ObjectLock_legal_hold_delete":                          ObjectLock_legal_hold_delete,
+		"ObjectLock_multipart_upload_retention":                 ObjectLock_multipart_upload_retention,
+		"ObjectLock_bypass_requires_permission":                 ObjectLock_bypass_requires_permission,
+		"PutBucketLifecycleConfiguration_non_existing_bucket":   PutBucketLifecycleConfiguration_non_existing_bucket,
+		"PutBucketLifecycleConfiguration_invalid_expiration":    PutBucketLifecycleConfiguration_invalid_expiration,
+		"GetBucketLifecycleConfiguration_not_found":             GetBucketLifecycleConfiguration_not_found,
+		"PutBucketLifecycleConfiguration_success":               PutBucketLifecycleConfiguration_success,
+		"PutBucketCors_invalid_method":                          PutBucketCors_invalid_method,
+		"GetBucketCors_not_found":                               GetBucketCors_not_found,
+		"PutBucketCors_success":                                 PutBucketCors_success,
+		"CORS_preflight_request":                                CORS_preflight_request,
+		"PutBucketWebsite_missing_index_document":               PutBucketWebsite_missing_index_document,
+		"GetBucketWebsite_not_found":                            GetBucketWebsite_not_found,
+		"PutBucketWebsite_success":                              PutBucketWebsite_success,
+		"SelectObjectContent_csv_success":                       SelectObjectContent_csv_success,
+		"SelectObjectContent_invalid_expression":                SelectObjectContent_invalid_expression,
+		"PostObject_success":                                    PostObject_success,
+		"PostObject_policy_condition_failed":                    PostObject_policy_condition_failed,
+		"PostObject_entity_too_large":                           PostObject_entity_too_large,
+		"PostObject_invalid_signature":                          PostObject_invalid_signature,
+		"PutObject_checksum_success":                            PutObject_checksum_success,
+		"PutObject_checksum_mismatch":                           PutObject_checksum_mismatch,
+		"CompleteMultipartUpload_checksum_success":              CompleteMultipartUpload_checksum_success,
+		"UploadPart_checksum_algorithm_mismatch":                UploadPart_checksum_algorithm_mismatch,
+		"PutObject_trailing_checksum_success":                   PutObject_trailing_checksum_success,
+		"PutObject_trailing_checksum_mismatch":                  PutObject_trailing_checksum_mismatch,
+		"SigV4a_put_get_object_success":                         SigV4a_put_get_object_success,
+		"SigV4a_region_set_mismatch":                            SigV4a_region_set_mismatch,
+		"SigV4a_signature_mismatch":                             SigV4a_signature_mismatch,
+		"SigV4a_presigned_get_object_success":                   SigV4a_presigned_get_object_success,
+		"STS_GetSessionToken_success":                           STS_GetSessionToken_success,
+		"STS_AssumeRole_session_policy":                         STS_AssumeRole_session_policy,
+		"STS_AssumeRole_malformed_policy":                       STS_AssumeRole_malformed_policy,
+		"STS_GetSessionToken_invalid_duration":                  STS_GetSessionToken_invalid_duration,
+		"STS_GetSessionToken_with_session_credentials":          STS_GetSessionToken_with_session_credentials,
+		"STS_GetSessionToken_presigned_session_credentials":     STS_GetSessionToken_presigned_session_credentials,
+		"STS_invalid_session_token":                             STS_invalid_session_token,
+		"STS_session_credentials_presigned":                     STS_session_credentials_presigned,
+		"STS_AssumeRoleWithWebIdentity_invalid_token":           STS_AssumeRoleWithWebIdentity_invalid_token,
+		"IdentityPolicy_read_only_grant":                        IdentityPolicy_read_only_grant,
+		"IdentityPolicy_explicit_deny":                          IdentityPolicy_explicit_deny,
+		"IdentityPolicy_bucket_policy_deny":                     IdentityPolicy_bucket_policy_deny,
+		"IdentityPolicy_delete_policy":                          IdentityPolicy_delete_policy,
+		"IdentityPolicy_invalid_policy":                         IdentityPolicy_invalid_policy,
+		"SigV2_put_get_object_success":                          SigV2_put_get_object_success,
+		"SigV2_sub_resource_success":                            SigV2_sub_resource_success,
+		"SigV2_signature_mismatch":                              SigV2_signature_mismatch,
+		"SigV2_invalid_auth_header":                             SigV2_invalid_auth_header,
+		"SigV2_invalid_access_key":                              SigV2_invalid_access_key,
+		"SigV2_expired_date":                                    SigV2_expired_date,
+		"SigV2_presigned_get_object_success":                    SigV2_presigned_get_object_success,
+		"SigV2_presigned_expired":                               SigV2_presigned_expired,
+		"HostStyle_put_get_object_success":                      HostStyle_put_get_object_success,
+		"HostStyle_list_objects_success":                        HostStyle_list_objects_success,
+		"HostStyle_list_buckets_success":                        HostStyle_list_buckets_success,
+		"HostStyle_presigned_get_object_success":                HostStyle_presigned_get_object_success,
+		"OIDC_AssumeRoleWithWebIdentity_success":                OIDC_AssumeRoleWithWebIdentity_success,
+		"OIDC_identity_without_account_access_denied":           OIDC_identity_without_account_access_denied,
+		"OIDC_identity_mapped_to_account":                       OIDC_identity_mapped_to_account,
+		"OIDC_root_account_denied":                              OIDC_root_account_denied,
+		"OIDC_invalid_signature":                                OIDC_invalid_signature,
+		"OIDC_invalid_issuer":                                   OIDC_invalid_issuer,
+		"OIDC_invalid_audience":                                 OIDC_invalid_audience,
+		"OIDC_expired_token":                                    OIDC_expired_token,
+		"Quota_bucket_bytes_exceeded":                           Quota_bucket_bytes_exceeded,
+		"Quota_bucket_objects_exceeded":                         Quota_bucket_objects_exceeded,
+		"Quota_multipart_upload_exceeded":                       Quota_multipart_upload_exceeded,
+		"Quota_account_exceeded":                                Quota_account_exceeded,
+		"Quota_invalid_target":                                  Quota_invalid_target,
+		"RateLimit_account_requests_slow_down":                  RateLimit_account_requests_slow_down,
+		"RateLimit_account_bandwidth_throttled":                 RateLimit_account_bandwidth_throttled,
+		"RateLimit_invalid_account":                             RateLimit_invalid_account,
+		"SSE_C_PutObject_GetObject_success":                     SSE_C_PutObject_GetObject_success,
+		"SSE_C_GetObject_missing_key":                           SSE_C_GetObject_missing_key,
+		"SSE_C_PutObject_invalid_key":                           SSE_C_PutObject_invalid_key,
+		"SSE_C_CompleteMultipartUpload_success":                 SSE_C_CompleteMultipartUpload_success,
+		"SSE_C_UploadPart_unencrypted_upload":                   SSE_C_UploadPart_unencrypted_upload,
+		"SSE_S3_PutObject_GetObject_success":                    SSE_S3_PutObject_GetObject_success,
+		"SSE_S3_UploadPart_reupload_success":                    SSE_S3_UploadPart_reupload_success,
 		"IAM_user_access_denied":                                IAM_user_access_denied,
 		"IAM_userplus_access_denied":                            IAM_userplus_access_denied,
 		"IAM_userplus_CreateBucket":                             IAM_userplus_CreateBucket,
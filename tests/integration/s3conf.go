@@ -23,6 +23,10 @@ type S3Conf struct {
 	endpoint        string
 	checksumDisable bool
 	pathStyle       bool
+	virtualDomain   string
+	oidcIssuer      string
+	oidcAudience    string
+	oidcKeyFile     string
 	PartSize        int64
 	Concurrency     int
 	debug           bool
@@ -57,6 +61,16 @@ func WithDisableChecksum() Option {
 func WithPathStyle() Option {
 	return func(s *S3Conf) { s.pathStyle = true }
 }
+func WithVirtualDomain(d string) Option {
+	return func(s *S3Conf) { s.virtualDomain = d }
+}
+func WithOIDC(issuer, audience, keyFile string) Option {
+	return func(s *S3Conf) {
+		s.oidcIssuer = issuer
+		s.oidcAudience = audience
+		s.oidcKeyFile = keyFile
+	}
+}
 func WithPartSize(p int64) Option {
 	return func(s *S3Conf) { s.PartSize = p }
 }
@@ -1,11 +1,16 @@
 package integration
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"net/http"
 	"net/url"
@@ -13,8 +18,14 @@ import (
 	"strings"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/versity/versitygw/s3err"
 	"github.com/versity/versitygw/s3response"
 )
@@ -28,12 +39,13 @@ func Authentication_empty_auth_header(s *S3Conf) error {
 	testName := "Authentication_empty_auth_header"
 	return authHandler(s, &authConfig{
 		testName: testName,
-		path:     "my-bucket",
+		path:     "",
 		method:   http.MethodGet,
 		body:     nil,
 		service:  "s3",
 		date:     time.Now(),
 	}, func(req *http.Request) error {
+		// unsigned requests are anonymous, which can't list buckets
 		req.Header.Set("Authorization", "")
 		client := http.Client{
 			Timeout: shortTimeout,
@@ -44,7 +56,7 @@ func Authentication_empty_auth_header(s *S3Conf) error {
 			return err
 		}
 		defer resp.Body.Close()
-		if err := checkAuthErr(resp, s3err.GetAPIError(s3err.ErrAuthHeaderEmpty)); err != nil {
+		if err := checkAuthErr(resp, s3err.GetAPIError(s3err.ErrAccessDenied)); err != nil {
 			return err
 		}
 
@@ -5345,7 +5357,7 @@ func PutBucketPolicy_invalid_action(s *S3Conf) error {
 func PutBucketPolicy_unsupported_action(s *S3Conf) error {
 	testName := "PutBucketPolicy_unsupported_action"
 	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
-		doc := genPolicyDoc("Allow", `"*"`, `"s3:PutLifecycleConfiguration"`, `"arn:aws:s3:::*"`)
+		doc := genPolicyDoc("Allow", `"*"`, `"s3:PutBucketNotification"`, `"arn:aws:s3:::*"`)
 
 		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
 		_, err := s3client.PutBucketPolicy(ctx, &s3.PutBucketPolicyInput{
@@ -5354,7 +5366,7 @@ func PutBucketPolicy_unsupported_action(s *S3Conf) error {
 		})
 		cancel()
 
-		if err := checkApiErr(err, getMalformedPolicyError("unsupported action: s3:PutLifecycleConfiguration")); err != nil {
+		if err := checkApiErr(err, getMalformedPolicyError("unsupported action: s3:PutBucketNotification")); err != nil {
 			return err
 		}
 		return nil
@@ -5658,48 +5670,84 @@ func PutBucketPolicy_success(s *S3Conf) error {
 	})
 }
 
-func GetBucketPolicy_non_existing_bucket(s *S3Conf) error {
-	testName := "GetBucketPolicy_non_existing_bucket"
+func PutBucketPolicy_invalid_condition_operator(s *S3Conf) error {
+	testName := "PutBucketPolicy_invalid_condition_operator"
 	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		resource := fmt.Sprintf(`"arn:aws:s3:::%v"`, bucket)
+		doc := genPolicyDocWithCondition("Allow", `"*"`, `"s3:ListBucket"`, resource, `{"StringMatches": {"s3:prefix": "public/"}}`)
+
 		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
-		_, err := s3client.GetBucketPolicy(ctx, &s3.GetBucketPolicyInput{
-			Bucket: getPtr("non_existing_bucket"),
+		_, err := s3client.PutBucketPolicy(ctx, &s3.PutBucketPolicyInput{
+			Bucket: &bucket,
+			Policy: &doc,
 		})
 		cancel()
 
-		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrNoSuchBucket)); err != nil {
+		if err := checkApiErr(err, getMalformedPolicyError("invalid condition operator: StringMatches")); err != nil {
 			return err
 		}
 		return nil
 	})
 }
 
-func GetBucketPolicy_default_empty_policy(s *S3Conf) error {
-	testName := "GetBucketPolicy_default_empty_policy"
+func PutBucketPolicy_unsupported_condition_key(s *S3Conf) error {
+	testName := "PutBucketPolicy_unsupported_condition_key"
 	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		resource := fmt.Sprintf(`"arn:aws:s3:::%v"`, bucket)
+		doc := genPolicyDocWithCondition("Allow", `"*"`, `"s3:ListBucket"`, resource, `{"StringEquals": {"s3:invalid-key": "value"}}`)
+
 		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
-		out, err := s3client.GetBucketPolicy(ctx, &s3.GetBucketPolicyInput{
+		_, err := s3client.PutBucketPolicy(ctx, &s3.PutBucketPolicyInput{
 			Bucket: &bucket,
+			Policy: &doc,
 		})
 		cancel()
-		if err != nil {
+
+		if err := checkApiErr(err, getMalformedPolicyError("unsupported condition key: s3:invalid-key")); err != nil {
 			return err
 		}
+		return nil
+	})
+}
 
-		if out.Policy != nil {
-			return fmt.Errorf("expected policy to be nil, instead got %s", *out.Policy)
-		}
+func PutBucketPolicy_invalid_condition_value(s *S3Conf) error {
+	testName := "PutBucketPolicy_invalid_condition_value"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		resource := fmt.Sprintf(`"arn:aws:s3:::%v"`, bucket)
+		doc := genPolicyDocWithCondition("Allow", `"*"`, `"s3:ListBucket"`, resource, `{"IpAddress": {"aws:SourceIp": "invalid_ip"}}`)
+
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err := s3client.PutBucketPolicy(ctx, &s3.PutBucketPolicyInput{
+			Bucket: &bucket,
+			Policy: &doc,
+		})
+		cancel()
 
+		if err := checkApiErr(err, getMalformedPolicyError("invalid IpAddress value for aws:sourceip: invalid ip address: invalid_ip")); err != nil {
+			return err
+		}
 		return nil
 	})
 }
 
-func GetBucketPolicy_success(s *S3Conf) error {
-	testName := "GetBucketPolicy_success"
+func PutBucketPolicy_condition_prefix(s *S3Conf) error {
+	testName := "PutBucketPolicy_condition_prefix"
 	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
-		doc := genPolicyDoc("Allow", `"*"`, `["s3:DeleteBucket", "s3:GetBucketTagging"]`, fmt.Sprintf(`"arn:aws:s3:::%v"`, bucket))
+		err := createUsers(s, []user{{"grt1", "grt1secret", "user"}})
+		if err != nil {
+			return err
+		}
+
+		err = putBucketAclGrants(s3client, bucket, s.awsID, types.PermissionRead, "grt1")
+		if err != nil {
+			return err
+		}
+
+		resource := fmt.Sprintf(`"arn:aws:s3:::%v"`, bucket)
+		doc := genPolicyDocWithCondition("Allow", `"grt1"`, `"s3:ListBucket"`, resource, `{"StringLike": {"s3:prefix": "public/*"}}`)
+
 		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
-		_, err := s3client.PutBucketPolicy(ctx, &s3.PutBucketPolicyInput{
+		_, err = s3client.PutBucketPolicy(ctx, &s3.PutBucketPolicyInput{
 			Bucket: &bucket,
 			Policy: &doc,
 		})
@@ -5707,296 +5755,5039 @@ func GetBucketPolicy_success(s *S3Conf) error {
 		if err != nil {
 			return err
 		}
+
+		newConf := *s
+		newConf.awsID = "grt1"
+		newConf.awsSecret = "grt1secret"
+		userClient := s3.NewFromConfig(newConf.Config())
+
 		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
-		out, err := s3client.GetBucketPolicy(ctx, &s3.GetBucketPolicyInput{
+		_, err = userClient.ListObjects(ctx, &s3.ListObjectsInput{
 			Bucket: &bucket,
+			Prefix: getPtr("public/data/"),
 		})
 		cancel()
 		if err != nil {
 			return err
 		}
 
-		if out.Policy == nil {
-			return fmt.Errorf("expected non nil policy result")
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = userClient.ListObjects(ctx, &s3.ListObjectsInput{
+			Bucket: &bucket,
+			Prefix: getPtr("private/"),
+		})
+		cancel()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrAccessDenied)); err != nil {
+			return err
 		}
 
-		if *out.Policy != doc {
-			return fmt.Errorf("expected the bucket policy to be %v, instead got %v", doc, *out.Policy)
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = userClient.ListObjects(ctx, &s3.ListObjectsInput{
+			Bucket: &bucket,
+		})
+		cancel()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrAccessDenied)); err != nil {
+			return err
 		}
 
 		return nil
 	})
 }
 
-func DeleteBucketPolicy_non_existing_bucket(s *S3Conf) error {
-	testName := "DeleteBucketPolicy_non_existing_bucket"
+func PutBucketPolicy_condition_source_ip(s *S3Conf) error {
+	testName := "PutBucketPolicy_condition_source_ip"
 	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
-		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
-		_, err := s3client.DeleteBucketPolicy(ctx, &s3.DeleteBucketPolicyInput{
-			Bucket: getPtr("non_existing_bucket"),
-		})
-		cancel()
+		err := createUsers(s, []user{{"grt1", "grt1secret", "user"}})
+		if err != nil {
+			return err
+		}
 
-		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrNoSuchBucket)); err != nil {
+		err = putBucketAclGrants(s3client, bucket, s.awsID, types.PermissionRead, "grt1")
+		if err != nil {
 			return err
 		}
+
+		newConf := *s
+		newConf.awsID = "grt1"
+		newConf.awsSecret = "grt1secret"
+		userClient := s3.NewFromConfig(newConf.Config())
+
+		resource := fmt.Sprintf(`"arn:aws:s3:::%v"`, bucket)
+		for _, test := range []struct {
+			condition string
+			allowed   bool
+		}{
+			// 192.0.2.0/24 is reserved for documentation
+			{`{"IpAddress": {"aws:SourceIp": "192.0.2.0/24"}}`, false},
+			{`{"NotIpAddress": {"aws:SourceIp": ["192.0.2.0/24", "198.51.100.7"]}}`, true},
+		} {
+			doc := genPolicyDocWithCondition("Allow", `"grt1"`, `"s3:ListBucket"`, resource, test.condition)
+			ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+			_, err = s3client.PutBucketPolicy(ctx, &s3.PutBucketPolicyInput{
+				Bucket: &bucket,
+				Policy: &doc,
+			})
+			cancel()
+			if err != nil {
+				return err
+			}
+
+			ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+			_, err = userClient.ListObjects(ctx, &s3.ListObjectsInput{
+				Bucket: &bucket,
+			})
+			cancel()
+			if test.allowed {
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrAccessDenied)); err != nil {
+				return err
+			}
+		}
+
 		return nil
 	})
 }
 
-func DeleteBucketPolicy_remove_before_setting(s *S3Conf) error {
-	testName := "DeleteBucketPolicy_remove_before_setting"
+func PutBucketPolicy_principal_and_not_principal(s *S3Conf) error {
+	testName := "PutBucketPolicy_principal_and_not_principal"
 	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		doc := fmt.Sprintf(`{
+			"Statement": [
+				{
+					"Effect": "Allow",
+					"Principal": "*",
+					"NotPrincipal": "*",
+					"Action": "s3:ListBucket",
+					"Resource": "arn:aws:s3:::%v"
+				}
+			]
+		}`, bucket)
+
 		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
-		_, err := s3client.DeleteBucketPolicy(ctx, &s3.DeleteBucketPolicyInput{
+		_, err := s3client.PutBucketPolicy(ctx, &s3.PutBucketPolicyInput{
 			Bucket: &bucket,
+			Policy: &doc,
 		})
 		cancel()
 
-		return err
+		if err := checkApiErr(err, getMalformedPolicyError("only one of Principal and NotPrincipal can be specified")); err != nil {
+			return err
+		}
+		return nil
 	})
 }
 
-func DeleteBucketPolicy_success(s *S3Conf) error {
-	testName := "DeleteBucketPolicy_success"
+func PutBucketPolicy_missing_action(s *S3Conf) error {
+	testName := "PutBucketPolicy_missing_action"
 	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
-		doc := genPolicyDoc("Allow", `"*"`, `["s3:DeleteBucket", "s3:GetBucketTagging"]`, fmt.Sprintf(`"arn:aws:s3:::%v"`, bucket))
+		doc := fmt.Sprintf(`{
+			"Statement": [
+				{
+					"Effect": "Allow",
+					"Principal": "*",
+					"Resource": "arn:aws:s3:::%v"
+				}
+			]
+		}`, bucket)
+
 		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
 		_, err := s3client.PutBucketPolicy(ctx, &s3.PutBucketPolicyInput{
 			Bucket: &bucket,
 			Policy: &doc,
 		})
 		cancel()
-		if err != nil {
+
+		if err := checkApiErr(err, getMalformedPolicyError("missing Action or NotAction")); err != nil {
 			return err
 		}
+		return nil
+	})
+}
 
-		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
-		_, err = s3client.DeleteBucketPolicy(ctx, &s3.DeleteBucketPolicyInput{
-			Bucket: &bucket,
+func PutBucketPolicy_explicit_deny(s *S3Conf) error {
+	testName := "PutBucketPolicy_explicit_deny"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		err := createUsers(s, []user{
+			{"grt1", "grt1secret", "user"},
+			{"grt2", "grt2secret", "user"},
 		})
-		cancel()
 		if err != nil {
 			return err
 		}
 
-		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
-		out, err := s3client.GetBucketPolicy(ctx, &s3.GetBucketPolicyInput{
+		err = putBucketAclGrants(s3client, bucket, s.awsID, types.PermissionRead, "grt1", "grt2")
+		if err != nil {
+			return err
+		}
+
+		// the deny statement takes precedence regardless of the order
+		doc := fmt.Sprintf(`{
+			"Statement": [
+				{
+					"Effect": "Deny",
+					"Principal": "grt1",
+					"Action": "s3:ListBucket",
+					"Resource": "arn:aws:s3:::%[1]v"
+				},
+				{
+					"Effect": "Allow",
+					"Principal": "*",
+					"Action": "s3:ListBucket",
+					"Resource": "arn:aws:s3:::%[1]v"
+				}
+			]
+		}`, bucket)
+
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.PutBucketPolicy(ctx, &s3.PutBucketPolicyInput{
 			Bucket: &bucket,
+			Policy: &doc,
 		})
 		cancel()
 		if err != nil {
 			return err
 		}
 
-		if out.Policy != nil {
-			return fmt.Errorf("expected policy to be nil, instead got %s", *out.Policy)
+		for _, usr := range []struct {
+			access  string
+			secret  string
+			allowed bool
+		}{
+			{"grt1", "grt1secret", false},
+			{"grt2", "grt2secret", true},
+		} {
+			newConf := *s
+			newConf.awsID = usr.access
+			newConf.awsSecret = usr.secret
+			userClient := s3.NewFromConfig(newConf.Config())
+
+			ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+			_, err = userClient.ListObjects(ctx, &s3.ListObjectsInput{
+				Bucket: &bucket,
+			})
+			cancel()
+			if usr.allowed {
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrAccessDenied)); err != nil {
+				return err
+			}
 		}
 
-		return err
+		return nil
 	})
 }
 
-// IAM related tests
-// multi-user iam tests
-func IAM_user_access_denied(s *S3Conf) error {
-	testName := "IAM_user_access_denied"
-	runF(testName)
-
-	usr := user{
-		access: "grt1",
-		secret: "grt1secret",
-		role:   "user",
-	}
-	err := deleteUser(s, usr.access)
-	if err != nil {
-		failF("%v: %v", testName, err)
-		return fmt.Errorf("%v: %w", testName, err)
-	}
-
-	err = createUsers(s, []user{usr})
-	if err != nil {
-		failF("%v: %v", testName, err)
-		return fmt.Errorf("%v: %w", testName, err)
-	}
-
-	out, err := execCommand("admin", "-a", usr.access, "-s", usr.secret, "-er", s.endpoint, "delete-user", "-a", "random_access")
-	if err != nil {
-		failF("%v: %v", testName, err)
-		return fmt.Errorf("%v: %w", testName, err)
-	}
-	if !strings.Contains(string(out), adminAccessDeniedMsg) {
-		failF("%v: expected response error message to be %v, instead got %s", testName, adminAccessDeniedMsg, out)
-		return fmt.Errorf("%v: expected response error message to be %v, instead got %s", testName, adminAccessDeniedMsg, out)
-	}
-
-	passF(testName)
-
-	return nil
-}
-
-func IAM_userplus_access_denied(s *S3Conf) error {
-	testName := "IAM_userplus_access_denied"
-	runF(testName)
-
-	usr := user{
-		access: "grt1",
-		secret: "grt1secret",
-		role:   "userplus",
-	}
-	err := deleteUser(s, usr.access)
-	if err != nil {
-		failF("%v: %v", testName, err)
-		return fmt.Errorf("%v: %w", testName, err)
-	}
-
-	err = createUsers(s, []user{usr})
-	if err != nil {
-		failF("%v: %v", testName, err)
-		return fmt.Errorf("%v: %w", testName, err)
-	}
-
-	out, err := execCommand("admin", "-a", usr.access, "-s", usr.secret, "-er", s.endpoint, "delete-user", "-a", "random_access")
-	if err != nil {
-		failF("%v: %v", testName, err)
-		return fmt.Errorf("%v: %w", testName, err)
-	}
-	if !strings.Contains(string(out), adminAccessDeniedMsg) {
-		failF("%v: expected response error message to be %v, instead got %s", testName, adminAccessDeniedMsg, out)
-		return fmt.Errorf("%v: expected response error message to be %v, instead got %s", testName, adminAccessDeniedMsg, out)
-	}
-
-	passF(testName)
-
-	return nil
-}
-
-func IAM_userplus_CreateBucket(s *S3Conf) error {
-	testName := "IAM_userplus_CreateBucket"
+func PutBucketPolicy_not_principal(s *S3Conf) error {
+	testName := "PutBucketPolicy_not_principal"
 	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
-		usr := user{
-			access: "grt1",
-			secret: "grt1secret",
-			role:   "userplus",
-		}
-		err := deleteUser(s, usr.access)
+		err := createUsers(s, []user{
+			{"grt1", "grt1secret", "user"},
+			{"grt2", "grt2secret", "user"},
+		})
 		if err != nil {
 			return err
 		}
 
-		err = createUsers(s, []user{usr})
+		err = putBucketAclGrants(s3client, bucket, s.awsID, types.PermissionRead, "grt1", "grt2")
 		if err != nil {
 			return err
 		}
 
-		cfg := *s
-		cfg.awsID = usr.access
-		cfg.awsSecret = usr.secret
+		doc := fmt.Sprintf(`{
+			"Statement": [
+				{
+					"Effect": "Allow",
+					"NotPrincipal": "grt2",
+					"Action": "s3:ListBucket",
+					"Resource": "arn:aws:s3:::%v"
+				}
+			]
+		}`, bucket)
 
-		bckt := getBucketName()
-		err = setup(&cfg, bckt)
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.PutBucketPolicy(ctx, &s3.PutBucketPolicyInput{
+			Bucket: &bucket,
+			Policy: &doc,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		for _, usr := range []struct {
+			access  string
+			secret  string
+			allowed bool
+		}{
+			{"grt1", "grt1secret", true},
+			{"grt2", "grt2secret", false},
+		} {
+			newConf := *s
+			newConf.awsID = usr.access
+			newConf.awsSecret = usr.secret
+			userClient := s3.NewFromConfig(newConf.Config())
+
+			ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+			_, err = userClient.ListObjects(ctx, &s3.ListObjectsInput{
+				Bucket: &bucket,
+			})
+			cancel()
+			if usr.allowed {
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrAccessDenied)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func PutBucketPolicy_not_action(s *S3Conf) error {
+	testName := "PutBucketPolicy_not_action"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		err := createUsers(s, []user{{"grt1", "grt1secret", "user"}})
+		if err != nil {
+			return err
+		}
+
+		err = putBucketAclGrants(s3client, bucket, s.awsID, types.PermissionFullControl, "grt1")
 		if err != nil {
 			return err
 		}
 
+		doc := fmt.Sprintf(`{
+			"Statement": [
+				{
+					"Effect": "Allow",
+					"Principal": "grt1",
+					"NotAction": "s3:GetBucketAcl",
+					"Resource": "arn:aws:s3:::%v"
+				}
+			]
+		}`, bucket)
+
 		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
-		_, err = s3client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: &bckt})
+		_, err = s3client.PutBucketPolicy(ctx, &s3.PutBucketPolicyInput{
+			Bucket: &bucket,
+			Policy: &doc,
+		})
 		cancel()
 		if err != nil {
 			return err
 		}
 
-		err = teardown(&cfg, bckt)
+		newConf := *s
+		newConf.awsID = "grt1"
+		newConf.awsSecret = "grt1secret"
+		userClient := s3.NewFromConfig(newConf.Config())
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = userClient.ListObjects(ctx, &s3.ListObjectsInput{
+			Bucket: &bucket,
+		})
+		cancel()
 		if err != nil {
 			return err
 		}
 
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = userClient.GetBucketAcl(ctx, &s3.GetBucketAclInput{
+			Bucket: &bucket,
+		})
+		cancel()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrAccessDenied)); err != nil {
+			return err
+		}
+
 		return nil
 	})
 }
 
-func IAM_admin_ChangeBucketOwner(s *S3Conf) error {
-	testName := "IAM_admin_ChangeBucketOwner"
+func PutBucketPolicy_not_resource(s *S3Conf) error {
+	testName := "PutBucketPolicy_not_resource"
 	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
-		admin := user{
-			access: "admin1",
-			secret: "admin1secret",
-			role:   "admin",
-		}
-		usr := user{
-			access: "grt1",
-			secret: "grt1secret",
-			role:   "user",
+		err := createUsers(s, []user{{"grt1", "grt1secret", "user"}})
+		if err != nil {
+			return err
 		}
-		err := createUsers(s, []user{admin, usr})
+
+		err = putObjects(s3client, []string{"public/obj", "private/obj"}, bucket)
 		if err != nil {
 			return err
 		}
 
-		err = changeBucketsOwner(s, []string{bucket}, usr.access)
+		err = putBucketAclGrants(s3client, bucket, s.awsID, types.PermissionRead, "grt1")
 		if err != nil {
 			return err
 		}
 
+		doc := fmt.Sprintf(`{
+			"Statement": [
+				{
+					"Effect": "Allow",
+					"Principal": "grt1",
+					"Action": "s3:GetObject",
+					"NotResource": "arn:aws:s3:::%v/private/*"
+				}
+			]
+		}`, bucket)
+
 		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
-		resp, err := s3client.GetBucketAcl(ctx, &s3.GetBucketAclInput{Bucket: &bucket})
+		_, err = s3client.PutBucketPolicy(ctx, &s3.PutBucketPolicyInput{
+			Bucket: &bucket,
+			Policy: &doc,
+		})
 		cancel()
 		if err != nil {
 			return err
 		}
 
-		if *resp.Owner.ID != usr.access {
-			return fmt.Errorf("expected the bucket owner to be %v, instead got %v", usr.access, *resp.Owner.ID)
+		newConf := *s
+		newConf.awsID = "grt1"
+		newConf.awsSecret = "grt1secret"
+		userClient := s3.NewFromConfig(newConf.Config())
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		out, err := userClient.GetObject(ctx, &s3.GetObjectInput{
+			Bucket: &bucket,
+			Key:    getPtr("public/obj"),
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+		out.Body.Close()
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = userClient.GetObject(ctx, &s3.GetObjectInput{
+			Bucket: &bucket,
+			Key:    getPtr("private/obj"),
+		})
+		cancel()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrAccessDenied)); err != nil {
+			return err
 		}
 
 		return nil
 	})
 }
 
-// Posix related tests
-func PutObject_overwrite_dir_obj(s *S3Conf) error {
-	testName := "PutObject_overwrite_dir_obj"
+func PublicAccess_private_bucket(s *S3Conf) error {
+	testName := "PublicAccess_private_bucket"
 	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
-		err := putObjects(s3client, []string{"foo/", "foo"}, bucket)
-		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrExistingObjectIsDirectory)); err != nil {
+		obj := "my-obj"
+		err := putObjects(s3client, []string{obj}, bucket)
+		if err != nil {
+			return err
+		}
+
+		anonClient := getAnonymousClient(s)
+
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err = anonClient.GetObject(ctx, &s3.GetObjectInput{
+			Bucket: &bucket,
+			Key:    &obj,
+		})
+		cancel()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrAccessDenied)); err != nil {
+			return err
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = anonClient.CreateBucket(ctx, &s3.CreateBucketInput{
+			Bucket: getPtr(getBucketName()),
+		})
+		cancel()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrAccessDenied)); err != nil {
 			return err
 		}
+
 		return nil
 	})
 }
 
-func PutObject_overwrite_file_obj(s *S3Conf) error {
-	testName := "PutObject_overwrite_file_obj"
+func PublicAccess_canned_acl(s *S3Conf) error {
+	testName := "PublicAccess_canned_acl"
 	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
-		err := putObjects(s3client, []string{"foo", "foo/"}, bucket)
-		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrObjectParentIsFile)); err != nil {
+		obj := "my-obj"
+		_, data, err := putObjectWithData(100, &s3.PutObjectInput{
+			Bucket: &bucket,
+			Key:    &obj,
+		}, s3client)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.PutBucketAcl(ctx, &s3.PutBucketAclInput{
+			Bucket: &bucket,
+			ACL:    types.BucketCannedACLPublicRead,
+			AccessControlPolicy: &types.AccessControlPolicy{
+				Owner: &types.Owner{
+					ID: &s.awsID,
+				},
+			},
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		anonClient := getAnonymousClient(s)
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		out, err := anonClient.GetObject(ctx, &s3.GetObjectInput{
+			Bucket: &bucket,
+			Key:    &obj,
+		})
+		defer cancel()
+		if err != nil {
+			return err
+		}
+		body, err := io.ReadAll(out.Body)
+		out.Body.Close()
+		if err != nil {
+			return err
+		}
+		if !isEqual(body, data) {
+			return fmt.Errorf("incorrect object data")
+		}
+
+		err = putObjects(anonClient, []string{"anon-obj"}, bucket)
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrAccessDenied)); err != nil {
 			return err
 		}
+
 		return nil
 	})
 }
 
-func PutObject_dir_obj_with_data(s *S3Conf) error {
-	testName := "PutObject_dir_obj_with_data"
+func PublicAccess_all_users_grant(s *S3Conf) error {
+	testName := "PublicAccess_all_users_grant"
 	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
-		_, _, err := putObjectWithData(int64(20), &s3.PutObjectInput{
+		err := putObjects(s3client, []string{"my-obj"}, bucket)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.PutBucketAcl(ctx, &s3.PutBucketAclInput{
 			Bucket: &bucket,
-			Key:    getPtr("obj/"),
-		}, s3client)
-		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrDirectoryObjectContainsData)); err != nil {
+			AccessControlPolicy: &types.AccessControlPolicy{
+				Grants: []types.Grant{
+					{
+						Grantee: &types.Grantee{
+							URI:  getPtr("http://acs.amazonaws.com/groups/global/AllUsers"),
+							Type: types.TypeGroup,
+						},
+						Permission: types.PermissionRead,
+					},
+				},
+				Owner: &types.Owner{
+					ID: &s.awsID,
+				},
+			},
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		acl, err := s3client.GetBucketAcl(ctx, &s3.GetBucketAclInput{
+			Bucket: &bucket,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+		if len(acl.Grants) != 1 || acl.Grants[0].Grantee == nil ||
+			getString(acl.Grants[0].Grantee.URI) != "http://acs.amazonaws.com/groups/global/AllUsers" {
+			return fmt.Errorf("expected the AllUsers group grant, instead got %v", acl.Grants)
+		}
+
+		anonClient := getAnonymousClient(s)
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		out, err := anonClient.ListObjects(ctx, &s3.ListObjectsInput{
+			Bucket: &bucket,
+		})
+		cancel()
+		if err != nil {
 			return err
 		}
+		if !compareObjects([]string{"my-obj"}, out.Contents) {
+			return fmt.Errorf("expected the objects to be [my-obj], instead got %v", out.Contents)
+		}
+
 		return nil
 	})
 }
 
-func CreateMultipartUpload_dir_obj(s *S3Conf) error {
-	testName := "CreateMultipartUpload_dir_obj"
+func PublicAccess_bucket_policy(s *S3Conf) error {
+	testName := "PublicAccess_bucket_policy"
 	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
-		_, err := createMp(s3client, bucket, "obj/")
-		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrDirectoryObjectContainsData)); err != nil {
+		err := putObjects(s3client, []string{"public/obj", "private/obj"}, bucket)
+		if err != nil {
+			return err
+		}
+
+		doc := fmt.Sprintf(`{
+			"Statement": [
+				{
+					"Effect": "Allow",
+					"Principal": "*",
+					"Action": "s3:GetObject",
+					"Resource": "arn:aws:s3:::%[1]v/*"
+				},
+				{
+					"Effect": "Deny",
+					"Principal": "*",
+					"Action": "s3:GetObject",
+					"Resource": "arn:aws:s3:::%[1]v/private/*"
+				}
+			]
+		}`, bucket)
+
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.PutBucketPolicy(ctx, &s3.PutBucketPolicyInput{
+			Bucket: &bucket,
+			Policy: &doc,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		anonClient := getAnonymousClient(s)
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		out, err := anonClient.GetObject(ctx, &s3.GetObjectInput{
+			Bucket: &bucket,
+			Key:    getPtr("public/obj"),
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+		out.Body.Close()
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = anonClient.GetObject(ctx, &s3.GetObjectInput{
+			Bucket: &bucket,
+			Key:    getPtr("private/obj"),
+		})
+		cancel()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrAccessDenied)); err != nil {
 			return err
 		}
+
+		// the policy doesn't allow listing the bucket
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = anonClient.ListObjects(ctx, &s3.ListObjectsInput{
+			Bucket: &bucket,
+		})
+		cancel()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrAccessDenied)); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+func PublicAccess_public_read_write_bucket_config(s *S3Conf) error {
+	testName := "PublicAccess_public_read_write_bucket_config"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err := s3client.PutBucketAcl(ctx, &s3.PutBucketAclInput{
+			Bucket: &bucket,
+			ACL:    types.BucketCannedACLPublicReadWrite,
+			AccessControlPolicy: &types.AccessControlPolicy{
+				Owner: &types.Owner{
+					ID: &s.awsID,
+				},
+			},
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		anonClient := getAnonymousClient(s)
+
+		// the canned ACL grants the object writes only
+		err = putObjects(anonClient, []string{"anon-obj"}, bucket)
+		if err != nil {
+			return err
+		}
+
+		doc := fmt.Sprintf(`{
+			"Statement": [
+				{
+					"Effect": "Allow",
+					"Principal": "*",
+					"Action": "s3:*",
+					"Resource": "arn:aws:s3:::%v"
+				}
+			]
+		}`, bucket)
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = anonClient.PutBucketPolicy(ctx, &s3.PutBucketPolicyInput{
+			Bucket: &bucket,
+			Policy: &doc,
+		})
+		cancel()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrAccessDenied)); err != nil {
+			return err
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = anonClient.PutBucketAcl(ctx, &s3.PutBucketAclInput{
+			Bucket: &bucket,
+			ACL:    types.BucketCannedACLPrivate,
+		})
+		cancel()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrAccessDenied)); err != nil {
+			return err
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = anonClient.GetBucketAcl(ctx, &s3.GetBucketAclInput{
+			Bucket: &bucket,
+		})
+		cancel()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrAccessDenied)); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+func GetBucketPolicy_non_existing_bucket(s *S3Conf) error {
+	testName := "GetBucketPolicy_non_existing_bucket"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err := s3client.GetBucketPolicy(ctx, &s3.GetBucketPolicyInput{
+			Bucket: getPtr("non_existing_bucket"),
+		})
+		cancel()
+
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrNoSuchBucket)); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+func GetBucketPolicy_default_empty_policy(s *S3Conf) error {
+	testName := "GetBucketPolicy_default_empty_policy"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		out, err := s3client.GetBucketPolicy(ctx, &s3.GetBucketPolicyInput{
+			Bucket: &bucket,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		if out.Policy != nil {
+			return fmt.Errorf("expected policy to be nil, instead got %s", *out.Policy)
+		}
+
+		return nil
+	})
+}
+
+func GetBucketPolicy_success(s *S3Conf) error {
+	testName := "GetBucketPolicy_success"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		doc := genPolicyDoc("Allow", `"*"`, `["s3:DeleteBucket", "s3:GetBucketTagging"]`, fmt.Sprintf(`"arn:aws:s3:::%v"`, bucket))
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err := s3client.PutBucketPolicy(ctx, &s3.PutBucketPolicyInput{
+			Bucket: &bucket,
+			Policy: &doc,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		out, err := s3client.GetBucketPolicy(ctx, &s3.GetBucketPolicyInput{
+			Bucket: &bucket,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		if out.Policy == nil {
+			return fmt.Errorf("expected non nil policy result")
+		}
+
+		if *out.Policy != doc {
+			return fmt.Errorf("expected the bucket policy to be %v, instead got %v", doc, *out.Policy)
+		}
+
+		return nil
+	})
+}
+
+func DeleteBucketPolicy_non_existing_bucket(s *S3Conf) error {
+	testName := "DeleteBucketPolicy_non_existing_bucket"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err := s3client.DeleteBucketPolicy(ctx, &s3.DeleteBucketPolicyInput{
+			Bucket: getPtr("non_existing_bucket"),
+		})
+		cancel()
+
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrNoSuchBucket)); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+func DeleteBucketPolicy_remove_before_setting(s *S3Conf) error {
+	testName := "DeleteBucketPolicy_remove_before_setting"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err := s3client.DeleteBucketPolicy(ctx, &s3.DeleteBucketPolicyInput{
+			Bucket: &bucket,
+		})
+		cancel()
+
+		return err
+	})
+}
+
+func DeleteBucketPolicy_success(s *S3Conf) error {
+	testName := "DeleteBucketPolicy_success"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		doc := genPolicyDoc("Allow", `"*"`, `["s3:DeleteBucket", "s3:GetBucketTagging"]`, fmt.Sprintf(`"arn:aws:s3:::%v"`, bucket))
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err := s3client.PutBucketPolicy(ctx, &s3.PutBucketPolicyInput{
+			Bucket: &bucket,
+			Policy: &doc,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.DeleteBucketPolicy(ctx, &s3.DeleteBucketPolicyInput{
+			Bucket: &bucket,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		out, err := s3client.GetBucketPolicy(ctx, &s3.GetBucketPolicyInput{
+			Bucket: &bucket,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		if out.Policy != nil {
+			return fmt.Errorf("expected policy to be nil, instead got %s", *out.Policy)
+		}
+
+		return err
+	})
+}
+
+// IAM related tests
+// multi-user iam tests
+func IAM_user_access_denied(s *S3Conf) error {
+	testName := "IAM_user_access_denied"
+	runF(testName)
+
+	usr := user{
+		access: "grt1",
+		secret: "grt1secret",
+		role:   "user",
+	}
+	err := deleteUser(s, usr.access)
+	if err != nil {
+		failF("%v: %v", testName, err)
+		return fmt.Errorf("%v: %w", testName, err)
+	}
+
+	err = createUsers(s, []user{usr})
+	if err != nil {
+		failF("%v: %v", testName, err)
+		return fmt.Errorf("%v: %w", testName, err)
+	}
+
+	out, err := execCommand("admin", "-a", usr.access, "-s", usr.secret, "-er", s.endpoint, "delete-user", "-a", "random_access")
+	if err != nil {
+		failF("%v: %v", testName, err)
+		return fmt.Errorf("%v: %w", testName, err)
+	}
+	if !strings.Contains(string(out), adminAccessDeniedMsg) {
+		failF("%v: expected response error message to be %v, instead got %s", testName, adminAccessDeniedMsg, out)
+		return fmt.Errorf("%v: expected response error message to be %v, instead got %s", testName, adminAccessDeniedMsg, out)
+	}
+
+	passF(testName)
+
+	return nil
+}
+
+func IAM_userplus_access_denied(s *S3Conf) error {
+	testName := "IAM_userplus_access_denied"
+	runF(testName)
+
+	usr := user{
+		access: "grt1",
+		secret: "grt1secret",
+		role:   "userplus",
+	}
+	err := deleteUser(s, usr.access)
+	if err != nil {
+		failF("%v: %v", testName, err)
+		return fmt.Errorf("%v: %w", testName, err)
+	}
+
+	err = createUsers(s, []user{usr})
+	if err != nil {
+		failF("%v: %v", testName, err)
+		return fmt.Errorf("%v: %w", testName, err)
+	}
+
+	out, err := execCommand("admin", "-a", usr.access, "-s", usr.secret, "-er", s.endpoint, "delete-user", "-a", "random_access")
+	if err != nil {
+		failF("%v: %v", testName, err)
+		return fmt.Errorf("%v: %w", testName, err)
+	}
+	if !strings.Contains(string(out), adminAccessDeniedMsg) {
+		failF("%v: expected response error message to be %v, instead got %s", testName, adminAccessDeniedMsg, out)
+		return fmt.Errorf("%v: expected response error message to be %v, instead got %s", testName, adminAccessDeniedMsg, out)
+	}
+
+	passF(testName)
+
+	return nil
+}
+
+func IAM_userplus_CreateBucket(s *S3Conf) error {
+	testName := "IAM_userplus_CreateBucket"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		usr := user{
+			access: "grt1",
+			secret: "grt1secret",
+			role:   "userplus",
+		}
+		err := deleteUser(s, usr.access)
+		if err != nil {
+			return err
+		}
+
+		err = createUsers(s, []user{usr})
+		if err != nil {
+			return err
+		}
+
+		cfg := *s
+		cfg.awsID = usr.access
+		cfg.awsSecret = usr.secret
+
+		bckt := getBucketName()
+		err = setup(&cfg, bckt)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: &bckt})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		err = teardown(&cfg, bckt)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+func IAM_admin_ChangeBucketOwner(s *S3Conf) error {
+	testName := "IAM_admin_ChangeBucketOwner"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		admin := user{
+			access: "admin1",
+			secret: "admin1secret",
+			role:   "admin",
+		}
+		usr := user{
+			access: "grt1",
+			secret: "grt1secret",
+			role:   "user",
+		}
+		err := createUsers(s, []user{admin, usr})
+		if err != nil {
+			return err
+		}
+
+		err = changeBucketsOwner(s, []string{bucket}, usr.access)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		resp, err := s3client.GetBucketAcl(ctx, &s3.GetBucketAclInput{Bucket: &bucket})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		if *resp.Owner.ID != usr.access {
+			return fmt.Errorf("expected the bucket owner to be %v, instead got %v", usr.access, *resp.Owner.ID)
+		}
+
+		return nil
+	})
+}
+
+// Posix related tests
+func PutObject_overwrite_dir_obj(s *S3Conf) error {
+	testName := "PutObject_overwrite_dir_obj"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		err := putObjects(s3client, []string{"foo/", "foo"}, bucket)
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrExistingObjectIsDirectory)); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+func PutObject_overwrite_file_obj(s *S3Conf) error {
+	testName := "PutObject_overwrite_file_obj"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		err := putObjects(s3client, []string{"foo", "foo/"}, bucket)
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrObjectParentIsFile)); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+func PutObject_dir_obj_with_data(s *S3Conf) error {
+	testName := "PutObject_dir_obj_with_data"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		_, _, err := putObjectWithData(int64(20), &s3.PutObjectInput{
+			Bucket: &bucket,
+			Key:    getPtr("obj/"),
+		}, s3client)
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrDirectoryObjectContainsData)); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+func CreateMultipartUpload_dir_obj(s *S3Conf) error {
+	testName := "CreateMultipartUpload_dir_obj"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		_, err := createMp(s3client, bucket, "obj/")
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrDirectoryObjectContainsData)); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+func PutBucketVersioning_non_existing_bucket(s *S3Conf) error {
+	testName := "PutBucketVersioning_non_existing_bucket"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		err := putBucketVersioningStatus(s3client, "non_existing_bucket", types.BucketVersioningStatusEnabled)
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrNoSuchBucket)); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+func PutBucketVersioning_invalid_status(s *S3Conf) error {
+	testName := "PutBucketVersioning_invalid_status"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		err := putBucketVersioningStatus(s3client, bucket, types.BucketVersioningStatus("invalid"))
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrIllegalVersioningConfiguration)); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+func GetBucketVersioning_success(s *S3Conf) error {
+	testName := "GetBucketVersioning_success"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		out, err := s3client.GetBucketVersioning(ctx, &s3.GetBucketVersioningInput{
+			Bucket: &bucket,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+		if out.Status != "" {
+			return fmt.Errorf("expected empty versioning status, instead got %v", out.Status)
+		}
+
+		for _, status := range []types.BucketVersioningStatus{
+			types.BucketVersioningStatusEnabled,
+			types.BucketVersioningStatusSuspended,
+		} {
+			err = putBucketVersioningStatus(s3client, bucket, status)
+			if err != nil {
+				return err
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+			out, err := s3client.GetBucketVersioning(ctx, &s3.GetBucketVersioningInput{
+				Bucket: &bucket,
+			})
+			cancel()
+			if err != nil {
+				return err
+			}
+			if out.Status != status {
+				return fmt.Errorf("expected versioning status %v, instead got %v", status, out.Status)
+			}
+		}
+		return nil
+	})
+}
+
+func Versioning_PutObject_GetObject_by_version(s *S3Conf) error {
+	testName := "Versioning_PutObject_GetObject_by_version"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		err := putBucketVersioningStatus(s3client, bucket, types.BucketVersioningStatusEnabled)
+		if err != nil {
+			return err
+		}
+
+		obj := "my-obj"
+		var versions []string
+		var csums [][32]byte
+		for i := 0; i < 3; i++ {
+			input := &s3.PutObjectInput{
+				Bucket: &bucket,
+				Key:    &obj,
+			}
+			csum, _, err := putObjectWithData(int64(100+i), input, s3client)
+			if err != nil {
+				return err
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+			out, err := s3client.HeadObject(ctx, &s3.HeadObjectInput{
+				Bucket: &bucket,
+				Key:    &obj,
+			})
+			cancel()
+			if err != nil {
+				return err
+			}
+			if out.VersionId == nil || *out.VersionId == "" {
+				return fmt.Errorf("expected object version id to be set")
+			}
+			versions = append(versions, *out.VersionId)
+			csums = append(csums, csum)
+		}
+
+		for i, versionId := range versions {
+			ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+			out, err := s3client.GetObject(ctx, &s3.GetObjectInput{
+				Bucket:    &bucket,
+				Key:       &obj,
+				VersionId: &versionId,
+			})
+			if err != nil {
+				cancel()
+				return err
+			}
+			bdy, err := io.ReadAll(out.Body)
+			out.Body.Close()
+			cancel()
+			if err != nil {
+				return err
+			}
+			if getString(out.VersionId) != versionId {
+				return fmt.Errorf("expected version id %v, instead got %v", versionId, getString(out.VersionId))
+			}
+			if sha256.Sum256(bdy) != csums[i] {
+				return fmt.Errorf("invalid object data for version %v", versionId)
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.GetObject(ctx, &s3.GetObjectInput{
+			Bucket:    &bucket,
+			Key:       &obj,
+			VersionId: getPtr("01234567890123456789012345678901"),
+		})
+		cancel()
+		if err := checkSdkApiErr(err, "NoSuchVersion"); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+func Versioning_DeleteObject_delete_marker(s *S3Conf) error {
+	testName := "Versioning_DeleteObject_delete_marker"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		err := putBucketVersioningStatus(s3client, bucket, types.BucketVersioningStatusEnabled)
+		if err != nil {
+			return err
+		}
+
+		obj := "my-obj"
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		putOut, err := s3client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket: &bucket,
+			Key:    &obj,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		delOut, err := s3client.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket: &bucket,
+			Key:    &obj,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+		if delOut.DeleteMarker == nil || !*delOut.DeleteMarker {
+			return fmt.Errorf("expected delete marker to be created")
+		}
+		if getString(delOut.VersionId) == "" {
+			return fmt.Errorf("expected delete marker version id to be set")
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.HeadObject(ctx, &s3.HeadObjectInput{
+			Bucket: &bucket,
+			Key:    &obj,
+		})
+		cancel()
+		if err := checkSdkApiErr(err, "NotFound"); err != nil {
+			return err
+		}
+
+		// removing the delete marker restores the previous version
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket:    &bucket,
+			Key:       &obj,
+			VersionId: delOut.VersionId,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		out, err := s3client.HeadObject(ctx, &s3.HeadObjectInput{
+			Bucket: &bucket,
+			Key:    &obj,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+		if getString(out.VersionId) != getString(putOut.VersionId) {
+			return fmt.Errorf("expected version id %v, instead got %v",
+				getString(putOut.VersionId), getString(out.VersionId))
+		}
+		return nil
+	})
+}
+
+func ListObjectVersions_success(s *S3Conf) error {
+	testName := "ListObjectVersions_success"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		err := putBucketVersioningStatus(s3client, bucket, types.BucketVersioningStatusEnabled)
+		if err != nil {
+			return err
+		}
+
+		err = putObjects(s3client, []string{"bar", "foo", "foo"}, bucket)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket: &bucket,
+			Key:    getPtr("bar"),
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		out, err := s3client.ListObjectVersions(ctx, &s3.ListObjectVersionsInput{
+			Bucket: &bucket,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		if len(out.DeleteMarkers) != 1 {
+			return fmt.Errorf("expected 1 delete marker, instead got %v", len(out.DeleteMarkers))
+		}
+		if getString(out.DeleteMarkers[0].Key) != "bar" || !*out.DeleteMarkers[0].IsLatest {
+			return fmt.Errorf("expected latest delete marker for bar")
+		}
+		if len(out.Versions) != 3 {
+			return fmt.Errorf("expected 3 object versions, instead got %v", len(out.Versions))
+		}
+		latest := map[string]int{}
+		for _, v := range out.Versions {
+			if *v.IsLatest {
+				latest[getString(v.Key)]++
+			}
+		}
+		if latest["bar"] != 0 || latest["foo"] != 1 {
+			return fmt.Errorf("unexpected latest versions: %v", latest)
+		}
+
+		maxKeys := int32(2)
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		out, err = s3client.ListObjectVersions(ctx, &s3.ListObjectVersionsInput{
+			Bucket:  &bucket,
+			MaxKeys: &maxKeys,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+		if out.IsTruncated == nil || !*out.IsTruncated {
+			return fmt.Errorf("expected truncated output")
+		}
+		if len(out.Versions)+len(out.DeleteMarkers) != 2 {
+			return fmt.Errorf("expected 2 entries, instead got %v",
+				len(out.Versions)+len(out.DeleteMarkers))
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		out, err = s3client.ListObjectVersions(ctx, &s3.ListObjectVersionsInput{
+			Bucket:          &bucket,
+			KeyMarker:       out.NextKeyMarker,
+			VersionIdMarker: out.NextVersionIdMarker,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+		if len(out.Versions) != 2 || len(out.DeleteMarkers) != 0 {
+			return fmt.Errorf("expected the 2 remaining versions of foo, instead got %v versions and %v delete markers",
+				len(out.Versions), len(out.DeleteMarkers))
+		}
+		return nil
+	})
+}
+
+func Versioning_CompleteMultipartUpload_version_id(s *S3Conf) error {
+	testName := "Versioning_CompleteMultipartUpload_version_id"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		err := putBucketVersioningStatus(s3client, bucket, types.BucketVersioningStatusEnabled)
+		if err != nil {
+			return err
+		}
+
+		obj := "my-obj"
+		err = putObjects(s3client, []string{obj}, bucket)
+		if err != nil {
+			return err
+		}
+
+		mp, err := createMp(s3client, bucket, obj)
+		if err != nil {
+			return err
+		}
+		parts, err := uploadParts(s3client, 5*1024*1024, 1, bucket, obj, *mp.UploadId)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		out, err := s3client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+			Bucket:   &bucket,
+			Key:      &obj,
+			UploadId: mp.UploadId,
+			MultipartUpload: &types.CompletedMultipartUpload{
+				Parts: []types.CompletedPart{
+					{ETag: parts[0].ETag, PartNumber: parts[0].PartNumber},
+				},
+			},
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+		if getString(out.VersionId) == "" {
+			return fmt.Errorf("expected the version id of the completed upload")
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		versions, err := s3client.ListObjectVersions(ctx, &s3.ListObjectVersionsInput{
+			Bucket: &bucket,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+		if len(versions.Versions) != 2 {
+			return fmt.Errorf("expected 2 object versions, instead got %v", len(versions.Versions))
+		}
+		latest := versions.Versions[0]
+		if !*latest.IsLatest || getString(latest.VersionId) != getString(out.VersionId) {
+			return fmt.Errorf("expected the latest version %v, instead got %v",
+				getString(out.VersionId), getString(latest.VersionId))
+		}
+
+		return nil
+	})
+}
+
+func ListObjectVersions_paginated_nested(s *S3Conf) error {
+	testName := "ListObjectVersions_paginated_nested"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		err := putBucketVersioningStatus(s3client, bucket, types.BucketVersioningStatusEnabled)
+		if err != nil {
+			return err
+		}
+
+		err = putObjects(s3client, []string{"z", "a/c/d", "a-b", "deleted/x", "a/b"}, bucket)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket: &bucket,
+			Key:    getPtr("deleted/x"),
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		// list one entry per page, the entries are in key order
+		// across the nested directories and the version store
+		list := func(delim *string) ([]string, error) {
+			var entries []string
+			var keyMarker, versionIdMarker *string
+			maxKeys := int32(1)
+			for {
+				ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+				out, err := s3client.ListObjectVersions(ctx, &s3.ListObjectVersionsInput{
+					Bucket:          &bucket,
+					Delimiter:       delim,
+					KeyMarker:       keyMarker,
+					VersionIdMarker: versionIdMarker,
+					MaxKeys:         &maxKeys,
+				})
+				cancel()
+				if err != nil {
+					return nil, err
+				}
+				for _, cp := range out.CommonPrefixes {
+					entries = append(entries, getString(cp.Prefix))
+				}
+				for _, dm := range out.DeleteMarkers {
+					entries = append(entries, "marker:"+getString(dm.Key))
+				}
+				for _, v := range out.Versions {
+					entries = append(entries, getString(v.Key))
+				}
+				if out.IsTruncated == nil || !*out.IsTruncated {
+					return entries, nil
+				}
+				keyMarker, versionIdMarker = out.NextKeyMarker, out.NextVersionIdMarker
+			}
+		}
+
+		entries, err := list(nil)
+		if err != nil {
+			return err
+		}
+		expected := []string{"a-b", "a/b", "a/c/d", "marker:deleted/x", "deleted/x", "z"}
+		if strings.Join(entries, ",") != strings.Join(expected, ",") {
+			return fmt.Errorf("expected the versions %v, instead got %v", expected, entries)
+		}
+
+		entries, err = list(getPtr("/"))
+		if err != nil {
+			return err
+		}
+		expected = []string{"a-b", "a/", "deleted/", "z"}
+		if strings.Join(entries, ",") != strings.Join(expected, ",") {
+			return fmt.Errorf("expected the entries %v, instead got %v", expected, entries)
+		}
+
+		return nil
+	})
+}
+
+func PutObjectLockConfiguration_non_existing_bucket(s *S3Conf) error {
+	testName := "PutObjectLockConfiguration_non_existing_bucket"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		err := putObjectLockConfig(s3client, getBucketName(), &types.ObjectLockConfiguration{
+			ObjectLockEnabled: types.ObjectLockEnabledEnabled,
+		})
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrNoSuchBucket)); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+func GetObjectLockConfiguration_not_found(s *S3Conf) error {
+	testName := "GetObjectLockConfiguration_not_found"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err := s3client.GetObjectLockConfiguration(ctx, &s3.GetObjectLockConfigurationInput{
+			Bucket: &bucket,
+		})
+		cancel()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrObjectLockConfigurationNotFound)); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+func PutObjectLockConfiguration_success(s *S3Conf) error {
+	testName := "PutObjectLockConfiguration_success"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		days := int32(1)
+		config := &types.ObjectLockConfiguration{
+			ObjectLockEnabled: types.ObjectLockEnabledEnabled,
+			Rule: &types.ObjectLockRule{
+				DefaultRetention: &types.DefaultRetention{
+					Mode: types.ObjectLockRetentionModeGovernance,
+					Days: &days,
+				},
+			},
+		}
+
+		// object lock requires versioning to be enabled
+		err := putObjectLockConfig(s3client, bucket, config)
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrInvalidBucketState)); err != nil {
+			return err
+		}
+
+		err = putBucketVersioningStatus(s3client, bucket, types.BucketVersioningStatusEnabled)
+		if err != nil {
+			return err
+		}
+		err = putObjectLockConfig(s3client, bucket, config)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		out, err := s3client.GetObjectLockConfiguration(ctx, &s3.GetObjectLockConfigurationInput{
+			Bucket: &bucket,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		config = out.ObjectLockConfiguration
+		if config == nil || config.ObjectLockEnabled != types.ObjectLockEnabledEnabled {
+			return fmt.Errorf("expected object lock to be enabled")
+		}
+		if config.Rule == nil || config.Rule.DefaultRetention == nil {
+			return fmt.Errorf("expected default retention rule to be set")
+		}
+		if config.Rule.DefaultRetention.Mode != types.ObjectLockRetentionModeGovernance {
+			return fmt.Errorf("expected default retention mode %v, instead got %v",
+				types.ObjectLockRetentionModeGovernance, config.Rule.DefaultRetention.Mode)
+		}
+
+		// new objects get the default retention of the bucket
+		obj := "my-obj"
+		err = putObjects(s3client, []string{obj}, bucket)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		ret, err := s3client.GetObjectRetention(ctx, &s3.GetObjectRetentionInput{
+			Bucket: &bucket,
+			Key:    &obj,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+		if ret.Retention == nil || ret.Retention.Mode != types.ObjectLockRetentionModeGovernance {
+			return fmt.Errorf("expected object to have governance mode retention")
+		}
+		return nil
+	})
+}
+
+func PutObjectRetention_missing_lock_configuration(s *S3Conf) error {
+	testName := "PutObjectRetention_missing_lock_configuration"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		obj := "my-obj"
+		err := putObjects(s3client, []string{obj}, bucket)
+		if err != nil {
+			return err
+		}
+
+		retainUntil := time.Now().Add(time.Hour)
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.PutObjectRetention(ctx, &s3.PutObjectRetentionInput{
+			Bucket: &bucket,
+			Key:    &obj,
+			Retention: &types.ObjectLockRetention{
+				Mode:            types.ObjectLockRetentionModeGovernance,
+				RetainUntilDate: &retainUntil,
+			},
+		})
+		cancel()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrInvalidBucketObjectLockConfiguration)); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+func ObjectLock_governance_retention_delete(s *S3Conf) error {
+	testName := "ObjectLock_governance_retention_delete"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		err := putBucketVersioningStatus(s3client, bucket, types.BucketVersioningStatusEnabled)
+		if err != nil {
+			return err
+		}
+		err = putObjectLockConfig(s3client, bucket, &types.ObjectLockConfiguration{
+			ObjectLockEnabled: types.ObjectLockEnabledEnabled,
+		})
+		if err != nil {
+			return err
+		}
+
+		obj := "my-obj"
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		putOut, err := s3client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket: &bucket,
+			Key:    &obj,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		retainUntil := time.Now().Add(time.Hour)
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.PutObjectRetention(ctx, &s3.PutObjectRetentionInput{
+			Bucket:    &bucket,
+			Key:       &obj,
+			VersionId: putOut.VersionId,
+			Retention: &types.ObjectLockRetention{
+				Mode:            types.ObjectLockRetentionModeGovernance,
+				RetainUntilDate: &retainUntil,
+			},
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket:    &bucket,
+			Key:       &obj,
+			VersionId: putOut.VersionId,
+		})
+		cancel()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrObjectLocked)); err != nil {
+			return err
+		}
+
+		// versioning can not be suspended while object lock is enabled
+		err = putBucketVersioningStatus(s3client, bucket, types.BucketVersioningStatusSuspended)
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrInvalidBucketState)); err != nil {
+			return err
+		}
+
+		bypass := true
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket:                    &bucket,
+			Key:                       &obj,
+			VersionId:                 putOut.VersionId,
+			BypassGovernanceRetention: &bypass,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+func ObjectLock_legal_hold_delete(s *S3Conf) error {
+	testName := "ObjectLock_legal_hold_delete"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		err := putBucketVersioningStatus(s3client, bucket, types.BucketVersioningStatusEnabled)
+		if err != nil {
+			return err
+		}
+		err = putObjectLockConfig(s3client, bucket, &types.ObjectLockConfiguration{
+			ObjectLockEnabled: types.ObjectLockEnabledEnabled,
+		})
+		if err != nil {
+			return err
+		}
+
+		obj := "my-obj"
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		putOut, err := s3client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:                    &bucket,
+			Key:                       &obj,
+			ObjectLockLegalHoldStatus: types.ObjectLockLegalHoldStatusOn,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		out, err := s3client.GetObjectLegalHold(ctx, &s3.GetObjectLegalHoldInput{
+			Bucket: &bucket,
+			Key:    &obj,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+		if out.LegalHold == nil || out.LegalHold.Status != types.ObjectLockLegalHoldStatusOn {
+			return fmt.Errorf("expected legal hold to be %v", types.ObjectLockLegalHoldStatusOn)
+		}
+
+		// a legal hold can't be bypassed
+		bypass := true
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket:                    &bucket,
+			Key:                       &obj,
+			VersionId:                 putOut.VersionId,
+			BypassGovernanceRetention: &bypass,
+		})
+		cancel()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrObjectLocked)); err != nil {
+			return err
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.PutObjectLegalHold(ctx, &s3.PutObjectLegalHoldInput{
+			Bucket: &bucket,
+			Key:    &obj,
+			LegalHold: &types.ObjectLockLegalHold{
+				Status: types.ObjectLockLegalHoldStatusOff,
+			},
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket:    &bucket,
+			Key:       &obj,
+			VersionId: putOut.VersionId,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+func ObjectLock_multipart_upload_retention(s *S3Conf) error {
+	testName := "ObjectLock_multipart_upload_retention"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		err := putBucketVersioningStatus(s3client, bucket, types.BucketVersioningStatusEnabled)
+		if err != nil {
+			return err
+		}
+		err = putObjectLockConfig(s3client, bucket, &types.ObjectLockConfiguration{
+			ObjectLockEnabled: types.ObjectLockEnabledEnabled,
+		})
+		if err != nil {
+			return err
+		}
+
+		obj := "my-obj"
+		retainUntil := time.Now().Add(time.Hour).Truncate(time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		mp, err := s3client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+			Bucket:                    &bucket,
+			Key:                       &obj,
+			ObjectLockMode:            types.ObjectLockModeGovernance,
+			ObjectLockRetainUntilDate: &retainUntil,
+			ObjectLockLegalHoldStatus: types.ObjectLockLegalHoldStatusOn,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		parts, err := uploadParts(s3client, 1024, 1, bucket, obj, *mp.UploadId)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		out, err := s3client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+			Bucket:   &bucket,
+			Key:      &obj,
+			UploadId: mp.UploadId,
+			MultipartUpload: &types.CompletedMultipartUpload{
+				Parts: []types.CompletedPart{
+					{ETag: parts[0].ETag, PartNumber: parts[0].PartNumber},
+				},
+			},
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		retention, err := s3client.GetObjectRetention(ctx, &s3.GetObjectRetentionInput{
+			Bucket: &bucket,
+			Key:    &obj,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+		if retention.Retention == nil || retention.Retention.Mode != types.ObjectLockRetentionModeGovernance ||
+			retention.Retention.RetainUntilDate == nil || !retention.Retention.RetainUntilDate.Equal(retainUntil) {
+			return fmt.Errorf("expected the retention of the upload %v until %v, instead got %+v",
+				types.ObjectLockRetentionModeGovernance, retainUntil, retention.Retention)
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		legalHold, err := s3client.GetObjectLegalHold(ctx, &s3.GetObjectLegalHoldInput{
+			Bucket: &bucket,
+			Key:    &obj,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+		if legalHold.LegalHold == nil || legalHold.LegalHold.Status != types.ObjectLockLegalHoldStatusOn {
+			return fmt.Errorf("expected the legal hold of the upload to be %v", types.ObjectLockLegalHoldStatusOn)
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.PutObjectLegalHold(ctx, &s3.PutObjectLegalHoldInput{
+			Bucket: &bucket,
+			Key:    &obj,
+			LegalHold: &types.ObjectLockLegalHold{
+				Status: types.ObjectLockLegalHoldStatusOff,
+			},
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		bypass := true
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket:                    &bucket,
+			Key:                       &obj,
+			VersionId:                 out.VersionId,
+			BypassGovernanceRetention: &bypass,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+func ObjectLock_bypass_requires_permission(s *S3Conf) error {
+	testName := "ObjectLock_bypass_requires_permission"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		err := createUsers(s, []user{{"grt1", "grt1secret", "user"}})
+		if err != nil {
+			return err
+		}
+
+		err = putBucketVersioningStatus(s3client, bucket, types.BucketVersioningStatusEnabled)
+		if err != nil {
+			return err
+		}
+		err = putObjectLockConfig(s3client, bucket, &types.ObjectLockConfiguration{
+			ObjectLockEnabled: types.ObjectLockEnabledEnabled,
+		})
+		if err != nil {
+			return err
+		}
+
+		obj := "my-obj"
+		retainUntil := time.Now().Add(time.Hour)
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		putOut, err := s3client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:                    &bucket,
+			Key:                       &obj,
+			ObjectLockMode:            types.ObjectLockModeGovernance,
+			ObjectLockRetainUntilDate: &retainUntil,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.PutBucketAcl(ctx, &s3.PutBucketAclInput{
+			Bucket: &bucket,
+			AccessControlPolicy: &types.AccessControlPolicy{
+				Owner: &types.Owner{
+					ID: &s.awsID,
+				},
+			},
+			GrantWrite: getPtr("grt1"),
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		newConf := *s
+		newConf.awsID = "grt1"
+		newConf.awsSecret = "grt1secret"
+		userClient := s3.NewFromConfig(newConf.Config())
+
+		// the bucket ACL write grant does not allow the bypass
+		bypass := true
+		deleteVersion := func() error {
+			ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+			_, err := userClient.DeleteObject(ctx, &s3.DeleteObjectInput{
+				Bucket:                    &bucket,
+				Key:                       &obj,
+				VersionId:                 putOut.VersionId,
+				BypassGovernanceRetention: &bypass,
+			})
+			cancel()
+			return err
+		}
+		err = deleteVersion()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrObjectLocked)); err != nil {
+			return err
+		}
+
+		doc := genPolicyDoc("Allow", `["grt1"]`, `["s3:DeleteObject", "s3:BypassGovernanceRetention"]`,
+			fmt.Sprintf(`"arn:aws:s3:::%v/*"`, bucket))
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.PutBucketPolicy(ctx, &s3.PutBucketPolicyInput{
+			Bucket: &bucket,
+			Policy: &doc,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		return deleteVersion()
+	})
+}
+
+func PutBucketLifecycleConfiguration_non_existing_bucket(s *S3Conf) error {
+	testName := "PutBucketLifecycleConfiguration_non_existing_bucket"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		days := int32(1)
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err := s3client.PutBucketLifecycleConfiguration(ctx, &s3.PutBucketLifecycleConfigurationInput{
+			Bucket: getPtr(getBucketName()),
+			LifecycleConfiguration: &types.BucketLifecycleConfiguration{
+				Rules: []types.LifecycleRule{
+					{
+						Status:     types.ExpirationStatusEnabled,
+						Filter:     &types.LifecycleRuleFilterMemberPrefix{Value: ""},
+						Expiration: &types.LifecycleExpiration{Days: &days},
+					},
+				},
+			},
+		})
+		cancel()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrNoSuchBucket)); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+func PutBucketLifecycleConfiguration_invalid_expiration(s *S3Conf) error {
+	testName := "PutBucketLifecycleConfiguration_invalid_expiration"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err := s3client.PutBucketLifecycleConfiguration(ctx, &s3.PutBucketLifecycleConfigurationInput{
+			Bucket: &bucket,
+			LifecycleConfiguration: &types.BucketLifecycleConfiguration{
+				Rules: []types.LifecycleRule{
+					{
+						Status:     types.ExpirationStatusEnabled,
+						Filter:     &types.LifecycleRuleFilterMemberPrefix{Value: ""},
+						Expiration: &types.LifecycleExpiration{},
+					},
+				},
+			},
+		})
+		cancel()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrMalformedXML)); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+func GetBucketLifecycleConfiguration_not_found(s *S3Conf) error {
+	testName := "GetBucketLifecycleConfiguration_not_found"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err := s3client.GetBucketLifecycleConfiguration(ctx, &s3.GetBucketLifecycleConfigurationInput{
+			Bucket: &bucket,
+		})
+		cancel()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrNoSuchLifecycleConfiguration)); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+func PutBucketLifecycleConfiguration_success(s *S3Conf) error {
+	testName := "PutBucketLifecycleConfiguration_success"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		id, days, abortDays := "expire-logs", int32(30), int32(7)
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err := s3client.PutBucketLifecycleConfiguration(ctx, &s3.PutBucketLifecycleConfigurationInput{
+			Bucket: &bucket,
+			LifecycleConfiguration: &types.BucketLifecycleConfiguration{
+				Rules: []types.LifecycleRule{
+					{
+						ID:         &id,
+						Status:     types.ExpirationStatusEnabled,
+						Filter:     &types.LifecycleRuleFilterMemberPrefix{Value: "logs/"},
+						Expiration: &types.LifecycleExpiration{Days: &days},
+						AbortIncompleteMultipartUpload: &types.AbortIncompleteMultipartUpload{
+							DaysAfterInitiation: &abortDays,
+						},
+					},
+				},
+			},
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		out, err := s3client.GetBucketLifecycleConfiguration(ctx, &s3.GetBucketLifecycleConfigurationInput{
+			Bucket: &bucket,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		if len(out.Rules) != 1 {
+			return fmt.Errorf("expected 1 lifecycle rule, instead got %v", len(out.Rules))
+		}
+		rule := out.Rules[0]
+		if getString(rule.ID) != id {
+			return fmt.Errorf("expected rule id %v, instead got %v", id, getString(rule.ID))
+		}
+		prefix, ok := rule.Filter.(*types.LifecycleRuleFilterMemberPrefix)
+		if !ok || prefix.Value != "logs/" {
+			return fmt.Errorf("expected rule prefix filter logs/, instead got %v", rule.Filter)
+		}
+		if rule.Expiration == nil || rule.Expiration.Days == nil || *rule.Expiration.Days != days {
+			return fmt.Errorf("expected expiration days %v", days)
+		}
+		if rule.AbortIncompleteMultipartUpload == nil ||
+			rule.AbortIncompleteMultipartUpload.DaysAfterInitiation == nil ||
+			*rule.AbortIncompleteMultipartUpload.DaysAfterInitiation != abortDays {
+			return fmt.Errorf("expected abort incomplete multipart upload days %v", abortDays)
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.DeleteBucketLifecycle(ctx, &s3.DeleteBucketLifecycleInput{
+			Bucket: &bucket,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.GetBucketLifecycleConfiguration(ctx, &s3.GetBucketLifecycleConfigurationInput{
+			Bucket: &bucket,
+		})
+		cancel()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrNoSuchLifecycleConfiguration)); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+func PutBucketCors_invalid_method(s *S3Conf) error {
+	testName := "PutBucketCors_invalid_method"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err := s3client.PutBucketCors(ctx, &s3.PutBucketCorsInput{
+			Bucket: &bucket,
+			CORSConfiguration: &types.CORSConfiguration{
+				CORSRules: []types.CORSRule{
+					{
+						AllowedOrigins: []string{"*"},
+						AllowedMethods: []string{"PATCH"},
+					},
+				},
+			},
+		})
+		cancel()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrInvalidCORSMethod)); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+func GetBucketCors_not_found(s *S3Conf) error {
+	testName := "GetBucketCors_not_found"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err := s3client.GetBucketCors(ctx, &s3.GetBucketCorsInput{
+			Bucket: &bucket,
+		})
+		cancel()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrNoSuchCORSConfiguration)); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+func PutBucketCors_success(s *S3Conf) error {
+	testName := "PutBucketCors_success"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		maxAge := int32(3000)
+		rule := types.CORSRule{
+			AllowedOrigins: []string{"https://www.example.com"},
+			AllowedMethods: []string{"GET", "PUT"},
+			AllowedHeaders: []string{"*"},
+			ExposeHeaders:  []string{"ETag"},
+			MaxAgeSeconds:  &maxAge,
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err := s3client.PutBucketCors(ctx, &s3.PutBucketCorsInput{
+			Bucket: &bucket,
+			CORSConfiguration: &types.CORSConfiguration{
+				CORSRules: []types.CORSRule{rule},
+			},
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		out, err := s3client.GetBucketCors(ctx, &s3.GetBucketCorsInput{
+			Bucket: &bucket,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		if len(out.CORSRules) != 1 {
+			return fmt.Errorf("expected 1 cors rule, instead got %v", len(out.CORSRules))
+		}
+		got := out.CORSRules[0]
+		if !areStringsSame(got.AllowedOrigins, rule.AllowedOrigins) ||
+			!areStringsSame(got.AllowedMethods, rule.AllowedMethods) ||
+			!areStringsSame(got.AllowedHeaders, rule.AllowedHeaders) ||
+			!areStringsSame(got.ExposeHeaders, rule.ExposeHeaders) {
+			return fmt.Errorf("expected cors rule %+v, instead got %+v", rule, got)
+		}
+		if got.MaxAgeSeconds == nil || *got.MaxAgeSeconds != maxAge {
+			return fmt.Errorf("expected max age seconds %v", maxAge)
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.DeleteBucketCors(ctx, &s3.DeleteBucketCorsInput{
+			Bucket: &bucket,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.GetBucketCors(ctx, &s3.GetBucketCorsInput{
+			Bucket: &bucket,
+		})
+		cancel()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrNoSuchCORSConfiguration)); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+func CORS_preflight_request(s *S3Conf) error {
+	testName := "CORS_preflight_request"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		origin := "https://www.example.com"
+		preflight := func() (*http.Response, error) {
+			req, err := http.NewRequest(http.MethodOptions,
+				fmt.Sprintf("%v/%v/my-obj", s.endpoint, bucket), nil)
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set("Origin", origin)
+			req.Header.Set("Access-Control-Request-Method", http.MethodPut)
+			req.Header.Set("Access-Control-Request-Headers", "content-type")
+
+			httpClient := http.Client{
+				Timeout: shortTimeout,
+			}
+			return httpClient.Do(req)
+		}
+
+		// no cors configuration, the preflight is rejected
+		resp, err := preflight()
+		if err != nil {
+			return err
+		}
+		if err := checkAuthErr(resp, s3err.GetAPIError(s3err.ErrCORSForbidden)); err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.PutBucketCors(ctx, &s3.PutBucketCorsInput{
+			Bucket: &bucket,
+			CORSConfiguration: &types.CORSConfiguration{
+				CORSRules: []types.CORSRule{
+					{
+						AllowedOrigins: []string{origin},
+						AllowedMethods: []string{http.MethodPut},
+						AllowedHeaders: []string{"Content-*"},
+					},
+				},
+			},
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		resp, err = preflight()
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("expected preflight status %v, instead got %v",
+				http.StatusOK, resp.StatusCode)
+		}
+		if got := resp.Header.Get("Access-Control-Allow-Origin"); got != origin {
+			return fmt.Errorf("expected allowed origin %v, instead got %v", origin, got)
+		}
+		if got := resp.Header.Get("Access-Control-Allow-Methods"); got != http.MethodPut {
+			return fmt.Errorf("expected allowed methods %v, instead got %v", http.MethodPut, got)
+		}
+		return nil
+	})
+}
+
+func PutBucketWebsite_missing_index_document(s *S3Conf) error {
+	testName := "PutBucketWebsite_missing_index_document"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err := s3client.PutBucketWebsite(ctx, &s3.PutBucketWebsiteInput{
+			Bucket: &bucket,
+			WebsiteConfiguration: &types.WebsiteConfiguration{
+				ErrorDocument: &types.ErrorDocument{
+					Key: getPtr("error.html"),
+				},
+			},
+		})
+		cancel()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrInvalidWebsiteConfiguration)); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+func GetBucketWebsite_not_found(s *S3Conf) error {
+	testName := "GetBucketWebsite_not_found"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err := s3client.GetBucketWebsite(ctx, &s3.GetBucketWebsiteInput{
+			Bucket: &bucket,
+		})
+		cancel()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrNoSuchWebsiteConfiguration)); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+func PutBucketWebsite_success(s *S3Conf) error {
+	testName := "PutBucketWebsite_success"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err := s3client.PutBucketWebsite(ctx, &s3.PutBucketWebsiteInput{
+			Bucket: &bucket,
+			WebsiteConfiguration: &types.WebsiteConfiguration{
+				IndexDocument: &types.IndexDocument{
+					Suffix: getPtr("index.html"),
+				},
+				ErrorDocument: &types.ErrorDocument{
+					Key: getPtr("error.html"),
+				},
+				RoutingRules: []types.RoutingRule{
+					{
+						Condition: &types.Condition{
+							KeyPrefixEquals: getPtr("docs/"),
+						},
+						Redirect: &types.Redirect{
+							ReplaceKeyPrefixWith: getPtr("documents/"),
+						},
+					},
+				},
+			},
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		out, err := s3client.GetBucketWebsite(ctx, &s3.GetBucketWebsiteInput{
+			Bucket: &bucket,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		if out.IndexDocument == nil || getString(out.IndexDocument.Suffix) != "index.html" {
+			return fmt.Errorf("expected index document suffix index.html")
+		}
+		if out.ErrorDocument == nil || getString(out.ErrorDocument.Key) != "error.html" {
+			return fmt.Errorf("expected error document key error.html")
+		}
+		if len(out.RoutingRules) != 1 {
+			return fmt.Errorf("expected 1 routing rule, instead got %v", len(out.RoutingRules))
+		}
+		rule := out.RoutingRules[0]
+		if rule.Condition == nil || getString(rule.Condition.KeyPrefixEquals) != "docs/" ||
+			rule.Redirect == nil || getString(rule.Redirect.ReplaceKeyPrefixWith) != "documents/" {
+			return fmt.Errorf("unexpected routing rule %+v", rule)
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.DeleteBucketWebsite(ctx, &s3.DeleteBucketWebsiteInput{
+			Bucket: &bucket,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.GetBucketWebsite(ctx, &s3.GetBucketWebsiteInput{
+			Bucket: &bucket,
+		})
+		cancel()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrNoSuchWebsiteConfiguration)); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+func SSE_C_PutObject_GetObject_success(s *S3Conf) error {
+	testName := "SSE_C_PutObject_GetObject_success"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		obj := "my-obj"
+		key, keyMD5 := genSSECustomerKey()
+		alg := "AES256"
+		dataLen := int64(200 * 1024)
+
+		_, data, err := putObjectWithData(dataLen, &s3.PutObjectInput{
+			Bucket:               &bucket,
+			Key:                  &obj,
+			SSECustomerAlgorithm: &alg,
+			SSECustomerKey:       &key,
+			SSECustomerKeyMD5:    &keyMD5,
+		}, s3client)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		head, err := s3client.HeadObject(ctx, &s3.HeadObjectInput{
+			Bucket:               &bucket,
+			Key:                  &obj,
+			SSECustomerAlgorithm: &alg,
+			SSECustomerKey:       &key,
+			SSECustomerKeyMD5:    &keyMD5,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+		if head.ContentLength == nil || *head.ContentLength != dataLen {
+			return fmt.Errorf("expected content length %v, instead got %v", dataLen, head.ContentLength)
+		}
+		if getString(head.SSECustomerAlgorithm) != alg || getString(head.SSECustomerKeyMD5) != keyMD5 {
+			return fmt.Errorf("expected sse-c headers %v/%v, instead got %v/%v", alg, keyMD5,
+				getString(head.SSECustomerAlgorithm), getString(head.SSECustomerKeyMD5))
+		}
+
+		ranges := []struct {
+			rng        string
+			start, end int64
+		}{
+			{"", 0, dataLen},
+			{"bytes=100000-150000", 100000, 150001},
+			{"bytes=0-0", 0, 1},
+		}
+		for _, r := range ranges {
+			input := &s3.GetObjectInput{
+				Bucket:               &bucket,
+				Key:                  &obj,
+				SSECustomerAlgorithm: &alg,
+				SSECustomerKey:       &key,
+				SSECustomerKeyMD5:    &keyMD5,
+			}
+			if r.rng != "" {
+				input.Range = &r.rng
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+			out, err := s3client.GetObject(ctx, input)
+			if err != nil {
+				cancel()
+				return err
+			}
+			body, err := io.ReadAll(out.Body)
+			out.Body.Close()
+			cancel()
+			if err != nil {
+				return err
+			}
+			if !isEqual(body, data[r.start:r.end]) {
+				return fmt.Errorf("range %q: object data mismatch", r.rng)
+			}
+		}
+
+		return nil
+	})
+}
+
+func SSE_C_GetObject_missing_key(s *S3Conf) error {
+	testName := "SSE_C_GetObject_missing_key"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		obj := "my-obj"
+		key, keyMD5 := genSSECustomerKey()
+		alg := "AES256"
+
+		_, _, err := putObjectWithData(100, &s3.PutObjectInput{
+			Bucket:               &bucket,
+			Key:                  &obj,
+			SSECustomerAlgorithm: &alg,
+			SSECustomerKey:       &key,
+			SSECustomerKeyMD5:    &keyMD5,
+		}, s3client)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.GetObject(ctx, &s3.GetObjectInput{
+			Bucket: &bucket,
+			Key:    &obj,
+		})
+		cancel()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrMissingSSECustomerKey)); err != nil {
+			return err
+		}
+
+		otherKey, otherKeyMD5 := genSSECustomerKey()
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.GetObject(ctx, &s3.GetObjectInput{
+			Bucket:               &bucket,
+			Key:                  &obj,
+			SSECustomerAlgorithm: &alg,
+			SSECustomerKey:       &otherKey,
+			SSECustomerKeyMD5:    &otherKeyMD5,
+		})
+		cancel()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrIncorrectSSECustomerKey)); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+func SSE_C_PutObject_invalid_key(s *S3Conf) error {
+	testName := "SSE_C_PutObject_invalid_key"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		obj := "my-obj"
+		key, _ := genSSECustomerKey()
+		_, otherKeyMD5 := genSSECustomerKey()
+		alg := "AES256"
+
+		_, _, err := putObjectWithData(100, &s3.PutObjectInput{
+			Bucket:               &bucket,
+			Key:                  &obj,
+			SSECustomerAlgorithm: &alg,
+			SSECustomerKey:       &key,
+			SSECustomerKeyMD5:    &otherKeyMD5,
+		}, s3client)
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrSSECustomerKeyMD5Mismatch)); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+func SSE_C_CompleteMultipartUpload_success(s *S3Conf) error {
+	testName := "SSE_C_CompleteMultipartUpload_success"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		obj := "my-obj"
+		key, keyMD5 := genSSECustomerKey()
+		alg := "AES256"
+
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		mp, err := s3client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+			Bucket:               &bucket,
+			Key:                  &obj,
+			SSECustomerAlgorithm: &alg,
+			SSECustomerKey:       &key,
+			SSECustomerKeyMD5:    &keyMD5,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		partSize := 100 * 1024
+		data := make([]byte, 2*partSize+100)
+		rand.Read(data)
+
+		var parts []types.CompletedPart
+		for i := 0; i*partSize < len(data); i++ {
+			end := min((i+1)*partSize, len(data))
+			pn := int32(i + 1)
+			ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+			out, err := s3client.UploadPart(ctx, &s3.UploadPartInput{
+				Bucket:               &bucket,
+				Key:                  &obj,
+				UploadId:             mp.UploadId,
+				PartNumber:           &pn,
+				Body:                 bytes.NewReader(data[i*partSize : end]),
+				SSECustomerAlgorithm: &alg,
+				SSECustomerKey:       &key,
+				SSECustomerKeyMD5:    &keyMD5,
+			})
+			cancel()
+			if err != nil {
+				return err
+			}
+			parts = append(parts, types.CompletedPart{
+				ETag:       out.ETag,
+				PartNumber: &pn,
+			})
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+			Bucket:   &bucket,
+			Key:      &obj,
+			UploadId: mp.UploadId,
+			MultipartUpload: &types.CompletedMultipartUpload{
+				Parts: parts,
+			},
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		rng := fmt.Sprintf("bytes=%v-%v", partSize-10, 2*partSize+10)
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		out, err := s3client.GetObject(ctx, &s3.GetObjectInput{
+			Bucket:               &bucket,
+			Key:                  &obj,
+			Range:                &rng,
+			SSECustomerAlgorithm: &alg,
+			SSECustomerKey:       &key,
+			SSECustomerKeyMD5:    &keyMD5,
+		})
+		if err != nil {
+			cancel()
+			return err
+		}
+		body, err := io.ReadAll(out.Body)
+		out.Body.Close()
+		cancel()
+		if err != nil {
+			return err
+		}
+		if !isEqual(body, data[partSize-10:2*partSize+11]) {
+			return fmt.Errorf("object data mismatch")
+		}
+
+		return nil
+	})
+}
+
+func SSE_C_UploadPart_unencrypted_upload(s *S3Conf) error {
+	testName := "SSE_C_UploadPart_unencrypted_upload"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		obj := "my-obj"
+		key, keyMD5 := genSSECustomerKey()
+		alg := "AES256"
+
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		mp, err := s3client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+			Bucket: &bucket,
+			Key:    &obj,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:               &bucket,
+			Key:                  &obj,
+			UploadId:             mp.UploadId,
+			PartNumber:           aws.Int32(1),
+			Body:                 bytes.NewReader([]byte("data")),
+			SSECustomerAlgorithm: &alg,
+			SSECustomerKey:       &key,
+			SSECustomerKeyMD5:    &keyMD5,
+		})
+		cancel()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrSSECustomerKeyNotApplicable)); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+func SSE_S3_PutObject_GetObject_success(s *S3Conf) error {
+	testName := "SSE_S3_PutObject_GetObject_success"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		obj := "my-obj"
+		dataLen := int64(200 * 1024)
+
+		_, data, err := putObjectWithData(dataLen, &s3.PutObjectInput{
+			Bucket:               &bucket,
+			Key:                  &obj,
+			ServerSideEncryption: types.ServerSideEncryptionAes256,
+		}, s3client)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		out, err := s3client.GetObject(ctx, &s3.GetObjectInput{
+			Bucket: &bucket,
+			Key:    &obj,
+		})
+		if err != nil {
+			cancel()
+			return err
+		}
+		body, err := io.ReadAll(out.Body)
+		out.Body.Close()
+		cancel()
+		if err != nil {
+			return err
+		}
+		if out.ServerSideEncryption != types.ServerSideEncryptionAes256 {
+			return fmt.Errorf("expected the server side encryption %v, instead got %v",
+				types.ServerSideEncryptionAes256, out.ServerSideEncryption)
+		}
+		if !isEqual(body, data) {
+			return fmt.Errorf("object data mismatch")
+		}
+
+		return nil
+	})
+}
+
+func SSE_S3_UploadPart_reupload_success(s *S3Conf) error {
+	testName := "SSE_S3_UploadPart_reupload_success"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		obj := "my-obj"
+
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		mp, err := s3client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+			Bucket:               &bucket,
+			Key:                  &obj,
+			ServerSideEncryption: types.ServerSideEncryptionAes256,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		// the second upload of the part replaces the first one, each
+		// upload must be sealed with its own segment key
+		pn := int32(1)
+		var data []byte
+		var etag *string
+		for i := 0; i < 2; i++ {
+			data = make([]byte, 100*1024)
+			rand.Read(data)
+			ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+			out, err := s3client.UploadPart(ctx, &s3.UploadPartInput{
+				Bucket:     &bucket,
+				Key:        &obj,
+				UploadId:   mp.UploadId,
+				PartNumber: &pn,
+				Body:       bytes.NewReader(data),
+			})
+			cancel()
+			if err != nil {
+				return err
+			}
+			etag = out.ETag
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+			Bucket:   &bucket,
+			Key:      &obj,
+			UploadId: mp.UploadId,
+			MultipartUpload: &types.CompletedMultipartUpload{
+				Parts: []types.CompletedPart{
+					{ETag: etag, PartNumber: &pn},
+				},
+			},
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		out, err := s3client.GetObject(ctx, &s3.GetObjectInput{
+			Bucket: &bucket,
+			Key:    &obj,
+		})
+		if err != nil {
+			cancel()
+			return err
+		}
+		body, err := io.ReadAll(out.Body)
+		out.Body.Close()
+		cancel()
+		if err != nil {
+			return err
+		}
+		if !isEqual(body, data) {
+			return fmt.Errorf("expected the data of the last part upload")
+		}
+
+		return nil
+	})
+}
+
+func selectObjectRecords(client *s3.Client, input *s3.SelectObjectContentInput) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+	defer cancel()
+	out, err := client.SelectObjectContent(ctx, input)
+	if err != nil {
+		return "", err
+	}
+	stream := out.GetStream()
+	defer stream.Close()
+
+	var records strings.Builder
+	for ev := range stream.Events() {
+		if rec, ok := ev.(*types.SelectObjectContentEventStreamMemberRecords); ok {
+			records.Write(rec.Value.Payload)
+		}
+	}
+	return records.String(), stream.Err()
+}
+
+func SelectObjectContent_csv_success(s *S3Conf) error {
+	testName := "SelectObjectContent_csv_success"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		obj := "data.csv"
+		data := "name,age\nalice,34\nbob,27\ncarol,45\n"
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err := s3client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket: &bucket,
+			Key:    &obj,
+			Body:   strings.NewReader(data),
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		records, err := selectObjectRecords(s3client, &s3.SelectObjectContentInput{
+			Bucket:         &bucket,
+			Key:            &obj,
+			Expression:     getPtr("SELECT s.name FROM S3Object s WHERE CAST(s.age AS INT) > 30"),
+			ExpressionType: types.ExpressionTypeSql,
+			InputSerialization: &types.InputSerialization{
+				CSV: &types.CSVInput{FileHeaderInfo: types.FileHeaderInfoUse},
+			},
+			OutputSerialization: &types.OutputSerialization{
+				JSON: &types.JSONOutput{},
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		expected := "{\"name\":\"alice\"}\n{\"name\":\"carol\"}\n"
+		if records != expected {
+			return fmt.Errorf("expected records %q, instead got %q", expected, records)
+		}
+		return nil
+	})
+}
+
+func SelectObjectContent_invalid_expression(s *S3Conf) error {
+	testName := "SelectObjectContent_invalid_expression"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		obj := "data.json"
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err := s3client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket: &bucket,
+			Key:    &obj,
+			Body:   strings.NewReader("{\"a\":1}\n"),
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		_, err = selectObjectRecords(s3client, &s3.SelectObjectContentInput{
+			Bucket:         &bucket,
+			Key:            &obj,
+			Expression:     getPtr("SELECT FROM S3Object"),
+			ExpressionType: types.ExpressionTypeSql,
+			InputSerialization: &types.InputSerialization{
+				JSON: &types.JSONInput{Type: types.JSONTypeLines},
+			},
+			OutputSerialization: &types.OutputSerialization{
+				JSON: &types.JSONOutput{},
+			},
+		})
+		if err == nil {
+			return fmt.Errorf("expected ParseUnexpectedToken, instead got nil")
+		}
+		return checkSdkApiErr(err, "ParseUnexpectedToken")
+	})
+}
+
+func PostObject_success(s *S3Conf) error {
+	testName := "PostObject_success"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		data := []byte("uploaded with a form")
+		resp, err := sendPostObjectForm(s, bucket, map[string]string{
+			"key":                   "uploads/${filename}",
+			"success_action_status": "201",
+			"x-amz-meta-origin":     "portal",
+		}, []string{
+			`["starts-with", "$key", "uploads/"]`,
+			`{"success_action_status": "201"}`,
+			`["starts-with", "$x-amz-meta-origin", ""]`,
+			`["content-length-range", 1, 1024]`,
+		}, data)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusCreated {
+			return fmt.Errorf("expected response status code to be %v, instead got %v", http.StatusCreated, resp.StatusCode)
+		}
+		var postResp s3response.PostResponse
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		if err := xml.Unmarshal(body, &postResp); err != nil {
+			return err
+		}
+		if postResp.Bucket != bucket || postResp.Key != "uploads/upload.txt" {
+			return fmt.Errorf("expected bucket %v and key uploads/upload.txt, instead got %v and %v", bucket, postResp.Bucket, postResp.Key)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		out, err := s3client.GetObject(ctx, &s3.GetObjectInput{
+			Bucket: &bucket,
+			Key:    &postResp.Key,
+		})
+		defer cancel()
+		if err != nil {
+			return err
+		}
+		defer out.Body.Close()
+
+		got, err := io.ReadAll(out.Body)
+		if err != nil {
+			return err
+		}
+		if !isEqual(got, data) {
+			return fmt.Errorf("expected the object data to be %q, instead got %q", data, got)
+		}
+		if out.Metadata["origin"] != "portal" {
+			return fmt.Errorf("expected the metadata origin to be portal, instead got %v", out.Metadata)
+		}
+		if getString(out.ETag) != postResp.ETag {
+			return fmt.Errorf("expected the object etag to be %v, instead got %v", postResp.ETag, getString(out.ETag))
+		}
+		return nil
+	})
+}
+
+func PostObject_policy_condition_failed(s *S3Conf) error {
+	testName := "PostObject_policy_condition_failed"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		resp, err := sendPostObjectForm(s, bucket, map[string]string{
+			"key": "other/my-obj",
+		}, []string{
+			`["starts-with", "$key", "uploads/"]`,
+		}, []byte("data"))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		return checkAuthErr(resp, s3err.APIError{
+			Code:           "AccessDenied",
+			Description:    `Invalid according to Policy: Policy Condition failed: ["starts-with", "$key", "uploads/"]`,
+			HTTPStatusCode: http.StatusForbidden,
+		})
+	})
+}
+
+func PostObject_entity_too_large(s *S3Conf) error {
+	testName := "PostObject_entity_too_large"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		resp, err := sendPostObjectForm(s, bucket, map[string]string{
+			"key": "my-obj",
+		}, []string{
+			`{"key": "my-obj"}`,
+			`["content-length-range", 0, 3]`,
+		}, []byte("data"))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		return checkAuthErr(resp, s3err.GetAPIError(s3err.ErrEntityTooLarge))
+	})
+}
+
+func PostObject_invalid_signature(s *S3Conf) error {
+	testName := "PostObject_invalid_signature"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		cfg := *s
+		cfg.awsSecret = s.awsSecret + "a"
+		resp, err := sendPostObjectForm(&cfg, bucket, map[string]string{
+			"key": "my-obj",
+		}, []string{
+			`{"key": "my-obj"}`,
+		}, []byte("data"))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		return checkAuthErr(resp, s3err.GetAPIError(s3err.ErrSignatureDoesNotMatch))
+	})
+}
+
+func PutObject_checksum_success(s *S3Conf) error {
+	testName := "PutObject_checksum_success"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		obj := "my-obj"
+		data := []byte("checksummed object data")
+		crc := crc32.ChecksumIEEE(data)
+		expected := base64.StdEncoding.EncodeToString(
+			[]byte{byte(crc >> 24), byte(crc >> 16), byte(crc >> 8), byte(crc)})
+
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		out, err := s3client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:            &bucket,
+			Key:               &obj,
+			Body:              bytes.NewReader(data),
+			ChecksumAlgorithm: types.ChecksumAlgorithmCrc32,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+		if getString(out.ChecksumCRC32) != expected {
+			return fmt.Errorf("expected the crc32 checksum to be %v, instead got %v", expected, getString(out.ChecksumCRC32))
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		head, err := s3client.HeadObject(ctx, &s3.HeadObjectInput{
+			Bucket:       &bucket,
+			Key:          &obj,
+			ChecksumMode: types.ChecksumModeEnabled,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+		if getString(head.ChecksumCRC32) != expected {
+			return fmt.Errorf("expected the head object crc32 checksum to be %v, instead got %v", expected, getString(head.ChecksumCRC32))
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		head, err = s3client.HeadObject(ctx, &s3.HeadObjectInput{
+			Bucket: &bucket,
+			Key:    &obj,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+		if head.ChecksumCRC32 != nil {
+			return fmt.Errorf("expected no checksum without checksum mode, instead got %v", *head.ChecksumCRC32)
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		get, err := s3client.GetObject(ctx, &s3.GetObjectInput{
+			Bucket:       &bucket,
+			Key:          &obj,
+			ChecksumMode: types.ChecksumModeEnabled,
+		})
+		defer cancel()
+		if err != nil {
+			return err
+		}
+		body, err := io.ReadAll(get.Body)
+		get.Body.Close()
+		if err != nil {
+			return err
+		}
+		if !isEqual(body, data) {
+			return fmt.Errorf("expected the object data to be %s, instead got %s", data, body)
+		}
+		if getString(get.ChecksumCRC32) != expected {
+			return fmt.Errorf("expected the get object crc32 checksum to be %v, instead got %v", expected, getString(get.ChecksumCRC32))
+		}
+
+		return nil
+	})
+}
+
+func PutObject_checksum_mismatch(s *S3Conf) error {
+	testName := "PutObject_checksum_mismatch"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		obj := "my-obj"
+		sum := sha256.Sum256([]byte("other data"))
+
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err := s3client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:         &bucket,
+			Key:            &obj,
+			Body:           strings.NewReader("object data"),
+			ChecksumSHA256: getPtr(base64.StdEncoding.EncodeToString(sum[:])),
+		})
+		cancel()
+		if err := checkSdkApiErr(err, "BadDigest"); err != nil {
+			return err
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.HeadObject(ctx, &s3.HeadObjectInput{
+			Bucket: &bucket,
+			Key:    &obj,
+		})
+		cancel()
+		if err := checkSdkApiErr(err, "NotFound"); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+func CompleteMultipartUpload_checksum_success(s *S3Conf) error {
+	testName := "CompleteMultipartUpload_checksum_success"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		obj := "my-obj"
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		mp, err := s3client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+			Bucket:            &bucket,
+			Key:               &obj,
+			ChecksumAlgorithm: types.ChecksumAlgorithmSha256,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+		if mp.ChecksumAlgorithm != types.ChecksumAlgorithmSha256 {
+			return fmt.Errorf("expected the upload checksum algorithm to be SHA256, instead got %v", mp.ChecksumAlgorithm)
+		}
+
+		partData := [][]byte{
+			bytes.Repeat([]byte("a"), 5*1024*1024),
+			[]byte("last part"),
+		}
+		composite := sha256.New()
+		var compParts []types.CompletedPart
+		for i, data := range partData {
+			sum := sha256.Sum256(data)
+			composite.Write(sum[:])
+			expected := base64.StdEncoding.EncodeToString(sum[:])
+
+			pn := int32(i + 1)
+			ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+			out, err := s3client.UploadPart(ctx, &s3.UploadPartInput{
+				Bucket:            &bucket,
+				Key:               &obj,
+				UploadId:          mp.UploadId,
+				PartNumber:        &pn,
+				Body:              bytes.NewReader(data),
+				ChecksumAlgorithm: types.ChecksumAlgorithmSha256,
+			})
+			cancel()
+			if err != nil {
+				return err
+			}
+			if getString(out.ChecksumSHA256) != expected {
+				return fmt.Errorf("expected part %v checksum to be %v, instead got %v", pn, expected, getString(out.ChecksumSHA256))
+			}
+			compParts = append(compParts, types.CompletedPart{
+				ETag:           out.ETag,
+				PartNumber:     &pn,
+				ChecksumSHA256: out.ChecksumSHA256,
+			})
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		lp, err := s3client.ListParts(ctx, &s3.ListPartsInput{
+			Bucket:   &bucket,
+			Key:      &obj,
+			UploadId: mp.UploadId,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+		for i, part := range lp.Parts {
+			if getString(part.ChecksumSHA256) != getString(compParts[i].ChecksumSHA256) {
+				return fmt.Errorf("expected listed part %v checksum to be %v, instead got %v",
+					i+1, getString(compParts[i].ChecksumSHA256), getString(part.ChecksumSHA256))
+			}
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		res, err := s3client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+			Bucket:   &bucket,
+			Key:      &obj,
+			UploadId: mp.UploadId,
+			MultipartUpload: &types.CompletedMultipartUpload{
+				Parts: compParts,
+			},
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		expected := fmt.Sprintf("%v-%v", base64.StdEncoding.EncodeToString(composite.Sum(nil)), len(partData))
+		if getString(res.ChecksumSHA256) != expected {
+			return fmt.Errorf("expected the object checksum to be %v, instead got %v", expected, getString(res.ChecksumSHA256))
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		attrs, err := s3client.GetObjectAttributes(ctx, &s3.GetObjectAttributesInput{
+			Bucket: &bucket,
+			Key:    &obj,
+			ObjectAttributes: []types.ObjectAttributes{
+				types.ObjectAttributesChecksum,
+				types.ObjectAttributesObjectSize,
+			},
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+		if attrs.Checksum == nil || getString(attrs.Checksum.ChecksumSHA256) != expected {
+			return fmt.Errorf("expected the object attributes checksum to be %v, instead got %+v", expected, attrs.Checksum)
+		}
+		size := int64(len(partData[0]) + len(partData[1]))
+		if attrs.ObjectSize == nil || *attrs.ObjectSize != size {
+			return fmt.Errorf("expected the object size to be %v, instead got %v", size, attrs.ObjectSize)
+		}
+		if attrs.ETag != nil {
+			return fmt.Errorf("expected no etag attribute, instead got %v", *attrs.ETag)
+		}
+
+		return nil
+	})
+}
+
+func UploadPart_checksum_algorithm_mismatch(s *S3Conf) error {
+	testName := "UploadPart_checksum_algorithm_mismatch"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		obj := "my-obj"
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		mp, err := s3client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+			Bucket:            &bucket,
+			Key:               &obj,
+			ChecksumAlgorithm: types.ChecksumAlgorithmCrc32c,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		pn := int32(1)
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:            &bucket,
+			Key:               &obj,
+			UploadId:          mp.UploadId,
+			PartNumber:        &pn,
+			Body:              strings.NewReader("part data"),
+			ChecksumAlgorithm: types.ChecksumAlgorithmSha1,
+		})
+		cancel()
+		if err := checkSdkApiErr(err, "InvalidRequest"); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+func PutObject_trailing_checksum_success(s *S3Conf) error {
+	testName := "PutObject_trailing_checksum_success"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		data := []byte("object data with a trailing checksum")
+		crc := crc32.ChecksumIEEE(data)
+		expected := base64.StdEncoding.EncodeToString(
+			[]byte{byte(crc >> 24), byte(crc >> 16), byte(crc >> 8), byte(crc)})
+
+		for _, signed := range []bool{false, true} {
+			obj := fmt.Sprintf("my-obj-%v", signed)
+			resp, err := sendChunkedPutObject(s, bucket, obj, data, "x-amz-checksum-crc32:"+expected, signed)
+			if err != nil {
+				return err
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				return fmt.Errorf("expected the response status code to be %v, instead got %v", http.StatusOK, resp.StatusCode)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+			out, err := s3client.GetObject(ctx, &s3.GetObjectInput{
+				Bucket:       &bucket,
+				Key:          &obj,
+				ChecksumMode: types.ChecksumModeEnabled,
+			})
+			if err != nil {
+				cancel()
+				return err
+			}
+			body, err := io.ReadAll(out.Body)
+			out.Body.Close()
+			cancel()
+			if err != nil {
+				return err
+			}
+			if !isEqual(body, data) {
+				return fmt.Errorf("expected the object data to be %q, instead got %q", data, body)
+			}
+			if getString(out.ChecksumCRC32) != expected {
+				return fmt.Errorf("expected the crc32 checksum to be %v, instead got %v", expected, getString(out.ChecksumCRC32))
+			}
+		}
+
+		return nil
+	})
+}
+
+func PutObject_trailing_checksum_mismatch(s *S3Conf) error {
+	testName := "PutObject_trailing_checksum_mismatch"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		obj := "my-obj"
+		crc := crc32.ChecksumIEEE([]byte("other data"))
+		checksum := base64.StdEncoding.EncodeToString(
+			[]byte{byte(crc >> 24), byte(crc >> 16), byte(crc >> 8), byte(crc)})
+
+		resp, err := sendChunkedPutObject(s, bucket, obj, []byte("object data"), "x-amz-checksum-crc32:"+checksum, true)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			return fmt.Errorf("expected the response status code to be %v, instead got %v", http.StatusBadRequest, resp.StatusCode)
+		}
+		var errResp s3err.APIErrorResponse
+		if err := xml.NewDecoder(resp.Body).Decode(&errResp); err != nil {
+			return err
+		}
+		if errResp.Code != "BadDigest" {
+			return fmt.Errorf("expected error code to be BadDigest, instead got %v", errResp.Code)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.HeadObject(ctx, &s3.HeadObjectInput{
+			Bucket: &bucket,
+			Key:    &obj,
+		})
+		cancel()
+		if err := checkSdkApiErr(err, "NotFound"); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+func SigV4a_put_get_object_success(s *S3Conf) error {
+	testName := "SigV4a_put_get_object_success"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		path := fmt.Sprintf("%v/my-obj", bucket)
+		data := []byte("object data signed with sigv4a")
+		client := http.Client{
+			Timeout: shortTimeout,
+		}
+
+		for _, regionSet := range [][]string{{s.awsRegion}, {"*"}} {
+			req, err := createV4aSignedReq(s, http.MethodPut, path, regionSet, data)
+			if err != nil {
+				return err
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				return err
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				return fmt.Errorf("expected the put object status code to be %v, instead got %v", http.StatusOK, resp.StatusCode)
+			}
+		}
+
+		req, err := createV4aSignedReq(s, http.MethodGet, path, []string{"us-*", s.awsRegion}, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("expected the get object status code to be %v, instead got %v", http.StatusOK, resp.StatusCode)
+		}
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		if !isEqual(body, data) {
+			return fmt.Errorf("expected the object data to be %q, instead got %q", data, body)
+		}
+
+		return nil
+	})
+}
+
+func SigV4a_region_set_mismatch(s *S3Conf) error {
+	testName := "SigV4a_region_set_mismatch"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		req, err := createV4aSignedReq(s, http.MethodGet, bucket, []string{"invalid-region"}, nil)
+		if err != nil {
+			return err
+		}
+		client := http.Client{
+			Timeout: shortTimeout,
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		return checkAuthErr(resp, s3err.APIError{
+			Code:           "SignatureDoesNotMatch",
+			Description:    fmt.Sprintf("Credential should be scoped to a valid Region set including %v, not invalid-region", s.awsRegion),
+			HTTPStatusCode: http.StatusForbidden,
+		})
+	})
+}
+
+func SigV4a_signature_mismatch(s *S3Conf) error {
+	testName := "SigV4a_signature_mismatch"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		req, err := createV4aSignedReq(s, http.MethodPut, fmt.Sprintf("%v/my-obj", bucket), []string{s.awsRegion}, nil)
+		if err != nil {
+			return err
+		}
+		// the signed path no longer matches the request
+		req.URL.Path += "-other"
+		client := http.Client{
+			Timeout: shortTimeout,
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		return checkAuthErr(resp, s3err.GetAPIError(s3err.ErrSignatureDoesNotMatch))
+	})
+}
+
+func SigV4a_presigned_get_object_success(s *S3Conf) error {
+	testName := "SigV4a_presigned_get_object_success"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		obj := "my-obj"
+		data := []byte("presigned object data")
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err := s3client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket: &bucket,
+			Key:    &obj,
+			Body:   bytes.NewReader(data),
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		uri, err := presignV4aReq(s, http.MethodGet, fmt.Sprintf("%v/%v", bucket, obj), []string{"*"})
+		if err != nil {
+			return err
+		}
+		client := http.Client{
+			Timeout: shortTimeout,
+		}
+		resp, err := client.Get(uri)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("expected the response status code to be %v, instead got %v", http.StatusOK, resp.StatusCode)
+		}
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		if !isEqual(body, data) {
+			return fmt.Errorf("expected the object data to be %q, instead got %q", data, body)
+		}
+
+		return nil
+	})
+}
+
+func SigV2_put_get_object_success(s *S3Conf) error {
+	testName := "SigV2_put_get_object_success"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		path := fmt.Sprintf("%v/my-obj", bucket)
+		data := []byte("object data signed with sigv2")
+		client := http.Client{
+			Timeout: shortTimeout,
+		}
+
+		req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("%v/%v", s.endpoint, path), bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "text/plain")
+		req.Header.Set("X-Amz-Meta-Color", "blue")
+		req.Header.Set("X-Amz-Date", time.Now().UTC().Format(http.TimeFormat))
+		signature := v2Signature(s.awsSecret, v2StringToSign(req, ""))
+		req.Header.Set("Authorization", fmt.Sprintf("AWS %v:%v", s.awsID, signature))
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("expected the put object status code to be %v, instead got %v", http.StatusOK, resp.StatusCode)
+		}
+
+		req, err = createV2SignedReq(s, http.MethodGet, path, nil, time.Now())
+		if err != nil {
+			return err
+		}
+		resp, err = client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("expected the get object status code to be %v, instead got %v", http.StatusOK, resp.StatusCode)
+		}
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		if !isEqual(body, data) {
+			return fmt.Errorf("expected the object data to be %q, instead got %q", data, body)
+		}
+		if color := resp.Header.Get("X-Amz-Meta-Color"); color != "blue" {
+			return fmt.Errorf("expected the object metadata color to be blue, instead got %v", color)
+		}
+
+		return nil
+	})
+}
+
+func SigV2_sub_resource_success(s *S3Conf) error {
+	testName := "SigV2_sub_resource_success"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		obj := "my-obj"
+		err := putObjects(s3client, []string{obj}, bucket)
+		if err != nil {
+			return err
+		}
+
+		req, err := createV2SignedReq(s, http.MethodGet, fmt.Sprintf("%v/%v?tagging", bucket, obj), nil, time.Now())
+		if err != nil {
+			return err
+		}
+		client := http.Client{
+			Timeout: shortTimeout,
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("expected the get object tagging status code to be %v, instead got %v", http.StatusOK, resp.StatusCode)
+		}
+
+		return nil
+	})
+}
+
+func SigV2_signature_mismatch(s *S3Conf) error {
+	testName := "SigV2_signature_mismatch"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		req, err := createV2SignedReq(s, http.MethodGet, bucket, nil, time.Now())
+		if err != nil {
+			return err
+		}
+		// the signed date no longer matches the signature
+		req.Header.Set("Date", time.Now().Add(-10*time.Second).UTC().Format(http.TimeFormat))
+
+		client := http.Client{
+			Timeout: shortTimeout,
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		return checkAuthErr(resp, s3err.GetAPIError(s3err.ErrSignatureDoesNotMatch))
+	})
+}
+
+func SigV2_invalid_auth_header(s *S3Conf) error {
+	testName := "SigV2_invalid_auth_header"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		req, err := createV2SignedReq(s, http.MethodGet, bucket, nil, time.Now())
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "AWS "+s.awsID)
+
+		client := http.Client{
+			Timeout: shortTimeout,
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		return checkAuthErr(resp, s3err.APIError{
+			Code:           "InvalidArgument",
+			Description:    "AWS authorization header is invalid.  Expected AwsAccessKeyId:signature",
+			HTTPStatusCode: http.StatusBadRequest,
+		})
+	})
+}
+
+func SigV2_invalid_access_key(s *S3Conf) error {
+	testName := "SigV2_invalid_access_key"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		cfg := *s
+		cfg.awsID = "a_rarely_existing_access_key_id_a7s86df78as6df89790a8sd7f"
+		req, err := createV2SignedReq(&cfg, http.MethodGet, bucket, nil, time.Now())
+		if err != nil {
+			return err
+		}
+
+		client := http.Client{
+			Timeout: shortTimeout,
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		return checkAuthErr(resp, s3err.GetAPIError(s3err.ErrInvalidAccessKeyID))
+	})
+}
+
+func SigV2_expired_date(s *S3Conf) error {
+	testName := "SigV2_expired_date"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		req, err := createV2SignedReq(s, http.MethodGet, bucket, nil, time.Now().Add(-5*time.Minute))
+		if err != nil {
+			return err
+		}
+
+		client := http.Client{
+			Timeout: shortTimeout,
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusForbidden {
+			return fmt.Errorf("expected response status code to be %v, instead got %v", http.StatusForbidden, resp.StatusCode)
+		}
+
+		return nil
+	})
+}
+
+func SigV2_presigned_get_object_success(s *S3Conf) error {
+	testName := "SigV2_presigned_get_object_success"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		obj := "my-obj"
+		data := []byte("presigned with sigv2")
+		_, err := s3client.PutObject(context.Background(), &s3.PutObjectInput{
+			Bucket: &bucket,
+			Key:    &obj,
+			Body:   bytes.NewReader(data),
+		})
+		if err != nil {
+			return err
+		}
+
+		uri, err := presignV2Req(s, http.MethodGet, fmt.Sprintf("%v/%v", bucket, obj), time.Now().Add(time.Minute))
+		if err != nil {
+			return err
+		}
+
+		client := http.Client{
+			Timeout: shortTimeout,
+		}
+		resp, err := client.Get(uri)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("expected the get object status code to be %v, instead got %v", http.StatusOK, resp.StatusCode)
+		}
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		if !isEqual(body, data) {
+			return fmt.Errorf("expected the object data to be %q, instead got %q", data, body)
+		}
+
+		return nil
+	})
+}
+
+func SigV2_presigned_expired(s *S3Conf) error {
+	testName := "SigV2_presigned_expired"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		uri, err := presignV2Req(s, http.MethodGet, bucket, time.Now().Add(-time.Minute))
+		if err != nil {
+			return err
+		}
+
+		client := http.Client{
+			Timeout: shortTimeout,
+		}
+		resp, err := client.Get(uri)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		return checkAuthErr(resp, s3err.GetAPIError(s3err.ErrExpiredPresignRequest))
+	})
+}
+
+func HostStyle_put_get_object_success(s *S3Conf) error {
+	testName := "HostStyle_put_get_object_success"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		client, err := hostStyleClient(s)
+		if err != nil {
+			return err
+		}
+
+		obj, data := "my-obj", []byte("virtual hosted style object")
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err = client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket: &bucket,
+			Key:    &obj,
+			Body:   bytes.NewReader(data),
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		out, err := client.GetObject(ctx, &s3.GetObjectInput{
+			Bucket: &bucket,
+			Key:    &obj,
+		})
+		defer cancel()
+		if err != nil {
+			return err
+		}
+		defer out.Body.Close()
+		body, err := io.ReadAll(out.Body)
+		if err != nil {
+			return err
+		}
+		if !isEqual(body, data) {
+			return fmt.Errorf("expected the object data to be %q, instead got %q", data, body)
+		}
+
+		// the object is the same as the path style one
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		head, err := s3client.HeadObject(ctx, &s3.HeadObjectInput{
+			Bucket: &bucket,
+			Key:    &obj,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+		if *head.ContentLength != int64(len(data)) {
+			return fmt.Errorf("expected the object size to be %v, instead got %v", len(data), *head.ContentLength)
+		}
+
+		return nil
+	})
+}
+
+func HostStyle_list_objects_success(s *S3Conf) error {
+	testName := "HostStyle_list_objects_success"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		objs := []string{"bar", "baz", "foo/bar"}
+		err := putObjects(s3client, objs, bucket)
+		if err != nil {
+			return err
+		}
+
+		client, err := hostStyleClient(s)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		out, err := client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+			Bucket: &bucket,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+		if getString(out.Name) != bucket {
+			return fmt.Errorf("expected the bucket name to be %v, instead got %v", bucket, getString(out.Name))
+		}
+		if len(out.Contents) != len(objs) {
+			return fmt.Errorf("expected %v objects, instead got %v", len(objs), len(out.Contents))
+		}
+		for i, obj := range out.Contents {
+			if getString(obj.Key) != objs[i] {
+				return fmt.Errorf("expected the object key to be %v, instead got %v", objs[i], getString(obj.Key))
+			}
+		}
+
+		return nil
+	})
+}
+
+func HostStyle_list_buckets_success(s *S3Conf) error {
+	testName := "HostStyle_list_buckets_success"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		client, err := hostStyleClient(s)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		out, err := client.ListBuckets(ctx, &s3.ListBucketsInput{})
+		cancel()
+		if err != nil {
+			return err
+		}
+		for _, b := range out.Buckets {
+			if getString(b.Name) == bucket {
+				return nil
+			}
+		}
+
+		return fmt.Errorf("expected the bucket %v to be listed", bucket)
+	})
+}
+
+func HostStyle_presigned_get_object_success(s *S3Conf) error {
+	testName := "HostStyle_presigned_get_object_success"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		obj, data := "my-obj", []byte("presigned virtual hosted style object")
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err := s3client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket: &bucket,
+			Key:    &obj,
+			Body:   bytes.NewReader(data),
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		client, err := hostStyleClient(s)
+		if err != nil {
+			return err
+		}
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		v4req, err := s3.NewPresignClient(client).PresignGetObject(ctx, &s3.GetObjectInput{
+			Bucket: &bucket,
+			Key:    &obj,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(v4req.URL, bucket+"."+s.virtualDomain) {
+			return fmt.Errorf("expected a virtual hosted style url, instead got %v", v4req.URL)
+		}
+
+		httpClient, err := hostStyleHTTPClient(s)
+		if err != nil {
+			return err
+		}
+		resp, err := httpClient.Get(v4req.URL)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("expected the get object status code to be %v, instead got %v", http.StatusOK, resp.StatusCode)
+		}
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		if !isEqual(body, data) {
+			return fmt.Errorf("expected the object data to be %q, instead got %q", data, body)
+		}
+
+		return nil
+	})
+}
+
+func STS_GetSessionToken_success(s *S3Conf) error {
+	testName := "STS_GetSessionToken_success"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		creds, err := getSessionToken(s, 900)
+		if err != nil {
+			return err
+		}
+		if !strings.HasPrefix(getString(creds.AccessKeyId), "ASIA") {
+			return fmt.Errorf("expected a temporary access key, instead got %v", getString(creds.AccessKeyId))
+		}
+		if getString(creds.SessionToken) == "" {
+			return fmt.Errorf("expected a non-empty session token")
+		}
+		if creds.Expiration == nil || creds.Expiration.Before(time.Now().Add(14*time.Minute)) {
+			return fmt.Errorf("expected the credentials to expire in 15 minutes, instead got %v", creds.Expiration)
+		}
+
+		client := sessionClient(s, creds)
+		obj := "my-obj"
+		err = putObjects(client, []string{obj}, bucket)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		out, err := client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+			Bucket: &bucket,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+		if len(out.Contents) != 1 || getString(out.Contents[0].Key) != obj {
+			return fmt.Errorf("expected the session to list the object %v", obj)
+		}
+
+		return nil
+	})
+}
+
+func STS_AssumeRole_session_policy(s *S3Conf) error {
+	testName := "STS_AssumeRole_session_policy"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		obj := "my-obj"
+		err := putObjects(s3client, []string{obj}, bucket)
+		if err != nil {
+			return err
+		}
+
+		policy := fmt.Sprintf(`{
+			"Version": "2012-10-17",
+			"Statement": [{
+				"Effect": "Allow",
+				"Action": "s3:GetObject",
+				"Resource": "arn:aws:s3:::%v/*"
+			}]
+		}`, bucket)
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		out, err := sts.NewFromConfig(s.Config()).AssumeRole(ctx, &sts.AssumeRoleInput{
+			RoleArn:         getPtr("arn:aws:iam::123456789012:role/readonly"),
+			RoleSessionName: getPtr("ci-job"),
+			Policy:          &policy,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+		if getString(out.AssumedRoleUser.Arn) != "arn:aws:sts:::assumed-role/readonly/ci-job" {
+			return fmt.Errorf("unexpected assumed role arn %v", getString(out.AssumedRoleUser.Arn))
+		}
+
+		client := sessionClient(s, out.Credentials)
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		getOut, err := client.GetObject(ctx, &s3.GetObjectInput{
+			Bucket: &bucket,
+			Key:    &obj,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+		getOut.Body.Close()
+
+		// the session policy does not allow the object upload
+		err = putObjects(client, []string{"other-obj"}, bucket)
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrAccessDenied)); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+func STS_AssumeRole_malformed_policy(s *S3Conf) error {
+	testName := "STS_AssumeRole_malformed_policy"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err := sts.NewFromConfig(s.Config()).AssumeRole(ctx, &sts.AssumeRoleInput{
+			RoleArn:         getPtr("arn:aws:iam::123456789012:role/readonly"),
+			RoleSessionName: getPtr("ci-job"),
+			Policy:          getPtr(`{"Statement": [{"Effect": "Allow", "Action": "s3:GetObject"}]}`),
+		})
+		cancel()
+		if err == nil {
+			return fmt.Errorf("expected MalformedPolicyDocument, instead got nil")
+		}
+
+		return checkSdkApiErr(err, "MalformedPolicyDocument")
+	})
+}
+
+func STS_GetSessionToken_invalid_duration(s *S3Conf) error {
+	testName := "STS_GetSessionToken_invalid_duration"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		_, err := getSessionToken(s, 60)
+		return checkApiErr(err, s3err.APIError{
+			Code:        "ValidationError",
+			Description: "1 validation error detected: Value '60' at 'durationSeconds' failed to satisfy constraint: Member must have value greater than or equal to 900",
+		})
+	})
+}
+
+func STS_GetSessionToken_with_session_credentials(s *S3Conf) error {
+	testName := "STS_GetSessionToken_with_session_credentials"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		creds, err := getSessionToken(s, 900)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err = sts.NewFromConfig(s.Config(), func(o *sts.Options) {
+			o.Credentials = credentials.NewStaticCredentialsProvider(
+				getString(creds.AccessKeyId), getString(creds.SecretAccessKey), getString(creds.SessionToken))
+		}).GetSessionToken(ctx, &sts.GetSessionTokenInput{})
+		cancel()
+
+		return checkApiErr(err, s3err.APIError{
+			Code:        "AccessDenied",
+			Description: "Cannot call GetSessionToken with session credentials",
+		})
+	})
+}
+
+func STS_GetSessionToken_presigned_session_credentials(s *S3Conf) error {
+	testName := "STS_GetSessionToken_presigned_session_credentials"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		creds, err := getSessionToken(s, 900)
+		if err != nil {
+			return err
+		}
+
+		req, err := http.NewRequest(http.MethodPost,
+			s.endpoint+"/?Action=GetSessionToken&Version=2011-06-15&X-Amz-Expires=60", nil)
+		if err != nil {
+			return err
+		}
+		// the session token is signed in the query of the presigned url
+		uri, _, err := v4.NewSigner().PresignHTTP(req.Context(), aws.Credentials{
+			AccessKeyID:     getString(creds.AccessKeyId),
+			SecretAccessKey: getString(creds.SecretAccessKey),
+			SessionToken:    getString(creds.SessionToken),
+		}, req, "UNSIGNED-PAYLOAD", "s3", s.awsRegion, time.Now())
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(uri, "X-Amz-Security-Token=") {
+			return fmt.Errorf("expected the presigned url to include the session token")
+		}
+
+		client := http.Client{
+			Timeout: shortTimeout,
+		}
+		resp, err := client.Post(uri, "", nil)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusForbidden || !strings.Contains(string(body), "AccessDenied") {
+			return fmt.Errorf("expected AccessDenied, instead got %v: %s", resp.StatusCode, body)
+		}
+
+		return nil
+	})
+}
+
+func STS_invalid_session_token(s *S3Conf) error {
+	testName := "STS_invalid_session_token"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		creds, err := getSessionToken(s, 900)
+		if err != nil {
+			return err
+		}
+		creds.SessionToken = getPtr(getString(creds.SessionToken) + "x")
+
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err = sessionClient(s, creds).HeadBucket(ctx, &s3.HeadBucketInput{
+			Bucket: &bucket,
+		})
+		cancel()
+		if err == nil {
+			return fmt.Errorf("expected the invalid session token to be rejected")
+		}
+		// HeadBucket errors have no body
+		var re *awshttp.ResponseError
+		if !errors.As(err, &re) || re.HTTPStatusCode() != http.StatusBadRequest {
+			return fmt.Errorf("expected the response status code to be %v, instead got %v", http.StatusBadRequest, err)
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = sessionClient(s, creds).ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+			Bucket: &bucket,
+		})
+		cancel()
+
+		return checkApiErr(err, s3err.GetAPIError(s3err.ErrInvalidToken))
+	})
+}
+
+func STS_session_credentials_presigned(s *S3Conf) error {
+	testName := "STS_session_credentials_presigned"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		obj, data := "my-obj", []byte("presigned with session credentials")
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err := s3client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket: &bucket,
+			Key:    &obj,
+			Body:   bytes.NewReader(data),
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		creds, err := getSessionToken(s, 900)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		v4req, err := s3.NewPresignClient(sessionClient(s, creds)).PresignGetObject(ctx, &s3.GetObjectInput{
+			Bucket: &bucket,
+			Key:    &obj,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(v4req.URL, "X-Amz-Security-Token=") {
+			return fmt.Errorf("expected the presigned url to include the session token")
+		}
+
+		client := http.Client{
+			Timeout: shortTimeout,
+		}
+		resp, err := client.Get(v4req.URL)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("expected the get object status code to be %v, instead got %v", http.StatusOK, resp.StatusCode)
+		}
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		if !isEqual(body, data) {
+			return fmt.Errorf("expected the object data to be %q, instead got %q", data, body)
+		}
+
+		return nil
+	})
+}
+
+func STS_AssumeRoleWithWebIdentity_invalid_token(s *S3Conf) error {
+	testName := "STS_AssumeRoleWithWebIdentity_invalid_token"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		_, err := assumeRoleWithWebIdentity(s, "invalid-token")
+		if err == nil {
+			return fmt.Errorf("expected InvalidIdentityToken, instead got nil")
+		}
+
+		return checkSdkApiErr(err, "InvalidIdentityToken")
+	})
+}
+
+func OIDC_AssumeRoleWithWebIdentity_success(s *S3Conf) error {
+	testName := "OIDC_AssumeRoleWithWebIdentity_success"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		subject := "oidc-" + genRandString(8)
+		token, err := webIdentityToken(s, jwt.MapClaims{"sub": subject})
+		if err != nil {
+			return err
+		}
+
+		out, err := assumeRoleWithWebIdentity(s, token)
+		if err != nil {
+			return err
+		}
+		if getString(out.SubjectFromWebIdentityToken) != subject {
+			return fmt.Errorf("expected the subject %v, instead got %v", subject, getString(out.SubjectFromWebIdentityToken))
+		}
+		if getString(out.Provider) != s.oidcIssuer {
+			return fmt.Errorf("expected the provider %v, instead got %v", s.oidcIssuer, getString(out.Provider))
+		}
+		if getString(out.AssumedRoleUser.Arn) != "arn:aws:sts:::assumed-role/web-identity/web-session" {
+			return fmt.Errorf("unexpected assumed role arn %v", getString(out.AssumedRoleUser.Arn))
+		}
+		if !strings.HasPrefix(getString(out.Credentials.AccessKeyId), "ASIA") {
+			return fmt.Errorf("expected a temporary access key, instead got %v", getString(out.Credentials.AccessKeyId))
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err = sessionClient(s, out.Credentials).ListBuckets(ctx, &s3.ListBucketsInput{})
+		cancel()
+		return err
+	})
+}
+
+func OIDC_identity_without_account_access_denied(s *S3Conf) error {
+	testName := "OIDC_identity_without_account_access_denied"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		token, err := webIdentityToken(s, jwt.MapClaims{"sub": "oidc-" + genRandString(8)})
+		if err != nil {
+			return err
+		}
+
+		out, err := assumeRoleWithWebIdentity(s, token)
+		if err != nil {
+			return err
+		}
+
+		// the bucket is owned by the root account
+		err = putObjects(sessionClient(s, out.Credentials), []string{"my-obj"}, bucket)
+		return checkApiErr(err, s3err.GetAPIError(s3err.ErrAccessDenied))
+	})
+}
+
+func OIDC_identity_mapped_to_account(s *S3Conf) error {
+	testName := "OIDC_identity_mapped_to_account"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		usr := user{
+			access: "oidc-mapped-user",
+			secret: "oidc-mapped-secret",
+			role:   "user",
+		}
+		err := createUsers(s, []user{usr})
+		if err != nil {
+			return err
+		}
+		err = changeBucketsOwner(s, []string{bucket}, usr.access)
+		if err != nil {
+			return err
+		}
+
+		token, err := webIdentityToken(s, jwt.MapClaims{"sub": usr.access})
+		if err != nil {
+			return err
+		}
+		out, err := assumeRoleWithWebIdentity(s, token)
+		if err != nil {
+			return err
+		}
+
+		// the session has the permissions of the mapped account
+		err = putObjects(sessionClient(s, out.Credentials), []string{"my-obj"}, bucket)
+		if err != nil {
+			return err
+		}
+
+		return deleteUser(s, usr.access)
+	})
+}
+
+func OIDC_root_account_denied(s *S3Conf) error {
+	testName := "OIDC_root_account_denied"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		token, err := webIdentityToken(s, jwt.MapClaims{"sub": s.awsID})
+		if err != nil {
+			return err
+		}
+
+		// the web identity is never granted the root account
+		out, err := assumeRoleWithWebIdentity(s, token)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err = sessionClient(s, out.Credentials).ListBuckets(ctx, &s3.ListBucketsInput{})
+		cancel()
+		return checkApiErr(err, s3err.GetAPIError(s3err.ErrInvalidToken))
+	})
+}
+
+func OIDC_invalid_signature(s *S3Conf) error {
+	testName := "OIDC_invalid_signature"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return err
+		}
+		token, err := signWebIdentityToken(s, key, jwt.MapClaims{"sub": "oidc-" + genRandString(8)})
+		if err != nil {
+			return err
+		}
+
+		_, err = assumeRoleWithWebIdentity(s, token)
+		if err == nil {
+			return fmt.Errorf("expected InvalidIdentityToken, instead got nil")
+		}
+
+		return checkSdkApiErr(err, "InvalidIdentityToken")
+	})
+}
+
+func OIDC_invalid_issuer(s *S3Conf) error {
+	testName := "OIDC_invalid_issuer"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		token, err := webIdentityToken(s, jwt.MapClaims{
+			"sub": "oidc-" + genRandString(8),
+			"iss": "https://untrusted.example.com",
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = assumeRoleWithWebIdentity(s, token)
+		if err == nil {
+			return fmt.Errorf("expected InvalidIdentityToken, instead got nil")
+		}
+
+		return checkSdkApiErr(err, "InvalidIdentityToken")
+	})
+}
+
+func OIDC_invalid_audience(s *S3Conf) error {
+	testName := "OIDC_invalid_audience"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		token, err := webIdentityToken(s, jwt.MapClaims{
+			"sub": "oidc-" + genRandString(8),
+			"aud": "other-client",
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = assumeRoleWithWebIdentity(s, token)
+		if err == nil {
+			return fmt.Errorf("expected InvalidIdentityToken, instead got nil")
+		}
+
+		return checkSdkApiErr(err, "InvalidIdentityToken")
+	})
+}
+
+func OIDC_expired_token(s *S3Conf) error {
+	testName := "OIDC_expired_token"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		token, err := webIdentityToken(s, jwt.MapClaims{
+			"sub": "oidc-" + genRandString(8),
+			"exp": time.Now().Add(-time.Hour).Unix(),
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = assumeRoleWithWebIdentity(s, token)
+		if err == nil {
+			return fmt.Errorf("expected ExpiredTokenException, instead got nil")
+		}
+
+		return checkSdkApiErr(err, "ExpiredTokenException")
+	})
+}
+
+func IdentityPolicy_read_only_grant(s *S3Conf) error {
+	testName := "IdentityPolicy_read_only_grant"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		obj := "my-obj"
+		err := putObjects(s3client, []string{obj}, bucket)
+		if err != nil {
+			return err
+		}
+
+		usr := user{
+			access: "policyusr1",
+			secret: "policyusr1secret",
+			role:   "user",
+		}
+		err = createUsers(s, []user{usr})
+		if err != nil {
+			return err
+		}
+
+		cfg := *s
+		cfg.awsID = usr.access
+		cfg.awsSecret = usr.secret
+		userClient := s3.NewFromConfig(cfg.Config())
+
+		// the bucket is owned by the root account
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err = userClient.ListObjectsV2(ctx, &s3.ListObjectsV2Input{Bucket: &bucket})
+		cancel()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrAccessDenied)); err != nil {
+			return err
+		}
+
+		err = putUserPolicy(s, usr.access, fmt.Sprintf(`{
+			"Statement": [{
+				"Effect": "Allow",
+				"Action": ["s3:ListBucket", "s3:GetObject"],
+				"Resource": ["arn:aws:s3:::%v", "arn:aws:s3:::%v/*"]
+			}]
+		}`, bucket, bucket))
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		out, err := userClient.ListObjectsV2(ctx, &s3.ListObjectsV2Input{Bucket: &bucket})
+		cancel()
+		if err != nil {
+			return err
+		}
+		if len(out.Contents) != 1 || getString(out.Contents[0].Key) != obj {
+			return fmt.Errorf("expected the user to list the object %v", obj)
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		getOut, err := userClient.GetObject(ctx, &s3.GetObjectInput{
+			Bucket: &bucket,
+			Key:    &obj,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+		getOut.Body.Close()
+
+		// the policy only grants read access
+		err = putObjects(userClient, []string{"other-obj"}, bucket)
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrAccessDenied)); err != nil {
+			return err
+		}
+
+		return deleteUserPolicy(s, usr.access)
+	})
+}
+
+func IdentityPolicy_explicit_deny(s *S3Conf) error {
+	testName := "IdentityPolicy_explicit_deny"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		usr := user{
+			access: "policyusr2",
+			secret: "policyusr2secret",
+			role:   "user",
+		}
+		err := createUsers(s, []user{usr})
+		if err != nil {
+			return err
+		}
+		err = changeBucketsOwner(s, []string{bucket}, usr.access)
+		if err != nil {
+			return err
+		}
+
+		err = putUserPolicy(s, usr.access, `{
+			"Statement": [{
+				"Effect": "Deny",
+				"Action": "s3:DeleteObject",
+				"Resource": "arn:aws:s3:::*"
+			}]
+		}`)
+		if err != nil {
+			return err
+		}
+
+		cfg := *s
+		cfg.awsID = usr.access
+		cfg.awsSecret = usr.secret
+		userClient := s3.NewFromConfig(cfg.Config())
+
+		obj := "my-obj"
+		err = putObjects(userClient, []string{obj}, bucket)
+		if err != nil {
+			return err
+		}
+
+		// the deny applies to the bucket owner
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err = userClient.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket: &bucket,
+			Key:    &obj,
+		})
+		cancel()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrAccessDenied)); err != nil {
+			return err
+		}
+
+		return deleteUserPolicy(s, usr.access)
+	})
+}
+
+func IdentityPolicy_bucket_policy_deny(s *S3Conf) error {
+	testName := "IdentityPolicy_bucket_policy_deny"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		obj := "my-obj"
+		err := putObjects(s3client, []string{obj}, bucket)
+		if err != nil {
+			return err
+		}
+
+		usr := user{
+			access: "policyusr3",
+			secret: "policyusr3secret",
+			role:   "user",
+		}
+		err = createUsers(s, []user{usr})
+		if err != nil {
+			return err
+		}
+		err = putUserPolicy(s, usr.access, `{
+			"Statement": [{
+				"Effect": "Allow",
+				"Action": "s3:GetObject",
+				"Resource": "arn:aws:s3:::*"
+			}]
+		}`)
+		if err != nil {
+			return err
+		}
+
+		doc := genPolicyDoc("Deny", fmt.Sprintf(`"%v"`, usr.access), `"s3:GetObject"`, fmt.Sprintf(`"arn:aws:s3:::%v/*"`, bucket))
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.PutBucketPolicy(ctx, &s3.PutBucketPolicyInput{
+			Bucket: &bucket,
+			Policy: &doc,
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		cfg := *s
+		cfg.awsID = usr.access
+		cfg.awsSecret = usr.secret
+		userClient := s3.NewFromConfig(cfg.Config())
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = userClient.GetObject(ctx, &s3.GetObjectInput{
+			Bucket: &bucket,
+			Key:    &obj,
+		})
+		cancel()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrAccessDenied)); err != nil {
+			return err
+		}
+
+		return deleteUserPolicy(s, usr.access)
+	})
+}
+
+func IdentityPolicy_delete_policy(s *S3Conf) error {
+	testName := "IdentityPolicy_delete_policy"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		usr := user{
+			access: "policyusr4",
+			secret: "policyusr4secret",
+			role:   "user",
+		}
+		err := createUsers(s, []user{usr})
+		if err != nil {
+			return err
+		}
+		err = putUserPolicy(s, usr.access, fmt.Sprintf(`{
+			"Statement": [{
+				"Effect": "Allow",
+				"Action": "s3:PutObject",
+				"Resource": "arn:aws:s3:::%v/*"
+			}]
+		}`, bucket))
+		if err != nil {
+			return err
+		}
+
+		cfg := *s
+		cfg.awsID = usr.access
+		cfg.awsSecret = usr.secret
+		userClient := s3.NewFromConfig(cfg.Config())
+
+		err = putObjects(userClient, []string{"my-obj"}, bucket)
+		if err != nil {
+			return err
+		}
+
+		err = deleteUserPolicy(s, usr.access)
+		if err != nil {
+			return err
+		}
+
+		err = putObjects(userClient, []string{"other-obj"}, bucket)
+		return checkApiErr(err, s3err.GetAPIError(s3err.ErrAccessDenied))
+	})
+}
+
+func IdentityPolicy_invalid_policy(s *S3Conf) error {
+	testName := "IdentityPolicy_invalid_policy"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		usr := user{
+			access: "policyusr5",
+			secret: "policyusr5secret",
+			role:   "user",
+		}
+		err := createUsers(s, []user{usr})
+		if err != nil {
+			return err
+		}
+
+		err = putUserPolicy(s, usr.access, genPolicyDoc("Allow", `"*"`, `"s3:GetObject"`, `"arn:aws:s3:::*"`))
+		if err == nil {
+			return fmt.Errorf("expected the identity policy with a Principal to be rejected")
+		}
+		if !strings.Contains(err.Error(), "identity policies do not support Principal") {
+			return fmt.Errorf("unexpected error: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func Quota_bucket_bytes_exceeded(s *S3Conf) error {
+	testName := "Quota_bucket_bytes_exceeded"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		err := putQuota(s, "--bucket", bucket, 10, 0)
+		if err != nil {
+			return err
+		}
+
+		put := func(key string, size int) error {
+			ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+			_, err := s3client.PutObject(ctx, &s3.PutObjectInput{
+				Bucket: &bucket,
+				Key:    &key,
+				Body:   bytes.NewReader(make([]byte, size)),
+			})
+			cancel()
+			return err
+		}
+
+		if err := put("obj1", 8); err != nil {
+			return err
+		}
+		err = put("obj2", 5)
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrQuotaExceeded)); err != nil {
+			return err
+		}
+
+		// the rejected object is not written
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.HeadObject(ctx, &s3.HeadObjectInput{
+			Bucket: &bucket,
+			Key:    getPtr("obj2"),
+		})
+		cancel()
+		if err := checkSdkApiErr(err, "NotFound"); err != nil {
+			return err
+		}
+
+		// overwriting an object only counts the size difference
+		if err := put("obj1", 10); err != nil {
+			return err
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket: &bucket,
+			Key:    getPtr("obj1"),
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		return put("obj2", 5)
+	})
+}
+
+func Quota_bucket_objects_exceeded(s *S3Conf) error {
+	testName := "Quota_bucket_objects_exceeded"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		err := putQuota(s, "--bucket", bucket, 0, 2)
+		if err != nil {
+			return err
+		}
+
+		err = putObjects(s3client, []string{"obj1", "obj2"}, bucket)
+		if err != nil {
+			return err
+		}
+
+		err = putObjects(s3client, []string{"obj3"}, bucket)
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrQuotaExceeded)); err != nil {
+			return err
+		}
+
+		// overwriting an existing object does not add an object
+		err = putObjects(s3client, []string{"obj1"}, bucket)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.CopyObject(ctx, &s3.CopyObjectInput{
+			Bucket:     &bucket,
+			Key:        getPtr("obj3"),
+			CopySource: getPtr(bucket + "/obj1"),
+		})
+		cancel()
+		return checkApiErr(err, s3err.GetAPIError(s3err.ErrQuotaExceeded))
+	})
+}
+
+func Quota_multipart_upload_exceeded(s *S3Conf) error {
+	testName := "Quota_multipart_upload_exceeded"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		obj := "my-obj"
+		out, err := createMp(s3client, bucket, obj)
+		if err != nil {
+			return err
+		}
+
+		parts, err := uploadParts(s3client, 5*1024*1024, 1, bucket, obj, *out.UploadId)
+		if err != nil {
+			return err
+		}
+
+		err = putQuota(s, "--bucket", bucket, 1024, 0)
+		if err != nil {
+			return err
+		}
+
+		_, err = uploadParts(s3client, 2048, 1, bucket, obj, *out.UploadId)
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrQuotaExceeded)); err != nil {
+			return err
+		}
+
+		compParts := []types.CompletedPart{}
+		for _, p := range parts {
+			compParts = append(compParts, types.CompletedPart{
+				ETag:       p.ETag,
+				PartNumber: p.PartNumber,
+			})
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+			Bucket:   &bucket,
+			Key:      &obj,
+			UploadId: out.UploadId,
+			MultipartUpload: &types.CompletedMultipartUpload{
+				Parts: compParts,
+			},
+		})
+		cancel()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrQuotaExceeded)); err != nil {
+			return err
+		}
+
+		err = deleteQuota(s, "--bucket", bucket)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+			Bucket:   &bucket,
+			Key:      &obj,
+			UploadId: out.UploadId,
+			MultipartUpload: &types.CompletedMultipartUpload{
+				Parts: compParts,
+			},
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		size, objects, err := getQuotaUsage(s, "--bucket", bucket)
+		if err != nil {
+			return err
+		}
+		if size != 5*1024*1024 || objects != 1 {
+			return fmt.Errorf("expected the usage to be 5MiB in 1 object, instead got %v bytes in %v objects", size, objects)
+		}
+
+		return nil
+	})
+}
+
+func Quota_account_exceeded(s *S3Conf) error {
+	testName := "Quota_account_exceeded"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		usr := user{
+			access: "quotausr1",
+			secret: "quotausr1secret",
+			role:   "user",
+		}
+		err := createUsers(s, []user{usr})
+		if err != nil {
+			return err
+		}
+
+		otherBucket := getBucketName()
+		err = setup(s, otherBucket)
+		if err != nil {
+			return err
+		}
+		defer teardown(s, otherBucket)
+
+		err = changeBucketsOwner(s, []string{bucket, otherBucket}, usr.access)
+		if err != nil {
+			return err
+		}
+
+		err = putQuota(s, "--account", usr.access, 0, 3)
+		if err != nil {
+			return err
+		}
+		defer deleteQuota(s, "--account", usr.access)
+
+		err = putObjects(s3client, []string{"obj1", "obj2"}, bucket)
+		if err != nil {
+			return err
+		}
+		err = putObjects(s3client, []string{"obj1"}, otherBucket)
+		if err != nil {
+			return err
+		}
+
+		// the quota is shared by all the buckets owned by the account
+		err = putObjects(s3client, []string{"obj3"}, bucket)
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrQuotaExceeded)); err != nil {
+			return err
+		}
+		err = putObjects(s3client, []string{"obj2"}, otherBucket)
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrQuotaExceeded)); err != nil {
+			return err
+		}
+
+		_, objects, err := getQuotaUsage(s, "--account", usr.access)
+		if err != nil {
+			return err
+		}
+		if objects != 3 {
+			return fmt.Errorf("expected the account usage to be 3 objects, instead got %v", objects)
+		}
+
+		return nil
+	})
+}
+
+func Quota_invalid_target(s *S3Conf) error {
+	testName := "Quota_invalid_target"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		err := putQuota(s, "--account", "quota-non-existing-user", 10, 0)
+		if err == nil {
+			return fmt.Errorf("expected the quota of a non existing user to be rejected")
+		}
+		if !strings.Contains(err.Error(), "user specified for the quota does not exist") {
+			return fmt.Errorf("unexpected error: %w", err)
+		}
+
+		err = putQuota(s, "--bucket", bucket, -1, 0)
+		if err == nil {
+			return fmt.Errorf("expected a negative quota to be rejected")
+		}
+		if !strings.Contains(err.Error(), "limits can not be negative") {
+			return fmt.Errorf("unexpected error: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func RateLimit_account_requests_slow_down(s *S3Conf) error {
+	testName := "RateLimit_account_requests_slow_down"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		usr := user{
+			access: "ratelimitusr1",
+			secret: "ratelimitusr1secret",
+			role:   "user",
+		}
+		err := createUsers(s, []user{usr})
+		if err != nil {
+			return err
+		}
+		err = changeBucketsOwner(s, []string{bucket}, usr.access)
+		if err != nil {
+			return err
+		}
+
+		err = putRateLimit(s, usr.access, 1, 0)
+		if err != nil {
+			return err
+		}
+		defer deleteRateLimit(s, usr.access)
+
+		cfg := *s
+		cfg.awsID = usr.access
+		cfg.awsSecret = usr.secret
+		userClient := s3.NewFromConfig(cfg.Config(), func(o *s3.Options) {
+			o.Retryer = aws.NopRetryer{}
+		})
+
+		listObjects := func() error {
+			ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+			_, err := userClient.ListObjectsV2(ctx, &s3.ListObjectsV2Input{Bucket: &bucket})
+			cancel()
+			return err
+		}
+
+		if err := listObjects(); err != nil {
+			return err
+		}
+		err = listObjects()
+		if err := checkApiErr(err, s3err.GetAPIError(s3err.ErrSlowDown)); err != nil {
+			return err
+		}
+
+		// other accounts are not limited
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err = s3client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{Bucket: &bucket})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		err = deleteRateLimit(s, usr.access)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < 3; i++ {
+			if err := listObjects(); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func RateLimit_account_bandwidth_throttled(s *S3Conf) error {
+	testName := "RateLimit_account_bandwidth_throttled"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		usr := user{
+			access: "ratelimitusr2",
+			secret: "ratelimitusr2secret",
+			role:   "user",
+		}
+		err := createUsers(s, []user{usr})
+		if err != nil {
+			return err
+		}
+		err = changeBucketsOwner(s, []string{bucket}, usr.access)
+		if err != nil {
+			return err
+		}
+
+		err = putRateLimit(s, usr.access, 0, 64*1024)
+		if err != nil {
+			return err
+		}
+		defer deleteRateLimit(s, usr.access)
+
+		cfg := *s
+		cfg.awsID = usr.access
+		cfg.awsSecret = usr.secret
+		userClient := s3.NewFromConfig(cfg.Config())
+
+		// the first second of data is the burst, the rest is throttled
+		obj, data := "my-obj", make([]byte, 192*1024)
+		start := time.Now()
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		_, err = userClient.PutObject(ctx, &s3.PutObjectInput{
+			Bucket: &bucket,
+			Key:    &obj,
+			Body:   bytes.NewReader(data),
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+		if elapsed := time.Since(start); elapsed < time.Second {
+			return fmt.Errorf("expected the upload to be throttled to 64KiB/s, took %v", elapsed)
+		}
+
+		return nil
+	})
+}
+
+func RateLimit_invalid_account(s *S3Conf) error {
+	testName := "RateLimit_invalid_account"
+	return actionHandler(s, testName, func(s3client *s3.Client, bucket string) error {
+		err := putRateLimit(s, "ratelimit-non-existing-user", 1, 0)
+		if err == nil {
+			return fmt.Errorf("expected the rate limit of a non existing user to be rejected")
+		}
+		if !strings.Contains(err.Error(), "user specified for the rate limit does not exist") {
+			return fmt.Errorf("unexpected error: %w", err)
+		}
+
 		return nil
 	})
 }
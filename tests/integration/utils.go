@@ -3,26 +3,42 @@ package integration
 import (
 	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/md5"
 	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
 	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
 	"encoding/hex"
+	"encoding/pem"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
 	rnd "math/rand"
+	"mime/multipart"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"os/exec"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	ststypes "github.com/aws/aws-sdk-go-v2/service/sts/types"
 	"github.com/aws/smithy-go"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/versity/versitygw/aws/signer/v4a"
 	"github.com/versity/versitygw/s3err"
 	"github.com/versity/versitygw/s3response"
 )
@@ -33,6 +49,12 @@ var (
 	failUsrCrt           = "failed to create a user: update iam data: account already exists"
 	adminAccessDeniedMsg = "access denied: only admin users have access to this resource"
 	succDeleteUserMsg    = "The user has been deleted successfully"
+	succPutUserPolicy    = "The user policy has been updated successfully"
+	succDelUserPolicy    = "The user policy has been deleted successfully"
+	succPutQuota         = "The quota has been updated successfully"
+	succDelQuota         = "The quota has been deleted successfully"
+	succPutRateLimit     = "The rate limit has been updated successfully"
+	succDelRateLimit     = "The rate limit has been deleted successfully"
 )
 
 func getBucketName() string {
@@ -54,12 +76,17 @@ func setup(s *S3Conf, bucket string) error {
 func teardown(s *S3Conf, bucket string) error {
 	s3client := s3.NewFromConfig(s.Config())
 
+	// objects left under governance mode retention by object lock
+	// tests can still be removed by the root user
+	bypassGovernance := true
+
 	deleteObject := func(bucket, key, versionId *string) error {
 		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
 		_, err := s3client.DeleteObject(ctx, &s3.DeleteObjectInput{
-			Bucket:    bucket,
-			Key:       key,
-			VersionId: versionId,
+			Bucket:                    bucket,
+			Key:                       key,
+			VersionId:                 versionId,
+			BypassGovernanceRetention: &bypassGovernance,
 		})
 		cancel()
 		if err != nil {
@@ -91,6 +118,38 @@ func teardown(s *S3Conf, bucket string) error {
 		}
 	}
 
+	// remove any object versions and delete markers left behind
+	// in versioned buckets, not all backends support listing
+	// versions so ignore list errors here
+	vin := &s3.ListObjectVersionsInput{Bucket: &bucket}
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+		out, err := s3client.ListObjectVersions(ctx, vin)
+		cancel()
+		if err != nil {
+			break
+		}
+
+		for _, item := range out.Versions {
+			err = deleteObject(&bucket, item.Key, item.VersionId)
+			if err != nil {
+				return err
+			}
+		}
+		for _, item := range out.DeleteMarkers {
+			err = deleteObject(&bucket, item.Key, item.VersionId)
+			if err != nil {
+				return err
+			}
+		}
+
+		// everything listed was removed, so list again from the
+		// start until there are no more versions
+		if out.IsTruncated == nil || !*out.IsTruncated {
+			break
+		}
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
 	_, err := s3client.DeleteBucket(ctx, &s3.DeleteBucketInput{
 		Bucket: &bucket,
@@ -259,6 +318,61 @@ func putObjects(client *s3.Client, objs []string, bucket string) error {
 	return nil
 }
 
+func putBucketVersioningStatus(client *s3.Client, bucket string, status types.BucketVersioningStatus) error {
+	ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+	_, err := client.PutBucketVersioning(ctx, &s3.PutBucketVersioningInput{
+		Bucket: &bucket,
+		VersioningConfiguration: &types.VersioningConfiguration{
+			Status: status,
+		},
+	})
+	cancel()
+	return err
+}
+
+// getAnonymousClient returns a client that sends unsigned requests
+func getAnonymousClient(s *S3Conf) *s3.Client {
+	cfg := s.Config()
+	cfg.Credentials = aws.AnonymousCredentials{}
+	return s3.NewFromConfig(cfg)
+}
+
+func putBucketAclGrants(client *s3.Client, bucket, owner string, perm types.Permission, grantees ...string) error {
+	grants := []types.Grant{}
+	for _, grantee := range grantees {
+		grants = append(grants, types.Grant{
+			Grantee: &types.Grantee{
+				ID:   getPtr(grantee),
+				Type: types.TypeCanonicalUser,
+			},
+			Permission: perm,
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+	_, err := client.PutBucketAcl(ctx, &s3.PutBucketAclInput{
+		Bucket: &bucket,
+		AccessControlPolicy: &types.AccessControlPolicy{
+			Grants: grants,
+			Owner: &types.Owner{
+				ID: &owner,
+			},
+		},
+	})
+	cancel()
+	return err
+}
+
+func putObjectLockConfig(client *s3.Client, bucket string, config *types.ObjectLockConfiguration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+	_, err := client.PutObjectLockConfiguration(ctx, &s3.PutObjectLockConfigurationInput{
+		Bucket:                  &bucket,
+		ObjectLockConfiguration: config,
+	})
+	cancel()
+	return err
+}
+
 func putObjectWithData(lgth int64, input *s3.PutObjectInput, client *s3.Client) (csum [32]byte, data []byte, err error) {
 	data = make([]byte, lgth)
 	rand.Read(data)
@@ -283,6 +397,16 @@ func createMp(s3client *s3.Client, bucket, key string) (*s3.CreateMultipartUploa
 	return out, err
 }
 
+// genSSECustomerKey returns a random base64 encoded SSE-C key and the
+// base64 encoded MD5 of the key
+func genSSECustomerKey() (key, keyMD5 string) {
+	b := make([]byte, 32)
+	rand.Read(b)
+	sum := md5.Sum(b)
+	return base64.StdEncoding.EncodeToString(b),
+		base64.StdEncoding.EncodeToString(sum[:])
+}
+
 func isEqual(a, b []byte) bool {
 	if len(a) != len(b) {
 		return false
@@ -297,6 +421,20 @@ func isEqual(a, b []byte) bool {
 	return true
 }
 
+func areStringsSame(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i, str := range a {
+		if str != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
 func compareMultipartUploads(list1, list2 []types.MultipartUpload) bool {
 	if len(list1) != len(list2) {
 		return false
@@ -560,6 +698,121 @@ func deleteUser(s *S3Conf, access string) error {
 	return nil
 }
 
+func putUserPolicy(s *S3Conf, access, policy string) error {
+	f, err := os.CreateTemp("", "user-policy")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString(policy)
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	out, err := execCommand("admin", "-a", s.awsID, "-s", s.awsSecret, "-er", s.endpoint, "put-user-policy", "-a", access, "-p", f.Name())
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(string(out), succPutUserPolicy) {
+		return fmt.Errorf("failed to put the user policy: %s", out)
+	}
+
+	return nil
+}
+
+func deleteUserPolicy(s *S3Conf, access string) error {
+	out, err := execCommand("admin", "-a", s.awsID, "-s", s.awsSecret, "-er", s.endpoint, "delete-user-policy", "-a", access)
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(string(out), succDelUserPolicy) {
+		return fmt.Errorf("failed to delete the user policy: %s", out)
+	}
+
+	return nil
+}
+
+// putQuota sets the quota of the --bucket or --account target
+func putQuota(s *S3Conf, target, name string, maxBytes, maxObjects int64) error {
+	out, err := execCommand("admin", "-a", s.awsID, "-s", s.awsSecret, "-er", s.endpoint, "put-quota", target, name,
+		"--max-bytes", fmt.Sprint(maxBytes), "--max-objects", fmt.Sprint(maxObjects))
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(string(out), succPutQuota) {
+		return fmt.Errorf("failed to put the quota: %s", out)
+	}
+
+	return nil
+}
+
+func deleteQuota(s *S3Conf, target, name string) error {
+	out, err := execCommand("admin", "-a", s.awsID, "-s", s.awsSecret, "-er", s.endpoint, "delete-quota", target, name)
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(string(out), succDelQuota) {
+		return fmt.Errorf("failed to delete the quota: %s", out)
+	}
+
+	return nil
+}
+
+// getQuotaUsage returns the bytes and objects usage of the --bucket or
+// --account target
+func getQuotaUsage(s *S3Conf, target, name string) (int64, int64, error) {
+	out, err := execCommand("admin", "-a", s.awsID, "-s", s.awsSecret, "-er", s.endpoint, "get-quota", target, name)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get the quota: %w: %s", err, out)
+	}
+
+	var size, objects int64
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "Bytes":
+			size, err = strconv.ParseInt(fields[1], 10, 64)
+		case "Objects":
+			objects, err = strconv.ParseInt(fields[1], 10, 64)
+		}
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid get-quota output: %s", out)
+		}
+	}
+
+	return size, objects, nil
+}
+
+func putRateLimit(s *S3Conf, access string, rps float64, bps int64) error {
+	out, err := execCommand("admin", "-a", s.awsID, "-s", s.awsSecret, "-er", s.endpoint, "put-rate-limit", "-u", access,
+		"--requests-per-second", fmt.Sprint(rps), "--bytes-per-second", fmt.Sprint(bps))
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(string(out), succPutRateLimit) {
+		return fmt.Errorf("failed to put the rate limit: %s", out)
+	}
+
+	return nil
+}
+
+func deleteRateLimit(s *S3Conf, access string) error {
+	out, err := execCommand("admin", "-a", s.awsID, "-s", s.awsSecret, "-er", s.endpoint, "delete-rate-limit", "-u", access)
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(string(out), succDelRateLimit) {
+		return fmt.Errorf("failed to delete the rate limit: %s", out)
+	}
+
+	return nil
+}
+
 func changeBucketsOwner(s *S3Conf, buckets []string, owner string) error {
 	for _, bucket := range buckets {
 		out, err := execCommand("admin", "-a", s.awsID, "-s", s.awsSecret, "-er", s.endpoint, "change-bucket-owner", "-b", bucket, "-o", owner)
@@ -626,6 +879,24 @@ func genPolicyDoc(effect, principal, action, resource string) string {
 	return fmt.Sprintf(jsonTemplate, effect, principal, action, resource)
 }
 
+func genPolicyDocWithCondition(effect, principal, action, resource, condition string) string {
+	jsonTemplate := `
+	{
+		"Statement": [
+			{
+				"Effect":  "%s",
+				"Principal": %s,
+				"Action":  %s,
+				"Resource":  %s,
+				"Condition": %s
+			}
+		]
+	}
+	`
+
+	return fmt.Sprintf(jsonTemplate, effect, principal, action, resource, condition)
+}
+
 func getMalformedPolicyError(msg string) s3err.APIError {
 	return s3err.APIError{
 		Code:           "MalformedPolicy",
@@ -633,3 +904,382 @@ func getMalformedPolicyError(msg string) s3err.APIError {
 		HTTPStatusCode: http.StatusBadRequest,
 	}
 }
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// sendPostObjectForm uploads data with a browser based POST Object form,
+// the fields are completed with a policy of the conditions signed with
+// the credentials of the config
+func sendPostObjectForm(s *S3Conf, bucket string, fields map[string]string, conditions []string, data []byte) (*http.Response, error) {
+	now := time.Now().UTC()
+	date := now.Format("20060102")
+	credential := fmt.Sprintf("%v/%v/%v/s3/aws4_request", s.awsID, date, s.awsRegion)
+
+	conditions = append(conditions,
+		fmt.Sprintf(`{"bucket": %q}`, bucket),
+		`{"x-amz-algorithm": "AWS4-HMAC-SHA256"}`,
+		fmt.Sprintf(`{"x-amz-credential": %q}`, credential),
+		fmt.Sprintf(`{"x-amz-date": %q}`, now.Format(iso8601Format)),
+	)
+	policyDoc := fmt.Sprintf(`{"expiration": %q, "conditions": [%v]}`,
+		now.Add(10*time.Minute).Format(time.RFC3339), strings.Join(conditions, ","))
+	policy := base64.StdEncoding.EncodeToString([]byte(policyDoc))
+
+	key := hmacSHA256([]byte("AWS4"+s.awsSecret), date)
+	key = hmacSHA256(key, s.awsRegion)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for name, value := range fields {
+		mw.WriteField(name, value)
+	}
+	mw.WriteField("x-amz-algorithm", "AWS4-HMAC-SHA256")
+	mw.WriteField("x-amz-credential", credential)
+	mw.WriteField("x-amz-date", now.Format(iso8601Format))
+	mw.WriteField("policy", policy)
+	mw.WriteField("x-amz-signature", hex.EncodeToString(hmacSHA256(key, policy)))
+	fw, err := mw.CreateFormFile("file", "upload.txt")
+	if err != nil {
+		return nil, err
+	}
+	fw.Write(data)
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%v/%v", s.endpoint, bucket), &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	httpClient := http.Client{
+		Timeout: shortTimeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return httpClient.Do(req)
+}
+
+// sendChunkedPutObject uploads data with an aws-chunked encoded PutObject
+// request with the trailing header. The chunk and trailer signatures are
+// chained from the seed signature of the request if signed is set,
+// otherwise the payload is STREAMING-UNSIGNED-PAYLOAD-TRAILER.
+func sendChunkedPutObject(s *S3Conf, bucket, obj string, data []byte, trailer string, signed bool) (*http.Response, error) {
+	now := time.Now().UTC()
+	date := now.Format("20060102")
+	scope := fmt.Sprintf("%v/%v/s3/aws4_request", date, s.awsRegion)
+
+	key := hmacSHA256([]byte("AWS4"+s.awsSecret), date)
+	key = hmacSHA256(key, s.awsRegion)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+
+	payload := "STREAMING-UNSIGNED-PAYLOAD-TRAILER"
+	if signed {
+		payload = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD-TRAILER"
+	}
+	trailerName, _, _ := strings.Cut(trailer, ":")
+
+	encode := func(seedSig string) []byte {
+		var body bytes.Buffer
+		prevSig := seedSig
+		emptyHash := sha256.Sum256(nil)
+		for _, chunk := range [][]byte{data, nil} {
+			fmt.Fprintf(&body, "%x", len(chunk))
+			if signed {
+				chunkHash := sha256.Sum256(chunk)
+				prevSig = hex.EncodeToString(hmacSHA256(key, fmt.Sprintf(
+					"AWS4-HMAC-SHA256-PAYLOAD\n%v\n%v\n%v\n%v\n%v",
+					now.Format(iso8601Format), scope, prevSig,
+					hex.EncodeToString(emptyHash[:]), hex.EncodeToString(chunkHash[:]))))
+				fmt.Fprintf(&body, ";chunk-signature=%v", prevSig)
+			}
+			body.WriteString("\r\n")
+			body.Write(chunk)
+			if len(chunk) != 0 {
+				body.WriteString("\r\n")
+			}
+		}
+		body.WriteString(trailer + "\r\n")
+		if signed {
+			trailerHash := sha256.Sum256([]byte(trailer + "\n"))
+			fmt.Fprintf(&body, "x-amz-trailer-signature:%v\r\n",
+				hex.EncodeToString(hmacSHA256(key, fmt.Sprintf(
+					"AWS4-HMAC-SHA256-TRAILER\n%v\n%v\n%v\n%v",
+					now.Format(iso8601Format), scope, prevSig,
+					hex.EncodeToString(trailerHash[:])))))
+		}
+		body.WriteString("\r\n")
+		return body.Bytes()
+	}
+
+	// the signatures have a fixed length, so the content length is
+	// known before the request is signed
+	body := encode(strings.Repeat("0", 64))
+	req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("%v/%v/%v", s.endpoint, bucket, obj), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Encoding", "aws-chunked")
+	req.Header.Set("X-Amz-Content-Sha256", payload)
+	req.Header.Set("X-Amz-Decoded-Content-Length", fmt.Sprint(len(data)))
+	req.Header.Set("X-Amz-Trailer", trailerName)
+
+	signer := v4.NewSigner()
+	err = signer.SignHTTP(req.Context(), aws.Credentials{AccessKeyID: s.awsID, SecretAccessKey: s.awsSecret}, req, payload, "s3", s.awsRegion, now)
+	if err != nil {
+		return nil, err
+	}
+	_, seedSig, _ := strings.Cut(req.Header.Get("Authorization"), "Signature=")
+
+	body = encode(seedSig)
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+
+	httpClient := http.Client{
+		Timeout: shortTimeout,
+	}
+	return httpClient.Do(req)
+}
+
+// createV4aSignedReq returns a request signed with SigV4a for the region set
+func createV4aSignedReq(s *S3Conf, method, path string, regionSet []string, body []byte) (*http.Request, error) {
+	req, err := http.NewRequest(method, fmt.Sprintf("%v/%v", s.endpoint, path), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	creds, err := v4a.NewCredentials(s.awsID, s.awsSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	hashedPayload := sha256.Sum256(body)
+	hexPayload := hex.EncodeToString(hashedPayload[:])
+	req.Header.Set("X-Amz-Content-Sha256", hexPayload)
+
+	signer := v4a.NewSigner(func(o *v4a.SignerOptions) {
+		o.DisableURIPathEscaping = true
+	})
+	err = signer.SignHTTP(req.Context(), creds, req, hexPayload, "s3", regionSet, time.Now(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign the request: %w", err)
+	}
+
+	return req, nil
+}
+
+// presignV4aReq returns a SigV4a presigned url valid for the region set
+func presignV4aReq(s *S3Conf, method, path string, regionSet []string) (string, error) {
+	req, err := http.NewRequest(method, fmt.Sprintf("%v/%v?X-Amz-Expires=60", s.endpoint, path), nil)
+	if err != nil {
+		return "", err
+	}
+
+	creds, err := v4a.NewCredentials(s.awsID, s.awsSecret)
+	if err != nil {
+		return "", err
+	}
+
+	signer := v4a.NewSigner(func(o *v4a.SignerOptions) {
+		o.DisableURIPathEscaping = true
+	})
+	uri, _, err := signer.PresignHTTP(req.Context(), creds, req, "UNSIGNED-PAYLOAD", "s3", regionSet, time.Now(), nil)
+	return uri, err
+}
+
+// v2StringToSign returns the SigV2 string to sign of the request, date is
+// the Date header or the Expires value of presigned urls. Only the
+// sub-resources used by the tests are supported.
+func v2StringToSign(req *http.Request, date string) string {
+	var amzHeaders []string
+	for key, values := range req.Header {
+		key = strings.ToLower(key)
+		if strings.HasPrefix(key, "x-amz-") {
+			amzHeaders = append(amzHeaders, fmt.Sprintf("%v:%v\n", key, strings.Join(values, ",")))
+		}
+	}
+	sort.Strings(amzHeaders)
+
+	resource := req.URL.EscapedPath()
+	if req.URL.Query().Has("tagging") {
+		resource += "?tagging"
+	}
+
+	return fmt.Sprintf("%v\n%v\n%v\n%v\n%v%v", req.Method, req.Header.Get("Content-Md5"),
+		req.Header.Get("Content-Type"), date, strings.Join(amzHeaders, ""), resource)
+}
+
+func v2Signature(secret, strToSign string) string {
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write([]byte(strToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// createV2SignedReq returns a request signed with SigV2 in the
+// Authorization header
+func createV2SignedReq(s *S3Conf, method, path string, body []byte, date time.Time) (*http.Request, error) {
+	req, err := http.NewRequest(method, fmt.Sprintf("%v/%v", s.endpoint, path), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Date", date.UTC().Format(http.TimeFormat))
+	signature := v2Signature(s.awsSecret, v2StringToSign(req, req.Header.Get("Date")))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS %v:%v", s.awsID, signature))
+
+	return req, nil
+}
+
+// presignV2Req returns a SigV2 presigned url expiring at expires
+func presignV2Req(s *S3Conf, method, path string, expires time.Time) (string, error) {
+	req, err := http.NewRequest(method, fmt.Sprintf("%v/%v", s.endpoint, path), nil)
+	if err != nil {
+		return "", err
+	}
+
+	exp := fmt.Sprint(expires.Unix())
+	signature := v2Signature(s.awsSecret, v2StringToSign(req, exp))
+
+	query := url.Values{}
+	query.Set("AWSAccessKeyId", s.awsID)
+	query.Set("Expires", exp)
+	query.Set("Signature", signature)
+
+	return fmt.Sprintf("%v?%v", req.URL.String(), query.Encode()), nil
+}
+
+// hostStyleHTTPClient returns an http client sending the requests of any
+// host to the gateway endpoint, so that the bucket sub-domains of the
+// virtual domain do not need to be resolvable
+func hostStyleHTTPClient(s *S3Conf) (*http.Client, error) {
+	u, err := url.Parse(s.endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{}
+	tr := &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, u.Host)
+		},
+	}
+
+	return &http.Client{Transport: tr, Timeout: shortTimeout}, nil
+}
+
+// hostStyleClient returns an s3 client addressing the buckets as
+// sub-domains of the virtual domain
+func hostStyleClient(s *S3Conf) (*s3.Client, error) {
+	if s.virtualDomain == "" {
+		return nil, fmt.Errorf("the virtual domain is not specified")
+	}
+	u, err := url.Parse(s.endpoint)
+	if err != nil {
+		return nil, err
+	}
+	httpClient, err := hostStyleHTTPClient(s)
+	if err != nil {
+		return nil, err
+	}
+
+	baseEndpoint := fmt.Sprintf("%v://%v", u.Scheme, s.virtualDomain)
+	if port := u.Port(); port != "" {
+		baseEndpoint += ":" + port
+	}
+
+	return s3.NewFromConfig(s.Config(), func(o *s3.Options) {
+		// the default endpoint resolver is needed for virtual host
+		// style addressing
+		o.EndpointResolver = nil
+		o.BaseEndpoint = &baseEndpoint
+		o.HTTPClient = httpClient
+	}), nil
+}
+
+// getSessionToken returns the temporary credentials of the configured
+// account issued by the gateway security token service
+func getSessionToken(s *S3Conf, duration int32) (*ststypes.Credentials, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+	defer cancel()
+	out, err := sts.NewFromConfig(s.Config()).GetSessionToken(ctx, &sts.GetSessionTokenInput{
+		DurationSeconds: &duration,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return out.Credentials, nil
+}
+
+// sessionClient returns an s3 client signing with the session credentials
+func sessionClient(s *S3Conf, creds *ststypes.Credentials) *s3.Client {
+	return s3.NewFromConfig(s.Config(), func(o *s3.Options) {
+		o.Credentials = credentials.NewStaticCredentialsProvider(
+			getString(creds.AccessKeyId), getString(creds.SecretAccessKey), getString(creds.SessionToken))
+	})
+}
+
+// webIdentityToken returns a web identity token of the configured issuer
+// signed with the configured oidc private key. The claims override the
+// default issuer, audience and expiration.
+func webIdentityToken(s *S3Conf, claims jwt.MapClaims) (string, error) {
+	if s.oidcKeyFile == "" {
+		return "", fmt.Errorf("the oidc private key is not specified")
+	}
+	data, err := os.ReadFile(s.oidcKeyFile)
+	if err != nil {
+		return "", err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return "", fmt.Errorf("invalid oidc private key")
+	}
+
+	var key any
+	key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return "", fmt.Errorf("parse oidc private key: %w", err)
+		}
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return "", fmt.Errorf("the oidc private key is not an rsa key")
+	}
+
+	return signWebIdentityToken(s, rsaKey, claims)
+}
+
+func signWebIdentityToken(s *S3Conf, key *rsa.PrivateKey, claims jwt.MapClaims) (string, error) {
+	tokenClaims := jwt.MapClaims{
+		"iss": s.oidcIssuer,
+		"aud": s.oidcAudience,
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	for k, v := range claims {
+		tokenClaims[k] = v
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodRS256, tokenClaims).SignedString(key)
+}
+
+func assumeRoleWithWebIdentity(s *S3Conf, token string) (*sts.AssumeRoleWithWebIdentityOutput, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), shortTimeout)
+	defer cancel()
+	return sts.NewFromConfig(s.Config()).AssumeRoleWithWebIdentity(ctx, &sts.AssumeRoleWithWebIdentityInput{
+		RoleArn:          getPtr("arn:aws:iam::123456789012:role/web-identity"),
+		RoleSessionName:  getPtr("web-session"),
+		WebIdentityToken: &token,
+	})
+}
@@ -0,0 +1,444 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tracing
+
+import (
+	"bufio"
+	"context"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/versity/versitygw/backend"
+	"github.com/versity/versitygw/s3response"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Backend starts a span for each call to the wrapped backend
+type Backend struct {
+	backend.Backend
+	attrs trace.SpanStartOption
+}
+
+var _ backend.Backend = &Backend{}
+
+// NewBackend wraps be with the backend call spans
+func NewBackend(be backend.Backend) *Backend {
+	return &Backend{
+		Backend: be,
+		attrs:   trace.WithAttributes(attribute.String("backend", be.String())),
+	}
+}
+
+func (b *Backend) start(ctx context.Context, name string) (context.Context, trace.Span) {
+	return Start(ctx, "Backend."+name, b.attrs)
+}
+
+// bucket operations
+func (b *Backend) ListBuckets(ctx context.Context, owner string, isAdmin bool) (s3response.ListAllMyBucketsResult, error) {
+	ctx, span := b.start(ctx, "ListBuckets")
+	out, err := b.Backend.ListBuckets(ctx, owner, isAdmin)
+	End(span, err)
+	return out, err
+}
+
+func (b *Backend) HeadBucket(ctx context.Context, input *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
+	ctx, span := b.start(ctx, "HeadBucket")
+	out, err := b.Backend.HeadBucket(ctx, input)
+	End(span, err)
+	return out, err
+}
+
+func (b *Backend) GetBucketAcl(ctx context.Context, input *s3.GetBucketAclInput) ([]byte, error) {
+	ctx, span := b.start(ctx, "GetBucketAcl")
+	out, err := b.Backend.GetBucketAcl(ctx, input)
+	End(span, err)
+	return out, err
+}
+
+func (b *Backend) CreateBucket(ctx context.Context, input *s3.CreateBucketInput, defaultACL []byte) error {
+	ctx, span := b.start(ctx, "CreateBucket")
+	err := b.Backend.CreateBucket(ctx, input, defaultACL)
+	End(span, err)
+	return err
+}
+
+func (b *Backend) PutBucketAcl(ctx context.Context, bucket string, data []byte) error {
+	ctx, span := b.start(ctx, "PutBucketAcl")
+	err := b.Backend.PutBucketAcl(ctx, bucket, data)
+	End(span, err)
+	return err
+}
+
+func (b *Backend) DeleteBucket(ctx context.Context, input *s3.DeleteBucketInput) error {
+	ctx, span := b.start(ctx, "DeleteBucket")
+	err := b.Backend.DeleteBucket(ctx, input)
+	End(span, err)
+	return err
+}
+
+func (b *Backend) PutBucketVersioning(ctx context.Context, input *s3.PutBucketVersioningInput) error {
+	ctx, span := b.start(ctx, "PutBucketVersioning")
+	err := b.Backend.PutBucketVersioning(ctx, input)
+	End(span, err)
+	return err
+}
+
+func (b *Backend) GetBucketVersioning(ctx context.Context, bucket string) (*s3.GetBucketVersioningOutput, error) {
+	ctx, span := b.start(ctx, "GetBucketVersioning")
+	out, err := b.Backend.GetBucketVersioning(ctx, bucket)
+	End(span, err)
+	return out, err
+}
+
+func (b *Backend) PutBucketPolicy(ctx context.Context, bucket string, policy []byte) error {
+	ctx, span := b.start(ctx, "PutBucketPolicy")
+	err := b.Backend.PutBucketPolicy(ctx, bucket, policy)
+	End(span, err)
+	return err
+}
+
+func (b *Backend) GetBucketPolicy(ctx context.Context, bucket string) ([]byte, error) {
+	ctx, span := b.start(ctx, "GetBucketPolicy")
+	out, err := b.Backend.GetBucketPolicy(ctx, bucket)
+	End(span, err)
+	return out, err
+}
+
+func (b *Backend) DeleteBucketPolicy(ctx context.Context, bucket string) error {
+	ctx, span := b.start(ctx, "DeleteBucketPolicy")
+	err := b.Backend.DeleteBucketPolicy(ctx, bucket)
+	End(span, err)
+	return err
+}
+
+func (b *Backend) PutBucketLifecycleConfiguration(ctx context.Context, bucket string, config s3response.LifecycleConfiguration) error {
+	ctx, span := b.start(ctx, "PutBucketLifecycleConfiguration")
+	err := b.Backend.PutBucketLifecycleConfiguration(ctx, bucket, config)
+	End(span, err)
+	return err
+}
+
+func (b *Backend) GetBucketLifecycleConfiguration(ctx context.Context, bucket string) (s3response.LifecycleConfiguration, error) {
+	ctx, span := b.start(ctx, "GetBucketLifecycleConfiguration")
+	out, err := b.Backend.GetBucketLifecycleConfiguration(ctx, bucket)
+	End(span, err)
+	return out, err
+}
+
+func (b *Backend) DeleteBucketLifecycle(ctx context.Context, bucket string) error {
+	ctx, span := b.start(ctx, "DeleteBucketLifecycle")
+	err := b.Backend.DeleteBucketLifecycle(ctx, bucket)
+	End(span, err)
+	return err
+}
+
+func (b *Backend) PutBucketCors(ctx context.Context, bucket string, cors []byte) error {
+	ctx, span := b.start(ctx, "PutBucketCors")
+	err := b.Backend.PutBucketCors(ctx, bucket, cors)
+	End(span, err)
+	return err
+}
+
+func (b *Backend) GetBucketCors(ctx context.Context, bucket string) ([]byte, error) {
+	ctx, span := b.start(ctx, "GetBucketCors")
+	out, err := b.Backend.GetBucketCors(ctx, bucket)
+	End(span, err)
+	return out, err
+}
+
+func (b *Backend) DeleteBucketCors(ctx context.Context, bucket string) error {
+	ctx, span := b.start(ctx, "DeleteBucketCors")
+	err := b.Backend.DeleteBucketCors(ctx, bucket)
+	End(span, err)
+	return err
+}
+
+func (b *Backend) PutBucketWebsite(ctx context.Context, bucket string, website []byte) error {
+	ctx, span := b.start(ctx, "PutBucketWebsite")
+	err := b.Backend.PutBucketWebsite(ctx, bucket, website)
+	End(span, err)
+	return err
+}
+
+func (b *Backend) GetBucketWebsite(ctx context.Context, bucket string) ([]byte, error) {
+	ctx, span := b.start(ctx, "GetBucketWebsite")
+	out, err := b.Backend.GetBucketWebsite(ctx, bucket)
+	End(span, err)
+	return out, err
+}
+
+func (b *Backend) DeleteBucketWebsite(ctx context.Context, bucket string) error {
+	ctx, span := b.start(ctx, "DeleteBucketWebsite")
+	err := b.Backend.DeleteBucketWebsite(ctx, bucket)
+	End(span, err)
+	return err
+}
+
+// multipart operations
+func (b *Backend) CreateMultipartUpload(ctx context.Context, input *s3.CreateMultipartUploadInput) (*s3.CreateMultipartUploadOutput, error) {
+	ctx, span := b.start(ctx, "CreateMultipartUpload")
+	out, err := b.Backend.CreateMultipartUpload(ctx, input)
+	End(span, err)
+	return out, err
+}
+
+func (b *Backend) CompleteMultipartUpload(ctx context.Context, input *s3.CompleteMultipartUploadInput) (*s3.CompleteMultipartUploadOutput, error) {
+	ctx, span := b.start(ctx, "CompleteMultipartUpload")
+	out, err := b.Backend.CompleteMultipartUpload(ctx, input)
+	End(span, err)
+	return out, err
+}
+
+func (b *Backend) AbortMultipartUpload(ctx context.Context, input *s3.AbortMultipartUploadInput) error {
+	ctx, span := b.start(ctx, "AbortMultipartUpload")
+	err := b.Backend.AbortMultipartUpload(ctx, input)
+	End(span, err)
+	return err
+}
+
+func (b *Backend) ListMultipartUploads(ctx context.Context, input *s3.ListMultipartUploadsInput) (s3response.ListMultipartUploadsResult, error) {
+	ctx, span := b.start(ctx, "ListMultipartUploads")
+	out, err := b.Backend.ListMultipartUploads(ctx, input)
+	End(span, err)
+	return out, err
+}
+
+func (b *Backend) ListParts(ctx context.Context, input *s3.ListPartsInput) (s3response.ListPartsResult, error) {
+	ctx, span := b.start(ctx, "ListParts")
+	out, err := b.Backend.ListParts(ctx, input)
+	End(span, err)
+	return out, err
+}
+
+func (b *Backend) UploadPart(ctx context.Context, input *s3.UploadPartInput) (*s3.UploadPartOutput, error) {
+	ctx, span := b.start(ctx, "UploadPart")
+	out, err := b.Backend.UploadPart(ctx, input)
+	End(span, err)
+	return out, err
+}
+
+func (b *Backend) UploadPartCopy(ctx context.Context, input *s3.UploadPartCopyInput) (s3response.CopyObjectResult, error) {
+	ctx, span := b.start(ctx, "UploadPartCopy")
+	out, err := b.Backend.UploadPartCopy(ctx, input)
+	End(span, err)
+	return out, err
+}
+
+// standard object operations
+func (b *Backend) PutObject(ctx context.Context, input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	ctx, span := b.start(ctx, "PutObject")
+	out, err := b.Backend.PutObject(ctx, input)
+	End(span, err)
+	return out, err
+}
+
+func (b *Backend) HeadObject(ctx context.Context, input *s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+	ctx, span := b.start(ctx, "HeadObject")
+	out, err := b.Backend.HeadObject(ctx, input)
+	End(span, err)
+	return out, err
+}
+
+func (b *Backend) GetObject(ctx context.Context, input *s3.GetObjectInput, w io.Writer) (*s3.GetObjectOutput, error) {
+	ctx, span := b.start(ctx, "GetObject")
+	out, err := b.Backend.GetObject(ctx, input, w)
+	End(span, err)
+	return out, err
+}
+
+func (b *Backend) GetObjectAcl(ctx context.Context, input *s3.GetObjectAclInput) (*s3.GetObjectAclOutput, error) {
+	ctx, span := b.start(ctx, "GetObjectAcl")
+	out, err := b.Backend.GetObjectAcl(ctx, input)
+	End(span, err)
+	return out, err
+}
+
+func (b *Backend) GetObjectAttributes(ctx context.Context, input *s3.GetObjectAttributesInput) (*s3.GetObjectAttributesOutput, error) {
+	ctx, span := b.start(ctx, "GetObjectAttributes")
+	out, err := b.Backend.GetObjectAttributes(ctx, input)
+	End(span, err)
+	return out, err
+}
+
+func (b *Backend) CopyObject(ctx context.Context, input *s3.CopyObjectInput) (*s3.CopyObjectOutput, error) {
+	ctx, span := b.start(ctx, "CopyObject")
+	out, err := b.Backend.CopyObject(ctx, input)
+	End(span, err)
+	return out, err
+}
+
+func (b *Backend) ListObjects(ctx context.Context, input *s3.ListObjectsInput) (*s3.ListObjectsOutput, error) {
+	ctx, span := b.start(ctx, "ListObjects")
+	out, err := b.Backend.ListObjects(ctx, input)
+	End(span, err)
+	return out, err
+}
+
+func (b *Backend) ListObjectsV2(ctx context.Context, input *s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error) {
+	ctx, span := b.start(ctx, "ListObjectsV2")
+	out, err := b.Backend.ListObjectsV2(ctx, input)
+	End(span, err)
+	return out, err
+}
+
+func (b *Backend) DeleteObject(ctx context.Context, input *s3.DeleteObjectInput) (*s3.DeleteObjectOutput, error) {
+	ctx, span := b.start(ctx, "DeleteObject")
+	out, err := b.Backend.DeleteObject(ctx, input)
+	End(span, err)
+	return out, err
+}
+
+func (b *Backend) DeleteObjects(ctx context.Context, input *s3.DeleteObjectsInput) (s3response.DeleteResult, error) {
+	ctx, span := b.start(ctx, "DeleteObjects")
+	out, err := b.Backend.DeleteObjects(ctx, input)
+	End(span, err)
+	return out, err
+}
+
+func (b *Backend) PutObjectAcl(ctx context.Context, input *s3.PutObjectAclInput) error {
+	ctx, span := b.start(ctx, "PutObjectAcl")
+	err := b.Backend.PutObjectAcl(ctx, input)
+	End(span, err)
+	return err
+}
+
+func (b *Backend) ListObjectVersions(ctx context.Context, input *s3.ListObjectVersionsInput) (s3response.ListVersionsResult, error) {
+	ctx, span := b.start(ctx, "ListObjectVersions")
+	out, err := b.Backend.ListObjectVersions(ctx, input)
+	End(span, err)
+	return out, err
+}
+
+// special case object operations
+func (b *Backend) RestoreObject(ctx context.Context, input *s3.RestoreObjectInput) error {
+	ctx, span := b.start(ctx, "RestoreObject")
+	err := b.Backend.RestoreObject(ctx, input)
+	End(span, err)
+	return err
+}
+
+func (b *Backend) SelectObjectContent(ctx context.Context, input *s3.SelectObjectContentInput) func(w *bufio.Writer) {
+	// the results are streamed after the handler returns
+	ctx, span := b.start(ctx, "SelectObjectContent")
+	handler := b.Backend.SelectObjectContent(ctx, input)
+	return func(w *bufio.Writer) {
+		defer span.End()
+		handler(w)
+	}
+}
+
+// bucket tagging operations
+func (b *Backend) GetBucketTagging(ctx context.Context, bucket string) (map[string]string, error) {
+	ctx, span := b.start(ctx, "GetBucketTagging")
+	out, err := b.Backend.GetBucketTagging(ctx, bucket)
+	End(span, err)
+	return out, err
+}
+
+func (b *Backend) PutBucketTagging(ctx context.Context, bucket string, tags map[string]string) error {
+	ctx, span := b.start(ctx, "PutBucketTagging")
+	err := b.Backend.PutBucketTagging(ctx, bucket, tags)
+	End(span, err)
+	return err
+}
+
+func (b *Backend) DeleteBucketTagging(ctx context.Context, bucket string) error {
+	ctx, span := b.start(ctx, "DeleteBucketTagging")
+	err := b.Backend.DeleteBucketTagging(ctx, bucket)
+	End(span, err)
+	return err
+}
+
+// object tagging operations
+func (b *Backend) GetObjectTagging(ctx context.Context, bucket, object string) (map[string]string, error) {
+	ctx, span := b.start(ctx, "GetObjectTagging")
+	out, err := b.Backend.GetObjectTagging(ctx, bucket, object)
+	End(span, err)
+	return out, err
+}
+
+func (b *Backend) PutObjectTagging(ctx context.Context, bucket, object string, tags map[string]string) error {
+	ctx, span := b.start(ctx, "PutObjectTagging")
+	err := b.Backend.PutObjectTagging(ctx, bucket, object, tags)
+	End(span, err)
+	return err
+}
+
+func (b *Backend) DeleteObjectTagging(ctx context.Context, bucket, object string) error {
+	ctx, span := b.start(ctx, "DeleteObjectTagging")
+	err := b.Backend.DeleteObjectTagging(ctx, bucket, object)
+	End(span, err)
+	return err
+}
+
+// object lock operations
+func (b *Backend) PutObjectLockConfiguration(ctx context.Context, input *s3.PutObjectLockConfigurationInput) error {
+	ctx, span := b.start(ctx, "PutObjectLockConfiguration")
+	err := b.Backend.PutObjectLockConfiguration(ctx, input)
+	End(span, err)
+	return err
+}
+
+func (b *Backend) GetObjectLockConfiguration(ctx context.Context, bucket string) (*types.ObjectLockConfiguration, error) {
+	ctx, span := b.start(ctx, "GetObjectLockConfiguration")
+	out, err := b.Backend.GetObjectLockConfiguration(ctx, bucket)
+	End(span, err)
+	return out, err
+}
+
+func (b *Backend) PutObjectRetention(ctx context.Context, input *s3.PutObjectRetentionInput) error {
+	ctx, span := b.start(ctx, "PutObjectRetention")
+	err := b.Backend.PutObjectRetention(ctx, input)
+	End(span, err)
+	return err
+}
+
+func (b *Backend) GetObjectRetention(ctx context.Context, input *s3.GetObjectRetentionInput) (*types.ObjectLockRetention, error) {
+	ctx, span := b.start(ctx, "GetObjectRetention")
+	out, err := b.Backend.GetObjectRetention(ctx, input)
+	End(span, err)
+	return out, err
+}
+
+func (b *Backend) PutObjectLegalHold(ctx context.Context, input *s3.PutObjectLegalHoldInput) error {
+	ctx, span := b.start(ctx, "PutObjectLegalHold")
+	err := b.Backend.PutObjectLegalHold(ctx, input)
+	End(span, err)
+	return err
+}
+
+func (b *Backend) GetObjectLegalHold(ctx context.Context, input *s3.GetObjectLegalHoldInput) (*types.ObjectLockLegalHold, error) {
+	ctx, span := b.start(ctx, "GetObjectLegalHold")
+	out, err := b.Backend.GetObjectLegalHold(ctx, input)
+	End(span, err)
+	return out, err
+}
+
+// non AWS actions
+func (b *Backend) ChangeBucketOwner(ctx context.Context, bucket, newOwner string) error {
+	ctx, span := b.start(ctx, "ChangeBucketOwner")
+	err := b.Backend.ChangeBucketOwner(ctx, bucket, newOwner)
+	End(span, err)
+	return err
+}
+
+func (b *Backend) ListBucketsAndOwners(ctx context.Context) ([]s3response.Bucket, error) {
+	ctx, span := b.start(ctx, "ListBucketsAndOwners")
+	out, err := b.Backend.ListBucketsAndOwners(ctx)
+	End(span, err)
+	return out, err
+}
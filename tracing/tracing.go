@@ -0,0 +1,266 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package tracing exports OpenTelemetry traces of the gateway requests.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ExporterOTLPHTTP = "otlp-http"
+	ExporterOTLPGRPC = "otlp-grpc"
+	ExporterStdout   = "stdout"
+
+	tracerName = "github.com/versity/versitygw"
+)
+
+// Config selects the trace exporter
+type Config struct {
+	// Exporter is one of otlp-http, otlp-grpc or stdout
+	Exporter string
+	// Endpoint is the host:port of the OTLP collector, the exporters
+	// default to the OTEL_EXPORTER_OTLP_* environment variables
+	Endpoint string
+	// Insecure disables TLS to the OTLP collector
+	Insecure bool
+	// SampleRatio is the ratio of the traces started by the gateway that
+	// are sampled, the sampling of propagated traces is kept
+	SampleRatio float64
+	// Version is the service version of the traces
+	Version string
+}
+
+var tracer = otel.Tracer(tracerName)
+
+// Init sets up the global tracer provider with the exporter. The returned
+// function flushes the pending spans and stops the exporter.
+func Init(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	exp, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("init %v exporter: %w", cfg.Exporter, err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(
+			semconv.ServiceName("versitygw"),
+			semconv.ServiceVersion(cfg.Version),
+		),
+		// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the defaults
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("init trace resource: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+
+	return tp.Shutdown, nil
+}
+
+func newExporter(ctx context.Context, cfg Config) (sdktrace.SpanExporter, error) {
+	switch cfg.Exporter {
+	case ExporterOTLPHTTP:
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(ctx, opts...)
+	case ExporterOTLPGRPC:
+		var opts []otlptracegrpc.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		return otlptracegrpc.New(ctx, opts...)
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q, should be one of %v, %v, %v",
+			cfg.Exporter, ExporterOTLPHTTP, ExporterOTLPGRPC, ExporterStdout)
+	}
+}
+
+// Extract returns the trace context propagated in the request headers
+func Extract(ctx *fiber.Ctx) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx.Context(), headerCarrier{ctx: ctx})
+}
+
+type headerCarrier struct {
+	ctx *fiber.Ctx
+}
+
+// Get clones the header values, the baggage may be kept past the request
+func (c headerCarrier) Get(key string) string {
+	return strings.Clone(c.ctx.Get(key))
+}
+
+func (c headerCarrier) Set(key, value string) {
+	c.ctx.Request().Header.Set(key, value)
+}
+
+func (c headerCarrier) Keys() []string {
+	var keys []string
+	c.ctx.Request().Header.VisitAll(func(key, _ []byte) {
+		keys = append(keys, string(key))
+	})
+	return keys
+}
+
+type requestSpanKey struct{}
+
+// SetRequestSpan makes span the parent of the spans started with the
+// request context. The fasthttp request context used by the controllers
+// can not carry the span, so it is kept in the request user values.
+func SetRequestSpan(ctx *fiber.Ctx, span trace.Span) {
+	ctx.Context().SetUserValue(requestSpanKey{}, span)
+}
+
+// Start starts a span as a child of the span of ctx, or of the span set on
+// the request when ctx is a request context
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		if span, ok := ctx.Value(requestSpanKey{}).(trace.Span); ok {
+			ctx = trace.ContextWithSpan(ctx, span)
+		}
+	}
+	return tracer.Start(ctx, name, opts...)
+}
+
+// End records the error of the span and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Reader times the reads of r in a span from the first read to the end of
+// the stream. The reads of the request body are interleaved with the
+// writes of the backend, so the time spent in r is recorded as the
+// read.seconds attribute.
+func Reader(ctx context.Context, name string, r io.Reader) *BodyReader {
+	return &BodyReader{ctx: ctx, name: name, r: r}
+}
+
+// BodyReader is a reader timed by a span
+type BodyReader struct {
+	ctx   context.Context
+	name  string
+	r     io.Reader
+	span  trace.Span
+	bytes int64
+	time  time.Duration
+}
+
+func (r *BodyReader) Read(p []byte) (int, error) {
+	if r.span == nil {
+		_, r.span = Start(r.ctx, r.name)
+	}
+
+	start := time.Now()
+	n, err := r.r.Read(p)
+	r.time += time.Since(start)
+	r.bytes += int64(n)
+
+	if err == io.EOF {
+		r.End(nil)
+	} else if err != nil {
+		r.End(err)
+	}
+	return n, err
+}
+
+// End ends the span of the reads if not already ended
+func (r *BodyReader) End(err error) {
+	if r.span == nil || !r.span.IsRecording() {
+		return
+	}
+	r.span.SetAttributes(
+		attribute.Int64("read.bytes", r.bytes),
+		attribute.Float64("read.seconds", r.time.Seconds()),
+	)
+	End(r.span, err)
+}
+
+// Transport creates a client span for each request sent with rt, and
+// propagates the trace context to the server in the request headers
+func Transport(rt http.RoundTripper) http.RoundTripper {
+	return &transport{rt: rt}
+}
+
+type transport struct {
+	rt http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// the query is left out, it may hold credentials
+	ctx, span := Start(req.Context(), "HTTP "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.ServerAddress(req.URL.Hostname()),
+			semconv.URLPath(req.URL.Path),
+		))
+
+	req = req.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := t.rt.RoundTrip(req)
+	if err != nil {
+		End(span, err)
+		return nil, err
+	}
+
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	span.End()
+	return resp, nil
+}
@@ -0,0 +1,164 @@
+// Copyright 2023 Versity Software
+// This file is licensed under the Apache License, Version 2.0
+// (the "License"); you may not use this file except in compliance
+// with the License.  You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tracing
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/gofiber/fiber/v2"
+	"github.com/versity/versitygw/backend"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recorder is set once, the package tracer delegates to the first global
+// tracer provider
+var recorder = tracetest.NewSpanRecorder()
+
+func TestMain(m *testing.M) {
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	os.Exit(m.Run())
+}
+
+func endedSpan(t *testing.T, name string) sdktrace.ReadOnlySpan {
+	t.Helper()
+	for _, span := range recorder.Ended() {
+		if span.Name() == name {
+			return span
+		}
+	}
+	t.Fatalf("span %v not found", name)
+	return nil
+}
+
+type unsupported struct {
+	backend.BackendUnsupported
+}
+
+func (unsupported) String() string {
+	return "Unsupported"
+}
+
+func TestBackend(t *testing.T) {
+	be := NewBackend(unsupported{})
+
+	ctx, parent := Start(context.Background(), "parent")
+	bucket, key := "bucket", "key"
+	_, err := be.PutObject(ctx, &s3.PutObjectInput{Bucket: &bucket, Key: &key})
+	if err == nil {
+		t.Fatal("expected the backend error")
+	}
+	parent.End()
+
+	span := endedSpan(t, "Backend.PutObject")
+	if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Fatal("expected the backend span to be a child of the context span")
+	}
+	if span.Status().Code != codes.Error {
+		t.Fatalf("expected the error status, got %v", span.Status())
+	}
+}
+
+func TestRequestSpan(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", func(ctx *fiber.Ctx) error {
+		_, span := Start(Extract(ctx), "request")
+		SetRequestSpan(ctx, span)
+
+		// the request context is not derived from the span context
+		_, child := Start(ctx.Context(), "request-child")
+		child.End()
+		span.End()
+		return nil
+	})
+
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	_, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	span := endedSpan(t, "request")
+	if span.SpanContext().TraceID().String() != traceID {
+		t.Fatalf("expected the propagated trace, got %v", span.SpanContext().TraceID())
+	}
+	child := endedSpan(t, "request-child")
+	if child.Parent().SpanID() != span.SpanContext().SpanID() {
+		t.Fatal("expected the child of the request span")
+	}
+}
+
+func TestTransport(t *testing.T) {
+	var traceparent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("Traceparent")
+	}))
+	defer srv.Close()
+
+	ctx, parent := Start(context.Background(), "client-parent")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/bucket?secret=1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: Transport(http.DefaultTransport)}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	parent.End()
+
+	span := endedSpan(t, "HTTP GET")
+	if !strings.Contains(traceparent, span.SpanContext().SpanID().String()) {
+		t.Fatalf("expected the client span to be propagated, got %q", traceparent)
+	}
+	for _, attr := range span.Attributes() {
+		if strings.Contains(attr.Value.Emit(), "secret") {
+			t.Fatalf("expected the query to be left out, got %v", attr)
+		}
+	}
+}
+
+func TestReader(t *testing.T) {
+	r := Reader(context.Background(), "reader", strings.NewReader("0123456789"))
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "0123456789" {
+		t.Fatalf("unexpected data %q", data)
+	}
+	r.End(nil)
+
+	span := endedSpan(t, "reader")
+	for _, attr := range span.Attributes() {
+		if attr.Key == "read.bytes" && attr.Value.AsInt64() != 10 {
+			t.Fatalf("expected 10 bytes read, got %v", attr.Value.AsInt64())
+		}
+	}
+}